@@ -13,6 +13,10 @@ import (
 	"github.com/catstream/claude-relay-go/internal/handlers"
 	"github.com/catstream/claude-relay-go/internal/middleware"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
+	"github.com/catstream/claude-relay-go/internal/services/metrics"
+	"github.com/catstream/claude-relay-go/internal/services/pricing"
+	"github.com/catstream/claude-relay-go/internal/services/webhook"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"github.com/catstream/claude-relay-go/pkg/types"
 	"github.com/gin-gonic/gin"
@@ -27,6 +31,7 @@ const (
 	redisQueryTimeout  = 5 * time.Second   // 简单查询超时
 	redisScanTimeout   = 10 * time.Second  // SCAN 操作超时（可能遍历大量数据）
 	shutdownTimeout    = 30 * time.Second  // 优雅关闭超时
+	drainTimeout       = 10 * time.Second  // 关闭后释放并发占用超时
 	readTimeout        = 30 * time.Second  // HTTP 读取超时
 	writeTimeout       = 600 * time.Second // HTTP 写入超时（流式响应需要较长时间）
 	idleTimeout        = 120 * time.Second // HTTP 空闲超时
@@ -60,29 +65,71 @@ func main() {
 	}
 	defer redisClient.Disconnect()
 
+	// Redis 自检（探针键读写与 Lua 脚本，结果反映在 /health）
+	selfTester := redis.NewSelfTesterFromConfig(redisClient)
+	if selfTester != nil {
+		selfTester.Start()
+		defer selfTester.Stop()
+	}
+
+	// 并发数计数器定时校准（启用后并发数读取使用 O(1) 计数器）
+	if reconciler := redis.NewConcurrencyReconcilerFromConfig(redisClient); reconciler != nil {
+		reconciler.Start()
+		defer reconciler.Stop()
+	}
+
+	// 初始化定价服务（远程价格更新与灰度发布）
+	pricingService := pricing.NewService(redisClient)
+	if err := pricingService.Initialize(context.Background()); err != nil {
+		logger.Warn("Failed to initialize pricing service", zap.Error(err))
+	}
+	defer pricingService.Stop()
+
+	// 指标推送（statsd / OTLP，未配置时不启动）
+	metricsExporter, err := metrics.NewExporterFromConfig(redisClient)
+	if err != nil {
+		logger.Warn("Failed to create metrics exporter", zap.Error(err))
+	} else if metricsExporter != nil {
+		metricsExporter.Start()
+		defer metricsExporter.Stop()
+	}
+
 	// 4. 设置 Gin 模式
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// 本服务不承载转发路由，流式空闲超时只对挂载了 StreamIdleTimeout 的转发路由生效
+	if cfg.System.StreamIdleTimeout > 0 {
+		logger.Warn("STREAM_IDLE_TIMEOUT is set but this service mounts no relay routes; it applies only where middleware.StreamIdleTimeout is mounted",
+			zap.Duration("streamIdleTimeout", cfg.System.StreamIdleTimeout))
+	}
+
 	// 5. 创建路由
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(ginLogger())
 
 	// 健康检查
-	router.GET("/health", healthHandler(redisClient))
+	router.GET("/health", healthHandler(redisClient, selfTester))
 
 	// 版本信息
 	router.GET("/version", versionHandler())
 
 	// 初始化 handlers
-	apiKeyHandler := handlers.NewAPIKeyHandler(redisClient)
+	apiKeyHandler := handlers.NewAPIKeyHandler(redisClient).
+		WithWebhookNotifier(webhook.NewNotifierFromConfig()).
+		WithPricingService(pricingService).
+		WithAPIKeyService(apikey.NewService(redisClient))
 	concurrencyHandler := handlers.NewConcurrencyHandler(redisClient)
 	sessionHandler := handlers.NewSessionHandler(redisClient)
 	accountHandler := handlers.NewAccountHandler(redisClient)
 	lockHandler := handlers.NewLockHandler(redisClient)
 	genericHandler := handlers.NewGenericHandler(redisClient)
+	authHandler := handlers.NewAuthHandler(redisClient)
+	pricingHandler := handlers.NewPricingHandler(pricingService)
+	schedulerHandler := handlers.NewSchedulerHandler(redisClient)
+	clientHandler := handlers.NewClientHandler()
 
 	// Redis 代理 API（供 Node.js 调用）
 	redisAPI := router.Group("/redis")
@@ -92,19 +139,65 @@ func main() {
 		{
 			apikeys.GET("", apiKeyHandler.GetAllAPIKeys)
 			apikeys.GET("/paginated", apiKeyHandler.GetAPIKeysPaginated)
+			apikeys.GET("/cursor", apiKeyHandler.GetAPIKeysByCursor)
 			apikeys.GET("/stats", apiKeyHandler.GetAPIKeyStats)
+			apikeys.GET("/diff", apiKeyHandler.DiffAPIKeys)
+			apikeys.GET("/tag/:tag/usage", apiKeyHandler.GetTagUsage)
+			apikeys.GET("/top-cost", apiKeyHandler.GetTopCostKeys)
+			apikeys.GET("/export", middleware.RequireAdmin(redisClient), apiKeyHandler.ExportAPIKeys)
+			apikeys.POST("/import", middleware.RequireAdmin(redisClient), apiKeyHandler.ImportAPIKeys)
 			apikeys.GET("/:id", apiKeyHandler.GetAPIKey)
 			apikeys.GET("/hash/:hash", apiKeyHandler.GetAPIKeyByHash)
 			apikeys.POST("", apiKeyHandler.SetAPIKey)
+			apikeys.POST("/batch", apiKeyHandler.SetAPIKeysBatch)
+			apikeys.POST("/rebuild-hashmap", middleware.RequireAdmin(redisClient), apiKeyHandler.RebuildHashMap)
 			apikeys.PUT("/:id", apiKeyHandler.UpdateAPIKeyFields)
+			apikeys.PUT("/:id/config", apiKeyHandler.SwapAPIKeyConfig)
+			apikeys.POST("/:id/config/rollback", apiKeyHandler.RollbackAPIKeyConfig)
+			apikeys.PUT("/:id/features", middleware.RequireAdmin(redisClient), apiKeyHandler.SetAPIKeyFeatureFlags)
 			apikeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
 			apikeys.DELETE("/:id/hard", apiKeyHandler.HardDeleteAPIKey)
+			apikeys.POST("/:id/restore", apiKeyHandler.RestoreAPIKey)
 			// 成本和使用统计
 			apikeys.POST("/:id/cost/daily", apiKeyHandler.IncrementDailyCost)
 			apikeys.GET("/:id/cost/daily", apiKeyHandler.GetDailyCost)
+			apikeys.GET("/:id/daily-tokens", apiKeyHandler.GetDailyTokens)
+			apikeys.GET("/:id/health", apiKeyHandler.GetKeyHealth)
+			apikeys.POST("/:id/daily-tokens/reset", middleware.RequireAdmin(redisClient), apiKeyHandler.ResetDailyTokens)
 			apikeys.GET("/:id/cost/stats", apiKeyHandler.GetCostStats)
+			apikeys.GET("/:id/cost/history", apiKeyHandler.GetCostHistory)
+			apikeys.GET("/:id/cost/projection", apiKeyHandler.GetCostProjection)
+			apikeys.GET("/:id/cost/reconciliation", apiKeyHandler.GetCostReconciliation)
+			apikeys.GET("/:id/cost/by-model", apiKeyHandler.GetCostByModel)
+			apikeys.POST("/:id/cost/micros-migration", middleware.RequireAdmin(redisClient), apiKeyHandler.MigrateCostToMicros)
+			apikeys.POST("/:id/cost/tags", apiKeyHandler.IncrementTagCost)
+			apikeys.GET("/:id/cost/tags", apiKeyHandler.GetCostByTag)
+			apikeys.POST("/:id/simulate", apiKeyHandler.SimulateLimits)
+			apikeys.POST("/:id/diagnose", apiKeyHandler.DiagnoseLimits)
+			apikeys.POST("/:id/concurrency/boost", middleware.RequireAdmin(redisClient), apiKeyHandler.BoostConcurrency)
+			// 调试采样
+			apikeys.GET("/:id/timeline", apiKeyHandler.GetAPIKeyTimeline)
+			apikeys.GET("/:id/debug-captures", apiKeyHandler.GetDebugCaptures)
+			apikeys.POST("/:id/debug-captures", apiKeyHandler.SetDebugCaptureCount)
+			apikeys.DELETE("/:id/debug-captures", apiKeyHandler.ClearDebugCaptures)
 			apikeys.POST("/usage", apiKeyHandler.IncrementTokenUsage)
+			apikeys.POST("/usage/batch", apiKeyHandler.IncrementTokenUsageBatch)
 			apikeys.GET("/:id/usage", apiKeyHandler.GetUsageStats)
+			apikeys.GET("/:id/usage/hourly", apiKeyHandler.GetHourlyUsage)
+			apikeys.GET("/:id/usage/range", apiKeyHandler.GetUsageRange)
+			apikeys.GET("/:id/usage/export", apiKeyHandler.ExportUsage)
+			apikeys.GET("/:id/rates", apiKeyHandler.GetRecentRates)
+		}
+
+		// 模型使用统计清理（所有 Key 与账户）
+		redisAPI.DELETE("/usage/models", middleware.RequireAdmin(redisClient), apiKeyHandler.PurgeModelUsage)
+
+		// 用户级默认限制（API Key 未设置的限制继承用户默认值）与并发汇总
+		users := redisAPI.Group("/users")
+		{
+			users.GET("/:id/defaults", apiKeyHandler.GetUserLimitDefaults)
+			users.PUT("/:id/defaults", middleware.RequireAdmin(redisClient), apiKeyHandler.SetUserLimitDefaults)
+			users.GET("/:id/concurrency", concurrencyHandler.GetUserConcurrency)
 		}
 
 		// 并发控制
@@ -112,10 +205,14 @@ func main() {
 		{
 			concurrency.POST("/incr", concurrencyHandler.IncrConcurrency)
 			concurrency.POST("/decr", concurrencyHandler.DecrConcurrency)
+			concurrency.POST("/acquire-with-intent", concurrencyHandler.AcquireWithIntent)
+			concurrency.POST("/intent/complete", concurrencyHandler.CompleteIntent)
+			concurrency.GET("/intent/:apiKeyId", concurrencyHandler.GetPendingIntents)
 			concurrency.GET("/:apiKeyId", concurrencyHandler.GetConcurrency)
 			concurrency.GET("/:apiKeyId/status", concurrencyHandler.GetConcurrencyStatus)
 			concurrency.GET("/status/all", concurrencyHandler.GetAllConcurrencyStatus)
 			concurrency.POST("/lease/refresh", concurrencyHandler.RefreshConcurrencyLease)
+			concurrency.POST("/lease/refresh/batch", concurrencyHandler.RefreshConcurrencyLeasesBatch)
 			concurrency.POST("/cleanup", concurrencyHandler.CleanupExpiredConcurrency)
 			concurrency.DELETE("/:apiKeyId/force", concurrencyHandler.ForceClearConcurrency)
 			concurrency.DELETE("/force/all", concurrencyHandler.ForceClearAllConcurrency)
@@ -130,6 +227,8 @@ func main() {
 			concurrency.DELETE("/queue/:apiKeyId", concurrencyHandler.ClearConcurrencyQueue)
 			concurrency.DELETE("/queue/all", concurrencyHandler.ClearAllConcurrencyQueues)
 			concurrency.GET("/queue/:apiKeyId/stats", concurrencyHandler.GetQueueStats)
+			concurrency.GET("/queue/:apiKeyId/stats/window", concurrencyHandler.GetQueueStatsWindow)
+			concurrency.GET("/queue/:apiKeyId/samples", concurrencyHandler.GetWaitTimeSamples)
 			concurrency.GET("/queue/global/stats", concurrencyHandler.GetGlobalQueueStats)
 			concurrency.GET("/queue/health", concurrencyHandler.CheckQueueHealth)
 			concurrency.POST("/queue/wait-time", concurrencyHandler.RecordWaitTime)
@@ -154,6 +253,7 @@ func main() {
 			sessions.DELETE("/sticky/:sessionHash", sessionHandler.DeleteStickySession)
 			sessions.POST("/sticky/renew", sessionHandler.RenewStickySession)
 			sessions.GET("/sticky/all", sessionHandler.GetAllStickySessions)
+			sessions.GET("/sticky/by-account", sessionHandler.GetStickySessionCountsByAccount)
 			sessions.POST("/sticky/cleanup", sessionHandler.CleanupExpiredStickySessions)
 		}
 
@@ -162,15 +262,19 @@ func main() {
 		{
 			accounts.GET("/:type", accountHandler.GetAllAccounts)
 			accounts.GET("/:type/active", accountHandler.GetActiveAccounts)
+			accounts.GET("/:type/export", accountHandler.ExportAccounts)
 			accounts.GET("/:type/:id", accountHandler.GetAccount)
 			accounts.GET("/:type/:id/raw", accountHandler.GetAccountRaw)
 			accounts.POST("/:type/:id", accountHandler.SetAccount)
+			accounts.PATCH("/:type/:id", accountHandler.UpdateAccountFields)
 			accounts.DELETE("/:type/:id", accountHandler.DeleteAccount)
 			accounts.PUT("/:type/:id/status", accountHandler.UpdateAccountStatus)
 			accounts.POST("/:type/:id/error", accountHandler.SetAccountError)
 			accounts.DELETE("/:type/:id/error", accountHandler.ClearAccountError)
 			accounts.POST("/:type/:id/overloaded", accountHandler.SetAccountOverloaded)
 			accounts.DELETE("/:type/:id/overloaded", accountHandler.ClearAccountOverloaded)
+			accounts.POST("/:type/clear-overloaded", middleware.RequireAdmin(redisClient), accountHandler.ClearOverloadedAccounts)
+			accounts.POST("/:type/status/batch", middleware.RequireAdmin(redisClient), accountHandler.BatchUpdateAccountStatus)
 			// 账户锁
 			accounts.POST("/lock", accountHandler.SetAccountLock)
 			accounts.POST("/lock/release", accountHandler.ReleaseAccountLock)
@@ -194,27 +298,62 @@ func main() {
 			locks.POST("/release", lockHandler.ReleaseLock)
 			locks.POST("/extend", lockHandler.ExtendLock)
 			// 用户消息锁
+			locks.GET("/user-message", lockHandler.ListUserMessageLocks)
+			locks.GET("/user-message/stuck", lockHandler.GetStuckUserMessageLocks)
 			locks.POST("/user-message/acquire", lockHandler.AcquireUserMessageLock)
 			locks.POST("/user-message/release", lockHandler.ReleaseUserMessageLock)
 			locks.DELETE("/user-message/:accountId/force", lockHandler.ForceReleaseUserMessageLock)
 			locks.GET("/user-message/:accountId/stats", lockHandler.GetUserMessageQueueStats)
 		}
 
-		// 通用 Redis 操作
+		// 调度（返回排序后的候选账户，供客户端自行故障转移）
+		sched := redisAPI.Group("/scheduler")
+		{
+			sched.POST("/:category/ranked", schedulerHandler.SelectRankedAccounts)
+			sched.GET("/pool/:category/capacity", schedulerHandler.EstimatePoolCapacity)
+			sched.GET("/eligible", schedulerHandler.ListEligibleAccounts)
+		}
+
+		// 客户端识别（仅开发环境，用于排查 User-Agent 识别问题）
+		clientsAPI := redisAPI.Group("/clients")
+		{
+			clientsAPI.GET("/parse", middleware.DevelopmentOnly(cfg.Server.Env), clientHandler.ParseClientType)
+		}
+
+		// 认证统计
+		auth := redisAPI.Group("/auth")
+		{
+			auth.GET("/failure-stats", authHandler.GetAuthFailureStats)
+		}
+
+		// 通用 Redis 操作（可通过 GENERIC_REDIS_COMMANDS 限制允许的操作）
 		generic := redisAPI.Group("/generic")
 		{
-			generic.GET("/get/*key", genericHandler.Get)
-			generic.POST("/set", genericHandler.Set)
-			generic.POST("/del", genericHandler.Del)
-			generic.GET("/scan", genericHandler.ScanKeys)
-			generic.GET("/hgetall/*key", genericHandler.HGetAll)
-			generic.POST("/hset", genericHandler.HSet)
-			generic.GET("/dbsize", genericHandler.DBSize)
-			generic.GET("/info", genericHandler.Info)
-			generic.GET("/models", genericHandler.GetAllUsedModels)
+			generic.GET("/get/*key", middleware.AllowGenericCommand("get"), genericHandler.Get)
+			generic.POST("/set", middleware.AllowGenericCommand("set"), genericHandler.Set)
+			generic.POST("/del", middleware.AllowGenericCommand("del"), genericHandler.Del)
+			generic.GET("/scan", middleware.AllowGenericCommand("scan"), genericHandler.ScanKeys)
+			generic.GET("/hgetall/*key", middleware.AllowGenericCommand("hgetall"), genericHandler.HGetAll)
+			generic.GET("/hscan/*key", middleware.AllowGenericCommand("hscan"), genericHandler.HScan)
+			generic.POST("/hset", middleware.AllowGenericCommand("hset"), genericHandler.HSet)
+			generic.GET("/dbsize", middleware.AllowGenericCommand("dbsize"), genericHandler.DBSize)
+			generic.GET("/info", middleware.AllowGenericCommand("info"), genericHandler.Info)
+			generic.GET("/models", middleware.AllowGenericCommand("models"), genericHandler.GetAllUsedModels)
 		}
 	}
 
+	// 定价管理（价格更新灰度发布）
+	pricingAPI := router.Group("/pricing")
+	pricingAPI.Use(middleware.RequireAdmin(redisClient))
+	{
+		pricingAPI.GET("/status", pricingHandler.GetStatus)
+		pricingAPI.POST("/promote", pricingHandler.Promote)
+		pricingAPI.POST("/rollback", pricingHandler.Rollback)
+		pricingAPI.POST("/coverage", pricingHandler.Coverage)
+		pricingAPI.POST("/refresh", pricingHandler.StartRefresh)
+		pricingAPI.GET("/refresh/:jobId", pricingHandler.GetRefreshJob)
+	}
+
 	// Redis 数据读取测试（仅开发环境）
 	testRoutes := router.Group("/test")
 	testRoutes.Use(middleware.DevelopmentOnly(cfg.Server.Env))
@@ -258,9 +397,22 @@ func main() {
 		logger.Error("❌ Server forced to shutdown", zap.Error(err))
 	}
 
+	// 释放本实例仍持有的并发租约与排队计数（处理器可能在超时后被强制终止）
+	drainConcurrency(redisClient)
+
 	logger.Info("👋 Server exited")
 }
 
+// drainConcurrency 关闭时释放并发占用（使用独立超时，不受已过期的关闭上下文影响）
+func drainConcurrency(redisClient *redis.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if _, err := redisClient.DrainConcurrency(ctx); err != nil {
+		logger.Warn("⚠️ Concurrency drain incomplete", zap.Error(err))
+	}
+}
+
 // ginLogger Gin 日志中间件
 func ginLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -281,8 +433,8 @@ func ginLogger() gin.HandlerFunc {
 	}
 }
 
-// healthHandler 健康检查处理器
-func healthHandler(redisClient *redis.Client) gin.HandlerFunc {
+// healthHandler 健康检查处理器（selfTester 为 nil 表示未启用 Redis 自检）
+func healthHandler(redisClient *redis.Client, selfTester *redis.SelfTester) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 设置健康检查超时（应快速响应）
 		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
@@ -309,6 +461,20 @@ func healthHandler(redisClient *redis.Client) gin.HandlerFunc {
 			},
 		}
 
+		// Redis 自检连续失败（如只读副本、键被淘汰）时同样视为不健康
+		if selfTester != nil {
+			selfTest := selfTester.Status()
+			response.Components["redis_selftest"] = selfTest.Healthy
+			if !selfTest.Healthy {
+				response.Status = "unhealthy"
+				if response.Details == nil {
+					response.Details = make(map[string]string)
+				}
+				response.Details["redis_selftest"] = selfTest.Error
+				httpStatus = http.StatusServiceUnavailable
+			}
+		}
+
 		c.JSON(httpStatus, response)
 	}
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -17,8 +19,11 @@ type Config struct {
 	Security       SecurityConfig
 	System         SystemConfig
 	Pricing        PricingConfig
+	Cost           CostConfig
 	UserManagement UserManagementConfig
 	Web            WebConfig
+	Webhook        WebhookConfig
+	MetricsExport  MetricsExportConfig
 }
 
 type ServerConfig struct {
@@ -38,6 +43,10 @@ type RedisConfig struct {
 	CommandTimeout time.Duration
 	MaxRetries     int
 	EnableTLS      bool
+	KeyPrefix      string // 全局 key 命名空间前缀（多个部署共享同一 Redis DB 时使用；Node.js 不支持，启用后无法与 Node.js 共享数据）
+	// 只读副本（为空表示不使用），用于扫描与统计等可容忍复制延迟的读取，密码与 DB 与主库相同
+	ReplicaHost string
+	ReplicaPort int
 }
 
 type PostgresConfig struct {
@@ -57,11 +66,104 @@ type SecurityConfig struct {
 	APIKeyPrefix   string
 	EncryptionKey  string
 	ClaudeCodeOnly bool // 全局 Claude Code Only 限制
+	// 自定义 User-Agent 片段到客户端类型的映射（优先于内置识别，片段越长越优先）
+	CustomClientTypes map[string]string
+	// 验证诊断（仅非生产环境生效）
+	ValidationDiagnostics      bool   // 所有验证失败均返回诊断信息
+	ValidationDiagnosticsToken string // 携带匹配的 X-Validation-Diagnostics-Token 请求头时返回诊断信息
+	// 认证失败统计采样比例（1-100，按权重补偿计数）
+	AuthFailureSamplePercent int
+	// 响应头暴露实际服务账户（默认关闭，避免泄露账户池信息）
+	ExposeAccountHeaders bool   // 所有响应均返回账户头
+	AccountHeadersToken  string // 携带匹配的 X-CRS-Debug-Token 请求头时返回账户头
+	// 启用 API Key 调试采样（记录脱敏后的请求/响应，默认关闭）
+	DebugCaptureEnabled bool
+	// 允许的通用 Redis 操作（/redis/generic，如 get,scan,hgetall；为空表示全部允许）
+	GenericRedisCommands []string
+	// 软限制预警阈值（占限制的百分比，1-99；API Key 可单独覆盖）
+	LimitWarningPercent int
+	// 过期 Key 统一返回 expired 错误码（不区分未激活、激活后过期与固定过期，兼容旧客户端）
+	LegacyExpiryErrors bool
+	// 验证时 API Key 数据的进程内缓存时长（0 表示不缓存，最长 30 秒；API Key 可通过 cacheTTLSeconds 单独覆盖）
+	APIKeyCacheTTL time.Duration
+	// 请求未指定模型时的处理方式：passthrough（默认，保持为空）、strict（拒绝）、default（使用 Key 的默认模型）、unknown（使用 unknown 占位模型）
+	MissingModelPolicy string
+	// 按路由覆盖未指定模型的处理方式（路由路径 -> 处理方式，如 /v1/models=passthrough）
+	MissingModelRoutePolicies map[string]string
+	// unknown 模式下优先路由到的账户类型
+	MissingModelAccountType string
 }
 
 type SystemConfig struct {
 	TimezoneOffset int
 	MetricsWindow  int
+	// 全局并发上限（所有 API Key 合计，0 表示不限制）
+	GlobalConcurrencyLimit int
+	// 并发排队模式：simple（轮询抢占，默认）或 priority（按 Key 调度优先级与入队时间依次放行）
+	ConcurrencyQueueMode string
+	// 并发租约自动续约：检查间隔（0 表示不自动续约）与续约阈值（剩余租约低于完整租约的百分比时才续约）
+	ConcurrencyLeaseCheckInterval  time.Duration
+	ConcurrencyLeaseRefreshPercent int
+	// 账户错误统计窗口与熔断阈值（窗口内错误数达到阈值时冷却一个窗口，0 表示不熔断）
+	AccountErrorWindow    time.Duration
+	AccountErrorThreshold int
+	// 统计聚合默认排除测试/开发 Key（请求可通过 excludeTest 参数覆盖）
+	ExcludeTestKeysFromStats bool
+	// 请求失败后切换账户重试的最大次数（0 表示不切换）
+	MaxFailoverAttempts int
+	// 为高优先级 API Key 预留的最优账户比例（百分比，0 表示不预留）
+	ReservedAccountPercent int
+	// 账户选择策略（priority：按优先级与负载；cost-optimized：按成本与负载加权）
+	SelectionStrategy string
+	// 账户类型成本系数（type 或 type:模型关键字 -> 系数，默认 1），用于 cost-optimized 策略
+	AccountCostFactors map[string]float64
+	// cost-optimized 策略中成本所占权重（百分比，其余为负载权重）
+	SelectionCostWeightPercent int
+	// 账户（重新）激活或恢复后的预热时长（0 表示不预热）与预热开始时的流量比例（百分比，随时间线性升至 100）
+	AccountWarmupDuration       time.Duration
+	AccountWarmupInitialPercent int
+	// 账户选择（收集与评估候选账户）的截止时间（0 表示不限制），超时后使用已收集到的候选账户
+	AccountSelectionTimeout time.Duration
+	// 账户并发已满时是否排队等待账户槽位释放（而非直接返回无可用账户）
+	AccountQueueEnabled bool
+	// 账户排队最长等待时间
+	AccountQueueTimeout time.Duration
+	// API Key 健康分各项权重（cost、rateLimit、concurrency、expiry -> 权重，未设置的项使用默认权重）
+	KeyHealthWeights map[string]float64
+	// API Key 每日 Token 限额的计数权重（input、output、cacheCreate、cacheRead -> 倍数，未设置的项为 1.0；原始用量统计不受影响）
+	DailyTokenWeights map[string]float64
+	// 重复释放并发槽位（租约已不存在）时返回错误而非视为无操作（用于排查释放逻辑）
+	StrictConcurrencyRelease bool
+	// 并发请求元数据（model|clientType|startMs）写入旁路哈希 concurrency_meta:{keyId}，便于并发状态接口展示请求详情
+	ConcurrencyMemberMetadata bool
+	// Redis 自检间隔（写入探针键、读回并执行 Lua 脚本校验，0 表示不自检）
+	RedisSelfTestInterval time.Duration
+	// Redis 自检连续失败多少次后健康检查才判定为不健康
+	RedisSelfTestFailureThreshold int
+	// 并发软上限：达到并发上限时默认进入短时排队而非直接拒绝（API Key 可将 concurrentRequestQueueMode 设为 disabled 退出）
+	ConcurrencySoftCap bool
+	// 软上限排队的最长等待时间（Key 未设置 concurrentRequestQueueTimeoutMs 时使用）
+	ConcurrencySoftCapTimeout time.Duration
+	// 流式转发首次输出后无数据输出的最长空闲时间（超时后取消请求并释放并发槽位，0 表示不限制；API Key 可通过 streamIdleTimeoutSeconds 单独覆盖）
+	// 仅在挂载了 middleware.StreamIdleTimeout 的转发路由上生效，本服务的 /redis 代理接口不受影响
+	StreamIdleTimeout time.Duration
+	// 全局活跃粘性会话上限（0 表示不限制）
+	StickySessionMaxCount int
+	// 达到粘性会话上限时的处理方式：evict（淘汰最早到期的会话）或 skip（本次请求不绑定会话）
+	StickySessionCapMode string
+	// 并发计数器校准间隔（>0 时并发数读取使用 O(1) 计数器，并定时按有序集合校准；0 表示不启用，读取时实时统计）
+	ConcurrencyCounterReconcileInterval time.Duration
+	// 加油包耗尽或过期导致成本限制拒绝时返回 HTTP 402（默认 429，错误码均为 insufficient_fuel）
+	InsufficientFuelPaymentRequired bool
+}
+
+// CostConfig 成本精度与货币展示配置
+type CostConfig struct {
+	StorageDecimals int    // 存储/计算保留的小数位（最多 6 位，即微美元）
+	DisplayDecimals int    // 展示保留的小数位
+	Currency        string // 货币代码（预留多币种支持）
+	// 成本累加方式：float（INCRBYFLOAT，默认，与 Node.js 直接读取兼容）或 micros（整数微美元计数，无浮点漂移，需经 Go 服务读取）
+	StorageMode string
 }
 
 type UserManagementConfig struct {
@@ -72,6 +174,21 @@ type WebConfig struct {
 	EnableCors bool
 }
 
+// WebhookConfig 事件通知（如 API Key 成本预警）的 Webhook 配置
+type WebhookConfig struct {
+	URLs    []string      // 接收通知的地址（为空表示不发送）
+	Timeout time.Duration // 单次发送超时
+}
+
+// MetricsExportConfig 指标推送（statsd / OTLP）配置
+type MetricsExportConfig struct {
+	Protocol string        // statsd 或 otlp（为空表示不推送）
+	Endpoint string        // statsd 为 host:port，otlp 为完整的 HTTP 地址（如 http://collector:4318/v1/metrics）
+	Interval time.Duration // 推送间隔
+	Timeout  time.Duration // 单次推送超时
+	Prefix   string        // 指标名前缀
+}
+
 type PricingConfig struct {
 	// 远程价格源配置
 	MirrorRepo     string        // GitHub 仓库，如 "Wei-Shaw/claude-relay-service"
@@ -85,6 +202,12 @@ type PricingConfig struct {
 	HashCheckInterval time.Duration // 哈希校验间隔（默认 10 分钟）
 	DataDir        string        // 数据目录
 	FallbackFile   string        // 回退文件路径
+	// 附加价格源（远程 URL 或本地文件），按顺序合并到主价格源之上，靠后的覆盖靠前的
+	Sources []string
+	// 价格更新灰度比例（百分比，0 表示下载后立即全量生效）
+	CanaryPercent int
+	// 由 Go 服务下载并写入 model_pricing.json（默认 false：文件由 Node 写入，Go 只读并跟随文件变更）
+	ManageFile bool
 }
 
 // Cfg 全局配置实例
@@ -133,6 +256,9 @@ func Load() (*Config, error) {
 			CommandTimeout: time.Duration(getEnvInt("REDIS_COMMAND_TIMEOUT", 5000)) * time.Millisecond,
 			MaxRetries:     getEnvInt("REDIS_MAX_RETRIES", 3),
 			EnableTLS:      getEnvBool("REDIS_ENABLE_TLS", false),
+			KeyPrefix:      getEnv("REDIS_KEY_PREFIX", ""),
+			ReplicaHost:    getEnv("REDIS_REPLICA_HOST", ""),
+			ReplicaPort:    getEnvInt("REDIS_REPLICA_PORT", getEnvInt("REDIS_PORT", 6379)),
 		},
 		Postgres: PostgresConfig{
 			Enabled:  getEnvBool("POSTGRES_ENABLED", false) || getEnv("POSTGRES_URL", "") != "",
@@ -150,18 +276,105 @@ func Load() (*Config, error) {
 			APIKeyPrefix:   getEnv("API_KEY_PREFIX", "cr_"),
 			EncryptionKey:  getEnv("ENCRYPTION_KEY", ""),
 			ClaudeCodeOnly: getEnvBool("CLAUDE_CODE_ONLY", false),
+
+			CustomClientTypes: getEnvStringMap("CUSTOM_CLIENT_TYPES"),
+
+			ValidationDiagnostics:      getEnvBool("VALIDATION_DIAGNOSTICS", false),
+			ValidationDiagnosticsToken: getEnv("VALIDATION_DIAGNOSTICS_TOKEN", ""),
+
+			AuthFailureSamplePercent: getEnvInt("AUTH_FAILURE_SAMPLE_PERCENT", 100),
+
+			ExposeAccountHeaders: getEnvBool("EXPOSE_ACCOUNT_HEADERS", false),
+			AccountHeadersToken:  getEnv("ACCOUNT_HEADERS_TOKEN", ""),
+
+			DebugCaptureEnabled: getEnvBool("DEBUG_CAPTURE_ENABLED", false),
+
+			GenericRedisCommands: getEnvList("GENERIC_REDIS_COMMANDS"),
+
+			LimitWarningPercent: getEnvInt("LIMIT_WARNING_PERCENT", 80),
+
+			LegacyExpiryErrors: getEnvBool("LEGACY_EXPIRY_ERRORS", false),
+
+			APIKeyCacheTTL: getEnvDuration("API_KEY_CACHE_TTL", 0),
+
+			MissingModelPolicy:        getEnv("MISSING_MODEL_POLICY", "passthrough"),
+			MissingModelRoutePolicies: getEnvStringMap("MISSING_MODEL_ROUTE_POLICIES"),
+			MissingModelAccountType:   getEnv("MISSING_MODEL_ACCOUNT_TYPE", "claude-official"),
 		},
 		System: SystemConfig{
 			TimezoneOffset: getEnvInt("TIMEZONE_OFFSET", 8),
 			MetricsWindow:  getEnvInt("METRICS_WINDOW", 5),
+
+			GlobalConcurrencyLimit: getEnvInt("GLOBAL_CONCURRENCY_LIMIT", 0),
+			ConcurrencyQueueMode:   getEnv("CONCURRENCY_QUEUE_MODE", "simple"),
+
+			ConcurrencyLeaseCheckInterval:  getEnvDuration("CONCURRENCY_LEASE_CHECK_INTERVAL", 30*time.Second),
+			ConcurrencyLeaseRefreshPercent: getEnvInt("CONCURRENCY_LEASE_REFRESH_PERCENT", 50),
+
+			AccountErrorWindow:    getEnvDuration("ACCOUNT_ERROR_WINDOW", 10*time.Minute),
+			AccountErrorThreshold: getEnvInt("ACCOUNT_ERROR_THRESHOLD", 0),
+
+			ExcludeTestKeysFromStats: getEnvBool("EXCLUDE_TEST_KEYS_FROM_STATS", false),
+
+			MaxFailoverAttempts: getEnvInt("MAX_FAILOVER_ATTEMPTS", 0),
+
+			ReservedAccountPercent: getEnvInt("RESERVED_ACCOUNT_PERCENT", 0),
+
+			SelectionStrategy:          getEnv("ACCOUNT_SELECTION_STRATEGY", "priority"),
+			AccountCostFactors:         getEnvFloatMap("ACCOUNT_COST_FACTORS"),
+			SelectionCostWeightPercent: getEnvInt("SELECTION_COST_WEIGHT_PERCENT", 50),
+
+			AccountWarmupDuration:       getEnvDuration("ACCOUNT_WARMUP_DURATION", 0),
+			AccountWarmupInitialPercent: getEnvInt("ACCOUNT_WARMUP_INITIAL_PERCENT", 10),
+
+			AccountSelectionTimeout: getEnvDuration("ACCOUNT_SELECTION_TIMEOUT", 0),
+			AccountQueueEnabled:     getEnvBool("ACCOUNT_QUEUE_ENABLED", false),
+			AccountQueueTimeout:     getEnvDuration("ACCOUNT_QUEUE_TIMEOUT", 5*time.Second),
+
+			KeyHealthWeights:  getEnvFloatMap("KEY_HEALTH_WEIGHTS"),
+			DailyTokenWeights: getEnvFloatMap("DAILY_TOKEN_WEIGHTS"),
+
+			StrictConcurrencyRelease:  getEnvBool("STRICT_CONCURRENCY_RELEASE", false),
+			ConcurrencyMemberMetadata: getEnvBool("CONCURRENCY_MEMBER_METADATA", false),
+
+			RedisSelfTestInterval:         getEnvDuration("REDIS_SELFTEST_INTERVAL", time.Minute),
+			RedisSelfTestFailureThreshold: getEnvInt("REDIS_SELFTEST_FAILURE_THRESHOLD", 3),
+
+			StreamIdleTimeout: getEnvDuration("STREAM_IDLE_TIMEOUT", 0),
+
+			ConcurrencySoftCap:        getEnvBool("CONCURRENCY_SOFT_CAP", false),
+			ConcurrencySoftCapTimeout: getEnvDuration("CONCURRENCY_SOFT_CAP_TIMEOUT", 2*time.Second),
+
+			StickySessionMaxCount: getEnvInt("STICKY_SESSION_MAX_COUNT", 0),
+			StickySessionCapMode:  getEnv("STICKY_SESSION_CAP_MODE", "evict"),
+
+			ConcurrencyCounterReconcileInterval: getEnvDuration("CONCURRENCY_COUNTER_RECONCILE_INTERVAL", 0),
+			InsufficientFuelPaymentRequired:     getEnvBool("INSUFFICIENT_FUEL_PAYMENT_REQUIRED", false),
 		},
 		Pricing: buildPricingConfig(),
+		Cost: CostConfig{
+			StorageDecimals: getEnvInt("COST_STORAGE_DECIMALS", 6),
+			DisplayDecimals: getEnvInt("COST_DISPLAY_DECIMALS", 2),
+			Currency:        getEnv("COST_CURRENCY", "USD"),
+			StorageMode:     getEnv("COST_STORAGE_MODE", "float"),
+		},
 		UserManagement: UserManagementConfig{
 			Enabled: getEnvBool("USER_MANAGEMENT_ENABLED", false),
 		},
 		Web: WebConfig{
 			EnableCors: getEnvBool("ENABLE_CORS", false),
 		},
+		Webhook: WebhookConfig{
+			URLs:    getEnvList("WEBHOOK_URLS"),
+			Timeout: getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		},
+		MetricsExport: MetricsExportConfig{
+			Protocol: getEnv("METRICS_EXPORT_PROTOCOL", ""),
+			Endpoint: getEnv("METRICS_EXPORT_ENDPOINT", ""),
+			Interval: getEnvDuration("METRICS_EXPORT_INTERVAL", 60*time.Second),
+			Timeout:  getEnvDuration("METRICS_EXPORT_TIMEOUT", 5*time.Second),
+			Prefix:   getEnv("METRICS_EXPORT_PREFIX", "claude_relay"),
+		},
 	}
 
 	// 验证必要配置
@@ -171,11 +384,32 @@ func Load() (*Config, error) {
 	if cfg.Security.EncryptionKey == "" {
 		return nil, fmt.Errorf("ENCRYPTION_KEY is required")
 	}
+	if err := validateMissingModelPolicies(cfg.Security); err != nil {
+		return nil, err
+	}
 
 	Cfg = cfg
 	return cfg, nil
 }
 
+// missingModelPolicies 支持的未指定模型处理方式
+var missingModelPolicies = []string{"passthrough", "strict", "default", "unknown"}
+
+// validateMissingModelPolicies 校验 MISSING_MODEL_POLICY 与 MISSING_MODEL_ROUTE_POLICIES 的取值
+func validateMissingModelPolicies(security SecurityConfig) error {
+	if !slices.Contains(missingModelPolicies, strings.ToLower(strings.TrimSpace(security.MissingModelPolicy))) {
+		return fmt.Errorf("invalid MISSING_MODEL_POLICY %q (expected one of %s)",
+			security.MissingModelPolicy, strings.Join(missingModelPolicies, ", "))
+	}
+	for route, policy := range security.MissingModelRoutePolicies {
+		if !slices.Contains(missingModelPolicies, strings.ToLower(strings.TrimSpace(policy))) {
+			return fmt.Errorf("invalid MISSING_MODEL_ROUTE_POLICIES entry %s=%q (expected one of %s)",
+				route, policy, strings.Join(missingModelPolicies, ", "))
+		}
+	}
+	return nil
+}
+
 // 辅助函数
 func getEnv(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
@@ -200,6 +434,48 @@ func getEnvBool(key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// getEnvList 读取逗号分隔的列表（忽略空项）
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvFloatMap 读取逗号分隔的 key=value 浮点映射（忽略格式错误的项）
+func getEnvFloatMap(key string) map[string]float64 {
+	items := make(map[string]float64)
+	for _, item := range getEnvList(key) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		items[strings.TrimSpace(name)] = f
+	}
+	return items
+}
+
+// getEnvStringMap 读取逗号分隔的 key=value 字符串映射（忽略格式错误的项）
+func getEnvStringMap(key string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		name, value, ok := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		items[name] = value
+	}
+	return items
+}
+
 func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
 	if val := os.Getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
@@ -242,5 +518,8 @@ func buildPricingConfig() PricingConfig {
 		HashCheckInterval: getEnvDuration("PRICE_HASH_CHECK_INTERVAL", 10*time.Minute),
 		DataDir:           getEnv("PRICE_DATA_DIR", "../data"),
 		FallbackFile:      getEnv("PRICE_FALLBACK_FILE", "../resources/model-pricing/model_prices_and_context_window.json"),
+		Sources:           getEnvList("PRICE_SOURCES"),
+		CanaryPercent:     getEnvInt("PRICE_CANARY_PERCENT", 0),
+		ManageFile:        getEnvBool("PRICE_MANAGE_FILE", false),
 	}
 }
@@ -155,6 +155,35 @@ func TestGetEnvBool(t *testing.T) {
 	}
 }
 
+func TestGetEnvList(t *testing.T) {
+	os.Setenv("TEST_LIST", " a.json , ,https://example.com/b.json,")
+	defer os.Unsetenv("TEST_LIST")
+
+	got := getEnvList("TEST_LIST")
+	if len(got) != 2 || got[0] != "a.json" || got[1] != "https://example.com/b.json" {
+		t.Errorf("getEnvList() = %v, want [a.json https://example.com/b.json]", got)
+	}
+	if got := getEnvList("TEST_LIST_NOT_EXISTS"); len(got) != 0 {
+		t.Errorf("getEnvList() = %v, want empty", got)
+	}
+}
+
+func TestGetEnvFloatMap(t *testing.T) {
+	os.Setenv("TEST_FLOAT_MAP", "bedrock=0.8, claude-console = 1.2,invalid,bad=x,bedrock:opus=0.7")
+	defer os.Unsetenv("TEST_FLOAT_MAP")
+
+	got := getEnvFloatMap("TEST_FLOAT_MAP")
+	want := map[string]float64{"bedrock": 0.8, "claude-console": 1.2, "bedrock:opus": 0.7}
+	if len(got) != len(want) {
+		t.Fatalf("getEnvFloatMap() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("getEnvFloatMap()[%s] = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
 func TestLoad(t *testing.T) {
 	// 设置必需的环境变量
 	os.Setenv("JWT_SECRET", "test_jwt_secret_32_characters_long")
@@ -209,3 +238,27 @@ func TestLoadWithoutRequiredConfig(t *testing.T) {
 		t.Error("Load() should fail without ENCRYPTION_KEY")
 	}
 }
+
+func TestLoadRejectsInvalidMissingModelPolicy(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test_jwt_secret_32_characters_long")
+	os.Setenv("ENCRYPTION_KEY", "test_encryption_key_32_chars_00")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("ENCRYPTION_KEY")
+
+	os.Setenv("MISSING_MODEL_POLICY", "strcit")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should reject an unknown MISSING_MODEL_POLICY")
+	}
+	os.Unsetenv("MISSING_MODEL_POLICY")
+
+	os.Setenv("MISSING_MODEL_ROUTE_POLICIES", "/v1/models=allow")
+	defer os.Unsetenv("MISSING_MODEL_ROUTE_POLICIES")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should reject an unknown route policy")
+	}
+
+	os.Setenv("MISSING_MODEL_ROUTE_POLICIES", "/v1/models=Passthrough")
+	if _, err := Load(); err != nil {
+		t.Errorf("Load() error = %v, want valid route policy accepted", err)
+	}
+}
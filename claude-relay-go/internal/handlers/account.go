@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
@@ -91,6 +93,46 @@ func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"accounts": accounts, "total": len(accounts)})
 }
 
+// ExportAccounts 按 SCAN 游标分页导出账户
+// 查询参数: cursor（上一页返回的 nextCursor，默认 0）、count（每页 SCAN 数量）
+func (h *AccountHandler) ExportAccounts(c *gin.Context) {
+	accountType := c.Param("type")
+	if accountType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required"})
+		return
+	}
+
+	var cursor uint64
+	if v := c.Query("cursor"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		cursor = parsed
+	}
+
+	count := int64(redis.DefaultAccountExportCount)
+	if v := c.Query("count"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid count"})
+			return
+		}
+		count = parsed
+	}
+
+	ctx := c.Request.Context()
+	page, err := h.redis.ExportAccountsPage(ctx, redis.AccountType(accountType), cursor, count)
+	if err != nil {
+		logger.Error("Failed to export accounts", zap.String("type", accountType), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
 // GetActiveAccounts 获取活跃账户
 func (h *AccountHandler) GetActiveAccounts(c *gin.Context) {
 	accountType := c.Param("type")
@@ -184,6 +226,96 @@ func (h *AccountHandler) UpdateAccountStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// BatchUpdateAccountStatus 批量更新账户状态（未指定 ids 时更新该类型全部账户）
+func (h *AccountHandler) BatchUpdateAccountStatus(c *gin.Context) {
+	accountType := c.Param("type")
+	if accountType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required"})
+		return
+	}
+
+	var req struct {
+		IDs    []string `json:"ids"`
+		Status string   `json:"status"`
+		DryRun bool     `json:"dryRun"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := h.redis.BatchUpdateAccountStatus(ctx, redis.AccountType(accountType), req.IDs, req.Status, req.DryRun)
+	if err != nil {
+		logger.Error("Failed to batch update account status", zap.String("type", accountType), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, failed := 0, 0
+	for _, result := range results {
+		if result.Updated {
+			updated++
+		}
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":  req.DryRun,
+		"results": results,
+		"total":   len(results),
+		"updated": updated,
+		"failed":  failed,
+	})
+}
+
+// UpdateAccountFields 原子更新账户部分字段（值为 null 时删除字段），不覆盖其他字段
+func (h *AccountHandler) UpdateAccountFields(c *gin.Context) {
+	accountType := c.Param("type")
+	accountID := c.Param("id")
+
+	if accountType == "" || accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type and id are required"})
+		return
+	}
+
+	var fields map[string]interface{}
+	if err := c.ShouldBindJSON(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+	if _, ok := fields["id"]; ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id cannot be updated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.redis.UpdateAccountFields(ctx, redis.AccountType(accountType), accountID, fields); err != nil {
+		switch {
+		case errors.Is(err, redis.ErrAccountNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, redis.ErrAccountUpdateConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			logger.Error("Failed to update account fields", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
 // SetAccountError 设置账户错误
 func (h *AccountHandler) SetAccountError(c *gin.Context) {
 	accountType := c.Param("type")
@@ -196,6 +328,7 @@ func (h *AccountHandler) SetAccountError(c *gin.Context) {
 
 	var req struct {
 		ErrorMsg string `json:"errorMsg"`
+		EventID  string `json:"eventId"` // 可选，同一事件重放不会重复计数
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -203,7 +336,7 @@ func (h *AccountHandler) SetAccountError(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	if err := h.redis.SetAccountError(ctx, redis.AccountType(accountType), accountID, req.ErrorMsg); err != nil {
+	if err := h.redis.SetAccountErrorWithEvent(ctx, redis.AccountType(accountType), accountID, req.ErrorMsg, req.EventID); err != nil {
 		logger.Error("Failed to set account error", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -285,6 +418,35 @@ func (h *AccountHandler) ClearAccountOverloaded(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// ClearOverloadedAccounts 批量清除过载状态
+// 默认按账户类型处理；?category=true 时将路径参数视为类别（如 claude 包含 console/bedrock/ccr）
+func (h *AccountHandler) ClearOverloadedAccounts(c *gin.Context) {
+	name := c.Param("type")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required"})
+		return
+	}
+
+	accountTypes := []redis.AccountType{redis.AccountType(name)}
+	if c.Query("category") == "true" {
+		accountTypes = redis.GetAccountTypesByCategory(name)
+		if len(accountTypes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown account category"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	cleared, err := h.redis.ClearOverloadedAccounts(ctx, accountTypes...)
+	if err != nil {
+		logger.Error("Failed to clear overloaded accounts", zap.String("type", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "cleared": cleared})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "cleared": cleared})
+}
+
 // GetAccountCost 获取账户成本
 func (h *AccountHandler) GetAccountCost(c *gin.Context) {
 	accountID := c.Param("id")
@@ -293,6 +455,11 @@ func (h *AccountHandler) GetAccountCost(c *gin.Context) {
 		return
 	}
 
+	formatter, ok := costFormatterParam(c)
+	if !ok {
+		return
+	}
+
 	ctx := c.Request.Context()
 	cost, err := h.redis.GetAccountCost(ctx, accountID)
 	if err != nil {
@@ -301,7 +468,11 @@ func (h *AccountHandler) GetAccountCost(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"cost": cost})
+	resp := gin.H{"cost": cost}
+	if formatter != nil {
+		resp["formatted"] = formatter.Format(cost)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // GetAccountDailyCost 获取账户每日成本
@@ -324,6 +495,10 @@ func (h *AccountHandler) GetAccountDailyCost(c *gin.Context) {
 	} else {
 		date = time.Now()
 	}
+	formatter, ok := costFormatterParam(c)
+	if !ok {
+		return
+	}
 
 	ctx := c.Request.Context()
 	cost, err := h.redis.GetAccountDailyCost(ctx, accountID, date)
@@ -333,7 +508,11 @@ func (h *AccountHandler) GetAccountDailyCost(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"cost": cost, "date": date.Format("2006-01-02")})
+	resp := gin.H{"cost": cost, "date": date.Format("2006-01-02")}
+	if formatter != nil {
+		resp["formatted"] = formatter.Format(cost)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // IncrementAccountCost 增加账户成本
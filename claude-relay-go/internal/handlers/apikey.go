@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
+	"github.com/catstream/claude-relay-go/internal/services/pricing"
+	"github.com/catstream/claude-relay-go/internal/services/webhook"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -12,7 +22,10 @@ import (
 
 // APIKeyHandler API Key 处理器
 type APIKeyHandler struct {
-	redis *redis.Client
+	redis    *redis.Client
+	notifier *webhook.Notifier
+	pricing  *pricing.Service
+	apiKeys  *apikey.Service
 }
 
 // NewAPIKeyHandler 创建 API Key 处理器
@@ -20,6 +33,32 @@ func NewAPIKeyHandler(redisClient *redis.Client) *APIKeyHandler {
 	return &APIKeyHandler{redis: redisClient}
 }
 
+// WithWebhookNotifier 设置成本预警等事件的 Webhook 通知器
+func (h *APIKeyHandler) WithWebhookNotifier(notifier *webhook.Notifier) *APIKeyHandler {
+	h.notifier = notifier
+	return h
+}
+
+// WithPricingService 设置进程级定价服务（成本模拟与诊断共用，避免每次请求重新加载价格）
+func (h *APIKeyHandler) WithPricingService(pricingService *pricing.Service) *APIKeyHandler {
+	h.pricing = pricingService
+	return h
+}
+
+// WithAPIKeyService 设置进程级 API Key 服务（用户默认限制等写入需清除其进程内缓存）
+func (h *APIKeyHandler) WithAPIKeyService(apiKeyService *apikey.Service) *APIKeyHandler {
+	h.apiKeys = apiKeyService
+	return h
+}
+
+// apiKeyService 获取 API Key 服务（未注入时按需创建）
+func (h *APIKeyHandler) apiKeyService() *apikey.Service {
+	if h.apiKeys != nil {
+		return h.apiKeys
+	}
+	return apikey.NewService(h.redis)
+}
+
 // GetAPIKey 获取单个 API Key
 func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
 	keyID := c.Param("id")
@@ -86,10 +125,45 @@ func (h *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
 // GetAPIKeysPaginated 分页获取 API Key
 func (h *APIKeyHandler) GetAPIKeysPaginated(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	opts := apiKeyFilterParams(c)
+	opts.Page = page
+	opts.SortBy = c.DefaultQuery("sortBy", "createdAt")
+	opts.SortOrder = c.DefaultQuery("order", "desc")
+
+	ctx := c.Request.Context()
+	result, err := h.redis.GetAPIKeysPaginated(ctx, opts)
+	if err != nil {
+		logger.Error("Failed to get paginated API keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAPIKeysByCursor 基于 SCAN 游标分页获取 API Key（不加载全部 Key，结果不排序）
+// 查询参数: cursor（上一页返回的 nextCursor，首页为空）、pageSize 及与分页接口相同的过滤参数
+func (h *APIKeyHandler) GetAPIKeysByCursor(c *gin.Context) {
+	opts := apiKeyFilterParams(c)
+
+	ctx := c.Request.Context()
+	result, err := h.redis.GetAPIKeysByCursor(ctx, opts, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidAPIKeyCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to get API keys by cursor", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// apiKeyFilterParams 解析 API Key 列表的分页大小与过滤参数
+func apiKeyFilterParams(c *gin.Context) redis.APIKeyQueryOptions {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-	sortBy := c.DefaultQuery("sortBy", "createdAt")
-	order := c.DefaultQuery("order", "desc")
-	search := c.Query("search")
 	status := c.Query("status")
 	excludeDeleted := c.Query("excludeDeleted") != "false"
 
@@ -103,25 +177,54 @@ func (h *APIKeyHandler) GetAPIKeysPaginated(c *gin.Context) {
 		isActive = &f
 	}
 
-	opts := redis.APIKeyQueryOptions{
-		Page:           page,
+	return redis.APIKeyQueryOptions{
 		PageSize:       pageSize,
-		SortBy:         sortBy,
-		SortOrder:      order,
-		Search:         search,
+		Search:         c.Query("search"),
 		IsActive:       isActive,
 		IncludeDeleted: !excludeDeleted,
+		ExcludeTest:    excludeTestKeysParam(c),
+		Permission:     c.Query("permission"),
+		AllowedClient:  c.Query("allowedClient"),
+	}
+}
+
+// DiffAPIKeys 对比两个 API Key 的配置差异（不含敏感字段）
+func (h *APIKeyHandler) DiffAPIKeys(c *gin.Context) {
+	idA, idB := c.Query("a"), c.Query("b")
+	if idA == "" || idB == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameters a and b are required"})
+		return
 	}
 
 	ctx := c.Request.Context()
-	result, err := h.redis.GetAPIKeysPaginated(ctx, opts)
+	keys := make([]*redis.APIKey, 0, 2)
+	for _, keyID := range []string{idA, idB} {
+		apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+		if err != nil {
+			logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if apiKey == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found", "keyId": keyID})
+			return
+		}
+		keys = append(keys, apiKey)
+	}
+
+	diffs, err := redis.DiffAPIKeyConfigs(keys[0], keys[1])
 	if err != nil {
-		logger.Error("Failed to get paginated API keys", zap.Error(err))
+		logger.Error("Failed to diff API keys", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{
+		"a":         idA,
+		"b":         idB,
+		"identical": len(diffs) == 0,
+		"diffs":     diffs,
+	})
 }
 
 // SetAPIKey 创建或更新 API Key
@@ -147,6 +250,44 @@ func (h *APIKeyHandler) SetAPIKey(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "id": apiKey.ID})
 }
 
+// SetAPIKeysBatch 批量创建或更新 API Key（请求体为 API Key 数组，返回每个 Key 的结果）
+func (h *APIKeyHandler) SetAPIKeysBatch(c *gin.Context) {
+	var keys []redis.APIKey
+	if err := c.ShouldBindJSON(&keys); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(keys) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keys are required"})
+		return
+	}
+	if len(keys) > redis.MaxAPIKeyBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch size exceeds %d", redis.MaxAPIKeyBatchSize)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := h.redis.SetAPIKeysBatch(ctx, keys)
+	if err != nil {
+		logger.Error("Failed to set API key batch", zap.Int("keys", len(keys)), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   failed == 0,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
 // UpdateAPIKeyFields 更新 API Key 字段
 func (h *APIKeyHandler) UpdateAPIKeyFields(c *gin.Context) {
 	keyID := c.Param("id")
@@ -163,6 +304,15 @@ func (h *APIKeyHandler) UpdateAPIKeyFields(c *gin.Context) {
 
 	ctx := c.Request.Context()
 	if err := h.redis.UpdateAPIKeyFields(ctx, keyID, updates); err != nil {
+		var fieldsErr *redis.APIKeyFieldsError
+		if errors.As(err, &fieldsErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         err.Error(),
+				"unknownFields": fieldsErr.Unknown,
+				"invalidFields": fieldsErr.Invalid,
+			})
+			return
+		}
 		logger.Error("Failed to update API key fields", zap.String("keyID", keyID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -171,6 +321,105 @@ func (h *APIKeyHandler) UpdateAPIKeyFields(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// SwapAPIKeyConfig 原子替换 API Key 配置
+func (h *APIKeyHandler) SwapAPIKeyConfig(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var cfg map[string]interface{}
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.SwapAPIKeyConfig(ctx, keyID, cfg)
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidAPIKeyConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, redis.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, redis.ErrAPIKeyConfigConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to swap API key config", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "apiKey": apiKey})
+}
+
+// SetAPIKeyFeatureFlags 开启/关闭 API Key 的功能开关（与现有开关合并）
+func (h *APIKeyHandler) SetAPIKeyFeatureFlags(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Flags map[string]bool `json:"flags" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	flags, err := h.redis.SetAPIKeyFeatureFlags(ctx, keyID, req.Flags)
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidFeatureFlag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, redis.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, redis.ErrAPIKeyConfigConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to set API key feature flags", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "featureFlags": flags})
+}
+
+// RollbackAPIKeyConfig 回滚 API Key 配置到最近一次替换前的状态
+func (h *APIKeyHandler) RollbackAPIKeyConfig(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.RollbackAPIKeyConfig(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, redis.ErrAPIKeyConfigSnapshotNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to rollback API key config", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "apiKey": apiKey})
+}
+
 // DeleteAPIKey 删除 API Key (软删除)
 func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
 	keyID := c.Param("id")
@@ -189,6 +438,31 @@ func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// RestoreAPIKey 恢复软删除的 API Key（不存在返回 404，未删除或哈希被占用返回 409）
+func (h *APIKeyHandler) RestoreAPIKey(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.redis.RestoreAPIKey(ctx, keyID); err != nil {
+		switch {
+		case errors.Is(err, redis.ErrAPIKeyNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, redis.ErrAPIKeyNotDeleted), errors.Is(err, redis.ErrAPIKeyHashConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			logger.Error("Failed to restore API key", zap.String("keyID", keyID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
 // HardDeleteAPIKey 硬删除 API Key
 func (h *APIKeyHandler) HardDeleteAPIKey(c *gin.Context) {
 	keyID := c.Param("id")
@@ -209,8 +483,10 @@ func (h *APIKeyHandler) HardDeleteAPIKey(c *gin.Context) {
 
 // GetAPIKeyStats 获取 API Key 统计
 func (h *APIKeyHandler) GetAPIKeyStats(c *gin.Context) {
+	excludeTest := excludeTestKeysParam(c)
+
 	ctx := c.Request.Context()
-	stats, err := h.redis.GetAPIKeyStats(ctx)
+	stats, err := h.redis.GetAPIKeyStats(ctx, excludeTest)
 	if err != nil {
 		logger.Error("Failed to get API key stats", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -220,6 +496,28 @@ func (h *APIKeyHandler) GetAPIKeyStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// costFormatterParam 解析可选的 locale 参数，未指定时返回 nil；locale 无效时写入 400 并返回 false
+func costFormatterParam(c *gin.Context) (*redis.CostFormatter, bool) {
+	locale := c.Query("locale")
+	if locale == "" {
+		return nil, true
+	}
+	formatter, err := redis.NewCostFormatter(locale)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return formatter, true
+}
+
+// excludeTestKeysParam 解析 excludeTest 参数（未指定时使用全局配置）
+func excludeTestKeysParam(c *gin.Context) bool {
+	if value := c.Query("excludeTest"); value != "" {
+		return value == "true"
+	}
+	return redis.ExcludeTestKeysByDefault()
+}
+
 // IncrementDailyCost 增加每日成本
 func (h *APIKeyHandler) IncrementDailyCost(c *gin.Context) {
 	keyID := c.Param("id")
@@ -229,7 +527,9 @@ func (h *APIKeyHandler) IncrementDailyCost(c *gin.Context) {
 	}
 
 	var req struct {
-		Amount float64 `json:"amount"`
+		Amount       float64  `json:"amount"`
+		Model        string   `json:"model,omitempty"`        // 提供时同时记入对账桶
+		ReportedCost *float64 `json:"reportedCost,omitempty"` // 上游报告的实际成本
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -243,69 +543,128 @@ func (h *APIKeyHandler) IncrementDailyCost(c *gin.Context) {
 		return
 	}
 
+	if req.Model != "" {
+		if err := h.redis.RecordCostReconciliation(ctx, keyID, req.Model, req.Amount, req.ReportedCost); err != nil {
+			logger.Warn("Failed to record cost reconciliation", zap.String("keyID", keyID), zap.Error(err))
+		}
+	}
+
+	h.notifyCostAlerts(ctx, keyID)
+
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
-// GetDailyCost 获取每日成本
-func (h *APIKeyHandler) GetDailyCost(c *gin.Context) {
+// notifyCostAlerts 成本新跨越预警阈值时发送 Webhook 通知（失败不影响成本记录）
+func (h *APIKeyHandler) notifyCostAlerts(ctx context.Context, keyID string) {
+	if !h.notifier.Enabled() {
+		return
+	}
+
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil || apiKey == nil || len(apiKey.CostAlertThresholds) == 0 {
+		return
+	}
+
+	alerts, err := h.redis.CheckCostAlerts(ctx, apiKey)
+	if err != nil {
+		logger.Warn("Failed to check cost alerts", zap.String("keyID", keyID), zap.Error(err))
+	}
+	for _, alert := range alerts {
+		h.notifier.Notify(webhook.Event{
+			Type: webhook.EventCostAlert,
+			Data: map[string]interface{}{
+				"keyId":      alert.KeyID,
+				"keyName":    alert.KeyName,
+				"threshold":  alert.Threshold,
+				"dailyCost":  alert.DailyCost,
+				"dailyLimit": alert.DailyLimit,
+				"date":       alert.Date,
+				"currency":   redis.GetCostCurrency(),
+			},
+		})
+	}
+}
+
+// GetCostReconciliation 获取计算成本与上游报告成本的对账结果
+func (h *APIKeyHandler) GetCostReconciliation(c *gin.Context) {
 	keyID := c.Param("id")
 	if keyID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
 		return
 	}
 
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+
 	ctx := c.Request.Context()
-	cost, err := h.redis.GetDailyCost(ctx, keyID)
+	result, err := h.redis.GetCostReconciliation(ctx, keyID, days)
 	if err != nil {
-		logger.Error("Failed to get daily cost", zap.String("keyID", keyID), zap.Error(err))
+		logger.Error("Failed to get cost reconciliation", zap.String("keyID", keyID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"cost": cost})
+	result.Currency = redis.GetCostCurrency()
+	c.JSON(http.StatusOK, result)
 }
 
-// GetCostStats 获取成本统计
-func (h *APIKeyHandler) GetCostStats(c *gin.Context) {
+// GetCostByModel 获取最近若干天按模型汇总的成本
+func (h *APIKeyHandler) GetCostByModel(c *gin.Context) {
 	keyID := c.Param("id")
 	if keyID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
 		return
 	}
 
-	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > redis.MaxCostReconciliationDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", redis.MaxCostReconciliationDays)})
+		return
+	}
+
+	if h.pricing == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "pricing service not available"})
+		return
+	}
 
 	ctx := c.Request.Context()
-	stats, err := h.redis.GetCostStats(ctx, keyID, days)
+	result, err := h.redis.GetCostByModel(ctx, keyID, days, h.modelUsageCost)
 	if err != nil {
-		logger.Error("Failed to get cost stats", zap.String("keyID", keyID), zap.Error(err))
+		logger.Error("Failed to get cost by model", zap.String("keyID", keyID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	result.Currency = redis.GetCostCurrency()
+	c.JSON(http.StatusOK, result)
 }
 
-// IncrementTokenUsage 增加 Token 使用量
-func (h *APIKeyHandler) IncrementTokenUsage(c *gin.Context) {
-	var params redis.TokenUsageParams
-	if err := c.ShouldBindJSON(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// MigrateCostToMicros 以浮点成本初始化微美元伴随计数（仅 micros 方式下可用，浮点值保留）
+func (h *APIKeyHandler) MigrateCostToMicros(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+	if redis.GetCostStorageMode() != redis.CostStorageMicros {
+		c.JSON(http.StatusConflict, gin.H{"error": "cost storage mode is not micros"})
 		return
 	}
 
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+
 	ctx := c.Request.Context()
-	if err := h.redis.IncrementTokenUsage(ctx, params); err != nil {
-		logger.Error("Failed to increment token usage", zap.Error(err))
+	result, err := h.redis.MigrateCostToMicros(ctx, keyID, days)
+	if err != nil {
+		logger.Error("Failed to migrate cost to micros", zap.String("keyID", keyID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, result)
 }
 
-// GetUsageStats 获取使用统计
-func (h *APIKeyHandler) GetUsageStats(c *gin.Context) {
+// GetDailyTokens 获取今日 Token 计数与限额
+func (h *APIKeyHandler) GetDailyTokens(c *gin.Context) {
 	keyID := c.Param("id")
 	if keyID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
@@ -313,12 +672,1069 @@ func (h *APIKeyHandler) GetUsageStats(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	stats, err := h.redis.GetUsageStats(ctx, keyID)
+	status, err := h.redis.GetDailyTokenStatus(ctx, keyID)
 	if err != nil {
-		logger.Error("Failed to get usage stats", zap.String("keyID", keyID), zap.Error(err))
+		logger.Error("Failed to get daily tokens", zap.String("keyID", keyID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
 
-	c.JSON(http.StatusOK, stats)
+	c.JSON(http.StatusOK, status)
+}
+
+// GetKeyHealth 获取 API Key 综合健康分（0-100）
+func (h *APIKeyHandler) GetKeyHealth(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	health, err := h.redis.GetKeyHealthScore(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get key health", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if health == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
+
+// ResetDailyTokens 将 daily-tokens 状态接口的今日计数清零
+// 影响状态接口与认证中间件的每日 Token 限额判断：API Key 的 usedToday 字段、usage:daily 计数与 Node 侧的限额判断均不变
+func (h *APIKeyHandler) ResetDailyTokens(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.redis.ResetDailyTokens(ctx, keyID); err != nil {
+		logger.Error("Failed to reset daily tokens", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.redis.GetDailyTokenStatus(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get daily tokens", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetDailyCost 获取每日成本
+func (h *APIKeyHandler) GetDailyCost(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	formatter, ok := costFormatterParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	cost, err := h.redis.GetDailyCost(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get daily cost", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := gin.H{
+		"cost":        redis.RoundCostForStorage(cost),
+		"displayCost": redis.RoundCostForDisplay(cost),
+		"currency":    redis.GetCostCurrency(),
+	}
+	if formatter != nil {
+		resp["formatted"] = formatter.Format(cost)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetCostProjection 按当前消耗速度预测周期末成本
+func (h *APIKeyHandler) GetCostProjection(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	period := c.DefaultQuery("period", redis.CostPeriodMonthly)
+	if period != redis.CostPeriodDaily && period != redis.CostPeriodMonthly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be daily or monthly"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	projection, err := h.redis.ProjectPeriodCost(ctx, keyID, period)
+	if err != nil {
+		logger.Error("Failed to project cost", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if projection == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projection)
+}
+
+// IncrementTagCost 记录成本归因标签的成本与 Token 用量（Key 需启用 allowCostTags）
+func (h *APIKeyHandler) IncrementTagCost(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Tag string `json:"tag" binding:"required"`
+		redis.CostTagUsage
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if !apiKey.AllowCostTags {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cost tags are not enabled for this API key"})
+		return
+	}
+
+	tag, err := h.redis.IncrementTagCost(ctx, keyID, req.Tag, req.CostTagUsage)
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidCostTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to increment tag cost", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "tag": tag})
+}
+
+// GetCostByTag 获取指定日期按标签归因的成本
+func (h *APIKeyHandler) GetCostByTag(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	date := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	ctx := c.Request.Context()
+	tags, err := h.redis.GetCostByTag(ctx, keyID, date)
+	if err != nil {
+		logger.Error("Failed to get cost by tag", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":     date.Format("2006-01-02"),
+		"tags":     tags,
+		"currency": redis.GetCostCurrency(),
+	})
+}
+
+// GetDebugCaptures 获取 API Key 的调试采样与剩余采样次数
+func (h *APIKeyHandler) GetDebugCaptures(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	captures, err := h.redis.GetDebugCaptures(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get debug captures", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"remaining": apiKey.DebugCaptureCount,
+		"captures":  captures,
+	})
+}
+
+// GetAPIKeyTimeline 获取 API Key 的生命周期时间线
+func (h *APIKeyHandler) GetAPIKeyTimeline(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	timeline, err := h.redis.GetAPIKeyTimeline(c.Request.Context(), keyID)
+	if err != nil {
+		logger.Error("Failed to get API key timeline", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if timeline == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// SetDebugCaptureCount 开启（或关闭）API Key 的调试采样，count 为接下来采样的请求数
+func (h *APIKeyHandler) SetDebugCaptureCount(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Count int `json:"count"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Count < 0 || req.Count > redis.MaxDebugCaptureCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 0 and %d", redis.MaxDebugCaptureCount)})
+		return
+	}
+	if req.Count > 0 && !redis.DebugCaptureEnabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "debug capture is disabled (DEBUG_CAPTURE_ENABLED)"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	if err := h.redis.SetDebugCaptureCount(ctx, keyID, req.Count); err != nil {
+		logger.Error("Failed to set debug capture count", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remaining": req.Count})
+}
+
+// BoostConcurrency 临时提升 API Key 的并发上限，到期后自动恢复静态上限
+func (h *APIKeyHandler) BoostConcurrency(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Limit      int `json:"limit" binding:"required"`
+		TTLSeconds int `json:"ttlSeconds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if req.TTLSeconds <= 0 || ttl > redis.MaxConcurrencyBoostTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ttlSeconds must be between 1 and %d", int(redis.MaxConcurrencyBoostTTL.Seconds()))})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if apiKey.ConcurrentLimit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key has no concurrency limit to boost"})
+		return
+	}
+	if req.Limit <= apiKey.ConcurrentLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be greater than the current concurrency limit (%d)", apiKey.ConcurrentLimit)})
+		return
+	}
+
+	boost, err := h.redis.SetConcurrencyBoost(ctx, keyID, req.Limit, ttl)
+	if err != nil {
+		logger.Error("Failed to set concurrency boost", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"limit":       boost.Limit,
+		"staticLimit": apiKey.ConcurrentLimit,
+		"expiresAt":   boost.ExpiresAt,
+	})
+}
+
+// PurgeModelUsage 删除指定模型在所有 Key 与账户下的使用统计（dryRun=true 时只返回将被删除的键）
+func (h *APIKeyHandler) PurgeModelUsage(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	ctx := c.Request.Context()
+	if dryRun {
+		keys, err := h.redis.FindModelUsageKeys(ctx, model)
+		if err != nil {
+			logger.Error("Failed to find model usage keys", zap.String("model", model), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		c.JSON(http.StatusOK, gin.H{"model": model, "dryRun": true, "keys": keys, "count": len(keys)})
+		return
+	}
+
+	deleted, err := h.redis.PurgeModelUsage(ctx, model)
+	if err != nil {
+		logger.Error("Failed to purge model usage", zap.String("model", model), zap.Int("deleted", deleted), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": deleted})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"model": model, "dryRun": false, "deleted": deleted})
+}
+
+// GetUserLimitDefaults 获取用户级默认限制
+func (h *APIKeyHandler) GetUserLimitDefaults(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userID is required"})
+		return
+	}
+
+	defaults, err := h.redis.GetUserLimitDefaults(c.Request.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user limit defaults", zap.String("userID", userID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if defaults == nil {
+		defaults = &redis.UserLimitDefaults{}
+	}
+
+	c.JSON(http.StatusOK, defaults)
+}
+
+// SetUserLimitDefaults 覆盖用户级默认限制（全部为 0 时清除）
+func (h *APIKeyHandler) SetUserLimitDefaults(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userID is required"})
+		return
+	}
+
+	var req redis.UserLimitDefaults
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ConcurrentLimit < 0 || req.RateLimitPerMin < 0 || req.RateLimitPerHour < 0 || req.RateLimitWindow < 0 ||
+		req.RateLimitCost < 0 || req.DailyCostLimit < 0 || req.TotalCostLimit < 0 || req.WeeklyOpusCostLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limits must not be negative"})
+		return
+	}
+
+	if err := h.apiKeyService().SetUserLimitDefaults(c.Request.Context(), userID, &req); err != nil {
+		logger.Error("Failed to set user limit defaults", zap.String("userID", userID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// ClearDebugCaptures 清空 API Key 的调试采样
+func (h *APIKeyHandler) ClearDebugCaptures(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	if err := h.redis.ClearDebugCaptures(c.Request.Context(), keyID); err != nil {
+		logger.Error("Failed to clear debug captures", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cleared": true})
+}
+
+// SimulateLimits 只读模拟一次假设请求的限制检查结果（不计数、不占用槽位）
+func (h *APIKeyHandler) SimulateLimits(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Model string `json:"model"`
+		pricing.UsageData
+		EstimatedCost *float64 `json:"estimatedCost"` // 显式指定预计成本时不再按模型价格计算
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.InputTokens < 0 || req.OutputTokens < 0 || req.CacheCreationTokens < 0 || req.CacheReadTokens < 0 ||
+		(req.EstimatedCost != nil && *req.EstimatedCost < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token counts and estimatedCost must be non-negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	estimatedCost := h.estimateRequestCost(req.Model, req.UsageData, req.EstimatedCost)
+	result, err := apikey.NewService(h.redis).SimulateLimits(ctx, apiKey, req.Model, estimatedCost)
+	if err != nil {
+		logger.Error("Failed to simulate limits", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// modelUsageCost 按模型当前价格计算一段累计用量的成本
+func (h *APIKeyHandler) modelUsageCost(model string, usage *redis.UsageStats) float64 {
+	return h.pricing.CalculateTotalCost(model, pricing.UsageData{
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheCreationTokens: usage.CacheCreateTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+	})
+}
+
+// estimateRequestCost 估算假设请求的成本（显式指定时直接使用，否则按模型价格计算）
+func (h *APIKeyHandler) estimateRequestCost(model string, usage pricing.UsageData, explicit *float64) float64 {
+	if explicit != nil {
+		return *explicit
+	}
+	if h.pricing == nil {
+		return 0
+	}
+	return h.pricing.CalculateTotalCost(model, usage)
+}
+
+// DiagnoseLimits 只读重放一次假设请求的全部检查，返回逐项通过/拒绝原因及当前值与阈值
+func (h *APIKeyHandler) DiagnoseLimits(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	var req struct {
+		Model      string `json:"model"`
+		ClientType string `json:"clientType"`
+		Permission string `json:"permission"`
+		pricing.UsageData
+		EstimatedCost *float64 `json:"estimatedCost"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.InputTokens < 0 || req.OutputTokens < 0 || req.CacheCreationTokens < 0 || req.CacheReadTokens < 0 ||
+		(req.EstimatedCost != nil && *req.EstimatedCost < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token counts and estimatedCost must be non-negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	report, err := apikey.NewService(h.redis).DiagnoseLimits(ctx, apiKey, apikey.DiagnoseRequest{
+		Model:              req.Model,
+		ClientType:         req.ClientType,
+		RequiredPermission: req.Permission,
+		EstimatedCost:      h.estimateRequestCost(req.Model, req.UsageData, req.EstimatedCost),
+	})
+	if err != nil {
+		logger.Error("Failed to diagnose limits", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RebuildHashMap 扫描所有 API Key 重建哈希映射（映射损坏或与 Key 数据不一致时使用）
+func (h *APIKeyHandler) RebuildHashMap(c *gin.Context) {
+	result, err := h.redis.RebuildAPIKeyHashMap(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to rebuild API key hash map", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportAPIKeys 流式导出全部 API Key 与哈希映射（逐行 JSON，可用于 ImportAPIKeys 恢复）
+// 查询参数: includeSecrets（是否包含 Key 哈希与哈希映射，默认 false）
+func (h *APIKeyHandler) ExportAPIKeys(c *gin.Context) {
+	includeSecrets := false
+	if v := c.Query("includeSecrets"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid includeSecrets"})
+			return
+		}
+		includeSecrets = parsed
+	}
+
+	started := false
+	encoder := json.NewEncoder(c.Writer)
+	err := h.redis.ExportAPIKeys(c.Request.Context(), includeSecrets, func(record *redis.APIKeyBackupRecord) error {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Content-Disposition", `attachment; filename="apikeys-backup.ndjson"`)
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		if record.Type != redis.APIKeyBackupRecordKey {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to export API keys", zap.Bool("includeSecrets", includeSecrets), zap.Error(err))
+		// 已开始输出时无法再返回错误状态，文档缺少 end 记录，恢复时会被拒绝
+		if !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Writer.Flush()
+}
+
+// ImportAPIKeys 从 ExportAPIKeys 导出的文档恢复 API Key 并重建哈希映射
+// 查询参数: mode（merge 合并，默认；replace 覆盖并删除备份外的 Key，要求备份包含密钥）
+func (h *APIKeyHandler) ImportAPIKeys(c *gin.Context) {
+	decoder := json.NewDecoder(c.Request.Body)
+	next := func() (*redis.APIKeyBackupRecord, error) {
+		var record redis.APIKeyBackupRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		return &record, nil
+	}
+
+	mode := c.DefaultQuery("mode", redis.APIKeyImportModeMerge)
+	result, err := h.redis.ImportAPIKeys(c.Request.Context(), mode, next)
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidAPIKeyBackup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to import API keys", zap.String("mode", mode), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetTagUsage 汇总带有指定标签的所有 Key 的用量与成本
+func (h *APIKeyHandler) GetTagUsage(c *gin.Context) {
+	tag := c.Param("tag")
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag is required"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > redis.MaxTagUsageDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", redis.MaxTagUsageDays)})
+		return
+	}
+
+	rollup, err := h.redis.GetTagUsageRollup(c.Request.Context(), tag, days)
+	if err != nil {
+		logger.Error("Failed to get tag usage", zap.String("tag", tag), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tag":      rollup.Tag,
+		"days":     rollup.Days,
+		"total":    rollup.Total,
+		"keys":     rollup.Keys,
+		"keyCount": len(rollup.Keys),
+		"currency": redis.GetCostCurrency(),
+	})
+}
+
+// GetTopCostKeys 获取最近 days 天成本最高的 Key（账单排查）
+func (h *APIKeyHandler) GetTopCostKeys(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > redis.MaxTopCostDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", redis.MaxTopCostDays)})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(redis.DefaultTopCostLimit)))
+	if err != nil || limit <= 0 || limit > redis.MaxTopCostLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", redis.MaxTopCostLimit)})
+		return
+	}
+
+	top, err := h.redis.GetTopCostKeys(c.Request.Context(), days, limit)
+	if err != nil {
+		logger.Error("Failed to get top cost keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":        top.Days,
+		"limit":       top.Limit,
+		"keysScanned": top.KeysScanned,
+		"truncated":   top.Truncated,
+		"keys":        top.Keys,
+		"currency":    redis.GetCostCurrency(),
+	})
+}
+
+// GetCostStats 获取成本统计
+func (h *APIKeyHandler) GetCostStats(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	formatter, ok := costFormatterParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.redis.GetCostStats(ctx, keyID, days)
+	if err != nil {
+		logger.Error("Failed to get cost stats", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats.Currency = redis.GetCostCurrency()
+	if formatter != nil {
+		stats.Formatted = formatter.FormatStats(stats)
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetCostHistory 获取最近 N 天的每日成本明细（只返回有记录的日期，按日期倒序）
+// 查询参数: days（默认 30，超过上限时按上限处理）
+func (h *APIKeyHandler) GetCostHistory(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(redis.DefaultCostHistoryDays)))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return
+	}
+	days = min(days, redis.MaxCostHistoryDays)
+
+	ctx := c.Request.Context()
+	records, err := h.redis.GetCostHistory(ctx, keyID, days)
+	if err != nil {
+		logger.Error("Failed to get cost history", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if records == nil {
+		records = []redis.DailyCostRecord{}
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// IncrementTokenUsage 增加 Token 使用量
+func (h *APIKeyHandler) IncrementTokenUsage(c *gin.Context) {
+	var params redis.TokenUsageParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.redis.IncrementTokenUsage(ctx, params); err != nil {
+		logger.Error("Failed to increment token usage", zap.Error(err))
+		// 部分命令已生效时告知调用方，整体重试会重复计数
+		var pipeErr *redis.PartialPipelineError
+		if errors.As(err, &pipeErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":     err.Error(),
+				"partial":   pipeErr.Partial(),
+				"succeeded": pipeErr.Succeeded(),
+				"failures":  pipeErr.Failures,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// IncrementTokenUsageBatch 批量增加 Token 使用量（请求体为使用量数组，返回每条的处理结果）
+func (h *APIKeyHandler) IncrementTokenUsageBatch(c *gin.Context) {
+	var entries []redis.UsageBatchEntry
+	if err := c.ShouldBindJSON(&entries); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entries are required"})
+		return
+	}
+	if len(entries) > redis.MaxUsageBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch size exceeds %d", redis.MaxUsageBatchSize)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := h.redis.IncrementTokenUsageBatch(ctx, entries)
+	if err != nil {
+		logger.Error("Failed to increment batched token usage", zap.Int("entries", len(entries)), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   failed == 0,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
+// GetUsageStats 获取使用统计
+func (h *APIKeyHandler) GetUsageStats(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.redis.GetUsageStats(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get usage stats", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetHourlyUsage 获取 API Key 指定日期按小时拆分的使用统计
+func (h *APIKeyHandler) GetHourlyUsage(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	date := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		// 按统计时区解析，避免负偏移时 UTC 零点落到前一天
+		parsed, err := time.ParseInLocation("2006-01-02", dateStr, redis.UsageLocation())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	hours, err := h.redis.GetHourlyUsage(c.Request.Context(), keyID, date)
+	if err != nil {
+		logger.Error("Failed to get hourly usage", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":  date.In(redis.UsageLocation()).Format("2006-01-02"),
+		"hours": hours,
+	})
+}
+
+// GetUsageRange 汇总 API Key 在日期区间内（含两端，配置时区）的用量与成本
+// 查询参数: from、to（YYYY-MM-DD），均未提供时返回本周（周一至今天）
+func (h *APIKeyHandler) GetUsageRange(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" && toStr == "" {
+		now := time.Now()
+		stats, err := h.redis.GetWeeklyUsage(ctx, keyID, now)
+		if err != nil {
+			logger.Error("Failed to get weekly usage", zap.String("keyID", keyID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"from":     redis.WeekStartDate(now),
+			"to":       now.In(redis.UsageLocation()).Format("2006-01-02"),
+			"usage":    stats,
+			"currency": redis.GetCostCurrency(),
+		})
+		return
+	}
+	if fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required together"})
+		return
+	}
+
+	// 日期按统计时区解析，确保与每日统计桶对齐
+	from, err := time.ParseInLocation("2006-01-02", fromStr, redis.UsageLocation())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	to, err := time.ParseInLocation("2006-01-02", toStr, redis.UsageLocation())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+		return
+	}
+
+	stats, err := h.redis.GetUsageStatsRange(ctx, keyID, from, to)
+	if err != nil {
+		if errors.Is(err, redis.ErrInvalidUsageRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to get usage range", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     fromStr,
+		"to":       toStr,
+		"usage":    stats,
+		"currency": redis.GetCostCurrency(),
+	})
+}
+
+// usageExportCSVHeader 用量导出 CSV 表头
+var usageExportCSVHeader = []string{"date", "inputTokens", "outputTokens", "cacheCreateTokens", "cacheReadTokens", "requests", "totalCost"}
+
+// unsafeFilenameChars 导出文件名中需替换的字符
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ExportUsage 导出 API Key 最近 N 天的每日用量与成本
+// 查询参数: format（csv 默认，流式输出；json 返回相同的行）、days（默认 30）
+func (h *APIKeyHandler) ExportUsage(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(redis.DefaultCostHistoryDays)))
+	if err != nil || days <= 0 || days > redis.MaxCostHistoryDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", redis.MaxCostHistoryDays)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	from, to := redis.UsageExportDates(time.Now(), days)
+
+	if format == "json" {
+		rows := make([]*redis.UsageExportRow, 0, days)
+		if err := h.redis.ExportDailyUsage(ctx, keyID, days, func(row *redis.UsageExportRow) error {
+			rows = append(rows, row)
+			return nil
+		}); err != nil {
+			logger.Error("Failed to export usage", zap.String("keyID", keyID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"keyId": keyID, "from": from, "to": to, "rows": rows})
+		return
+	}
+
+	filename := fmt.Sprintf("usage-%s-%s-%s.csv", unsafeFilenameChars.ReplaceAllString(keyID, "_"), from, to)
+	writer := csv.NewWriter(c.Writer)
+	started := false
+	err = h.redis.ExportDailyUsage(ctx, keyID, days, func(row *redis.UsageExportRow) error {
+		if !started {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+			c.Status(http.StatusOK)
+			if err := writer.Write(usageExportCSVHeader); err != nil {
+				return err
+			}
+			started = true
+		}
+		if err := writer.Write([]string{
+			row.Date,
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheCreateTokens, 10),
+			strconv.FormatInt(row.CacheReadTokens, 10),
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatFloat(row.TotalCost, 'f', -1, 64),
+		}); err != nil {
+			return err
+		}
+		writer.Flush()
+		c.Writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		logger.Error("Failed to export usage", zap.String("keyID", keyID), zap.Error(err))
+		// 已开始输出时无法再返回错误状态
+		if !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+// GetRecentRates 获取 API Key 近期窗口内的 RPM/TPM
+func (h *APIKeyHandler) GetRecentRates(c *gin.Context) {
+	keyID := c.Param("id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	window, err := strconv.Atoi(c.DefaultQuery("window", strconv.Itoa(redis.DefaultRecentRateWindowMinutes)))
+	if err != nil || window <= 0 || window > redis.MaxRecentRateWindowMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("window must be between 1 and %d minutes", redis.MaxRecentRateWindowMinutes)})
+		return
+	}
+
+	rates, err := h.redis.GetRecentRates(c.Request.Context(), keyID, window)
+	if err != nil {
+		logger.Error("Failed to get recent rates", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
 }
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AuthHandler 认证统计处理器
+type AuthHandler struct {
+	redis *redis.Client
+}
+
+// NewAuthHandler 创建认证统计处理器
+func NewAuthHandler(redisClient *redis.Client) *AuthHandler {
+	return &AuthHandler{redis: redisClient}
+}
+
+// GetAuthFailureStats 获取最近窗口内按原因汇总的认证失败次数
+// 查询参数: window（Go duration 格式，如 15m、1h，默认 1h，最大 24h）
+func (h *AuthHandler) GetAuthFailureStats(c *gin.Context) {
+	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window < time.Minute || window > redis.MaxAuthFailureWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration between 1m and 24h"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.redis.GetAuthFailureStats(ctx, window)
+	if err != nil {
+		logger.Error("Failed to get auth failure stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/clients"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientHandler 客户端识别处理器
+type ClientHandler struct{}
+
+// NewClientHandler 创建客户端识别处理器
+func NewClientHandler() *ClientHandler {
+	return &ClientHandler{}
+}
+
+// ParseClientType 返回 User-Agent 的客户端识别结果（未指定 userAgent 时使用请求自身的 User-Agent）
+func (h *ClientHandler) ParseClientType(c *gin.Context) {
+	userAgent := c.Query("userAgent")
+	if userAgent == "" {
+		userAgent = c.GetHeader("User-Agent")
+	}
+
+	c.JSON(http.StatusOK, clients.GetClientInfo(userAgent))
+}
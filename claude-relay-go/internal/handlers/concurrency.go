@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -26,6 +29,9 @@ func (h *ConcurrencyHandler) IncrConcurrency(c *gin.Context) {
 		APIKeyID     string `json:"apiKeyId"`
 		RequestID    string `json:"requestId"`
 		LeaseSeconds int    `json:"leaseSeconds"`
+		// 可选元数据（启用 CONCURRENCY_MEMBER_METADATA 时写入 concurrency_meta 旁路哈希）
+		Model      string `json:"model"`
+		ClientType string `json:"clientType"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -36,7 +42,11 @@ func (h *ConcurrencyHandler) IncrConcurrency(c *gin.Context) {
 		req.LeaseSeconds = 600 // 默认 10 分钟
 	}
 
-	ctx := c.Request.Context()
+	ctx := redis.WithConcurrencyMeta(c.Request.Context(), redis.ConcurrencyMemberMeta{
+		Model:      req.Model,
+		ClientType: req.ClientType,
+		StartedAt:  time.Now(),
+	})
 	count, err := h.redis.IncrConcurrency(ctx, req.APIKeyID, req.RequestID, req.LeaseSeconds)
 	if err != nil {
 		logger.Error("Failed to incr concurrency", zap.Error(err))
@@ -69,6 +79,90 @@ func (h *ConcurrencyHandler) DecrConcurrency(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"count": count})
 }
 
+// AcquireWithIntent 原子地占用并发槽位并记录在途请求的预估成本（同时受全局并发上限约束）
+func (h *ConcurrencyHandler) AcquireWithIntent(c *gin.Context) {
+	var req struct {
+		APIKeyID      string  `json:"apiKeyId" binding:"required"`
+		RequestID     string  `json:"requestId" binding:"required"`
+		LeaseSeconds  int     `json:"leaseSeconds"`
+		Limit         int     `json:"limit"`
+		EstimatedCost float64 `json:"estimatedCost"`
+		Model         string  `json:"model"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EstimatedCost < 0 || req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "estimatedCost and limit must not be negative"})
+		return
+	}
+
+	if req.LeaseSeconds == 0 {
+		req.LeaseSeconds = 600 // 默认 10 分钟
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.redis.AcquireWithIntent(ctx, req.APIKeyID, req.RequestID, req.LeaseSeconds, req.Limit, int64(apikey.GlobalConcurrencyLimit()), req.EstimatedCost, req.Model)
+	if err != nil {
+		logger.Error("Failed to acquire concurrency with intent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !result.Acquired {
+		c.JSON(http.StatusTooManyRequests, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CompleteIntent 结算意图：释放并发槽位并记录实际成本
+func (h *ConcurrencyHandler) CompleteIntent(c *gin.Context) {
+	var req struct {
+		APIKeyID   string  `json:"apiKeyId" binding:"required"`
+		RequestID  string  `json:"requestId" binding:"required"`
+		ActualCost float64 `json:"actualCost"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ActualCost < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "actualCost must not be negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	settlement, err := h.redis.CompleteIntent(ctx, req.APIKeyID, req.RequestID, req.ActualCost)
+	if err != nil {
+		logger.Error("Failed to complete concurrency intent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+// GetPendingIntents 获取 API Key 的在途请求及预估成本
+func (h *ConcurrencyHandler) GetPendingIntents(c *gin.Context) {
+	apiKeyID := c.Param("apiKeyId")
+	if apiKeyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apiKeyId is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	pending, err := h.redis.GetPendingIntents(ctx, apiKeyID)
+	if err != nil {
+		logger.Error("Failed to get pending intents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pending)
+}
+
 // GetConcurrency 获取并发计数
 func (h *ConcurrencyHandler) GetConcurrency(c *gin.Context) {
 	apiKeyID := c.Param("apiKeyId")
@@ -120,6 +214,24 @@ func (h *ConcurrencyHandler) GetAllConcurrencyStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"statuses": statuses, "total": len(statuses)})
 }
 
+// GetUserConcurrency 获取用户全部 API Key 的并发与排队汇总
+func (h *ConcurrencyHandler) GetUserConcurrency(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId is required"})
+		return
+	}
+
+	result, err := h.redis.GetUserConcurrency(c.Request.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user concurrency", zap.String("userID", userID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // RefreshConcurrencyLease 刷新并发租约
 func (h *ConcurrencyHandler) RefreshConcurrencyLease(c *gin.Context) {
 	var req struct {
@@ -147,6 +259,37 @@ func (h *ConcurrencyHandler) RefreshConcurrencyLease(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"refreshed": refreshed})
 }
 
+// RefreshConcurrencyLeasesBatch 批量刷新并发租约（Redis 恢复后重新声明实例持有的租约）
+func (h *ConcurrencyHandler) RefreshConcurrencyLeasesBatch(c *gin.Context) {
+	var req struct {
+		Items []redis.LeaseRefreshItem `json:"items" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Items) > redis.MaxLeaseRefreshBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d items per batch", redis.MaxLeaseRefreshBatch)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := h.redis.RefreshLeasesBatch(ctx, req.Items)
+	if err != nil {
+		logger.Error("Failed to refresh concurrency leases in batch", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshed := 0
+	for _, result := range results {
+		if result.Refreshed {
+			refreshed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results, "refreshed": refreshed, "total": len(results)})
+}
+
 // CleanupExpiredConcurrency 清理过期并发
 func (h *ConcurrencyHandler) CleanupExpiredConcurrency(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -376,6 +519,89 @@ func (h *ConcurrencyHandler) GetQueueStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetWaitTimeSamples 获取排队等待时间原始样本（最新在前）
+func (h *ConcurrencyHandler) GetWaitTimeSamples(c *gin.Context) {
+	apiKeyID := c.Param("apiKeyId")
+	if apiKeyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apiKeyId is required"})
+		return
+	}
+
+	limit := redis.DefaultWaitTimeSamplesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	ctx := c.Request.Context()
+	samples, err := h.redis.GetWaitTimeSamples(ctx, apiKeyID, limit)
+	if err != nil {
+		logger.Error("Failed to get wait time samples", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"apiKeyId": apiKeyID,
+		"samples":  samples,
+		"count":    len(samples),
+	})
+}
+
+// GetQueueStatsWindow 获取时间窗口内的队列统计
+// 支持 from/to（RFC3339）或 hours（最近 N 小时，默认 1）
+func (h *ConcurrencyHandler) GetQueueStatsWindow(c *gin.Context) {
+	apiKeyID := c.Param("apiKeyId")
+	if apiKeyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apiKeyId is required"})
+		return
+	}
+
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "1"))
+	if err != nil || hours <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive integer"})
+		return
+	}
+	from := to.Add(-time.Duration(hours) * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.redis.GetQueueStatsWindow(ctx, apiKeyID, from, to)
+	if err != nil {
+		logger.Error("Failed to get queue stats window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // GetGlobalQueueStats 获取全局队列统计
 func (h *ConcurrencyHandler) GetGlobalQueueStats(c *gin.Context) {
 	includePerKey := c.Query("includePerKey") == "true"
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
@@ -142,6 +144,53 @@ func (h *GenericHandler) HGetAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"values": values})
 }
 
+// HScan 使用 HSCAN 游标分页读取大 Hash
+// 查询参数: cursor（默认 0）、count（每页数量提示，默认 100，最大 1000）、match（字段匹配模式）
+func (h *GenericHandler) HScan(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+
+	var cursor uint64
+	if v := c.Query("cursor"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		cursor = parsed
+	}
+
+	count := int64(100)
+	if v := c.Query("count"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid count"})
+			return
+		}
+		count = parsed
+	}
+	if count > 1000 {
+		count = 1000
+	}
+
+	ctx := c.Request.Context()
+	fields, next, err := h.redis.HScanPage(ctx, key, cursor, c.Query("match"), count)
+	if err != nil {
+		logger.Error("Failed to hscan", zap.String("key", key), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fields":     fields,
+		"nextCursor": strconv.FormatUint(next, 10),
+		"done":       next == 0,
+	})
+}
+
 // HSet 设置 Hash 字段
 func (h *GenericHandler) HSet(c *gin.Context) {
 	var req struct {
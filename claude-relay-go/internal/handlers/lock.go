@@ -218,3 +218,37 @@ func (h *LockHandler) GetUserMessageQueueStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// ListUserMessageLocks 列出当前持有的用户消息锁
+func (h *LockHandler) ListUserMessageLocks(c *gin.Context) {
+	locks, err := h.redis.ListUserMessageLocks(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list user message locks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locks": locks, "total": len(locks)})
+}
+
+// GetStuckUserMessageLocks 获取持有时间超过 olderThan（默认 5m）或永不过期的用户消息锁
+func (h *LockHandler) GetStuckUserMessageLocks(c *gin.Context) {
+	olderThan, err := time.ParseDuration(c.DefaultQuery("olderThan", "5m"))
+	if err != nil || olderThan <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "olderThan must be a positive duration, e.g. 5m"})
+		return
+	}
+
+	locks, err := h.redis.GetStuckUserMessageLocks(c.Request.Context(), olderThan)
+	if err != nil {
+		logger.Error("Failed to get stuck user message locks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"olderThan": olderThan.String(),
+		"locks":     locks,
+		"total":     len(locks),
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/pricing"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PricingHandler 定价处理器
+type PricingHandler struct {
+	pricing *pricing.Service
+}
+
+// NewPricingHandler 创建定价处理器
+func NewPricingHandler(pricingService *pricing.Service) *PricingHandler {
+	return &PricingHandler{pricing: pricingService}
+}
+
+// GetStatus 获取定价服务状态（含灰度状态）
+func (h *PricingHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pricing.GetStatus())
+}
+
+// Promote 发布灰度中的价格更新
+func (h *PricingHandler) Promote(c *gin.Context) {
+	if err := h.pricing.PromoteStagedPricing(); err != nil {
+		if errors.Is(err, pricing.ErrNoStagedPricing) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to promote staged pricing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"promoted": true})
+}
+
+// Rollback 丢弃灰度中的价格更新
+func (h *PricingHandler) Rollback(c *gin.Context) {
+	if err := h.pricing.RollbackStagedPricing(); err != nil {
+		if errors.Is(err, pricing.ErrNoStagedPricing) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Failed to roll back staged pricing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rolledBack": true})
+}
+
+// maxCoverageModels 单次价格覆盖检查的最大模型数
+const maxCoverageModels = 100
+
+// Coverage 检查一组模型是否有真实（非默认）价格
+func (h *PricingHandler) Coverage(c *gin.Context) {
+	var req struct {
+		Models []string `json:"models"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Models) == 0 || len(req.Models) > maxCoverageModels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("models must contain 1-%d entries", maxCoverageModels)})
+		return
+	}
+	for _, model := range req.Models {
+		if strings.TrimSpace(model) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "model names must not be empty"})
+			return
+		}
+	}
+
+	coverage := h.pricing.GetPricingCoverage(req.Models)
+	uncovered := make([]string, 0)
+	for _, item := range coverage {
+		if !item.Covered {
+			uncovered = append(uncovered, item.Model)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"models":    coverage,
+		"uncovered": uncovered,
+	})
+}
+
+// StartRefresh 触发一次后台价格刷新，返回任务 ID
+func (h *PricingHandler) StartRefresh(c *gin.Context) {
+	c.JSON(http.StatusAccepted, h.pricing.StartRefresh())
+}
+
+// GetRefreshJob 查询价格刷新任务状态
+func (h *PricingHandler) GetRefreshJob(c *gin.Context) {
+	job, ok := h.pricing.GetRefreshJob(c.Param("jobId"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "refresh job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
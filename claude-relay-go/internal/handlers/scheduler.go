@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultRankedAccounts 未指定 n 时返回的候选账户数
+const defaultRankedAccounts = 3
+
+// maxRankedAccounts 单次最多返回的候选账户数
+const maxRankedAccounts = 20
+
+// SchedulerHandler 调度处理器
+type SchedulerHandler struct {
+	redis      *redis.Client
+	schedulers map[scheduler.AccountCategory]*scheduler.BaseScheduler
+}
+
+// NewSchedulerHandler 创建调度处理器
+func NewSchedulerHandler(redisClient *redis.Client) *SchedulerHandler {
+	return &SchedulerHandler{
+		redis: redisClient,
+		schedulers: map[scheduler.AccountCategory]*scheduler.BaseScheduler{
+			scheduler.CategoryClaude: scheduler.NewBaseScheduler(redisClient, scheduler.CategoryClaude, scheduler.ClaudeAccountTypes),
+			scheduler.CategoryGemini: scheduler.NewBaseScheduler(redisClient, scheduler.CategoryGemini, scheduler.GeminiAccountTypes),
+			scheduler.CategoryOpenAI: scheduler.NewBaseScheduler(redisClient, scheduler.CategoryOpenAI, scheduler.OpenAIAccountTypes),
+			scheduler.CategoryDroid:  scheduler.NewBaseScheduler(redisClient, scheduler.CategoryDroid, scheduler.DroidAccountTypes),
+		},
+	}
+}
+
+// SelectRankedAccounts 返回按选择顺序排列的前 N 个可用账户（供客户端自行故障转移）
+func (h *SchedulerHandler) SelectRankedAccounts(c *gin.Context) {
+	category := scheduler.AccountCategory(c.Param("category"))
+	s, ok := h.schedulers[category]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown account category"})
+		return
+	}
+
+	var req struct {
+		Model                 string                  `json:"model"`
+		APIKeyID              string                  `json:"apiKeyId"`
+		PreferredAccountTypes []scheduler.AccountType `json:"preferredAccountTypes"`
+		ExcludeAccountIDs     []string                `json:"excludeAccountIds"`
+		RequireFeatures       []string                `json:"requireFeatures"`
+		ClientType            string                  `json:"clientType"`
+		Payload               map[string]interface{}  `json:"payload"` // 原始请求体，用于检测所需功能（可选）
+		N                     int                     `json:"n"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n := req.N
+	if n <= 0 {
+		n = defaultRankedAccounts
+	}
+	if n > maxRankedAccounts {
+		n = maxRankedAccounts
+	}
+
+	opts := scheduler.SelectOptions{
+		Model:                 req.Model,
+		APIKeyID:              req.APIKeyID,
+		PreferredAccountTypes: req.PreferredAccountTypes,
+		ExcludeAccountIDs:     req.ExcludeAccountIDs,
+		RequireFeatures:       req.RequireFeatures,
+		ClientType:            req.ClientType,
+	}
+	// 按请求体检测 vision/thinking/tools 等所需功能，与显式传入的 requireFeatures 合并
+	opts.ApplyRequestFeatures(category, req.Payload)
+
+	ranked := s.SelectRankedAccounts(c.Request.Context(), opts, n)
+
+	c.JSON(http.StatusOK, gin.H{
+		"category": category,
+		"strategy": scheduler.SelectionStrategy(),
+		"accounts": ranked,
+		"count":    len(ranked),
+	})
+}
+
+// EstimatePoolCapacity 估算账户池剩余并发容量（供自动扩缩容决策）
+func (h *SchedulerHandler) EstimatePoolCapacity(c *gin.Context) {
+	category := scheduler.AccountCategory(c.Param("category"))
+	s, ok := h.schedulers[category]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown account category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.EstimateCapacity(c.Request.Context()))
+}
+
+// ListEligibleAccounts 列出某个 API Key 请求指定模型时当前可调度的账户及得分（不选择、不绑定会话）
+func (h *SchedulerHandler) ListEligibleAccounts(c *gin.Context) {
+	keyID := c.Query("keyId")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyId is required"})
+		return
+	}
+	category := scheduler.AccountCategory(c.DefaultQuery("category", string(scheduler.CategoryClaude)))
+	s, ok := h.schedulers[category]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown account category"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	apiKey, err := h.redis.GetAPIKey(ctx, keyID)
+	if err != nil {
+		logger.Error("Failed to get API key", zap.String("keyID", keyID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.ListEligibleAccounts(ctx, apiKey, c.Query("model")))
+}
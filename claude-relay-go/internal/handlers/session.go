@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -236,13 +237,19 @@ func (h *SessionHandler) SetStickySession(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	if err := h.redis.SetStickySession(ctx, req.SessionHash, req.AccountID, req.AccountType, ttl); err != nil {
+	err := h.redis.SetStickySession(ctx, req.SessionHash, req.AccountID, req.AccountType, ttl)
+	if errors.Is(err, redis.ErrStickySessionCapReached) {
+		// 粘性会话已达上限：不绑定会话，由调用方按无状态方式路由
+		c.JSON(http.StatusOK, gin.H{"success": true, "bound": false})
+		return
+	}
+	if err != nil {
 		logger.Error("Failed to set sticky session", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"success": true, "bound": true})
 }
 
 // GetStickySession 获取粘性会话
@@ -289,6 +296,11 @@ func (h *SessionHandler) GetOrCreateStickySession(c *gin.Context) {
 
 	ctx := c.Request.Context()
 	session, created, err := h.redis.GetOrCreateStickySession(ctx, req.SessionHash, req.AccountID, req.AccountType, ttl)
+	if errors.Is(err, redis.ErrStickySessionCapReached) {
+		// 粘性会话已达上限：本次请求不绑定会话，按无状态方式路由
+		c.JSON(http.StatusOK, gin.H{"session": nil, "created": false, "skipped": true})
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get or create sticky session", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -355,6 +367,24 @@ func (h *SessionHandler) GetAllStickySessions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "total": len(sessions)})
 }
 
+// GetStickySessionCountsByAccount 按账户统计粘性会话数
+func (h *SessionHandler) GetStickySessionCountsByAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	counts, err := h.redis.GetStickySessionCountsByAccount(ctx)
+	if err != nil {
+		logger.Error("Failed to get sticky session counts by account", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": counts, "total": total})
+}
+
 // CleanupExpiredStickySessions 清理过期粘性会话
 func (h *SessionHandler) CleanupExpiredStickySessions(c *gin.Context) {
 	ctx := c.Request.Context()
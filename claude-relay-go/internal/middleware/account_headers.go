@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// 实际服务账户调试响应头
+const (
+	HeaderAccountType = "X-CRS-Account-Type"
+	HeaderAccountID   = "X-CRS-Account-Id"
+	// HeaderDebugToken 请求携带的调试令牌
+	HeaderDebugToken = "X-CRS-Debug-Token"
+)
+
+// accountHeadersEnabled 是否在响应中暴露实际服务账户（默认关闭）
+func accountHeadersEnabled(c *gin.Context) bool {
+	if config.Cfg == nil {
+		return false
+	}
+	if config.Cfg.Security.ExposeAccountHeaders {
+		return true
+	}
+
+	token := config.Cfg.Security.AccountHeadersToken
+	header := c.GetHeader(HeaderDebugToken)
+	if token == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte(token)) == 1
+}
+
+// SetResolvedAccountHeaders 根据调度结果设置账户调试响应头（需在写入响应前调用）
+func SetResolvedAccountHeaders(c *gin.Context, result *scheduler.SelectResult) {
+	if result == nil || result.AccountID == "" || !accountHeadersEnabled(c) {
+		return
+	}
+	c.Header(HeaderAccountType, string(result.AccountType))
+	c.Header(HeaderAccountID, result.AccountID)
+}
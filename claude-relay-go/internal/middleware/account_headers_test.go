@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetResolvedAccountHeaders(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	result := &scheduler.SelectResult{AccountType: scheduler.AccountTypeClaudeConsole, AccountID: "acct-1"}
+	run := func(headers map[string]string) *httptest.ResponseRecorder {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/v1/messages", nil)
+		for k, v := range headers {
+			c.Request.Header.Set(k, v)
+		}
+		SetResolvedAccountHeaders(c, result)
+		return w
+	}
+
+	config.Cfg = &config.Config{}
+	if w := run(nil); w.Header().Get(HeaderAccountID) != "" || w.Header().Get(HeaderAccountType) != "" {
+		t.Error("account headers must be absent by default")
+	}
+
+	config.Cfg = &config.Config{Security: config.SecurityConfig{ExposeAccountHeaders: true}}
+	w := run(nil)
+	if w.Header().Get(HeaderAccountType) != "claude-console" || w.Header().Get(HeaderAccountID) != "acct-1" {
+		t.Errorf("headers = %v, want account type and id", w.Header())
+	}
+
+	config.Cfg = &config.Config{Security: config.SecurityConfig{AccountHeadersToken: "support-token"}}
+	if w := run(map[string]string{HeaderDebugToken: "support-token"}); w.Header().Get(HeaderAccountID) != "acct-1" {
+		t.Error("account headers should be present with matching debug token")
+	}
+	if w := run(map[string]string{HeaderDebugToken: "wrong"}); w.Header().Get(HeaderAccountID) != "" {
+		t.Error("account headers must be absent with mismatched debug token")
+	}
+}
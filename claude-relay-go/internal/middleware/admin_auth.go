@@ -51,6 +51,21 @@ func NewAdminAuthMiddleware(redisClient *redis.Client) (*AdminAuthMiddleware, er
 	}, nil
 }
 
+// RequireAdmin 创建管理员认证中间件；JWT 密钥未配置时拒绝所有请求（fail closed）
+func RequireAdmin(redisClient *redis.Client) gin.HandlerFunc {
+	m, err := NewAdminAuthMiddleware(redisClient)
+	if err != nil {
+		logger.Warn("Admin authentication is not configured, admin routes are disabled", zap.Error(err))
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Admin authentication is not configured",
+				"code":  "admin_auth_unavailable",
+			})
+		}
+	}
+	return m.Authenticate()
+}
+
 // Authenticate 管理员认证中间件
 func (m *AdminAuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
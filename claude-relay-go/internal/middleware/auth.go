@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -13,6 +15,7 @@ import (
 	"github.com/catstream/claude-relay-go/internal/pkg/clients"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
 	"github.com/catstream/claude-relay-go/internal/services/apikey"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -37,18 +40,27 @@ const (
 	ContextKeyAuthDuration ContextKey = "authDuration"
 )
 
+// authFailureQueueSize 待记录认证失败的缓冲数（写满时丢弃，统计本身是采样值）
+const authFailureQueueSize = 1024
+
 // AuthMiddleware 认证中间件配置
 type AuthMiddleware struct {
 	apiKeyService *apikey.Service
 	redis         *redis.Client
+	authFailures  chan string // 由单个后台协程异步写入 Redis
 }
 
 // NewAuthMiddleware 创建认证中间件
 func NewAuthMiddleware(apiKeyService *apikey.Service, redisClient *redis.Client) *AuthMiddleware {
-	return &AuthMiddleware{
+	m := &AuthMiddleware{
 		apiKeyService: apiKeyService,
 		redis:         redisClient,
 	}
+	if redisClient != nil {
+		m.authFailures = make(chan string, authFailureQueueSize)
+		go m.runAuthFailureRecorder()
+	}
+	return m
 }
 
 // Authenticate 认证中间件
@@ -63,6 +75,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		// 1. 提取 API Key
 		rawKey := m.extractAPIKey(c)
 		if rawKey == "" {
+			m.recordAuthFailure("missing_api_key")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":     "Missing API key",
 				"code":      "missing_api_key",
@@ -74,6 +87,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		// 2. 解析客户端类型
 		clientType := m.parseClientType(c.GetHeader("User-Agent"))
 		c.Set(string(ContextKeyClientType), clientType)
+		c.Request = c.Request.WithContext(scheduler.WithClientType(c.Request.Context(), clientType))
 
 		// 3. 检查全局 Claude Code Only 限制
 		if config.Cfg != nil && config.Cfg.Security.ClaudeCodeOnly {
@@ -81,6 +95,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 				logger.Warn("Request rejected: Claude Code Only mode enabled",
 					zap.String("clientType", clientType),
 					zap.String("userAgent", c.GetHeader("User-Agent")))
+				m.recordAuthFailure("claude_code_only")
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 					"error":     "This API only accepts requests from Claude Code",
 					"code":      "claude_code_only",
@@ -99,6 +114,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 			RequiredPermission: requiredPermission,
 			ClientType:         clientType,
 			Model:              model,
+			Verbose:            m.diagnosticsRequested(c),
 		})
 
 		if !result.Valid {
@@ -107,16 +123,30 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 				zap.String("code", result.ErrorCode),
 				zap.String("clientType", clientType))
 
-			c.AbortWithStatusJSON(result.StatusCode, gin.H{
+			resp := gin.H{
 				"error":     result.Error,
 				"code":      result.ErrorCode,
 				"requestId": requestID,
-			})
+			}
+			if result.Diagnostics != nil {
+				resp["diagnostics"] = result.Diagnostics
+			}
+			if result.Expiry != nil {
+				resp["expiry"] = result.Expiry
+			}
+			m.recordAuthFailure(result.ErrorCode)
+			c.AbortWithStatusJSON(result.StatusCode, resp)
 			return
 		}
 
 		apiKey := result.APIKey
 
+		// 按配置处理未指定模型的请求
+		model, ok := m.applyMissingModelPolicy(c, apiKey, model, requestID)
+		if !ok {
+			return
+		}
+
 		// 6. 检查速率限制
 		rateLimitResult, err := m.apiKeyService.CheckRateLimit(c.Request.Context(), apiKey)
 		if err != nil {
@@ -128,6 +158,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 			c.Header("X-RateLimit-Reset", rateLimitResult.ResetAt.Format(time.RFC3339))
 			c.Header("Retry-After", strconv.Itoa(int(rateLimitResult.RetryAfter.Seconds())))
 
+			m.recordAuthFailure("rate_limit_exceeded")
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":      "Rate limit exceeded",
 				"code":       "rate_limit_exceeded",
@@ -138,18 +169,35 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 			return
 		}
 
-		// 7. 检查并发限制（领取并发槽位，请求结束释放）
+		// 7. 检查并发限制（领取并发槽位，请求结束释放；启用全局并发上限时所有 Key 都需领取）
+		// 启用成员元数据时，模型、客户端与开始时间写入旁路哈希（并发成员仍为原始请求 ID）
 		slotAcquired := false
-		if apiKey.ConcurrentLimit > 0 {
-			acquired, currentCount, err := m.apiKeyService.TryAcquireConcurrencySlot(c.Request.Context(), apiKey, requestID, 0)
-			if err != nil {
+		slotCtx := redis.WithHeldConcurrency(c.Request.Context()) // 本实例自身的请求，关闭时统一释放
+		slotCtx = redis.WithConcurrencyMeta(slotCtx, redis.ConcurrencyMemberMeta{
+			Model:      model,
+			ClientType: clientType,
+			StartedAt:  startTime,
+		})
+		if apiKey.ConcurrentLimit > 0 || apikey.GlobalConcurrencyLimit() > 0 {
+			acquired, currentCount, err := m.apiKeyService.TryAcquireConcurrencySlot(slotCtx, apiKey, requestID, 0)
+			if errors.Is(err, apikey.ErrGlobalConcurrencyLimitExceeded) {
+				m.recordAuthFailure("global_concurrency_limit")
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error":              "Global concurrency limit exceeded",
+					"code":               "global_concurrency_limit",
+					"currentConcurrency": currentCount,
+					"limit":              apikey.GlobalConcurrencyLimit(),
+					"requestId":          requestID,
+				})
+				return
+			} else if err != nil {
 				logger.Error("Concurrency acquire failed", zap.Error(err))
 				// 出错时允许通过，避免阻塞请求
 			} else if acquired {
 				slotAcquired = true
 			} else {
 				// 并发已满：检查是否启用了并发排队
-				if apiKey.ConcurrentRequestQueueEnabled {
+				if apikey.QueueEnabled(apiKey) {
 					// 检查队列健康状态（P90 等待时间）
 					isHealthy, p90WaitTime, healthErr := m.apiKeyService.CheckQueueHealth(c.Request.Context(), apiKey)
 					if healthErr != nil {
@@ -159,6 +207,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 					if !isHealthy {
 						// 队列过载，快速失败
 						m.redis.IncrQueueStats(c.Request.Context(), apiKey.ID, "rejected_overload", 1)
+						m.recordAuthFailure("queue_overloaded")
 						c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 							"error":       "Queue overloaded",
 							"code":        "queue_overloaded",
@@ -172,8 +221,9 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 					m.redis.IncrQueueStats(c.Request.Context(), apiKey.ID, "entered", 1)
 
 					// 进入排队逻辑（成功后即持有并发槽位）
-					queueResult := m.apiKeyService.WaitInQueue(c.Request.Context(), apiKey, requestID)
+					queueResult := m.apiKeyService.WaitInQueue(slotCtx, apiKey, requestID)
 					if !queueResult.Success {
+						m.recordAuthFailure("queue_" + queueResult.TimeoutReason)
 						c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 							"error":         "Concurrency limit exceeded and queue timeout",
 							"code":          "queue_" + queueResult.TimeoutReason,
@@ -193,11 +243,12 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 					if currentConcurrency > 0 {
 						currentConcurrency--
 					}
+					m.recordAuthFailure("concurrency_limit_exceeded")
 					c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 						"error":              "Concurrency limit exceeded",
 						"code":               "concurrency_limit_exceeded",
 						"currentConcurrency": currentConcurrency,
-						"limit":              apiKey.ConcurrentLimit,
+						"limit":              m.apiKeyService.EffectiveConcurrencyLimit(c.Request.Context(), apiKey),
 						"requestId":          requestID,
 					})
 					return
@@ -206,7 +257,10 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		}
 
 		if slotAcquired {
+			// 长请求在剩余租约低于阈值时自动续约
+			stopLeaseRefresher := m.apiKeyService.StartLeaseRefresher(apiKey.ID, requestID)
 			defer func() {
+				stopLeaseRefresher()
 				releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
 				if err := m.apiKeyService.ReleaseConcurrencySlot(releaseCtx, apiKey.ID, requestID); err != nil {
@@ -225,6 +279,10 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		}
 
 		if costResult != nil && !costResult.Allowed {
+			if m.abortInsufficientFuel(c, costResult.FuelStatus, "daily_cost_limit_exceeded", costResult.CurrentCost, costResult.DailyLimit, requestID) {
+				return
+			}
+			m.recordAuthFailure("daily_cost_limit_exceeded")
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Daily cost limit exceeded",
 				"code":        "daily_cost_limit_exceeded",
@@ -242,6 +300,10 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		}
 
 		if totalCostResult != nil && !totalCostResult.Allowed {
+			if m.abortInsufficientFuel(c, totalCostResult.FuelStatus, "total_cost_limit_exceeded", totalCostResult.CurrentCost, totalCostResult.TotalLimit, requestID) {
+				return
+			}
+			m.recordAuthFailure("total_cost_limit_exceeded")
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Total cost limit exceeded",
 				"code":        "total_cost_limit_exceeded",
@@ -259,6 +321,7 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		}
 
 		if weeklyOpusResult != nil && !weeklyOpusResult.Allowed {
+			m.recordAuthFailure("weekly_opus_cost_limit_exceeded")
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Weekly Opus cost limit exceeded",
 				"code":        "weekly_opus_cost_limit_exceeded",
@@ -277,6 +340,10 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 		}
 
 		if rateLimitCostResult != nil && !rateLimitCostResult.Allowed {
+			if m.abortInsufficientFuel(c, rateLimitCostResult.FuelStatus, "rate_limit_cost_exceeded", rateLimitCostResult.CurrentCost, rateLimitCostResult.CostLimit, requestID) {
+				return
+			}
+			m.recordAuthFailure("rate_limit_cost_exceeded")
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":         "Rate limit cost exceeded",
 				"code":          "rate_limit_cost_exceeded",
@@ -289,15 +356,41 @@ func (m *AuthMiddleware) Authenticate(requiredPermission string) gin.HandlerFunc
 			return
 		}
 
-		// 12. 设置上下文
+		// 12. 检查每日 Token 限额（按权重计数）
+		tokenLimitResult, err := m.apiKeyService.CheckDailyTokenLimit(c.Request.Context(), apiKey)
+		if err != nil {
+			logger.Error("Daily token limit check failed", zap.Error(err))
+		}
+
+		if tokenLimitResult != nil && !tokenLimitResult.Allowed {
+			m.recordAuthFailure("daily_token_limit_exceeded")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":     "Daily token limit exceeded",
+				"code":      "daily_token_limit_exceeded",
+				"used":      tokenLimitResult.Used,
+				"rawUsed":   tokenLimitResult.RawUsed,
+				"limit":     tokenLimitResult.Limit,
+				"resetAt":   tokenLimitResult.ResetAt.Format(time.RFC3339),
+				"requestId": requestID,
+			})
+			return
+		}
+
+		// 13. 软限制预警（仅提示，不拒绝）
+		m.applyLimitWarnings(c, apiKey.ID, rateLimitResult, costResult)
+
+		// 14. 设置上下文
 		c.Set(string(ContextKeyAPIKey), apiKey)
 		c.Set(string(ContextKeyAPIKeyID), apiKey.ID)
 		c.Set(string(ContextKeyAuthDuration), time.Since(startTime))
 
-		// 13. 更新最后使用时间（异步）
+		// 移除 Key 配置的请求头（在读取成本归因标签之后）
+		stripRequestHeaders(c, apiKey)
+
+		// 15. 更新最后使用时间（异步）
 		go m.updateLastUsedAt(context.Background(), apiKey.ID)
 
-		// 14. 添加响应头
+		// 16. 添加响应头
 		if rateLimitResult != nil && rateLimitResult.Allowed {
 			c.Header("X-RateLimit-Remaining", strconv.FormatInt(rateLimitResult.Remaining, 10))
 		}
@@ -372,6 +465,105 @@ func (m *AuthMiddleware) extractAPIKey(c *gin.Context) string {
 	return ""
 }
 
+// HeaderRateLimitWarning 用量超过软限制预警阈值时返回的响应头
+const HeaderRateLimitWarning = "X-RateLimit-Warning"
+
+// applyLimitWarnings 用量超过预警阈值时添加响应头并记录（异步计数）
+func (m *AuthMiddleware) applyLimitWarnings(c *gin.Context, keyID string, rateLimit *apikey.RateLimitResult, dailyCost *apikey.CostLimitResult) {
+	var limitTypes []string
+	if rateLimit != nil && rateLimit.Allowed && rateLimit.Warning {
+		limitTypes = append(limitTypes, "rate_limit_"+rateLimit.Window)
+	}
+	if dailyCost != nil && dailyCost.Allowed && dailyCost.Warning {
+		limitTypes = append(limitTypes, "daily_cost")
+	}
+	if len(limitTypes) == 0 {
+		return
+	}
+
+	c.Header(HeaderRateLimitWarning, "true")
+	logger.Debug("API key approaching limit",
+		zap.String("apiKeyId", keyID),
+		zap.Strings("limits", limitTypes))
+
+	if m.redis == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		for _, limitType := range limitTypes {
+			if err := m.redis.IncrLimitWarning(ctx, keyID, limitType); err != nil {
+				logger.Debug("Failed to record limit warning", zap.String("limit", limitType), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// abortInsufficientFuel 成本限制拒绝且本可由加油包放行（加油包已耗尽或过期）时返回 insufficient_fuel，便于计费界面提示充值
+// 未购买过加油包时返回 false，由调用方返回常规的限制错误
+func (m *AuthMiddleware) abortInsufficientFuel(c *gin.Context, fuelStatus, limitCode string, currentCost, limit float64, requestID string) bool {
+	if !apikey.IsFuelExhausted(fuelStatus) {
+		return false
+	}
+
+	status := http.StatusTooManyRequests
+	if config.Cfg != nil && config.Cfg.System.InsufficientFuelPaymentRequired {
+		status = http.StatusPaymentRequired
+	}
+
+	m.recordAuthFailure("insufficient_fuel")
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":       "Insufficient fuel: fuel pack " + fuelStatus,
+		"code":        "insufficient_fuel",
+		"fuelStatus":  fuelStatus,
+		"limitCode":   limitCode,
+		"currentCost": currentCost,
+		"limit":       limit,
+		"requestId":   requestID,
+	})
+	return true
+}
+
+// recordAuthFailure 异步记录认证拒绝原因（不阻塞请求，队列已满时丢弃）
+func (m *AuthMiddleware) recordAuthFailure(code string) {
+	if m.authFailures == nil {
+		return
+	}
+	select {
+	case m.authFailures <- code:
+	default:
+	}
+}
+
+// runAuthFailureRecorder 逐个写入认证拒绝原因（拒绝风暴时不会为每次拒绝创建协程）
+func (m *AuthMiddleware) runAuthFailureRecorder() {
+	for code := range m.authFailures {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := m.redis.RecordAuthFailure(ctx, code); err != nil {
+			logger.Debug("Failed to record auth failure", zap.String("code", code), zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// diagnosticsRequested 是否请求验证诊断信息（生产环境始终关闭）
+func (m *AuthMiddleware) diagnosticsRequested(c *gin.Context) bool {
+	if config.Cfg == nil || config.Cfg.Server.Env == "production" {
+		return false
+	}
+	if config.Cfg.Security.ValidationDiagnostics {
+		return true
+	}
+
+	token := config.Cfg.Security.ValidationDiagnosticsToken
+	header := c.GetHeader("X-Validation-Diagnostics-Token")
+	if token == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte(token)) == 1
+}
+
 // parseClientType 解析客户端类型
 func (m *AuthMiddleware) parseClientType(userAgent string) string {
 	return clients.ParseClientType(userAgent)
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext(headers map[string]string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	return c
+}
+
+func TestDiagnosticsRequested(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	m := &AuthMiddleware{}
+	tokenHeader := map[string]string{"X-Validation-Diagnostics-Token": "debug-token"}
+
+	config.Cfg = nil
+	if m.diagnosticsRequested(newTestContext(tokenHeader)) {
+		t.Error("diagnostics must be off without config")
+	}
+
+	config.Cfg = &config.Config{
+		Server:   config.ServerConfig{Env: "production"},
+		Security: config.SecurityConfig{ValidationDiagnostics: true, ValidationDiagnosticsToken: "debug-token"},
+	}
+	if m.diagnosticsRequested(newTestContext(tokenHeader)) {
+		t.Error("diagnostics must be off in production")
+	}
+
+	config.Cfg = &config.Config{
+		Server:   config.ServerConfig{Env: "development"},
+		Security: config.SecurityConfig{ValidationDiagnosticsToken: "debug-token"},
+	}
+	if !m.diagnosticsRequested(newTestContext(tokenHeader)) {
+		t.Error("diagnostics should be on with matching token in development")
+	}
+	if m.diagnosticsRequested(newTestContext(map[string]string{"X-Validation-Diagnostics-Token": "wrong"})) {
+		t.Error("diagnostics should be off with mismatched token")
+	}
+
+	config.Cfg.Security.ValidationDiagnostics = true
+	if !m.diagnosticsRequested(newTestContext(nil)) {
+		t.Error("diagnostics should be on when enabled by env in development")
+	}
+}
+
+func TestApplyLimitWarnings(t *testing.T) {
+	m := &AuthMiddleware{}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	m.applyLimitWarnings(c, "key-1",
+		&apikey.RateLimitResult{Allowed: true, Window: "minute"},
+		&apikey.CostLimitResult{Allowed: true, Warning: false})
+	if w.Header().Get(HeaderRateLimitWarning) != "" {
+		t.Error("warning header must be absent below threshold")
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	m.applyLimitWarnings(c, "key-1", nil, &apikey.CostLimitResult{Allowed: true, Warning: true})
+	if w.Header().Get(HeaderRateLimitWarning) != "true" {
+		t.Error("warning header should be set when daily cost crosses threshold")
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	m.applyLimitWarnings(c, "key-1", &apikey.RateLimitResult{Allowed: true, Warning: true, Window: "hour"}, nil)
+	if w.Header().Get(HeaderRateLimitWarning) != "true" {
+		t.Error("warning header should be set when rate limit crosses threshold")
+	}
+}
+
+func TestAbortInsufficientFuel(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+	gin.SetMode(gin.TestMode)
+	m := &AuthMiddleware{}
+
+	run := func(fuelStatus string) (*httptest.ResponseRecorder, bool) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		aborted := m.abortInsufficientFuel(c, fuelStatus, "daily_cost_limit_exceeded", 5, 5, "req-1")
+		return w, aborted
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+		}
+		return body
+	}
+
+	// 加油包余额用尽
+	w, aborted := run(apikey.FuelStatusDepleted)
+	if !aborted || w.Code != http.StatusTooManyRequests {
+		t.Fatalf("depleted fuel = aborted %v, status %d; want aborted with 429", aborted, w.Code)
+	}
+	if body := decode(w); body["code"] != "insufficient_fuel" || body["fuelStatus"] != "depleted" {
+		t.Errorf("depleted body = %v, want insufficient_fuel / depleted", body)
+	}
+
+	// 加油包过期，配置为返回 402
+	config.Cfg.System.InsufficientFuelPaymentRequired = true
+	w, aborted = run(apikey.FuelStatusExpired)
+	if !aborted || w.Code != http.StatusPaymentRequired {
+		t.Fatalf("expired fuel = aborted %v, status %d; want aborted with 402", aborted, w.Code)
+	}
+	if body := decode(w); body["code"] != "insufficient_fuel" || body["fuelStatus"] != "expired" || body["limitCode"] != "daily_cost_limit_exceeded" {
+		t.Errorf("expired body = %v, want insufficient_fuel / expired", body)
+	}
+
+	// 从未购买加油包时由调用方返回常规限制错误
+	if w, aborted = run(apikey.FuelStatusNone); aborted || w.Body.Len() != 0 {
+		t.Errorf("no fuel = aborted %v, body %q; want untouched", aborted, w.Body.String())
+	}
+}
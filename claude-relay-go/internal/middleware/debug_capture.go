@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DebugCapture 调试采样中间件（需在 Authenticate 之后使用，未启用 DEBUG_CAPTURE_ENABLED 时直接放行）
+// API Key 的 debugCaptureCount > 0 时记录脱敏后的请求体与响应元数据，每次采样扣减一次
+func DebugCapture(redisClient *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !redis.DebugCaptureEnabled() {
+			c.Next()
+			return
+		}
+		apiKey := GetAPIKeyFromContext(c)
+		if apiKey == nil || apiKey.DebugCaptureCount <= 0 {
+			c.Next()
+			return
+		}
+
+		// 最多读取 MaxDebugCaptureBodyBytes+1 字节用于采样，其余部分原样留给后续处理
+		var body []byte
+		if c.Request.Body != nil {
+			data, err := io.ReadAll(io.LimitReader(c.Request.Body, redis.MaxDebugCaptureBodyBytes+1))
+			if err == nil {
+				body = data
+			}
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(data), c.Request.Body), c.Request.Body}
+		}
+
+		startTime := time.Now()
+		c.Next()
+
+		capture := redis.NewDebugCapture(c.Request.Method, c.Request.URL.Path, flattenHeaders(c.Request.Header), body)
+		if len(body) > redis.MaxDebugCaptureBodyBytes && c.Request.ContentLength > 0 {
+			capture.RequestBodySize = int(c.Request.ContentLength)
+		}
+		capture.RequestID = GetRequestIDFromContext(c)
+		capture.Model = GetRequestModelFromContext(c)
+		capture.StatusCode = c.Writer.Status()
+		capture.ResponseHeaders = redis.RedactDebugHeaders(flattenHeaders(c.Writer.Header()))
+		capture.ResponseSize = c.Writer.Size()
+		capture.DurationMs = time.Since(startTime).Milliseconds()
+
+		if _, err := redisClient.RecordDebugCapture(context.Background(), apiKey.ID, capture); err != nil {
+			logger.Warn("Failed to record debug capture",
+				zap.String("apiKeyId", apiKey.ID),
+				zap.Error(err))
+		}
+	}
+}
+
+// readCloser 组合读取器与原始 body 的 Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// flattenHeaders 将多值头合并为单值（取第一个）
+func flattenHeaders(header map[string][]string) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		if len(values) > 0 {
+			result[name] = values[0]
+		}
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// GenericCommandAllowed 检查通用 Redis 操作是否在允许列表中（未配置时全部允许）
+func GenericCommandAllowed(command string) bool {
+	if config.Cfg == nil || len(config.Cfg.Security.GenericRedisCommands) == 0 {
+		return true
+	}
+	for _, allowed := range config.Cfg.Security.GenericRedisCommands {
+		if strings.EqualFold(allowed, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowGenericCommand 中间件：拒绝未在 GENERIC_REDIS_COMMANDS 中允许的通用 Redis 操作
+func AllowGenericCommand(command string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GenericCommandAllowed(command) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Generic Redis command '" + command + "' is disabled",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
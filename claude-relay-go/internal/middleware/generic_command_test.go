@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowGenericCommand(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/generic/get/*key", AllowGenericCommand("get"), ok)
+	router.POST("/generic/set", AllowGenericCommand("set"), ok)
+
+	request := func(method, path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	// 默认全部允许
+	config.Cfg = &config.Config{}
+	if code := request("POST", "/generic/set"); code != http.StatusOK {
+		t.Errorf("set without allowlist = %d, want 200", code)
+	}
+
+	config.Cfg = &config.Config{Security: config.SecurityConfig{GenericRedisCommands: []string{"GET", "scan"}}}
+	if code := request("GET", "/generic/get/some:key"); code != http.StatusOK {
+		t.Errorf("allowed get = %d, want 200", code)
+	}
+	if code := request("POST", "/generic/set"); code != http.StatusForbidden {
+		t.Errorf("disabled set = %d, want 403", code)
+	}
+}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/gin-gonic/gin"
 )
 
 func TestNewAdminAuthMiddleware_RequiresJWTSecret(t *testing.T) {
@@ -69,3 +72,20 @@ func TestNewUserAuthMiddleware_RequiresJWTSecret(t *testing.T) {
 		t.Fatal("expected user management enabled")
 	}
 }
+
+func TestRequireAdmin_FailsClosedWithoutJWTSecret(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/", nil)
+
+	RequireAdmin(nil)(c)
+
+	if !c.IsAborted() || w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("aborted = %v, status = %d; want aborted with 503", c.IsAborted(), w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+)
+
+// 请求未指定模型时的处理方式
+const (
+	MissingModelPassthrough = "passthrough" // 保持为空（计费按默认模型，路由不限制模型）
+	MissingModelStrict      = "strict"      // 拒绝请求（model_required）
+	MissingModelDefault     = "default"     // 使用 API Key 的默认模型（未设置时保持为空）
+	MissingModelUnknown     = "unknown"     // 使用 unknown 占位模型并优先路由到默认账户类型（MISSING_MODEL_ACCOUNT_TYPE）
+)
+
+// missingModelPolicy 获取路由的未指定模型处理方式（路由覆盖优先于全局配置）
+func missingModelPolicy(route string) string {
+	if config.Cfg == nil {
+		return MissingModelPassthrough
+	}
+	policy := config.Cfg.Security.MissingModelPolicy
+	if override, ok := config.Cfg.Security.MissingModelRoutePolicies[route]; ok {
+		policy = override
+	}
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case MissingModelStrict, MissingModelDefault, MissingModelUnknown:
+		return policy
+	default:
+		return MissingModelPassthrough
+	}
+}
+
+// applyMissingModelPolicy 按配置处理未指定模型的请求，返回最终模型；拒绝时已中止请求并返回 false
+func (m *AuthMiddleware) applyMissingModelPolicy(c *gin.Context, apiKey *redis.APIKey, model, requestID string) (string, bool) {
+	if model != "" {
+		return model, true
+	}
+
+	switch missingModelPolicy(c.FullPath()) {
+	case MissingModelStrict:
+		m.recordAuthFailure("model_required")
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":     "Model is required",
+			"code":      "model_required",
+			"requestId": requestID,
+		})
+		return "", false
+	case MissingModelDefault:
+		if apiKey != nil {
+			model = apiKey.DefaultModel
+		}
+	case MissingModelUnknown:
+		model = scheduler.UnknownModel
+		// 记录到请求上下文，调度器选择账户时优先使用该账户类型
+		if accountType := config.Cfg.Security.MissingModelAccountType; accountType != "" {
+			c.Request = c.Request.WithContext(scheduler.WithPreferredAccountType(c.Request.Context(), scheduler.AccountType(accountType)))
+		}
+	}
+
+	c.Set(string(ContextKeyRequestModel), model)
+	return model, true
+}
+
+// GetMissingModelAccountTypeFromContext 获取 unknown 模式下优先路由的账户类型（未指定时为空）
+// 调度器的 SelectAccount 通过请求上下文读取同一个值
+func GetMissingModelAccountTypeFromContext(c *gin.Context) scheduler.AccountType {
+	return scheduler.PreferredAccountTypeFromContext(c.Request.Context())
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/services/scheduler"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+)
+
+// runMissingModel 以不带模型的请求调用指定路由，返回响应与处理后的模型
+func runMissingModel(t *testing.T, route string, apiKey *redis.APIKey) (*httptest.ResponseRecorder, string, scheduler.AccountType) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	m := &AuthMiddleware{}
+	var model string
+	var accountType scheduler.AccountType
+	r := gin.New()
+	r.POST(route, func(c *gin.Context) {
+		got, ok := m.applyMissingModelPolicy(c, apiKey, m.parseRequestModel(c), "req-1")
+		if !ok {
+			return
+		}
+		model = got
+		accountType = GetMissingModelAccountTypeFromContext(c)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, route, strings.NewReader(`{"messages":[]}`)))
+	return w, model, accountType
+}
+
+func TestApplyMissingModelPolicy(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	apiKey := &redis.APIKey{ID: "key-1", DefaultModel: "claude-sonnet-4-20250514"}
+	tests := []struct {
+		policy      string
+		wantStatus  int
+		wantModel   string
+		wantAccount scheduler.AccountType
+	}{
+		{MissingModelPassthrough, http.StatusOK, "", ""},
+		{MissingModelStrict, http.StatusBadRequest, "", ""},
+		{MissingModelDefault, http.StatusOK, "claude-sonnet-4-20250514", ""},
+		{MissingModelUnknown, http.StatusOK, scheduler.UnknownModel, scheduler.AccountTypeClaudeConsole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			config.Cfg = &config.Config{Security: config.SecurityConfig{
+				MissingModelPolicy:      tt.policy,
+				MissingModelAccountType: "claude-console",
+			}}
+
+			w, model, accountType := runMissingModel(t, "/v1/messages", apiKey)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if !strings.Contains(w.Body.String(), `"code":"model_required"`) {
+					t.Errorf("body = %s, want model_required code", w.Body.String())
+				}
+				return
+			}
+			if model != tt.wantModel || accountType != tt.wantAccount {
+				t.Errorf("model = %q, accountType = %q, want %q, %q", model, accountType, tt.wantModel, tt.wantAccount)
+			}
+		})
+	}
+}
+
+func TestApplyMissingModelPolicy_RouteOverrideAndExplicitModel(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Security: config.SecurityConfig{
+		MissingModelPolicy:        MissingModelStrict,
+		MissingModelRoutePolicies: map[string]string{"/v1/messages/count_tokens": MissingModelPassthrough},
+	}}
+
+	if w, model, _ := runMissingModel(t, "/v1/messages/count_tokens", nil); w.Code != http.StatusOK || model != "" {
+		t.Errorf("overridden route: status = %d, model = %q, want 200 with empty model", w.Code, model)
+	}
+	if w, _, _ := runMissingModel(t, "/v1/messages", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("strict route: status = %d, want 400", w.Code)
+	}
+
+	// 默认模式下 Key 未设置默认模型时保持为空
+	config.Cfg.Security.MissingModelPolicy = MissingModelDefault
+	if w, model, _ := runMissingModel(t, "/v1/messages", &redis.APIKey{ID: "key-2"}); w.Code != http.StatusOK || model != "" {
+		t.Errorf("default without key model: status = %d, model = %q", w.Code, model)
+	}
+
+	// 已指定模型时不受策略影响
+	m := &AuthMiddleware{}
+	c := newTestContext(nil)
+	config.Cfg.Security.MissingModelPolicy = MissingModelStrict
+	if model, ok := m.applyMissingModelPolicy(c, nil, "claude-opus-4", "req-2"); !ok || model != "claude-opus-4" {
+		t.Errorf("explicit model = %q, ok = %v", model, ok)
+	}
+}
@@ -200,7 +200,7 @@ func (cl *ConcurrencyLimiter) WithKeyFunc(fn func(*gin.Context) string) *Concurr
 // Limit 返回并发限制中间件
 func (cl *ConcurrencyLimiter) Limit() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx := c.Request.Context()
+		ctx := redis.WithHeldConcurrency(c.Request.Context())
 		key := cl.keyFunc(c)
 		requestID := GetRequestIDFromContext(c)
 		if requestID == "" {
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorCodeStreamIdleTimeout 流式转发空闲超时的错误码
+const ErrorCodeStreamIdleTimeout = "stream_idle_timeout"
+
+// streamIdleTimeout 获取 API Key 的流式空闲超时（Key 配置优先，负数表示不限制，0 使用全局配置）
+func streamIdleTimeout(apiKey *redis.APIKey) time.Duration {
+	switch {
+	case apiKey != nil && apiKey.StreamIdleTimeoutSeconds < 0:
+		return 0
+	case apiKey != nil && apiKey.StreamIdleTimeoutSeconds > 0:
+		return time.Duration(apiKey.StreamIdleTimeoutSeconds) * time.Second
+	case config.Cfg != nil:
+		return config.Cfg.System.StreamIdleTimeout
+	}
+	return 0
+}
+
+// idleWriter 记录响应数据输出的 ResponseWriter
+type idleWriter struct {
+	gin.ResponseWriter
+	activity chan struct{}
+}
+
+// touch 通知有数据输出（不阻塞）
+func (w *idleWriter) touch() {
+	select {
+	case w.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (w *idleWriter) Write(data []byte) (int, error) {
+	w.touch()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idleWriter) WriteString(s string) (int, error) {
+	w.touch()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// StreamIdleTimeout 流式转发空闲超时中间件（需在 Authenticate 之后使用）
+// 首次输出数据后开始计时（首字节前的上游等待不计入），此后超过空闲时间没有任何数据输出时
+// 取消请求上下文并以 SSE 错误事件结束流，转发处理返回后并发槽位随之释放
+//
+// 本服务目前只提供 /redis 代理接口，不承载转发路由；该中间件供转发路由组挂载：
+//
+//	relay.Use(authMiddleware.Authenticate(), middleware.StreamIdleTimeout())
+func StreamIdleTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := GetAPIKeyFromContext(c)
+		timeout := streamIdleTimeout(apiKey)
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &idleWriter{ResponseWriter: original, activity: make(chan struct{}, 1)}
+		c.Writer = writer
+
+		var timedOut atomic.Bool
+		done := make(chan struct{})
+		go func() {
+			// 等待首次输出后再开始计时
+			select {
+			case <-writer.activity:
+			case <-done:
+				return
+			}
+
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			for {
+				select {
+				case <-writer.activity:
+					if !timer.Stop() {
+						<-timer.C
+					}
+					timer.Reset(timeout)
+				case <-timer.C:
+					timedOut.Store(true)
+					cancel()
+					return
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		c.Next()
+		close(done)
+		c.Writer = original
+
+		if !timedOut.Load() {
+			return
+		}
+
+		keyID := ""
+		if apiKey != nil {
+			keyID = apiKey.ID
+		}
+		logger.Warn("Stream idle timeout, request cancelled",
+			zap.String("apiKeyId", keyID),
+			zap.String("requestId", GetRequestIDFromContext(c)),
+			zap.Duration("idleTimeout", timeout))
+
+		// 计时从首次输出开始，超时时响应必然已开始输出，以 SSE 错误事件结束流
+		message := fmt.Sprintf("No data received for %s", timeout)
+		payload, _ := json.Marshal(gin.H{
+			"type":  "error",
+			"error": gin.H{"type": ErrorCodeStreamIdleTimeout, "message": message},
+		})
+		fmt.Fprintf(original, "event: error\ndata: %s\n\n", payload)
+		original.Flush()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+)
+
+// runStreamIdle 以指定 Key 执行 StreamIdleTimeout 中间件与转发处理，返回响应与处理是否被取消
+func runStreamIdle(t *testing.T, apiKey *redis.APIKey, relay func(c *gin.Context)) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cancelled := false
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(string(ContextKeyAPIKey), apiKey)
+		c.Next()
+	}, StreamIdleTimeout())
+	router.POST("/v1/messages", func(c *gin.Context) {
+		relay(c)
+		cancelled = c.Request.Context().Err() != nil
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", nil))
+	return w, cancelled
+}
+
+func TestStreamIdleTimeout_StalledStreamIsCancelled(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{StreamIdleTimeout: 50 * time.Millisecond}}
+
+	w, cancelled := runStreamIdle(t, &redis.APIKey{ID: "key-1"}, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("event: message_start\ndata: {}\n\n")
+		c.Writer.Flush()
+		// 上游停止输出，直到请求被取消
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+
+	if !cancelled {
+		t.Fatal("stalled stream should cancel the request context")
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "event: message_start") || !strings.Contains(body, "event: error") || !strings.Contains(body, ErrorCodeStreamIdleTimeout) {
+		t.Errorf("body = %q, want stream followed by idle timeout error event", body)
+	}
+}
+
+func TestStreamIdleTimeout_WaitBeforeFirstByteIsNotCounted(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{StreamIdleTimeout: 50 * time.Millisecond}}
+
+	// 首字节前的上游等待远超空闲时间，之后持续输出
+	w, cancelled := runStreamIdle(t, &redis.APIKey{ID: "key-1"}, func(c *gin.Context) {
+		time.Sleep(150 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("data: {}\n\n")
+			c.Writer.Flush()
+			time.Sleep(15 * time.Millisecond)
+		}
+	})
+
+	if cancelled {
+		t.Error("wait before the first byte should not cancel the request")
+	}
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), ErrorCodeStreamIdleTimeout) {
+		t.Errorf("response = %d %q, want 200 without idle timeout error", w.Code, w.Body.String())
+	}
+}
+
+func TestStreamIdleTimeout_ActiveStreamIsNotCancelled(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{StreamIdleTimeout: 50 * time.Millisecond}}
+
+	// 总时长远超空闲时间，但每次输出间隔都短于空闲时间
+	w, cancelled := runStreamIdle(t, &redis.APIKey{ID: "key-1"}, func(c *gin.Context) {
+		for i := 0; i < 10; i++ {
+			c.Writer.WriteString("data: {}\n\n")
+			c.Writer.Flush()
+			time.Sleep(15 * time.Millisecond)
+		}
+	})
+
+	if cancelled {
+		t.Error("active stream should not be cancelled")
+	}
+	if strings.Contains(w.Body.String(), ErrorCodeStreamIdleTimeout) {
+		t.Errorf("body = %q, want no idle timeout error", w.Body.String())
+	}
+
+	// Key 关闭空闲超时
+	_, cancelled = runStreamIdle(t, &redis.APIKey{ID: "key-2", StreamIdleTimeoutSeconds: -1}, func(c *gin.Context) {
+		time.Sleep(80 * time.Millisecond)
+	})
+	if cancelled {
+		t.Error("key with negative streamIdleTimeoutSeconds should not be reaped")
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// protectedRequestHeaders CRS 自身依赖的请求头（认证、客户端识别、请求体解析），不允许按 Key 移除
+var protectedRequestHeaders = map[string]bool{
+	"Authorization":     true,
+	"X-Api-Key":         true,
+	"X-Goog-Api-Key":    true,
+	"Content-Type":      true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Host":              true,
+	"User-Agent":        true,
+	"Anthropic-Version": true,
+	"Anthropic-Beta":    true,
+}
+
+// stripRequestHeaders 移除 API Key 配置的请求头（认证通过后执行，转发时不再携带）
+func stripRequestHeaders(c *gin.Context, apiKey *redis.APIKey) {
+	if apiKey == nil {
+		return
+	}
+	for _, header := range apiKey.StripRequestHeaders {
+		name := http.CanonicalHeaderKey(header)
+		if name == "" {
+			continue
+		}
+		if protectedRequestHeaders[name] {
+			logger.Debug("Skip stripping protected request header",
+				zap.String("apiKeyId", apiKey.ID),
+				zap.String("header", name))
+			continue
+		}
+		c.Request.Header.Del(name)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestStripRequestHeaders(t *testing.T) {
+	c := newTestContext(map[string]string{
+		"X-Internal-Route": "shard-7",
+		"X-Debug-Trace":    "abc",
+		"X-Request-Source": "web",
+		"Authorization":    "Bearer cr_test",
+		"Content-Type":     "application/json",
+	})
+	apiKey := &redis.APIKey{
+		ID:                  "key-1",
+		StripRequestHeaders: []string{"x-internal-route", "X-DEBUG-TRACE", "authorization", "content-type", ""},
+	}
+
+	stripRequestHeaders(c, apiKey)
+
+	for _, header := range []string{"X-Internal-Route", "X-Debug-Trace"} {
+		if got := c.Request.Header.Get(header); got != "" {
+			t.Errorf("%s = %q, want stripped", header, got)
+		}
+	}
+	// 未配置的请求头与 CRS 依赖的请求头保留
+	for header, want := range map[string]string{
+		"X-Request-Source": "web",
+		"Authorization":    "Bearer cr_test",
+		"Content-Type":     "application/json",
+	} {
+		if got := c.Request.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestStripRequestHeaders_NoConfig(t *testing.T) {
+	c := newTestContext(map[string]string{"X-Internal-Route": "shard-7"})
+
+	stripRequestHeaders(c, &redis.APIKey{ID: "key-1"})
+	stripRequestHeaders(c, nil)
+
+	if c.Request.Header.Get("X-Internal-Route") != "shard-7" {
+		t.Error("headers should pass through when no strip list is configured")
+	}
+}
@@ -2,6 +2,8 @@ package clients
 
 import (
 	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/config"
 )
 
 // ClientType 客户端类型常量
@@ -35,6 +37,11 @@ func ParseClientType(userAgent string) string {
 
 	ua := strings.ToLower(userAgent)
 
+	// 自定义映射优先
+	if clientType := matchCustomClientType(ua, customClientTypes()); clientType != "" {
+		return clientType
+	}
+
 	// Claude Code 客户端
 	if strings.Contains(ua, "claude-code") || strings.Contains(ua, "claudecode") {
 		return TypeClaudeCode
@@ -73,6 +80,29 @@ func ParseClientType(userAgent string) string {
 	return TypeUnknown
 }
 
+// customClientTypes 获取配置的自定义 User-Agent 映射
+func customClientTypes() map[string]string {
+	if config.Cfg != nil {
+		return config.Cfg.Security.CustomClientTypes
+	}
+	return nil
+}
+
+// matchCustomClientType 按自定义映射匹配（不区分大小写的片段匹配，最长片段优先，未匹配返回空字符串）
+func matchCustomClientType(ua string, mappings map[string]string) string {
+	var matched, clientType string
+	for fragment, t := range mappings {
+		f := strings.ToLower(fragment)
+		if f == "" || !strings.Contains(ua, f) {
+			continue
+		}
+		if len(f) > len(matched) || (len(f) == len(matched) && f < matched) {
+			matched, clientType = f, t
+		}
+	}
+	return clientType
+}
+
 // IsClientAllowed 检查客户端是否在允许列表中
 func IsClientAllowed(allowedClients []string, clientType string) bool {
 	if len(allowedClients) == 0 {
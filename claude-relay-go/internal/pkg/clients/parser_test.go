@@ -0,0 +1,63 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestParseClientType_KnownAndUnknownAgents(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"claude-cli/1.0.58 (external, cli) claude-code", TypeClaudeCode},
+		{"GeminiCLI/0.1.5 (linux; x64)", TypeGeminiCLI},
+		{"codex_cli_rs/0.20.0", TypeCodex},
+		{"Mozilla/5.0 CherryStudio/1.2.0", TypeCherryStudio},
+		{"curl/8.4.0", TypeUnknown},
+		{"", TypeUnknown},
+	}
+	for _, tt := range tests {
+		if got := ParseClientType(tt.userAgent); got != tt.want {
+			t.Errorf("ParseClientType(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestParseClientType_CustomMappings(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Security: config.SecurityConfig{CustomClientTypes: map[string]string{
+		"MyTool":         "MyTool",
+		"mytool-pro":     "MyToolPro",
+		"claude-code-ci": "ClaudeCodeCI",
+	}}}
+
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"mytool/2.1", "MyTool"},
+		// 最长片段优先
+		{"MyTool-Pro/3.0", "MyToolPro"},
+		// 自定义映射优先于内置识别
+		{"claude-code-ci/1.0", "ClaudeCodeCI"},
+		{"claude-cli/1.0 claude-code", TypeClaudeCode},
+		{"other/1.0", TypeUnknown},
+	}
+	for _, tt := range tests {
+		if got := ParseClientType(tt.userAgent); got != tt.want {
+			t.Errorf("ParseClientType(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+
+	info := GetClientInfo("mytool/2.1")
+	if info.Type != "MyTool" || info.IsPredefined || info.Category != "unknown" {
+		t.Errorf("GetClientInfo() = %+v, want custom non-predefined type", info)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
@@ -31,6 +32,7 @@ type BaseService struct {
 	redis         *redis.Client
 	encryptionKey []byte
 	accountType   redis.AccountType
+	proxyClients  *ProxyClientCache
 }
 
 // 密钥派生常量
@@ -58,6 +60,7 @@ func NewBaseService(redisClient *redis.Client, encryptionKey string, accountType
 		redis:         redisClient,
 		encryptionKey: key,
 		accountType:   accountType,
+		proxyClients:  sharedProxyClientCache(redisClient),
 	}
 }
 
@@ -254,6 +257,7 @@ type AccountInfo struct {
 	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
 	LastError   string     `json:"lastError,omitempty"`
 	ErrorCount  int        `json:"errorCount,omitempty"`
+	ErrorTotal  int64      `json:"errorTotal,omitempty"`
 	IsOverloaded bool      `json:"isOverloaded,omitempty"`
 }
 
@@ -295,6 +299,9 @@ func (s *BaseService) GetAllAccountsInfo(ctx context.Context) ([]AccountInfo, er
 		if errorCount, ok := account["errorCount"].(float64); ok {
 			info.ErrorCount = int(errorCount)
 		}
+		if errorTotal, ok := account["errorTotal"].(float64); ok {
+			info.ErrorTotal = int64(errorTotal)
+		}
 		if isOverloaded, ok := account["isOverloaded"].(bool); ok {
 			info.IsOverloaded = isOverloaded
 		}
@@ -305,6 +312,17 @@ func (s *BaseService) GetAllAccountsInfo(ctx context.Context) ([]AccountInfo, er
 	return result, nil
 }
 
+// ProxyHTTPClient 获取账户的 HTTP 客户端（按代理配置缓存，账户变更后自动重新校验）
+// load 通常为具体账户服务的 GetProxyConfig
+func (s *BaseService) ProxyHTTPClient(ctx context.Context, accountID string, load ProxyConfigLoader) (*http.Client, error) {
+	return s.proxyClients.Client(ctx, accountID, load)
+}
+
+// InvalidateAccountProxyCache 使账户缓存的代理客户端失效
+func (s *BaseService) InvalidateAccountProxyCache(accountID string) {
+	s.proxyClients.InvalidateAccountProxyCache(accountID)
+}
+
 // ProxyConfig 代理配置
 type ProxyConfig struct {
 	Enabled  bool   `json:"enabled"`
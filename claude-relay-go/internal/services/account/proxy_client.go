@@ -0,0 +1,158 @@
+package account
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// DefaultProxyClientTimeout 代理客户端默认超时（流式响应可能持续较长时间）
+const DefaultProxyClientTimeout = 10 * time.Minute
+
+// ProxyConfigLoader 加载账户的代理配置（各账户服务的 GetProxyConfig）
+type ProxyConfigLoader func(ctx context.Context, accountID string) (*ProxyConfig, error)
+
+// ProxyClientCache 按账户缓存使用代理的 HTTP 客户端
+// 账户变更后标记为待校验，下次使用时重新加载代理配置；代理字段未变化时继续复用原客户端
+type ProxyClientCache struct {
+	mu      sync.Mutex
+	entries map[string]*proxyClientEntry
+	timeout time.Duration
+}
+
+// proxyClientEntry 缓存的代理客户端
+type proxyClientEntry struct {
+	fingerprint string // 代理字段的摘要
+	client      *http.Client
+	stale       bool // 账户已变更，需要重新校验代理配置
+}
+
+// NewProxyClientCache 创建代理客户端缓存
+func NewProxyClientCache(timeout time.Duration) *ProxyClientCache {
+	if timeout <= 0 {
+		timeout = DefaultProxyClientTimeout
+	}
+	return &ProxyClientCache{
+		entries: make(map[string]*proxyClientEntry),
+		timeout: timeout,
+	}
+}
+
+// proxyClientCaches 每个 Redis 客户端共享的代理客户端缓存（*redis.Client -> *ProxyClientCache）
+var proxyClientCaches sync.Map
+
+// sharedProxyClientCache 获取 Redis 客户端共享的代理客户端缓存
+// 同一 Redis 客户端上的各账户服务复用同一缓存，账户变更监听只注册一次
+func sharedProxyClientCache(redisClient *redis.Client) *ProxyClientCache {
+	if redisClient == nil {
+		return NewProxyClientCache(DefaultProxyClientTimeout)
+	}
+	if cache, ok := proxyClientCaches.Load(redisClient); ok {
+		return cache.(*ProxyClientCache)
+	}
+	cache, loaded := proxyClientCaches.LoadOrStore(redisClient, NewProxyClientCache(DefaultProxyClientTimeout))
+	if !loaded {
+		cache.(*ProxyClientCache).Watch(redisClient)
+	}
+	return cache.(*ProxyClientCache)
+}
+
+// Watch 监听账户写入，账户变更时使其代理客户端待校验
+func (c *ProxyClientCache) Watch(redisClient *redis.Client) {
+	redisClient.OnAccountChanged(func(_ redis.AccountType, accountID string) {
+		c.InvalidateAccountProxyCache(accountID)
+	})
+}
+
+// InvalidateAccountProxyCache 使账户的代理客户端待校验（下次使用时重新加载代理配置）
+func (c *ProxyClientCache) InvalidateAccountProxyCache(accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[accountID]; ok {
+		entry.stale = true
+	}
+}
+
+// Client 获取账户的 HTTP 客户端，未缓存或已失效时通过 load 加载代理配置
+func (c *ProxyClientCache) Client(ctx context.Context, accountID string, load ProxyConfigLoader) (*http.Client, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[accountID]
+	if ok && !entry.stale {
+		c.mu.Unlock()
+		return entry.client, nil
+	}
+	c.mu.Unlock()
+
+	proxy, err := load(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proxy config: %w", err)
+	}
+	fingerprint := proxyFingerprint(proxy)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 加载期间可能已被其他请求重建
+	if current, ok := c.entries[accountID]; ok && current.fingerprint == fingerprint {
+		current.stale = false
+		return current.client, nil
+	}
+
+	client, err := c.newProxyHTTPClient(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if previous, ok := c.entries[accountID]; ok {
+		previous.client.CloseIdleConnections()
+		logger.Info("Rebuilt account proxy client after proxy change", zap.String("accountId", accountID))
+	}
+	c.entries[accountID] = &proxyClientEntry{fingerprint: fingerprint, client: client}
+	return client, nil
+}
+
+// proxyFingerprint 计算代理字段的摘要（不在缓存中保留明文凭据）
+func proxyFingerprint(proxy *ProxyConfig) string {
+	var proxyURL string
+	if proxy != nil {
+		proxyURL = proxy.GetProxyURL()
+	}
+	sum := sha256.Sum256([]byte(proxyURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// newProxyHTTPClient 根据代理配置创建 HTTP 客户端（未启用代理时直连）
+func (c *ProxyClientCache) newProxyHTTPClient(proxy *ProxyConfig) (*http.Client, error) {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if proxy != nil {
+		if raw := proxy.GetProxyURL(); raw != "" {
+			proxyURL, err := url.Parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy config: %w", err)
+			}
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	return &http.Client{Transport: transport, Timeout: c.timeout}, nil
+}
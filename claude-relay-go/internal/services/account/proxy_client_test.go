@@ -0,0 +1,112 @@
+package account
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// newFakeProxy 创建一个 HTTP 代理，返回其名称以便区分请求经过哪个代理
+func newFakeProxy(t *testing.T, name string) *ProxyConfig {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &ProxyConfig{Enabled: true, Protocol: "http", Host: u.Hostname(), Port: port}
+}
+
+// fetchVia 通过客户端发送请求并返回响应的代理名称
+func fetchVia(t *testing.T, client *http.Client) string {
+	t.Helper()
+	resp, err := client.Get("http://upstream.invalid/v1/messages")
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestProxyClientCache_InvalidationPicksUpNewProxy(t *testing.T) {
+	proxyA := newFakeProxy(t, "proxy-a")
+	proxyB := newFakeProxy(t, "proxy-b")
+	current := proxyA
+	loads := 0
+	load := func(ctx context.Context, accountID string) (*ProxyConfig, error) {
+		loads++
+		return current, nil
+	}
+
+	cache := NewProxyClientCache(0)
+	ctx := context.Background()
+
+	client, err := cache.Client(ctx, "acct-1", load)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	if got := fetchVia(t, client); got != "proxy-a" {
+		t.Fatalf("first request went through %q, want proxy-a", got)
+	}
+
+	// 未失效时直接复用，不重新加载
+	current = proxyB
+	if cached, _ := cache.Client(ctx, "acct-1", load); cached != client || loads != 1 {
+		t.Fatalf("cached client reused = %v, loads = %d, want reuse without reload", cached == client, loads)
+	}
+
+	cache.InvalidateAccountProxyCache("acct-1")
+	updated, err := cache.Client(ctx, "acct-1", load)
+	if err != nil {
+		t.Fatalf("Client() after invalidation error = %v", err)
+	}
+	if updated == client {
+		t.Fatal("proxy change should build a new client")
+	}
+	if got := fetchVia(t, updated); got != "proxy-b" {
+		t.Errorf("request after proxy change went through %q, want proxy-b", got)
+	}
+}
+
+func TestProxyClientCache_UnrelatedUpdateKeepsClient(t *testing.T) {
+	proxy := newFakeProxy(t, "proxy-a")
+	load := func(ctx context.Context, accountID string) (*ProxyConfig, error) {
+		// 每次返回新的对象，但代理字段相同
+		copied := *proxy
+		return &copied, nil
+	}
+
+	cache := NewProxyClientCache(0)
+	ctx := context.Background()
+
+	client, _ := cache.Client(ctx, "acct-1", load)
+	cache.InvalidateAccountProxyCache("acct-1")
+	again, err := cache.Client(ctx, "acct-1", load)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	if again != client {
+		t.Error("update without proxy change should keep the existing client")
+	}
+}
+
+func TestSharedProxyClientCache_OnePerRedisClient(t *testing.T) {
+	redisClient := &redis.Client{}
+	claude := NewBaseService(redisClient, "", redis.AccountTypeClaude)
+	gemini := NewBaseService(redisClient, "", redis.AccountTypeGemini)
+	if claude.proxyClients != gemini.proxyClients {
+		t.Fatal("services on the same redis client should share one proxy client cache")
+	}
+	if other := NewBaseService(&redis.Client{}, "", redis.AccountTypeClaude); other.proxyClients == claude.proxyClients {
+		t.Error("a different redis client should get its own cache")
+	}
+}
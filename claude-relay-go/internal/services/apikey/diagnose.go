@@ -0,0 +1,260 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// 诊断检查的类别
+const (
+	DiagnoseCategoryAccess = "access"
+	DiagnoseCategoryLimit  = "limit"
+)
+
+// 诊断检查的访问控制项名称（限制项沿用 SimulateLimit* 名称）
+const (
+	DiagnoseCheckActive     = "active"
+	DiagnoseCheckDeleted    = "deleted"
+	DiagnoseCheckExpiry     = "expiry"
+	DiagnoseCheckPermission = "permission"
+	DiagnoseCheckClient     = "client"
+	DiagnoseCheckModel      = "model_blacklist"
+)
+
+// diagnoseLimitNames 全部限制项（未配置或不适用的项在报告中标记为跳过）
+var diagnoseLimitNames = []string{
+	SimulateLimitRateMinute,
+	SimulateLimitRateHour,
+	SimulateLimitConcurrency,
+	SimulateLimitGlobalConcurrency,
+	SimulateLimitDailyCost,
+	SimulateLimitTotalCost,
+	SimulateLimitWeeklyOpusCost,
+	SimulateLimitRateLimitCost,
+}
+
+// DiagnoseRequest 诊断的假设请求
+type DiagnoseRequest struct {
+	Model              string  `json:"model"`
+	ClientType         string  `json:"clientType"`
+	RequiredPermission string  `json:"permission"`
+	EstimatedCost      float64 `json:"estimatedCost"`
+}
+
+// DiagnosticCheck 单项检查的诊断结果
+type DiagnosticCheck struct {
+	Name        string      `json:"name"`
+	Category    string      `json:"category"`
+	Passed      bool        `json:"passed"`
+	Skipped     bool        `json:"skipped,omitempty"` // 未配置或不适用
+	Current     interface{} `json:"current,omitempty"`
+	Projected   interface{} `json:"projected,omitempty"`
+	Limit       interface{} `json:"limit,omitempty"`
+	Explanation string      `json:"explanation"`
+}
+
+// DiagnosisReport 一次假设请求的完整检查报告
+type DiagnosisReport struct {
+	KeyID         string            `json:"keyId"`
+	Allowed       bool              `json:"allowed"`
+	RejectedBy    []string          `json:"rejectedBy"`
+	EstimatedCost float64           `json:"estimatedCost"`
+	Checks        []DiagnosticCheck `json:"checks"`
+	EvaluatedAt   time.Time         `json:"evaluatedAt"`
+}
+
+// DiagnoseLimits 只读重放一次假设请求的全部检查（访问控制与各项限制），逐项说明通过或拒绝的原因
+// 与验证流程不同，不会在首个失败项处停止，也不会激活 Key、计数或占用槽位
+func (s *Service) DiagnoseLimits(ctx context.Context, apiKey *redis.APIKey, req DiagnoseRequest) (*DiagnosisReport, error) {
+	inherited := *apiKey
+	s.applyUserDefaults(ctx, &inherited)
+	apiKey = &inherited
+
+	now := time.Now()
+	snapshot, err := s.loadLimitSnapshot(ctx, apiKey, req.Model, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildDiagnosis(apiKey, req, snapshot, now), nil
+}
+
+// buildDiagnosis 按当前用量生成诊断报告
+func (s *Service) buildDiagnosis(apiKey *redis.APIKey, req DiagnoseRequest, snapshot *limitSnapshot, now time.Time) *DiagnosisReport {
+	report := &DiagnosisReport{
+		KeyID:         apiKey.ID,
+		Allowed:       true,
+		RejectedBy:    []string{},
+		EstimatedCost: req.EstimatedCost,
+		Checks:        []DiagnosticCheck{},
+		EvaluatedAt:   now,
+	}
+	add := func(check DiagnosticCheck) {
+		if !check.Passed && !check.Skipped {
+			report.Allowed = false
+			report.RejectedBy = append(report.RejectedBy, check.Name)
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	for _, check := range s.diagnoseAccess(apiKey, req, now) {
+		add(check)
+	}
+
+	simulation := s.evaluateSimulation(apiKey, req.Model, req.EstimatedCost, snapshot, now)
+	evaluated := make(map[string]LimitSimulation, len(simulation.Checks))
+	for _, check := range simulation.Checks {
+		evaluated[check.Name] = check
+	}
+	for _, name := range diagnoseLimitNames {
+		check, ok := evaluated[name]
+		if !ok {
+			add(DiagnosticCheck{
+				Name:        name,
+				Category:    DiagnoseCategoryLimit,
+				Passed:      true,
+				Skipped:     true,
+				Explanation: "limit not configured or not applicable to this request",
+			})
+			continue
+		}
+		add(DiagnosticCheck{
+			Name:        name,
+			Category:    DiagnoseCategoryLimit,
+			Passed:      check.Allowed,
+			Current:     check.Current,
+			Projected:   check.Projected,
+			Limit:       check.Limit,
+			Explanation: explainLimit(check),
+		})
+	}
+
+	return report
+}
+
+// diagnoseAccess 访问控制类检查（与验证流程的判断一致）
+func (s *Service) diagnoseAccess(apiKey *redis.APIKey, req DiagnoseRequest, now time.Time) []DiagnosticCheck {
+	checks := []DiagnosticCheck{
+		{
+			Name:        DiagnoseCheckActive,
+			Category:    DiagnoseCategoryAccess,
+			Passed:      apiKey.IsActive,
+			Current:     apiKey.IsActive,
+			Explanation: pick(apiKey.IsActive, "key is active", "key is disabled"),
+		},
+		{
+			Name:        DiagnoseCheckDeleted,
+			Category:    DiagnoseCategoryAccess,
+			Passed:      !apiKey.IsDeleted,
+			Current:     apiKey.IsDeleted,
+			Explanation: pick(!apiKey.IsDeleted, "key is not deleted", "key has been deleted"),
+		},
+	}
+
+	expiry := DiagnosticCheck{Name: DiagnoseCheckExpiry, Category: DiagnoseCategoryAccess, Passed: true}
+	if apiKey.ExpiresAt != nil {
+		expiry.Limit = apiKey.ExpiresAt.Format(time.RFC3339)
+	}
+	if expired := checkExpiry(apiKey, now); expired != nil {
+		expiry.Passed = false
+		expiry.Current = expired.ErrorCode
+		expiry.Explanation = expired.Error
+	} else if apiKey.ExpiresAt == nil {
+		expiry.Explanation = "key does not expire"
+	} else {
+		expiry.Explanation = "key expires at " + apiKey.ExpiresAt.Format(time.RFC3339)
+	}
+	checks = append(checks, expiry)
+
+	permission := DiagnosticCheck{Name: DiagnoseCheckPermission, Category: DiagnoseCategoryAccess, Passed: true, Limit: apiKey.Permissions}
+	switch {
+	case req.RequiredPermission == "":
+		permission.Skipped = true
+		permission.Explanation = "no permission requested"
+	case s.CheckPermission(apiKey, req.RequiredPermission):
+		permission.Current = req.RequiredPermission
+		permission.Explanation = fmt.Sprintf("key has '%s' permission", req.RequiredPermission)
+	default:
+		permission.Passed = false
+		permission.Current = req.RequiredPermission
+		permission.Explanation = fmt.Sprintf("key does not have '%s' permission (allowed: %s)",
+			req.RequiredPermission, strings.Join(apiKey.Permissions, ", "))
+	}
+	checks = append(checks, permission)
+
+	client := DiagnosticCheck{Name: DiagnoseCheckClient, Category: DiagnoseCategoryAccess, Passed: true, Limit: apiKey.AllowedClients}
+	switch {
+	case len(apiKey.AllowedClients) == 0:
+		client.Skipped = true
+		client.Explanation = "key has no client restriction"
+	case req.ClientType == "":
+		client.Skipped = true
+		client.Explanation = "no client type given; client restriction is not enforced"
+	case s.IsClientAllowed(apiKey.AllowedClients, req.ClientType):
+		client.Current = req.ClientType
+		client.Explanation = fmt.Sprintf("client '%s' is allowed", req.ClientType)
+	default:
+		client.Passed = false
+		client.Current = req.ClientType
+		client.Explanation = fmt.Sprintf("client '%s' is not in allowed clients (%s)",
+			req.ClientType, strings.Join(apiKey.AllowedClients, ", "))
+	}
+	checks = append(checks, client)
+
+	model := DiagnosticCheck{Name: DiagnoseCheckModel, Category: DiagnoseCategoryAccess, Passed: true, Limit: apiKey.ModelBlacklist}
+	switch {
+	case len(apiKey.ModelBlacklist) == 0:
+		model.Skipped = true
+		model.Explanation = "key has no model blacklist"
+	case req.Model == "":
+		model.Skipped = true
+		model.Explanation = "no model given; model blacklist is not enforced"
+	case s.IsModelBlacklisted(apiKey.ModelBlacklist, req.Model):
+		model.Passed = false
+		model.Current = req.Model
+		model.Explanation = fmt.Sprintf("model '%s' matches the key's model blacklist", req.Model)
+	default:
+		model.Current = req.Model
+		model.Explanation = fmt.Sprintf("model '%s' is not blacklisted", req.Model)
+	}
+	checks = append(checks, model)
+
+	return checks
+}
+
+// explainLimit 生成限制项的说明
+func explainLimit(check LimitSimulation) string {
+	switch check.Name {
+	case SimulateLimitRateMinute, SimulateLimitRateHour:
+		if check.Allowed {
+			return fmt.Sprintf("%.0f requests in the current window, %.0f with this request, limit %.0f",
+				check.Current, check.Projected, check.Limit)
+		}
+		return fmt.Sprintf("this request would be number %.0f in the current window, exceeding the limit of %.0f",
+			check.Projected, check.Limit)
+	case SimulateLimitConcurrency, SimulateLimitGlobalConcurrency:
+		if check.Allowed {
+			return fmt.Sprintf("%.0f requests in flight, limit %.0f", check.Current, check.Limit)
+		}
+		return fmt.Sprintf("%.0f requests already in flight, at the limit of %.0f", check.Current, check.Limit)
+	}
+
+	if check.Bypassed {
+		return fmt.Sprintf("$%.4f spent of $%.4f limit; bypassed by an active fuel pack", check.Current, check.Limit)
+	}
+	if check.Allowed {
+		return fmt.Sprintf("$%.4f spent of $%.4f limit, $%.4f after this request", check.Current, check.Limit, check.Projected)
+	}
+	return fmt.Sprintf("$%.4f spent, which has reached the $%.4f limit", check.Current, check.Limit)
+}
+
+// pick 按条件选择说明文本
+func pick(cond bool, yes, no string) string {
+	if cond {
+		return yes
+	}
+	return no
+}
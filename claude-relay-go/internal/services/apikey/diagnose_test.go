@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestBuildDiagnosis_ReportsEveryCheckWithSingleFailure(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(24 * time.Hour)
+	apiKey := &redis.APIKey{
+		ID:              "key-1",
+		IsActive:        true,
+		ExpiresAt:       &expiresAt,
+		Permissions:     []string{"claude"},
+		AllowedClients:  []string{"claude_code"},
+		ModelBlacklist:  []string{"opus"},
+		RateLimitPerMin: 10,
+		ConcurrentLimit: 2,
+		DailyCostLimit:  5,
+	}
+	snapshot := &limitSnapshot{minuteCount: 3, concurrency: 1, concurrencyLimit: 2, dailyCost: 5}
+	req := DiagnoseRequest{
+		Model:              "claude-sonnet-4",
+		ClientType:         "claude_code",
+		RequiredPermission: "claude",
+		EstimatedCost:      0.25,
+	}
+
+	s := &Service{}
+	report := s.buildDiagnosis(apiKey, req, snapshot, now)
+
+	if report.Allowed {
+		t.Fatal("expected the daily cost limit to reject the request")
+	}
+	if len(report.RejectedBy) != 1 || report.RejectedBy[0] != SimulateLimitDailyCost {
+		t.Fatalf("RejectedBy = %v, want [%s]", report.RejectedBy, SimulateLimitDailyCost)
+	}
+	// 6 项访问控制 + 8 项限制，未配置的限制标记为跳过
+	if len(report.Checks) != 14 {
+		t.Fatalf("got %d checks, want 14: %+v", len(report.Checks), report.Checks)
+	}
+
+	byName := make(map[string]DiagnosticCheck, len(report.Checks))
+	for _, check := range report.Checks {
+		byName[check.Name] = check
+	}
+	for _, name := range []string{DiagnoseCheckActive, DiagnoseCheckDeleted, DiagnoseCheckExpiry,
+		DiagnoseCheckPermission, DiagnoseCheckClient, DiagnoseCheckModel} {
+		if got := byName[name]; !got.Passed || got.Skipped {
+			t.Errorf("%s = %+v, want passed", name, got)
+		}
+	}
+
+	if got := byName[SimulateLimitRateMinute]; !got.Passed || got.Current != 3.0 || got.Projected != 4.0 || got.Limit != 10.0 {
+		t.Errorf("rate_minute = %+v, want passed 3 -> 4 of 10", got)
+	}
+	if got := byName[SimulateLimitConcurrency]; !got.Passed || got.Current != 1.0 || got.Limit != 2.0 {
+		t.Errorf("concurrency = %+v, want passed 1 of 2", got)
+	}
+	daily := byName[SimulateLimitDailyCost]
+	if daily.Passed || daily.Current != 5.0 || daily.Projected != 5.25 || daily.Limit != 5.0 {
+		t.Errorf("daily_cost = %+v, want failed 5 (5.25 projected) of 5", daily)
+	}
+	if daily.Explanation == "" {
+		t.Error("failed check should carry an explanation")
+	}
+	if got := byName[SimulateLimitTotalCost]; !got.Skipped || !got.Passed {
+		t.Errorf("total_cost = %+v, want skipped", got)
+	}
+}
+
+func TestBuildDiagnosis_AccessChecksDoNotStopAtFirstFailure(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	now := time.Now()
+	apiKey := &redis.APIKey{
+		ID:             "key-1",
+		IsActive:       false,
+		AllowedClients: []string{"claude_code"},
+		ModelBlacklist: []string{"opus"},
+	}
+	req := DiagnoseRequest{Model: "claude-opus-4", ClientType: "curl"}
+
+	s := &Service{}
+	report := s.buildDiagnosis(apiKey, req, &limitSnapshot{}, now)
+
+	want := []string{DiagnoseCheckActive, DiagnoseCheckClient, DiagnoseCheckModel}
+	if len(report.RejectedBy) != len(want) {
+		t.Fatalf("RejectedBy = %v, want %v", report.RejectedBy, want)
+	}
+	for i, name := range want {
+		if report.RejectedBy[i] != name {
+			t.Errorf("RejectedBy[%d] = %s, want %s", i, report.RejectedBy[i], name)
+		}
+	}
+}
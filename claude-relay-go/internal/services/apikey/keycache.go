@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// MaxKeyCacheTTL 验证缓存时长上限
+// Node.js 修改 Key 时不会通知本进程，缓存只能依赖过期淘汰，因此限制最长陈旧时间
+const MaxKeyCacheTTL = 30 * time.Second
+
+// keyCache 验证时使用的 API Key 数据进程内缓存（按哈希索引）
+// 仅缓存 Key 数据本身，激活、过期、权限等检查每次验证时仍会重新执行
+type keyCache struct {
+	mu      sync.RWMutex
+	entries map[string]keyCacheEntry
+}
+
+type keyCacheEntry struct {
+	apiKey    *redis.APIKey
+	expiresAt time.Time
+}
+
+func newKeyCache() *keyCache {
+	return &keyCache{entries: make(map[string]keyCacheEntry)}
+}
+
+// keyCacheTTL 获取 API Key 的缓存时长（Key 配置优先，负数表示不缓存，0 使用全局配置；不超过 MaxKeyCacheTTL）
+func keyCacheTTL(apiKey *redis.APIKey) time.Duration {
+	var ttl time.Duration
+	switch {
+	case apiKey.CacheTTLSeconds < 0:
+		return 0
+	case apiKey.CacheTTLSeconds > 0:
+		ttl = time.Duration(apiKey.CacheTTLSeconds) * time.Second
+	case config.Cfg != nil:
+		ttl = config.Cfg.Security.APIKeyCacheTTL
+	}
+	return min(ttl, MaxKeyCacheTTL)
+}
+
+// cloneAPIKey 深拷贝 API Key（切片、Map 与时间指针均复制，缓存与调用方互不影响）
+func cloneAPIKey(apiKey *redis.APIKey) *redis.APIKey {
+	cloned := *apiKey
+	cloned.ExpiresAt = cloneTime(apiKey.ExpiresAt)
+	cloned.LastUsedAt = cloneTime(apiKey.LastUsedAt)
+	cloned.ActivatedAt = cloneTime(apiKey.ActivatedAt)
+	cloned.Permissions = slices.Clone(apiKey.Permissions)
+	cloned.AllowedClients = slices.Clone(apiKey.AllowedClients)
+	cloned.ModelBlacklist = slices.Clone(apiKey.ModelBlacklist)
+	cloned.CostAlertThresholds = slices.Clone(apiKey.CostAlertThresholds)
+	cloned.Tags = slices.Clone(apiKey.Tags)
+	cloned.UnlimitedLimits = slices.Clone(apiKey.UnlimitedLimits)
+	cloned.BlockedAccountIDs = slices.Clone(apiKey.BlockedAccountIDs)
+	cloned.StripRequestHeaders = slices.Clone(apiKey.StripRequestHeaders)
+	cloned.FeatureFlags = maps.Clone(apiKey.FeatureFlags)
+	return &cloned
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	cloned := *t
+	return &cloned
+}
+
+// get 获取未过期的缓存（返回深拷贝，调用方修改不影响缓存）
+func (c *keyCache) get(hashedKey string, now time.Time) *redis.APIKey {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	entry, ok := c.entries[hashedKey]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if !now.Before(entry.expiresAt) {
+		c.mu.Lock()
+		if current, ok := c.entries[hashedKey]; ok && current.expiresAt == entry.expiresAt {
+			delete(c.entries, hashedKey)
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	return cloneAPIKey(entry.apiKey)
+}
+
+// put 按 Key 的缓存时长写入缓存（存入深拷贝，时长为 0 时不缓存）
+func (c *keyCache) put(hashedKey string, apiKey *redis.APIKey, now time.Time) {
+	if c == nil || apiKey == nil {
+		return
+	}
+	ttl := keyCacheTTL(apiKey)
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.entries[hashedKey] = keyCacheEntry{apiKey: cloneAPIKey(apiKey), expiresAt: now.Add(ttl)}
+	c.mu.Unlock()
+}
+
+// invalidate 移除指定 Key ID 的缓存（Key 配置修改、删除或激活后调用）
+func (c *keyCache) invalidate(keyID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for hashedKey, entry := range c.entries {
+		if entry.apiKey.ID == keyID {
+			delete(c.entries, hashedKey)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// lookupAPIKey 通过哈希查找 API Key（优先使用进程内缓存）
+func (s *Service) lookupAPIKey(ctx context.Context, hashedKey string) (*redis.APIKey, error) {
+	now := time.Now()
+	if apiKey := s.cache.get(hashedKey, now); apiKey != nil {
+		return apiKey, nil
+	}
+	apiKey, err := s.redis.GetAPIKeyByHash(ctx, hashedKey)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(hashedKey, apiKey, now)
+	return apiKey, nil
+}
@@ -0,0 +1,92 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestKeyCache_PerKeyTTLOverridesGlobal(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Security: config.SecurityConfig{APIKeyCacheTTL: time.Minute}}
+
+	if ttl := keyCacheTTL(&redis.APIKey{ID: "k"}); ttl != MaxKeyCacheTTL {
+		t.Errorf("keyCacheTTL() = %v, want capped at %v", ttl, MaxKeyCacheTTL)
+	}
+
+	cache := newKeyCache()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cache.put("hash-admin", &redis.APIKey{ID: "admin", CacheTTLSeconds: 5}, now)
+	cache.put("hash-service", &redis.APIKey{ID: "service"}, now)
+	cache.put("hash-opt-out", &redis.APIKey{ID: "opt-out", CacheTTLSeconds: -1}, now)
+
+	if cache.get("hash-opt-out", now) != nil {
+		t.Error("key with negative cacheTTLSeconds should not be cached")
+	}
+	if cache.get("hash-admin", now.Add(4*time.Second)) == nil {
+		t.Error("short-TTL key should still be cached before its override expires")
+	}
+
+	later := now.Add(10 * time.Second)
+	if cache.get("hash-admin", later) != nil {
+		t.Error("short-TTL key should expire after 5s")
+	}
+	if got := cache.get("hash-service", later); got == nil || got.ID != "service" {
+		t.Errorf("default-TTL key = %+v, want still cached", got)
+	}
+	if cache.get("hash-service", now.Add(MaxKeyCacheTTL)) != nil {
+		t.Error("default-TTL key should expire once the TTL bound elapses")
+	}
+}
+
+func TestKeyCache_InvalidateAndCopy(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	cache := newKeyCache()
+	now := time.Now()
+
+	// 全局未开启缓存时，Key 仍可单独开启
+	cache.put("hash-1", &redis.APIKey{ID: "key-1", CacheTTLSeconds: 30}, now)
+	got := cache.get("hash-1", now)
+	if got == nil {
+		t.Fatal("expected per-key TTL to enable caching when global TTL is 0")
+	}
+	got.IsActivated = true
+	if cache.get("hash-1", now).IsActivated {
+		t.Error("mutating a returned key must not affect the cached entry")
+	}
+
+	// 切片、Map 与时间指针同样深拷贝
+	expiresAt := now.Add(time.Hour)
+	source := &redis.APIKey{ID: "key-3", CacheTTLSeconds: 30, Permissions: []string{"claude"},
+		FeatureFlags: map[string]bool{"a": true}, ExpiresAt: &expiresAt}
+	cache.put("hash-3", source, now)
+	source.Permissions[0] = "gemini"
+	source.FeatureFlags["a"] = false
+	*source.ExpiresAt = now
+	cached := cache.get("hash-3", now)
+	if cached.Permissions[0] != "claude" || !cached.FeatureFlags["a"] || !cached.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("cached key = %+v, want unaffected by caller mutation", cached)
+	}
+	cached.ModelBlacklist = append(cached.ModelBlacklist, "x")
+	cached.Permissions[0] = "openai"
+	if again := cache.get("hash-3", now); again.Permissions[0] != "claude" {
+		t.Error("mutating a returned slice must not affect the cached entry")
+	}
+
+	cache.put("hash-2", &redis.APIKey{ID: "key-2"}, now)
+	if cache.get("hash-2", now) != nil {
+		t.Error("key without override should not be cached when global TTL is 0")
+	}
+
+	cache.invalidate("key-1")
+	if cache.get("hash-1", now) != nil {
+		t.Error("invalidated key should be removed from cache")
+	}
+}
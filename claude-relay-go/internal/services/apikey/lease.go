@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// defaultLeaseRefreshPercent 剩余租约低于完整租约的该百分比时续约
+const defaultLeaseRefreshPercent = 50
+
+// LeaseCheckInterval 获取并发租约自动续约的检查间隔（0 表示不自动续约）
+func LeaseCheckInterval() time.Duration {
+	if config.Cfg != nil && config.Cfg.System.ConcurrencyLeaseCheckInterval > 0 {
+		return config.Cfg.System.ConcurrencyLeaseCheckInterval
+	}
+	return 0
+}
+
+// leaseRefreshPercent 获取续约阈值（1-100）
+func leaseRefreshPercent() int {
+	percent := defaultLeaseRefreshPercent
+	if config.Cfg != nil && config.Cfg.System.ConcurrencyLeaseRefreshPercent > 0 {
+		percent = config.Cfg.System.ConcurrencyLeaseRefreshPercent
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// leaseNeedsRefresh 剩余租约是否已低于阈值
+func leaseNeedsRefresh(remaining, lease time.Duration, percent int) bool {
+	return remaining < lease*time.Duration(percent)/100
+}
+
+// leaseRefresher 并发租约续约器：只在剩余租约低于阈值时写入，短请求不会产生续约
+type leaseRefresher struct {
+	lease   time.Duration
+	percent int
+	expiry  func(ctx context.Context) (time.Time, bool, error)
+	refresh func(ctx context.Context) (bool, error)
+}
+
+// check 检查一次租约，返回是否执行了续约
+func (r *leaseRefresher) check(ctx context.Context, now time.Time) (bool, error) {
+	expiresAt, exists, err := r.expiry(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+	if !leaseNeedsRefresh(expiresAt.Sub(now), r.lease, r.percent) {
+		return false, nil
+	}
+	return r.refresh(ctx)
+}
+
+// StartLeaseRefresher 在后台按检查间隔为持有的并发槽位续约，返回停止函数
+// 租约使用默认时长（与 TryAcquireConcurrencySlot 传入 0 时一致）
+func (s *Service) StartLeaseRefresher(apiKeyID, requestID string) func() {
+	interval := LeaseCheckInterval()
+	if interval <= 0 || requestID == "" {
+		return func() {}
+	}
+
+	r := &leaseRefresher{
+		lease:   time.Duration(redis.DefaultConcurrencyLeaseSeconds) * time.Second,
+		percent: leaseRefreshPercent(),
+		expiry: func(ctx context.Context) (time.Time, bool, error) {
+			return s.redis.GetConcurrencyLeaseExpiry(ctx, apiKeyID, requestID)
+		},
+		refresh: func(ctx context.Context) (bool, error) {
+			return s.redis.RefreshConcurrencyLease(ctx, apiKeyID, requestID, redis.DefaultConcurrencyLeaseSeconds)
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := r.check(ctx, now); err != nil && ctx.Err() == nil {
+					logger.Warn("Failed to refresh concurrency lease",
+						zap.String("apiKeyId", apiKeyID),
+						zap.String("requestId", requestID),
+						zap.Error(err))
+				}
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeLease 模拟 Redis 中的租约到期时间
+type fakeLease struct {
+	expiresAt time.Time
+	lease     time.Duration
+	now       time.Time
+	refreshes []time.Duration // 相对开始时间的续约时刻
+	start     time.Time
+}
+
+func (f *fakeLease) refresher(percent int) *leaseRefresher {
+	return &leaseRefresher{
+		lease:   f.lease,
+		percent: percent,
+		expiry: func(context.Context) (time.Time, bool, error) {
+			return f.expiresAt, true, nil
+		},
+		refresh: func(context.Context) (bool, error) {
+			f.expiresAt = f.now.Add(f.lease)
+			f.refreshes = append(f.refreshes, f.now.Sub(f.start))
+			return true, nil
+		},
+	}
+}
+
+func newFakeLease(lease time.Duration) *fakeLease {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeLease{expiresAt: start.Add(lease), lease: lease, now: start, start: start}
+}
+
+func TestLeaseRefresher_QuickRequestNeverRefreshes(t *testing.T) {
+	f := newFakeLease(300 * time.Second)
+	r := f.refresher(50)
+
+	// 短请求在第一次检查前后即结束，剩余租约远高于阈值
+	for _, elapsed := range []time.Duration{time.Second, 30 * time.Second} {
+		f.now = f.start.Add(elapsed)
+		if refreshed, err := r.check(context.Background(), f.now); err != nil || refreshed {
+			t.Fatalf("check at %v = %v, %v, want no refresh", elapsed, refreshed, err)
+		}
+	}
+	if len(f.refreshes) != 0 {
+		t.Errorf("refreshes = %v, want none", f.refreshes)
+	}
+}
+
+func TestLeaseRefresher_LongRequestRefreshesAtThreshold(t *testing.T) {
+	f := newFakeLease(300 * time.Second)
+	r := f.refresher(50)
+
+	// 每 30 秒检查一次，持续 10 分钟
+	for elapsed := 30 * time.Second; elapsed <= 10*time.Minute; elapsed += 30 * time.Second {
+		f.now = f.start.Add(elapsed)
+		if _, err := r.check(context.Background(), f.now); err != nil {
+			t.Fatalf("check error = %v", err)
+		}
+		if remaining := f.expiresAt.Sub(f.now); remaining <= 0 {
+			t.Fatalf("lease expired at %v", elapsed)
+		}
+	}
+
+	// 剩余租约低于 150 秒（首次为 180 秒时剩余 120 秒）才续约
+	want := []time.Duration{180 * time.Second, 360 * time.Second, 540 * time.Second}
+	if len(f.refreshes) != len(want) {
+		t.Fatalf("refreshes = %v, want %v", f.refreshes, want)
+	}
+	for i := range want {
+		if f.refreshes[i] != want[i] {
+			t.Errorf("refreshes = %v, want %v", f.refreshes, want)
+			break
+		}
+	}
+}
+
+func TestLeaseRefresher_MissingLeaseIsNotRefreshed(t *testing.T) {
+	called := false
+	r := &leaseRefresher{
+		lease:   time.Minute,
+		percent: 100,
+		expiry: func(context.Context) (time.Time, bool, error) {
+			return time.Time{}, false, nil
+		},
+		refresh: func(context.Context) (bool, error) {
+			called = true
+			return true, nil
+		},
+	}
+
+	if refreshed, err := r.check(context.Background(), time.Now()); err != nil || refreshed || called {
+		t.Errorf("check = %v, %v (refresh called: %v), want no refresh for released lease", refreshed, err, called)
+	}
+}
@@ -0,0 +1,16 @@
+package apikey
+
+import (
+	"os"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// 测试中使用空日志，避免未初始化的全局 logger 导致 panic
+	logger.Log = zap.NewNop()
+	logger.Sugar = logger.Log.Sugar()
+	os.Exit(m.Run())
+}
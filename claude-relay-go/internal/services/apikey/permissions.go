@@ -206,6 +206,42 @@ func (pc *PermissionChecker) IsModelAllowed(model string) bool {
 	return true
 }
 
+// CanUseCategory 检查是否可以使用指定类别的账户请求模型（权限 + 模型黑名单，model 为空时只检查权限）
+func (pc *PermissionChecker) CanUseCategory(category, model string) bool {
+	if !pc.HasPermission(category) {
+		return false
+	}
+	return model == "" || pc.IsModelAllowed(model)
+}
+
+// DedicatedAccountIDs 获取指定类别下绑定的专属账户 ID（去除 responses:/api: 类型前缀）
+// 分组绑定（group: 前缀）由 Node 端解析，不在此返回
+func (pc *PermissionChecker) DedicatedAccountIDs(category string) []string {
+	if pc.apiKey == nil {
+		return nil
+	}
+
+	var bound []string
+	switch strings.ToLower(category) {
+	case "claude":
+		bound = []string{pc.apiKey.ClaudeAccountID, pc.apiKey.ClaudeConsoleAccountID, pc.apiKey.BedrockAccountID}
+	case "gemini":
+		bound = []string{strings.TrimPrefix(pc.apiKey.GeminiAccountID, "api:")}
+	case "openai":
+		bound = []string{strings.TrimPrefix(pc.apiKey.OpenAIAccountID, "responses:"), pc.apiKey.AzureOpenAIAccountID}
+	case "droid":
+		bound = []string{pc.apiKey.DroidAccountID}
+	}
+
+	ids := make([]string, 0, len(bound))
+	for _, id := range bound {
+		if id != "" && !strings.HasPrefix(id, "group:") {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // ValidatePermissions 验证权限列表是否有效
 func ValidatePermissions(permissions []string) bool {
 	validSet := make(map[string]bool)
@@ -0,0 +1,34 @@
+package apikey
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestDedicatedAccountIDs(t *testing.T) {
+	checker := NewPermissionChecker(&redis.APIKey{
+		ClaudeAccountID:        "group:team-a",
+		ClaudeConsoleAccountID: "console-1",
+		GeminiAccountID:        "api:gemini-1",
+		OpenAIAccountID:        "responses:resp-1",
+		AzureOpenAIAccountID:   "azure-1",
+	})
+
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		// 分组绑定由 Node 端解析
+		{"claude", []string{"console-1"}},
+		{"gemini", []string{"gemini-1"}},
+		{"openai", []string{"resp-1", "azure-1"}},
+		{"droid", []string{}},
+	}
+	for _, tt := range tests {
+		if got := checker.DedicatedAccountIDs(tt.category); !slices.Equal(got, tt.want) {
+			t.Errorf("DedicatedAccountIDs(%q) = %v, want %v", tt.category, got, tt.want)
+		}
+	}
+}
@@ -2,17 +2,25 @@ package apikey
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/catstream/claude-relay-go/internal/config"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"go.uber.org/zap"
 )
 
+// ErrGlobalConcurrencyLimitExceeded 全局并发已达上限（未占用任何槽位）
+var ErrGlobalConcurrencyLimitExceeded = errors.New("global concurrency limit exceeded")
+
+// ErrConcurrencySlotNotHeld 释放的并发槽位已不存在（仅 STRICT_CONCURRENCY_RELEASE 开启时返回）
+var ErrConcurrencySlotNotHeld = errors.New("concurrency slot not held")
+
 // RateLimitResult 速率限制检查结果
 type RateLimitResult struct {
 	Allowed    bool
@@ -21,6 +29,7 @@ type RateLimitResult struct {
 	ResetAt    time.Time
 	RetryAfter time.Duration
 	Window     string // "minute" or "hour"
+	Warning    bool   // 已超过软限制预警阈值（仍放行）
 }
 
 // ConcurrencyResult 并发限制检查结果
@@ -38,6 +47,8 @@ type CostLimitResult struct {
 	CurrentCost float64
 	DailyLimit  float64
 	LimitType   string // "daily", "total", "weekly_opus", "rate_limit_cost"
+	Warning     bool   // 已超过软限制预警阈值（仍放行）
+	FuelStatus  string // 被拒绝时的加油包状态（见 FuelStatus* 常量）
 }
 
 // TotalCostLimitResult 总成本限制检查结果
@@ -45,6 +56,7 @@ type TotalCostLimitResult struct {
 	Allowed     bool
 	CurrentCost float64
 	TotalLimit  float64
+	FuelStatus  string // 被拒绝时的加油包状态（见 FuelStatus* 常量）
 }
 
 // WeeklyOpusCostResult Opus 周成本限制检查结果
@@ -55,6 +67,15 @@ type WeeklyOpusCostResult struct {
 	ResetAt     time.Time
 }
 
+// DailyTokenLimitResult 每日 Token 限额检查结果
+type DailyTokenLimitResult struct {
+	Allowed bool
+	Used    int64 // 今日已用 Token（按权重计数）
+	RawUsed int64 // 今日已用 Token（未加权）
+	Limit   int64
+	ResetAt time.Time
+}
+
 // RateLimitCostResult 速率限制窗口费用检查结果
 type RateLimitCostResult struct {
 	Allowed       bool
@@ -63,6 +84,7 @@ type RateLimitCostResult struct {
 	WindowMinutes int
 	ResetAt       time.Time
 	HasActiveFuel bool
+	FuelStatus    string // 被拒绝时的加油包状态（见 FuelStatus* 常量）
 }
 
 // QueueWaitResult 排队等待结果
@@ -73,37 +95,48 @@ type QueueWaitResult struct {
 }
 
 // CheckRateLimit 检查速率限制
+// 全部窗口通过时返回剩余额度最少的窗口结果，任一窗口超过预警阈值即标记 Warning
 func (s *Service) CheckRateLimit(ctx context.Context, apiKey *redis.APIKey) (*RateLimitResult, error) {
-	// 检查每分钟限制
-	if apiKey.RateLimitPerMin > 0 {
-		result, err := s.checkRateLimitWindow(ctx, apiKey.ID, "minute", apiKey.RateLimitPerMin, time.Minute)
-		if err != nil {
-			return nil, err
+	warnPercent := limitWarningPercent(apiKey)
+	windows := []struct {
+		name     string
+		limit    int
+		duration time.Duration
+	}{
+		{"minute", apiKey.RateLimitPerMin, time.Minute},
+		{"hour", apiKey.RateLimitPerHour, time.Hour},
+	}
+
+	var tightest *RateLimitResult
+	warning := false
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
 		}
-		if !result.Allowed {
-			return result, nil
-		}
-	}
-
-	// 检查每小时限制
-	if apiKey.RateLimitPerHour > 0 {
-		result, err := s.checkRateLimitWindow(ctx, apiKey.ID, "hour", apiKey.RateLimitPerHour, time.Hour)
+		result, err := s.checkRateLimitWindow(ctx, apiKey.ID, w.name, w.limit, w.duration, warnPercent)
 		if err != nil {
 			return nil, err
 		}
 		if !result.Allowed {
 			return result, nil
 		}
+		warning = warning || result.Warning
+		if tightest == nil || result.Remaining < tightest.Remaining {
+			tightest = result
+		}
 	}
 
-	// 没有配置限制或全部通过
-	return &RateLimitResult{Allowed: true}, nil
+	// 没有配置限制
+	if tightest == nil {
+		return &RateLimitResult{Allowed: true}, nil
+	}
+	tightest.Warning = warning
+	return tightest, nil
 }
 
 // checkRateLimitWindow 检查单个时间窗口的速率限制
-func (s *Service) checkRateLimitWindow(ctx context.Context, keyID, window string, limit int, duration time.Duration) (*RateLimitResult, error) {
-	windowSeconds := int64(duration.Seconds())
-	windowKey := fmt.Sprintf("rate_limit:%s:%s:%d", keyID, window, time.Now().Unix()/windowSeconds)
+func (s *Service) checkRateLimitWindow(ctx context.Context, keyID, window string, limit int, duration time.Duration, warnPercent int) (*RateLimitResult, error) {
+	windowKey := rateLimitWindowKey(keyID, window, duration, time.Now())
 
 	// 原子递增并获取计数
 	count, err := s.redis.IncrWithExpiry(ctx, windowKey, duration)
@@ -111,31 +144,67 @@ func (s *Service) checkRateLimitWindow(ctx context.Context, keyID, window string
 		return nil, fmt.Errorf("failed to check rate limit: %w", err)
 	}
 
-	remaining := int64(limit) - count
-	if remaining < 0 {
-		remaining = 0
-	}
-
 	resetAt := time.Now().Truncate(duration).Add(duration)
+	return evaluateRateLimitWindow(window, count, int64(limit), resetAt, warnPercent), nil
+}
 
-	if count > int64(limit) {
+// rateLimitWindowKey 速率限制固定窗口计数的 key
+func rateLimitWindowKey(keyID, window string, duration time.Duration, now time.Time) string {
+	windowSeconds := int64(duration.Seconds())
+	return fmt.Sprintf("rate_limit:%s:%s:%d", keyID, window, now.Unix()/windowSeconds)
+}
+
+// evaluateRateLimitWindow 根据窗口内计数判断是否放行及是否预警
+func evaluateRateLimitWindow(window string, count, limit int64, resetAt time.Time, warnPercent int) *RateLimitResult {
+	if count > limit {
 		return &RateLimitResult{
 			Allowed:    false,
 			Remaining:  0,
-			Limit:      int64(limit),
+			Limit:      limit,
 			ResetAt:    resetAt,
 			RetryAfter: time.Until(resetAt),
 			Window:     window,
-		}, nil
+		}
+	}
+
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
 	}
 
 	return &RateLimitResult{
 		Allowed:   true,
 		Remaining: remaining,
-		Limit:     int64(limit),
+		Limit:     limit,
 		ResetAt:   resetAt,
 		Window:    window,
-	}, nil
+		Warning:   crossesWarningThreshold(float64(count), float64(limit), warnPercent),
+	}
+}
+
+// limitWarningPercent 获取软限制预警阈值（API Key 配置优先，0 表示关闭）
+func limitWarningPercent(apiKey *redis.APIKey) int {
+	if apiKey != nil && apiKey.LimitWarningsDisabled {
+		return 0
+	}
+	percent := 0
+	if apiKey != nil && apiKey.LimitWarningPercent > 0 {
+		percent = apiKey.LimitWarningPercent
+	} else if config.Cfg != nil && config.Cfg.Security.LimitWarningPercent > 0 {
+		percent = config.Cfg.Security.LimitWarningPercent
+	}
+	if percent >= 100 {
+		return 0
+	}
+	return percent
+}
+
+// crossesWarningThreshold 检查用量是否达到预警阈值
+func crossesWarningThreshold(current, limit float64, warnPercent int) bool {
+	if warnPercent <= 0 || limit <= 0 {
+		return false
+	}
+	return current >= limit*float64(warnPercent)/100
 }
 
 // CheckConcurrencyLimit 检查并发限制
@@ -144,7 +213,7 @@ func (s *Service) CheckConcurrencyLimit(ctx context.Context, apiKey *redis.APIKe
 		return &ConcurrencyResult{
 			Allowed:      true,
 			RequestID:    requestID,
-			QueueEnabled: apiKey.ConcurrentRequestQueueEnabled,
+			QueueEnabled: QueueEnabled(apiKey),
 		}, nil
 	}
 
@@ -155,21 +224,48 @@ func (s *Service) CheckConcurrencyLimit(ctx context.Context, apiKey *redis.APIKe
 		return &ConcurrencyResult{
 			Allowed:      true,
 			RequestID:    requestID,
-			QueueEnabled: apiKey.ConcurrentRequestQueueEnabled,
+			QueueEnabled: QueueEnabled(apiKey),
 		}, nil
 	}
 
-	allowed := current < int64(apiKey.ConcurrentLimit)
+	limit := s.EffectiveConcurrencyLimit(ctx, apiKey)
 
 	return &ConcurrencyResult{
-		Allowed:            allowed,
+		Allowed:            concurrencyAllowed(current, limit),
 		CurrentConcurrency: current,
-		Limit:              apiKey.ConcurrentLimit,
+		Limit:              limit,
 		RequestID:          requestID,
-		QueueEnabled:       apiKey.ConcurrentRequestQueueEnabled,
+		QueueEnabled:       QueueEnabled(apiKey),
 	}, nil
 }
 
+// concurrencyAllowed 并发判定（实际检查与模拟共用）：在途请求数未达上限
+func concurrencyAllowed(current int64, limit int) bool {
+	return current < int64(limit)
+}
+
+// EffectiveConcurrencyLimit 获取 API Key 当前生效的并发上限（临时提升未过期时优先，0 表示不限制）
+func (s *Service) EffectiveConcurrencyLimit(ctx context.Context, apiKey *redis.APIKey) int {
+	if apiKey.ConcurrentLimit <= 0 {
+		return 0
+	}
+	boost, err := s.redis.GetConcurrencyBoost(ctx, apiKey.ID)
+	if err != nil {
+		logger.Warn("Failed to get concurrency boost, using static limit",
+			zap.String("apiKeyId", apiKey.ID),
+			zap.Error(err))
+	}
+	return boostedConcurrencyLimit(apiKey.ConcurrentLimit, boost)
+}
+
+// boostedConcurrencyLimit 临时上限高于静态上限时使用临时上限
+func boostedConcurrencyLimit(staticLimit int, boost *redis.ConcurrencyBoost) int {
+	if boost != nil && boost.Limit > staticLimit {
+		return boost.Limit
+	}
+	return staticLimit
+}
+
 // AcquireConcurrencySlot 获取并发槽位
 func (s *Service) AcquireConcurrencySlot(ctx context.Context, apiKey *redis.APIKey, requestID string, leaseSeconds int) (int64, error) {
 	if leaseSeconds <= 0 {
@@ -189,15 +285,122 @@ func (s *Service) AcquireConcurrencySlot(ctx context.Context, apiKey *redis.APIK
 	return count, nil
 }
 
+// GlobalConcurrencyLimit 获取全局并发上限（0 表示不限制）
+func GlobalConcurrencyLimit() int {
+	if config.Cfg != nil && config.Cfg.System.GlobalConcurrencyLimit > 0 {
+		return config.Cfg.System.GlobalConcurrencyLimit
+	}
+	return 0
+}
+
+// 并发排队模式
+const (
+	QueueModeSimple   = "simple"
+	QueueModePriority = "priority"
+)
+
+// ConcurrencyQueueMode 获取并发排队模式（未配置或无法识别时为 simple）
+func ConcurrencyQueueMode() string {
+	if config.Cfg != nil && config.Cfg.System.ConcurrencyQueueMode == QueueModePriority {
+		return QueueModePriority
+	}
+	return QueueModeSimple
+}
+
+// 排队默认等待时间
+const (
+	defaultQueueTimeout        = 10 * time.Second
+	defaultSoftCapQueueTimeout = 2 * time.Second
+)
+
+// ConcurrencySoftCapEnabled 是否开启全局并发软上限
+func ConcurrencySoftCapEnabled() bool {
+	return config.Cfg != nil && config.Cfg.System.ConcurrencySoftCap
+}
+
+// QueueEnabled API Key 达到并发上限时是否排队（Key 显式开启排队，或全局软上限开启且 Key 未显式关闭）
+func QueueEnabled(apiKey *redis.APIKey) bool {
+	switch apiKey.QueueMode() {
+	case redis.ConcurrentRequestQueueModeEnabled:
+		return true
+	case redis.ConcurrentRequestQueueModeDisabled:
+		return false
+	}
+	return ConcurrencySoftCapEnabled()
+}
+
+// queueTimeout 排队最长等待时间（Key 配置优先；仅因软上限排队时使用较短的全局时长）
+func queueTimeout(apiKey *redis.APIKey) time.Duration {
+	if apiKey.ConcurrentRequestQueueTimeoutMs > 0 {
+		return time.Duration(apiKey.ConcurrentRequestQueueTimeoutMs) * time.Millisecond
+	}
+	if apiKey.QueueMode() == redis.ConcurrentRequestQueueModeEnabled {
+		return defaultQueueTimeout
+	}
+	if config.Cfg != nil && config.Cfg.System.ConcurrencySoftCapTimeout > 0 {
+		return config.Cfg.System.ConcurrencySoftCapTimeout
+	}
+	return defaultSoftCapQueueTimeout
+}
+
+// newPriorityWaiter 创建优先级排队的等待者
+// 启用全局并发上限时跨 Key 排队，否则在 Key 自身的队列内按入队时间排队
+func newPriorityWaiter(apiKey *redis.APIKey, requestID string, enqueuedAt, deadline time.Time) *redis.PriorityWaiter {
+	queueKey := redis.PriorityQueueKey(apiKey.ID)
+	if GlobalConcurrencyLimit() > 0 {
+		queueKey = redis.PriorityQueueKey("")
+	}
+	return redis.NewPriorityWaiter(queueKey, requestID, apiKey.SchedulingPriority, enqueuedAt, deadline)
+}
+
+// priorityTurn 检查优先级排队的等待者是否轮到放行（出错时放行，避免阻塞请求）
+// 只有 Key 自身并发有空位的等待者才留在队列中，避免被 Key 上限卡住的等待者挡住其他 Key
+func (s *Service) priorityTurn(ctx context.Context, waiter *redis.PriorityWaiter, keyHasRoom bool, timeout time.Duration) bool {
+	if !keyHasRoom {
+		if err := s.redis.LeavePriorityQueue(ctx, waiter); err != nil {
+			logger.Warn("Failed to leave priority queue", zap.Error(err))
+		}
+		return false
+	}
+
+	if err := s.redis.JoinPriorityQueue(ctx, waiter, timeout); err != nil {
+		logger.Warn("Failed to join priority queue", zap.Error(err))
+		return true
+	}
+	isHead, err := s.redis.IsPriorityQueueHead(ctx, waiter)
+	if err != nil {
+		logger.Warn("Failed to check priority queue head", zap.Error(err))
+		return true
+	}
+	return isHead
+}
+
 // TryAcquireConcurrencySlot 尝试获取并发槽位（超过上限则立即释放）
+// 启用全局并发上限时，全局已满返回 ErrGlobalConcurrencyLimitExceeded
 func (s *Service) TryAcquireConcurrencySlot(ctx context.Context, apiKey *redis.APIKey, requestID string, leaseSeconds int) (bool, int64, error) {
-	count, err := s.AcquireConcurrencySlot(ctx, apiKey, requestID, leaseSeconds)
-	if err != nil {
-		return false, 0, err
+	var count int64
+	if globalLimit := GlobalConcurrencyLimit(); globalLimit > 0 {
+		if leaseSeconds <= 0 {
+			leaseSeconds = 300 // 默认 5 分钟
+		}
+		keyCount, globalCount, acquired, err := s.redis.IncrConcurrencyWithGlobal(ctx, apiKey.ID, requestID, leaseSeconds, int64(globalLimit))
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to acquire concurrency slot: %w", err)
+		}
+		if !acquired {
+			return false, globalCount, ErrGlobalConcurrencyLimitExceeded
+		}
+		count = keyCount
+	} else {
+		var err error
+		count, err = s.AcquireConcurrencySlot(ctx, apiKey, requestID, leaseSeconds)
+		if err != nil {
+			return false, 0, err
+		}
 	}
 
 	// 并发上限检查（Acquire 是自增/续约，必须在这里做原子化判断）
-	if apiKey.ConcurrentLimit > 0 && count > int64(apiKey.ConcurrentLimit) {
+	if limit := s.EffectiveConcurrencyLimit(ctx, apiKey); limit > 0 && count > int64(limit) {
 		if releaseErr := s.ReleaseConcurrencySlot(ctx, apiKey.ID, requestID); releaseErr != nil {
 			logger.Warn("Failed to release concurrency slot after limit exceeded",
 				zap.String("apiKeyId", apiKey.ID),
@@ -211,16 +414,30 @@ func (s *Service) TryAcquireConcurrencySlot(ctx context.Context, apiKey *redis.A
 }
 
 // ReleaseConcurrencySlot 释放并发槽位
+// 重复释放（租约已不存在）视为无操作，开启 STRICT_CONCURRENCY_RELEASE 时返回 ErrConcurrencySlotNotHeld
 func (s *Service) ReleaseConcurrencySlot(ctx context.Context, apiKeyID, requestID string) error {
-	_, err := s.redis.DecrConcurrency(ctx, apiKeyID, requestID)
+	_, released, err := s.redis.ReleaseConcurrency(ctx, apiKeyID, requestID)
 	if err != nil {
 		return fmt.Errorf("failed to release concurrency slot: %w", err)
 	}
+	return releaseOutcome(apiKeyID, requestID, released)
+}
 
-	logger.Debug("Released concurrency slot",
+// releaseOutcome 根据租约是否确实被移除决定释放结果
+func releaseOutcome(apiKeyID, requestID string, released bool) error {
+	if released {
+		logger.Debug("Released concurrency slot",
+			zap.String("apiKeyId", apiKeyID),
+			zap.String("requestId", requestID))
+		return nil
+	}
+
+	logger.Debug("Concurrency slot already released, ignoring",
 		zap.String("apiKeyId", apiKeyID),
 		zap.String("requestId", requestID))
-
+	if config.Cfg != nil && config.Cfg.System.StrictConcurrencyRelease {
+		return fmt.Errorf("api key %s request %s: %w", apiKeyID, requestID, ErrConcurrencySlotNotHeld)
+	}
 	return nil
 }
 
@@ -246,24 +463,39 @@ func (s *Service) CheckDailyCostLimit(ctx context.Context, apiKey *redis.APIKey)
 		return &CostLimitResult{Allowed: true}, nil
 	}
 
-	if dailyCost >= dailyLimit {
-		return &CostLimitResult{
-			Allowed:     false,
-			CurrentCost: dailyCost,
-			DailyLimit:  dailyLimit,
-		}, nil
-	}
-
 	return &CostLimitResult{
-		Allowed:     true,
+		Allowed:     decideCostLimit(apiKey, dailyCost, dailyLimit, false, time.Now()).allowed,
 		CurrentCost: dailyCost,
 		DailyLimit:  dailyLimit,
 	}, nil
 }
 
+// CheckDailyTokenLimit 检查每日 Token 限额（按 DAILY_TOKEN_WEIGHTS 加权计数）
+func (s *Service) CheckDailyTokenLimit(ctx context.Context, apiKey *redis.APIKey) (*DailyTokenLimitResult, error) {
+	if apiKey.Limit <= 0 {
+		// 未设置限制时允许通过
+		return &DailyTokenLimitResult{Allowed: true}, nil
+	}
+
+	status, err := s.redis.GetDailyTokenStatusForKey(ctx, apiKey)
+	if err != nil {
+		logger.Warn("Failed to get daily tokens", zap.Error(err))
+		// 出错时允许通过，避免阻塞请求
+		return &DailyTokenLimitResult{Allowed: true}, nil
+	}
+
+	return &DailyTokenLimitResult{
+		Allowed: !status.Exceeded,
+		Used:    status.Used,
+		RawUsed: status.RawUsed,
+		Limit:   status.Limit,
+		ResetAt: status.ResetAt,
+	}, nil
+}
+
 // WaitInQueue 在队列中等待
 func (s *Service) WaitInQueue(ctx context.Context, apiKey *redis.APIKey, requestID string) *QueueWaitResult {
-	if !apiKey.ConcurrentRequestQueueEnabled {
+	if !QueueEnabled(apiKey) {
 		return &QueueWaitResult{
 			Success:       false,
 			TimeoutReason: "queue_disabled",
@@ -287,10 +519,7 @@ func (s *Service) WaitInQueue(ctx context.Context, apiKey *redis.APIKey, request
 	}
 
 	// 获取超时时间
-	timeoutMs := apiKey.ConcurrentRequestQueueTimeoutMs
-	if timeoutMs <= 0 {
-		timeoutMs = 10000 // 默认 10 秒
-	}
+	timeoutMs := int(queueTimeout(apiKey).Milliseconds())
 
 	// 增加排队计数
 	_, err = s.redis.IncrConcurrencyQueue(ctx, apiKey.ID, int64(timeoutMs))
@@ -308,7 +537,17 @@ func (s *Service) WaitInQueue(ctx context.Context, apiKey *redis.APIKey, request
 	backoffFactor := 1.5
 	jitterFactor := 0.2
 
+	// 优先级模式：等待者按优先级与入队时间依次放行
+	var waiter *redis.PriorityWaiter
+	if ConcurrencyQueueMode() == QueueModePriority {
+		waiter = newPriorityWaiter(apiKey, requestID, startTime, deadline)
+	}
+
 	defer func() {
+		if waiter != nil {
+			s.redis.LeavePriorityQueue(ctx, waiter)
+		}
+
 		// 减少排队计数
 		s.redis.DecrConcurrencyQueue(ctx, apiKey.ID)
 
@@ -337,9 +576,15 @@ func (s *Service) WaitInQueue(ctx context.Context, apiKey *redis.APIKey, request
 			logger.Warn("Queue check failed", zap.Error(err))
 		}
 
-		if result.Allowed {
+		allowed := result.Allowed
+		if waiter != nil {
+			allowed = s.priorityTurn(ctx, waiter, result.Allowed, time.Duration(timeoutMs)*time.Millisecond)
+		}
+
+		if allowed {
 			acquired, _, acquireErr := s.TryAcquireConcurrencySlot(ctx, apiKey, requestID, 0)
-			if acquireErr != nil {
+			// 全局并发已满时继续排队等待，其他错误允许通过
+			if acquireErr != nil && !errors.Is(acquireErr, ErrGlobalConcurrencyLimitExceeded) {
 				logger.Warn("Queue acquire failed", zap.Error(acquireErr))
 				// 出错时允许通过，避免阻塞请求
 				s.redis.IncrQueueStats(ctx, apiKey.ID, "success", 1)
@@ -411,15 +656,12 @@ func (s *Service) calculateMaxQueueSize(apiKey *redis.APIKey) int {
 
 // CheckQueueHealth 检查队列健康状态
 func (s *Service) CheckQueueHealth(ctx context.Context, apiKey *redis.APIKey) (bool, float64, error) {
-	timeoutMs := apiKey.ConcurrentRequestQueueTimeoutMs
-	if timeoutMs <= 0 {
-		timeoutMs = 10000
-	}
+	timeoutMs := queueTimeout(apiKey).Milliseconds()
 
 	// 默认阈值 0.8
 	threshold := 0.8
 
-	return s.redis.CheckQueueHealth(ctx, threshold, int64(timeoutMs))
+	return s.redis.CheckQueueHealth(ctx, threshold, timeoutMs)
 }
 
 // GetQueueStats 获取排队统计
@@ -436,7 +678,7 @@ func (s *Service) CheckTotalCostLimit(ctx context.Context, apiKey *redis.APIKey)
 		return &TotalCostLimitResult{Allowed: true}, nil
 	}
 
-	// 检查是否有活跃的加油包
+	// 活跃的加油包可绕过限制，无需读取成本
 	if s.hasActiveFuel(apiKey) {
 		return &TotalCostLimitResult{Allowed: true}, nil
 	}
@@ -449,18 +691,12 @@ func (s *Service) CheckTotalCostLimit(ctx context.Context, apiKey *redis.APIKey)
 	}
 
 	totalCost := costStats.TotalCost
-	if totalCost >= totalLimit {
-		return &TotalCostLimitResult{
-			Allowed:     false,
-			CurrentCost: totalCost,
-			TotalLimit:  totalLimit,
-		}, nil
-	}
-
+	decision := decideCostLimit(apiKey, totalCost, totalLimit, true, time.Now())
 	return &TotalCostLimitResult{
-		Allowed:     true,
+		Allowed:     decision.allowed,
 		CurrentCost: totalCost,
 		TotalLimit:  totalLimit,
+		FuelStatus:  decision.fuelStatus,
 	}, nil
 }
 
@@ -487,17 +723,8 @@ func (s *Service) CheckWeeklyOpusCostLimit(ctx context.Context, apiKey *redis.AP
 	// 计算下周一的重置时间
 	resetAt := getNextMondayMidnight()
 
-	if weeklyCost >= weeklyLimit {
-		return &WeeklyOpusCostResult{
-			Allowed:     false,
-			CurrentCost: weeklyCost,
-			WeeklyLimit: weeklyLimit,
-			ResetAt:     resetAt,
-		}, nil
-	}
-
 	return &WeeklyOpusCostResult{
-		Allowed:     true,
+		Allowed:     decideCostLimit(apiKey, weeklyCost, weeklyLimit, false, time.Now()).allowed,
 		CurrentCost: weeklyCost,
 		WeeklyLimit: weeklyLimit,
 		ResetAt:     resetAt,
@@ -514,9 +741,8 @@ func (s *Service) CheckRateLimitCost(ctx context.Context, apiKey *redis.APIKey)
 		return &RateLimitCostResult{Allowed: true}, nil
 	}
 
-	// 检查是否有活跃的加油包
-	hasActiveFuel := s.hasActiveFuel(apiKey)
-	if hasActiveFuel {
+	// 活跃的加油包可绕过限制，无需读取成本
+	if s.hasActiveFuel(apiKey) {
 		return &RateLimitCostResult{
 			Allowed:       true,
 			HasActiveFuel: true,
@@ -529,37 +755,71 @@ func (s *Service) CheckRateLimitCost(ctx context.Context, apiKey *redis.APIKey)
 		return &RateLimitCostResult{Allowed: true}, nil
 	}
 
-	resetAt := time.Now().Add(time.Duration(windowMinutes) * time.Minute)
-
-	if currentCost >= costLimit {
-		return &RateLimitCostResult{
-			Allowed:       false,
-			CurrentCost:   currentCost,
-			CostLimit:     costLimit,
-			WindowMinutes: windowMinutes,
-			ResetAt:       resetAt,
-		}, nil
-	}
-
+	now := time.Now()
+	decision := decideCostLimit(apiKey, currentCost, costLimit, true, now)
 	return &RateLimitCostResult{
-		Allowed:       true,
+		Allowed:       decision.allowed,
 		CurrentCost:   currentCost,
 		CostLimit:     costLimit,
 		WindowMinutes: windowMinutes,
-		ResetAt:       resetAt,
+		ResetAt:       now.Add(time.Duration(windowMinutes) * time.Minute),
+		FuelStatus:    decision.fuelStatus,
 	}, nil
 }
 
-// hasActiveFuel 检查是否有活跃的加油包
-func (s *Service) hasActiveFuel(apiKey *redis.APIKey) bool {
-	if apiKey.FuelBalance <= 0 {
-		return false
+// 加油包状态（成本限制可由活跃的加油包绕过）
+const (
+	FuelStatusNone     = ""         // 从未购买加油包
+	FuelStatusActive   = "active"   // 有余额且未过期
+	FuelStatusDepleted = "depleted" // 余额已用尽
+	FuelStatusExpired  = "expired"  // 仍有余额但已过期
+)
+
+// costLimitDecision 成本限制判定结果
+type costLimitDecision struct {
+	allowed    bool
+	bypassed   bool   // 活跃的加油包绕过了限制
+	warning    bool   // 已超过软限制预警阈值（仍放行）
+	fuelStatus string // 被拒绝时的加油包状态
+}
+
+// decideCostLimit 成本限制判定（实际检查与模拟共用）
+// 请求前用量达到上限即拒绝；fuelBypass 为 true 的限制在加油包有效时放行
+func decideCostLimit(apiKey *redis.APIKey, current, limit float64, fuelBypass bool, now time.Time) costLimitDecision {
+	fuelStatus := fuelStatusAt(apiKey, now)
+	if fuelBypass && fuelStatus == FuelStatusActive {
+		return costLimitDecision{allowed: true, bypassed: true}
 	}
-	if apiKey.FuelNextExpiresAtMs <= 0 {
-		return false
+	if current >= limit {
+		if !fuelBypass {
+			fuelStatus = FuelStatusNone
+		}
+		return costLimitDecision{fuelStatus: fuelStatus}
 	}
-	now := time.Now().UnixMilli()
-	return apiKey.FuelNextExpiresAtMs > now
+	return costLimitDecision{allowed: true, warning: crossesWarningThreshold(current, limit, limitWarningPercent(apiKey))}
+}
+
+// hasActiveFuel 检查是否有活跃的加油包
+func (s *Service) hasActiveFuel(apiKey *redis.APIKey) bool {
+	return fuelStatusAt(apiKey, time.Now()) == FuelStatusActive
+}
+
+// fuelStatusAt 获取指定时间的加油包状态
+func fuelStatusAt(apiKey *redis.APIKey, now time.Time) string {
+	switch {
+	case apiKey.FuelBalance > 0 && apiKey.FuelNextExpiresAtMs > now.UnixMilli():
+		return FuelStatusActive
+	case apiKey.FuelBalance > 0:
+		return FuelStatusExpired
+	case apiKey.FuelEntries > 0 || apiKey.FuelNextExpiresAtMs > 0:
+		return FuelStatusDepleted
+	}
+	return FuelStatusNone
+}
+
+// IsFuelExhausted 加油包是否已耗尽或过期（此时成本限制拒绝应提示充值）
+func IsFuelExhausted(fuelStatus string) bool {
+	return fuelStatus == FuelStatusDepleted || fuelStatus == FuelStatusExpired
 }
 
 // isOpusModel 检查是否为 Opus 模型
@@ -573,19 +833,9 @@ func isOpusModel(model string) bool {
 		strings.Contains(modelLower, "opus")
 }
 
-// getNextMondayMidnight 获取下周一凌晨时间
+// getNextMondayMidnight 获取 Opus 周成本的重置时间（统计时区的下周一零点，与周成本统计桶一致）
 func getNextMondayMidnight() time.Time {
-	now := time.Now()
-	dayOfWeek := int(now.Weekday())
-	if dayOfWeek == 0 {
-		dayOfWeek = 7 // 周日
-	}
-	daysUntilMonday := (8 - dayOfWeek) % 7
-	if daysUntilMonday == 0 {
-		daysUntilMonday = 7 // 已是周一，返回下周一
-	}
-	nextMonday := now.AddDate(0, 0, daysUntilMonday)
-	return time.Date(nextMonday.Year(), nextMonday.Month(), nextMonday.Day(), 0, 0, 0, 0, now.Location())
+	return redis.NextWeeklyReset(time.Now())
 }
 
 // CheckDailyCostLimitWithFuel 检查每日成本限制（带加油包支持）
@@ -596,7 +846,7 @@ func (s *Service) CheckDailyCostLimitWithFuel(ctx context.Context, apiKey *redis
 		return &CostLimitResult{Allowed: true, LimitType: "daily"}, nil
 	}
 
-	// 检查是否有活跃的加油包
+	// 活跃的加油包可绕过限制，无需读取成本
 	if s.hasActiveFuel(apiKey) {
 		return &CostLimitResult{Allowed: true, LimitType: "daily"}, nil
 	}
@@ -607,19 +857,13 @@ func (s *Service) CheckDailyCostLimitWithFuel(ctx context.Context, apiKey *redis
 		return &CostLimitResult{Allowed: true, LimitType: "daily"}, nil
 	}
 
-	if dailyCost >= dailyLimit {
-		return &CostLimitResult{
-			Allowed:     false,
-			CurrentCost: dailyCost,
-			DailyLimit:  dailyLimit,
-			LimitType:   "daily",
-		}, nil
-	}
-
+	decision := decideCostLimit(apiKey, dailyCost, dailyLimit, true, time.Now())
 	return &CostLimitResult{
-		Allowed:     true,
+		Allowed:     decision.allowed,
 		CurrentCost: dailyCost,
 		DailyLimit:  dailyLimit,
 		LimitType:   "daily",
+		Warning:     decision.warning,
+		FuelStatus:  decision.fuelStatus,
 	}, nil
 }
@@ -0,0 +1,206 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestEvaluateRateLimitWindow_WarnThenReject(t *testing.T) {
+	resetAt := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		count       int64
+		wantAllowed bool
+		wantWarning bool
+	}{
+		{count: 7, wantAllowed: true, wantWarning: false},
+		{count: 8, wantAllowed: true, wantWarning: true},
+		{count: 10, wantAllowed: true, wantWarning: true},
+		{count: 11, wantAllowed: false, wantWarning: false},
+	}
+
+	for _, tt := range tests {
+		result := evaluateRateLimitWindow("minute", tt.count, 10, resetAt, 80)
+		if result.Allowed != tt.wantAllowed || result.Warning != tt.wantWarning {
+			t.Errorf("count=%d: Allowed=%v Warning=%v, want %v %v",
+				tt.count, result.Allowed, result.Warning, tt.wantAllowed, tt.wantWarning)
+		}
+	}
+
+	if evaluateRateLimitWindow("minute", 9, 10, resetAt, 0).Warning {
+		t.Error("warning must be disabled when threshold is 0")
+	}
+}
+
+func TestLimitWarningPercent_KeyOverridesGlobal(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = nil
+	if got := limitWarningPercent(&redis.APIKey{}); got != 0 {
+		t.Errorf("without config = %d, want 0", got)
+	}
+
+	config.Cfg = &config.Config{Security: config.SecurityConfig{LimitWarningPercent: 80}}
+	if got := limitWarningPercent(&redis.APIKey{}); got != 80 {
+		t.Errorf("global = %d, want 80", got)
+	}
+	if got := limitWarningPercent(&redis.APIKey{LimitWarningPercent: 50}); got != 50 {
+		t.Errorf("per-key = %d, want 50", got)
+	}
+	if got := limitWarningPercent(&redis.APIKey{LimitWarningPercent: 100}); got != 0 {
+		t.Errorf("threshold at 100%% = %d, want disabled", got)
+	}
+	if got := limitWarningPercent(&redis.APIKey{LimitWarningPercent: 50, LimitWarningsDisabled: true}); got != 0 {
+		t.Errorf("opted-out key = %d, want disabled", got)
+	}
+}
+
+func TestCrossesWarningThreshold_DailyCost(t *testing.T) {
+	if !crossesWarningThreshold(8.5, 10, 80) {
+		t.Error("8.5 of 10 should cross 80% threshold")
+	}
+	if crossesWarningThreshold(7.9, 10, 80) {
+		t.Error("7.9 of 10 should not cross 80% threshold")
+	}
+}
+
+func TestGlobalConcurrencyLimit(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = nil
+	if got := GlobalConcurrencyLimit(); got != 0 {
+		t.Errorf("without config = %d, want 0", got)
+	}
+
+	config.Cfg = &config.Config{System: config.SystemConfig{GlobalConcurrencyLimit: 200}}
+	if got := GlobalConcurrencyLimit(); got != 200 {
+		t.Errorf("configured = %d, want 200", got)
+	}
+}
+
+func TestQueueEnabled_GlobalSoftCap(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	plain := &redis.APIKey{ID: "plain", ConcurrentLimit: 1}
+	optedOut := &redis.APIKey{ID: "opted-out", ConcurrentLimit: 1, ConcurrentRequestQueueMode: redis.ConcurrentRequestQueueModeDisabled}
+	explicit := &redis.APIKey{ID: "explicit", ConcurrentLimit: 1, ConcurrentRequestQueueEnabled: true}
+
+	config.Cfg = &config.Config{}
+	if QueueEnabled(plain) || QueueEnabled(optedOut) || !QueueEnabled(explicit) {
+		t.Error("without soft cap only keys with queueing enabled should queue")
+	}
+
+	config.Cfg = &config.Config{System: config.SystemConfig{ConcurrencySoftCap: true, ConcurrencySoftCapTimeout: 1500 * time.Millisecond}}
+	if !QueueEnabled(plain) {
+		t.Error("soft cap should queue keys that did not opt out")
+	}
+	if got := queueTimeout(plain); got != 1500*time.Millisecond {
+		t.Errorf("soft cap queue timeout = %v, want 1.5s", got)
+	}
+	if QueueEnabled(optedOut) {
+		t.Error("opted-out key should be rejected immediately")
+	}
+	// 显式开启排队的 Key 保持原有的排队时长
+	if got := queueTimeout(explicit); got != defaultQueueTimeout {
+		t.Errorf("explicit queue timeout = %v, want %v", got, defaultQueueTimeout)
+	}
+	if got := queueTimeout(&redis.APIKey{ConcurrentRequestQueueTimeoutMs: 500}); got != 500*time.Millisecond {
+		t.Errorf("key queue timeout = %v, want 500ms", got)
+	}
+
+	// 退出的 Key 不进入队列（不访问 Redis）
+	s := &Service{}
+	if result := s.WaitInQueue(context.Background(), optedOut, "req-1"); result.Success || result.TimeoutReason != "queue_disabled" {
+		t.Errorf("opted-out wait = %+v, want queue_disabled", result)
+	}
+}
+
+func TestNewPriorityWaiter_QueueScope(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	apiKey := &redis.APIKey{ID: "key-1", SchedulingPriority: 5}
+	now := time.Now()
+
+	config.Cfg = &config.Config{System: config.SystemConfig{ConcurrencyQueueMode: QueueModePriority}}
+	if got := ConcurrencyQueueMode(); got != QueueModePriority {
+		t.Errorf("ConcurrencyQueueMode() = %q, want priority", got)
+	}
+	if waiter := newPriorityWaiter(apiKey, "req-1", now, now.Add(time.Second)); waiter.Key != redis.PriorityQueueKey("key-1") {
+		t.Errorf("per-key queue = %q", waiter.Key)
+	}
+
+	config.Cfg.System.GlobalConcurrencyLimit = 10
+	if waiter := newPriorityWaiter(apiKey, "req-1", now, now.Add(time.Second)); waiter.Key != redis.KeyGlobalPriorityQueue {
+		t.Errorf("global queue = %q, want %q", waiter.Key, redis.KeyGlobalPriorityQueue)
+	}
+
+	config.Cfg.System.ConcurrencyQueueMode = "unknown"
+	if got := ConcurrencyQueueMode(); got != QueueModeSimple {
+		t.Errorf("unknown mode = %q, want simple", got)
+	}
+}
+
+func TestReleaseOutcome_DoubleReleaseIsNoOpUnlessStrict(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = &config.Config{}
+	if err := releaseOutcome("key-1", "req-1", true); err != nil {
+		t.Errorf("first release error = %v, want nil", err)
+	}
+	if err := releaseOutcome("key-1", "req-1", false); err != nil {
+		t.Errorf("double release error = %v, want nil (no-op)", err)
+	}
+
+	config.Cfg = &config.Config{System: config.SystemConfig{StrictConcurrencyRelease: true}}
+	if err := releaseOutcome("key-1", "req-1", false); !errors.Is(err, ErrConcurrencySlotNotHeld) {
+		t.Errorf("strict double release error = %v, want ErrConcurrencySlotNotHeld", err)
+	}
+}
+
+func TestBoostedConcurrencyLimit(t *testing.T) {
+	if got := boostedConcurrencyLimit(5, nil); got != 5 {
+		t.Errorf("no boost = %d, want static limit 5", got)
+	}
+	if got := boostedConcurrencyLimit(5, &redis.ConcurrencyBoost{Limit: 20}); got != 20 {
+		t.Errorf("active boost = %d, want elevated limit 20", got)
+	}
+	// 静态上限已调高到临时上限之上时不再降低
+	if got := boostedConcurrencyLimit(30, &redis.ConcurrencyBoost{Limit: 20}); got != 30 {
+		t.Errorf("lower boost = %d, want static limit 30", got)
+	}
+}
+
+func TestFuelStatusAt_DistinguishesDepletedAndExpired(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour).UnixMilli()
+	past := now.Add(-time.Hour).UnixMilli()
+
+	tests := []struct {
+		name string
+		key  redis.APIKey
+		want string
+	}{
+		{"never purchased", redis.APIKey{}, FuelStatusNone},
+		{"active", redis.APIKey{FuelBalance: 3, FuelEntries: 1, FuelNextExpiresAtMs: future}, FuelStatusActive},
+		{"depleted", redis.APIKey{FuelEntries: 2, FuelNextExpiresAtMs: future}, FuelStatusDepleted},
+		{"expired", redis.APIKey{FuelBalance: 3, FuelEntries: 1, FuelNextExpiresAtMs: past}, FuelStatusExpired},
+	}
+	for _, tt := range tests {
+		if got := fuelStatusAt(&tt.key, now); got != tt.want {
+			t.Errorf("%s: fuelStatusAt() = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := IsFuelExhausted(fuelStatusAt(&tt.key, now)); got != (tt.want == FuelStatusDepleted || tt.want == FuelStatusExpired) {
+			t.Errorf("%s: IsFuelExhausted() = %v", tt.name, got)
+		}
+	}
+}
@@ -60,8 +60,10 @@ var AccountCategoryMap = map[string]string{
 
 // Service API Key 服务
 type Service struct {
-	redis  *redis.Client
-	prefix string
+	redis        *redis.Client
+	prefix       string
+	cache        *keyCache
+	userDefaults *userDefaultsCache
 }
 
 // NewService 创建 API Key 服务
@@ -71,8 +73,10 @@ func NewService(redisClient *redis.Client) *Service {
 		prefix = config.Cfg.Security.APIKeyPrefix
 	}
 	return &Service{
-		redis:  redisClient,
-		prefix: prefix,
+		redis:        redisClient,
+		prefix:       prefix,
+		cache:        newKeyCache(),
+		userDefaults: newUserDefaultsCache(),
 	}
 }
 
@@ -94,6 +98,7 @@ type GenerateOptions struct {
 	Tags                                    []string
 	ActivationDays                          int
 	ConcurrentRequestQueueEnabled           bool
+	ConcurrentRequestQueueMode              string
 	ConcurrentRequestQueueMaxSize           int
 	ConcurrentRequestQueueMaxSizeMultiplier float64
 	ConcurrentRequestQueueTimeoutMs         int
@@ -132,6 +137,7 @@ func (s *Service) GenerateAPIKey(ctx context.Context, opts GenerateOptions) (*re
 
 		// 并发排队配置
 		ConcurrentRequestQueueEnabled:           opts.ConcurrentRequestQueueEnabled,
+		ConcurrentRequestQueueMode:              opts.ConcurrentRequestQueueMode,
 		ConcurrentRequestQueueMaxSize:           opts.ConcurrentRequestQueueMaxSize,
 		ConcurrentRequestQueueMaxSizeMultiplier: opts.ConcurrentRequestQueueMaxSizeMultiplier,
 		ConcurrentRequestQueueTimeoutMs:         opts.ConcurrentRequestQueueTimeoutMs,
@@ -172,16 +178,19 @@ func (s *Service) GetAPIKeyByRawKey(ctx context.Context, rawKey string) (*redis.
 
 // UpdateAPIKey 更新 API Key
 func (s *Service) UpdateAPIKey(ctx context.Context, keyID string, updates map[string]interface{}) error {
+	defer s.cache.invalidate(keyID)
 	return s.redis.UpdateAPIKeyFields(ctx, keyID, updates)
 }
 
 // DeleteAPIKey 软删除 API Key
 func (s *Service) DeleteAPIKey(ctx context.Context, keyID string) error {
+	defer s.cache.invalidate(keyID)
 	return s.redis.DeleteAPIKey(ctx, keyID)
 }
 
 // HardDeleteAPIKey 硬删除 API Key
 func (s *Service) HardDeleteAPIKey(ctx context.Context, keyID string) error {
+	defer s.cache.invalidate(keyID)
 	return s.redis.HardDeleteAPIKey(ctx, keyID)
 }
 
@@ -196,8 +205,8 @@ func (s *Service) GetAPIKeysPaginated(ctx context.Context, opts redis.APIKeyQuer
 }
 
 // GetAPIKeyStats 获取 API Key 统计
-func (s *Service) GetAPIKeyStats(ctx context.Context) (*redis.APIKeyStats, error) {
-	return s.redis.GetAPIKeyStats(ctx)
+func (s *Service) GetAPIKeyStats(ctx context.Context, excludeTest bool) (*redis.APIKeyStats, error) {
+	return s.redis.GetAPIKeyStats(ctx, excludeTest)
 }
 
 // HashAPIKey 计算 API Key 的 SHA256 哈希
@@ -272,6 +281,7 @@ func (s *Service) RestoreAPIKey(ctx context.Context, keyID string) error {
 	if err := s.redis.UpdateAPIKeyFields(ctx, keyID, updates); err != nil {
 		return fmt.Errorf("failed to restore API key: %w", err)
 	}
+	s.cache.invalidate(keyID)
 
 	logger.Info("API Key restored",
 		zap.String("id", keyID),
@@ -285,18 +295,7 @@ func (s *Service) activateAPIKey(ctx context.Context, apiKey *redis.APIKey) erro
 	now := time.Now()
 
 	// 计算激活有效期
-	activationDays := apiKey.ActivationDays
-	if activationDays <= 0 {
-		activationDays = 30 // 默认 30 天
-	}
-
-	var expiresAt time.Time
-	if apiKey.ActivationUnit == "hours" {
-		expiresAt = now.Add(time.Duration(activationDays) * time.Hour)
-	} else {
-		// 默认按天计算
-		expiresAt = now.AddDate(0, 0, activationDays)
-	}
+	expiresAt := redis.ActivationExpiry(now, apiKey.ActivationDays, apiKey.ActivationUnit)
 
 	// 更新 API Key 状态
 	updates := map[string]interface{}{
@@ -308,6 +307,7 @@ func (s *Service) activateAPIKey(ctx context.Context, apiKey *redis.APIKey) erro
 	if err := s.redis.UpdateAPIKeyFields(ctx, apiKey.ID, updates); err != nil {
 		return fmt.Errorf("failed to activate API key: %w", err)
 	}
+	s.cache.invalidate(apiKey.ID)
 
 	// 更新内存中的对象
 	apiKey.IsActivated = true
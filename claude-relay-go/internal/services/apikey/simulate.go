@@ -0,0 +1,217 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// 模拟检查的限制名称
+const (
+	SimulateLimitRateMinute        = "rate_minute"
+	SimulateLimitRateHour          = "rate_hour"
+	SimulateLimitConcurrency       = "concurrency"
+	SimulateLimitGlobalConcurrency = "global_concurrency"
+	SimulateLimitDailyCost         = "daily_cost"
+	SimulateLimitTotalCost         = "total_cost"
+	SimulateLimitWeeklyOpusCost    = "weekly_opus_cost"
+	SimulateLimitRateLimitCost     = "rate_limit_cost"
+)
+
+// LimitSimulation 单项限制的模拟结果
+type LimitSimulation struct {
+	Name      string  `json:"name"`
+	Allowed   bool    `json:"allowed"`
+	Current   float64 `json:"current"`   // 请求前用量
+	Projected float64 `json:"projected"` // 请求后预计用量
+	Limit     float64 `json:"limit"`
+	Warning   bool    `json:"warning,omitempty"`
+	Bypassed  bool    `json:"bypassed,omitempty"` // 加油包生效，跳过该限制
+}
+
+// SimulationResult 限制模拟结果
+type SimulationResult struct {
+	Allowed       bool              `json:"allowed"`
+	EstimatedCost float64           `json:"estimatedCost"`
+	RejectedBy    []string          `json:"rejectedBy"`
+	Checks        []LimitSimulation `json:"checks"`
+}
+
+// limitSnapshot 模拟所需的当前用量
+type limitSnapshot struct {
+	minuteCount       int64
+	hourCount         int64
+	concurrency       int64
+	concurrencyLimit  int // 当前生效的并发上限（含临时提升）
+	globalConcurrency int64
+	dailyCost         float64
+	totalCost         float64
+	weeklyOpusCost    float64
+	windowCost        float64
+}
+
+// SimulateLimits 只读模拟一次请求的全部限制检查（不计数、不占用槽位）
+// estimatedCost 为请求的预计成本，用于计算请求后的预计用量
+func (s *Service) SimulateLimits(ctx context.Context, apiKey *redis.APIKey, model string, estimatedCost float64) (*SimulationResult, error) {
+	// 与验证时一致，未设置的限制按用户级默认值模拟
+	inherited := *apiKey
+	s.applyUserDefaults(ctx, &inherited)
+	apiKey = &inherited
+
+	now := time.Now()
+	snapshot, err := s.loadLimitSnapshot(ctx, apiKey, model, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluateSimulation(apiKey, model, estimatedCost, snapshot, now), nil
+}
+
+// loadLimitSnapshot 只读获取已配置限制的当前用量
+func (s *Service) loadLimitSnapshot(ctx context.Context, apiKey *redis.APIKey, model string, now time.Time) (*limitSnapshot, error) {
+	snapshot := &limitSnapshot{}
+	var err error
+
+	if apiKey.RateLimitPerMin > 0 {
+		if snapshot.minuteCount, err = s.redis.GetCounter(ctx, rateLimitWindowKey(apiKey.ID, "minute", time.Minute, now)); err != nil {
+			return nil, fmt.Errorf("failed to get minute rate limit count: %w", err)
+		}
+	}
+	if apiKey.RateLimitPerHour > 0 {
+		if snapshot.hourCount, err = s.redis.GetCounter(ctx, rateLimitWindowKey(apiKey.ID, "hour", time.Hour, now)); err != nil {
+			return nil, fmt.Errorf("failed to get hour rate limit count: %w", err)
+		}
+	}
+	if apiKey.ConcurrentLimit > 0 {
+		if snapshot.concurrency, err = s.redis.GetConcurrency(ctx, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to get concurrency: %w", err)
+		}
+		snapshot.concurrencyLimit = s.EffectiveConcurrencyLimit(ctx, apiKey)
+	}
+	if GlobalConcurrencyLimit() > 0 {
+		if snapshot.globalConcurrency, err = s.redis.GetGlobalConcurrency(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get global concurrency: %w", err)
+		}
+	}
+	if apiKey.DailyCostLimit > 0 {
+		if snapshot.dailyCost, err = s.redis.GetDailyCost(ctx, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to get daily cost: %w", err)
+		}
+	}
+	if apiKey.TotalCostLimit > 0 {
+		costStats, err := s.redis.GetTotalCost(ctx, apiKey.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get total cost: %w", err)
+		}
+		snapshot.totalCost = costStats.TotalCost
+	}
+	if apiKey.WeeklyOpusCostLimit > 0 && isOpusModel(model) {
+		if snapshot.weeklyOpusCost, err = s.redis.GetWeeklyOpusCost(ctx, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to get weekly opus cost: %w", err)
+		}
+	}
+	if apiKey.RateLimitWindow > 0 && apiKey.RateLimitCost > 0 {
+		if snapshot.windowCost, err = s.redis.GetRateLimitWindowCost(ctx, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to get rate limit window cost: %w", err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// evaluateSimulation 使用实际检查的判定函数（evaluateRateLimitWindow、concurrencyAllowed、decideCostLimit）评估各项限制
+func (s *Service) evaluateSimulation(apiKey *redis.APIKey, model string, estimatedCost float64, snapshot *limitSnapshot, now time.Time) *SimulationResult {
+	warnPercent := limitWarningPercent(apiKey)
+	result := &SimulationResult{
+		Allowed:       true,
+		EstimatedCost: estimatedCost,
+		RejectedBy:    []string{},
+		Checks:        []LimitSimulation{},
+	}
+
+	add := func(check LimitSimulation) {
+		if !check.Allowed {
+			result.Allowed = false
+			result.RejectedBy = append(result.RejectedBy, check.Name)
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	// 速率限制：本次请求计入后的窗口计数
+	rateWindows := []struct {
+		name     string
+		window   string
+		limit    int
+		count    int64
+		duration time.Duration
+	}{
+		{SimulateLimitRateMinute, "minute", apiKey.RateLimitPerMin, snapshot.minuteCount, time.Minute},
+		{SimulateLimitRateHour, "hour", apiKey.RateLimitPerHour, snapshot.hourCount, time.Hour},
+	}
+	for _, w := range rateWindows {
+		if w.limit <= 0 {
+			continue
+		}
+		resetAt := now.Truncate(w.duration).Add(w.duration)
+		evaluated := evaluateRateLimitWindow(w.window, w.count+1, int64(w.limit), resetAt, warnPercent)
+		add(LimitSimulation{
+			Name:      w.name,
+			Allowed:   evaluated.Allowed,
+			Current:   float64(w.count),
+			Projected: float64(w.count + 1),
+			Limit:     float64(w.limit),
+			Warning:   evaluated.Warning,
+		})
+	}
+
+	if apiKey.ConcurrentLimit > 0 {
+		add(LimitSimulation{
+			Name:      SimulateLimitConcurrency,
+			Allowed:   concurrencyAllowed(snapshot.concurrency, snapshot.concurrencyLimit),
+			Current:   float64(snapshot.concurrency),
+			Projected: float64(snapshot.concurrency + 1),
+			Limit:     float64(snapshot.concurrencyLimit),
+		})
+	}
+	if globalLimit := GlobalConcurrencyLimit(); globalLimit > 0 {
+		add(LimitSimulation{
+			Name:      SimulateLimitGlobalConcurrency,
+			Allowed:   concurrencyAllowed(snapshot.globalConcurrency, globalLimit),
+			Current:   float64(snapshot.globalConcurrency),
+			Projected: float64(snapshot.globalConcurrency + 1),
+			Limit:     float64(globalLimit),
+		})
+	}
+
+	// 成本限制：请求前用量达到上限即拒绝，部分限制可由加油包绕过
+	costLimits := []struct {
+		name       string
+		enabled    bool
+		current    float64
+		limit      float64
+		fuelBypass bool
+	}{
+		{SimulateLimitDailyCost, apiKey.DailyCostLimit > 0, snapshot.dailyCost, apiKey.DailyCostLimit, true},
+		{SimulateLimitTotalCost, apiKey.TotalCostLimit > 0, snapshot.totalCost, apiKey.TotalCostLimit, true},
+		{SimulateLimitWeeklyOpusCost, apiKey.WeeklyOpusCostLimit > 0 && isOpusModel(model), snapshot.weeklyOpusCost, apiKey.WeeklyOpusCostLimit, false},
+		{SimulateLimitRateLimitCost, apiKey.RateLimitWindow > 0 && apiKey.RateLimitCost > 0, snapshot.windowCost, apiKey.RateLimitCost, true},
+	}
+	for _, c := range costLimits {
+		if !c.enabled {
+			continue
+		}
+		decision := decideCostLimit(apiKey, c.current, c.limit, c.fuelBypass, now)
+		add(LimitSimulation{
+			Name:      c.name,
+			Allowed:   decision.allowed,
+			Current:   c.current,
+			Projected: redis.SumCosts(c.current, estimatedCost),
+			Limit:     c.limit,
+			Warning:   decision.warning,
+			Bypassed:  decision.bypassed,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestEvaluateSimulation_AllChecksPass(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	apiKey := &redis.APIKey{
+		ID:              "key-1",
+		RateLimitPerMin: 10,
+		ConcurrentLimit: 2,
+		DailyCostLimit:  5,
+		TotalCostLimit:  100,
+		RateLimitWindow: 60,
+		RateLimitCost:   3,
+	}
+	snapshot := &limitSnapshot{minuteCount: 3, concurrency: 1, concurrencyLimit: 2, dailyCost: 1.5, totalCost: 20, windowCost: 0.5}
+
+	s := &Service{}
+	result := s.evaluateSimulation(apiKey, "claude-sonnet-4", 0.25, snapshot, time.Now())
+
+	if !result.Allowed || len(result.RejectedBy) != 0 {
+		t.Fatalf("Allowed = %v, RejectedBy = %v; want allowed", result.Allowed, result.RejectedBy)
+	}
+	// 未配置的小时限制与非 Opus 模型的周限制不参与模拟
+	if len(result.Checks) != 5 {
+		t.Fatalf("got %d checks, want 5: %+v", len(result.Checks), result.Checks)
+	}
+
+	byName := make(map[string]LimitSimulation, len(result.Checks))
+	for _, check := range result.Checks {
+		byName[check.Name] = check
+	}
+	if got := byName[SimulateLimitRateMinute]; got.Current != 3 || got.Projected != 4 {
+		t.Errorf("rate_minute = %+v, want current 3 projected 4", got)
+	}
+	if got := byName[SimulateLimitDailyCost]; got.Projected != 1.75 {
+		t.Errorf("daily_cost projected = %v, want 1.75", got.Projected)
+	}
+}
+
+func TestEvaluateSimulation_DailyCostLimitTrips(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	apiKey := &redis.APIKey{ID: "key-1", RateLimitPerMin: 10, DailyCostLimit: 5}
+	snapshot := &limitSnapshot{minuteCount: 1, dailyCost: 5}
+
+	s := &Service{}
+	result := s.evaluateSimulation(apiKey, "claude-sonnet-4", 0.4, snapshot, time.Now())
+
+	if result.Allowed {
+		t.Fatal("expected simulation to be rejected by the daily cost limit")
+	}
+	if len(result.RejectedBy) != 1 || result.RejectedBy[0] != SimulateLimitDailyCost {
+		t.Fatalf("RejectedBy = %v, want [%s]", result.RejectedBy, SimulateLimitDailyCost)
+	}
+	for _, check := range result.Checks {
+		if check.Name == SimulateLimitDailyCost && check.Projected != 5.4 {
+			t.Errorf("daily_cost projected = %v, want 5.4", check.Projected)
+		}
+	}
+
+	// 加油包生效时跳过每日成本限制
+	apiKey.FuelBalance = 10
+	apiKey.FuelNextExpiresAtMs = time.Now().Add(time.Hour).UnixMilli()
+	if result := s.evaluateSimulation(apiKey, "claude-sonnet-4", 0.4, snapshot, time.Now()); !result.Allowed {
+		t.Errorf("active fuel should bypass the daily cost limit, RejectedBy = %v", result.RejectedBy)
+	}
+}
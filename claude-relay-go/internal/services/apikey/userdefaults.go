@@ -0,0 +1,94 @@
+package apikey
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// userDefaultsCacheTTL 用户级默认限制的进程内缓存时长
+const userDefaultsCacheTTL = 30 * time.Second
+
+// userDefaultsCache 用户级默认限制的进程内缓存（按用户 ID 索引，未设置也会缓存）
+type userDefaultsCache struct {
+	mu      sync.RWMutex
+	entries map[string]userDefaultsCacheEntry
+}
+
+type userDefaultsCacheEntry struct {
+	defaults  *redis.UserLimitDefaults
+	expiresAt time.Time
+}
+
+func newUserDefaultsCache() *userDefaultsCache {
+	return &userDefaultsCache{entries: make(map[string]userDefaultsCacheEntry)}
+}
+
+// get 获取未过期的缓存（ok 为 false 表示需要重新读取）
+func (c *userDefaultsCache) get(userID string, now time.Time) (*redis.UserLimitDefaults, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	entry, ok := c.entries[userID]
+	c.mu.RUnlock()
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.defaults, true
+}
+
+func (c *userDefaultsCache) put(userID string, defaults *redis.UserLimitDefaults, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries[userID] = userDefaultsCacheEntry{defaults: defaults, expiresAt: now.Add(userDefaultsCacheTTL)}
+	c.mu.Unlock()
+}
+
+func (c *userDefaultsCache) invalidate(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// lookupUserDefaults 获取用户级默认限制（优先使用进程内缓存）
+func (s *Service) lookupUserDefaults(ctx context.Context, userID string) (*redis.UserLimitDefaults, error) {
+	now := time.Now()
+	if defaults, ok := s.userDefaults.get(userID, now); ok {
+		return defaults, nil
+	}
+	defaults, err := s.redis.GetUserLimitDefaults(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.userDefaults.put(userID, defaults, now)
+	return defaults, nil
+}
+
+// applyUserDefaults 为关联用户的 API Key 填入未设置的限制（读取失败时保留 Key 自身的限制）
+func (s *Service) applyUserDefaults(ctx context.Context, apiKey *redis.APIKey) {
+	if apiKey == nil || apiKey.UserID == "" {
+		return
+	}
+	defaults, err := s.lookupUserDefaults(ctx, apiKey.UserID)
+	if err != nil {
+		logger.Warn("Failed to get user limit defaults", zap.String("userId", apiKey.UserID), zap.Error(err))
+		return
+	}
+	defaults.ApplyTo(apiKey)
+}
+
+// SetUserLimitDefaults 设置用户级默认限制并清除本进程缓存
+func (s *Service) SetUserLimitDefaults(ctx context.Context, userID string, defaults *redis.UserLimitDefaults) error {
+	defer s.userDefaults.invalidate(userID)
+	return s.redis.SetUserLimitDefaults(ctx, userID, defaults)
+}
@@ -0,0 +1,54 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestApplyUserDefaults_InheritsUnsetLimits(t *testing.T) {
+	s := &Service{userDefaults: newUserDefaultsCache()}
+	s.userDefaults.put("user-1", &redis.UserLimitDefaults{ConcurrentLimit: 5, DailyCostLimit: 20}, time.Now())
+	ctx := context.Background()
+
+	inherited := &redis.APIKey{ID: "k1", UserID: "user-1"}
+	s.applyUserDefaults(ctx, inherited)
+	if inherited.ConcurrentLimit != 5 || inherited.DailyCostLimit != 20 {
+		t.Errorf("inherited = %+v, want user defaults", inherited)
+	}
+
+	overridden := &redis.APIKey{ID: "k2", UserID: "user-1", ConcurrentLimit: 1}
+	s.applyUserDefaults(ctx, overridden)
+	if overridden.ConcurrentLimit != 1 || overridden.DailyCostLimit != 20 {
+		t.Errorf("overridden = %+v, want own concurrency limit", overridden)
+	}
+
+	// 未关联用户的 Key 不读取默认值
+	standalone := &redis.APIKey{ID: "k3"}
+	s.applyUserDefaults(ctx, standalone)
+	if standalone.ConcurrentLimit != 0 {
+		t.Errorf("standalone = %+v, want no inherited limits", standalone)
+	}
+}
+
+func TestUserDefaultsCache_Expires(t *testing.T) {
+	cache := newUserDefaultsCache()
+	now := time.Now()
+
+	// 未设置默认值的用户同样缓存，避免每次验证都读取 Redis
+	cache.put("user-1", nil, now)
+	if _, ok := cache.get("user-1", now); !ok {
+		t.Error("expected nil defaults to be cached")
+	}
+	if _, ok := cache.get("user-1", now.Add(userDefaultsCacheTTL)); ok {
+		t.Error("expected cache entry to expire after TTL")
+	}
+
+	cache.put("user-2", &redis.UserLimitDefaults{ConcurrentLimit: 3}, now)
+	cache.invalidate("user-2")
+	if _, ok := cache.get("user-2", now); ok {
+		t.Error("invalidated entry should be removed")
+	}
+}
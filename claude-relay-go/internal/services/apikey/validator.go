@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/catstream/claude-relay-go/internal/config"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"go.uber.org/zap"
@@ -18,6 +19,34 @@ type ValidationResult struct {
 	Error      string
 	ErrorCode  string
 	StatusCode int
+	// Diagnostics 详细诊断信息（仅开发模式且显式请求时返回）
+	Diagnostics *ValidationDiagnostics
+	// Expiry 过期相关日期（仅过期类错误返回）
+	Expiry *ExpiryDetails
+}
+
+// 过期类错误码
+const (
+	ErrorCodeExpired           = "expired"            // 未区分原因的过期（LEGACY_EXPIRY_ERRORS）
+	ErrorCodeKeyNotActivated   = "key_not_activated"  // 激活模式 Key 激活失败，仍保留已过期的 expiresAt（如管理员手动设置）
+	ErrorCodeActivationExpired = "activation_expired" // 激活模式 Key 激活后的有效期已过
+	ErrorCodeFixedExpired      = "fixed_expired"      // 固定过期时间已过
+)
+
+// ExpiryDetails 过期类错误的相关日期
+type ExpiryDetails struct {
+	ExpirationMode string     `json:"expirationMode"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	ActivatedAt    *time.Time `json:"activatedAt,omitempty"`
+	ActivationDays int        `json:"activationDays,omitempty"`
+	ActivationUnit string     `json:"activationUnit,omitempty"`
+}
+
+// ValidationDiagnostics 验证失败诊断信息
+type ValidationDiagnostics struct {
+	Check   string                 `json:"check"`             // 失败的检查项
+	Reason  string                 `json:"reason"`            // 失败原因
+	Details map[string]interface{} `json:"details,omitempty"` // 相关取值
 }
 
 // ValidationOptions 验证选项
@@ -28,50 +57,77 @@ type ValidationOptions struct {
 	SkipRateLimit      bool     // 跳过速率限制检查
 	SkipConcurrency    bool     // 跳过并发检查
 	SkipCostLimit      bool     // 跳过成本限制检查
+	Verbose            bool     // 返回详细诊断信息（生产环境下始终忽略）
+}
+
+// diagnosticsAllowed 是否允许返回诊断信息（生产环境或配置缺失时一律不允许）
+func diagnosticsAllowed() bool {
+	return config.Cfg != nil && config.Cfg.Server.Env != "production"
+}
+
+// withDiagnostics 按需附加诊断信息
+func withDiagnostics(opts ValidationOptions, check string, details map[string]interface{}, result *ValidationResult) *ValidationResult {
+	if !opts.Verbose || !diagnosticsAllowed() {
+		return result
+	}
+	result.Diagnostics = &ValidationDiagnostics{
+		Check:   check,
+		Reason:  result.Error,
+		Details: details,
+	}
+	return result
 }
 
 // ValidateAPIKey 验证 API Key
 func (s *Service) ValidateAPIKey(ctx context.Context, rawKey string, opts ValidationOptions) *ValidationResult {
 	// 1. 格式检查
 	if !strings.HasPrefix(rawKey, s.prefix) {
-		return &ValidationResult{
+		return withDiagnostics(opts, "format", map[string]interface{}{
+			"expectedPrefix": s.prefix,
+			"keyLength":      len(rawKey),
+		}, &ValidationResult{
 			Valid:      false,
 			Error:      fmt.Sprintf("Invalid API key format, expected prefix '%s'", s.prefix),
 			ErrorCode:  "invalid_format",
 			StatusCode: 401,
-		}
+		})
 	}
 
 	// 2. 查找 API Key
 	hashedKey := s.HashAPIKey(rawKey)
-	apiKey, err := s.redis.GetAPIKeyByHash(ctx, hashedKey)
+	apiKey, err := s.lookupAPIKey(ctx, hashedKey)
 	if err != nil {
-		return &ValidationResult{
+		return withDiagnostics(opts, "lookup", map[string]interface{}{
+			"error": err.Error(),
+		}, &ValidationResult{
 			Valid:      false,
 			Error:      "Failed to lookup API key",
 			ErrorCode:  "lookup_error",
 			StatusCode: 500,
-		}
+		})
 	}
 
 	if apiKey == nil {
-		return &ValidationResult{
+		return withDiagnostics(opts, "lookup", nil, &ValidationResult{
 			Valid:      false,
 			Error:      "API key not found",
 			ErrorCode:  "not_found",
 			StatusCode: 401,
-		}
+		})
 	}
 
 	// 3. 检查是否激活
 	if !apiKey.IsActive {
-		return &ValidationResult{
+		return withDiagnostics(opts, "active", map[string]interface{}{
+			"keyId":    apiKey.ID,
+			"isActive": apiKey.IsActive,
+		}, &ValidationResult{
 			Valid:      false,
 			APIKey:    apiKey,
 			Error:      "API key is inactive",
 			ErrorCode:  "inactive",
 			StatusCode: 403,
-		}
+		})
 	}
 
 	// 4. 检查激活模式
@@ -83,64 +139,81 @@ func (s *Service) ValidateAPIKey(ctx context.Context, rawKey string, opts Valida
 	}
 
 	// 5. 检查是否过期
-	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
-		return &ValidationResult{
-			Valid:      false,
-			APIKey:    apiKey,
-			Error:      "API key has expired",
-			ErrorCode:  "expired",
-			StatusCode: 403,
-		}
+	now := time.Now()
+	if result := checkExpiry(apiKey, now); result != nil {
+		return withDiagnostics(opts, "expiry", map[string]interface{}{
+			"keyId":          apiKey.ID,
+			"expiresAt":      apiKey.ExpiresAt.Format(time.RFC3339),
+			"now":            now.Format(time.RFC3339),
+			"expirationMode": apiKey.ExpirationMode,
+		}, result)
 	}
 
 	// 5. 检查是否被删除
 	if apiKey.IsDeleted {
-		return &ValidationResult{
+		return withDiagnostics(opts, "deleted", map[string]interface{}{
+			"keyId": apiKey.ID,
+		}, &ValidationResult{
 			Valid:      false,
 			APIKey:    apiKey,
 			Error:      "API key has been deleted",
 			ErrorCode:  "deleted",
 			StatusCode: 403,
-		}
+		})
 	}
 
 	// 6. 检查权限
 	if opts.RequiredPermission != "" && !s.CheckPermission(apiKey, opts.RequiredPermission) {
-		return &ValidationResult{
+		return withDiagnostics(opts, "permission", map[string]interface{}{
+			"keyId":              apiKey.ID,
+			"requiredPermission": opts.RequiredPermission,
+			"permissions":        apiKey.Permissions,
+		}, &ValidationResult{
 			Valid:      false,
 			APIKey:    apiKey,
 			Error:      fmt.Sprintf("API key does not have '%s' permission", opts.RequiredPermission),
 			ErrorCode:  "permission_denied",
 			StatusCode: 403,
-		}
+		})
 	}
 
 	// 7. 检查客户端限制
 	if len(apiKey.AllowedClients) > 0 && opts.ClientType != "" {
 		if !s.IsClientAllowed(apiKey.AllowedClients, opts.ClientType) {
-			return &ValidationResult{
+			return withDiagnostics(opts, "client", map[string]interface{}{
+				"keyId":          apiKey.ID,
+				"clientType":     opts.ClientType,
+				"allowedClients": apiKey.AllowedClients,
+			}, &ValidationResult{
 				Valid:      false,
 				APIKey:    apiKey,
 				Error:      fmt.Sprintf("Client '%s' is not allowed for this API key", opts.ClientType),
 				ErrorCode:  "client_not_allowed",
 				StatusCode: 403,
-			}
+			})
 		}
 	}
 
 	// 8. 检查模型黑名单
 	if len(apiKey.ModelBlacklist) > 0 && opts.Model != "" {
 		if s.IsModelBlacklisted(apiKey.ModelBlacklist, opts.Model) {
-			return &ValidationResult{
+			return withDiagnostics(opts, "model", map[string]interface{}{
+				"keyId":          apiKey.ID,
+				"model":          opts.Model,
+				"modelBlacklist": apiKey.ModelBlacklist,
+			}, &ValidationResult{
 				Valid:      false,
 				APIKey:    apiKey,
 				Error:      fmt.Sprintf("Model '%s' is blacklisted for this API key", opts.Model),
 				ErrorCode:  "model_blacklisted",
 				StatusCode: 403,
-			}
+			})
 		}
 	}
 
+	// 9. 未设置的限制继承用户级默认值
+	s.applyUserDefaults(ctx, apiKey)
+
 	// 验证通过
 	return &ValidationResult{
 		Valid:      true,
@@ -166,6 +239,11 @@ func (s *Service) CheckPermission(apiKey *redis.APIKey, required string) bool {
 
 // IsClientAllowed 检查客户端是否允许
 func (s *Service) IsClientAllowed(allowedClients []string, clientType string) bool {
+	return IsClientAllowed(allowedClients, clientType)
+}
+
+// IsClientAllowed 检查客户端类型是否匹配允许列表（支持 * / all 通配与 xxx* 前缀匹配，不区分大小写）
+func IsClientAllowed(allowedClients []string, clientType string) bool {
 	clientLower := strings.ToLower(clientType)
 	for _, allowed := range allowedClients {
 		allowedLower := strings.ToLower(allowed)
@@ -242,3 +320,43 @@ func (s *Service) QuickValidate(ctx context.Context, rawKey string) (bool, *redi
 
 	return true, apiKey
 }
+
+// checkExpiry 检查 API Key 是否过期，按过期模式与激活状态返回不同的错误码（未过期返回 nil）
+// 与 Node.js 一致，在首次使用激活之后调用：未激活的激活模式 Key 不设置 expiresAt，激活后才写入。
+func checkExpiry(apiKey *redis.APIKey, now time.Time) *ValidationResult {
+	if apiKey.ExpiresAt == nil || !now.After(*apiKey.ExpiresAt) {
+		return nil
+	}
+
+	result := &ValidationResult{
+		Valid:      false,
+		APIKey:     apiKey,
+		Error:      "API key has expired",
+		ErrorCode:  ErrorCodeExpired,
+		StatusCode: 403,
+	}
+	if config.Cfg != nil && config.Cfg.Security.LegacyExpiryErrors {
+		return result
+	}
+
+	result.Expiry = &ExpiryDetails{
+		ExpirationMode: apiKey.ExpirationMode,
+		ExpiresAt:      apiKey.ExpiresAt,
+		ActivatedAt:    apiKey.ActivatedAt,
+	}
+	switch {
+	case apiKey.ExpirationMode == "activation" && !apiKey.IsActivated:
+		result.Error = "API key could not be activated and has expired"
+		result.ErrorCode = ErrorCodeKeyNotActivated
+	case apiKey.ExpirationMode == "activation":
+		result.Error = "API key activation period has expired"
+		result.ErrorCode = ErrorCodeActivationExpired
+		result.Expiry.ActivationDays = apiKey.ActivationDays
+		result.Expiry.ActivationUnit = apiKey.ActivationUnit
+	default:
+		result.Expiry.ExpirationMode = "fixed"
+		result.Error = "API key has expired"
+		result.ErrorCode = ErrorCodeFixedExpired
+	}
+	return result
+}
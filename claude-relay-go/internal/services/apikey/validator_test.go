@@ -0,0 +1,120 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestValidateAPIKey_DiagnosticsInDevelopment(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = &config.Config{Server: config.ServerConfig{Env: "development"}}
+	s := &Service{prefix: "cr_"}
+
+	result := s.ValidateAPIKey(context.Background(), "sk-invalid", ValidationOptions{Verbose: true})
+	if result.Valid {
+		t.Fatal("expected validation failure")
+	}
+	if result.Diagnostics == nil {
+		t.Fatal("expected diagnostics in development mode")
+	}
+	if result.Diagnostics.Check != "format" {
+		t.Errorf("Diagnostics.Check = %q, want format", result.Diagnostics.Check)
+	}
+	if result.Diagnostics.Details["expectedPrefix"] != "cr_" {
+		t.Errorf("expected prefix detail, got %v", result.Diagnostics.Details)
+	}
+}
+
+func TestValidateAPIKey_NoDiagnosticsInProduction(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = &config.Config{Server: config.ServerConfig{Env: "production"}}
+	s := &Service{prefix: "cr_"}
+
+	result := s.ValidateAPIKey(context.Background(), "sk-invalid", ValidationOptions{Verbose: true})
+	if result.Valid {
+		t.Fatal("expected validation failure")
+	}
+	if result.Diagnostics != nil {
+		t.Fatalf("diagnostics must never be returned in production, got %+v", result.Diagnostics)
+	}
+}
+
+func TestValidateAPIKey_NoDiagnosticsWithoutVerbose(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = &config.Config{Server: config.ServerConfig{Env: "development"}}
+	s := &Service{prefix: "cr_"}
+
+	result := s.ValidateAPIKey(context.Background(), "sk-invalid", ValidationOptions{})
+	if result.Diagnostics != nil {
+		t.Fatalf("diagnostics should only be returned when requested, got %+v", result.Diagnostics)
+	}
+}
+
+func TestCheckExpiry_DistinguishesExpiryScenarios(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	activatedAt := now.Add(-31 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		key      *redis.APIKey
+		wantCode string
+		wantMode string
+	}{
+		{"no expiry", &redis.APIKey{ID: "k"}, "", ""},
+		{"fixed not yet expired", &redis.APIKey{ID: "k", ExpirationMode: "fixed", ExpiresAt: &future}, "", ""},
+		{"fixed expired", &redis.APIKey{ID: "k", ExpirationMode: "fixed", ExpiresAt: &past}, ErrorCodeFixedExpired, "fixed"},
+		{"legacy key without mode", &redis.APIKey{ID: "k", ExpiresAt: &past}, ErrorCodeFixedExpired, "fixed"},
+		{"activation pending without deadline", &redis.APIKey{ID: "k", ExpirationMode: "activation", ActivationDays: 30}, "", ""},
+		{"activation failed with stale expiry", &redis.APIKey{ID: "k", ExpirationMode: "activation", ActivationDays: 30, ExpiresAt: &past}, ErrorCodeKeyNotActivated, "activation"},
+		{"activation window elapsed", &redis.APIKey{ID: "k", ExpirationMode: "activation", ActivationDays: 30, IsActivated: true, ActivatedAt: &activatedAt, ExpiresAt: &past}, ErrorCodeActivationExpired, "activation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkExpiry(tt.key, now)
+			if tt.wantCode == "" {
+				if result != nil {
+					t.Fatalf("checkExpiry() = %+v, want nil", result)
+				}
+				return
+			}
+			if result == nil || result.Valid || result.ErrorCode != tt.wantCode || result.StatusCode != 403 {
+				t.Fatalf("checkExpiry() = %+v, want code %s", result, tt.wantCode)
+			}
+			if result.Expiry == nil || result.Expiry.ExpirationMode != tt.wantMode || !result.Expiry.ExpiresAt.Equal(past) {
+				t.Errorf("Expiry = %+v, want mode %s with expiresAt", result.Expiry, tt.wantMode)
+			}
+			if tt.wantCode == ErrorCodeActivationExpired && (result.Expiry.ActivatedAt == nil || result.Expiry.ActivationDays != 30) {
+				t.Errorf("Expiry = %+v, want activation date and window", result.Expiry)
+			}
+		})
+	}
+}
+
+func TestCheckExpiry_LegacyErrorCode(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Security: config.SecurityConfig{LegacyExpiryErrors: true}}
+
+	past := time.Now().Add(-time.Hour)
+	result := checkExpiry(&redis.APIKey{ExpirationMode: "activation", ExpiresAt: &past}, time.Now())
+	if result == nil || result.ErrorCode != ErrorCodeExpired || result.Expiry != nil {
+		t.Errorf("checkExpiry() = %+v, want legacy expired code without details", result)
+	}
+}
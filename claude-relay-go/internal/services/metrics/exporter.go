@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// 推送协议
+const (
+	ProtocolStatsd = "statsd"
+	ProtocolOTLP   = "otlp"
+)
+
+const (
+	// DefaultInterval 默认推送间隔
+	DefaultInterval = 60 * time.Second
+	// DefaultTimeout 单次推送默认超时
+	DefaultTimeout = 5 * time.Second
+	// DefaultPrefix 默认指标名前缀
+	DefaultPrefix = "claude_relay"
+	// maxBackoffFactor 连续失败时推送间隔最多放大的倍数
+	maxBackoffFactor = 16
+)
+
+// Metric 单个指标（均按 gauge 推送）
+type Metric struct {
+	Name  string
+	Value float64
+}
+
+// CollectFunc 采集系统指标快照
+type CollectFunc func(ctx context.Context) (*redis.SystemMetricsSnapshot, error)
+
+// sink 指标推送目标
+type sink interface {
+	push(ctx context.Context, metrics []Metric, now time.Time) error
+}
+
+// Exporter 定时将关键指标推送到 statsd / OTLP
+type Exporter struct {
+	collect  CollectFunc
+	sink     sink
+	interval time.Duration
+	timeout  time.Duration
+	prefix   string
+
+	failures int
+	stopChan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewExporter 创建指标推送器
+func NewExporter(cfg config.MetricsExportConfig, collect CollectFunc) (*Exporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("metrics export endpoint is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+
+	e := &Exporter{
+		collect:  collect,
+		interval: cfg.Interval,
+		timeout:  cfg.Timeout,
+		prefix:   cfg.Prefix,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	switch strings.ToLower(cfg.Protocol) {
+	case ProtocolStatsd:
+		e.sink = &statsdSink{addr: cfg.Endpoint}
+	case ProtocolOTLP:
+		e.sink = newOTLPSink(cfg.Endpoint, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unsupported metrics export protocol: %q", cfg.Protocol)
+	}
+	return e, nil
+}
+
+// NewExporterFromConfig 根据全局配置创建推送器（未配置协议时返回 nil）
+func NewExporterFromConfig(redisClient *redis.Client) (*Exporter, error) {
+	if config.Cfg == nil || config.Cfg.MetricsExport.Protocol == "" {
+		return nil, nil
+	}
+	return NewExporter(config.Cfg.MetricsExport, redisClient.GetSystemMetricsSnapshot)
+}
+
+// Start 启动定时推送
+func (e *Exporter) Start() {
+	go e.run()
+	logger.Info("Metrics exporter started",
+		zap.Duration("interval", e.interval))
+}
+
+// Stop 停止推送并等待进行中的推送结束
+func (e *Exporter) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopChan)
+		<-e.done
+		logger.Info("Metrics exporter stopped")
+	})
+}
+
+// run 推送循环，失败时按 nextDelay 退避
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	timer := time.NewTimer(e.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-timer.C:
+			if err := e.Flush(context.Background()); err != nil {
+				e.failures++
+				logger.Warn("Failed to export metrics",
+					zap.Int("failures", e.failures),
+					zap.Error(err))
+			} else {
+				e.failures = 0
+			}
+			timer.Reset(nextDelay(e.interval, e.failures))
+		}
+	}
+}
+
+// Flush 采集并推送一次指标（受单次推送超时限制）
+func (e *Exporter) Flush(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	snapshot, err := e.collect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	return e.sink.push(ctx, e.metrics(snapshot), time.Now())
+}
+
+// metrics 将快照转换为带前缀的指标
+func (e *Exporter) metrics(s *redis.SystemMetricsSnapshot) []Metric {
+	return []Metric{
+		{Name: e.prefix + ".requests_per_minute", Value: float64(s.Requests)},
+		{Name: e.prefix + ".auth_failures_per_minute", Value: float64(s.AuthFailures)},
+		{Name: e.prefix + ".concurrency", Value: float64(s.Concurrency)},
+		{Name: e.prefix + ".queue_depth", Value: float64(s.QueueDepth)},
+		{Name: e.prefix + ".cost_per_minute", Value: s.CostPerMinute},
+	}
+}
+
+// nextDelay 计算下次推送的等待时间（连续失败时翻倍，最多 maxBackoffFactor 倍）
+func nextDelay(interval time.Duration, failures int) time.Duration {
+	factor := 1
+	for i := 0; i < failures && factor < maxBackoffFactor; i++ {
+		factor *= 2
+	}
+	return interval * time.Duration(factor)
+}
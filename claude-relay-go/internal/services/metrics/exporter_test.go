@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func stubSnapshot(context.Context) (*redis.SystemMetricsSnapshot, error) {
+	return &redis.SystemMetricsSnapshot{
+		Requests:      120,
+		AuthFailures:  3,
+		Concurrency:   7,
+		QueueDepth:    2,
+		CostPerMinute: 1.25,
+	}, nil
+}
+
+func TestExporter_StatsdPushesGauges(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	e, err := NewExporter(config.MetricsExportConfig{Protocol: "statsd", Endpoint: conn.LocalAddr().String(), Prefix: "relay"}, stubSnapshot)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read statsd packet: %v", err)
+	}
+
+	got := strings.Split(string(buf[:n]), "\n")
+	want := []string{
+		"relay.requests_per_minute:120|g",
+		"relay.auth_failures_per_minute:3|g",
+		"relay.concurrency:7|g",
+		"relay.queue_depth:2|g",
+		"relay.cost_per_minute:1.25|g",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("statsd packet = %q, want %q", got, want)
+	}
+}
+
+func TestExporter_OTLPPushesGauges(t *testing.T) {
+	var received otlpRequest
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid OTLP body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(config.MetricsExportConfig{Protocol: "otlp", Endpoint: srv.URL + "/v1/metrics"}, stubSnapshot)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if len(received.ResourceMetrics) != 1 || len(received.ResourceMetrics[0].ScopeMetrics) != 1 {
+		t.Fatalf("received = %+v, want one resource with one scope", received)
+	}
+
+	values := make(map[string]float64)
+	var names []string
+	for _, m := range received.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		if len(m.Gauge.DataPoints) != 1 || m.Gauge.DataPoints[0].TimeUnixNano == "" {
+			t.Fatalf("metric %s data points = %+v, want one timestamped point", m.Name, m.Gauge.DataPoints)
+		}
+		values[m.Name] = m.Gauge.DataPoints[0].AsDouble
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 5 || values["claude_relay.requests_per_minute"] != 120 || values["claude_relay.cost_per_minute"] != 1.25 || values["claude_relay.queue_depth"] != 2 {
+		t.Errorf("metrics = %v, want the 5 default-prefixed gauges", values)
+	}
+}
+
+func TestExporter_FlushErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	e, _ := NewExporter(config.MetricsExportConfig{Protocol: "otlp", Endpoint: srv.URL}, stubSnapshot)
+	if err := e.Flush(context.Background()); err == nil {
+		t.Error("Flush() error = nil, want error for 503 response")
+	}
+
+	collectErr := errors.New("redis down")
+	e, _ = NewExporter(config.MetricsExportConfig{Protocol: "otlp", Endpoint: srv.URL}, func(context.Context) (*redis.SystemMetricsSnapshot, error) {
+		return nil, collectErr
+	})
+	if err := e.Flush(context.Background()); !errors.Is(err, collectErr) {
+		t.Errorf("Flush() error = %v, want collect error", err)
+	}
+
+	if _, err := NewExporter(config.MetricsExportConfig{Protocol: "prometheus", Endpoint: "x"}, stubSnapshot); err == nil {
+		t.Error("NewExporter() with unsupported protocol should fail")
+	}
+}
+
+func TestNextDelay_Backoff(t *testing.T) {
+	interval := 10 * time.Second
+	cases := map[int]time.Duration{0: 10 * time.Second, 1: 20 * time.Second, 3: 80 * time.Second, 4: 160 * time.Second, 10: 160 * time.Second}
+	for failures, want := range cases {
+		if got := nextDelay(interval, failures); got != want {
+			t.Errorf("nextDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
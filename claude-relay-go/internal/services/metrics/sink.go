@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdSink 以 statsd gauge 格式（name:value|g）通过 UDP 推送，所有指标合并为一个数据包
+type statsdSink struct {
+	addr string
+}
+
+func (s *statsdSink) push(ctx context.Context, metrics []Metric, _ time.Time) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetWriteDeadline(deadline)
+	}
+
+	lines := make([]string, len(metrics))
+	for i, m := range metrics {
+		lines[i] = fmt.Sprintf("%s:%s|g", m.Name, strconv.FormatFloat(m.Value, 'f', -1, 64))
+	}
+	_, err = conn.Write([]byte(strings.Join(lines, "\n")))
+	return err
+}
+
+// otlpSink 以 OTLP/HTTP JSON 格式推送
+type otlpSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPSink(endpoint string, timeout time.Duration) *otlpSink {
+	return &otlpSink{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// OTLP 指标请求（仅包含本服务用到的字段）
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   map[string]string `json:"scope"`
+	Metrics []otlpMetric      `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	TimeUnixNano string  `json:"timeUnixNano"`
+	AsDouble     float64 `json:"asDouble"`
+}
+
+func (s *otlpSink) push(ctx context.Context, metrics []Metric, now time.Time) error {
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	scope := otlpScopeMetrics{
+		Scope:   map[string]string{"name": "claude-relay-go"},
+		Metrics: make([]otlpMetric, len(metrics)),
+	}
+	for i, m := range metrics {
+		scope.Metrics[i] = otlpMetric{
+			Name:  m.Name,
+			Gauge: otlpGauge{DataPoints: []otlpDataPoint{{TimeUnixNano: ts, AsDouble: m.Value}}},
+		}
+	}
+
+	body, err := json.Marshal(otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": "claude-relay-go"}},
+		}},
+		ScopeMetrics: []otlpScopeMetrics{scope},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP metrics: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package pricing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrNoStagedPricing 没有待发布的价格更新
+var ErrNoStagedPricing = errors.New("no staged pricing update")
+
+// stagedPricing 灰度中的价格更新（仅按比例用于部分成本计算，发布后才全量生效并写入本地文件）
+type stagedPricing struct {
+	body     []byte
+	hash     string
+	remote   map[string]*RemoteModelPricing
+	cache    map[string]*ModelPricing // 应用更新后的完整价格表
+	stagedAt time.Time
+}
+
+// canaryPercent 获取灰度比例（0-100）
+func (s *Service) canaryPercent() int {
+	if s.config.CanaryPercent <= 0 {
+		return 0
+	}
+	if s.config.CanaryPercent > 100 {
+		return 100
+	}
+	return s.config.CanaryPercent
+}
+
+// canaryEnabled 是否对新下载的价格进行灰度（首次加载没有基线价格时直接生效）
+func (s *Service) canaryEnabled() bool {
+	if s.canaryPercent() == 0 {
+		return false
+	}
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return s.primaryModelCount > 0
+}
+
+// isPendingOrRejected 检查价格数据哈希是否已暂存或已回滚
+func (s *Service) isPendingOrRejected(hash string) bool {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return hash == s.rejectedHash || (s.staged != nil && hash == s.staged.hash)
+}
+
+// stagePricing 暂存下载的价格更新，替换之前未发布的版本
+func (s *Service) stagePricing(body []byte, hash string, remotePricing map[string]*RemoteModelPricing) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	if hash == s.rejectedHash {
+		logger.Debug("Skipping rolled back pricing data", zap.String("hash", hash))
+		return
+	}
+	if hash == s.primaryHash || (s.staged != nil && s.staged.hash == hash) {
+		return
+	}
+
+	// 在当前价格表上应用更新，附加价格源仍然优先
+	cache := make(map[string]*ModelPricing, len(s.cache)+len(remotePricing))
+	for model, pricing := range s.cache {
+		cache[model] = pricing
+	}
+	for model, pricing := range convertRemotePricing(remotePricing) {
+		cache[model] = pricing
+	}
+	for _, src := range s.sources {
+		for model, pricing := range src.models {
+			cache[model] = pricing
+		}
+	}
+
+	s.staged = &stagedPricing{
+		body:     body,
+		hash:     hash,
+		remote:   remotePricing,
+		cache:    cache,
+		stagedAt: time.Now(),
+	}
+
+	logger.Info("Staged pricing update for canary",
+		zap.Int("modelCount", len(remotePricing)),
+		zap.Int("canaryPercent", s.canaryPercent()))
+}
+
+// canaryPricing 按灰度比例决定本次计算是否使用暂存价格
+func (s *Service) canaryPricing(model string) (*ModelPricing, bool) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
+	if s.staged == nil || s.canaryRoll() >= s.canaryPercent() {
+		return nil, false
+	}
+	return s.lookupPricing(s.staged.cache, model), true
+}
+
+// PromoteStagedPricing 发布暂存的价格更新（全量生效，ManageFile 时同时写入本地文件）
+func (s *Service) PromoteStagedPricing() error {
+	s.cacheMu.Lock()
+	staged := s.staged
+	s.staged = nil
+	s.cacheMu.Unlock()
+
+	if staged == nil {
+		return ErrNoStagedPricing
+	}
+
+	s.savePricingFile(staged.body, staged.hash)
+	s.updateCacheFromRemote(staged.remote, staged.hash, time.Now())
+
+	logger.Info("Promoted staged pricing update",
+		zap.Int("modelCount", len(staged.remote)),
+		zap.String("hash", staged.hash))
+	return nil
+}
+
+// RollbackStagedPricing 丢弃暂存的价格更新（同一版本不会再次暂存）
+func (s *Service) RollbackStagedPricing() error {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	if s.staged == nil {
+		return ErrNoStagedPricing
+	}
+
+	s.rejectedHash = s.staged.hash
+	s.staged = nil
+
+	logger.Info("Rolled back staged pricing update", zap.String("hash", s.rejectedHash))
+	return nil
+}
+
+// canaryStatus 获取灰度状态
+func (s *Service) canaryStatus() map[string]interface{} {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
+	status := map[string]interface{}{
+		"percent": s.canaryPercent(),
+		"staged":  s.staged != nil,
+	}
+	if s.staged != nil {
+		status["stagedAt"] = s.staged.stagedAt
+		status["stagedHash"] = s.staged.hash
+		status["stagedModelCount"] = len(s.staged.remote)
+	}
+	if s.rejectedHash != "" {
+		status["rejectedHash"] = s.rejectedHash
+	}
+	return status
+}
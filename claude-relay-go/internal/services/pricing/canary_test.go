@@ -0,0 +1,174 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// newCanaryTestService 创建启用灰度的定价服务，feed 为远程价格数据的当前内容
+func newCanaryTestService(t *testing.T, feed *atomic.Value) *Service {
+	t.Helper()
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feed.Load().(string)))
+	}))
+	t.Cleanup(server.Close)
+
+	config.Cfg = &config.Config{Pricing: config.PricingConfig{
+		DataDir:       t.TempDir(),
+		JSONUrl:       server.URL,
+		CanaryPercent: 20,
+		ManageFile:    true,
+	}}
+	return NewService(nil)
+}
+
+// inputCost 计算一百万输入 token 的成本，roll 为本次灰度随机数
+func inputCost(s *Service, roll int) *CostResult {
+	s.canaryRoll = func() int { return roll }
+	return s.CalculateCost("model-x", UsageData{InputTokens: 1_000_000})
+}
+
+func TestCanary_StagedPricingOnlyAffectsCanaryUntilPromoted(t *testing.T) {
+	var feed atomic.Value
+	feed.Store(`{"model-x": {"input_cost_per_token": 0.000001}}`)
+	s := newCanaryTestService(t, &feed)
+	ctx := context.Background()
+
+	// 没有基线价格时首次下载直接生效
+	if err := s.downloadPricingData(ctx); err != nil {
+		t.Fatalf("downloadPricingData() error = %v", err)
+	}
+	if got := inputCost(s, 0); got.TotalCost != 1 || got.Canary {
+		t.Fatalf("initial cost = %+v, want 1 without canary", got)
+	}
+
+	// 新版本只暂存，不写入本地文件
+	feed.Store(`{"model-x": {"input_cost_per_token": 0.000002}}`)
+	if err := s.downloadPricingData(ctx); err != nil {
+		t.Fatalf("downloadPricingData() error = %v", err)
+	}
+	if data, _ := os.ReadFile(s.pricingFile); !strings.Contains(string(data), "0.000001") {
+		t.Errorf("pricing file should keep the current version while staged, got %s", data)
+	}
+
+	if got := inputCost(s, 19); got.TotalCost != 2 || !got.Canary {
+		t.Errorf("canary cost = %+v, want 2 with canary", got)
+	}
+	if got := inputCost(s, 20); got.TotalCost != 1 || got.Canary {
+		t.Errorf("non-canary cost = %+v, want 1 without canary", got)
+	}
+	if got := s.GetPricing("model-x").InputPricePerMillion; got != 1 {
+		t.Errorf("GetPricing before promote = %v, want 1", got)
+	}
+
+	if err := s.PromoteStagedPricing(); err != nil {
+		t.Fatalf("PromoteStagedPricing() error = %v", err)
+	}
+	if got := inputCost(s, 99); got.TotalCost != 2 || got.Canary {
+		t.Errorf("promoted cost = %+v, want 2 without canary", got)
+	}
+	if data, _ := os.ReadFile(s.pricingFile); !strings.Contains(string(data), "0.000002") {
+		t.Errorf("pricing file should contain the promoted version, got %s", data)
+	}
+	if err := s.PromoteStagedPricing(); !errors.Is(err, ErrNoStagedPricing) {
+		t.Errorf("second PromoteStagedPricing() error = %v, want ErrNoStagedPricing", err)
+	}
+}
+
+func TestCanary_RollbackDiscardsStagedPricing(t *testing.T) {
+	var feed atomic.Value
+	feed.Store(`{"model-x": {"input_cost_per_token": 0.000001}}`)
+	s := newCanaryTestService(t, &feed)
+	ctx := context.Background()
+
+	if err := s.downloadPricingData(ctx); err != nil {
+		t.Fatalf("downloadPricingData() error = %v", err)
+	}
+	feed.Store(`{"model-x": {"input_cost_per_token": 0.000005}}`)
+	if err := s.downloadPricingData(ctx); err != nil {
+		t.Fatalf("downloadPricingData() error = %v", err)
+	}
+
+	if err := s.RollbackStagedPricing(); err != nil {
+		t.Fatalf("RollbackStagedPricing() error = %v", err)
+	}
+	if got := inputCost(s, 0); got.TotalCost != 1 || got.Canary {
+		t.Errorf("cost after rollback = %+v, want 1 without canary", got)
+	}
+
+	// 已回滚的版本再次下载不会重新暂存
+	if err := s.downloadPricingData(ctx); err != nil {
+		t.Fatalf("downloadPricingData() error = %v", err)
+	}
+	if staged := s.GetStatus()["canary"].(map[string]interface{})["staged"]; staged != false {
+		t.Errorf("rolled back version was staged again")
+	}
+	if err := s.RollbackStagedPricing(); !errors.Is(err, ErrNoStagedPricing) {
+		t.Errorf("RollbackStagedPricing() error = %v, want ErrNoStagedPricing", err)
+	}
+}
+
+func TestCanary_FollowerStagesFileChangesWithoutWriting(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Pricing: config.PricingConfig{
+		DataDir:       t.TempDir(),
+		CanaryPercent: 20,
+	}}
+	s := NewService(nil)
+
+	// 文件由 Node 写入，首次加载直接生效
+	if err := os.WriteFile(s.pricingFile, []byte(`{"model-x": {"input_cost_per_token": 0.000001}}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := s.loadPricingData(); err != nil {
+		t.Fatalf("loadPricingData() error = %v", err)
+	}
+
+	// 文件变更后只暂存，CalculateTotalCost 按灰度比例使用新价格
+	updated := `{"model-x": {"input_cost_per_token": 0.000002}}`
+	if err := os.WriteFile(s.pricingFile, []byte(updated), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := s.loadPricingData(); err != nil {
+		t.Fatalf("loadPricingData() error = %v", err)
+	}
+	usage := UsageData{InputTokens: 1_000_000}
+	s.canaryRoll = func() int { return 0 }
+	if got := s.CalculateTotalCost("model-x", usage); got != 2 {
+		t.Errorf("canary CalculateTotalCost() = %v, want 2", got)
+	}
+	s.canaryRoll = func() int { return 50 }
+	if got := s.CalculateTotalCost("model-x", usage); got != 1 {
+		t.Errorf("non-canary CalculateTotalCost() = %v, want 1", got)
+	}
+
+	if err := s.PromoteStagedPricing(); err != nil {
+		t.Fatalf("PromoteStagedPricing() error = %v", err)
+	}
+	if got := s.CalculateTotalCost("model-x", usage); got != 2 {
+		t.Errorf("promoted CalculateTotalCost() = %v, want 2", got)
+	}
+	if _, err := os.Stat(s.hashFile); !os.IsNotExist(err) {
+		t.Errorf("follower should not write the hash file, stat error = %v", err)
+	}
+
+	// 已生效的版本重新加载不会再次暂存
+	if err := s.loadPricingData(); err != nil {
+		t.Fatalf("loadPricingData() error = %v", err)
+	}
+	if staged := s.GetStatus()["canary"].(map[string]interface{})["staged"]; staged != false {
+		t.Errorf("promoted version was staged again")
+	}
+}
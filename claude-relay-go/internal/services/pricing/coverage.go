@@ -0,0 +1,42 @@
+package pricing
+
+// MatchType 模型价格的匹配方式
+type MatchType string
+
+const (
+	MatchExact   MatchType = "exact"   // 价格表中存在同名模型
+	MatchFuzzy   MatchType = "fuzzy"   // 模型名与价格表中的模型互相包含（版本后缀等变体）
+	MatchFamily  MatchType = "family"  // 仅按模型系列套用内置默认价格
+	MatchDefault MatchType = "default" // 无法识别，按默认模型（Sonnet）计价
+)
+
+// defaultPricingModel 无法匹配时使用的默认价格模型
+const defaultPricingModel = "claude-3-5-sonnet-20241022"
+
+// PricingCoverage 单个模型的价格覆盖情况
+type PricingCoverage struct {
+	Model        string        `json:"model"`
+	MatchType    MatchType     `json:"matchType"`
+	MatchedModel string        `json:"matchedModel,omitempty"` // 命中的价格表模型（系列匹配时为空）
+	Covered      bool          `json:"covered"`                // 使用价格表中的真实价格（精确或模糊匹配）
+	Pricing      *ModelPricing `json:"pricing"`
+}
+
+// GetPricingCoverage 检查一组模型的价格覆盖情况（按当前生效的价格表，不含灰度价格）
+func (s *Service) GetPricingCoverage(models []string) []PricingCoverage {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
+	result := make([]PricingCoverage, len(models))
+	for i, model := range models {
+		pricing, matchType, matched := s.lookupPricingMatch(s.cache, model)
+		result[i] = PricingCoverage{
+			Model:        model,
+			MatchType:    matchType,
+			MatchedModel: matched,
+			Covered:      matchType == MatchExact || matchType == MatchFuzzy,
+			Pricing:      pricing,
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package pricing
+
+import "testing"
+
+func TestGetPricingCoverage_ClassifiesMatches(t *testing.T) {
+	s := NewService(nil)
+	sonnet := &ModelPricing{InputPricePerMillion: 3, OutputPricePerMillion: 15}
+	gpt := &ModelPricing{InputPricePerMillion: 2.5, OutputPricePerMillion: 10}
+	s.cache = map[string]*ModelPricing{
+		"claude-sonnet-4-20250514": sonnet,
+		"gpt-4o":                   gpt,
+	}
+
+	coverage := s.GetPricingCoverage([]string{
+		"claude-sonnet-4-20250514",
+		"claude-sonnet-4-20250514-v1:0",
+		"claude-opus-4-1",
+		"mystery-model",
+	})
+
+	tests := []struct {
+		matchType MatchType
+		matched   string
+		covered   bool
+		pricing   *ModelPricing
+	}{
+		{MatchExact, "claude-sonnet-4-20250514", true, sonnet},
+		{MatchFuzzy, "claude-sonnet-4-20250514", true, sonnet},
+		{MatchFamily, "", false, DefaultPricing["claude-opus-4-20250514"]},
+		{MatchDefault, defaultPricingModel, false, DefaultPricing[defaultPricingModel]},
+	}
+	if len(coverage) != len(tests) {
+		t.Fatalf("coverage = %+v, want %d entries", coverage, len(tests))
+	}
+	for i, tt := range tests {
+		got := coverage[i]
+		if got.MatchType != tt.matchType || got.MatchedModel != tt.matched || got.Covered != tt.covered || got.Pricing != tt.pricing {
+			t.Errorf("%s: got %+v, want matchType %s matched %q covered %v", got.Model, got, tt.matchType, tt.matched, tt.covered)
+		}
+	}
+
+	// 覆盖检查与计价使用同一匹配逻辑
+	if s.GetPricing("mystery-model") != coverage[3].Pricing {
+		t.Error("coverage pricing differs from GetPricing for defaulted model")
+	}
+}
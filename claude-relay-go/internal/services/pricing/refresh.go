@@ -0,0 +1,106 @@
+package pricing
+
+import (
+	"context"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 刷新任务状态
+const (
+	RefreshStatusRunning   = "running"
+	RefreshStatusSucceeded = "succeeded"
+	RefreshStatusFailed    = "failed"
+)
+
+const (
+	// refreshJobTTL 已结束的刷新任务保留时长
+	refreshJobTTL = time.Hour
+	// refreshTimeout 单次刷新的最长执行时间
+	refreshTimeout = 2 * time.Minute
+)
+
+// RefreshJob 手动触发的价格刷新任务
+type RefreshJob struct {
+	ID            string     `json:"jobId"`
+	Status        string     `json:"status"`
+	ModelsUpdated int        `json:"modelsUpdated"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     time.Time  `json:"startedAt"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+}
+
+// StartRefresh 在后台下载价格数据并返回刷新任务（已有任务在执行时直接返回该任务）
+// 与 ForceUpdate 不同，下载失败时保留当前价格表，不回退到默认价格
+func (s *Service) StartRefresh() RefreshJob {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.purgeRefreshJobsLocked(time.Now())
+	for _, job := range s.refreshJobs {
+		if job.Status == RefreshStatusRunning {
+			return *job
+		}
+	}
+
+	job := &RefreshJob{
+		ID:        uuid.New().String(),
+		Status:    RefreshStatusRunning,
+		StartedAt: time.Now(),
+	}
+	s.refreshJobs[job.ID] = job
+	go s.runRefresh(job.ID)
+
+	return *job
+}
+
+// GetRefreshJob 获取刷新任务状态
+func (s *Service) GetRefreshJob(jobID string) (RefreshJob, bool) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.purgeRefreshJobsLocked(time.Now())
+	job, ok := s.refreshJobs[jobID]
+	if !ok {
+		return RefreshJob{}, false
+	}
+	return *job, true
+}
+
+// runRefresh 执行刷新任务并记录结果
+func (s *Service) runRefresh(jobID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	count, err := s.downloadPricingModels(ctx)
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	job, ok := s.refreshJobs[jobID]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = RefreshStatusFailed
+		job.Error = err.Error()
+		logger.Warn("Pricing refresh failed", zap.String("jobId", jobID), zap.Error(err))
+		return
+	}
+	job.Status = RefreshStatusSucceeded
+	job.ModelsUpdated = count
+}
+
+// purgeRefreshJobsLocked 清理超过保留时长的已结束任务（调用方需持有 refreshMu）
+func (s *Service) purgeRefreshJobsLocked(now time.Time) {
+	for id, job := range s.refreshJobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > refreshJobTTL {
+			delete(s.refreshJobs, id)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pricing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// newRefreshTestService 创建从测试服务器下载价格数据的定价服务
+func newRefreshTestService(t *testing.T, handler http.HandlerFunc) *Service {
+	t.Helper()
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	config.Cfg = &config.Config{Pricing: config.PricingConfig{
+		DataDir: t.TempDir(),
+		JSONUrl: server.URL,
+	}}
+	return NewService(nil)
+}
+
+// waitRefreshJob 等待刷新任务结束
+func waitRefreshJob(t *testing.T, s *Service, jobID string) RefreshJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := s.GetRefreshJob(jobID)
+		if !ok {
+			t.Fatalf("refresh job %s not found", jobID)
+		}
+		if job.Status != RefreshStatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("refresh job %s did not finish", jobID)
+	return RefreshJob{}
+}
+
+func TestStartRefresh_SucceedsWithModelCount(t *testing.T) {
+	s := newRefreshTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model-a": {"input_cost_per_token": 0.000001}, "model-b": {"input_cost_per_token": 0.000002}}`))
+	})
+
+	started := s.StartRefresh()
+	if started.ID == "" || started.Status != RefreshStatusRunning {
+		t.Fatalf("StartRefresh() = %+v, want running job with id", started)
+	}
+
+	job := waitRefreshJob(t, s, started.ID)
+	if job.Status != RefreshStatusSucceeded {
+		t.Fatalf("status = %s (error %q), want succeeded", job.Status, job.Error)
+	}
+	if job.ModelsUpdated != 2 {
+		t.Errorf("ModelsUpdated = %d, want 2", job.ModelsUpdated)
+	}
+	if job.FinishedAt == nil {
+		t.Error("FinishedAt should be set")
+	}
+	if s.GetPricing("model-b") == nil {
+		t.Error("downloaded pricing should be applied")
+	}
+}
+
+func TestStartRefresh_FailedDownloadReportsError(t *testing.T) {
+	s := newRefreshTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	before := s.GetPricingCount()
+
+	job := waitRefreshJob(t, s, s.StartRefresh().ID)
+	if job.Status != RefreshStatusFailed {
+		t.Fatalf("status = %s, want failed", job.Status)
+	}
+	if !strings.Contains(job.Error, "502") {
+		t.Errorf("Error = %q, want download status", job.Error)
+	}
+	if got := s.GetPricingCount(); got != before {
+		t.Errorf("pricing count = %d, want %d (failed refresh keeps current pricing)", got, before)
+	}
+
+	if _, ok := s.GetRefreshJob("missing"); ok {
+		t.Error("unknown job id should not be found")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -53,6 +54,8 @@ type CostResult struct {
 	CacheCreationCost float64 `json:"cacheCreationCost"`
 	CacheReadCost     float64 `json:"cacheReadCost"`
 	TotalCost         float64 `json:"totalCost"`
+	Currency          string  `json:"currency,omitempty"`
+	Canary            bool    `json:"canary,omitempty"` // 使用灰度中的价格计算
 }
 
 // Service 定价服务
@@ -63,14 +66,45 @@ type Service struct {
 	cacheMu sync.RWMutex
 
 	// 远程更新相关
-	pricingFile     string // 本地缓存的价格文件路径
-	hashFile        string // 本地缓存的哈希文件路径
-	lastUpdated     time.Time
+	pricingFile     string    // 本地缓存的价格文件路径
+	hashFile        string    // 本地缓存的哈希文件路径
+	lastUpdated     time.Time // 受 cacheMu 保护
 	updateTicker    *time.Ticker
 	hashCheckTicker *time.Ticker
 	fileWatcher     *fsnotify.Watcher
 	stopChan        chan struct{}
 	hashSyncMu      sync.Mutex
+
+	// 多价格源合并
+	primaryModelCount int              // 主价格源最近一次加载的模型数
+	primaryHash       string           // 主价格源当前生效数据的哈希
+	sources           []*pricingSource // 附加价格源（靠后的优先级更高）
+
+	// 价格更新灰度
+	staged       *stagedPricing // 待发布的价格更新
+	rejectedHash string         // 已回滚的价格数据哈希（不再重复暂存）
+	canaryRoll   func() int     // 返回 [0,100) 的随机数，决定单次计算是否使用灰度价格
+
+	// 手动触发的刷新任务
+	refreshJobs map[string]*RefreshJob
+	refreshMu   sync.Mutex
+}
+
+// pricingSource 附加价格源（远程 URL 或本地文件，与主价格源使用相同的 JSON 格式）
+type pricingSource struct {
+	location    string
+	remote      bool
+	models      map[string]*ModelPricing
+	lastUpdated time.Time
+	lastError   string
+}
+
+// newPricingSource 根据位置创建价格源（http/https 开头视为远程 URL，否则为本地文件）
+func newPricingSource(location string) *pricingSource {
+	return &pricingSource{
+		location: location,
+		remote:   strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://"),
+	}
 }
 
 // DefaultPricing 默认价格（备用，当远程下载失败时使用）
@@ -192,6 +226,8 @@ func NewService(redisClient *redis.Client) *Service {
 		pricingFile: filepath.Join(dataDir, "model_pricing.json"),
 		hashFile:    filepath.Join(dataDir, "model_pricing.sha256"),
 		stopChan:    make(chan struct{}),
+		canaryRoll:  func() int { return rand.Intn(100) },
+		refreshJobs: make(map[string]*RefreshJob),
 	}
 
 	// 初始化默认价格
@@ -199,33 +235,55 @@ func NewService(redisClient *redis.Client) *Service {
 		s.cache[model] = pricing
 	}
 
+	for _, location := range cfg.Sources {
+		s.sources = append(s.sources, newPricingSource(location))
+	}
+
 	return s
 }
 
-// Initialize 初始化定价服务（启动远程更新）
+// Initialize 初始化定价服务
+// 仅在 ManageFile 时启动远程下载与定时更新，否则只加载并跟随 Node 写入的价格文件，保证文件只有一个写入方
 func (s *Service) Initialize(ctx context.Context) error {
-	// 确保数据目录存在
-	dataDir := filepath.Dir(s.pricingFile)
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		logger.Warn("Failed to create data directory", zap.String("dir", dataDir), zap.Error(err))
+	if s.config.ManageFile {
+		// 确保数据目录存在
+		dataDir := filepath.Dir(s.pricingFile)
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			logger.Warn("Failed to create data directory", zap.String("dir", dataDir), zap.Error(err))
+		}
+
+		// 检查并更新价格数据
+		if err := s.checkAndUpdatePricing(ctx); err != nil {
+			logger.Warn("Failed to update pricing on init", zap.Error(err))
+		}
+	} else if err := s.loadPricingData(); err != nil {
+		logger.Warn("Failed to load pricing file on init", zap.Error(err))
 	}
 
-	// 检查并更新价格数据
-	if err := s.checkAndUpdatePricing(ctx); err != nil {
-		logger.Warn("Failed to update pricing on init", zap.Error(err))
+	// 加载附加价格源（各自独立刷新）
+	for _, src := range s.sources {
+		if err := s.refreshSource(ctx, src); err != nil {
+			logger.Warn("Failed to load pricing source on init",
+				zap.String("source", src.location), zap.Error(err))
+		}
+		if s.config.UpdateInterval > 0 {
+			go s.runSourceLoop(ctx, src)
+		}
 	}
 
-	// 初次启动时执行一次哈希校验
-	go s.syncWithRemoteHash(ctx)
+	if s.config.ManageFile {
+		// 初次启动时执行一次哈希校验
+		go s.syncWithRemoteHash(ctx)
+	}
 
 	// 设置定时更新
-	if s.config.UpdateInterval > 0 {
+	if s.config.ManageFile && s.config.UpdateInterval > 0 {
 		s.updateTicker = time.NewTicker(s.config.UpdateInterval)
 		go s.runUpdateLoop(ctx)
 	}
 
 	// 设置哈希轮询
-	if s.config.HashCheckInterval > 0 {
+	if s.config.ManageFile && s.config.HashCheckInterval > 0 {
 		s.hashCheckTicker = time.NewTicker(s.config.HashCheckInterval)
 		go s.runHashCheckLoop(ctx)
 		logger.Info("Pricing hash check enabled",
@@ -290,6 +348,12 @@ func (s *Service) checkAndUpdatePricing(ctx context.Context) error {
 	needsUpdate := s.needsUpdate()
 
 	if needsUpdate {
+		// 启用灰度时先加载现有数据作为基线，新数据下载后只暂存
+		if s.config.CanaryPercent > 0 {
+			if err := s.loadPricingData(); err != nil {
+				logger.Debug("No existing pricing data as canary baseline", zap.Error(err))
+			}
+		}
 		logger.Info("Updating model pricing data...")
 		if err := s.downloadPricingData(ctx); err != nil {
 			logger.Warn("Failed to download pricing, using fallback", zap.Error(err))
@@ -326,74 +390,197 @@ func (s *Service) needsUpdate() bool {
 
 // downloadPricingData 下载价格数据
 func (s *Service) downloadPricingData(ctx context.Context) error {
+	_, err := s.downloadPricingModels(ctx)
+	return err
+}
+
+// downloadPricingModels 下载价格数据并返回下载到的模型数
+func (s *Service) downloadPricingModels(ctx context.Context) (int, error) {
 	if s.config.JSONUrl == "" {
-		return fmt.Errorf("pricing JSON URL not configured")
+		return 0, fmt.Errorf("pricing JSON URL not configured")
 	}
 
 	// 创建 HTTP 请求
 	req, err := http.NewRequestWithContext(ctx, "GET", s.config.JSONUrl, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to download pricing: %w", err)
+		return 0, fmt.Errorf("failed to download pricing: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+		return 0, fmt.Errorf("download failed with status: %d", resp.StatusCode)
 	}
 
 	// 读取响应体
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return 0, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// 解析 JSON
 	var remotePricing map[string]*RemoteModelPricing
 	if err := json.Unmarshal(body, &remotePricing); err != nil {
-		return fmt.Errorf("failed to parse pricing JSON: %w", err)
-	}
-
-	// 保存到文件
-	if err := os.WriteFile(s.pricingFile, body, 0644); err != nil {
-		logger.Warn("Failed to save pricing file", zap.Error(err))
+		return 0, fmt.Errorf("failed to parse pricing JSON: %w", err)
 	}
 
-	// 计算并保存哈希
 	hash := sha256.Sum256(body)
 	hashStr := hex.EncodeToString(hash[:])
-	if err := os.WriteFile(s.hashFile, []byte(hashStr+"\n"), 0644); err != nil {
-		logger.Warn("Failed to save hash file", zap.Error(err))
+
+	// 启用灰度时先暂存，发布后再写入本地文件并全量生效
+	if s.canaryEnabled() {
+		s.stagePricing(body, hashStr, remotePricing)
+		return len(remotePricing), nil
 	}
 
+	s.savePricingFile(body, hashStr)
+
 	// 转换并更新缓存
-	s.updateCacheFromRemote(remotePricing)
-	s.lastUpdated = time.Now()
+	s.updateCacheFromRemote(remotePricing, hashStr, time.Now())
 
 	logger.Info("Downloaded pricing data",
 		zap.Int("modelCount", len(remotePricing)))
 
-	return nil
+	return len(remotePricing), nil
 }
 
-// updateCacheFromRemote 从远程数据更新缓存
-func (s *Service) updateCacheFromRemote(remotePricing map[string]*RemoteModelPricing) {
-	s.cacheMu.Lock()
-	defer s.cacheMu.Unlock()
+// savePricingFile 保存价格文件及其哈希（仅 ManageFile 时写入，否则文件由 Node 维护）
+func (s *Service) savePricingFile(body []byte, hashStr string) {
+	if !s.config.ManageFile {
+		return
+	}
+	if err := os.WriteFile(s.pricingFile, body, 0644); err != nil {
+		logger.Warn("Failed to save pricing file", zap.Error(err))
+	}
+	if err := os.WriteFile(s.hashFile, []byte(hashStr+"\n"), 0644); err != nil {
+		logger.Warn("Failed to save hash file", zap.Error(err))
+	}
+}
 
+// convertRemotePricing 将远程格式（每 token 价格）转换为每百万 token 价格
+func convertRemotePricing(remotePricing map[string]*RemoteModelPricing) map[string]*ModelPricing {
+	models := make(map[string]*ModelPricing, len(remotePricing))
 	for model, remote := range remotePricing {
-		s.cache[model] = &ModelPricing{
+		if remote == nil {
+			continue
+		}
+		models[model] = &ModelPricing{
 			InputPricePerMillion:         remote.InputCostPerToken * 1_000_000,
 			OutputPricePerMillion:        remote.OutputCostPerToken * 1_000_000,
 			CacheCreationPricePerMillion: remote.CacheCreationInputTokenCost * 1_000_000,
 			CacheReadPricePerMillion:     remote.CacheReadInputTokenCost * 1_000_000,
 		}
 	}
+	return models
+}
+
+// updateCacheFromRemote 从远程数据更新缓存（附加价格源随后重新覆盖）
+func (s *Service) updateCacheFromRemote(remotePricing map[string]*RemoteModelPricing, hash string, updatedAt time.Time) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	models := convertRemotePricing(remotePricing)
+	for model, pricing := range models {
+		s.cache[model] = pricing
+	}
+	s.primaryModelCount = len(models)
+	s.primaryHash = hash
+	s.lastUpdated = updatedAt
+	s.applySourcesLocked()
+}
+
+// applySourcesLocked 按顺序将附加价格源写入缓存，靠后的覆盖靠前的（调用方需持有写锁）
+func (s *Service) applySourcesLocked() {
+	for _, src := range s.sources {
+		for model, pricing := range src.models {
+			s.cache[model] = pricing
+		}
+	}
+}
+
+// readSource 读取价格源原始数据
+func (s *Service) readSource(ctx context.Context, src *pricingSource) ([]byte, error) {
+	if !src.remote {
+		return os.ReadFile(src.location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", src.location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download pricing: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// refreshSource 刷新单个附加价格源并重新合并缓存（失败时保留上次数据）
+func (s *Service) refreshSource(ctx context.Context, src *pricingSource) error {
+	data, err := s.readSource(ctx, src)
+	if err != nil {
+		s.setSourceError(src, err)
+		return err
+	}
+
+	var remotePricing map[string]*RemoteModelPricing
+	if err := json.Unmarshal(data, &remotePricing); err != nil {
+		err = fmt.Errorf("failed to parse pricing source: %w", err)
+		s.setSourceError(src, err)
+		return err
+	}
+
+	s.cacheMu.Lock()
+	src.models = convertRemotePricing(remotePricing)
+	src.lastUpdated = time.Now()
+	src.lastError = ""
+	s.applySourcesLocked()
+	modelCount := len(src.models)
+	s.cacheMu.Unlock()
+
+	logger.Info("Loaded pricing source",
+		zap.String("source", src.location),
+		zap.Int("modelCount", modelCount))
+
+	return nil
+}
+
+// setSourceError 记录价格源最近一次刷新错误
+func (s *Service) setSourceError(src *pricingSource, err error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	src.lastError = err.Error()
+}
+
+// runSourceLoop 运行附加价格源的定时刷新循环
+func (s *Service) runSourceLoop(ctx context.Context, src *pricingSource) {
+	ticker := time.NewTicker(s.config.UpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			if err := s.refreshSource(ctx, src); err != nil {
+				logger.Warn("Periodic pricing source update failed",
+					zap.String("source", src.location), zap.Error(err))
+			}
+		}
+	}
 }
 
 // loadPricingData 加载本地价格数据
@@ -412,12 +599,20 @@ func (s *Service) loadPricingData() error {
 		return fmt.Errorf("failed to parse pricing file: %w", err)
 	}
 
-	s.updateCacheFromRemote(remotePricing)
+	hash := sha256.Sum256(data)
+	hashStr := hex.EncodeToString(hash[:])
+
+	// 文件由 Node 维护时，文件变更即价格更新，启用灰度时同样只暂存
+	if !s.config.ManageFile && s.canaryEnabled() {
+		s.stagePricing(data, hashStr, remotePricing)
+		return nil
+	}
 
-	info, _ := os.Stat(s.pricingFile)
-	if info != nil {
-		s.lastUpdated = info.ModTime()
+	updatedAt := time.Now()
+	if info, _ := os.Stat(s.pricingFile); info != nil {
+		updatedAt = info.ModTime()
 	}
+	s.updateCacheFromRemote(remotePricing, hashStr, updatedAt)
 
 	logger.Info("Loaded pricing data from cache",
 		zap.Int("modelCount", len(remotePricing)))
@@ -448,12 +643,14 @@ func (s *Service) useFallbackPricing() error {
 	}
 
 	// 复制到数据目录
-	if err := os.WriteFile(s.pricingFile, data, 0644); err != nil {
-		logger.Warn("Failed to copy fallback to data dir", zap.Error(err))
+	if s.config.ManageFile {
+		if err := os.WriteFile(s.pricingFile, data, 0644); err != nil {
+			logger.Warn("Failed to copy fallback to data dir", zap.Error(err))
+		}
 	}
 
-	s.updateCacheFromRemote(remotePricing)
-	s.lastUpdated = time.Now()
+	hash := sha256.Sum256(data)
+	s.updateCacheFromRemote(remotePricing, hex.EncodeToString(hash[:]), time.Now())
 
 	logger.Warn("Using fallback pricing data",
 		zap.Int("modelCount", len(remotePricing)))
@@ -487,8 +684,8 @@ func (s *Service) syncWithRemoteHash(ctx context.Context) {
 		return
 	}
 
-	// 比较哈希
-	if remoteHash != localHash {
+	// 比较哈希（已暂存或已回滚的版本不再下载）
+	if remoteHash != localHash && !s.isPendingOrRejected(remoteHash) {
 		logger.Info("Remote pricing file updated, downloading...",
 			zap.String("localHash", localHash[:8]+"..."),
 			zap.String("remoteHash", remoteHash[:8]+"..."))
@@ -549,7 +746,9 @@ func (s *Service) computeLocalHash() string {
 	hashStr := hex.EncodeToString(hash[:])
 
 	// 保存哈希
-	os.WriteFile(s.hashFile, []byte(hashStr+"\n"), 0644)
+	if s.config.ManageFile {
+		os.WriteFile(s.hashFile, []byte(hashStr+"\n"), 0644)
+	}
 
 	return hashStr
 }
@@ -623,28 +822,39 @@ func (s *Service) GetPricing(model string) *ModelPricing {
 	s.cacheMu.RLock()
 	defer s.cacheMu.RUnlock()
 
+	return s.lookupPricing(s.cache, model)
+}
+
+// lookupPricing 在指定价格表中查找模型价格
+func (s *Service) lookupPricing(cache map[string]*ModelPricing, model string) *ModelPricing {
+	pricing, _, _ := s.lookupPricingMatch(cache, model)
+	return pricing
+}
+
+// lookupPricingMatch 在指定价格表中查找模型价格，同时返回匹配方式与命中的价格表模型名
+func (s *Service) lookupPricingMatch(cache map[string]*ModelPricing, model string) (*ModelPricing, MatchType, string) {
 	// 精确匹配
-	if pricing, ok := s.cache[model]; ok {
-		return pricing
+	if pricing, ok := cache[model]; ok {
+		return pricing, MatchExact, model
 	}
 
 	// 模糊匹配（处理版本后缀等变体）
 	modelLower := strings.ToLower(model)
-	for key, pricing := range s.cache {
+	for key, pricing := range cache {
 		keyLower := strings.ToLower(key)
 		if strings.Contains(modelLower, keyLower) || strings.Contains(keyLower, modelLower) {
-			return pricing
+			return pricing, MatchFuzzy, key
 		}
 	}
 
 	// 模型系列匹配
 	pricing := s.matchByModelFamily(modelLower)
 	if pricing != nil {
-		return pricing
+		return pricing, MatchFamily, ""
 	}
 
 	// 返回默认值（Sonnet 价格）
-	return DefaultPricing["claude-3-5-sonnet-20241022"]
+	return DefaultPricing[defaultPricingModel], MatchDefault, defaultPricingModel
 }
 
 // matchByModelFamily 按模型系列匹配
@@ -695,19 +905,25 @@ func (s *Service) matchByModelFamily(modelLower string) *ModelPricing {
 
 // CalculateCost 计算成本
 func (s *Service) CalculateCost(model string, usage UsageData) *CostResult {
-	pricing := s.GetPricing(model)
+	pricing, canary := s.canaryPricing(model)
+	if !canary {
+		pricing = s.GetPricing(model)
+	}
 	if pricing == nil {
 		return &CostResult{}
 	}
 
+	// 各分项按存储精度取整，总价以微美元累加，避免浮点漂移
 	result := &CostResult{
-		InputCost:         float64(usage.InputTokens) * pricing.InputPricePerMillion / 1_000_000,
-		OutputCost:        float64(usage.OutputTokens) * pricing.OutputPricePerMillion / 1_000_000,
-		CacheCreationCost: float64(usage.CacheCreationTokens) * pricing.CacheCreationPricePerMillion / 1_000_000,
-		CacheReadCost:     float64(usage.CacheReadTokens) * pricing.CacheReadPricePerMillion / 1_000_000,
+		InputCost:         redis.RoundCostForStorage(float64(usage.InputTokens) * pricing.InputPricePerMillion / 1_000_000),
+		OutputCost:        redis.RoundCostForStorage(float64(usage.OutputTokens) * pricing.OutputPricePerMillion / 1_000_000),
+		CacheCreationCost: redis.RoundCostForStorage(float64(usage.CacheCreationTokens) * pricing.CacheCreationPricePerMillion / 1_000_000),
+		CacheReadCost:     redis.RoundCostForStorage(float64(usage.CacheReadTokens) * pricing.CacheReadPricePerMillion / 1_000_000),
+		Currency:          redis.GetCostCurrency(),
+		Canary:            canary,
 	}
 
-	result.TotalCost = result.InputCost + result.OutputCost + result.CacheCreationCost + result.CacheReadCost
+	result.TotalCost = redis.SumCosts(result.InputCost, result.OutputCost, result.CacheCreationCost, result.CacheReadCost)
 
 	return result
 }
@@ -825,13 +1041,36 @@ func (s *Service) GetPricingCount() int {
 func (s *Service) GetStatus() map[string]interface{} {
 	s.cacheMu.RLock()
 	modelCount := len(s.cache)
+	lastUpdated := s.lastUpdated
+	sources := make([]map[string]interface{}, 0, len(s.sources)+1)
+	sources = append(sources, map[string]interface{}{
+		"location":   s.config.JSONUrl,
+		"type":       "primary",
+		"modelCount": s.primaryModelCount,
+	})
+	for _, src := range s.sources {
+		sourceType := "file"
+		if src.remote {
+			sourceType = "remote"
+		}
+		sources = append(sources, map[string]interface{}{
+			"location":    src.location,
+			"type":        sourceType,
+			"modelCount":  len(src.models),
+			"lastUpdated": src.lastUpdated,
+			"lastError":   src.lastError,
+		})
+	}
 	s.cacheMu.RUnlock()
 
 	return map[string]interface{}{
 		"initialized":    true,
-		"lastUpdated":    s.lastUpdated,
+		"lastUpdated":    lastUpdated,
+		"manageFile":     s.config.ManageFile,
 		"modelCount":     modelCount,
 		"pricingUrl":     s.config.JSONUrl,
 		"updateInterval": s.config.UpdateInterval.String(),
+		"sources":        sources,
+		"canary":         s.canaryStatus(),
 	}
 }
@@ -0,0 +1,105 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestRefreshSource_LaterSourceOverridesEarlier(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	// 低优先级：远程 LiteLLM 价格
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"model-a": {"input_cost_per_token": 0.000001, "output_cost_per_token": 0.000002},
+			"model-b": {"input_cost_per_token": 0.000003, "output_cost_per_token": 0.000004}
+		}`))
+	}))
+	defer server.Close()
+
+	// 高优先级：本地协议价覆盖
+	overrideFile := filepath.Join(t.TempDir(), "overrides.json")
+	if err := os.WriteFile(overrideFile, []byte(`{
+		"model-b": {"input_cost_per_token": 0.0000005, "output_cost_per_token": 0.000001},
+		"model-c": {"input_cost_per_token": 0.000007, "output_cost_per_token": 0.000008}
+	}`), 0644); err != nil {
+		t.Fatalf("write override file: %v", err)
+	}
+
+	config.Cfg = &config.Config{Pricing: config.PricingConfig{
+		DataDir: t.TempDir(),
+		Sources: []string{server.URL, overrideFile},
+	}}
+	s := NewService(nil)
+	if len(s.sources) != 2 || !s.sources[0].remote || s.sources[1].remote {
+		t.Fatalf("unexpected sources: %+v", s.sources)
+	}
+
+	ctx := context.Background()
+	// 先刷新高优先级源，再刷新低优先级源，合并结果不受刷新顺序影响
+	for _, src := range []*pricingSource{s.sources[1], s.sources[0]} {
+		if err := s.refreshSource(ctx, src); err != nil {
+			t.Fatalf("refreshSource(%s) error = %v", src.location, err)
+		}
+	}
+
+	tests := []struct {
+		model string
+		input float64
+	}{
+		{"model-a", 1.0}, // 仅低优先级源提供
+		{"model-b", 0.5}, // 高优先级源覆盖
+		{"model-c", 7.0}, // 仅高优先级源提供
+	}
+	for _, tt := range tests {
+		if got := s.GetPricing(tt.model).InputPricePerMillion; got < tt.input-1e-9 || got > tt.input+1e-9 {
+			t.Errorf("GetPricing(%s).InputPricePerMillion = %v, want %v", tt.model, got, tt.input)
+		}
+	}
+
+	sources := s.GetStatus()["sources"].([]map[string]interface{})
+	if len(sources) != 3 {
+		t.Fatalf("len(sources) = %d, want 3 (primary + 2)", len(sources))
+	}
+	if sources[1]["modelCount"] != 2 || sources[1]["type"] != "remote" {
+		t.Errorf("remote source status = %+v", sources[1])
+	}
+	if sources[2]["modelCount"] != 2 || sources[2]["type"] != "file" {
+		t.Errorf("file source status = %+v", sources[2])
+	}
+}
+
+func TestRefreshSource_KeepsModelsOnFailure(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	file := filepath.Join(t.TempDir(), "prices.json")
+	os.WriteFile(file, []byte(`{"model-x": {"input_cost_per_token": 0.000002}}`), 0644)
+
+	config.Cfg = &config.Config{Pricing: config.PricingConfig{DataDir: t.TempDir(), Sources: []string{file}}}
+	s := NewService(nil)
+	src := s.sources[0]
+
+	ctx := context.Background()
+	if err := s.refreshSource(ctx, src); err != nil {
+		t.Fatalf("refreshSource() error = %v", err)
+	}
+
+	os.WriteFile(file, []byte(`not json`), 0644)
+	if err := s.refreshSource(ctx, src); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if src.lastError == "" || len(src.models) != 1 {
+		t.Errorf("failed refresh should keep previous models and record error: models=%d lastError=%q", len(src.models), src.lastError)
+	}
+	if got := s.GetPricing("model-x").InputPricePerMillion; got < 2.0-1e-9 || got > 2.0+1e-9 {
+		t.Errorf("GetPricing(model-x).InputPricePerMillion = %v, want 2", got)
+	}
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrAccountQueueTimeout 账户并发已满，排队等待槽位超时
+var ErrAccountQueueTimeout = fmt.Errorf("%w: account queue wait timed out", ErrNoAvailableAccounts)
+
+const (
+	// defaultAccountQueueTimeout 账户排队默认最长等待时间
+	defaultAccountQueueTimeout = 5 * time.Second
+	// accountSlotLeaseSeconds 账户并发槽位租约时长
+	accountSlotLeaseSeconds = 300
+	// accountQueuePrefix 账户排队计数器与统计复用 API Key 排队机制时的 ID 前缀
+	accountQueuePrefix = "account:"
+)
+
+// 账户排队轮询参数（与 API Key 排队一致的指数退避）
+var (
+	accountQueuePollInterval    = 200 * time.Millisecond
+	accountQueueMaxPollInterval = 2 * time.Second
+)
+
+// AccountQueueEnabled 是否在账户并发已满时排队等待
+func AccountQueueEnabled() bool {
+	return config.Cfg != nil && config.Cfg.System.AccountQueueEnabled
+}
+
+// AccountQueueTimeout 获取账户排队最长等待时间
+func AccountQueueTimeout() time.Duration {
+	if config.Cfg != nil && config.Cfg.System.AccountQueueTimeout > 0 {
+		return config.Cfg.System.AccountQueueTimeout
+	}
+	return defaultAccountQueueTimeout
+}
+
+// accountQueueID 账户排队在排队计数器中使用的 ID
+func accountQueueID(accountID string) string {
+	return accountQueuePrefix + accountID
+}
+
+// accountMaxConcurrency 获取账户并发上限（0 表示不限制）
+func accountMaxConcurrency(account map[string]interface{}) int {
+	switch v := account["maxConcurrency"].(type) {
+	case float64:
+		if v > 0 {
+			return int(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// isAccountSaturated 账户并发是否已达上限
+func isAccountSaturated(account map[string]interface{}, load float64) bool {
+	limit := accountMaxConcurrency(account)
+	return limit > 0 && load >= float64(limit)
+}
+
+// accountSlots 账户并发槽位与排队计数
+type accountSlots interface {
+	acquire(ctx context.Context, accountID, requestID string, limit int) (bool, error)
+	enterQueue(ctx context.Context, accountID string, timeout time.Duration)
+	leaveQueue(ctx context.Context, accountID string, wait time.Duration, outcome string)
+}
+
+// redisAccountSlots 基于 Redis 并发计数与排队计数的账户槽位
+type redisAccountSlots struct {
+	s *BaseScheduler
+}
+
+// acquire 占用账户并发槽位（超过上限时立即释放）
+func (r redisAccountSlots) acquire(ctx context.Context, accountID, requestID string, limit int) (bool, error) {
+	count, err := r.s.redis.IncrConcurrency(ctx, accountID, requestID, accountSlotLeaseSeconds)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire account slot: %w", err)
+	}
+	if limit > 0 && count > int64(limit) {
+		if _, err := r.s.redis.DecrConcurrency(ctx, accountID, requestID); err != nil {
+			logger.Warn("Failed to release account slot after limit exceeded",
+				zap.String("accountId", accountID),
+				zap.String("requestId", requestID),
+				zap.Error(err))
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r redisAccountSlots) enterQueue(ctx context.Context, accountID string, timeout time.Duration) {
+	queueID := accountQueueID(accountID)
+	if _, err := r.s.redis.IncrConcurrencyQueue(ctx, queueID, timeout.Milliseconds()); err != nil {
+		logger.Warn("Failed to increment account queue count", zap.String("accountId", accountID), zap.Error(err))
+	}
+	r.s.redis.IncrQueueStats(ctx, queueID, "entered", 1)
+}
+
+func (r redisAccountSlots) leaveQueue(ctx context.Context, accountID string, wait time.Duration, outcome string) {
+	// 请求已取消时仍需清理排队计数
+	ctx = context.WithoutCancel(ctx)
+	queueID := accountQueueID(accountID)
+	r.s.redis.DecrConcurrencyQueue(ctx, queueID)
+	r.s.redis.IncrQueueStats(ctx, queueID, outcome, 1)
+	r.s.redis.RecordWaitTime(ctx, queueID, wait.Milliseconds())
+}
+
+// AcquireAccountSlot 占用选中账户的并发槽位（账户未设置上限时只计数）
+func (s *BaseScheduler) AcquireAccountSlot(ctx context.Context, result *SelectResult, requestID string) (bool, error) {
+	return redisAccountSlots{s: s}.acquire(ctx, result.AccountID, requestID, accountMaxConcurrency(result.Account))
+}
+
+// ReleaseAccountSlot 释放账户并发槽位
+func (s *BaseScheduler) ReleaseAccountSlot(ctx context.Context, accountID, requestID string) error {
+	if _, err := s.redis.DecrConcurrency(ctx, accountID, requestID); err != nil {
+		return fmt.Errorf("failed to release account slot: %w", err)
+	}
+	return nil
+}
+
+// selectSaturatedAccount 在并发已满的账户中选择排队目标（按与正常选择相同的优先级与负载排序）
+func (s *BaseScheduler) selectSaturatedAccount(ctx context.Context, opts SelectOptions) *SelectResult {
+	opts = s.applyAPIKeyExclusions(ctx, opts)
+	opts = s.applyRequestContext(ctx, opts)
+	opts.includeSaturated = true
+
+	candidates, _ := s.collectCandidates(ctx, opts)
+	saturated := make([]AccountCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Saturated {
+			saturated = append(saturated, c)
+		}
+	}
+	return s.SelectBestAccount(saturated)
+}
+
+// waitForAccountSlot 排队等待账户槽位释放，超时或 ctx 取消时返回 false
+func waitForAccountSlot(ctx context.Context, slots accountSlots, target *SelectResult, requestID string, timeout time.Duration) (bool, error) {
+	limit := accountMaxConcurrency(target.Account)
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	slots.enterQueue(ctx, target.AccountID, timeout)
+	outcome := "timeout"
+	defer func() {
+		slots.leaveQueue(ctx, target.AccountID, time.Since(start), outcome)
+	}()
+
+	pollInterval := accountQueuePollInterval
+	for {
+		acquired, err := slots.acquire(ctx, target.AccountID, requestID, limit)
+		if err != nil {
+			outcome = "cancelled"
+			return false, err
+		}
+		if acquired {
+			outcome = "success"
+			return true, nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return false, nil
+		}
+		// 带抖动的指数退避，不超过剩余等待时间
+		jittered := time.Duration(float64(pollInterval) * (0.8 + rand.Float64()*0.4))
+		if jittered < wait {
+			wait = jittered
+		}
+
+		select {
+		case <-ctx.Done():
+			outcome = "cancelled"
+			return false, ctx.Err()
+		case <-time.After(wait):
+		}
+		pollInterval = time.Duration(float64(pollInterval) * 1.5)
+		if pollInterval > accountQueueMaxPollInterval {
+			pollInterval = accountQueueMaxPollInterval
+		}
+	}
+}
+
+// selectAndAcquire 选择账户并占用其并发槽位
+// 启用账户排队时，若所有账户并发已满（或选中账户被并发抢占），在最优的已满账户上排队等待槽位释放
+func selectAndAcquire(
+	ctx context.Context,
+	selectFn func(context.Context, SelectOptions) *SelectResult,
+	selectSaturated func(context.Context, SelectOptions) *SelectResult,
+	slots accountSlots,
+	opts SelectOptions,
+	requestID string,
+) *SelectResult {
+	result := selectFn(ctx, opts)
+	if result.Error == nil {
+		acquired, err := slots.acquire(ctx, result.AccountID, requestID, accountMaxConcurrency(result.Account))
+		if err != nil {
+			return &SelectResult{Error: err}
+		}
+		if acquired {
+			return result
+		}
+		// 选中账户在选择后被其他请求占满
+		if !AccountQueueEnabled() {
+			return &SelectResult{
+				Error: fmt.Errorf("%w: account %s reached its concurrency limit", ErrNoAvailableAccounts, result.AccountID),
+			}
+		}
+		return queueForAccountSlot(ctx, slots, result, requestID)
+	}
+
+	// 选择超时或其他错误不排队
+	if !AccountQueueEnabled() || !errors.Is(result.Error, ErrNoAvailableAccounts) || errors.Is(result.Error, ErrAccountSelectionTimeout) {
+		return result
+	}
+	target := selectSaturated(ctx, opts)
+	if target == nil {
+		return result
+	}
+	return queueForAccountSlot(ctx, slots, withTransformHints(target, opts.Model), requestID)
+}
+
+// queueForAccountSlot 在目标账户上排队，获取槽位后返回该账户
+func queueForAccountSlot(ctx context.Context, slots accountSlots, target *SelectResult, requestID string) *SelectResult {
+	acquired, err := waitForAccountSlot(ctx, slots, target, requestID, AccountQueueTimeout())
+	if err != nil {
+		return &SelectResult{Error: err}
+	}
+	if !acquired {
+		return &SelectResult{Error: fmt.Errorf("account %s: %w", target.AccountID, ErrAccountQueueTimeout)}
+	}
+
+	logger.Info("Acquired account slot after queueing",
+		zap.String("accountType", string(target.AccountType)),
+		zap.String("accountId", target.AccountID))
+	return target
+}
+
+// SelectAndAcquire 选择账户并占用账户并发槽位，使用完毕后需调用 ReleaseAccountSlot
+func (s *UnifiedClaudeScheduler) SelectAndAcquire(ctx context.Context, opts SelectOptions, requestID string) *SelectResult {
+	return selectAndAcquire(ctx, s.SelectAccount, s.selectSaturatedAccount, redisAccountSlots{s: s.BaseScheduler}, opts, requestID)
+}
+
+// SelectAndAcquire 选择账户并占用账户并发槽位，使用完毕后需调用 ReleaseAccountSlot
+func (s *UnifiedGeminiScheduler) SelectAndAcquire(ctx context.Context, opts SelectOptions, requestID string) *SelectResult {
+	return selectAndAcquire(ctx, s.SelectAccount, s.selectSaturatedAccount, redisAccountSlots{s: s.BaseScheduler}, opts, requestID)
+}
+
+// SelectAndAcquire 选择账户并占用账户并发槽位，使用完毕后需调用 ReleaseAccountSlot
+func (s *UnifiedOpenAIScheduler) SelectAndAcquire(ctx context.Context, opts SelectOptions, requestID string) *SelectResult {
+	return selectAndAcquire(ctx, s.SelectAccount, s.selectSaturatedAccount, redisAccountSlots{s: s.BaseScheduler}, opts, requestID)
+}
+
+// SelectAndAcquire 选择账户并占用账户并发槽位，使用完毕后需调用 ReleaseAccountSlot
+func (s *DroidScheduler) SelectAndAcquire(ctx context.Context, opts SelectOptions, requestID string) *SelectResult {
+	return selectAndAcquire(ctx, s.SelectAccount, s.selectSaturatedAccount, redisAccountSlots{s: s.BaseScheduler}, opts, requestID)
+}
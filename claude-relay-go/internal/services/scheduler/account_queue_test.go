@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// fakeAccountSlots 模拟账户并发计数与排队计数
+type fakeAccountSlots struct {
+	mu       sync.Mutex
+	held     map[string]map[string]bool // accountID -> requestID
+	queued   map[string]int
+	outcomes []string
+}
+
+func newFakeAccountSlots() *fakeAccountSlots {
+	return &fakeAccountSlots{held: make(map[string]map[string]bool), queued: make(map[string]int)}
+}
+
+func (f *fakeAccountSlots) acquire(_ context.Context, accountID, requestID string, limit int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.held[accountID] == nil {
+		f.held[accountID] = make(map[string]bool)
+	}
+	if limit > 0 && len(f.held[accountID]) >= limit {
+		return false, nil
+	}
+	f.held[accountID][requestID] = true
+	return true, nil
+}
+
+func (f *fakeAccountSlots) release(accountID, requestID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.held[accountID], requestID)
+}
+
+func (f *fakeAccountSlots) enterQueue(_ context.Context, accountID string, _ time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued[accountID]++
+}
+
+func (f *fakeAccountSlots) leaveQueue(_ context.Context, accountID string, _ time.Duration, outcome string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued[accountID]--
+	f.outcomes = append(f.outcomes, outcome)
+}
+
+func setAccountQueueConfig(t *testing.T, enabled bool, timeout time.Duration) {
+	t.Helper()
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+	config.Cfg.System.AccountQueueEnabled = enabled
+	config.Cfg.System.AccountQueueTimeout = timeout
+}
+
+// saturatedSelection 模拟所有账户并发已满：正常选择无候选，排队目标为 acc-1（上限 1）
+func saturatedSelection() (func(context.Context, SelectOptions) *SelectResult, func(context.Context, SelectOptions) *SelectResult) {
+	selectFn := func(context.Context, SelectOptions) *SelectResult {
+		return &SelectResult{Error: noAvailableAccountsError("Claude", "claude-sonnet-4", false)}
+	}
+	selectSaturated := func(context.Context, SelectOptions) *SelectResult {
+		return &SelectResult{
+			Account:     map[string]interface{}{"id": "acc-1", "maxConcurrency": float64(1)},
+			AccountType: AccountTypeClaudeOfficial,
+			AccountID:   "acc-1",
+		}
+	}
+	return selectFn, selectSaturated
+}
+
+func TestSelectAndAcquire_WaitsForFreedAccountSlot(t *testing.T) {
+	setAccountQueueConfig(t, true, 3*time.Second)
+	slots := newFakeAccountSlots()
+	slots.acquire(context.Background(), "acc-1", "busy-request", 1)
+
+	// 占用中的请求稍后结束
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		slots.release("acc-1", "busy-request")
+	}()
+
+	selectFn, selectSaturated := saturatedSelection()
+	start := time.Now()
+	result := selectAndAcquire(context.Background(), selectFn, selectSaturated, slots, SelectOptions{Model: "claude-sonnet-4"}, "req-1")
+	if result.Error != nil {
+		t.Fatalf("selectAndAcquire() error = %v, want slot acquired after waiting", result.Error)
+	}
+	if result.AccountID != "acc-1" || !slots.held["acc-1"]["req-1"] {
+		t.Errorf("result = %+v, held = %v, want req-1 holding acc-1", result, slots.held["acc-1"])
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("acquired after %v, want to wait for the busy request", elapsed)
+	}
+	if slots.queued["acc-1"] != 0 || len(slots.outcomes) != 1 || slots.outcomes[0] != "success" {
+		t.Errorf("queued = %v, outcomes = %v, want queue left with success", slots.queued, slots.outcomes)
+	}
+}
+
+func TestSelectAndAcquire_QueueTimeout(t *testing.T) {
+	setAccountQueueConfig(t, true, 300*time.Millisecond)
+	slots := newFakeAccountSlots()
+	slots.acquire(context.Background(), "acc-1", "busy-request", 1)
+
+	selectFn, selectSaturated := saturatedSelection()
+	result := selectAndAcquire(context.Background(), selectFn, selectSaturated, slots, SelectOptions{}, "req-1")
+	if !errors.Is(result.Error, ErrAccountQueueTimeout) || !errors.Is(result.Error, ErrNoAvailableAccounts) {
+		t.Fatalf("error = %v, want ErrAccountQueueTimeout", result.Error)
+	}
+	if slots.held["acc-1"]["req-1"] || len(slots.outcomes) != 1 || slots.outcomes[0] != "timeout" {
+		t.Errorf("held = %v, outcomes = %v, want no slot and a timeout outcome", slots.held["acc-1"], slots.outcomes)
+	}
+}
+
+func TestSelectAndAcquire_QueueDisabledFailsFast(t *testing.T) {
+	setAccountQueueConfig(t, false, 3*time.Second)
+	slots := newFakeAccountSlots()
+
+	selectFn, _ := saturatedSelection()
+	selectSaturated := func(context.Context, SelectOptions) *SelectResult {
+		t.Fatal("saturated accounts should not be considered when the account queue is disabled")
+		return nil
+	}
+	result := selectAndAcquire(context.Background(), selectFn, selectSaturated, slots, SelectOptions{}, "req-1")
+	if !errors.Is(result.Error, ErrNoAvailableAccounts) || len(slots.outcomes) != 0 {
+		t.Errorf("error = %v, outcomes = %v, want immediate no-available error", result.Error, slots.outcomes)
+	}
+
+	// 有空闲账户时直接占用槽位
+	free := func(context.Context, SelectOptions) *SelectResult {
+		return &SelectResult{Account: map[string]interface{}{"maxConcurrency": float64(2)}, AccountID: "acc-2"}
+	}
+	if result := selectAndAcquire(context.Background(), free, selectSaturated, slots, SelectOptions{}, "req-2"); result.Error != nil || !slots.held["acc-2"]["req-2"] {
+		t.Errorf("free account: result = %+v, want slot acquired", result)
+	}
+}
+
+func TestIsAccountSaturated(t *testing.T) {
+	tests := []struct {
+		account map[string]interface{}
+		load    float64
+		want    bool
+	}{
+		{map[string]interface{}{}, 100, false},
+		{map[string]interface{}{"maxConcurrency": float64(2)}, 1, false},
+		{map[string]interface{}{"maxConcurrency": float64(2)}, 2, true},
+		{map[string]interface{}{"maxConcurrency": "3"}, 3, true},
+	}
+	for _, tt := range tests {
+		if got := isAccountSaturated(tt.account, tt.load); got != tt.want {
+			t.Errorf("isAccountSaturated(%v, %v) = %v, want %v", tt.account, tt.load, got, tt.want)
+		}
+	}
+}
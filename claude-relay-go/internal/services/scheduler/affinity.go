@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SelectAccountWithAffinity 按 API Key 账户亲和选择账户
+// 亲和账户仍在可用候选中（健康、未过载、未满并发）时直接使用；否则按优先级和负载选择，并将选中的账户记录为新的亲和账户
+func (s *BaseScheduler) SelectAccountWithAffinity(ctx context.Context, opts SelectOptions, candidates []AccountCandidate) *SelectResult {
+	if !opts.AccountAffinity || opts.APIKeyID == "" {
+		return s.SelectAccountForPriority(candidates, opts.KeyPriority)
+	}
+
+	affineID, err := s.redis.GetAPIKeyAffinity(ctx, opts.APIKeyID)
+	if err != nil {
+		logger.Warn("Failed to get API key account affinity",
+			zap.String("apiKeyId", opts.APIKeyID),
+			zap.Error(err))
+	}
+
+	selected, affine := selectAffineAccount(candidates, affineID, func(candidates []AccountCandidate) *SelectResult {
+		return s.SelectAccountForPriority(candidates, opts.KeyPriority)
+	})
+	if selected == nil {
+		return nil
+	}
+
+	if affine {
+		logger.Debug("Using API key affine account",
+			zap.String("apiKeyId", opts.APIKeyID),
+			zap.String("accountId", selected.AccountID))
+	} else if affineID != "" {
+		logger.Info("API key affine account unavailable, switching affinity",
+			zap.String("apiKeyId", opts.APIKeyID),
+			zap.String("previousAccountId", affineID),
+			zap.String("accountId", selected.AccountID))
+	}
+
+	// 记录或续期亲和账户
+	if err := s.redis.SetAPIKeyAffinity(ctx, opts.APIKeyID, selected.AccountID); err != nil {
+		logger.Warn("Failed to set API key account affinity",
+			zap.String("apiKeyId", opts.APIKeyID),
+			zap.Error(err))
+	}
+	return selected
+}
+
+// selectAffineAccount 亲和账户在候选中时返回该账户，否则使用 fallback 选择（第二个返回值表示是否命中亲和账户）
+func selectAffineAccount(candidates []AccountCandidate, affineID string, fallback func([]AccountCandidate) *SelectResult) (*SelectResult, bool) {
+	if affineID != "" {
+		for _, c := range candidates {
+			if c.AccountID == affineID {
+				return &SelectResult{
+					Account:     c.Account,
+					AccountType: c.AccountType,
+					AccountID:   c.AccountID,
+				}, true
+			}
+		}
+	}
+	return fallback(candidates), false
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestSelectAffineAccount_SticksAndFallsBackWhenOverloaded(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	s := &BaseScheduler{category: CategoryClaude}
+	accounts := []map[string]interface{}{
+		{"id": "acc-a"},
+		{"id": "acc-b"},
+	}
+	collect := func(probe accountProbe) []AccountCandidate {
+		var candidates []AccountCandidate
+		s.filterAccounts(context.Background(), SelectOptions{}, AccountTypeClaude, accounts, probe, func(c AccountCandidate) {
+			candidates = append(candidates, c)
+		})
+		return candidates
+	}
+
+	// 亲和账户负载更高，仍优先于常规选择的最优账户
+	candidates := collect(&fakeAccountProbe{loads: map[string]float64{"acc-b": 0.8, "acc-a": 0.1}})
+	selected, affine := selectAffineAccount(candidates, "acc-b", s.SelectBestAccount)
+	if !affine || selected.AccountID != "acc-b" {
+		t.Errorf("selected = %s (affine %v), want acc-b from affinity", selected.AccountID, affine)
+	}
+
+	// 亲和账户过载时不在候选中，回退到常规选择
+	candidates = collect(&fakeAccountProbe{unavailable: map[string]bool{"acc-b": true}})
+	selected, affine = selectAffineAccount(candidates, "acc-b", s.SelectBestAccount)
+	if affine || selected.AccountID != "acc-a" {
+		t.Errorf("selected = %s (affine %v), want fallback to acc-a", selected.AccountID, affine)
+	}
+
+	// 尚未记录亲和账户时使用常规选择
+	selected, affine = selectAffineAccount(candidates, "", s.SelectBestAccount)
+	if affine || selected.AccountID != "acc-a" {
+		t.Errorf("selected = %s (affine %v), want acc-a without affinity", selected.AccountID, affine)
+	}
+}
+
+func TestApplyAPIKey_EnablesAccountAffinity(t *testing.T) {
+	opts := SelectOptions{}
+	opts.ApplyAPIKey(&redis.APIKey{ID: "key-1", AccountAffinity: true})
+	if !opts.AccountAffinity || opts.APIKeyID != "key-1" {
+		t.Errorf("opts = %+v, want affinity enabled for key-1", opts)
+	}
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// AccountCapacity 单个账户的并发容量
+type AccountCapacity struct {
+	AccountID   string      `json:"accountId"`
+	AccountType AccountType `json:"accountType"`
+	Limit       int         `json:"limit"`     // 并发上限（0 表示不限制）
+	Current     int64       `json:"current"`   // 当前并发
+	Headroom    int         `json:"headroom"`  // 剩余可用并发（不可调度或不限制时为 0）
+	Unlimited   bool        `json:"unlimited"` // 未设置并发上限
+	Overloaded  bool        `json:"overloaded"`
+	Draining    bool        `json:"draining"` // 活跃但不再接收新请求（如存在临时错误），仅处理已有请求
+}
+
+// PoolCapacity 账户池并发容量估算
+type PoolCapacity struct {
+	Category   AccountCategory   `json:"category"`
+	Accounts   []AccountCapacity `json:"accounts"`
+	TotalLimit int               `json:"totalLimit"` // 可调度且设置了上限的账户并发上限合计
+	Current    int64             `json:"current"`    // 所有账户当前并发合计
+	Headroom   int               `json:"headroom"`   // 剩余可用并发合计
+	// 不限制并发的可调度账户数（存在时 headroom 仅为下限）
+	UnlimitedAccounts int `json:"unlimitedAccounts"`
+	// 因过载或排空而不计入容量的账户数
+	OverloadedAccounts int `json:"overloadedAccounts"`
+	DrainingAccounts   int `json:"drainingAccounts"`
+}
+
+// EstimateCapacity 估算账户池当前还能承接的并发（供自动扩缩容决策）
+func (s *BaseScheduler) EstimateCapacity(ctx context.Context) *PoolCapacity {
+	var accounts []AccountCapacity
+	for _, accountType := range s.supportedTypes {
+		if cat, ok := AccountTypeToCategory[accountType]; !ok || cat != s.category {
+			continue
+		}
+
+		list, err := s.redis.GetActiveAccounts(ctx, redis.AccountType(accountType))
+		if err != nil {
+			logger.Warn("Failed to get accounts for capacity estimate",
+				zap.String("type", string(accountType)),
+				zap.Error(err))
+			continue
+		}
+
+		for _, account := range list {
+			accountID := s.getAccountID(account)
+			concurrency, _ := s.redis.GetConcurrency(ctx, accountID)
+			accounts = append(accounts, AccountCapacity{
+				AccountID:   accountID,
+				AccountType: accountType,
+				Limit:       accountMaxConcurrency(account),
+				Current:     concurrency,
+				Overloaded:  s.isAccountOverloaded(ctx, accountType, accountID),
+				Draining:    !s.isAccountSchedulable(account),
+			})
+		}
+	}
+	return summarizeCapacity(s.category, accounts)
+}
+
+// summarizeCapacity 计算各账户剩余并发并汇总（过载与排空账户不计入容量）
+func summarizeCapacity(category AccountCategory, accounts []AccountCapacity) *PoolCapacity {
+	pool := &PoolCapacity{Category: category, Accounts: make([]AccountCapacity, 0, len(accounts))}
+	for _, account := range accounts {
+		account.Unlimited = account.Limit <= 0
+		account.Headroom = 0
+		pool.Current += account.Current
+
+		switch {
+		case account.Overloaded:
+			pool.OverloadedAccounts++
+		case account.Draining:
+			pool.DrainingAccounts++
+		case account.Unlimited:
+			pool.UnlimitedAccounts++
+		default:
+			if remaining := int64(account.Limit) - account.Current; remaining > 0 {
+				account.Headroom = int(remaining)
+			}
+			pool.TotalLimit += account.Limit
+			pool.Headroom += account.Headroom
+		}
+		pool.Accounts = append(pool.Accounts, account)
+	}
+	return pool
+}
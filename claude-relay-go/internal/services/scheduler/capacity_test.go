@@ -0,0 +1,44 @@
+package scheduler
+
+import "testing"
+
+func TestSummarizeCapacity_HeadroomFromLimitsAndConcurrency(t *testing.T) {
+	pool := summarizeCapacity(CategoryClaude, []AccountCapacity{
+		{AccountID: "a", AccountType: AccountTypeClaudeOfficial, Limit: 10, Current: 3},
+		{AccountID: "b", AccountType: AccountTypeClaudeOfficial, Limit: 5, Current: 7}, // 超出上限不产生负余量
+		{AccountID: "c", AccountType: AccountTypeClaudeConsole, Limit: 8, Current: 1, Overloaded: true},
+		{AccountID: "d", AccountType: AccountTypeClaudeConsole, Limit: 4, Current: 2, Draining: true},
+		{AccountID: "e", AccountType: AccountTypeClaudeConsole, Current: 6},
+	})
+
+	if pool.TotalLimit != 15 {
+		t.Errorf("TotalLimit = %d, want 15", pool.TotalLimit)
+	}
+	if pool.Headroom != 7 {
+		t.Errorf("Headroom = %d, want 7", pool.Headroom)
+	}
+	if pool.Current != 19 {
+		t.Errorf("Current = %d, want 19", pool.Current)
+	}
+	if pool.OverloadedAccounts != 1 || pool.DrainingAccounts != 1 || pool.UnlimitedAccounts != 1 {
+		t.Errorf("overloaded/draining/unlimited = %d/%d/%d, want 1/1/1",
+			pool.OverloadedAccounts, pool.DrainingAccounts, pool.UnlimitedAccounts)
+	}
+
+	want := map[string]int{"a": 7, "b": 0, "c": 0, "d": 0, "e": 0}
+	for _, account := range pool.Accounts {
+		if account.Headroom != want[account.AccountID] {
+			t.Errorf("account %s headroom = %d, want %d", account.AccountID, account.Headroom, want[account.AccountID])
+		}
+	}
+	if !pool.Accounts[4].Unlimited {
+		t.Error("account without maxConcurrency should be reported as unlimited")
+	}
+}
+
+func TestSummarizeCapacity_Empty(t *testing.T) {
+	pool := summarizeCapacity(CategoryGemini, nil)
+	if pool.Accounts == nil || len(pool.Accounts) != 0 || pool.Headroom != 0 {
+		t.Errorf("pool = %+v, want empty accounts and zero headroom", pool)
+	}
+}
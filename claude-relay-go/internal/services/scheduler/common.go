@@ -4,11 +4,16 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/catstream/claude-relay-go/internal/config"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
 	"github.com/catstream/claude-relay-go/internal/storage/redis"
 	"go.uber.org/zap"
 )
@@ -77,6 +82,9 @@ var AccountTypePriority = map[AccountType]int{
 	AccountTypeDroid:           100,
 }
 
+// UnknownModel 请求未指定模型时的占位模型（不参与模型兼容性过滤）
+const UnknownModel = "unknown"
+
 // SelectOptions 账户选择选项
 type SelectOptions struct {
 	Model                 string        // 请求的模型
@@ -86,6 +94,37 @@ type SelectOptions struct {
 	PreferredAccountTypes []AccountType // 优先选择的账户类型
 	ExcludeAccountIDs     []string      // 排除的账户 ID
 	RequireFeatures       []string      // 需要的功能（如 thinking、vision 等）
+	KeyPriority           int           // API Key 调度优先级（>0 时可使用预留的最优账户）
+	AccountAffinity       bool          // 优先选择 API Key 亲和的账户（跨会话保持同一账户）
+	ClientType            string        // 请求的客户端类型（账户设置 allowedClientTypes 时据此过滤，空表示不检查）
+
+	includeSaturated bool // 同时输出并发已满的账户（账户排队时选择等待目标）
+}
+
+// ApplyAPIKey 将 API Key 的屏蔽账户合并到排除列表
+func (o *SelectOptions) ApplyAPIKey(apiKey *redis.APIKey) {
+	if apiKey == nil {
+		return
+	}
+	if o.APIKeyID == "" {
+		o.APIKeyID = apiKey.ID
+	}
+	if o.KeyPriority == 0 {
+		o.KeyPriority = apiKey.SchedulingPriority
+	}
+	if apiKey.AccountAffinity {
+		o.AccountAffinity = true
+	}
+	for _, accountID := range apiKey.BlockedAccountIDs {
+		if accountID != "" && !contains(o.ExcludeAccountIDs, accountID) {
+			o.ExcludeAccountIDs = append(o.ExcludeAccountIDs, accountID)
+		}
+	}
+}
+
+// isAccountExcluded 检查账户是否被排除
+func isAccountExcluded(opts SelectOptions, accountID string) bool {
+	return contains(opts.ExcludeAccountIDs, accountID)
 }
 
 // SelectResult 账户选择结果
@@ -95,6 +134,8 @@ type SelectResult struct {
 	AccountID   string
 	FromSession bool
 	Error       error
+
+	TransformHints *TransformHints // 转发到该账户需要的请求转换
 }
 
 // AccountCandidate 候选账户
@@ -105,6 +146,8 @@ type AccountCandidate struct {
 	Priority    int
 	Load        float64
 	Features    []string
+	CostFactor  float64 // 处理请求模型的成本系数（cost-optimized 策略使用）
+	Saturated   bool    // 并发已达账户上限（仅 includeSaturated 时输出）
 }
 
 // BaseScheduler 基础调度器
@@ -113,6 +156,7 @@ type BaseScheduler struct {
 	sessionMappingPrefix string
 	category             AccountCategory
 	supportedTypes       []AccountType
+	warmupRoll           func() float64 // 预热期准入的随机数（测试可替换）
 }
 
 // NewBaseScheduler 创建基础调度器
@@ -122,6 +166,7 @@ func NewBaseScheduler(redisClient *redis.Client, category AccountCategory, suppo
 		sessionMappingPrefix: fmt.Sprintf("session_mapping:%s:", category),
 		category:             category,
 		supportedTypes:       supportedTypes,
+		warmupRoll:           rand.Float64,
 	}
 }
 
@@ -172,10 +217,51 @@ func (s *BaseScheduler) GetSessionAccount(ctx context.Context, sessionHash, mode
 	}
 }
 
-// CollectAvailableAccounts 收集可用账户
+// applyAPIKeyExclusions 按 APIKeyID 加载 Key 并合并其屏蔽账户（不修改调用方的切片）
+func (s *BaseScheduler) applyAPIKeyExclusions(ctx context.Context, opts SelectOptions) SelectOptions {
+	if opts.APIKeyID == "" {
+		return opts
+	}
+
+	apiKey, err := s.redis.GetAPIKey(ctx, opts.APIKeyID)
+	if err != nil {
+		logger.Warn("Failed to load API key for scheduling",
+			zap.String("apiKeyId", opts.APIKeyID),
+			zap.Error(err))
+		return opts
+	}
+	if apiKey == nil || (len(apiKey.BlockedAccountIDs) == 0 && apiKey.SchedulingPriority == 0 && !apiKey.AccountAffinity) {
+		return opts
+	}
+
+	opts.ExcludeAccountIDs = append([]string(nil), opts.ExcludeAccountIDs...)
+	opts.ApplyAPIKey(apiKey)
+	return opts
+}
+
+// CollectAvailableAccounts 收集可用账户（ctx 带截止时间时，超时后返回已收集到的候选账户）
 func (s *BaseScheduler) CollectAvailableAccounts(ctx context.Context, opts SelectOptions) []AccountCandidate {
-	var candidates []AccountCandidate
+	candidates, _ := s.collectCandidates(ctx, opts)
+	return candidates
+}
+
+// collectCandidates 收集可用账户并返回是否因截止时间提前结束
+func (s *BaseScheduler) collectCandidates(ctx context.Context, opts SelectOptions) ([]AccountCandidate, bool) {
+	candidates, timedOut := collectWithDeadline(ctx, func(ctx context.Context, emit func(AccountCandidate)) {
+		s.collectAccounts(ctx, opts, emit)
+	})
+	if timedOut {
+		logger.Warn("Account selection deadline exceeded, using candidates collected so far",
+			zap.String("category", string(s.category)),
+			zap.Int("candidates", len(candidates)))
+	}
 
+	// 刚（重新）激活的账户在预热期内只承接部分流量
+	return applyWarmup(candidates, time.Now(), AccountWarmupDuration(), accountWarmupInitialPercent(), s.warmupRoll), timedOut
+}
+
+// collectAccounts 逐个检查账户，通过 emit 输出可用的候选账户
+func (s *BaseScheduler) collectAccounts(ctx context.Context, opts SelectOptions, emit func(AccountCandidate)) {
 	// 确定要检查的账户类型
 	accountTypes := s.supportedTypes
 	if len(opts.PreferredAccountTypes) > 0 {
@@ -187,6 +273,9 @@ func (s *BaseScheduler) CollectAvailableAccounts(ctx context.Context, opts Selec
 		if cat, ok := AccountTypeToCategory[accountType]; !ok || cat != s.category {
 			continue
 		}
+		if ctx.Err() != nil {
+			return
+		}
 
 		accounts, err := s.redis.GetActiveAccounts(ctx, redis.AccountType(accountType))
 		if err != nil {
@@ -196,46 +285,86 @@ func (s *BaseScheduler) CollectAvailableAccounts(ctx context.Context, opts Selec
 			continue
 		}
 
-		for _, account := range accounts {
-			accountID := s.getAccountID(account)
+		if !s.filterAccounts(ctx, opts, accountType, accounts, s, emit) {
+			return
+		}
+	}
+}
 
-			// 检查是否在排除列表中
-			if contains(opts.ExcludeAccountIDs, accountID) {
-				continue
-			}
+// accountProbe 账户的实时状态检查（来自 Redis，测试可替换）
+type accountProbe interface {
+	// isAccountUnavailable 账户是否过载、超出每日 Token 上限或错误熔断
+	isAccountUnavailable(ctx context.Context, accountType AccountType, accountID string, account map[string]interface{}) bool
+	getAccountLoad(ctx context.Context, accountType AccountType, accountID string) float64
+}
 
-			// 检查账户是否可调度
-			if !s.isAccountSchedulable(account) {
-				continue
-			}
+// isAccountUnavailable 依次检查过载标记、每日 Token 上限与近期错误熔断
+func (s *BaseScheduler) isAccountUnavailable(ctx context.Context, accountType AccountType, accountID string, account map[string]interface{}) bool {
+	return s.isAccountOverloaded(ctx, accountType, accountID) ||
+		s.isOverDailyTokenLimit(ctx, accountType, accountID, account) ||
+		s.isErrorBreakerOpen(ctx, accountType, accountID)
+}
 
-			// 检查账户是否支持模型
-			if opts.Model != "" && !s.isModelSupported(account, accountType, opts.Model) {
-				continue
-			}
+// filterAccounts 逐个检查同一类型的账户，通过 emit 输出可用的候选账户（截止时间已过时返回 false）
+func (s *BaseScheduler) filterAccounts(ctx context.Context, opts SelectOptions, accountType AccountType, accounts []map[string]interface{}, probe accountProbe, emit func(AccountCandidate)) bool {
+	for _, account := range accounts {
+		accountID := s.getAccountID(account)
 
-			// 检查账户是否过载
-			if s.isAccountOverloaded(ctx, accountType, accountID) {
-				continue
-			}
+		// 检查是否在排除列表中
+		if isAccountExcluded(opts, accountID) {
+			continue
+		}
 
-			// 检查功能要求
-			if len(opts.RequireFeatures) > 0 && !s.hasRequiredFeatures(account, opts.RequireFeatures) {
-				continue
-			}
+		// 检查账户是否可调度
+		if !s.isAccountSchedulable(account) {
+			continue
+		}
 
-			candidates = append(candidates, AccountCandidate{
-				Account:     account,
-				AccountType: accountType,
-				AccountID:   accountID,
-				Priority:    s.getAccountPriority(accountType, account),
-				Load:        s.getAccountLoad(ctx, accountType, accountID),
-				Features:    s.getAccountFeatures(account),
-			})
+		// 检查账户是否支持模型
+		if opts.Model != "" && !s.isModelSupported(account, accountType, opts.Model) {
+			continue
 		}
-	}
 
-	return candidates
+		// 检查账户是否过载、超出每日 Token 上限或错误熔断
+		if probe.isAccountUnavailable(ctx, accountType, accountID, account) {
+			continue
+		}
+
+		// 检查功能要求
+		if len(opts.RequireFeatures) > 0 && !s.hasRequiredFeatures(account, opts.RequireFeatures) {
+			continue
+		}
+
+		// 检查账户是否允许该客户端类型
+		if !isClientTypeAllowed(account, opts.ClientType) {
+			continue
+		}
+
+		// 检查账户并发上限
+		load := probe.getAccountLoad(ctx, accountType, accountID)
+		saturated := isAccountSaturated(account, load)
+		if saturated && !opts.includeSaturated {
+			continue
+		}
+
+		candidate := AccountCandidate{
+			Account:     account,
+			AccountType: accountType,
+			AccountID:   accountID,
+			Priority:    s.getAccountPriority(accountType, account),
+			Load:        load,
+			Features:    s.getAccountFeatures(account),
+			CostFactor:  AccountCostFactor(accountType, opts.Model),
+			Saturated:   saturated,
+		}
+
+		// 截止时间已过时上述检查结果不可靠，不再输出
+		if ctx.Err() != nil {
+			return false
+		}
+		emit(candidate)
+	}
+	return true
 }
 
 // SelectBestAccount 选择最优账户
@@ -244,6 +373,17 @@ func (s *BaseScheduler) SelectBestAccount(candidates []AccountCandidate) *Select
 		return nil
 	}
 
+	// 成本优先策略：按成本与负载加权得分选择
+	if SelectionStrategy() == SelectionStrategyCostOptimized {
+		best := selectCostOptimized(candidates, selectionCostWeight())
+		return &SelectResult{
+			Account:     best.Account,
+			AccountType: best.AccountType,
+			AccountID:   best.AccountID,
+			FromSession: false,
+		}
+	}
+
 	// 按优先级和负载排序选择最优账户
 	best := candidates[0]
 	for _, c := range candidates[1:] {
@@ -266,6 +406,41 @@ func (s *BaseScheduler) SelectBestAccount(candidates []AccountCandidate) *Select
 	}
 }
 
+// ReservedAccountPercent 获取为高优先级 API Key 预留的最优账户比例（0 表示不预留）
+func ReservedAccountPercent() int {
+	if config.Cfg != nil && config.Cfg.System.ReservedAccountPercent > 0 && config.Cfg.System.ReservedAccountPercent < 100 {
+		return config.Cfg.System.ReservedAccountPercent
+	}
+	return 0
+}
+
+// SelectAccountForPriority 按 API Key 优先级选择账户
+// 启用预留时，排序最靠前的一部分账户只分配给高优先级 Key，普通 Key 从其余账户中选择（至少保留一个）
+func (s *BaseScheduler) SelectAccountForPriority(candidates []AccountCandidate, keyPriority int) *SelectResult {
+	return selectForPriority(s.SelectBestAccount, candidates, keyPriority, ReservedAccountPercent())
+}
+
+// selectForPriority 按预留比例剔除最优账户后再选择
+func selectForPriority(selectBest func([]AccountCandidate) *SelectResult, candidates []AccountCandidate, keyPriority, reservedPercent int) *SelectResult {
+	if keyPriority > 0 || reservedPercent <= 0 || len(candidates) < 2 {
+		return selectBest(candidates)
+	}
+
+	ranked := append([]AccountCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Priority != ranked[j].Priority {
+			return ranked[i].Priority > ranked[j].Priority
+		}
+		return ranked[i].Load < ranked[j].Load
+	})
+
+	reserved := (len(ranked)*reservedPercent + 99) / 100
+	if reserved >= len(ranked) {
+		reserved = len(ranked) - 1
+	}
+	return selectBest(ranked[reserved:])
+}
+
 // BindSessionAccount 绑定会话账户
 func (s *BaseScheduler) BindSessionAccount(ctx context.Context, sessionHash string, accountType AccountType, accountID string, ttl time.Duration) error {
 	if ttl <= 0 {
@@ -273,6 +448,11 @@ func (s *BaseScheduler) BindSessionAccount(ctx context.Context, sessionHash stri
 	}
 
 	err := s.redis.SetStickySession(ctx, sessionHash, accountID, string(accountType), ttl)
+	if errors.Is(err, redis.ErrStickySessionCapReached) {
+		logger.Debug("Sticky session cap reached, routing without session binding",
+			zap.String("sessionHash", truncateString(sessionHash, 8)))
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -311,7 +491,7 @@ func (s *BaseScheduler) isAccountSchedulable(account map[string]interface{}) boo
 
 // isModelSupported 检查账户是否支持模型
 func (s *BaseScheduler) isModelSupported(account map[string]interface{}, accountType AccountType, model string) bool {
-	if model == "" {
+	if model == "" || model == UnknownModel {
 		return true
 	}
 
@@ -409,8 +589,101 @@ func (s *BaseScheduler) isAccountOverloaded(ctx context.Context, accountType Acc
 	return exists
 }
 
+// getAccountDailyTokenLimit 获取账户每日 Token 上限（0 表示不限制）
+func (s *BaseScheduler) getAccountDailyTokenLimit(account map[string]interface{}) int64 {
+	if limit, ok := account["accountDailyTokenLimit"].(float64); ok && limit > 0 {
+		return int64(limit)
+	}
+	return 0
+}
+
+// exceedsDailyTokenLimit 检查当日用量是否达到上限
+func exceedsDailyTokenLimit(limit, used int64) bool {
+	return limit > 0 && used >= limit
+}
+
+// isOverDailyTokenLimit 检查账户当日 Token 是否超限，超限时标记过载直到次日重置
+func (s *BaseScheduler) isOverDailyTokenLimit(ctx context.Context, accountType AccountType, accountID string, account map[string]interface{}) bool {
+	limit := s.getAccountDailyTokenLimit(account)
+	if limit <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	used, err := s.redis.GetAccountDailyTokens(ctx, accountID, now)
+	if err != nil {
+		logger.Warn("Failed to get account daily tokens",
+			zap.String("accountId", accountID),
+			zap.Error(err))
+		return false
+	}
+	if !exceedsDailyTokenLimit(limit, used) {
+		return false
+	}
+
+	resetAt := redis.NextDailyReset(now)
+	if err := s.redis.SetAccountOverloaded(ctx, redis.AccountType(accountType), accountID, resetAt.Sub(now)); err != nil {
+		logger.Warn("Failed to mark account overloaded after token limit",
+			zap.String("accountId", accountID),
+			zap.Error(err))
+	}
+
+	logger.Info("Account reached daily token limit",
+		zap.String("accountType", string(accountType)),
+		zap.String("accountId", accountID),
+		zap.Int64("used", used),
+		zap.Int64("limit", limit),
+		zap.Time("resetAt", resetAt))
+
+	return true
+}
+
+// errorBreakerTripped 检查窗口内错误数是否达到熔断阈值
+func errorBreakerTripped(threshold, recent int64) bool {
+	return threshold > 0 && recent >= threshold
+}
+
+// isErrorBreakerOpen 检查账户近期错误是否触发熔断，触发时标记过载冷却一个统计窗口
+func (s *BaseScheduler) isErrorBreakerOpen(ctx context.Context, accountType AccountType, accountID string) bool {
+	threshold := redis.GetAccountErrorThreshold()
+	if threshold <= 0 {
+		return false
+	}
+
+	stats, err := s.redis.GetAccountErrorStats(ctx, redis.AccountType(accountType), accountID)
+	if err != nil {
+		logger.Warn("Failed to get account error stats",
+			zap.String("accountId", accountID),
+			zap.Error(err))
+		return false
+	}
+	if !errorBreakerTripped(threshold, stats.Recent) {
+		return false
+	}
+
+	cooldown := redis.GetAccountErrorWindow()
+	if err := s.redis.SetAccountOverloaded(ctx, redis.AccountType(accountType), accountID, cooldown); err != nil {
+		logger.Warn("Failed to mark account overloaded after errors",
+			zap.String("accountId", accountID),
+			zap.Error(err))
+	}
+
+	logger.Info("Account error breaker tripped",
+		zap.String("accountType", string(accountType)),
+		zap.String("accountId", accountID),
+		zap.Int64("recentErrors", stats.Recent),
+		zap.Int64("threshold", threshold),
+		zap.Duration("cooldown", cooldown))
+
+	return true
+}
+
 // hasRequiredFeatures 检查账户是否有所需功能
+// 账户未声明 features 时视为具备全部功能（账户数据通常不写入该字段），仅显式声明的功能列表参与过滤
 func (s *BaseScheduler) hasRequiredFeatures(account map[string]interface{}, required []string) bool {
+	if declared, ok := account["features"]; !ok || declared == nil || declared == "" {
+		return true
+	}
 	features := s.getAccountFeatures(account)
 	featureSet := make(map[string]bool)
 	for _, f := range features {
@@ -426,6 +699,31 @@ func (s *BaseScheduler) hasRequiredFeatures(account map[string]interface{}, requ
 	return true
 }
 
+// sessionAccountUsable 粘性会话绑定的账户是否仍可用于本次请求（未被屏蔽、允许该客户端类型且具备所需功能）
+func (s *BaseScheduler) sessionAccountUsable(result *SelectResult, opts SelectOptions) bool {
+	return result != nil &&
+		!isAccountExcluded(opts, result.AccountID) &&
+		isClientTypeAllowed(result.Account, opts.ClientType) &&
+		(len(opts.RequireFeatures) == 0 || s.hasRequiredFeatures(result.Account, opts.RequireFeatures))
+}
+
+// isClientTypeAllowed 检查账户是否允许该客户端类型（allowedClientTypes 为空或未知客户端类型时不限制）
+// 匹配规则与 API Key 的 allowedClients 一致
+func isClientTypeAllowed(account map[string]interface{}, clientType string) bool {
+	items, ok := account["allowedClientTypes"].([]interface{})
+	if !ok || len(items) == 0 || clientType == "" {
+		return true
+	}
+
+	allowed := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			allowed = append(allowed, s)
+		}
+	}
+	return apikey.IsClientAllowed(allowed, clientType)
+}
+
 // getAccountID 获取账户 ID
 func (s *BaseScheduler) getAccountID(account map[string]interface{}) string {
 	if id, ok := account["id"].(string); ok {
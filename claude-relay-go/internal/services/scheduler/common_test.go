@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+func TestSelectOptions_ApplyAPIKey_BlockedAccountExcludedPerKey(t *testing.T) {
+	keyA := &redis.APIKey{ID: "key-a", BlockedAccountIDs: []string{"acct-1"}}
+	keyB := &redis.APIKey{ID: "key-b"}
+
+	optsA := SelectOptions{}
+	optsA.ApplyAPIKey(keyA)
+	optsB := SelectOptions{}
+	optsB.ApplyAPIKey(keyB)
+
+	if !isAccountExcluded(optsA, "acct-1") {
+		t.Error("acct-1 should be excluded for key-a")
+	}
+	if isAccountExcluded(optsA, "acct-2") {
+		t.Error("acct-2 should remain available for key-a")
+	}
+	if isAccountExcluded(optsB, "acct-1") {
+		t.Error("acct-1 should remain available for key-b")
+	}
+	if optsA.APIKeyID != "key-a" {
+		t.Errorf("APIKeyID = %q, want key-a", optsA.APIKeyID)
+	}
+}
+
+func TestSelectOptions_ApplyAPIKey_MergesWithoutDuplicates(t *testing.T) {
+	opts := SelectOptions{ExcludeAccountIDs: []string{"acct-1", "acct-3"}}
+	opts.ApplyAPIKey(&redis.APIKey{ID: "key-a", BlockedAccountIDs: []string{"acct-1", "acct-2", ""}})
+
+	want := []string{"acct-1", "acct-3", "acct-2"}
+	if len(opts.ExcludeAccountIDs) != len(want) {
+		t.Fatalf("ExcludeAccountIDs = %v, want %v", opts.ExcludeAccountIDs, want)
+	}
+	for i, id := range want {
+		if opts.ExcludeAccountIDs[i] != id {
+			t.Errorf("ExcludeAccountIDs[%d] = %q, want %q", i, opts.ExcludeAccountIDs[i], id)
+		}
+	}
+}
+
+func TestSelectOptions_ApplyAPIKey_Nil(t *testing.T) {
+	opts := SelectOptions{APIKeyID: "key-a"}
+	opts.ApplyAPIKey(nil)
+	if len(opts.ExcludeAccountIDs) != 0 {
+		t.Errorf("ExcludeAccountIDs = %v, want empty", opts.ExcludeAccountIDs)
+	}
+}
+
+func TestDailyTokenLimit(t *testing.T) {
+	s := &BaseScheduler{}
+
+	capped := map[string]interface{}{"id": "acct-1", "accountDailyTokenLimit": float64(1000)}
+	uncapped := map[string]interface{}{"id": "acct-2"}
+
+	limit := s.getAccountDailyTokenLimit(capped)
+	if limit != 1000 {
+		t.Fatalf("limit = %d, want 1000", limit)
+	}
+	if exceedsDailyTokenLimit(limit, 999) {
+		t.Error("account under its token cap should remain selectable")
+	}
+	if !exceedsDailyTokenLimit(limit, 1000) {
+		t.Error("account at its token cap should be excluded")
+	}
+	// 次日用量桶清零后恢复可选
+	if exceedsDailyTokenLimit(limit, 0) {
+		t.Error("account should be selectable again after the daily reset")
+	}
+	if exceedsDailyTokenLimit(s.getAccountDailyTokenLimit(uncapped), 1<<40) {
+		t.Error("account without a token cap must never be excluded")
+	}
+}
+
+func TestErrorBreakerTripped(t *testing.T) {
+	tests := []struct {
+		threshold, recent int64
+		want              bool
+	}{
+		{0, 100, false}, // 未配置阈值
+		{5, 4, false},
+		{5, 5, true},
+		{5, 9, true},
+	}
+	for _, tt := range tests {
+		if got := errorBreakerTripped(tt.threshold, tt.recent); got != tt.want {
+			t.Errorf("errorBreakerTripped(%d, %d) = %v, want %v", tt.threshold, tt.recent, got, tt.want)
+		}
+	}
+}
+
+func TestSelectForPriority_HighPriorityKeyGetsBestAccount(t *testing.T) {
+	s := &BaseScheduler{}
+	candidates := []AccountCandidate{
+		{AccountID: "busy", Priority: 100, Load: 0.8},
+		{AccountID: "best", Priority: 100, Load: 0.1},
+		{AccountID: "console", Priority: 90, Load: 0},
+	}
+
+	high := selectForPriority(s.SelectBestAccount, candidates, 10, 33)
+	low := selectForPriority(s.SelectBestAccount, candidates, 0, 33)
+	if high.AccountID != "best" {
+		t.Errorf("high-priority key got %s, want best", high.AccountID)
+	}
+	if low.AccountID != "busy" {
+		t.Errorf("normal key got %s, want busy (best is reserved)", low.AccountID)
+	}
+
+	// 未启用预留时两者都选择最优账户
+	if got := selectForPriority(s.SelectBestAccount, candidates, 0, 0); got.AccountID != "best" {
+		t.Errorf("without reservation normal key got %s, want best", got.AccountID)
+	}
+	// 只有一个候选时不预留
+	if got := selectForPriority(s.SelectBestAccount, candidates[1:2], 0, 90); got.AccountID != "best" {
+		t.Errorf("single candidate got %s, want best", got.AccountID)
+	}
+}
+
+func TestSelectOptions_ApplyAPIKey_SchedulingPriority(t *testing.T) {
+	opts := SelectOptions{}
+	opts.ApplyAPIKey(&redis.APIKey{ID: "key-a", SchedulingPriority: 5})
+	if opts.KeyPriority != 5 {
+		t.Errorf("KeyPriority = %d, want 5", opts.KeyPriority)
+	}
+}
+
+func TestFilterAccounts_AllowedClientTypes(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	s := &BaseScheduler{category: CategoryClaude}
+	accounts := []map[string]interface{}{
+		{"id": "console-cc", "allowedClientTypes": []interface{}{"claude_code"}},
+		{"id": "open"},
+	}
+	probe := &fakeAccountProbe{}
+
+	// 客户端类型不匹配时排除仅限 Claude Code 的账户
+	if got := filterIDs(t, s, SelectOptions{ClientType: "curl"}, AccountTypeClaudeConsole, accounts, probe); len(got) != 1 || got[0] != "open" {
+		t.Errorf("curl candidates = %v, want [open]", got)
+	}
+	// 匹配（不区分大小写）时包含
+	if got := filterIDs(t, s, SelectOptions{ClientType: "Claude_Code"}, AccountTypeClaudeConsole, accounts, probe); len(got) != 2 {
+		t.Errorf("claude_code candidates = %v, want both accounts", got)
+	}
+	// 未提供客户端类型时不限制
+	if got := filterIDs(t, s, SelectOptions{}, AccountTypeClaudeConsole, accounts, probe); len(got) != 2 {
+		t.Errorf("candidates without client type = %v, want both accounts", got)
+	}
+}
+
+func TestApplyRequestContext_PreferredAccountType(t *testing.T) {
+	ctx := WithPreferredAccountType(context.Background(), AccountTypeClaudeConsole)
+	claude := &BaseScheduler{category: CategoryClaude}
+	openai := &BaseScheduler{category: CategoryOpenAI}
+
+	if opts := claude.applyRequestContext(ctx, SelectOptions{}); len(opts.PreferredAccountTypes) != 1 || opts.PreferredAccountTypes[0] != AccountTypeClaudeConsole {
+		t.Errorf("PreferredAccountTypes = %v, want [%s]", opts.PreferredAccountTypes, AccountTypeClaudeConsole)
+	}
+	// 调用方显式指定时不覆盖
+	explicit := SelectOptions{PreferredAccountTypes: []AccountType{AccountTypeBedrock}}
+	if opts := claude.applyRequestContext(ctx, explicit); opts.PreferredAccountTypes[0] != AccountTypeBedrock {
+		t.Errorf("PreferredAccountTypes = %v, want explicit types kept", opts.PreferredAccountTypes)
+	}
+	// 其他类别的调度不受影响
+	if opts := openai.applyRequestContext(ctx, SelectOptions{}); len(opts.PreferredAccountTypes) != 0 {
+		t.Errorf("PreferredAccountTypes = %v, want none for another category", opts.PreferredAccountTypes)
+	}
+}
+
+func TestApplyRequestContext_ClientType(t *testing.T) {
+	ctx := WithClientType(context.Background(), "claude_code")
+	s := &BaseScheduler{category: CategoryClaude}
+
+	if opts := s.applyRequestContext(ctx, SelectOptions{}); opts.ClientType != "claude_code" {
+		t.Errorf("ClientType = %q, want claude_code from context", opts.ClientType)
+	}
+	// 调用方显式指定时不覆盖
+	if opts := s.applyRequestContext(ctx, SelectOptions{ClientType: "gemini_cli"}); opts.ClientType != "gemini_cli" {
+		t.Errorf("ClientType = %q, want explicit gemini_cli kept", opts.ClientType)
+	}
+	if opts := s.applyRequestContext(context.Background(), SelectOptions{}); opts.ClientType != "" {
+		t.Errorf("ClientType = %q, want empty without context", opts.ClientType)
+	}
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// 账户选择策略
+const (
+	// SelectionStrategyPriority 按优先级选择，优先级相同时选择负载低的账户（默认）
+	SelectionStrategyPriority = "priority"
+	// SelectionStrategyCostOptimized 按成本系数与负载的加权得分选择，优先级仅用于打平
+	SelectionStrategyCostOptimized = "cost-optimized"
+)
+
+// defaultSelectionCostWeightPercent cost-optimized 策略默认成本权重
+const defaultSelectionCostWeightPercent = 50
+
+// SelectionStrategy 获取账户选择策略
+func SelectionStrategy() string {
+	if config.Cfg != nil && config.Cfg.System.SelectionStrategy == SelectionStrategyCostOptimized {
+		return SelectionStrategyCostOptimized
+	}
+	return SelectionStrategyPriority
+}
+
+// selectionCostWeight 获取成本权重（0-1，其余为负载权重）
+func selectionCostWeight() float64 {
+	percent := defaultSelectionCostWeightPercent
+	if config.Cfg != nil && config.Cfg.System.SelectionCostWeightPercent > 0 {
+		percent = config.Cfg.System.SelectionCostWeightPercent
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return float64(percent) / 100
+}
+
+// AccountCostFactor 获取账户类型处理指定模型的成本系数（默认 1，越小越便宜）
+func AccountCostFactor(accountType AccountType, model string) float64 {
+	if config.Cfg == nil {
+		return 1
+	}
+	return costFactorFor(config.Cfg.System.AccountCostFactors, accountType, model)
+}
+
+// costFactorFor 查找成本系数：匹配模型关键字的 type:关键字 优先（关键字越长越优先），其次为 type
+func costFactorFor(factors map[string]float64, accountType AccountType, model string) float64 {
+	modelLower := strings.ToLower(model)
+	prefix := string(accountType) + ":"
+
+	factor, matchLen := 1.0, -1
+	for key, value := range factors {
+		if value <= 0 {
+			continue
+		}
+		if key == string(accountType) {
+			if matchLen < 0 {
+				factor, matchLen = value, 0
+			}
+			continue
+		}
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keyword := strings.ToLower(key[len(prefix):])
+		if keyword != "" && strings.Contains(modelLower, keyword) && len(keyword) > matchLen {
+			factor, matchLen = value, len(keyword)
+		}
+	}
+	return factor
+}
+
+// selectCostOptimized 按成本与负载加权得分选择账户（均按候选中的最大值归一化，得分越低越好）
+func selectCostOptimized(candidates []AccountCandidate, costWeight float64) AccountCandidate {
+	score := costOptimizedScorer(candidates, costWeight)
+
+	best, bestScore := candidates[0], score(candidates[0])
+	for _, c := range candidates[1:] {
+		s := score(c)
+		// 得分相同时优先级高的优先
+		if s < bestScore || (s == bestScore && c.Priority > best.Priority) {
+			best, bestScore = c, s
+		}
+	}
+	return best
+}
+
+// costOptimizedScorer 返回 cost-optimized 策略的得分函数（按 candidates 中的最大成本与负载归一化）
+func costOptimizedScorer(candidates []AccountCandidate, costWeight float64) func(AccountCandidate) float64 {
+	costOf := func(c AccountCandidate) float64 {
+		if c.CostFactor > 0 {
+			return c.CostFactor
+		}
+		return 1
+	}
+
+	var maxCost, maxLoad float64
+	for _, c := range candidates {
+		if cost := costOf(c); cost > maxCost {
+			maxCost = cost
+		}
+		if c.Load > maxLoad {
+			maxLoad = c.Load
+		}
+	}
+
+	return func(c AccountCandidate) float64 {
+		result := costWeight * costOf(c) / maxCost
+		if maxLoad > 0 {
+			result += (1 - costWeight) * c.Load / maxLoad
+		}
+		return result
+	}
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestSelectBestAccount_CostOptimizedPrefersCheaperAccount(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	candidates := []AccountCandidate{
+		{AccountID: "official", AccountType: AccountTypeClaude, Priority: 100, Load: 2, CostFactor: 1},
+		{AccountID: "bedrock", AccountType: AccountTypeBedrock, Priority: 80, Load: 2, CostFactor: 0.8},
+	}
+	s := &BaseScheduler{}
+
+	// 默认策略按优先级选择
+	config.Cfg = &config.Config{}
+	if got := s.SelectBestAccount(candidates); got.AccountID != "official" {
+		t.Errorf("priority strategy selected %s, want official", got.AccountID)
+	}
+
+	// 负载相当时选择更便宜的账户
+	config.Cfg = &config.Config{System: config.SystemConfig{SelectionStrategy: SelectionStrategyCostOptimized}}
+	if got := s.SelectBestAccount(candidates); got.AccountID != "bedrock" {
+		t.Errorf("cost-optimized strategy selected %s, want bedrock", got.AccountID)
+	}
+
+	// 便宜账户负载明显更高时仍会考虑负载
+	candidates[0].Load, candidates[1].Load = 0, 4
+	if got := s.SelectBestAccount(candidates); got.AccountID != "official" {
+		t.Errorf("cost-optimized strategy selected %s under heavy load, want official", got.AccountID)
+	}
+}
+
+func TestCostFactorFor_ModelKeywordOverridesType(t *testing.T) {
+	factors := map[string]float64{
+		"bedrock":          0.8,
+		"bedrock:opus":     0.7,
+		"bedrock:opus-4-5": 0.6,
+		"claude-console":   0,
+	}
+
+	tests := []struct {
+		accountType AccountType
+		model       string
+		want        float64
+	}{
+		{AccountTypeBedrock, "claude-sonnet-4-20250514", 0.8},
+		{AccountTypeBedrock, "claude-opus-4-20250514", 0.7},
+		{AccountTypeBedrock, "claude-opus-4-5-20251101", 0.6},
+		{AccountTypeClaudeConsole, "claude-sonnet-4", 1}, // 非正数系数忽略
+		{AccountTypeClaude, "claude-sonnet-4", 1},
+	}
+	for _, tt := range tests {
+		if got := costFactorFor(factors, tt.accountType, tt.model); got != tt.want {
+			t.Errorf("costFactorFor(%s, %s) = %v, want %v", tt.accountType, tt.model, got, tt.want)
+		}
+	}
+}
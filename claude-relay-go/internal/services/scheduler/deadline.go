@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+var (
+	// ErrNoAvailableAccounts 没有可用账户
+	ErrNoAvailableAccounts = errors.New("no available accounts")
+	// ErrAccountSelectionTimeout 账户选择超过截止时间且尚未收集到候选账户
+	ErrAccountSelectionTimeout = fmt.Errorf("%w: account selection timed out", ErrNoAvailableAccounts)
+)
+
+// AccountSelectionTimeout 获取账户选择的截止时间（0 表示不限制）
+func AccountSelectionTimeout() time.Duration {
+	if config.Cfg != nil && config.Cfg.System.AccountSelectionTimeout > 0 {
+		return config.Cfg.System.AccountSelectionTimeout
+	}
+	return 0
+}
+
+// withSelectionDeadline 为账户选择设置截止时间（未配置时仅返回可取消的 ctx）
+func withSelectionDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := AccountSelectionTimeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// noAvailableAccountsError 没有候选账户时的错误（均包装 ErrNoAvailableAccounts，超时时包装 ErrAccountSelectionTimeout）
+func noAvailableAccountsError(label, model string, timedOut bool) error {
+	if timedOut {
+		return fmt.Errorf("%s accounts for model %s: %w", label, model, ErrAccountSelectionTimeout)
+	}
+	return &noAvailableError{msg: fmt.Sprintf("no available %s accounts for model: %s", label, model)}
+}
+
+// noAvailableError 保留原有错误信息，同时可通过 errors.Is 识别为 ErrNoAvailableAccounts
+type noAvailableError struct {
+	msg string
+}
+
+func (e *noAvailableError) Error() string { return e.msg }
+
+func (e *noAvailableError) Unwrap() error { return ErrNoAvailableAccounts }
+
+// collectWithDeadline 执行候选账户收集，ctx 到期时立即返回已输出的候选账户
+// 收集在后台继续运行直到其 Redis 调用因 ctx 到期返回，到期后输出的候选账户被丢弃
+func collectWithDeadline(ctx context.Context, collect func(ctx context.Context, emit func(AccountCandidate))) ([]AccountCandidate, bool) {
+	if _, ok := ctx.Deadline(); !ok {
+		var candidates []AccountCandidate
+		collect(ctx, func(c AccountCandidate) { candidates = append(candidates, c) })
+		return candidates, false
+	}
+
+	var (
+		mu         sync.Mutex
+		candidates []AccountCandidate
+		closed     bool
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collect(ctx, func(c AccountCandidate) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !closed {
+				candidates = append(candidates, c)
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	// 收集完成但 ctx 已到期时，最后的检查可能不完整，同样视为超时
+	return candidates, ctx.Err() != nil
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowCollect 输出 fast 中的候选账户后模拟缓慢的 Redis：即使 ctx 已到期也要 1 秒后才返回
+func slowCollect(fast []AccountCandidate, late AccountCandidate) func(context.Context, func(AccountCandidate)) {
+	return func(ctx context.Context, emit func(AccountCandidate)) {
+		for _, c := range fast {
+			emit(c)
+		}
+		time.Sleep(time.Second)
+		emit(late)
+	}
+}
+
+func TestCollectWithDeadline_ReturnsBestSoFarPromptly(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	fast := []AccountCandidate{
+		{AccountID: "a", Priority: 50},
+		{AccountID: "b", Priority: 100},
+	}
+	start := time.Now()
+	candidates, timedOut := collectWithDeadline(ctx, slowCollect(fast, AccountCandidate{AccountID: "late", Priority: 999}))
+	elapsed := time.Since(start)
+
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("collectWithDeadline took %v, want prompt return after deadline", elapsed)
+	}
+	if !timedOut || len(candidates) != 2 {
+		t.Fatalf("candidates = %+v, timedOut = %v, want the 2 collected before the deadline", candidates, timedOut)
+	}
+	if best := (&BaseScheduler{}).SelectBestAccount(candidates); best.AccountID != "b" {
+		t.Errorf("best so far = %s, want b", best.AccountID)
+	}
+
+	// 超时后才输出的候选账户被丢弃
+	time.Sleep(1200 * time.Millisecond)
+	if len(candidates) != 2 {
+		t.Errorf("late candidate leaked into result: %+v", candidates)
+	}
+}
+
+func TestCollectWithDeadline_NoCandidatesReportsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	candidates, timedOut := collectWithDeadline(ctx, slowCollect(nil, AccountCandidate{AccountID: "late"}))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("collectWithDeadline took %v, want prompt return after deadline", elapsed)
+	}
+	if !timedOut || len(candidates) != 0 {
+		t.Fatalf("candidates = %+v, timedOut = %v, want none and timed out", candidates, timedOut)
+	}
+
+	err := noAvailableAccountsError("Claude", "claude-sonnet-4", timedOut)
+	if !errors.Is(err, ErrNoAvailableAccounts) || !errors.Is(err, ErrAccountSelectionTimeout) {
+		t.Errorf("error = %v, want ErrNoAvailableAccounts with timeout reason", err)
+	}
+	if err := noAvailableAccountsError("Claude", "claude-sonnet-4", false); errors.Is(err, ErrAccountSelectionTimeout) {
+		t.Errorf("non-timeout error = %v should not report a timeout", err)
+	}
+}
+
+func TestCollectWithDeadline_WithoutDeadlineCollectsAll(t *testing.T) {
+	collect := func(ctx context.Context, emit func(AccountCandidate)) {
+		emit(AccountCandidate{AccountID: "a"})
+		emit(AccountCandidate{AccountID: "b"})
+	}
+
+	candidates, timedOut := collectWithDeadline(context.Background(), collect)
+	if timedOut || len(candidates) != 2 {
+		t.Errorf("candidates = %+v, timedOut = %v, want both without timeout", candidates, timedOut)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	candidates, timedOut = collectWithDeadline(ctx, collect)
+	if timedOut || len(candidates) != 2 {
+		t.Errorf("with deadline: candidates = %+v, timedOut = %v, want both without timeout", candidates, timedOut)
+	}
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+
+	"github.com/catstream/claude-relay-go/internal/services/apikey"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// EligibleAccounts 某个 API Key 请求指定模型时当前可调度的账户（只读，不选择、不绑定会话）
+type EligibleAccounts struct {
+	Category  AccountCategory `json:"category"`
+	APIKeyID  string          `json:"apiKeyId"`
+	Model     string          `json:"model,omitempty"`
+	Permitted bool            `json:"permitted"` // API Key 是否有该类别的权限且模型未被屏蔽，否则账户列表为空
+	// API Key 绑定的专属账户（非空时仅列出这些账户）
+	DedicatedAccountIDs []string        `json:"dedicatedAccountIds,omitempty"`
+	Accounts            []RankedAccount `json:"accounts"`
+	Count               int             `json:"count"`
+}
+
+// HasCategoryPermission 检查 API Key 是否允许使用该类别的账户请求模型（权限与模型黑名单，与 API Key 服务一致）
+func HasCategoryPermission(apiKey *redis.APIKey, category AccountCategory, model string) bool {
+	return apikey.NewPermissionChecker(apiKey).CanUseCategory(string(category), model)
+}
+
+// ListEligibleAccounts 按与实际调度相同的候选收集逻辑（权限、模型、健康、过载、屏蔽、专属绑定）列出全部可用账户及其得分
+func (s *BaseScheduler) ListEligibleAccounts(ctx context.Context, apiKey *redis.APIKey, model string) *EligibleAccounts {
+	result := &EligibleAccounts{
+		Category:  s.category,
+		APIKeyID:  apiKey.ID,
+		Model:     model,
+		Permitted: HasCategoryPermission(apiKey, s.category, model),
+		Accounts:  []RankedAccount{},
+	}
+	if !result.Permitted {
+		return result
+	}
+	result.DedicatedAccountIDs = apikey.NewPermissionChecker(apiKey).DedicatedAccountIDs(string(s.category))
+
+	ctx, cancel := withSelectionDeadline(ctx)
+	defer cancel()
+
+	opts := SelectOptions{Model: model}
+	opts.ApplyAPIKey(apiKey)
+	candidates := s.CollectAvailableAccounts(ctx, opts)
+	if len(result.DedicatedAccountIDs) > 0 {
+		candidates = slices.DeleteFunc(candidates, func(c AccountCandidate) bool {
+			return !slices.Contains(result.DedicatedAccountIDs, c.AccountID)
+		})
+	}
+	result.Accounts = rankCandidates(candidates, opts, 0)
+	result.Count = len(result.Accounts)
+	return result
+}
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+)
+
+// fakeAccountProbe 模拟账户的过载状态与负载
+type fakeAccountProbe struct {
+	unavailable map[string]bool
+	loads       map[string]float64
+}
+
+func (p *fakeAccountProbe) isAccountUnavailable(_ context.Context, _ AccountType, accountID string, _ map[string]interface{}) bool {
+	return p.unavailable[accountID]
+}
+
+func (p *fakeAccountProbe) getAccountLoad(_ context.Context, _ AccountType, accountID string) float64 {
+	return p.loads[accountID]
+}
+
+func filterIDs(t *testing.T, s *BaseScheduler, opts SelectOptions, accountType AccountType, accounts []map[string]interface{}, probe accountProbe) []string {
+	t.Helper()
+	var candidates []AccountCandidate
+	s.filterAccounts(context.Background(), opts, accountType, accounts, probe, func(c AccountCandidate) {
+		candidates = append(candidates, c)
+	})
+	return rankedIDs(rankCandidates(candidates, opts, 0))
+}
+
+func TestFilterAccounts_ModelAndOverloadShapeEligibleList(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	s := &BaseScheduler{category: CategoryClaude}
+	accounts := []map[string]interface{}{
+		{"id": "max", "subscriptionLevel": "max"},
+		{"id": "free", "subscriptionLevel": "free"},
+		{"id": "overloaded", "subscriptionLevel": "max"},
+		{"id": "errored", "subscriptionLevel": "max", "errorMsg": "rate limited"},
+		{"id": "busy", "subscriptionLevel": "max"},
+	}
+	probe := &fakeAccountProbe{
+		unavailable: map[string]bool{"overloaded": true},
+		loads:       map[string]float64{"busy": 2},
+	}
+
+	got := filterIDs(t, s, SelectOptions{Model: "claude-opus-4-1"}, AccountTypeClaude, accounts, probe)
+	if len(got) != 2 || got[0] != "max" || got[1] != "busy" {
+		t.Errorf("opus eligible = %v, want [max busy]", got)
+	}
+
+	// Sonnet 不受订阅等级限制
+	got = filterIDs(t, s, SelectOptions{Model: "claude-sonnet-4"}, AccountTypeClaude, accounts, probe)
+	if len(got) != 3 || got[0] != "max" || got[1] != "free" || got[2] != "busy" {
+		t.Errorf("sonnet eligible = %v, want [max free busy]", got)
+	}
+
+	// 非 Claude 模型没有可用账户
+	if got := filterIDs(t, s, SelectOptions{Model: "gpt-4o"}, AccountTypeClaude, accounts, probe); len(got) != 0 {
+		t.Errorf("gpt eligible = %v, want none", got)
+	}
+
+	// API Key 屏蔽的账户被排除
+	opts := SelectOptions{Model: "claude-opus-4-1"}
+	opts.ApplyAPIKey(&redis.APIKey{ID: "key-1", BlockedAccountIDs: []string{"max"}})
+	if got := filterIDs(t, s, opts, AccountTypeClaude, accounts, probe); len(got) != 1 || got[0] != "busy" {
+		t.Errorf("blocked eligible = %v, want [busy]", got)
+	}
+}
+
+func TestListEligibleAccounts_RequiresCategoryPermission(t *testing.T) {
+	s := &BaseScheduler{category: CategoryClaude}
+
+	result := s.ListEligibleAccounts(context.Background(), &redis.APIKey{ID: "key-1", Permissions: []string{"gemini"}}, "claude-opus-4-1")
+	if result.Permitted || result.Count != 0 || result.Accounts == nil || len(result.Accounts) != 0 {
+		t.Errorf("result = %+v, want not permitted with empty accounts", result)
+	}
+
+	// 模型在黑名单中时同样不可调度
+	result = s.ListEligibleAccounts(context.Background(), &redis.APIKey{ID: "key-1", ModelBlacklist: []string{"opus"}}, "claude-opus-4-1")
+	if result.Permitted || result.Count != 0 {
+		t.Errorf("blacklisted result = %+v, want not permitted", result)
+	}
+
+	tests := []struct {
+		permissions []string
+		blacklist   []string
+		model       string
+		want        bool
+	}{
+		{nil, nil, "claude-opus-4-1", true},
+		{[]string{"all"}, nil, "", true},
+		{[]string{"gemini", "claude"}, nil, "claude-sonnet-4", true},
+		{[]string{"openai"}, nil, "claude-sonnet-4", false},
+		{[]string{"claude"}, []string{"claude-opus*"}, "claude-opus-4-1", false},
+		{[]string{"claude"}, []string{"claude-opus*"}, "claude-sonnet-4", true},
+	}
+	for _, tt := range tests {
+		apiKey := &redis.APIKey{ID: "key-1", Permissions: tt.permissions, ModelBlacklist: tt.blacklist}
+		if got := HasCategoryPermission(apiKey, CategoryClaude, tt.model); got != tt.want {
+			t.Errorf("HasCategoryPermission(%v, %v, %q) = %v, want %v", tt.permissions, tt.blacklist, tt.model, got, tt.want)
+		}
+	}
+}
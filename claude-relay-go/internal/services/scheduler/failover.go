@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/catstream/claude-relay-go/internal/storage/redis"
+	"go.uber.org/zap"
+)
+
+// ErrFailoverExhausted 已达到最大切换次数或没有剩余可用账户
+var ErrFailoverExhausted = errors.New("account failover exhausted")
+
+// MaxFailoverAttempts 获取请求失败后切换账户的最大次数（0 表示不切换）
+func MaxFailoverAttempts() int {
+	if config.Cfg != nil && config.Cfg.System.MaxFailoverAttempts > 0 {
+		return config.Cfg.System.MaxFailoverAttempts
+	}
+	return 0
+}
+
+// MarkFailed 将失败的账户加入排除列表，后续切换不再选择
+func (o *SelectOptions) MarkFailed(accountID string) {
+	if accountID != "" && !contains(o.ExcludeAccountIDs, accountID) {
+		o.ExcludeAccountIDs = append(o.ExcludeAccountIDs, accountID)
+	}
+}
+
+// selectWithFailover 按尝试次数选择账户（attempt 从 0 开始，0 为首次选择）
+// 已尝试的账户需由调用方通过 MarkFailed 记入 opts.ExcludeAccountIDs
+func selectWithFailover(ctx context.Context, selectFn func(context.Context, SelectOptions) *SelectResult, opts SelectOptions, attempt, maxAttempts int) *SelectResult {
+	if attempt > maxAttempts {
+		return &SelectResult{
+			Error: fmt.Errorf("%w: attempt %d exceeds max %d", ErrFailoverExhausted, attempt, maxAttempts),
+		}
+	}
+
+	opts.ExcludeAccountIDs = append([]string(nil), opts.ExcludeAccountIDs...)
+	result := selectFn(ctx, opts)
+	if attempt > 0 && result != nil && result.Error != nil {
+		result.Error = fmt.Errorf("%w: %v", ErrFailoverExhausted, result.Error)
+	}
+	return result
+}
+
+// markAccountFailed 记录账户请求失败（排除后续切换并计入账户错误统计）
+func (s *BaseScheduler) markAccountFailed(ctx context.Context, opts *SelectOptions, failed *SelectResult, eventID string) {
+	if failed == nil || failed.AccountID == "" {
+		return
+	}
+	opts.MarkFailed(failed.AccountID)
+
+	if _, _, err := s.redis.RecordAccountError(ctx, redis.AccountType(failed.AccountType), failed.AccountID, eventID); err != nil {
+		logger.Warn("Failed to record account failure",
+			zap.String("accountId", failed.AccountID),
+			zap.Error(err))
+	}
+
+	logger.Info("Account failed, failing over",
+		zap.String("accountType", string(failed.AccountType)),
+		zap.String("accountId", failed.AccountID),
+		zap.Int("tried", len(opts.ExcludeAccountIDs)))
+}
+
+// SelectWithFailover 选择账户，attempt > 0 时排除已尝试的账户并切换到次优账户
+func (s *UnifiedClaudeScheduler) SelectWithFailover(ctx context.Context, opts SelectOptions, attempt int) *SelectResult {
+	return selectWithFailover(ctx, s.SelectAccount, opts, attempt, MaxFailoverAttempts())
+}
+
+// MarkAccountFailed 记录账户请求失败，供下一次 SelectWithFailover 排除
+func (s *UnifiedClaudeScheduler) MarkAccountFailed(ctx context.Context, opts *SelectOptions, failed *SelectResult, eventID string) {
+	s.markAccountFailed(ctx, opts, failed, eventID)
+}
+
+// SelectWithFailover 选择账户，attempt > 0 时排除已尝试的账户并切换到次优账户
+func (s *UnifiedGeminiScheduler) SelectWithFailover(ctx context.Context, opts SelectOptions, attempt int) *SelectResult {
+	return selectWithFailover(ctx, s.SelectAccount, opts, attempt, MaxFailoverAttempts())
+}
+
+// MarkAccountFailed 记录账户请求失败，供下一次 SelectWithFailover 排除
+func (s *UnifiedGeminiScheduler) MarkAccountFailed(ctx context.Context, opts *SelectOptions, failed *SelectResult, eventID string) {
+	s.markAccountFailed(ctx, opts, failed, eventID)
+}
+
+// SelectWithFailover 选择账户，attempt > 0 时排除已尝试的账户并切换到次优账户
+func (s *UnifiedOpenAIScheduler) SelectWithFailover(ctx context.Context, opts SelectOptions, attempt int) *SelectResult {
+	return selectWithFailover(ctx, s.SelectAccount, opts, attempt, MaxFailoverAttempts())
+}
+
+// MarkAccountFailed 记录账户请求失败，供下一次 SelectWithFailover 排除
+func (s *UnifiedOpenAIScheduler) MarkAccountFailed(ctx context.Context, opts *SelectOptions, failed *SelectResult, eventID string) {
+	s.markAccountFailed(ctx, opts, failed, eventID)
+}
+
+// SelectWithFailover 选择账户，attempt > 0 时排除已尝试的账户并切换到次优账户
+func (s *DroidScheduler) SelectWithFailover(ctx context.Context, opts SelectOptions, attempt int) *SelectResult {
+	return selectWithFailover(ctx, s.SelectAccount, opts, attempt, MaxFailoverAttempts())
+}
+
+// MarkAccountFailed 记录账户请求失败，供下一次 SelectWithFailover 排除
+func (s *DroidScheduler) MarkAccountFailed(ctx context.Context, opts *SelectOptions, failed *SelectResult, eventID string) {
+	s.markAccountFailed(ctx, opts, failed, eventID)
+}
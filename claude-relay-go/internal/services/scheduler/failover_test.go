@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// poolSelector 按顺序返回第一个未被排除的账户
+func poolSelector(pool []string) func(context.Context, SelectOptions) *SelectResult {
+	return func(_ context.Context, opts SelectOptions) *SelectResult {
+		for _, id := range pool {
+			if !isAccountExcluded(opts, id) {
+				return &SelectResult{AccountID: id, AccountType: AccountTypeClaude}
+			}
+		}
+		return &SelectResult{Error: fmt.Errorf("no available accounts")}
+	}
+}
+
+func TestSelectWithFailover_DistinctUntilExhausted(t *testing.T) {
+	ctx := context.Background()
+	selectFn := poolSelector([]string{"acct-1", "acct-2", "acct-3"})
+	opts := SelectOptions{Model: "claude-sonnet-4"}
+
+	seen := make(map[string]bool)
+	for attempt := 0; attempt < 3; attempt++ {
+		result := selectWithFailover(ctx, selectFn, opts, attempt, 5)
+		if result.Error != nil {
+			t.Fatalf("attempt %d error = %v", attempt, result.Error)
+		}
+		if seen[result.AccountID] {
+			t.Fatalf("attempt %d returned %s again", attempt, result.AccountID)
+		}
+		seen[result.AccountID] = true
+		opts.MarkFailed(result.AccountID)
+	}
+
+	result := selectWithFailover(ctx, selectFn, opts, 3, 5)
+	if !errors.Is(result.Error, ErrFailoverExhausted) {
+		t.Fatalf("after pool exhausted error = %v, want ErrFailoverExhausted", result.Error)
+	}
+}
+
+func TestSelectWithFailover_RespectsMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	selectFn := poolSelector([]string{"acct-1", "acct-2", "acct-3"})
+	opts := SelectOptions{}
+	opts.MarkFailed("acct-1")
+	opts.MarkFailed("acct-1")
+
+	if len(opts.ExcludeAccountIDs) != 1 {
+		t.Errorf("MarkFailed should not duplicate, got %v", opts.ExcludeAccountIDs)
+	}
+
+	if result := selectWithFailover(ctx, selectFn, opts, 1, 1); result.Error != nil || result.AccountID != "acct-2" {
+		t.Errorf("attempt 1 = %+v, want acct-2", result)
+	}
+	if result := selectWithFailover(ctx, selectFn, opts, 2, 1); !errors.Is(result.Error, ErrFailoverExhausted) {
+		t.Errorf("attempt beyond max error = %v, want ErrFailoverExhausted", result.Error)
+	}
+}
+
+func TestMaxFailoverAttempts(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	config.Cfg = nil
+	if got := MaxFailoverAttempts(); got != 0 {
+		t.Errorf("without config = %d, want 0", got)
+	}
+
+	config.Cfg = &config.Config{System: config.SystemConfig{MaxFailoverAttempts: 2}}
+	if got := MaxFailoverAttempts(); got != 2 {
+		t.Errorf("configured = %d, want 2", got)
+	}
+}
@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"strings"
+	"sync"
+)
+
+// 请求所需的账户功能
+const (
+	FeatureVision   = "vision"
+	FeatureThinking = "thinking"
+	FeatureTools    = "tools"
+)
+
+// FeatureDetector 从请求体中检测所需功能
+type FeatureDetector func(payload map[string]interface{}) []string
+
+var (
+	featureDetectorsMu sync.RWMutex
+	featureDetectors   = map[AccountCategory]FeatureDetector{
+		CategoryClaude: DetectClaudeFeatures,
+		CategoryOpenAI: DetectOpenAIFeatures,
+		CategoryGemini: DetectGeminiFeatures,
+		CategoryDroid:  detectDroidFeatures,
+	}
+)
+
+// RegisterFeatureDetector 注册（或替换）指定类别的请求功能检测器
+func RegisterFeatureDetector(category AccountCategory, detector FeatureDetector) {
+	featureDetectorsMu.Lock()
+	defer featureDetectorsMu.Unlock()
+	featureDetectors[category] = detector
+}
+
+// DetectRequiredFeatures 按类别对应的请求格式检测所需功能
+func DetectRequiredFeatures(category AccountCategory, payload map[string]interface{}) []string {
+	if payload == nil {
+		return nil
+	}
+
+	featureDetectorsMu.RLock()
+	detector := featureDetectors[category]
+	featureDetectorsMu.RUnlock()
+
+	if detector == nil {
+		return nil
+	}
+	return detector(payload)
+}
+
+// ApplyRequestFeatures 检测请求所需功能并合并到 RequireFeatures
+func (o *SelectOptions) ApplyRequestFeatures(category AccountCategory, payload map[string]interface{}) {
+	for _, feature := range DetectRequiredFeatures(category, payload) {
+		if !contains(o.RequireFeatures, feature) {
+			o.RequireFeatures = append(o.RequireFeatures, feature)
+		}
+	}
+}
+
+// featureSet 按固定顺序收集功能
+type featureSet struct {
+	vision, thinking, tools bool
+}
+
+func (f featureSet) list() []string {
+	var features []string
+	if f.vision {
+		features = append(features, FeatureVision)
+	}
+	if f.thinking {
+		features = append(features, FeatureThinking)
+	}
+	if f.tools {
+		features = append(features, FeatureTools)
+	}
+	return features
+}
+
+// DetectClaudeFeatures 检测 Claude Messages API 请求所需功能
+func DetectClaudeFeatures(payload map[string]interface{}) []string {
+	var f featureSet
+
+	if thinking, ok := payload["thinking"].(map[string]interface{}); ok {
+		f.thinking = thinking["type"] != "disabled"
+	}
+	f.tools = nonEmptySlice(payload["tools"])
+
+	for _, block := range messageContentBlocks(payload["messages"], "content") {
+		switch block["type"] {
+		case "image":
+			f.vision = true
+		case "thinking", "redacted_thinking":
+			f.thinking = true
+		case "tool_use", "tool_result":
+			f.tools = true
+		}
+	}
+
+	return f.list()
+}
+
+// DetectOpenAIFeatures 检测 OpenAI Chat Completions / Responses API 请求所需功能
+func DetectOpenAIFeatures(payload map[string]interface{}) []string {
+	var f featureSet
+
+	if _, ok := payload["reasoning_effort"]; ok {
+		f.thinking = true
+	}
+	if _, ok := payload["reasoning"].(map[string]interface{}); ok {
+		f.thinking = true
+	}
+	f.tools = nonEmptySlice(payload["tools"]) || nonEmptySlice(payload["functions"])
+
+	blocks := messageContentBlocks(payload["messages"], "content")
+	blocks = append(blocks, messageContentBlocks(payload["input"], "content")...)
+	for _, block := range blocks {
+		switch block["type"] {
+		case "image_url", "input_image":
+			f.vision = true
+		case "function_call", "function_call_output":
+			f.tools = true
+		}
+	}
+
+	return f.list()
+}
+
+// DetectGeminiFeatures 检测 Gemini generateContent 请求所需功能
+func DetectGeminiFeatures(payload map[string]interface{}) []string {
+	var f featureSet
+
+	if cfg, ok := payload["generationConfig"].(map[string]interface{}); ok {
+		_, f.thinking = cfg["thinkingConfig"].(map[string]interface{})
+	}
+	f.tools = nonEmptySlice(payload["tools"])
+
+	for _, part := range messageContentBlocks(payload["contents"], "parts") {
+		for _, field := range []string{"inlineData", "fileData"} {
+			if data, ok := part[field].(map[string]interface{}); ok {
+				if mime, _ := data["mimeType"].(string); strings.HasPrefix(mime, "image/") {
+					f.vision = true
+				}
+			}
+		}
+		if _, ok := part["functionCall"]; ok {
+			f.tools = true
+		}
+		if _, ok := part["functionResponse"]; ok {
+			f.tools = true
+		}
+	}
+
+	return f.list()
+}
+
+// detectDroidFeatures Droid 同时转发 Anthropic 与 OpenAI 格式，合并两种检测结果
+func detectDroidFeatures(payload map[string]interface{}) []string {
+	var merged []string
+	for _, feature := range append(DetectClaudeFeatures(payload), DetectOpenAIFeatures(payload)...) {
+		if !contains(merged, feature) {
+			merged = append(merged, feature)
+		}
+	}
+	return merged
+}
+
+// messageContentBlocks 展开消息列表中的内容块（字符串内容不含功能块，直接跳过）
+func messageContentBlocks(messages interface{}, contentField string) []map[string]interface{} {
+	list, ok := messages.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var blocks []map[string]interface{}
+	for _, item := range list {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Responses API 的 input 项本身也可能是功能块
+		blocks = append(blocks, msg)
+		content, ok := msg[contentField].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range content {
+			if block, ok := c.(map[string]interface{}); ok {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	return blocks
+}
+
+// nonEmptySlice 检查值是否为非空数组
+func nonEmptySlice(v interface{}) bool {
+	list, ok := v.([]interface{})
+	return ok && len(list) > 0
+}
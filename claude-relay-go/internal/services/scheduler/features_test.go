@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func mustPayload(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	return payload
+}
+
+func TestDetectRequiredFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		category AccountCategory
+		body     string
+		want     []string
+	}{
+		{
+			name:     "claude plain text",
+			category: CategoryClaude,
+			body:     `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hello"}]}`,
+			want:     nil,
+		},
+		{
+			name:     "claude image",
+			category: CategoryClaude,
+			body:     `{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AA=="}},{"type":"text","text":"what is this"}]}]}`,
+			want:     []string{FeatureVision},
+		},
+		{
+			name:     "claude thinking and tools",
+			category: CategoryClaude,
+			body:     `{"thinking":{"type":"enabled","budget_tokens":1024},"tools":[{"name":"get_weather"}],"messages":[{"role":"user","content":"hi"}]}`,
+			want:     []string{FeatureThinking, FeatureTools},
+		},
+		{
+			name:     "claude thinking disabled",
+			category: CategoryClaude,
+			body:     `{"thinking":{"type":"disabled"},"messages":[]}`,
+			want:     nil,
+		},
+		{
+			name:     "openai image_url",
+			category: CategoryOpenAI,
+			body:     `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`,
+			want:     []string{FeatureVision},
+		},
+		{
+			name:     "openai responses reasoning",
+			category: CategoryOpenAI,
+			body:     `{"reasoning":{"effort":"high"},"input":[{"role":"user","content":[{"type":"input_text","text":"hi"}]}]}`,
+			want:     []string{FeatureThinking},
+		},
+		{
+			name:     "gemini inline image",
+			category: CategoryGemini,
+			body:     `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/jpeg","data":"AA=="}}]}]}`,
+			want:     []string{FeatureVision},
+		},
+		{
+			name:     "gemini plain text",
+			category: CategoryGemini,
+			body:     `{"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectRequiredFeatures(tt.category, mustPayload(t, tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectRequiredFeatures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectOptions_ApplyRequestFeatures(t *testing.T) {
+	opts := SelectOptions{RequireFeatures: []string{FeatureVision}}
+	opts.ApplyRequestFeatures(CategoryClaude, mustPayload(t,
+		`{"tools":[{"name":"x"}],"messages":[{"role":"user","content":[{"type":"image","source":{}}]}]}`))
+
+	want := []string{FeatureVision, FeatureTools}
+	if !reflect.DeepEqual(opts.RequireFeatures, want) {
+		t.Errorf("RequireFeatures = %v, want %v", opts.RequireFeatures, want)
+	}
+}
+
+func TestFilterAccounts_UndeclaredFeaturesAreCapable(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	s := &BaseScheduler{category: CategoryClaude}
+	accounts := []map[string]interface{}{
+		{"id": "undeclared"},
+		{"id": "text-only", "features": []interface{}{FeatureTools}},
+		{"id": "full", "features": []interface{}{FeatureTools, FeatureVision}},
+	}
+	opts := SelectOptions{}
+	opts.ApplyRequestFeatures(CategoryClaude, mustPayload(t,
+		`{"tools":[{"name":"x"}],"messages":[{"role":"user","content":[{"type":"image","source":{}}]}]}`))
+
+	got := filterIDs(t, s, opts, AccountTypeClaudeConsole, accounts, &fakeAccountProbe{})
+	if len(got) != 2 || !contains(got, "undeclared") || !contains(got, "full") {
+		t.Errorf("candidates = %v, want undeclared and full", got)
+	}
+
+	// 粘性会话绑定的账户同样按功能检查
+	if !s.sessionAccountUsable(&SelectResult{AccountID: "undeclared", Account: accounts[0]}, opts) {
+		t.Error("session account without declared features should stay usable")
+	}
+	if s.sessionAccountUsable(&SelectResult{AccountID: "text-only", Account: accounts[1]}, opts) {
+		t.Error("session account lacking vision should be reselected")
+	}
+}
+
+func TestRegisterFeatureDetector(t *testing.T) {
+	const category AccountCategory = "custom"
+	RegisterFeatureDetector(category, func(payload map[string]interface{}) []string {
+		return []string{"custom-feature"}
+	})
+	defer func() {
+		featureDetectorsMu.Lock()
+		delete(featureDetectors, category)
+		featureDetectorsMu.Unlock()
+	}()
+
+	got := DetectRequiredFeatures(category, map[string]interface{}{})
+	if !reflect.DeepEqual(got, []string{"custom-feature"}) {
+		t.Errorf("DetectRequiredFeatures() = %v", got)
+	}
+	if DetectRequiredFeatures("unknown", map[string]interface{}{}) != nil {
+		t.Error("unknown category should require no features")
+	}
+}
@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+)
+
+// RankedAccount 按选择顺序排列的候选账户（供客户端自行故障转移）
+type RankedAccount struct {
+	Rank           int                    `json:"rank"` // 从 1 开始，1 即 SelectBestAccount 会选中的账户
+	AccountID      string                 `json:"accountId"`
+	AccountType    AccountType            `json:"accountType"`
+	Priority       int                    `json:"priority"`
+	Load           float64                `json:"load"`
+	Score          float64                `json:"score"` // priority 策略为负载，cost-optimized 策略为加权得分，同优先级内越低越好
+	TransformHints *TransformHints        `json:"transformHints,omitempty"`
+	Account        map[string]interface{} `json:"-"`
+}
+
+// SelectRankedAccounts 返回前 n 个可用账户（按选择顺序，n <= 0 表示全部）
+// 过载、熔断、超出限额等不可调度的账户不会出现在列表中
+func (s *BaseScheduler) SelectRankedAccounts(ctx context.Context, opts SelectOptions, n int) []RankedAccount {
+	ctx, cancel := withSelectionDeadline(ctx)
+	defer cancel()
+
+	opts = s.applyAPIKeyExclusions(ctx, opts)
+	opts = s.applyRequestContext(ctx, opts)
+	candidates := s.CollectAvailableAccounts(ctx, opts)
+	return rankCandidates(candidates, opts, n)
+}
+
+// rankCandidates 按当前选择策略对候选账户排序，结果的第一项与 SelectBestAccount 一致
+func rankCandidates(candidates []AccountCandidate, opts SelectOptions, n int) []RankedAccount {
+	ranked := make([]AccountCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !isAccountExcluded(opts, c.AccountID) {
+			ranked = append(ranked, c)
+		}
+	}
+	if len(ranked) == 0 {
+		return []RankedAccount{}
+	}
+
+	score := func(c AccountCandidate) float64 { return c.Load }
+	less := func(a, b AccountCandidate) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Load < b.Load
+	}
+	if SelectionStrategy() == SelectionStrategyCostOptimized {
+		score = costOptimizedScorer(ranked, selectionCostWeight())
+		less = func(a, b AccountCandidate) bool {
+			if sa, sb := score(a), score(b); sa != sb {
+				return sa < sb
+			}
+			return a.Priority > b.Priority
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return less(ranked[i], ranked[j]) })
+
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+
+	result := make([]RankedAccount, len(ranked))
+	for i, c := range ranked {
+		result[i] = RankedAccount{
+			Rank:           i + 1,
+			AccountID:      c.AccountID,
+			AccountType:    c.AccountType,
+			Priority:       c.Priority,
+			Load:           c.Load,
+			Score:          score(c),
+			TransformHints: BuildTransformHints(c.AccountType, opts.Model, c.Account),
+			Account:        c.Account,
+		}
+	}
+	return result
+}
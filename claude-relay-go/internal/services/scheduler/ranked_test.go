@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func rankedIDs(ranked []RankedAccount) []string {
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.AccountID
+	}
+	return ids
+}
+
+func TestRankCandidates_FollowsSchedulerScoring(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+
+	candidates := []AccountCandidate{
+		{AccountID: "console", AccountType: AccountTypeClaudeConsole, Priority: 90, Load: 0, CostFactor: 1},
+		{AccountID: "official-busy", AccountType: AccountTypeClaude, Priority: 100, Load: 3, CostFactor: 1},
+		{AccountID: "bedrock", AccountType: AccountTypeBedrock, Priority: 80, Load: 1, CostFactor: 0.5},
+		{AccountID: "official-idle", AccountType: AccountTypeClaude, Priority: 100, Load: 1, CostFactor: 1},
+	}
+	s := &BaseScheduler{}
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want []string
+	}{
+		{"priority", &config.Config{}, []string{"official-idle", "official-busy", "console", "bedrock"}},
+		{"cost-optimized", &config.Config{System: config.SystemConfig{SelectionStrategy: SelectionStrategyCostOptimized}},
+			[]string{"bedrock", "console", "official-idle", "official-busy"}},
+	}
+	for _, tt := range tests {
+		config.Cfg = tt.cfg
+		ranked := rankCandidates(candidates, SelectOptions{}, 0)
+		got := rankedIDs(ranked)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: ranked = %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] || ranked[i].Rank != i+1 {
+				t.Errorf("%s: ranked = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		if best := s.SelectBestAccount(candidates); best.AccountID != got[0] {
+			t.Errorf("%s: first ranked %s, SelectBestAccount chose %s", tt.name, got[0], best.AccountID)
+		}
+		for i := 1; i < len(ranked); i++ {
+			if ranked[i].Priority == ranked[i-1].Priority && ranked[i].Score < ranked[i-1].Score {
+				t.Errorf("%s: scores out of order: %+v", tt.name, ranked)
+			}
+		}
+	}
+}
+
+func TestRankCandidates_RespectsExclusionsAndLimit(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+
+	candidates := []AccountCandidate{
+		{AccountID: "a", AccountType: AccountTypeClaude, Priority: 100, Load: 0},
+		{AccountID: "b", AccountType: AccountTypeClaude, Priority: 100, Load: 1},
+		{AccountID: "c", AccountType: AccountTypeClaude, Priority: 100, Load: 2},
+		{AccountID: "d", AccountType: AccountTypeClaude, Priority: 100, Load: 3},
+	}
+	opts := SelectOptions{ExcludeAccountIDs: []string{"a", "c"}}
+
+	if got := rankedIDs(rankCandidates(candidates, opts, 0)); len(got) != 2 || got[0] != "b" || got[1] != "d" {
+		t.Errorf("excluded ranking = %v, want [b d]", got)
+	}
+	if got := rankedIDs(rankCandidates(candidates, SelectOptions{}, 2)); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("top-2 ranking = %v, want [a b]", got)
+	}
+	if got := rankCandidates(nil, SelectOptions{}, 3); got == nil || len(got) != 0 {
+		t.Errorf("empty ranking = %#v, want empty slice", got)
+	}
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import "context"
+
+// requestContextKey 请求上下文中调度相关值的键
+type requestContextKey string
+
+const (
+	ctxKeyPreferredAccountType requestContextKey = "preferredAccountType"
+	ctxKeyClientType           requestContextKey = "clientType"
+)
+
+// WithPreferredAccountType 在请求上下文中记录优先路由的账户类型（如未指定模型的请求路由到默认账户类型）
+func WithPreferredAccountType(ctx context.Context, accountType AccountType) context.Context {
+	if accountType == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyPreferredAccountType, accountType)
+}
+
+// PreferredAccountTypeFromContext 获取请求上下文中记录的优先账户类型（未记录时为空）
+func PreferredAccountTypeFromContext(ctx context.Context) AccountType {
+	accountType, _ := ctx.Value(ctxKeyPreferredAccountType).(AccountType)
+	return accountType
+}
+
+// WithClientType 在请求上下文中记录请求的客户端类型（认证中间件解析 User-Agent 后写入）
+func WithClientType(ctx context.Context, clientType string) context.Context {
+	if clientType == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyClientType, clientType)
+}
+
+// ClientTypeFromContext 获取请求上下文中记录的客户端类型（未记录时为空）
+func ClientTypeFromContext(ctx context.Context) string {
+	clientType, _ := ctx.Value(ctxKeyClientType).(string)
+	return clientType
+}
+
+// applyRequestContext 用请求上下文补全调用方未指定的选项
+// 优先账户类型仅在属于当前调度器类别时生效，其他类别的调度不受影响
+func (s *BaseScheduler) applyRequestContext(ctx context.Context, opts SelectOptions) SelectOptions {
+	if len(opts.PreferredAccountTypes) == 0 {
+		if accountType := PreferredAccountTypeFromContext(ctx); accountType != "" && AccountTypeToCategory[accountType] == s.category {
+			opts.PreferredAccountTypes = []AccountType{accountType}
+		}
+	}
+	if opts.ClientType == "" {
+		opts.ClientType = ClientTypeFromContext(ctx)
+	}
+	return opts
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// 上游请求格式
+const (
+	FormatAnthropic       = "anthropic"
+	FormatBedrock         = "bedrock"
+	FormatOpenAIChat      = "openai-chat"
+	FormatOpenAIResponses = "openai-responses"
+	FormatGemini          = "gemini"
+)
+
+const (
+	anthropicAPIVersion     = "2023-06-01"
+	anthropicOAuthBeta      = "oauth-2025-04-20"
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+	defaultAzureAPIVersion  = "2024-02-01"
+)
+
+// TransformHints 选中账户转发请求时需要的转换（由账户类型与模型决定）
+type TransformHints struct {
+	Format       string                 `json:"format"`                // 上游请求格式
+	EndpointPath string                 `json:"endpointPath"`          // 上游端点路径（相对账户的 baseURL）
+	Headers      map[string]string      `json:"headers,omitempty"`     // 需要追加的请求头
+	BodyFields   map[string]interface{} `json:"bodyFields,omitempty"`  // 需要写入请求体的字段
+	StripFields  []string               `json:"stripFields,omitempty"` // 需要从请求体移除的字段
+}
+
+// BuildTransformHints 根据账户类型与模型生成转换提示（未知账户类型返回 nil）
+func BuildTransformHints(accountType AccountType, model string, account map[string]interface{}) *TransformHints {
+	switch accountType {
+	case AccountTypeClaude, AccountTypeClaudeOfficial:
+		return &TransformHints{
+			Format:       FormatAnthropic,
+			EndpointPath: "/v1/messages",
+			Headers: map[string]string{
+				"anthropic-version": anthropicAPIVersion,
+				"anthropic-beta":    anthropicOAuthBeta,
+			},
+		}
+	case AccountTypeClaudeConsole, AccountTypeCCR:
+		return &TransformHints{
+			Format:       FormatAnthropic,
+			EndpointPath: "/v1/messages",
+			Headers:      map[string]string{"anthropic-version": anthropicAPIVersion},
+		}
+	case AccountTypeBedrock:
+		// Bedrock 通过路径指定模型，请求体使用 Bedrock 的 anthropic_version 且不带 model/stream
+		return &TransformHints{
+			Format:       FormatBedrock,
+			EndpointPath: "/model/" + url.PathEscape(model) + "/invoke",
+			BodyFields:   map[string]interface{}{"anthropic_version": bedrockAnthropicVersion},
+			StripFields:  []string{"model", "stream"},
+		}
+	case AccountTypeGemini, AccountTypeGeminiAPI:
+		return &TransformHints{
+			Format:       FormatGemini,
+			EndpointPath: "/v1beta/models/" + model + ":generateContent",
+		}
+	case AccountTypeOpenAI, AccountTypeOpenAIResponses:
+		return &TransformHints{
+			Format:       FormatOpenAIResponses,
+			EndpointPath: "/responses",
+		}
+	case AccountTypeAzureOpenAI:
+		return azureTransformHints(model, account)
+	case AccountTypeDroid:
+		// Droid 按模型分流：Claude 模型走 Anthropic 格式，其余走 OpenAI Responses
+		if strings.HasPrefix(strings.ToLower(model), "claude") {
+			return &TransformHints{
+				Format:       FormatAnthropic,
+				EndpointPath: "/v1/messages",
+				Headers:      map[string]string{"anthropic-version": anthropicAPIVersion},
+			}
+		}
+		return &TransformHints{
+			Format:       FormatOpenAIResponses,
+			EndpointPath: "/v1/responses",
+		}
+	}
+	return nil
+}
+
+// azureTransformHints Azure OpenAI 使用部署名称与 api-version 组成端点
+func azureTransformHints(model string, account map[string]interface{}) *TransformHints {
+	deployment := stringField(account, "deploymentId")
+	if deployment == "" {
+		deployment = stringField(account, "deploymentName")
+	}
+	if deployment == "" {
+		deployment = model
+	}
+	apiVersion := stringField(account, "apiVersion")
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	return &TransformHints{
+		Format:       FormatOpenAIChat,
+		EndpointPath: "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions?api-version=" + url.QueryEscape(apiVersion),
+		StripFields:  []string{"model"},
+	}
+}
+
+// stringField 读取账户中的字符串字段
+func stringField(account map[string]interface{}, field string) string {
+	if value, ok := account[field].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// withTransformHints 为成功的选择结果附加转换提示
+func withTransformHints(result *SelectResult, model string) *SelectResult {
+	if result != nil && result.Error == nil {
+		result.TransformHints = BuildTransformHints(result.AccountType, model, result.Account)
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBuildTransformHints_Bedrock(t *testing.T) {
+	hints := BuildTransformHints(AccountTypeBedrock, "anthropic.claude-3-5-sonnet-20241022-v2:0", nil)
+	if hints == nil {
+		t.Fatal("expected hints for bedrock account")
+	}
+	if hints.Format != FormatBedrock {
+		t.Errorf("Format = %q, want %q", hints.Format, FormatBedrock)
+	}
+	if hints.EndpointPath != "/model/anthropic.claude-3-5-sonnet-20241022-v2:0/invoke" {
+		t.Errorf("EndpointPath = %q", hints.EndpointPath)
+	}
+	if hints.BodyFields["anthropic_version"] != bedrockAnthropicVersion {
+		t.Errorf("BodyFields = %v", hints.BodyFields)
+	}
+	if !reflect.DeepEqual(hints.StripFields, []string{"model", "stream"}) {
+		t.Errorf("StripFields = %v", hints.StripFields)
+	}
+	if len(hints.Headers) != 0 {
+		t.Errorf("Headers = %v, want none", hints.Headers)
+	}
+}
+
+func TestBuildTransformHints_OfficialDiffersFromBedrock(t *testing.T) {
+	model := "claude-sonnet-4-20250514"
+	official := BuildTransformHints(AccountTypeClaudeOfficial, model, nil)
+	bedrock := BuildTransformHints(AccountTypeBedrock, model, nil)
+
+	if official.Format != FormatAnthropic || official.EndpointPath != "/v1/messages" {
+		t.Errorf("official = %+v", official)
+	}
+	if official.Headers["anthropic-version"] != anthropicAPIVersion || official.Headers["anthropic-beta"] != anthropicOAuthBeta {
+		t.Errorf("official headers = %v", official.Headers)
+	}
+	if len(official.StripFields) != 0 || len(official.BodyFields) != 0 {
+		t.Errorf("official should not rewrite the body: %+v", official)
+	}
+	if reflect.DeepEqual(official, bedrock) {
+		t.Error("official and bedrock hints should differ")
+	}
+}
+
+func TestBuildTransformHints_AzureUsesDeployment(t *testing.T) {
+	hints := BuildTransformHints(AccountTypeAzureOpenAI, "gpt-4o", map[string]interface{}{
+		"deploymentName": "prod-gpt4o",
+		"apiVersion":     "2024-06-01",
+	})
+	if hints.Format != FormatOpenAIChat {
+		t.Errorf("Format = %q", hints.Format)
+	}
+	if hints.EndpointPath != "/openai/deployments/prod-gpt4o/chat/completions?api-version=2024-06-01" {
+		t.Errorf("EndpointPath = %q", hints.EndpointPath)
+	}
+}
+
+func TestWithTransformHints_SkipsFailedSelection(t *testing.T) {
+	failed := withTransformHints(&SelectResult{AccountType: AccountTypeBedrock, Error: errors.New("none")}, "m")
+	if failed.TransformHints != nil {
+		t.Errorf("failed selection got hints: %+v", failed.TransformHints)
+	}
+
+	selected := withTransformHints(&SelectResult{AccountType: AccountTypeBedrock, AccountID: "b1"}, "m")
+	if selected.TransformHints == nil || selected.TransformHints.Format != FormatBedrock {
+		t.Errorf("selected hints = %+v", selected.TransformHints)
+	}
+}
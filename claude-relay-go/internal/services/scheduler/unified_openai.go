@@ -39,23 +39,31 @@ func (s *UnifiedOpenAIScheduler) WithStickySessionTTL(ttl time.Duration) *Unifie
 
 // SelectAccount 选择最优账户
 func (s *UnifiedOpenAIScheduler) SelectAccount(ctx context.Context, opts SelectOptions) *SelectResult {
-	// 1. 检查粘性会话
+	// 选择过程受截止时间限制（会话绑定仍使用原始 ctx）
+	selectCtx, cancel := withSelectionDeadline(ctx)
+	defer cancel()
+
+	// 合并 API Key 屏蔽的账户
+	opts = s.applyAPIKeyExclusions(selectCtx, opts)
+	opts = s.applyRequestContext(ctx, opts)
+
+	// 1. 检查粘性会话（绑定账户被屏蔽或不满足本次请求时重新选择）
 	if opts.SessionHash != "" {
-		if result := s.GetSessionAccount(ctx, opts.SessionHash, opts.Model); result != nil {
-			return result
+		if result := s.GetSessionAccount(selectCtx, opts.SessionHash, opts.Model); s.sessionAccountUsable(result, opts) {
+			return withTransformHints(result, opts.Model)
 		}
 	}
 
 	// 2. 收集所有可用账户
-	candidates := s.CollectAvailableAccounts(ctx, opts)
+	candidates, timedOut := s.collectCandidates(selectCtx, opts)
 	if len(candidates) == 0 {
 		return &SelectResult{
-			Error: fmt.Errorf("no available OpenAI accounts for model: %s", opts.Model),
+			Error: noAvailableAccountsError("OpenAI", opts.Model, timedOut),
 		}
 	}
 
-	// 3. 按优先级和负载选择最优账户
-	selected := s.SelectBestAccount(candidates)
+	// 3. 按优先级和负载选择最优账户（高优先级 Key 可使用预留账户，启用账户亲和的 Key 优先使用亲和账户）
+	selected := s.SelectAccountWithAffinity(ctx, opts, candidates)
 	if selected == nil {
 		return &SelectResult{
 			Error: fmt.Errorf("failed to select OpenAI account"),
@@ -74,7 +82,7 @@ func (s *UnifiedOpenAIScheduler) SelectAccount(ctx context.Context, opts SelectO
 		zap.String("accountId", selected.AccountID),
 		zap.String("model", opts.Model))
 
-	return selected
+	return withTransformHints(selected, opts.Model)
 }
 
 // SelectAccountForModel 为特定模型选择账户（简化方法）
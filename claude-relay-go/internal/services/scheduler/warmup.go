@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// defaultWarmupInitialPercent 预热开始时默认的流量比例
+const defaultWarmupInitialPercent = 10
+
+// AccountWarmupDuration 获取账户（重新）激活后的预热时长（0 表示不预热）
+func AccountWarmupDuration() time.Duration {
+	if config.Cfg != nil && config.Cfg.System.AccountWarmupDuration > 0 {
+		return config.Cfg.System.AccountWarmupDuration
+	}
+	return 0
+}
+
+// accountWarmupInitialPercent 获取预热开始时的流量比例（1-100）
+func accountWarmupInitialPercent() int {
+	percent := defaultWarmupInitialPercent
+	if config.Cfg != nil && config.Cfg.System.AccountWarmupInitialPercent > 0 {
+		percent = config.Cfg.System.AccountWarmupInitialPercent
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// accountWarmupStart 获取账户预热的起点（reenabledAt，仅在切换为 active 时写入；缺失时不预热）
+func accountWarmupStart(account map[string]interface{}) time.Time {
+	if value, ok := account["reenabledAt"].(string); ok && value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// warmupFraction 计算账户当前可承接的流量比例：从 initialPercent 线性升至 1，预热期外为 1
+func warmupFraction(account map[string]interface{}, now time.Time, duration time.Duration, initialPercent int) float64 {
+	if duration <= 0 {
+		return 1
+	}
+	start := accountWarmupStart(account)
+	if start.IsZero() {
+		return 1
+	}
+	elapsed := now.Sub(start)
+	if elapsed >= duration {
+		return 1
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	initial := float64(initialPercent) / 100
+	return initial + (1-initial)*float64(elapsed)/float64(duration)
+}
+
+// applyWarmup 按预热比例随机剔除处于预热期的候选账户（roll 返回 [0,1) 的随机数）
+// 全部候选都被剔除时保留原列表，避免预热导致无账户可用
+func applyWarmup(candidates []AccountCandidate, now time.Time, duration time.Duration, initialPercent int, roll func() float64) []AccountCandidate {
+	if duration <= 0 || len(candidates) == 0 {
+		return candidates
+	}
+
+	admitted := make([]AccountCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if fraction := warmupFraction(c.Account, now, duration, initialPercent); fraction < 1 && roll() >= fraction {
+			continue
+		}
+		admitted = append(admitted, c)
+	}
+	if len(admitted) == 0 {
+		return candidates
+	}
+	return admitted
+}
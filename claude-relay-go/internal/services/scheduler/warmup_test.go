@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestApplyWarmup_RampsTrafficToRecentlyActivatedAccount(t *testing.T) {
+	activatedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	duration := 10 * time.Minute
+	candidates := []AccountCandidate{
+		// 预热中的账户优先级更高，一旦准入总会被选中
+		{AccountID: "warming", Priority: 100, Account: map[string]interface{}{
+			"reenabledAt": activatedAt.Format(time.RFC3339),
+		}},
+		{AccountID: "steady", Priority: 50, Account: map[string]interface{}{
+			"reenabledAt": activatedAt.Add(-24 * time.Hour).Format(time.RFC3339),
+		}},
+	}
+
+	s := &BaseScheduler{}
+	share := func(now time.Time) float64 {
+		roll := rand.New(rand.NewSource(1)).Float64
+		const requests = 2000
+		hits := 0
+		for i := 0; i < requests; i++ {
+			if s.SelectBestAccount(applyWarmup(candidates, now, duration, 10, roll)).AccountID == "warming" {
+				hits++
+			}
+		}
+		return float64(hits) / requests
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		min, max float64
+	}{
+		{"just activated", activatedAt, 0.05, 0.15},
+		{"half way", activatedAt.Add(duration / 2), 0.45, 0.65},
+		{"after warmup", activatedAt.Add(duration), 1, 1},
+	}
+	for _, tt := range tests {
+		if got := share(tt.now); got < tt.min || got > tt.max {
+			t.Errorf("%s: warming account share = %.3f, want [%.2f, %.2f]", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestApplyWarmup_KeepsCandidatesWhenAllWarming(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	candidates := []AccountCandidate{
+		{AccountID: "a", Account: map[string]interface{}{"reenabledAt": now.Format(time.RFC3339)}},
+	}
+
+	got := applyWarmup(candidates, now, time.Minute, 10, func() float64 { return 0.99 })
+	if len(got) != 1 {
+		t.Fatalf("got %d candidates, want the warming account kept as last resort", len(got))
+	}
+}
+
+func TestWarmupFraction_UsesOnlyReenabledAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	account := map[string]interface{}{"reenabledAt": now.Add(-5 * time.Minute).Format(time.RFC3339)}
+
+	if got := warmupFraction(account, now, 10*time.Minute, 20); math.Abs(got-0.6) > 1e-9 {
+		t.Errorf("fraction = %v, want 0.6", got)
+	}
+	// 普通字段更新（updatedAt）不触发预热
+	edited := map[string]interface{}{"updatedAt": now.Format(time.RFC3339)}
+	if got := warmupFraction(edited, now, 10*time.Minute, 20); got != 1 {
+		t.Errorf("updatedAt-only fraction = %v, want 1", got)
+	}
+	if got := warmupFraction(account, now, 0, 20); got != 1 {
+		t.Errorf("disabled warmup fraction = %v, want 1", got)
+	}
+	if got := warmupFraction(map[string]interface{}{}, now, 10*time.Minute, 20); got != 1 {
+		t.Errorf("unknown start fraction = %v, want 1", got)
+	}
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout 单次发送默认超时
+const DefaultTimeout = 10 * time.Second
+
+// 事件类型
+const (
+	EventCostAlert = "cost_alert"
+)
+
+// Event Webhook 通知内容
+type Event struct {
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Notifier 将事件以 JSON POST 到配置的 Webhook 地址
+type Notifier struct {
+	urls   []string
+	client *http.Client
+}
+
+// NewNotifier 创建通知器（urls 为空时不发送）
+func NewNotifier(urls []string, timeout time.Duration) *Notifier {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Notifier{
+		urls:   urls,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// NewNotifierFromConfig 根据全局配置创建通知器
+func NewNotifierFromConfig() *Notifier {
+	if config.Cfg == nil {
+		return NewNotifier(nil, DefaultTimeout)
+	}
+	return NewNotifier(config.Cfg.Webhook.URLs, config.Cfg.Webhook.Timeout)
+}
+
+// Enabled 是否配置了 Webhook 地址
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.urls) > 0
+}
+
+// Send 同步发送事件到所有地址，返回各地址的发送错误
+func (n *Notifier) Send(ctx context.Context, event Event) error {
+	if !n.Enabled() {
+		return nil
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	var errs []error
+	for _, url := range n.urls {
+		if err := n.post(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notify 异步发送事件（失败只记录日志，不影响调用方）
+func (n *Notifier) Notify(event Event) {
+	if !n.Enabled() {
+		return
+	}
+	go func() {
+		if err := n.Send(context.Background(), event); err != nil {
+			logger.Warn("Failed to send webhook notification",
+				zap.String("type", event.Type),
+				zap.Error(err))
+		}
+	}()
+}
+
+// post 发送单个请求
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
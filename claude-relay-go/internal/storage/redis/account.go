@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -28,6 +29,19 @@ const (
 	AccountTypeCCR             AccountType = "ccr"
 )
 
+// accountCategoryTypes 账户类别包含的账户类型
+var accountCategoryTypes = map[string][]AccountType{
+	"claude": {AccountTypeClaude, AccountTypeClaudeConsole, AccountTypeBedrock, AccountTypeCCR},
+	"gemini": {AccountTypeGemini, AccountTypeGeminiAPI},
+	"openai": {AccountTypeOpenAI, AccountTypeOpenAIResponses, AccountTypeAzureOpenAI},
+	"droid":  {AccountTypeDroid},
+}
+
+// GetAccountTypesByCategory 获取类别包含的账户类型（未知类别返回 nil）
+func GetAccountTypesByCategory(category string) []AccountType {
+	return accountCategoryTypes[category]
+}
+
 // getAccountPrefix 获取账户类型对应的 Redis 前缀
 func getAccountPrefix(accountType AccountType) string {
 	switch accountType {
@@ -65,6 +79,8 @@ type BaseAccount struct {
 	AccountType string    `json:"accountType"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt,omitempty"`
+	// 最近一次由非 active 状态切换为 active 的时间，调度器据此计算预热期
+	ReenabledAt time.Time `json:"reenabledAt,omitempty"`
 
 	// 代理配置
 	ProxyEnabled  bool   `json:"proxyEnabled,omitempty"`
@@ -76,13 +92,17 @@ type BaseAccount struct {
 
 	// 错误信息
 	LastError   string     `json:"lastError,omitempty"`
-	ErrorCount  int        `json:"errorCount,omitempty"`
+	ErrorCount  int        `json:"errorCount,omitempty"` // 统计窗口内错误数
+	ErrorTotal  int64      `json:"errorTotal,omitempty"` // 累计错误数
 	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
 
 	// 过载状态
 	IsOverloaded    bool       `json:"isOverloaded,omitempty"`
 	OverloadedAt    *time.Time `json:"overloadedAt,omitempty"`
 	OverloadedUntil *time.Time `json:"overloadedUntil,omitempty"`
+
+	// 用量上限
+	AccountDailyTokenLimit int64 `json:"accountDailyTokenLimit,omitempty"` // 每日 Token 上限（0 表示不限制）
 }
 
 // ClaudeAccount Claude 账户（官方 OAuth）
@@ -181,6 +201,7 @@ func (c *Client) SetAccount(ctx context.Context, accountType AccountType, accoun
 	if err := client.Set(ctx, key, jsonData, 0).Err(); err != nil {
 		return fmt.Errorf("failed to save account: %w", err)
 	}
+	c.notifyAccountChanged(accountType, accountID)
 
 	logger.Info("Account saved",
 		zap.String("type", string(accountType)),
@@ -210,6 +231,84 @@ func (c *Client) GetAccountRaw(ctx context.Context, accountType AccountType, acc
 	return []byte(data), nil
 }
 
+// 账户字段级更新
+const (
+	// accountUpdateMaxRetries 并发写入冲突时的最大重试次数
+	accountUpdateMaxRetries = 10
+
+	// luaAccountCompareAndSet 账户 JSON 比较并替换（期间被其他写入修改时放弃，由调用方重试）
+	luaAccountCompareAndSet = `
+local current = redis.call('GET', KEYS[1])
+if current ~= ARGV[1] then
+    return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return 1
+`
+)
+
+// ErrAccountNotFound 账户不存在
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrAccountUpdateConflict 并发写入冲突重试耗尽
+var ErrAccountUpdateConflict = errors.New("account update conflict")
+
+// UpdateAccountFields 原子更新账户的部分字段（值为 nil 时删除该字段），其余字段保持不变
+// 账户仍以 JSON 字符串存储，GetAccountRaw 始终返回完整文档，已有账户无需迁移
+func (c *Client) UpdateAccountFields(ctx context.Context, accountType AccountType, accountID string, fields map[string]interface{}) error {
+	return c.updateAccount(ctx, accountType, accountID, func(data map[string]interface{}) {
+		for field, value := range fields {
+			if value == nil {
+				delete(data, field)
+				continue
+			}
+			data[field] = value
+		}
+	})
+}
+
+// updateAccount 读取-修改-比较写入账户，期间被并发修改时基于最新数据重试
+func (c *Client) updateAccount(ctx context.Context, accountType AccountType, accountID string, mutate func(data map[string]interface{})) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key := getAccountPrefix(accountType) + accountID
+
+	for attempt := 0; attempt < accountUpdateMaxRetries; attempt++ {
+		current, err := client.Get(ctx, key).Result()
+		if err != nil {
+			if err == goredis.Nil {
+				return ErrAccountNotFound
+			}
+			return fmt.Errorf("failed to get account: %w", err)
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(current), &data); err != nil {
+			return fmt.Errorf("failed to unmarshal account: %w", err)
+		}
+		mutate(data)
+
+		updated, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal account: %w", err)
+		}
+
+		swapped, err := client.Eval(ctx, luaAccountCompareAndSet, []string{key}, current, string(updated)).Int()
+		if err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		if swapped == 1 {
+			c.notifyAccountChanged(accountType, accountID)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrAccountUpdateConflict, accountID)
+}
+
 // GetAccount 获取账户（通用方法）
 func (c *Client) GetAccount(ctx context.Context, accountType AccountType, accountID string) (map[string]interface{}, error) {
 	data, err := c.GetAccountRaw(ctx, accountType, accountID)
@@ -225,6 +324,11 @@ func (c *Client) GetAccount(ctx context.Context, accountType AccountType, accoun
 	return result, nil
 }
 
+// accountExists 检查账户是否存在
+func (c *Client) accountExists(ctx context.Context, accountType AccountType, accountID string) (bool, error) {
+	return c.Exists(ctx, getAccountPrefix(accountType)+accountID)
+}
+
 // DeleteAccount 删除账户
 func (c *Client) DeleteAccount(ctx context.Context, accountType AccountType, accountID string) error {
 	client, err := c.GetClientSafe()
@@ -235,8 +339,11 @@ func (c *Client) DeleteAccount(ctx context.Context, accountType AccountType, acc
 	prefix := getAccountPrefix(accountType)
 	key := prefix + accountID
 
-	_, err = client.Del(ctx, key).Result()
-	return err
+	if _, err := client.Del(ctx, key).Result(); err != nil {
+		return err
+	}
+	c.notifyAccountChanged(accountType, accountID)
+	return nil
 }
 
 // AccountBatchSize 账户批量获取大小
@@ -306,6 +413,81 @@ func (c *Client) GetAllAccountsRaw(ctx context.Context, accountType AccountType)
 	return results, nil
 }
 
+// 账户导出分页参数
+const (
+	// DefaultAccountExportCount 默认每页 SCAN 数量
+	DefaultAccountExportCount = 100
+	// MaxAccountExportCount 每页 SCAN 数量上限
+	MaxAccountExportCount = 1000
+)
+
+// AccountExportPage 账户导出分页结果
+type AccountExportPage struct {
+	Accounts   []map[string]interface{} `json:"accounts"`
+	NextCursor uint64                   `json:"nextCursor,string"` // 为 0 表示遍历结束
+	Done       bool                     `json:"done"`
+}
+
+// ExportAccountsPage 基于 Redis SCAN 游标导出一页账户
+// 每次只执行一次 SCAN，不加载全部键；cursor 为 0 时从头开始。
+// SCAN 语义下单页可能为空但游标未结束，调用方应持续请求直到 Done 为 true
+func (c *Client) ExportAccountsPage(ctx context.Context, accountType AccountType, cursor uint64, count int64) (*AccountExportPage, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	if count <= 0 {
+		count = DefaultAccountExportCount
+	}
+	if count > MaxAccountExportCount {
+		count = MaxAccountExportCount
+	}
+
+	prefix := getAccountPrefix(accountType)
+	keys, nextCursor, err := client.Scan(ctx, cursor, prefix+"*", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan accounts: %w", err)
+	}
+
+	page := &AccountExportPage{
+		Accounts:   make([]map[string]interface{}, 0, len(keys)),
+		NextCursor: nextCursor,
+		Done:       nextCursor == 0,
+	}
+	if len(keys) == 0 {
+		return page, nil
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to batch get accounts: %w", err)
+	}
+
+	for i, cmd := range cmds {
+		data, err := cmd.Result()
+		if err != nil {
+			// 键在 SCAN 与 GET 之间被删除
+			continue
+		}
+
+		accountID := strings.TrimPrefix(keys[i], prefix)
+		var account map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &account); err != nil {
+			logger.Warn("Failed to unmarshal account", zap.String("id", accountID), zap.Error(err))
+			continue
+		}
+		account["id"] = accountID
+		page.Accounts = append(page.Accounts, account)
+	}
+
+	return page, nil
+}
+
 // GetAllAccounts 获取所有指定类型的账户（复用 GetAllAccountsRaw 避免代码重复）
 func (c *Client) GetAllAccounts(ctx context.Context, accountType AccountType) ([]map[string]interface{}, error) {
 	rawData, err := c.GetAllAccountsRaw(ctx, accountType)
@@ -461,82 +643,193 @@ func (c *Client) GetAllBedrockAccounts(ctx context.Context) ([]*BedrockAccount,
 
 // ========== 账户状态管理 ==========
 
-// UpdateAccountStatus 更新账户状态
+// UpdateAccountStatus 更新账户状态（由非 active 切换为 active 时记录 reenabledAt）
 func (c *Client) UpdateAccountStatus(ctx context.Context, accountType AccountType, accountID, status string) error {
-	data, err := c.GetAccount(ctx, accountType, accountID)
-	if err != nil || data == nil {
-		return fmt.Errorf("account not found")
-	}
-
-	data["status"] = status
-	data["updatedAt"] = time.Now().Format(time.RFC3339)
-
-	return c.SetAccount(ctx, accountType, accountID, data)
+	now := time.Now().Format(time.RFC3339)
+	return c.updateAccount(ctx, accountType, accountID, func(data map[string]interface{}) {
+		if previous, _ := data["status"].(string); status == "active" && previous != "active" {
+			data["reenabledAt"] = now
+		}
+		data["status"] = status
+		data["updatedAt"] = now
+	})
 }
 
 // SetAccountError 设置账户错误状态
 func (c *Client) SetAccountError(ctx context.Context, accountType AccountType, accountID, errorMsg string) error {
-	data, err := c.GetAccount(ctx, accountType, accountID)
-	if err != nil || data == nil {
-		return fmt.Errorf("account not found")
-	}
+	return c.SetAccountErrorWithEvent(ctx, accountType, accountID, errorMsg, "")
+}
 
-	data["lastError"] = errorMsg
-	data["lastErrorAt"] = time.Now().Format(time.RFC3339)
+// SetAccountErrorWithEvent 设置账户错误状态（eventID 非空时同一事件重放不会重复计数）
+// errorCount 为统计窗口内的错误数，errorTotal 为累计错误数
+func (c *Client) SetAccountErrorWithEvent(ctx context.Context, accountType AccountType, accountID, errorMsg, eventID string) error {
+	exists, err := c.accountExists(ctx, accountType, accountID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccountNotFound
+	}
 
-	errorCount := 0
-	if count, ok := data["errorCount"].(float64); ok {
-		errorCount = int(count)
+	stats, recorded, err := c.RecordAccountError(ctx, accountType, accountID, eventID)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		return nil
 	}
-	data["errorCount"] = errorCount + 1
 
-	return c.SetAccount(ctx, accountType, accountID, data)
+	return c.UpdateAccountFields(ctx, accountType, accountID, map[string]interface{}{
+		"lastError":   errorMsg,
+		"lastErrorAt": time.Now().Format(time.RFC3339),
+		"errorCount":  stats.Recent,
+		"errorTotal":  stats.Total,
+	})
 }
 
 // ClearAccountError 清除账户错误状态
 func (c *Client) ClearAccountError(ctx context.Context, accountType AccountType, accountID string) error {
-	data, err := c.GetAccount(ctx, accountType, accountID)
-	if err != nil || data == nil {
-		return fmt.Errorf("account not found")
+	exists, err := c.accountExists(ctx, accountType, accountID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccountNotFound
 	}
 
-	delete(data, "lastError")
-	delete(data, "lastErrorAt")
-	data["errorCount"] = 0
-	data["updatedAt"] = time.Now().Format(time.RFC3339)
+	// 累计错误数保留，仅清空窗口内错误
+	if err := c.clearAccountErrorWindow(ctx, accountType, accountID); err != nil {
+		return fmt.Errorf("failed to clear account error window: %w", err)
+	}
 
-	return c.SetAccount(ctx, accountType, accountID, data)
+	return c.UpdateAccountFields(ctx, accountType, accountID, map[string]interface{}{
+		"lastError":   nil,
+		"lastErrorAt": nil,
+		"errorCount":  0,
+	})
 }
 
 // SetAccountOverloaded 设置账户过载状态
 func (c *Client) SetAccountOverloaded(ctx context.Context, accountType AccountType, accountID string, duration time.Duration) error {
-	data, err := c.GetAccount(ctx, accountType, accountID)
-	if err != nil || data == nil {
-		return fmt.Errorf("account not found")
-	}
-
 	now := time.Now()
-	data["isOverloaded"] = true
-	data["overloadedAt"] = now.Format(time.RFC3339)
-	data["overloadedUntil"] = now.Add(duration).Format(time.RFC3339)
-	data["updatedAt"] = now.Format(time.RFC3339)
-
-	return c.SetAccount(ctx, accountType, accountID, data)
+	return c.UpdateAccountFields(ctx, accountType, accountID, map[string]interface{}{
+		"isOverloaded":    true,
+		"overloadedAt":    now.Format(time.RFC3339),
+		"overloadedUntil": now.Add(duration).Format(time.RFC3339),
+		"updatedAt":       now.Format(time.RFC3339),
+	})
 }
 
 // ClearAccountOverloaded 清除账户过载状态
 func (c *Client) ClearAccountOverloaded(ctx context.Context, accountType AccountType, accountID string) error {
-	data, err := c.GetAccount(ctx, accountType, accountID)
-	if err != nil || data == nil {
-		return fmt.Errorf("account not found")
+	now := time.Now().Format(time.RFC3339)
+	return c.UpdateAccountFields(ctx, accountType, accountID, map[string]interface{}{
+		"isOverloaded":    false,
+		"overloadedAt":    nil,
+		"overloadedUntil": nil,
+		"updatedAt":       now,
+	})
+}
+
+// ClearOverloadedAccounts 批量清除指定类型中处于过载状态的账户，返回清除数量
+// 未过载的账户不会被改写；单个账户清除失败时记录日志并继续
+func (c *Client) ClearOverloadedAccounts(ctx context.Context, accountTypes ...AccountType) (int, error) {
+	cleared := 0
+	for _, accountType := range accountTypes {
+		accounts, err := c.GetAllAccounts(ctx, accountType)
+		if err != nil {
+			return cleared, fmt.Errorf("failed to list %s accounts: %w", accountType, err)
+		}
+
+		for _, account := range accounts {
+			if overloaded, _ := account["isOverloaded"].(bool); !overloaded {
+				continue
+			}
+
+			accountID, _ := account["id"].(string)
+			if err := c.ClearAccountOverloaded(ctx, accountType, accountID); err != nil {
+				logger.Warn("Failed to clear account overloaded status",
+					zap.String("type", string(accountType)),
+					zap.String("id", accountID),
+					zap.Error(err))
+				continue
+			}
+			cleared++
+		}
+	}
+
+	logger.Info("Overloaded accounts cleared", zap.Int("count", cleared))
+
+	return cleared, nil
+}
+
+// AccountStatusResult 批量更新账户状态时单个账户的结果
+type AccountStatusResult struct {
+	ID             string `json:"id"`
+	PreviousStatus string `json:"previousStatus,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Updated        bool   `json:"updated"` // 已写入（dryRun 时始终为 false）
+	Error          string `json:"error,omitempty"`
+}
+
+// BatchUpdateAccountStatus 批量更新账户状态，ids 为空时更新该类型的全部账户
+// dryRun 时只报告将要变更的账户而不写入；单个账户失败时记录在结果中并继续
+func (c *Client) BatchUpdateAccountStatus(ctx context.Context, accountType AccountType, ids []string, status string, dryRun bool) ([]AccountStatusResult, error) {
+	var accounts []map[string]interface{}
+	results := make([]AccountStatusResult, 0, len(ids))
+
+	if len(ids) == 0 {
+		all, err := c.GetAllAccounts(ctx, accountType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s accounts: %w", accountType, err)
+		}
+		accounts = all
+	} else {
+		for _, id := range ids {
+			account, err := c.GetAccount(ctx, accountType, id)
+			if err != nil {
+				results = append(results, AccountStatusResult{ID: id, Error: err.Error()})
+				continue
+			}
+			if account == nil {
+				results = append(results, AccountStatusResult{ID: id, Error: ErrAccountNotFound.Error()})
+				continue
+			}
+			account["id"] = id
+			accounts = append(accounts, account)
+		}
+	}
+
+	updated := 0
+	for _, account := range accounts {
+		accountID, _ := account["id"].(string)
+		previous, _ := account["status"].(string)
+		result := AccountStatusResult{ID: accountID, PreviousStatus: previous, Status: status}
+
+		if !dryRun {
+			if err := c.UpdateAccountStatus(ctx, accountType, accountID, status); err != nil {
+				logger.Warn("Failed to update account status",
+					zap.String("type", string(accountType)),
+					zap.String("id", accountID),
+					zap.Error(err))
+				result.Status = previous
+				result.Error = err.Error()
+			} else {
+				result.Updated = true
+				updated++
+			}
+		}
+		results = append(results, result)
 	}
 
-	data["isOverloaded"] = false
-	delete(data, "overloadedAt")
-	delete(data, "overloadedUntil")
-	data["updatedAt"] = time.Now().Format(time.RFC3339)
+	if !dryRun {
+		logger.Info("Account status batch updated",
+			zap.String("type", string(accountType)),
+			zap.String("status", status),
+			zap.Int("count", updated))
+	}
 
-	return c.SetAccount(ctx, accountType, accountID, data)
+	return results, nil
 }
 
 // GetActiveAccounts 获取所有活跃账户（指定类型）
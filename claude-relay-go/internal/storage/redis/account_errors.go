@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DefaultAccountErrorWindow 默认账户错误统计窗口
+const DefaultAccountErrorWindow = 10 * time.Minute
+
+// AccountErrorStats 账户错误统计
+type AccountErrorStats struct {
+	Recent int64 `json:"recent"` // 窗口内错误数（反映近期健康状况）
+	Total  int64 `json:"total"`  // 累计错误数（不随窗口衰减）
+}
+
+// GetAccountErrorWindow 获取账户错误统计窗口
+func GetAccountErrorWindow() time.Duration {
+	if config.Cfg != nil && config.Cfg.System.AccountErrorWindow > 0 {
+		return config.Cfg.System.AccountErrorWindow
+	}
+	return DefaultAccountErrorWindow
+}
+
+// GetAccountErrorThreshold 获取账户错误熔断阈值（0 表示不熔断）
+func GetAccountErrorThreshold() int64 {
+	if config.Cfg != nil && config.Cfg.System.AccountErrorThreshold > 0 {
+		return int64(config.Cfg.System.AccountErrorThreshold)
+	}
+	return 0
+}
+
+// accountErrorsKey 账户窗口内错误有序集合的 key
+func accountErrorsKey(accountType AccountType, accountID string) string {
+	return PrefixAccountErrors + string(accountType) + ":" + accountID
+}
+
+// accountErrorTotalField 累计错误数 Hash 字段
+func accountErrorTotalField(accountType AccountType, accountID string) string {
+	return string(accountType) + ":" + accountID
+}
+
+// RecordAccountError 记录一次账户错误，返回记录后的统计
+// eventID 相同的重放只计一次（窗口内去重）；eventID 为空时视为新事件
+func (c *Client) RecordAccountError(ctx context.Context, accountType AccountType, accountID, eventID string) (*AccountErrorStats, bool, error) {
+	return c.recordAccountErrorAt(ctx, accountType, accountID, eventID, time.Now())
+}
+
+// recordAccountErrorAt 记录指定时间的账户错误
+func (c *Client) recordAccountErrorAt(ctx context.Context, accountType AccountType, accountID, eventID string, now time.Time) (*AccountErrorStats, bool, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if eventID == "" {
+		eventID = uuid.New().String()
+	}
+
+	window := GetAccountErrorWindow()
+	key := accountErrorsKey(accountType, accountID)
+	windowStart := now.Add(-window).UnixMilli()
+
+	pipe := client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(windowStart, 10))
+	addCmd := pipe.ZAddNX(ctx, key, goredis.Z{Score: float64(now.UnixMilli()), Member: eventID})
+	recentCmd := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to record account error: %w", err)
+	}
+
+	stats := &AccountErrorStats{Recent: recentCmd.Val()}
+	field := accountErrorTotalField(accountType, accountID)
+
+	// 仅新事件计入累计错误数，重放直接返回当前统计
+	recorded := addCmd.Val() > 0
+	if recorded {
+		stats.Total, err = client.HIncrBy(ctx, KeyAccountErrorTotals, field, 1).Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to increment account error total: %w", err)
+		}
+	} else {
+		total, err := client.HGet(ctx, KeyAccountErrorTotals, field).Result()
+		if err != nil && err != goredis.Nil {
+			return nil, false, fmt.Errorf("failed to get account error total: %w", err)
+		}
+		stats.Total = parseInt64(total)
+	}
+
+	return stats, recorded, nil
+}
+
+// GetAccountErrorStats 获取账户错误统计（窗口内错误数与累计错误数）
+func (c *Client) GetAccountErrorStats(ctx context.Context, accountType AccountType, accountID string) (*AccountErrorStats, error) {
+	return c.getAccountErrorStatsAt(ctx, accountType, accountID, time.Now())
+}
+
+// getAccountErrorStatsAt 获取指定时间的账户错误统计
+func (c *Client) getAccountErrorStatsAt(ctx context.Context, accountType AccountType, accountID string, now time.Time) (*AccountErrorStats, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	windowStart := now.Add(-GetAccountErrorWindow()).UnixMilli()
+
+	pipe := client.Pipeline()
+	recentCmd := pipe.ZCount(ctx, accountErrorsKey(accountType, accountID), "("+strconv.FormatInt(windowStart, 10), "+inf")
+	totalCmd := pipe.HGet(ctx, KeyAccountErrorTotals, accountErrorTotalField(accountType, accountID))
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get account error stats: %w", err)
+	}
+
+	return &AccountErrorStats{
+		Recent: recentCmd.Val(),
+		Total:  parseInt64(totalCmd.Val()),
+	}, nil
+}
+
+// clearAccountErrorWindow 清空账户窗口内错误（累计错误数保留）
+func (c *Client) clearAccountErrorWindow(ctx context.Context, accountType AccountType, accountID string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+	return client.Del(ctx, accountErrorsKey(accountType, accountID)).Err()
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestAccountErrors_AgeOutOfWindow(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{AccountErrorWindow: 10 * time.Minute}}
+
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.recordAccountErrorAt(ctx, AccountTypeClaude, "acc-1", "", base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("recordAccountErrorAt() error = %v", err)
+		}
+	}
+
+	stats, err := c.getAccountErrorStatsAt(ctx, AccountTypeClaude, "acc-1", base.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("getAccountErrorStatsAt() error = %v", err)
+	}
+	if stats.Recent != 3 || stats.Total != 3 {
+		t.Fatalf("stats = %+v, want recent 3, total 3", stats)
+	}
+
+	// 前两次错误移出窗口后近期错误数下降，累计错误数不变
+	stats, err = c.getAccountErrorStatsAt(ctx, AccountTypeClaude, "acc-1", base.Add(11*time.Minute+30*time.Second))
+	if err != nil {
+		t.Fatalf("getAccountErrorStatsAt() error = %v", err)
+	}
+	if stats.Recent != 1 || stats.Total != 3 {
+		t.Errorf("stats = %+v, want recent 1, total 3", stats)
+	}
+
+	// 新错误写入时清理过期条目
+	stats, _, err = c.recordAccountErrorAt(ctx, AccountTypeClaude, "acc-1", "", base.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("recordAccountErrorAt() error = %v", err)
+	}
+	if stats.Recent != 1 || stats.Total != 4 {
+		t.Errorf("stats = %+v, want recent 1, total 4", stats)
+	}
+}
+
+func TestSetAccountErrorWithEvent_ReplaySafe(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.strings[PrefixClaudeAccount+"acc-1"] = `{"name":"acc","errorCount":7}`
+
+	for i := 0; i < 3; i++ {
+		if err := c.SetAccountErrorWithEvent(ctx, AccountTypeClaude, "acc-1", "upstream 529", "evt-1"); err != nil {
+			t.Fatalf("SetAccountErrorWithEvent() error = %v", err)
+		}
+	}
+
+	var account BaseAccount
+	if err := json.Unmarshal([]byte(hook.strings[PrefixClaudeAccount+"acc-1"]), &account); err != nil {
+		t.Fatalf("unmarshal account: %v", err)
+	}
+	if account.ErrorCount != 1 || account.ErrorTotal != 1 || account.LastError != "upstream 529" {
+		t.Errorf("account = errorCount %d, errorTotal %d, lastError %q; want 1, 1, upstream 529",
+			account.ErrorCount, account.ErrorTotal, account.LastError)
+	}
+
+	if err := c.ClearAccountError(ctx, AccountTypeClaude, "acc-1"); err != nil {
+		t.Fatalf("ClearAccountError() error = %v", err)
+	}
+	stats, err := c.GetAccountErrorStats(ctx, AccountTypeClaude, "acc-1")
+	if err != nil {
+		t.Fatalf("GetAccountErrorStats() error = %v", err)
+	}
+	if stats.Recent != 0 || stats.Total != 1 {
+		t.Errorf("after clear stats = %+v, want recent 0, total 1", stats)
+	}
+}
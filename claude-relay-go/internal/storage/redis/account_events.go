@@ -0,0 +1,31 @@
+package redis
+
+import "sync"
+
+// AccountChangeFunc 账户写入后的回调（在写入成功后同步调用，应尽快返回）
+type AccountChangeFunc func(accountType AccountType, accountID string)
+
+// accountListeners 账户变更监听器
+type accountListeners struct {
+	mu    sync.RWMutex
+	funcs []AccountChangeFunc
+}
+
+// OnAccountChanged 注册账户变更监听器（SetAccount、字段更新与删除后触发）
+// 用于让本实例缓存的账户派生状态（如代理客户端）在下次使用时重新加载
+func (c *Client) OnAccountChanged(fn AccountChangeFunc) {
+	c.accountListeners.mu.Lock()
+	defer c.accountListeners.mu.Unlock()
+	c.accountListeners.funcs = append(c.accountListeners.funcs, fn)
+}
+
+// notifyAccountChanged 通知账户变更
+func (c *Client) notifyAccountChanged(accountType AccountType, accountID string) {
+	c.accountListeners.mu.RLock()
+	funcs := c.accountListeners.funcs
+	c.accountListeners.mu.RUnlock()
+
+	for _, fn := range funcs {
+		fn(accountType, accountID)
+	}
+}
@@ -0,0 +1,393 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExportAccountsPage_IteratesAllAccountsOnce(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	const total = 25
+	for i := 0; i < total; i++ {
+		id := fmt.Sprintf("acct-%02d", i)
+		hook.strings[PrefixClaudeAccount+id] = fmt.Sprintf(`{"name":"account %d"}`, i)
+	}
+	// 其他类型的账户和无关键不应被导出
+	hook.strings[PrefixGeminiAccount+"gem-1"] = `{"name":"gemini"}`
+	hook.strings["unrelated:key"] = "x"
+
+	seen := make(map[string]int)
+	var cursor uint64
+	pages := 0
+	for {
+		page, err := c.ExportAccountsPage(ctx, AccountTypeClaude, cursor, 4)
+		if err != nil {
+			t.Fatalf("ExportAccountsPage() error = %v", err)
+		}
+		pages++
+		for _, account := range page.Accounts {
+			id, _ := account["id"].(string)
+			seen[id]++
+		}
+		if page.Done {
+			if page.NextCursor != 0 {
+				t.Errorf("done page should have cursor 0, got %d", page.NextCursor)
+			}
+			break
+		}
+		cursor = page.NextCursor
+		if pages > 100 {
+			t.Fatal("export did not terminate")
+		}
+	}
+
+	if pages < 2 {
+		t.Errorf("expected multiple pages, got %d", pages)
+	}
+	if len(seen) != total {
+		t.Fatalf("exported %d accounts, want %d", len(seen), total)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("account %s exported %d times", id, n)
+		}
+	}
+}
+
+func TestExportAccountsPage_Empty(t *testing.T) {
+	c := newConnectedClientForTest(t, newMemoryRedisHook())
+
+	page, err := c.ExportAccountsPage(context.Background(), AccountTypeClaude, 0, 0)
+	if err != nil {
+		t.Fatalf("ExportAccountsPage() error = %v", err)
+	}
+	if !page.Done || len(page.Accounts) != 0 {
+		t.Errorf("page = %+v, want empty done page", page)
+	}
+}
+
+func TestClearOverloadedAccounts_OnlyClearsOverloaded(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	healthy := `{"name":"healthy","isOverloaded":false,"updatedAt":"2024-01-01T00:00:00Z"}`
+	hook.strings[PrefixClaudeAccount+"ok-1"] = healthy
+	hook.strings[PrefixClaudeAccount+"busy-1"] = `{"name":"busy","isOverloaded":true,"overloadedUntil":"2999-01-01T00:00:00Z"}`
+	hook.strings[PrefixClaudeConsoleAccount+"busy-2"] = `{"name":"console","isOverloaded":true}`
+
+	cleared, err := c.ClearOverloadedAccounts(ctx, GetAccountTypesByCategory("claude")...)
+	if err != nil {
+		t.Fatalf("ClearOverloadedAccounts() error = %v", err)
+	}
+	if cleared != 2 {
+		t.Errorf("cleared = %d, want 2", cleared)
+	}
+
+	for _, key := range []string{PrefixClaudeAccount + "busy-1", PrefixClaudeConsoleAccount + "busy-2"} {
+		var account map[string]interface{}
+		if err := json.Unmarshal([]byte(hook.strings[key]), &account); err != nil {
+			t.Fatalf("invalid account JSON for %s: %v", key, err)
+		}
+		if account["isOverloaded"] != false || account["overloadedUntil"] != nil {
+			t.Errorf("%s still overloaded: %v", key, account)
+		}
+	}
+
+	if hook.strings[PrefixClaudeAccount+"ok-1"] != healthy {
+		t.Errorf("healthy account was rewritten: %s", hook.strings[PrefixClaudeAccount+"ok-1"])
+	}
+}
+
+func TestGetAccountDailyTokens(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+
+	hook.hashes["account_usage:daily:acct-1:2024-06-10"] = map[string]string{"allTokens": "1500"}
+
+	used, err := c.GetAccountDailyTokens(ctx, "acct-1", now)
+	if err != nil || used != 1500 {
+		t.Errorf("GetAccountDailyTokens() = %d, %v; want 1500", used, err)
+	}
+	// 次日的桶为空
+	used, err = c.GetAccountDailyTokens(ctx, "acct-1", now.Add(24*time.Hour))
+	if err != nil || used != 0 {
+		t.Errorf("next day GetAccountDailyTokens() = %d, %v; want 0", used, err)
+	}
+}
+
+func TestNextDailyReset(t *testing.T) {
+	// UTC+8 下 2024-06-10 12:00，下次重置为 2024-06-11 00:00（UTC 2024-06-10 16:00）
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+	want := time.Date(2024, 6, 10, 16, 0, 0, 0, time.UTC)
+	if got := NextDailyReset(now); !got.Equal(want) {
+		t.Errorf("NextDailyReset() = %v, want %v", got, want)
+	}
+}
+
+func TestGetActiveAccounts_OverloadExpiryRestoresAccount(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	hook.strings[PrefixClaudeAccount+"capped"] = `{"status":"active","isOverloaded":true,"overloadedUntil":"` + future + `"}`
+	hook.strings[PrefixClaudeAccount+"reset"] = `{"status":"active","isOverloaded":true,"overloadedUntil":"` + past + `"}`
+
+	accounts, err := c.GetActiveAccounts(context.Background(), AccountTypeClaude)
+	if err != nil {
+		t.Fatalf("GetActiveAccounts() error = %v", err)
+	}
+	if len(accounts) != 1 || accounts[0]["id"] != "reset" {
+		t.Errorf("active accounts = %v, want only the account whose overload expired", accounts)
+	}
+}
+
+// readAccountJSON 读取 hook 中存储的账户 JSON
+func readAccountJSON(t *testing.T, hook *memoryRedisHook, key string) map[string]interface{} {
+	t.Helper()
+	hook.mu.Lock()
+	raw := hook.strings[key]
+	hook.mu.Unlock()
+
+	var account map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &account); err != nil {
+		t.Fatalf("invalid account JSON for %s: %v", key, err)
+	}
+	return account
+}
+
+func TestUpdateAccountFields_RetriesOnConcurrentWrite(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	key := PrefixClaudeAccount + "acct-1"
+	hook.strings[key] = `{"id":"acct-1","name":"primary","status":"active"}`
+
+	// 在首次比较写入前插入另一个写入者的修改
+	injected := false
+	hook.beforeEval = func(h *memoryRedisHook) {
+		if injected {
+			return
+		}
+		injected = true
+		h.strings[key] = `{"id":"acct-1","name":"primary","status":"active","lastError":"boom"}`
+	}
+
+	if err := c.UpdateAccountStatus(context.Background(), AccountTypeClaude, "acct-1", "error"); err != nil {
+		t.Fatalf("UpdateAccountStatus() error = %v", err)
+	}
+
+	account := readAccountJSON(t, hook, key)
+	if account["status"] != "error" || account["lastError"] != "boom" || account["name"] != "primary" {
+		t.Errorf("account = %v, want status update merged with the concurrent lastError write", account)
+	}
+}
+
+func TestAccountUpdates_ConcurrentStatusAndErrorDoNotClobber(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	key := PrefixClaudeAccount + "acct-1"
+	hook.strings[key] = `{"id":"acct-1","name":"primary","status":"active"}`
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for _, update := range []func() error{
+		func() error { return c.UpdateAccountStatus(ctx, AccountTypeClaude, "acct-1", "rate_limited") },
+		func() error { return c.SetAccountError(ctx, AccountTypeClaude, "acct-1", "upstream 529") },
+		func() error { return c.SetAccountOverloaded(ctx, AccountTypeClaude, "acct-1", time.Minute) },
+	} {
+		wg.Add(1)
+		go func(update func() error) {
+			defer wg.Done()
+			errs <- update()
+		}(update)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent update error = %v", err)
+		}
+	}
+
+	account := readAccountJSON(t, hook, key)
+	if account["status"] != "rate_limited" {
+		t.Errorf("status = %v, want rate_limited", account["status"])
+	}
+	if account["lastError"] != "upstream 529" || account["errorCount"] != float64(1) {
+		t.Errorf("error fields = %v / %v, want upstream 529 / 1", account["lastError"], account["errorCount"])
+	}
+	if account["isOverloaded"] != true || account["name"] != "primary" {
+		t.Errorf("account = %v, want overload flag and untouched name", account)
+	}
+}
+
+func TestUpdateAccountFields_DeletesNilAndReportsMissing(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	key := PrefixClaudeAccount + "acct-1"
+	hook.strings[key] = `{"id":"acct-1","isOverloaded":true,"overloadedUntil":"2999-01-01T00:00:00Z"}`
+
+	if err := c.ClearAccountOverloaded(ctx, AccountTypeClaude, "acct-1"); err != nil {
+		t.Fatalf("ClearAccountOverloaded() error = %v", err)
+	}
+	account := readAccountJSON(t, hook, key)
+	if _, ok := account["overloadedUntil"]; ok || account["isOverloaded"] != false {
+		t.Errorf("account = %v, want overload cleared", account)
+	}
+
+	err := c.UpdateAccountFields(ctx, AccountTypeClaude, "missing", map[string]interface{}{"status": "active"})
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("missing account error = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func TestAccountWrites_NotifyAccountChanged(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	var changed []string
+	c.OnAccountChanged(func(accountType AccountType, accountID string) {
+		changed = append(changed, string(accountType)+":"+accountID)
+	})
+
+	if err := c.SetAccount(ctx, AccountTypeClaude, "acct-1", map[string]interface{}{"id": "acct-1", "status": "active"}); err != nil {
+		t.Fatalf("SetAccount() error = %v", err)
+	}
+	if err := c.UpdateAccountStatus(ctx, AccountTypeClaude, "acct-1", "error"); err != nil {
+		t.Fatalf("UpdateAccountStatus() error = %v", err)
+	}
+	if err := c.UpdateAccountStatus(ctx, AccountTypeClaude, "missing", "error"); err == nil {
+		t.Fatal("UpdateAccountStatus() on missing account should fail")
+	}
+
+	want := []string{"claude:acct-1", "claude:acct-1"}
+	if len(changed) != len(want) || changed[0] != want[0] || changed[1] != want[1] {
+		t.Errorf("notifications = %v, want %v", changed, want)
+	}
+}
+
+func seedStatusAccounts(hook *memoryRedisHook) {
+	hook.strings[PrefixClaudeAccount+"acc-1"] = `{"name":"one","status":"active"}`
+	hook.strings[PrefixClaudeAccount+"acc-2"] = `{"name":"two","status":"active"}`
+	hook.strings[PrefixClaudeAccount+"acc-3"] = `{"name":"three","status":"error"}`
+	hook.strings[PrefixGeminiAccount+"gem-1"] = `{"name":"gemini","status":"active"}`
+}
+
+func accountStatus(t *testing.T, hook *memoryRedisHook, key string) string {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(hook.strings[key]), &data); err != nil {
+		t.Fatalf("unmarshal %s: %v", key, err)
+	}
+	status, _ := data["status"].(string)
+	return status
+}
+
+func TestBatchUpdateAccountStatus_SpecificIDs(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	seedStatusAccounts(hook)
+
+	results, err := c.BatchUpdateAccountStatus(context.Background(), AccountTypeClaude, []string{"acc-1", "missing", "acc-3"}, "disabled", false)
+	if err != nil {
+		t.Fatalf("BatchUpdateAccountStatus() error = %v", err)
+	}
+
+	byID := make(map[string]AccountStatusResult)
+	for _, r := range results {
+		byID[r.ID] = r
+	}
+	if len(results) != 3 || !byID["acc-1"].Updated || byID["acc-1"].PreviousStatus != "active" || !byID["acc-3"].Updated {
+		t.Errorf("results = %+v, want acc-1 and acc-3 updated", results)
+	}
+	if byID["missing"].Updated || byID["missing"].Error == "" {
+		t.Errorf("missing result = %+v, want not-found error", byID["missing"])
+	}
+
+	for key, want := range map[string]string{
+		PrefixClaudeAccount + "acc-1": "disabled",
+		PrefixClaudeAccount + "acc-2": "active",
+		PrefixClaudeAccount + "acc-3": "disabled",
+	} {
+		if got := accountStatus(t, hook, key); got != want {
+			t.Errorf("%s status = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestBatchUpdateAccountStatus_AllOfTypeAndDryRun(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	seedStatusAccounts(hook)
+
+	// dryRun 只报告不写入
+	before := hook.strings[PrefixClaudeAccount+"acc-1"]
+	results, err := c.BatchUpdateAccountStatus(ctx, AccountTypeClaude, nil, "disabled", true)
+	if err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("dry run results = %+v, want all 3 claude accounts", results)
+	}
+	for _, r := range results {
+		if r.Updated || r.Status != "disabled" || r.PreviousStatus == "" {
+			t.Errorf("dry run result = %+v, want reported but not updated", r)
+		}
+	}
+	if hook.strings[PrefixClaudeAccount+"acc-1"] != before {
+		t.Error("dry run mutated account data")
+	}
+
+	if _, err := c.BatchUpdateAccountStatus(ctx, AccountTypeClaude, nil, "disabled", false); err != nil {
+		t.Fatalf("BatchUpdateAccountStatus() error = %v", err)
+	}
+	for _, id := range []string{"acc-1", "acc-2", "acc-3"} {
+		if got := accountStatus(t, hook, PrefixClaudeAccount+id); got != "disabled" {
+			t.Errorf("%s status = %q, want disabled", id, got)
+		}
+	}
+	if got := accountStatus(t, hook, PrefixGeminiAccount+"gem-1"); got != "active" {
+		t.Errorf("other account type status = %q, want untouched", got)
+	}
+}
+
+func TestUpdateAccountStatus_SetsReenabledAtOnlyOnTransitionToActive(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	key := PrefixClaudeAccount + "acct-1"
+	hook.strings[key] = `{"id":"acct-1","status":"active"}`
+
+	// active -> active 与其他状态写入都不记录
+	for _, status := range []string{"active", "error"} {
+		if err := c.UpdateAccountStatus(ctx, AccountTypeClaude, "acct-1", status); err != nil {
+			t.Fatalf("UpdateAccountStatus(%s) error = %v", status, err)
+		}
+		if account := readAccountJSON(t, hook, key); account["reenabledAt"] != nil {
+			t.Fatalf("after %s: reenabledAt = %v, want unset", status, account["reenabledAt"])
+		}
+	}
+
+	if err := c.UpdateAccountStatus(ctx, AccountTypeClaude, "acct-1", "active"); err != nil {
+		t.Fatalf("UpdateAccountStatus(active) error = %v", err)
+	}
+	account := readAccountJSON(t, hook, key)
+	if _, ok := account["reenabledAt"].(string); !ok || account["status"] != "active" {
+		t.Errorf("account = %v, want reenabledAt set on error -> active", account)
+	}
+}
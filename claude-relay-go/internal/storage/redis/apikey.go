@@ -3,11 +3,13 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/catstream/claude-relay-go/internal/config"
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -25,6 +27,15 @@ const (
 	APIKeyScanLimit = 20000
 )
 
+var (
+	// ErrAPIKeyNotFound API Key 不存在
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyNotDeleted API Key 未被软删除，无需恢复
+	ErrAPIKeyNotDeleted = errors.New("API key is not deleted")
+	// ErrAPIKeyHashConflict API Key 的哈希已映射到其他 Key
+	ErrAPIKeyHashConflict = errors.New("API key hash is mapped to another key")
+)
+
 // APIKey API Key 数据结构（与 Node.js 保持一致）
 type APIKey struct {
 	ID          string     `json:"id"`
@@ -44,12 +55,18 @@ type APIKey struct {
 	Permissions      []string `json:"permissions,omitempty"`      // 权限列表 (all, claude, gemini, openai)
 	AllowedClients   []string `json:"allowedClients,omitempty"`   // 允许的客户端
 	ModelBlacklist   []string `json:"modelBlacklist,omitempty"`   // 模型黑名单
+	DefaultModel     string   `json:"defaultModel,omitempty"`     // 请求未指定模型时使用的模型（MISSING_MODEL_POLICY=default）
 	ConcurrentLimit  int      `json:"concurrentLimit,omitempty"`  // 并发限制
 	RateLimitPerMin  int      `json:"rateLimitPerMin,omitempty"`  // 每分钟请求限制
 	RateLimitPerHour int      `json:"rateLimitPerHour,omitempty"` // 每小时请求限制
+	// 软限制预警阈值（占限制的百分比，0 表示使用全局配置）
+	LimitWarningPercent int `json:"limitWarningPercent,omitempty"`
+	// 关闭该 Key 的软限制预警（不受全局配置影响）
+	LimitWarningsDisabled bool `json:"limitWarningsDisabled,omitempty"`
 
-	// 并发排队配置
+	// 并发排队配置（模式见 ConcurrentRequestQueueMode* 常量；Enabled 为 Node 端字段，与 Mode=enabled 等价）
 	ConcurrentRequestQueueEnabled           bool    `json:"concurrentRequestQueueEnabled,omitempty"`
+	ConcurrentRequestQueueMode              string  `json:"concurrentRequestQueueMode,omitempty"`
 	ConcurrentRequestQueueMaxSize           int     `json:"concurrentRequestQueueMaxSize,omitempty"`
 	ConcurrentRequestQueueMaxSizeMultiplier float64 `json:"concurrentRequestQueueMaxSizeMultiplier,omitempty"`
 	ConcurrentRequestQueueTimeoutMs         int     `json:"concurrentRequestQueueTimeoutMs,omitempty"`
@@ -58,6 +75,23 @@ type APIKey struct {
 	DailyCostLimit      float64 `json:"dailyCostLimit,omitempty"`      // 每日成本限制（美元）
 	TotalCostLimit      float64 `json:"totalCostLimit,omitempty"`      // 总成本限制（美元）
 	WeeklyOpusCostLimit float64 `json:"weeklyOpusCostLimit,omitempty"` // Opus 周成本限制（美元）
+	// 成本预警阈值（占每日成本限制的百分比，如 50、80、100；每个阈值每天最多通知一次）
+	CostAlertThresholds []float64 `json:"costAlertThresholds,omitempty"`
+
+	// 成本归因：允许通过 X-CRS-Cost-Tag 请求头按标签统计成本
+	AllowCostTags bool `json:"allowCostTags,omitempty"`
+
+	// 测试/开发 Key（压测等流量），可在统计聚合中排除
+	IsTest bool `json:"isTest,omitempty"`
+
+	// 验证缓存时长覆盖（秒，0 表示使用全局 API_KEY_CACHE_TTL，负数表示不缓存）
+	CacheTTLSeconds int `json:"cacheTTLSeconds,omitempty"`
+
+	// 流式转发空闲超时覆盖（秒，0 表示使用全局 STREAM_IDLE_TIMEOUT，负数表示不限制）
+	StreamIdleTimeoutSeconds int `json:"streamIdleTimeoutSeconds,omitempty"`
+
+	// 功能开关（按 Key 灰度新的转发行为，未设置的开关视为关闭）
+	FeatureFlags map[string]bool `json:"featureFlags,omitempty"`
 
 	// 速率限制（窗口费用）
 	RateLimitWindow int     `json:"rateLimitWindow,omitempty"` // 速率限制窗口（分钟）
@@ -78,6 +112,29 @@ type APIKey struct {
 	// 用户管理
 	UserID string   `json:"userId,omitempty"` // 关联用户 ID
 	Tags   []string `json:"tags,omitempty"`   // 标签
+	// 显式不限制的限制字段（如 dailyCostLimit），这些字段为 0 时不继承用户级默认值
+	UnlimitedLimits []string `json:"unlimitedLimits,omitempty"`
+
+	// 专属账户绑定（与 Node 端字段一致，group: 前缀表示绑定账户分组）
+	ClaudeAccountID        string `json:"claudeAccountId,omitempty"`
+	ClaudeConsoleAccountID string `json:"claudeConsoleAccountId,omitempty"`
+	GeminiAccountID        string `json:"geminiAccountId,omitempty"`
+	OpenAIAccountID        string `json:"openaiAccountId,omitempty"`
+	AzureOpenAIAccountID   string `json:"azureOpenaiAccountId,omitempty"`
+	BedrockAccountID       string `json:"bedrockAccountId,omitempty"`
+	DroidAccountID         string `json:"droidAccountId,omitempty"`
+
+	// 调度
+	BlockedAccountIDs  []string `json:"blockedAccountIds,omitempty"`  // 禁止调度到的账户 ID
+	SchedulingPriority int      `json:"schedulingPriority,omitempty"` // 调度优先级（>0 时可使用预留的最优账户）
+	// 账户亲和：跨会话优先调度到上次选中的账户（账户不可用时回退到常规选择）
+	AccountAffinity bool `json:"accountAffinity,omitempty"`
+
+	// 转发前从请求中移除的请求头（如内部路由头）
+	StripRequestHeaders []string `json:"stripRequestHeaders,omitempty"`
+
+	// 调试
+	DebugCaptureCount int `json:"debugCaptureCount,omitempty"` // 剩余的请求/响应采样次数（>0 时开启采样）
 }
 
 // APIKeyPaginated 分页结果
@@ -96,6 +153,7 @@ type APIKeyStats struct {
 	ExpiredKeys   int `json:"expiredKeys"`
 	DeletedKeys   int `json:"deletedKeys"`
 	KeysWithUsers int `json:"keysWithUsers"`
+	TestKeys      int `json:"testKeys"`
 }
 
 // APIKeyQueryOptions 查询选项
@@ -109,6 +167,9 @@ type APIKeyQueryOptions struct {
 	Search         string   // 搜索关键词 (名称或 ID)
 	SortBy         string   // 排序字段 (createdAt, name, usedToday)
 	SortOrder      string   // 排序顺序 (asc, desc)
+	ExcludeTest    bool     // 排除测试/开发 Key
+	Permission     string   // 按权限过滤（权限包含该值或 all，未设置权限视为 all）
+	AllowedClient  string   // 按客户端限制过滤（仅匹配设置了客户端限制且允许该客户端的 Key）
 }
 
 // getHashedKeyValue 获取哈希键值（HashedKey 为主，APIKey 为兼容别名）
@@ -215,7 +276,7 @@ func (c *Client) GetAPIKeyByHash(ctx context.Context, hashedKey string) (*APIKey
 	return c.GetAPIKey(ctx, keyID)
 }
 
-// GetAllAPIKeys 获取所有 API Key
+// GetAllAPIKeys 获取所有 API Key（从只读副本读取，可能存在复制延迟）
 func (c *Client) GetAllAPIKeys(ctx context.Context, includeDeleted bool) ([]APIKey, error) {
 	// 先从哈希映射获取所有 ID
 	keyIDs, err := c.scanAPIKeyIDs(ctx)
@@ -229,7 +290,7 @@ func (c *Client) GetAllAPIKeys(ctx context.Context, includeDeleted bool) ([]APIK
 
 // scanAPIKeyIDs 获取所有 API Key ID
 func (c *Client) scanAPIKeyIDs(ctx context.Context) ([]string, error) {
-	client, err := c.GetClientSafe()
+	client, err := c.GetReadClientSafe()
 	if err != nil {
 		return nil, err
 	}
@@ -247,10 +308,10 @@ func (c *Client) scanAPIKeyIDs(ctx context.Context) ([]string, error) {
 	}
 
 	// 2. SCAN 新前缀
-	keys, err := c.ScanKeys(ctx, PrefixAPIKey+"*", APIKeyScanLimit)
+	keys, err := c.ScanReadKeys(ctx, PrefixAPIKey+"*", APIKeyScanLimit)
 	if err == nil {
 		for _, key := range keys {
-			if key == PrefixAPIKeyHashMap {
+			if isAPIKeyAuxiliaryKey(key) {
 				continue
 			}
 			keyID := strings.TrimPrefix(key, PrefixAPIKey)
@@ -261,7 +322,7 @@ func (c *Client) scanAPIKeyIDs(ctx context.Context) ([]string, error) {
 	}
 
 	// 3. SCAN 旧前缀（兼容）
-	legacyKeys, err := c.ScanKeys(ctx, PrefixAPIKeyLegacy+"*", APIKeyScanLimit)
+	legacyKeys, err := c.ScanReadKeys(ctx, PrefixAPIKeyLegacy+"*", APIKeyScanLimit)
 	if err == nil {
 		for _, key := range legacyKeys {
 			keyID := strings.TrimPrefix(key, PrefixAPIKeyLegacy)
@@ -286,7 +347,7 @@ func (c *Client) batchGetAPIKeys(ctx context.Context, keyIDs []string, includeDe
 		return []APIKey{}, nil
 	}
 
-	client, err := c.GetClientSafe()
+	client, err := c.GetReadClientSafe()
 	if err != nil {
 		return nil, err
 	}
@@ -350,6 +411,60 @@ func (c *Client) DeleteAPIKey(ctx context.Context, keyID string) error {
 	return c.SetAPIKey(ctx, key)
 }
 
+// RestoreAPIKey 恢复软删除的 API Key（与 Node restoreApiKey 一致：重新启用、清除删除标记与删除信息、记录 restoredAt，并重新写入哈希映射）
+func (c *Client) RestoreAPIKey(ctx context.Context, keyID string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key, err := c.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+	}
+	if !key.IsDeleted {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotDeleted, keyID)
+	}
+
+	// 删除期间哈希映射可能被清理或被其他 Key 占用，占用时拒绝恢复以免覆盖
+	hashKey := key.getHashedKeyValue()
+	if hashKey != "" {
+		owner, err := client.HGet(ctx, PrefixAPIKeyHashMap, hashKey).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to check hash map: %w", err)
+		}
+		if owner != "" && owner != keyID {
+			return fmt.Errorf("%w: %s", ErrAPIKeyHashConflict, owner)
+		}
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return err
+	}
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisKey,
+			"isDeleted", "false",
+			"isActive", "true",
+			"restoredAt", time.Now().Format(time.RFC3339))
+		pipe.HDel(ctx, redisKey, "deletedAt", "deletedBy", "deletedByType")
+		if hashKey != "" {
+			pipe.HSet(ctx, PrefixAPIKeyHashMap, hashKey, keyID)
+		}
+		pipe.Expire(ctx, redisKey, TTLAPIKey)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore API key: %w", err)
+	}
+
+	logger.Info("API Key restored", zap.String("id", keyID), zap.String("name", key.Name))
+	return nil
+}
+
 // HardDeleteAPIKey 硬删除 API Key
 func (c *Client) HardDeleteAPIKey(ctx context.Context, keyID string) error {
 	client, err := c.GetClientSafe()
@@ -377,31 +492,22 @@ func (c *Client) HardDeleteAPIKey(ctx context.Context, keyID string) error {
 	return nil
 }
 
-// UpdateAPIKeyFields 更新指定字段
+// UpdateAPIKeyFields 更新指定字段（字段名与取值校验失败时返回 *APIKeyFieldsError）
 func (c *Client) UpdateAPIKeyFields(ctx context.Context, keyID string, updates map[string]interface{}) error {
 	client, err := c.GetClientSafe()
 	if err != nil {
 		return err
 	}
 
-	redisKey := PrefixAPIKey + keyID
-
-	// 检查 Key 是否存在
-	exists, err := client.Exists(ctx, redisKey).Result()
+	stringUpdates, newHashValue, hashValueUpdated, err := normalizeAPIKeyFieldUpdates(updates)
 	if err != nil {
 		return err
 	}
-	if exists == 0 {
-		// 尝试旧前缀
-		legacyKey := PrefixAPIKeyLegacy + keyID
-		exists, _ = client.Exists(ctx, legacyKey).Result()
-		if exists == 0 {
-			return fmt.Errorf("API key not found: %s", keyID)
-		}
-		redisKey = legacyKey
-	}
 
-	stringUpdates, newHashValue, hashValueUpdated := normalizeAPIKeyFieldUpdates(updates)
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return err
+	}
 	var oldHashValue string
 	if hashValueUpdated {
 		oldHashValue, err = getHashedKeyValueFromRedis(ctx, client, redisKey)
@@ -474,19 +580,34 @@ func (c *Client) GetAPIKeysPaginated(ctx context.Context, opts APIKeyQueryOption
 	}, nil
 }
 
-// GetAPIKeyStats 获取 API Key 统计
-func (c *Client) GetAPIKeyStats(ctx context.Context) (*APIKeyStats, error) {
+// GetAPIKeyStats 获取 API Key 统计（excludeTest 为 true 时不计入测试 Key）
+func (c *Client) GetAPIKeyStats(ctx context.Context, excludeTest bool) (*APIKeyStats, error) {
 	allKeys, err := c.GetAllAPIKeys(ctx, true)
 	if err != nil {
 		return nil, err
 	}
 
-	stats := &APIKeyStats{
-		TotalKeys: len(allKeys),
-	}
+	return computeAPIKeyStats(allKeys, excludeTest, time.Now()), nil
+}
+
+// ExcludeTestKeysByDefault 统计聚合是否默认排除测试 Key
+func ExcludeTestKeysByDefault() bool {
+	return config.Cfg != nil && config.Cfg.System.ExcludeTestKeysFromStats
+}
+
+// computeAPIKeyStats 计算 API Key 统计
+func computeAPIKeyStats(keys []APIKey, excludeTest bool, now time.Time) *APIKeyStats {
+	stats := &APIKeyStats{}
+
+	for _, key := range keys {
+		if key.IsTest {
+			if excludeTest {
+				continue
+			}
+			stats.TestKeys++
+		}
+		stats.TotalKeys++
 
-	now := time.Now()
-	for _, key := range allKeys {
 		if key.IsDeleted {
 			stats.DeletedKeys++
 			continue
@@ -505,13 +626,18 @@ func (c *Client) GetAPIKeyStats(ctx context.Context) (*APIKeyStats, error) {
 		}
 	}
 
-	return stats, nil
+	return stats
 }
 
 // filterAPIKeys 过滤 API Keys
 func (c *Client) filterAPIKeys(keys []APIKey, opts APIKeyQueryOptions) []APIKey {
 	var filtered []APIKey
 	for _, key := range keys {
+		// 测试 Key 过滤
+		if opts.ExcludeTest && key.IsTest {
+			continue
+		}
+
 		// UserID 过滤
 		if opts.UserID != "" && key.UserID != opts.UserID {
 			continue
@@ -527,6 +653,16 @@ func (c *Client) filterAPIKeys(keys []APIKey, opts APIKeyQueryOptions) []APIKey
 			continue
 		}
 
+		// 权限过滤
+		if opts.Permission != "" && !keyHasPermission(key.Permissions, opts.Permission) {
+			continue
+		}
+
+		// 客户端限制过滤
+		if opts.AllowedClient != "" && !keyRestrictedToClient(key.AllowedClients, opts.AllowedClient) {
+			continue
+		}
+
 		// 搜索过滤（名称或ID）
 		if opts.Search != "" {
 			search := strings.ToLower(opts.Search)
@@ -541,6 +677,34 @@ func (c *Client) filterAPIKeys(keys []APIKey, opts APIKeyQueryOptions) []APIKey
 	return filtered
 }
 
+// keyHasPermission 权限列表是否包含指定权限（与请求鉴权一致：未设置权限或包含 all 时拥有全部权限）
+func keyHasPermission(permissions []string, permission string) bool {
+	if len(permissions) == 0 {
+		return true
+	}
+	for _, p := range permissions {
+		if strings.EqualFold(p, "all") || strings.EqualFold(p, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyRestrictedToClient 是否设置了客户端限制且允许指定客户端（支持 * / all 通配与前缀通配）
+func keyRestrictedToClient(allowedClients []string, client string) bool {
+	client = strings.ToLower(client)
+	for _, allowed := range allowedClients {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == "all" || allowed == client {
+			return true
+		}
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(client, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
 // sortAPIKeys 排序 API Keys
 func (c *Client) sortAPIKeys(keys []APIKey, sortBy, order string) {
 	if sortBy == "" {
@@ -601,6 +765,19 @@ func apiKeyToMap(key *APIKey) map[string]interface{} {
 	if key.UserID != "" {
 		m["userId"] = key.UserID
 	}
+	for field, accountID := range map[string]string{
+		"claudeAccountId":        key.ClaudeAccountID,
+		"claudeConsoleAccountId": key.ClaudeConsoleAccountID,
+		"geminiAccountId":        key.GeminiAccountID,
+		"openaiAccountId":        key.OpenAIAccountID,
+		"azureOpenaiAccountId":   key.AzureOpenAIAccountID,
+		"bedrockAccountId":       key.BedrockAccountID,
+		"droidAccountId":         key.DroidAccountID,
+	} {
+		if accountID != "" {
+			m[field] = accountID
+		}
+	}
 	if key.ConcurrentLimit > 0 {
 		m["concurrentLimit"] = fmt.Sprintf("%d", key.ConcurrentLimit)
 	}
@@ -610,6 +787,30 @@ func apiKeyToMap(key *APIKey) map[string]interface{} {
 	if key.RateLimitPerHour > 0 {
 		m["rateLimitPerHour"] = fmt.Sprintf("%d", key.RateLimitPerHour)
 	}
+	if key.LimitWarningPercent > 0 {
+		m["limitWarningPercent"] = fmt.Sprintf("%d", key.LimitWarningPercent)
+	}
+	if key.LimitWarningsDisabled {
+		m["limitWarningsDisabled"] = "true"
+	}
+	if key.SchedulingPriority > 0 {
+		m["schedulingPriority"] = fmt.Sprintf("%d", key.SchedulingPriority)
+	}
+	if key.AccountAffinity {
+		m["accountAffinity"] = "true"
+	}
+	if key.DebugCaptureCount > 0 {
+		m["debugCaptureCount"] = fmt.Sprintf("%d", key.DebugCaptureCount)
+	}
+	if key.DefaultModel != "" {
+		m["defaultModel"] = key.DefaultModel
+	}
+	if key.CacheTTLSeconds != 0 {
+		m["cacheTTLSeconds"] = fmt.Sprintf("%d", key.CacheTTLSeconds)
+	}
+	if key.StreamIdleTimeoutSeconds != 0 {
+		m["streamIdleTimeoutSeconds"] = fmt.Sprintf("%d", key.StreamIdleTimeoutSeconds)
+	}
 
 	// 成本限制
 	if key.DailyCostLimit > 0 {
@@ -675,11 +876,38 @@ func apiKeyToMap(key *APIKey) map[string]interface{} {
 		data, _ := json.Marshal(key.Tags)
 		m["tags"] = string(data)
 	}
+	if len(key.UnlimitedLimits) > 0 {
+		data, _ := json.Marshal(key.UnlimitedLimits)
+		m["unlimitedLimits"] = string(data)
+	}
+	if len(key.BlockedAccountIDs) > 0 {
+		data, _ := json.Marshal(key.BlockedAccountIDs)
+		m["blockedAccountIds"] = string(data)
+	}
+	if len(key.StripRequestHeaders) > 0 {
+		data, _ := json.Marshal(key.StripRequestHeaders)
+		m["stripRequestHeaders"] = string(data)
+	}
+	if len(key.CostAlertThresholds) > 0 {
+		data, _ := json.Marshal(key.CostAlertThresholds)
+		m["costAlertThresholds"] = string(data)
+	}
+	if len(key.FeatureFlags) > 0 {
+		data, _ := json.Marshal(key.FeatureFlags)
+		m["featureFlags"] = string(data)
+	}
 
-	// 并发排队配置
-	if key.ConcurrentRequestQueueEnabled {
-		m["concurrentRequestQueueEnabled"] = "true"
+	if key.AllowCostTags {
+		m["allowCostTags"] = "true"
 	}
+	if key.IsTest {
+		m["isTest"] = "true"
+	}
+
+	// 并发排队配置（两个字段始终写入，避免 Hash 中残留的旧值覆盖新模式）
+	queueMode := key.QueueMode()
+	m["concurrentRequestQueueMode"] = queueMode
+	m["concurrentRequestQueueEnabled"] = fmt.Sprintf("%t", queueMode == ConcurrentRequestQueueModeEnabled)
 	if key.ConcurrentRequestQueueMaxSize > 0 {
 		m["concurrentRequestQueueMaxSize"] = fmt.Sprintf("%d", key.ConcurrentRequestQueueMaxSize)
 	}
@@ -693,6 +921,54 @@ func apiKeyToMap(key *APIKey) map[string]interface{} {
 	return m
 }
 
+// 并发排队模式（三态）
+const (
+	// ConcurrentRequestQueueModeDefault 跟随全局并发软上限
+	ConcurrentRequestQueueModeDefault = ""
+	// ConcurrentRequestQueueModeEnabled 显式开启排队
+	ConcurrentRequestQueueModeEnabled = "enabled"
+	// ConcurrentRequestQueueModeDisabled 显式关闭排队（退出全局并发软上限，达到上限时直接拒绝）
+	ConcurrentRequestQueueModeDisabled = "disabled"
+)
+
+// QueueMode 生效的并发排队模式（显式模式优先，未设置时 ConcurrentRequestQueueEnabled 视为开启）
+func (k *APIKey) QueueMode() string {
+	if k.ConcurrentRequestQueueMode == ConcurrentRequestQueueModeDefault && k.ConcurrentRequestQueueEnabled {
+		return ConcurrentRequestQueueModeEnabled
+	}
+	return k.ConcurrentRequestQueueMode
+}
+
+// parseConcurrentRequestQueueMode 解析并发排队模式
+// 显式设置的 concurrentRequestQueueMode 优先；未设置时 Node 端的 concurrentRequestQueueEnabled=true 视为开启
+// （创建 Key 时默认写入的 false 不视为关闭）
+func parseConcurrentRequestQueueMode(data map[string]string) string {
+	switch mode := data["concurrentRequestQueueMode"]; mode {
+	case ConcurrentRequestQueueModeEnabled, ConcurrentRequestQueueModeDisabled:
+		return mode
+	}
+	if data["concurrentRequestQueueEnabled"] == "true" || data["concurrentRequestQueueEnabled"] == "1" {
+		return ConcurrentRequestQueueModeEnabled
+	}
+	return ConcurrentRequestQueueModeDefault
+}
+
+// syncConcurrentRequestQueueFields 部分更新时保持两个排队字段一致
+// 只传 concurrentRequestQueueEnabled（Node 端）时同步写入模式，只传模式时同步写入 Node 端字段
+func syncConcurrentRequestQueueFields(values map[string]interface{}) {
+	if mode, ok := values["concurrentRequestQueueMode"]; ok {
+		values["concurrentRequestQueueEnabled"] = fmt.Sprintf("%t", mode == ConcurrentRequestQueueModeEnabled)
+		return
+	}
+	if enabled, ok := values["concurrentRequestQueueEnabled"]; ok {
+		if enabled == "true" || enabled == "1" {
+			values["concurrentRequestQueueMode"] = ConcurrentRequestQueueModeEnabled
+		} else {
+			values["concurrentRequestQueueMode"] = ConcurrentRequestQueueModeDefault
+		}
+	}
+}
+
 // mapToAPIKey 将 map 转换为 APIKey
 func mapToAPIKey(data map[string]string) *APIKey {
 	key := &APIKey{
@@ -704,6 +980,14 @@ func mapToAPIKey(data map[string]string) *APIKey {
 		UserID:         data["userId"],
 		ExpirationMode: data["expirationMode"],
 		ActivationUnit: data["activationUnit"],
+
+		ClaudeAccountID:        data["claudeAccountId"],
+		ClaudeConsoleAccountID: data["claudeConsoleAccountId"],
+		GeminiAccountID:        data["geminiAccountId"],
+		OpenAIAccountID:        data["openaiAccountId"],
+		AzureOpenAIAccountID:   data["azureOpenaiAccountId"],
+		BedrockAccountID:       data["bedrockAccountId"],
+		DroidAccountID:         data["droidAccountId"],
 	}
 
 	// 数值字段
@@ -712,6 +996,13 @@ func mapToAPIKey(data map[string]string) *APIKey {
 	key.ConcurrentLimit = int(parseInt64(data["concurrentLimit"]))
 	key.RateLimitPerMin = int(parseInt64(data["rateLimitPerMin"]))
 	key.RateLimitPerHour = int(parseInt64(data["rateLimitPerHour"]))
+	key.LimitWarningPercent = int(parseInt64(data["limitWarningPercent"]))
+	key.LimitWarningsDisabled = data["limitWarningsDisabled"] == "true" || data["limitWarningsDisabled"] == "1"
+	key.SchedulingPriority = int(parseInt64(data["schedulingPriority"]))
+	key.DebugCaptureCount = int(parseInt64(data["debugCaptureCount"]))
+	key.CacheTTLSeconds = int(parseInt64(data["cacheTTLSeconds"]))
+	key.StreamIdleTimeoutSeconds = int(parseInt64(data["streamIdleTimeoutSeconds"]))
+	key.DefaultModel = data["defaultModel"]
 	key.ConcurrentRequestQueueMaxSize = int(parseInt64(data["concurrentRequestQueueMaxSize"]))
 	key.ConcurrentRequestQueueTimeoutMs = int(parseInt64(data["concurrentRequestQueueTimeoutMs"]))
 	key.ConcurrentRequestQueueMaxSizeMultiplier = parseFloat64(data["concurrentRequestQueueMaxSizeMultiplier"])
@@ -736,8 +1027,12 @@ func mapToAPIKey(data map[string]string) *APIKey {
 	// 布尔字段
 	key.IsActive = data["isActive"] == "true" || data["isActive"] == "1"
 	key.IsDeleted = data["isDeleted"] == "true" || data["isDeleted"] == "1"
-	key.ConcurrentRequestQueueEnabled = data["concurrentRequestQueueEnabled"] == "true" || data["concurrentRequestQueueEnabled"] == "1"
+	key.AllowCostTags = data["allowCostTags"] == "true" || data["allowCostTags"] == "1"
+	key.IsTest = data["isTest"] == "true" || data["isTest"] == "1"
+	key.ConcurrentRequestQueueMode = parseConcurrentRequestQueueMode(data)
+	key.ConcurrentRequestQueueEnabled = key.ConcurrentRequestQueueMode == ConcurrentRequestQueueModeEnabled
 	key.IsActivated = data["isActivated"] == "true" || data["isActivated"] == "1"
+	key.AccountAffinity = data["accountAffinity"] == "true" || data["accountAffinity"] == "1"
 
 	// 时间字段
 	if t, err := time.Parse(time.RFC3339, data["createdAt"]); err == nil {
@@ -780,6 +1075,31 @@ func mapToAPIKey(data map[string]string) *APIKey {
 			logger.Warn("Failed to parse tags JSON", zap.String("data", data["tags"]), zap.Error(err))
 		}
 	}
+	if data["unlimitedLimits"] != "" {
+		if err := json.Unmarshal([]byte(data["unlimitedLimits"]), &key.UnlimitedLimits); err != nil {
+			logger.Warn("Failed to parse unlimitedLimits JSON", zap.String("data", data["unlimitedLimits"]), zap.Error(err))
+		}
+	}
+	if data["blockedAccountIds"] != "" {
+		if err := json.Unmarshal([]byte(data["blockedAccountIds"]), &key.BlockedAccountIDs); err != nil {
+			logger.Warn("Failed to parse blockedAccountIds JSON", zap.String("data", data["blockedAccountIds"]), zap.Error(err))
+		}
+	}
+	if data["stripRequestHeaders"] != "" {
+		if err := json.Unmarshal([]byte(data["stripRequestHeaders"]), &key.StripRequestHeaders); err != nil {
+			logger.Warn("Failed to parse stripRequestHeaders JSON", zap.String("data", data["stripRequestHeaders"]), zap.Error(err))
+		}
+	}
+	if data["costAlertThresholds"] != "" {
+		if err := json.Unmarshal([]byte(data["costAlertThresholds"]), &key.CostAlertThresholds); err != nil {
+			logger.Warn("Failed to parse costAlertThresholds JSON", zap.String("data", data["costAlertThresholds"]), zap.Error(err))
+		}
+	}
+	if data["featureFlags"] != "" {
+		if err := json.Unmarshal([]byte(data["featureFlags"]), &key.FeatureFlags); err != nil {
+			logger.Warn("Failed to parse featureFlags JSON", zap.String("data", data["featureFlags"]), zap.Error(err))
+		}
+	}
 
 	return key
 }
@@ -798,6 +1118,28 @@ func hasAnyTag(keyTags, searchTags []string) bool {
 	return false
 }
 
+// resolveAPIKeyRedisKey 解析 API Key 实际存储的 Redis 键（兼容旧前缀）
+func resolveAPIKeyRedisKey(ctx context.Context, client *redis.Client, keyID string) (string, error) {
+	redisKey := PrefixAPIKey + keyID
+
+	// 检查 Key 是否存在
+	exists, err := client.Exists(ctx, redisKey).Result()
+	if err != nil {
+		return "", err
+	}
+	if exists == 0 {
+		// 尝试旧前缀
+		legacyKey := PrefixAPIKeyLegacy + keyID
+		exists, _ = client.Exists(ctx, legacyKey).Result()
+		if exists == 0 {
+			return "", fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+		}
+		redisKey = legacyKey
+	}
+
+	return redisKey, nil
+}
+
 // interfaceToString 将 interface{} 转换为字符串
 func interfaceToString(v interface{}) string {
 	switch val := v.(type) {
@@ -869,7 +1211,12 @@ func getHashedKeyValueFromRedis(ctx context.Context, client *redis.Client, redis
 	return redisValueToString(values[1]), nil
 }
 
-func normalizeAPIKeyFieldUpdates(updates map[string]interface{}) (map[string]interface{}, string, bool) {
+// normalizeAPIKeyFieldUpdates 校验字段更新并转换为 Hash 字段值（同步 hashedKey/apiKey）
+func normalizeAPIKeyFieldUpdates(updates map[string]interface{}) (map[string]interface{}, string, bool, error) {
+	if err := validateAPIKeyFieldUpdates(updates); err != nil {
+		return nil, "", false, err
+	}
+
 	newHashValue, hashValueUpdated := extractUpdatedHashedKeyValue(updates)
 
 	stringUpdates := make(map[string]interface{}, len(updates)+2)
@@ -883,6 +1230,7 @@ func normalizeAPIKeyFieldUpdates(updates map[string]interface{}) (map[string]int
 		stringUpdates["hashedKey"] = newHashValue
 		stringUpdates["apiKey"] = newHashValue
 	}
+	syncConcurrentRequestQueueFields(stringUpdates)
 
-	return stringUpdates, newHashValue, hashValueUpdated
+	return stringUpdates, newHashValue, hashValueUpdated, nil
 }
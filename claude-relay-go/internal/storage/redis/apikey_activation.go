@@ -0,0 +1,34 @@
+package redis
+
+import "time"
+
+// DefaultActivationDays 激活模式未设置有效期时的默认时长（按激活时间单位计）
+const DefaultActivationDays = 30
+
+// ActivationExpiry 根据激活时间、有效期与单位（days / hours，默认 days）计算激活后的过期时间
+func ActivationExpiry(activatedAt time.Time, activationDays int, unit string) time.Time {
+	if activationDays <= 0 {
+		activationDays = DefaultActivationDays
+	}
+	if unit == "hours" {
+		return activatedAt.Add(time.Duration(activationDays) * time.Hour)
+	}
+	return activatedAt.AddDate(0, 0, activationDays)
+}
+
+// ActivationExpiresAt 激活模式 Key 激活后的过期时间（非激活模式或尚未激活时返回 nil）
+// 激活时写入的 expiresAt 可能被管理员修改，存在时以其为准，缺失时才按激活时间推算
+func (k *APIKey) ActivationExpiresAt() *time.Time {
+	if k.ExpirationMode != "activation" || !k.IsActivated {
+		return nil
+	}
+	if k.ExpiresAt != nil {
+		expiresAt := *k.ExpiresAt
+		return &expiresAt
+	}
+	if k.ActivatedAt == nil {
+		return nil
+	}
+	expiresAt := ActivationExpiry(*k.ActivatedAt, k.ActivationDays, k.ActivationUnit)
+	return &expiresAt
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// isAPIKeyAuxiliaryKey 是否为 apikey: 前缀下的辅助数据（哈希映射），而非 API Key 本身
+func isAPIKeyAuxiliaryKey(key string) bool {
+	return key == PrefixAPIKeyHashMap
+}
+
+// GetAPIKeyAffinity 获取 API Key 亲和的账户 ID（未设置时返回空字符串）
+func (c *Client) GetAPIKeyAffinity(ctx context.Context, keyID string) (string, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return "", err
+	}
+
+	accountID, err := client.Get(ctx, PrefixAPIKeyAffinity+keyID).Result()
+	if err == goredis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get API key affinity: %w", err)
+	}
+	return accountID, nil
+}
+
+// SetAPIKeyAffinity 设置（或续期）API Key 亲和的账户
+func (c *Client) SetAPIKeyAffinity(ctx context.Context, keyID, accountID string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Set(ctx, PrefixAPIKeyAffinity+keyID, accountID, TTLAPIKeyAffinity).Err(); err != nil {
+		return fmt.Errorf("failed to set API key affinity: %w", err)
+	}
+	return nil
+}
+
+// ClearAPIKeyAffinity 清除 API Key 的账户亲和
+func (c *Client) ClearAPIKeyAffinity(ctx context.Context, keyID string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	return client.Del(ctx, PrefixAPIKeyAffinity+keyID).Err()
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAPIKeyAffinity_SetGetAndExcludedFromKeyScan(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "k", "accountAffinity": "true"}
+
+	if accountID, err := c.GetAPIKeyAffinity(ctx, "key-1"); err != nil || accountID != "" {
+		t.Fatalf("GetAPIKeyAffinity() before set = %q, %v; want empty", accountID, err)
+	}
+	if err := c.SetAPIKeyAffinity(ctx, "key-1", "acc-1"); err != nil {
+		t.Fatalf("SetAPIKeyAffinity() error = %v", err)
+	}
+	if accountID, err := c.GetAPIKeyAffinity(ctx, "key-1"); err != nil || accountID != "acc-1" {
+		t.Errorf("GetAPIKeyAffinity() = %q, %v; want acc-1", accountID, err)
+	}
+
+	// 亲和记录与 API Key 同前缀，不应被当作 API Key
+	keys, err := c.GetAllAPIKeys(ctx, false)
+	if err != nil {
+		t.Fatalf("GetAllAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "key-1" || !keys[0].AccountAffinity {
+		t.Errorf("GetAllAPIKeys() = %+v, want only key-1 with affinity enabled", keys)
+	}
+
+	if err := c.ClearAPIKeyAffinity(ctx, "key-1"); err != nil {
+		t.Fatalf("ClearAPIKeyAffinity() error = %v", err)
+	}
+	if accountID, _ := c.GetAPIKeyAffinity(ctx, "key-1"); accountID != "" {
+		t.Errorf("affinity after clear = %q, want empty", accountID)
+	}
+}
@@ -0,0 +1,381 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyBackupVersion 备份文档格式版本
+	APIKeyBackupVersion = 1
+	// apiKeyBackupScanCount 导出时每次 SCAN/HSCAN 的数量（按页读取，避免一次加载全部 Key）
+	apiKeyBackupScanCount = 200
+)
+
+// 备份文档记录类型（文档为逐行 JSON：header、若干 key / hashMap、end）
+const (
+	APIKeyBackupRecordHeader  = "header"
+	APIKeyBackupRecordKey     = "key"
+	APIKeyBackupRecordHashMap = "hashMap"
+	APIKeyBackupRecordEnd     = "end"
+)
+
+// 恢复模式
+const (
+	// APIKeyImportModeMerge 按字段合并到现有 Key，备份中没有的 Key 保留
+	APIKeyImportModeMerge = "merge"
+	// APIKeyImportModeReplace 整体覆盖 Key，并删除备份中没有的 Key
+	APIKeyImportModeReplace = "replace"
+)
+
+// apiKeySecretFields 备份中视为密钥的字段（Key 哈希值，未显式要求时不导出）
+var apiKeySecretFields = []string{"hashedKey", "apiKey"}
+
+// ErrInvalidAPIKeyBackup 备份文档格式错误或不完整
+var ErrInvalidAPIKeyBackup = errors.New("invalid API key backup")
+
+// APIKeyBackupRecord 备份文档中的一条记录
+type APIKeyBackupRecord struct {
+	Type string `json:"type"`
+
+	// header
+	Version        int    `json:"version,omitempty"`
+	ExportedAt     string `json:"exportedAt,omitempty"`
+	IncludeSecrets bool   `json:"includeSecrets,omitempty"`
+
+	// key：ID + 完整哈希字段；hashMap：Hash -> ID
+	ID     string            `json:"id,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Hash   string            `json:"hash,omitempty"`
+
+	// end：已导出的 Key 数（用于恢复时检测截断）
+	Keys int `json:"keys,omitempty"`
+}
+
+// APIKeyImportResult API Key 备份恢复结果
+type APIKeyImportResult struct {
+	Mode              string                `json:"mode"`
+	IncludeSecrets    bool                  `json:"includeSecrets"`
+	Imported          int                   `json:"imported"`
+	Removed           int                   `json:"removed"`           // replace 模式下删除的备份外 Key 数
+	HashMapEntries    int                   `json:"hashMapEntries"`    // 备份中的哈希映射条目数
+	HashMapMismatches []string              `json:"hashMapMismatches"` // 重建后与备份映射不一致的 Key ID
+	HashMap           *HashMapRebuildResult `json:"hashMap"`
+}
+
+// ExportAPIKeys 按 SCAN 分页导出全部 API Key（新旧前缀）与哈希映射，逐条交给 emit 写出
+// includeSecrets 为 false 时去除 hashedKey/apiKey 字段且不导出哈希映射
+func (c *Client) ExportAPIKeys(ctx context.Context, includeSecrets bool, emit func(*APIKeyBackupRecord) error) error {
+	return c.exportAPIKeysAt(ctx, includeSecrets, time.Now(), emit)
+}
+
+func (c *Client) exportAPIKeysAt(ctx context.Context, includeSecrets bool, now time.Time, emit func(*APIKeyBackupRecord) error) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	if err := emit(&APIKeyBackupRecord{
+		Type:           APIKeyBackupRecordHeader,
+		Version:        APIKeyBackupVersion,
+		ExportedAt:     now.UTC().Format(time.RFC3339),
+		IncludeSecrets: includeSecrets,
+	}); err != nil {
+		return err
+	}
+
+	exported := 0
+	emitKeys := func(ids []string, cmds []*goredis.MapStringStringCmd) error {
+		for i, id := range ids {
+			fields, err := cmds[i].Result()
+			if err != nil && err != goredis.Nil {
+				return fmt.Errorf("failed to read API key %s: %w", id, err)
+			}
+			if len(fields) == 0 {
+				// 键在 SCAN 与读取之间被删除
+				continue
+			}
+			if !includeSecrets {
+				for _, field := range apiKeySecretFields {
+					delete(fields, field)
+				}
+			}
+			if err := emit(&APIKeyBackupRecord{Type: APIKeyBackupRecordKey, ID: id, Fields: fields}); err != nil {
+				return err
+			}
+			exported++
+		}
+		return nil
+	}
+
+	// 新前缀
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, PrefixAPIKey+"*", apiKeyBackupScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan API keys: %w", err)
+		}
+		ids := make([]string, 0, len(keys))
+		cmds := make([]*goredis.MapStringStringCmd, 0, len(keys))
+		pipe := client.Pipeline()
+		for _, key := range keys {
+			if isAPIKeyAuxiliaryKey(key) {
+				continue
+			}
+			ids = append(ids, strings.TrimPrefix(key, PrefixAPIKey))
+			cmds = append(cmds, pipe.HGetAll(ctx, key))
+		}
+		if len(ids) > 0 {
+			_, _ = pipe.Exec(ctx)
+		}
+		if err := emitKeys(ids, cmds); err != nil {
+			return err
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	// 旧前缀（新前缀已存在的 Key 以新前缀为准）
+	for {
+		keys, next, err := client.Scan(ctx, cursor, PrefixAPIKeyLegacy+"*", apiKeyBackupScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan legacy API keys: %w", err)
+		}
+		if len(keys) > 0 {
+			existsCmds := make([]*goredis.IntCmd, len(keys))
+			pipe := client.Pipeline()
+			for i, key := range keys {
+				existsCmds[i] = pipe.Exists(ctx, PrefixAPIKey+strings.TrimPrefix(key, PrefixAPIKeyLegacy))
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("failed to check legacy API keys: %w", err)
+			}
+
+			ids := make([]string, 0, len(keys))
+			cmds := make([]*goredis.MapStringStringCmd, 0, len(keys))
+			pipe = client.Pipeline()
+			for i, key := range keys {
+				if existsCmds[i].Val() > 0 {
+					continue
+				}
+				ids = append(ids, strings.TrimPrefix(key, PrefixAPIKeyLegacy))
+				cmds = append(cmds, pipe.HGetAll(ctx, key))
+			}
+			if len(ids) > 0 {
+				_, _ = pipe.Exec(ctx)
+			}
+			if err := emitKeys(ids, cmds); err != nil {
+				return err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	// 哈希映射（条目本身即 Key 哈希值，仅在包含密钥时导出）
+	if includeSecrets {
+		for {
+			items, next, err := client.HScan(ctx, PrefixAPIKeyHashMap, cursor, "*", apiKeyBackupScanCount).Result()
+			if err != nil {
+				return fmt.Errorf("failed to scan API key hash map: %w", err)
+			}
+			for i := 0; i+1 < len(items); i += 2 {
+				if err := emit(&APIKeyBackupRecord{Type: APIKeyBackupRecordHashMap, Hash: items[i], ID: items[i+1]}); err != nil {
+					return err
+				}
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+
+	return emit(&APIKeyBackupRecord{Type: APIKeyBackupRecordEnd, Keys: exported})
+}
+
+// ImportAPIKeys 从备份文档恢复 API Key，next 逐条返回记录，读完返回 io.EOF
+// 文档必须以 header 开始、以 end 结束，读到 end 并校验 Key 数后才按批次写入（截断的文档不做任何修改）；
+// replace 模式要求备份包含密钥，否则覆盖后的 Key 将缺少 hashedKey 而无法认证
+// 恢复完成后按 Key 数据重建哈希映射，并与备份中的映射比对
+func (c *Client) ImportAPIKeys(ctx context.Context, mode string, next func() (*APIKeyBackupRecord, error)) (*APIKeyImportResult, error) {
+	if mode == "" {
+		mode = APIKeyImportModeMerge
+	}
+	if mode != APIKeyImportModeMerge && mode != APIKeyImportModeReplace {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidAPIKeyBackup, mode)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := next()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: empty document", ErrInvalidAPIKeyBackup)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyBackup, err)
+	}
+	if header.Type != APIKeyBackupRecordHeader {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidAPIKeyBackup)
+	}
+	if header.Version != APIKeyBackupVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidAPIKeyBackup, header.Version)
+	}
+	if mode == APIKeyImportModeReplace && !header.IncludeSecrets {
+		return nil, fmt.Errorf("%w: replace mode requires a backup exported with secrets", ErrInvalidAPIKeyBackup)
+	}
+
+	result := &APIKeyImportResult{
+		Mode:              mode,
+		IncludeSecrets:    header.IncludeSecrets,
+		HashMapMismatches: []string{},
+	}
+	imported := make(map[string]struct{})
+	backupHashMap := make(map[string]string)
+	var keyRecords []*APIKeyBackupRecord
+
+	ended := false
+	for !ended {
+		record, err := next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: truncated document (missing end record)", ErrInvalidAPIKeyBackup)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyBackup, err)
+		}
+
+		switch record.Type {
+		case APIKeyBackupRecordKey:
+			if record.ID == "" || len(record.Fields) == 0 {
+				return nil, fmt.Errorf("%w: key record without id or fields", ErrInvalidAPIKeyBackup)
+			}
+			imported[record.ID] = struct{}{}
+			keyRecords = append(keyRecords, record)
+		case APIKeyBackupRecordHashMap:
+			if record.Hash != "" && record.ID != "" {
+				backupHashMap[record.Hash] = record.ID
+			}
+		case APIKeyBackupRecordEnd:
+			if record.Keys != len(imported) {
+				return nil, fmt.Errorf("%w: end record expects %d keys, got %d", ErrInvalidAPIKeyBackup, record.Keys, len(imported))
+			}
+			ended = true
+		default:
+			return nil, fmt.Errorf("%w: unknown record type %q", ErrInvalidAPIKeyBackup, record.Type)
+		}
+	}
+
+	for start := 0; start < len(keyRecords); start += APIKeyBatchSize {
+		end := start + APIKeyBatchSize
+		if end > len(keyRecords) {
+			end = len(keyRecords)
+		}
+		if err := c.writeAPIKeyBackupBatch(ctx, client, mode, keyRecords[start:end]); err != nil {
+			return nil, err
+		}
+		result.Imported += end - start
+	}
+	result.HashMapEntries = len(backupHashMap)
+
+	if mode == APIKeyImportModeReplace {
+		existing, err := c.scanAPIKeyRedisKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var stale []string
+		for keyID := range existing {
+			if _, ok := imported[keyID]; !ok {
+				stale = append(stale, PrefixAPIKey+keyID, PrefixAPIKeyLegacy+keyID)
+				result.Removed++
+			}
+		}
+		if len(stale) > 0 {
+			if err := client.Del(ctx, stale...).Err(); err != nil {
+				return nil, fmt.Errorf("failed to remove API keys absent from backup: %w", err)
+			}
+		}
+	}
+
+	result.HashMap, err = c.RebuildAPIKeyHashMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(backupHashMap) > 0 {
+		rebuilt, err := client.HGetAll(ctx, PrefixAPIKeyHashMap).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify API key hash map: %w", err)
+		}
+		for hashValue, keyID := range backupHashMap {
+			if rebuilt[hashValue] != keyID {
+				result.HashMapMismatches = append(result.HashMapMismatches, keyID)
+			}
+		}
+		sort.Strings(result.HashMapMismatches)
+	}
+
+	logger.Info("API keys imported from backup",
+		zap.String("mode", mode),
+		zap.Int("imported", result.Imported),
+		zap.Int("removed", result.Removed),
+		zap.Int("hashMapMismatches", len(result.HashMapMismatches)))
+	return result, nil
+}
+
+// writeAPIKeyBackupBatch 写入一批 Key 记录
+// merge：按字段写入现有 Key（仅存在旧前缀时写入旧前缀，保留备份中没有的字段，如未导出的密钥）
+// replace：删除新旧前缀下的原数据后写入新前缀
+func (c *Client) writeAPIKeyBackupBatch(ctx context.Context, client *goredis.Client, mode string, records []*APIKeyBackupRecord) error {
+	targets := make([]string, len(records))
+	for i, record := range records {
+		targets[i] = PrefixAPIKey + record.ID
+	}
+
+	if mode == APIKeyImportModeMerge {
+		newExists := make([]*goredis.IntCmd, len(records))
+		legacyExists := make([]*goredis.IntCmd, len(records))
+		pipe := client.Pipeline()
+		for i, record := range records {
+			newExists[i] = pipe.Exists(ctx, PrefixAPIKey+record.ID)
+			legacyExists[i] = pipe.Exists(ctx, PrefixAPIKeyLegacy+record.ID)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to check existing API keys: %w", err)
+		}
+		for i, record := range records {
+			if newExists[i].Val() == 0 && legacyExists[i].Val() > 0 {
+				targets[i] = PrefixAPIKeyLegacy + record.ID
+			}
+		}
+	}
+
+	pipe := client.Pipeline()
+	for i, record := range records {
+		if mode == APIKeyImportModeReplace {
+			pipe.Del(ctx, PrefixAPIKey+record.ID, PrefixAPIKeyLegacy+record.ID)
+		}
+		fields := make(map[string]interface{}, len(record.Fields))
+		for field, value := range record.Fields {
+			fields[field] = value
+		}
+		pipe.HSet(ctx, targets[i], fields)
+		pipe.Expire(ctx, targets[i], TTLAPIKey)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to restore API keys: %w", err)
+	}
+	return nil
+}
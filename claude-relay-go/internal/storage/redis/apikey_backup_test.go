@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// exportBackupForTest 导出备份并返回逐条记录的迭代器
+func exportBackupForTest(t *testing.T, c *Client, includeSecrets bool) ([]*APIKeyBackupRecord, func() (*APIKeyBackupRecord, error)) {
+	t.Helper()
+	var records []*APIKeyBackupRecord
+	if err := c.ExportAPIKeys(context.Background(), includeSecrets, func(r *APIKeyBackupRecord) error {
+		records = append(records, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("ExportAPIKeys() error = %v", err)
+	}
+	i := 0
+	return records, func() (*APIKeyBackupRecord, error) {
+		if i >= len(records) {
+			return nil, io.EOF
+		}
+		i++
+		return records[i-1], nil
+	}
+}
+
+func TestAPIKeyBackup_RoundTripReplace(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a", "hashedKey": "hash-a", "apiKey": "hash-a", "isActive": "true", "tags": `["team"]`}
+	hook.hashes[PrefixAPIKeyLegacy+"key-legacy"] = map[string]string{"id": "key-legacy", "name": "legacy", "hashedKey": "hash-legacy", "isActive": "true"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-a": "key-a", "hash-legacy": "key-legacy"}
+	hook.strings[PrefixAPIKeyAffinity+"key-a"] = "acct-1" // 辅助键不属于备份
+
+	records, next := exportBackupForTest(t, c, true)
+	if first, last := records[0], records[len(records)-1]; first.Type != APIKeyBackupRecordHeader || last.Type != APIKeyBackupRecordEnd || last.Keys != 2 {
+		t.Fatalf("document = %+v ... %+v, want header ... end(keys=2)", first, last)
+	}
+	hashEntries := 0
+	for _, r := range records {
+		if r.Type == APIKeyBackupRecordHashMap {
+			hashEntries++
+		}
+	}
+	if hashEntries != 2 {
+		t.Fatalf("hash map records = %d, want 2", hashEntries)
+	}
+
+	// 灾难：Key 被篡改或删除、映射损坏，并新增了备份外的 Key
+	delete(hook.hashes, PrefixAPIKeyLegacy+"key-legacy")
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "tampered"}
+	hook.hashes[PrefixAPIKey+"key-extra"] = map[string]string{"id": "key-extra", "name": "extra", "hashedKey": "hash-extra"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-extra": "key-extra", "hash-a": "key-gone"}
+
+	result, err := c.ImportAPIKeys(ctx, APIKeyImportModeReplace, next)
+	if err != nil {
+		t.Fatalf("ImportAPIKeys() error = %v", err)
+	}
+	if result.Imported != 2 || result.Removed != 1 || result.HashMapEntries != 2 || len(result.HashMapMismatches) != 0 {
+		t.Errorf("result = %+v, want 2 imported, 1 removed, 2 hash entries, no mismatches", result)
+	}
+
+	if _, ok := hook.hashes[PrefixAPIKey+"key-extra"]; ok {
+		t.Error("key absent from backup should be removed in replace mode")
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["name"]; got != "a" {
+		t.Errorf("key-a name = %q, want restored a", got)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["tags"]; got != `["team"]` {
+		t.Errorf("key-a tags = %q, want restored", got)
+	}
+	want := map[string]string{"hash-a": "key-a", "hash-legacy": "key-legacy"}
+	if got := hook.hashes[PrefixAPIKeyHashMap]; len(got) != len(want) || got["hash-a"] != "key-a" || got["hash-legacy"] != "key-legacy" {
+		t.Errorf("hash map = %v, want %v", got, want)
+	}
+	for hashValue, keyID := range want {
+		key, err := c.GetAPIKeyByHash(ctx, hashValue)
+		if err != nil || key == nil || key.ID != keyID {
+			t.Errorf("GetAPIKeyByHash(%s) = %+v, %v; want %s", hashValue, key, err, keyID)
+		}
+	}
+	if got := hook.strings[PrefixAPIKeyAffinity+"key-a"]; got != "acct-1" {
+		t.Errorf("affinity = %q, auxiliary keys must be untouched", got)
+	}
+}
+
+func TestAPIKeyBackup_MergeWithoutSecretsKeepsHashes(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a", "hashedKey": "hash-a", "isActive": "true"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-a": "key-a"}
+
+	records, next := exportBackupForTest(t, c, false)
+	for _, r := range records {
+		if r.Type == APIKeyBackupRecordHashMap {
+			t.Fatal("hash map must not be exported without includeSecrets")
+		}
+		if _, ok := r.Fields["hashedKey"]; ok {
+			t.Fatal("hashedKey must not be exported without includeSecrets")
+		}
+	}
+
+	hook.hashes[PrefixAPIKey+"key-a"]["name"] = "renamed"
+	hook.hashes[PrefixAPIKey+"key-b"] = map[string]string{"id": "key-b", "name": "b", "hashedKey": "hash-b"}
+
+	result, err := c.ImportAPIKeys(ctx, "", next)
+	if err != nil {
+		t.Fatalf("ImportAPIKeys() error = %v", err)
+	}
+	if result.Mode != APIKeyImportModeMerge || result.Imported != 1 || result.Removed != 0 {
+		t.Errorf("result = %+v, want merge with 1 imported and nothing removed", result)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]; got["name"] != "a" || got["hashedKey"] != "hash-a" {
+		t.Errorf("key-a = %v, want restored name and preserved hash", got)
+	}
+	for hashValue, keyID := range map[string]string{"hash-a": "key-a", "hash-b": "key-b"} {
+		if key, err := c.GetAPIKeyByHash(ctx, hashValue); err != nil || key == nil || key.ID != keyID {
+			t.Errorf("GetAPIKeyByHash(%s) = %+v, %v; want %s", hashValue, key, err, keyID)
+		}
+	}
+}
+
+func TestImportAPIKeys_RejectsTruncatedBackup(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a", "hashedKey": "hash-a"}
+	records, _ := exportBackupForTest(t, c, true)
+	hook.hashes[PrefixAPIKey+"key-a"]["name"] = "renamed"
+	hook.hashes[PrefixAPIKey+"key-keep"] = map[string]string{"id": "key-keep", "name": "keep"}
+
+	truncated := records[:len(records)-1]
+	i := 0
+	_, err := c.ImportAPIKeys(context.Background(), APIKeyImportModeReplace, func() (*APIKeyBackupRecord, error) {
+		if i >= len(truncated) {
+			return nil, io.EOF
+		}
+		i++
+		return truncated[i-1], nil
+	})
+	if !errors.Is(err, ErrInvalidAPIKeyBackup) {
+		t.Fatalf("ImportAPIKeys() error = %v, want ErrInvalidAPIKeyBackup", err)
+	}
+	if _, ok := hook.hashes[PrefixAPIKey+"key-keep"]; !ok {
+		t.Error("replace mode must not remove keys when the backup is truncated")
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["name"]; got != "renamed" {
+		t.Errorf("key-a name = %q, truncated backup must not write any key", got)
+	}
+}
+
+func TestImportAPIKeys_ReplaceRejectsBackupWithoutSecrets(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a", "hashedKey": "hash-a"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-a": "key-a"}
+	_, next := exportBackupForTest(t, c, false)
+
+	_, err := c.ImportAPIKeys(context.Background(), APIKeyImportModeReplace, next)
+	if !errors.Is(err, ErrInvalidAPIKeyBackup) {
+		t.Fatalf("ImportAPIKeys() error = %v, want ErrInvalidAPIKeyBackup", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["hashedKey"]; got != "hash-a" {
+		t.Errorf("key-a hashedKey = %q, replace without secrets must not drop the hash", got)
+	}
+}
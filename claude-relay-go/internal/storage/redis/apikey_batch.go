@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// MaxAPIKeyBatchSize 单次批量创建的最大 Key 数
+const MaxAPIKeyBatchSize = 1000
+
+// APIKeyBatchResult 批量创建中单个 Key 的结果
+type APIKeyBatchResult struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SetAPIKeysBatch 批量创建或更新 API Key
+// 先校验整批（缺少 ID、批内 ID 或哈希重复、哈希已映射到其他 Key 的条目会被拒绝），
+// 再在一个事务中写入全部通过校验的 Key 与哈希映射：要么全部生效，要么全部失败
+func (c *Client) SetAPIKeysBatch(ctx context.Context, keys []APIKey) ([]APIKeyBatchResult, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]APIKeyBatchResult, len(keys))
+	idIndex := make(map[string]int, len(keys))
+	hashIndex := make(map[string][]int, len(keys))
+	for i := range keys {
+		key := &keys[i]
+		key.syncHashedKeyFields()
+		results[i] = APIKeyBatchResult{Index: i, ID: key.ID}
+
+		if key.ID == "" {
+			results[i].Error = "id is required"
+			continue
+		}
+		if first, ok := idIndex[key.ID]; ok {
+			results[i].Error = fmt.Sprintf("duplicate id in batch (index %d)", first)
+			continue
+		}
+		idIndex[key.ID] = i
+		if hashValue := key.getHashedKeyValue(); hashValue != "" {
+			hashIndex[hashValue] = append(hashIndex[hashValue], i)
+		}
+	}
+
+	// 批内哈希重复：无法判断哪个 Key 应占用映射，全部拒绝
+	for _, indexes := range hashIndex {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes {
+			if results[i].Error == "" {
+				results[i].Error = "duplicate hashedKey in batch"
+			}
+		}
+	}
+
+	// 读取哈希映射现状与被覆盖 Key 的旧哈希
+	var pending []int
+	for i := range keys {
+		if results[i].Error == "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	mappedCmds := make(map[int]*goredis.StringCmd)
+	oldHashCmds := make(map[int]*goredis.SliceCmd, len(pending))
+	pipe := client.Pipeline()
+	for _, i := range pending {
+		if hashValue := keys[i].getHashedKeyValue(); hashValue != "" {
+			mappedCmds[i] = pipe.HGet(ctx, PrefixAPIKeyHashMap, hashValue)
+		}
+		oldHashCmds[i] = pipe.HMGet(ctx, PrefixAPIKey+keys[i].ID, "hashedKey", "apiKey")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to check API key hash map: %w", err)
+	}
+
+	staleHashes := make(map[int]string)
+	writes := make([]int, 0, len(pending))
+	for _, i := range pending {
+		hashValue := keys[i].getHashedKeyValue()
+		if cmd, ok := mappedCmds[i]; ok {
+			if owner, err := cmd.Result(); err == nil && owner != keys[i].ID {
+				results[i].Error = fmt.Sprintf("hashedKey already mapped to key %s", owner)
+				continue
+			}
+		}
+		if values, err := oldHashCmds[i].Result(); err == nil {
+			oldHash := redisValueToString(values[0])
+			if oldHash == "" {
+				oldHash = redisValueToString(values[1])
+			}
+			// 旧哈希不再属于该 Key 时清理映射（被批内其他 Key 使用的除外）
+			if oldHash != "" && oldHash != hashValue && len(hashIndex[oldHash]) == 0 {
+				staleHashes[i] = oldHash
+			}
+		}
+		writes = append(writes, i)
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, i := range writes {
+			key := &keys[i]
+			redisKey := PrefixAPIKey + key.ID
+			pipe.HSet(ctx, redisKey, apiKeyToMap(key))
+			pipe.Expire(ctx, redisKey, TTLAPIKey)
+			if oldHash, ok := staleHashes[i]; ok {
+				pipe.HDel(ctx, PrefixAPIKeyHashMap, oldHash)
+			}
+			if hashValue := key.getHashedKeyValue(); hashValue != "" {
+				pipe.HSet(ctx, PrefixAPIKeyHashMap, hashValue, key.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to save API key batch", zap.Int("keys", len(writes)), zap.Error(err))
+		for _, i := range writes {
+			results[i].Error = err.Error()
+		}
+		return results, nil
+	}
+	for _, i := range writes {
+		results[i].Success = true
+	}
+
+	logger.Info("API key batch saved", zap.Int("saved", len(writes)), zap.Int("rejected", len(keys)-len(writes)))
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSetAPIKeysBatch_WritesKeysAndRejectsDuplicates(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixAPIKey+"key-owner"] = map[string]string{"id": "key-owner", "hashedKey": "hash-taken"}
+	hook.hashes[PrefixAPIKey+"key-rotate"] = map[string]string{"id": "key-rotate", "hashedKey": "hash-old"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-taken": "key-owner", "hash-old": "key-rotate"}
+
+	results, err := c.SetAPIKeysBatch(ctx, []APIKey{
+		{ID: "key-a", Name: "a", HashedKey: "hash-a", IsActive: true},
+		{ID: "key-b", Name: "b", APIKey: "hash-b"}, // 仅 Node.js 兼容字段
+		{ID: "key-dup-1", HashedKey: "hash-dup"},
+		{ID: "key-dup-2", APIKey: "hash-dup"},
+		{ID: "key-a", HashedKey: "hash-a2"},
+		{Name: "missing id", HashedKey: "hash-x"},
+		{ID: "key-steal", HashedKey: "hash-taken"},
+		{ID: "key-rotate", HashedKey: "hash-new"},
+	})
+	if err != nil {
+		t.Fatalf("SetAPIKeysBatch() error = %v", err)
+	}
+
+	wantErr := []string{"", "", "duplicate hashedKey", "duplicate hashedKey", "duplicate id", "id is required", "already mapped", ""}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("results[%d].Index = %d", i, r.Index)
+		}
+		if wantErr[i] == "" {
+			if !r.Success || r.Error != "" {
+				t.Errorf("results[%d] = %+v, want success", i, r)
+			}
+		} else if r.Success || !strings.Contains(r.Error, wantErr[i]) {
+			t.Errorf("results[%d] = %+v, want error containing %q", i, r, wantErr[i])
+		}
+	}
+
+	if got := hook.hashes[PrefixAPIKey+"key-b"]; got["hashedKey"] != "hash-b" || got["apiKey"] != "hash-b" {
+		t.Errorf("key-b = %v, want hashedKey and apiKey synced", got)
+	}
+	for _, id := range []string{"key-dup-1", "key-dup-2", "key-steal"} {
+		if _, ok := hook.hashes[PrefixAPIKey+id]; ok {
+			t.Errorf("rejected key %s should not be written", id)
+		}
+	}
+
+	hashMap := hook.hashes[PrefixAPIKeyHashMap]
+	want := map[string]string{"hash-a": "key-a", "hash-b": "key-b", "hash-taken": "key-owner", "hash-new": "key-rotate"}
+	if len(hashMap) != len(want) {
+		t.Errorf("hash map = %v, want %v", hashMap, want)
+	}
+	for hashValue, keyID := range want {
+		if hashMap[hashValue] != keyID {
+			t.Errorf("hash map[%s] = %q, want %q", hashValue, hashMap[hashValue], keyID)
+		}
+	}
+	if key, err := c.GetAPIKeyByHash(ctx, "hash-a"); err != nil || key == nil || key.Name != "a" {
+		t.Errorf("GetAPIKeyByHash(hash-a) = %+v, %v; want key-a", key, err)
+	}
+}
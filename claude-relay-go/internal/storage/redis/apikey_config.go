@@ -0,0 +1,445 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// API Key 配置替换相关错误
+var (
+	// ErrInvalidAPIKeyConfig 配置校验失败
+	ErrInvalidAPIKeyConfig = errors.New("invalid API key config")
+	// ErrAPIKeyConfigSnapshotNotFound 没有可回滚的快照
+	ErrAPIKeyConfigSnapshotNotFound = errors.New("API key config snapshot not found")
+	// ErrAPIKeyConfigConflict 替换期间 Key 被并发修改
+	ErrAPIKeyConfigConflict = errors.New("API key config modified concurrently")
+)
+
+// apiKeyConfigFieldKind 配置字段类型
+type apiKeyConfigFieldKind int
+
+const (
+	configFieldString apiKeyConfigFieldKind = iota
+	configFieldNumber
+	configFieldBool
+	configFieldStringArray
+	configFieldNumberArray
+	configFieldBoolMap
+	configFieldTime
+)
+
+// apiKeyConfigFields 可通过配置替换修改的字段（不包含 id、哈希、使用量等运行时字段）
+var apiKeyConfigFields = map[string]apiKeyConfigFieldKind{
+	"name":                          configFieldString,
+	"description":                   configFieldString,
+	"limit":                         configFieldNumber,
+	"isActive":                      configFieldBool,
+	"expiresAt":                     configFieldTime,
+	"permissions":                   configFieldStringArray,
+	"allowedClients":                configFieldStringArray,
+	"modelBlacklist":                configFieldStringArray,
+	"defaultModel":                  configFieldString,
+	"concurrentLimit":               configFieldNumber,
+	"rateLimitPerMin":               configFieldNumber,
+	"rateLimitPerHour":              configFieldNumber,
+	"limitWarningPercent":           configFieldNumber,
+	"limitWarningsDisabled":         configFieldBool,
+	"concurrentRequestQueueEnabled": configFieldBool,
+	"concurrentRequestQueueMode":    configFieldString,
+	"concurrentRequestQueueMaxSize": configFieldNumber,
+	"concurrentRequestQueueMaxSizeMultiplier": configFieldNumber,
+	"concurrentRequestQueueTimeoutMs":         configFieldNumber,
+	"dailyCostLimit":                          configFieldNumber,
+	"totalCostLimit":                          configFieldNumber,
+	"weeklyOpusCostLimit":                     configFieldNumber,
+	"costAlertThresholds":                     configFieldNumberArray,
+	"rateLimitWindow":                         configFieldNumber,
+	"rateLimitCost":                           configFieldNumber,
+	"expirationMode":                          configFieldString,
+	"activationDays":                          configFieldNumber,
+	"activationUnit":                          configFieldString,
+	"userId":                                  configFieldString,
+	"tags":                                    configFieldStringArray,
+	"unlimitedLimits":                         configFieldStringArray,
+	"blockedAccountIds":                       configFieldStringArray,
+	"stripRequestHeaders":                     configFieldStringArray,
+	"schedulingPriority":                      configFieldNumber,
+	"accountAffinity":                         configFieldBool,
+	"cacheTTLSeconds":                         configFieldNumber,
+	"streamIdleTimeoutSeconds":                configFieldNumber,
+	"allowCostTags":                           configFieldBool,
+	"isTest":                                  configFieldBool,
+	"featureFlags":                            configFieldBoolMap,
+}
+
+// APIKeyConfigSnapshot 配置快照（替换前的字段值）
+type APIKeyConfigSnapshot struct {
+	Fields    map[string]string `json:"fields"`    // 替换前存在的字段值
+	Missing   []string          `json:"missing"`   // 替换前不存在的字段（回滚时删除）
+	CreatedAt time.Time         `json:"createdAt"` // 快照时间
+}
+
+// ValidateAPIKeyConfig 校验配置对象（字段名、类型和取值范围）
+func ValidateAPIKeyConfig(cfg map[string]interface{}) error {
+	if len(cfg) == 0 {
+		return fmt.Errorf("%w: config is empty", ErrInvalidAPIKeyConfig)
+	}
+
+	for field, value := range cfg {
+		kind, ok := apiKeyConfigFields[field]
+		if !ok {
+			return fmt.Errorf("%w: unsupported field %q", ErrInvalidAPIKeyConfig, field)
+		}
+
+		switch kind {
+		case configFieldString:
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("%w: field %q must be a string", ErrInvalidAPIKeyConfig, field)
+			}
+		case configFieldNumber:
+			num, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("%w: field %q must be a number", ErrInvalidAPIKeyConfig, field)
+			}
+			if num < 0 {
+				return fmt.Errorf("%w: field %q must not be negative", ErrInvalidAPIKeyConfig, field)
+			}
+		case configFieldBool:
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("%w: field %q must be a boolean", ErrInvalidAPIKeyConfig, field)
+			}
+		case configFieldStringArray:
+			items, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("%w: field %q must be an array of strings", ErrInvalidAPIKeyConfig, field)
+			}
+			for _, item := range items {
+				if _, ok := item.(string); !ok {
+					return fmt.Errorf("%w: field %q must be an array of strings", ErrInvalidAPIKeyConfig, field)
+				}
+			}
+		case configFieldNumberArray:
+			items, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("%w: field %q must be an array of numbers", ErrInvalidAPIKeyConfig, field)
+			}
+			for _, item := range items {
+				if num, ok := item.(float64); !ok || num <= 0 {
+					return fmt.Errorf("%w: field %q must be an array of positive numbers", ErrInvalidAPIKeyConfig, field)
+				}
+			}
+		case configFieldBoolMap:
+			items, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%w: field %q must be an object of booleans", ErrInvalidAPIKeyConfig, field)
+			}
+			for name, item := range items {
+				if _, ok := item.(bool); !ok || name == "" {
+					return fmt.Errorf("%w: field %q must be an object of booleans", ErrInvalidAPIKeyConfig, field)
+				}
+			}
+		case configFieldTime:
+			str, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%w: field %q must be an RFC3339 string", ErrInvalidAPIKeyConfig, field)
+			}
+			if str != "" {
+				if _, err := time.Parse(time.RFC3339, str); err != nil {
+					return fmt.Errorf("%w: field %q must be an RFC3339 string", ErrInvalidAPIKeyConfig, field)
+				}
+			}
+		}
+	}
+
+	if mode, ok := cfg["expirationMode"].(string); ok && mode != "" && mode != "fixed" && mode != "activation" {
+		return fmt.Errorf("%w: expirationMode must be fixed or activation", ErrInvalidAPIKeyConfig)
+	}
+	if unit, ok := cfg["activationUnit"].(string); ok && unit != "" && unit != "days" && unit != "hours" {
+		return fmt.Errorf("%w: activationUnit must be days or hours", ErrInvalidAPIKeyConfig)
+	}
+	if mode, ok := cfg["concurrentRequestQueueMode"].(string); ok && mode != ConcurrentRequestQueueModeDefault &&
+		mode != ConcurrentRequestQueueModeEnabled && mode != ConcurrentRequestQueueModeDisabled {
+		return fmt.Errorf("%w: concurrentRequestQueueMode must be empty, enabled or disabled", ErrInvalidAPIKeyConfig)
+	}
+
+	return nil
+}
+
+// apiKeyConfigToHash 将配置对象转换为 Hash 字段值（与 apiKeyToMap 格式保持一致）
+func apiKeyConfigToHash(cfg map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(cfg))
+	for field, value := range cfg {
+		switch v := value.(type) {
+		case float64:
+			// 整数值按整数存储，避免 "10.000000" 这类格式
+			if v == float64(int64(v)) {
+				values[field] = fmt.Sprintf("%d", int64(v))
+			} else {
+				values[field] = fmt.Sprintf("%g", v)
+			}
+		default:
+			values[field] = interfaceToString(v)
+		}
+	}
+	syncConcurrentRequestQueueFields(values)
+	return values
+}
+
+// API Key 配置差异分类（排序时按此顺序，便于优先查看影响访问的字段）
+const (
+	APIKeyDiffPermissions = "permissions"
+	APIKeyDiffClients     = "clients"
+	APIKeyDiffModels      = "models"
+	APIKeyDiffLimits      = "limits"
+	APIKeyDiffOther       = "other"
+)
+
+var apiKeyDiffCategoryOrder = map[string]int{
+	APIKeyDiffPermissions: 0,
+	APIKeyDiffClients:     1,
+	APIKeyDiffModels:      2,
+	APIKeyDiffLimits:      3,
+	APIKeyDiffOther:       4,
+}
+
+// apiKeyDiffCategories 重点字段的分类，其余配置字段归入 other
+var apiKeyDiffCategories = map[string]string{
+	"permissions":                             APIKeyDiffPermissions,
+	"isActive":                                APIKeyDiffPermissions,
+	"expiresAt":                               APIKeyDiffPermissions,
+	"allowedClients":                          APIKeyDiffClients,
+	"modelBlacklist":                          APIKeyDiffModels,
+	"limit":                                   APIKeyDiffLimits,
+	"concurrentLimit":                         APIKeyDiffLimits,
+	"rateLimitPerMin":                         APIKeyDiffLimits,
+	"rateLimitPerHour":                        APIKeyDiffLimits,
+	"limitWarningPercent":                     APIKeyDiffLimits,
+	"limitWarningsDisabled":                   APIKeyDiffLimits,
+	"concurrentRequestQueueEnabled":           APIKeyDiffLimits,
+	"concurrentRequestQueueMode":              APIKeyDiffLimits,
+	"concurrentRequestQueueMaxSize":           APIKeyDiffLimits,
+	"concurrentRequestQueueMaxSizeMultiplier": APIKeyDiffLimits,
+	"concurrentRequestQueueTimeoutMs":         APIKeyDiffLimits,
+	"dailyCostLimit":                          APIKeyDiffLimits,
+	"totalCostLimit":                          APIKeyDiffLimits,
+	"weeklyOpusCostLimit":                     APIKeyDiffLimits,
+	"costAlertThresholds":                     APIKeyDiffLimits,
+	"rateLimitWindow":                         APIKeyDiffLimits,
+	"rateLimitCost":                           APIKeyDiffLimits,
+}
+
+// apiKeyDiffIgnoredFields 不参与对比的标识类字段
+var apiKeyDiffIgnoredFields = map[string]bool{
+	"name":        true,
+	"description": true,
+}
+
+// APIKeyFieldDiff 单个配置字段的差异
+type APIKeyFieldDiff struct {
+	Field    string      `json:"field"`
+	Category string      `json:"category"`
+	A        interface{} `json:"a"`
+	B        interface{} `json:"b"`
+}
+
+// DiffAPIKeyConfigs 对比两个 API Key 的配置字段（不含哈希等敏感字段和使用量等运行时字段）
+func DiffAPIKeyConfigs(a, b *APIKey) ([]APIKeyFieldDiff, error) {
+	valuesA, err := apiKeyConfigValues(a)
+	if err != nil {
+		return nil, err
+	}
+	valuesB, err := apiKeyConfigValues(b)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []APIKeyFieldDiff{}
+	for field := range apiKeyConfigFields {
+		if apiKeyDiffIgnoredFields[field] || reflect.DeepEqual(valuesA[field], valuesB[field]) {
+			continue
+		}
+		category, ok := apiKeyDiffCategories[field]
+		if !ok {
+			category = APIKeyDiffOther
+		}
+		diffs = append(diffs, APIKeyFieldDiff{
+			Field:    field,
+			Category: category,
+			A:        valuesA[field],
+			B:        valuesB[field],
+		})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		ci, cj := apiKeyDiffCategoryOrder[diffs[i].Category], apiKeyDiffCategoryOrder[diffs[j].Category]
+		if ci != cj {
+			return ci < cj
+		}
+		return diffs[i].Field < diffs[j].Field
+	})
+	return diffs, nil
+}
+
+// apiKeyConfigValues 按 JSON 字段名获取 API Key 的配置字段值（零值字段不存在）
+func apiKeyConfigValues(key *APIKey) (map[string]interface{}, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal API key: %w", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal API key: %w", err)
+	}
+	return values, nil
+}
+
+// buildAPIKeyConfigSnapshot 根据当前 Hash 数据生成快照
+func buildAPIKeyConfigSnapshot(current map[string]string, cfg map[string]interface{}) *APIKeyConfigSnapshot {
+	snapshot := &APIKeyConfigSnapshot{
+		Fields:    make(map[string]string, len(cfg)),
+		Missing:   []string{},
+		CreatedAt: time.Now(),
+	}
+	for field := range cfg {
+		if val, ok := current[field]; ok {
+			snapshot.Fields[field] = val
+		} else {
+			snapshot.Missing = append(snapshot.Missing, field)
+		}
+	}
+	sort.Strings(snapshot.Missing)
+	return snapshot
+}
+
+// SwapAPIKeyConfig 原子替换 API Key 配置
+// 合并语义：只写入 cfg 中的字段，未指定的字段保持原值（清空字段需显式传入空值）。
+// 先校验再写入；校验失败时不做任何修改。读取与写入通过 WATCH 保护，期间 Key 被并发修改时返回 ErrAPIKeyConfigConflict。
+// 替换前的字段值保存为快照，可通过 RollbackAPIKeyConfig 恢复
+func (c *Client) SwapAPIKeyConfig(ctx context.Context, keyID string, cfg map[string]interface{}) (*APIKey, error) {
+	if err := ValidateAPIKeyConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.HGetAll(ctx, redisKey).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read API key config: %w", err)
+		}
+		if len(current) == 0 {
+			return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+		}
+
+		snapshotData, err := json.Marshal(buildAPIKeyConfigSnapshot(current, cfg))
+		if err != nil {
+			return fmt.Errorf("failed to marshal config snapshot: %w", err)
+		}
+
+		// 快照与配置更新在同一事务中写入
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, PrefixAPIKeyConfigSnapshot+keyID, string(snapshotData), TTLAPIKeyConfigSnapshot)
+			pipe.HSet(ctx, redisKey, apiKeyConfigToHash(cfg))
+			pipe.Expire(ctx, redisKey, TTLAPIKey)
+			return nil
+		})
+		return err
+	}, redisKey)
+	if err != nil {
+		if errors.Is(err, redis.TxFailedErr) {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyConfigConflict, keyID)
+		}
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to swap API key config: %w", err)
+	}
+
+	logger.Info("API key config swapped",
+		zap.String("keyId", keyID),
+		zap.Int("fields", len(cfg)))
+
+	return c.GetAPIKey(ctx, keyID)
+}
+
+// GetAPIKeyConfigSnapshot 获取最近一次配置替换的快照
+func (c *Client) GetAPIKeyConfigSnapshot(ctx context.Context, keyID string) (*APIKeyConfigSnapshot, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.Get(ctx, PrefixAPIKeyConfigSnapshot+keyID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get config snapshot: %w", err)
+	}
+
+	var snapshot APIKeyConfigSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse config snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// RollbackAPIKeyConfig 恢复最近一次配置替换前的字段值
+func (c *Client) RollbackAPIKeyConfig(ctx context.Context, keyID string) (*APIKey, error) {
+	snapshot, err := c.GetAPIKeyConfigSnapshot(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, ErrAPIKeyConfigSnapshotNotFound
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(snapshot.Fields) > 0 {
+			pipe.HSet(ctx, redisKey, snapshot.Fields)
+		}
+		if len(snapshot.Missing) > 0 {
+			pipe.HDel(ctx, redisKey, snapshot.Missing...)
+		}
+		pipe.Expire(ctx, redisKey, TTLAPIKey)
+		pipe.Del(ctx, PrefixAPIKeyConfigSnapshot+keyID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rollback API key config: %w", err)
+	}
+
+	logger.Info("API key config rolled back",
+		zap.String("keyId", keyID),
+		zap.Time("snapshotAt", snapshot.CreatedAt))
+
+	return c.GetAPIKey(ctx, keyID)
+}
@@ -0,0 +1,181 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateAPIKeyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", map[string]interface{}{"name": "k", "concurrentLimit": float64(5), "tags": []interface{}{"a"}}, false},
+		{"empty", map[string]interface{}{}, true},
+		{"unknown field", map[string]interface{}{"usedToday": float64(1)}, true},
+		{"wrong type", map[string]interface{}{"isActive": "yes"}, true},
+		{"negative number", map[string]interface{}{"dailyCostLimit": float64(-1)}, true},
+		{"non-string array", map[string]interface{}{"permissions": []interface{}{1}}, true},
+		{"bad time", map[string]interface{}{"expiresAt": "tomorrow"}, true},
+		{"bad expiration mode", map[string]interface{}{"expirationMode": "forever"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAPIKeyConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAPIKeyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAPIKeyConfig) {
+				t.Errorf("expected ErrInvalidAPIKeyConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSwapAPIKeyConfig_AtomicSwapAndRollback(t *testing.T) {
+	hook := newMemoryRedisHook()
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{
+		"id":              "key-1",
+		"name":            "original",
+		"concurrentLimit": "2",
+		"isActive":        "true",
+	}
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	updated, err := c.SwapAPIKeyConfig(ctx, "key-1", map[string]interface{}{
+		"name":            "swapped",
+		"concurrentLimit": float64(10),
+		"tags":            []interface{}{"team-a"},
+	})
+	if err != nil {
+		t.Fatalf("SwapAPIKeyConfig() error = %v", err)
+	}
+	if updated.Name != "swapped" || updated.ConcurrentLimit != 10 || len(updated.Tags) != 1 {
+		t.Errorf("unexpected swapped key: %+v", updated)
+	}
+
+	restored, err := c.RollbackAPIKeyConfig(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("RollbackAPIKeyConfig() error = %v", err)
+	}
+	if restored.Name != "original" || restored.ConcurrentLimit != 2 {
+		t.Errorf("rollback did not restore prior values: %+v", restored)
+	}
+	if _, ok := hook.hashes[PrefixAPIKey+"key-1"]["tags"]; ok {
+		t.Error("rollback should remove fields that did not exist before the swap")
+	}
+
+	// 快照回滚后即删除
+	if _, err := c.RollbackAPIKeyConfig(ctx, "key-1"); !errors.Is(err, ErrAPIKeyConfigSnapshotNotFound) {
+		t.Errorf("expected ErrAPIKeyConfigSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestSwapAPIKeyConfig_KeepsUnspecifiedFields(t *testing.T) {
+	hook := newMemoryRedisHook()
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{
+		"id":             "key-1",
+		"name":           "original",
+		"dailyCostLimit": "5",
+		"permissions":    `["claude"]`,
+	}
+	c := newConnectedClientForTest(t, hook)
+
+	updated, err := c.SwapAPIKeyConfig(context.Background(), "key-1", map[string]interface{}{"name": "swapped"})
+	if err != nil {
+		t.Fatalf("SwapAPIKeyConfig() error = %v", err)
+	}
+	if updated.Name != "swapped" || updated.DailyCostLimit != 5 || len(updated.Permissions) != 1 {
+		t.Errorf("unspecified fields should keep their values: %+v", updated)
+	}
+
+	if _, err := c.SwapAPIKeyConfig(context.Background(), "missing", map[string]interface{}{"name": "x"}); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("missing key error = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestSwapAPIKeyConfig_ValidationFailureLeavesKeyUntouched(t *testing.T) {
+	hook := newMemoryRedisHook()
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"name": "original", "concurrentLimit": "2"}
+	c := newConnectedClientForTest(t, hook)
+
+	_, err := c.SwapAPIKeyConfig(context.Background(), "key-1", map[string]interface{}{
+		"name":            "swapped",
+		"concurrentLimit": "not-a-number",
+	})
+	if !errors.Is(err, ErrInvalidAPIKeyConfig) {
+		t.Fatalf("expected ErrInvalidAPIKeyConfig, got %v", err)
+	}
+	if hook.hashes[PrefixAPIKey+"key-1"]["name"] != "original" {
+		t.Error("key should be untouched after validation failure")
+	}
+	if _, ok := hook.strings[PrefixAPIKeyConfigSnapshot+"key-1"]; ok {
+		t.Error("no snapshot should be written after validation failure")
+	}
+}
+
+func TestDiffAPIKeyConfigs_DifferingFields(t *testing.T) {
+	a := &APIKey{
+		ID:              "key-a",
+		Name:            "works",
+		HashedKey:       "hash-a",
+		IsActive:        true,
+		Permissions:     []string{"all"},
+		AllowedClients:  []string{"claude_code"},
+		ConcurrentLimit: 5,
+		DailyCostLimit:  10,
+	}
+	b := &APIKey{
+		ID:              "key-b",
+		Name:            "broken",
+		HashedKey:       "hash-b",
+		IsActive:        true,
+		Permissions:     []string{"gemini"},
+		ModelBlacklist:  []string{"claude-opus-4"},
+		ConcurrentLimit: 5,
+		DailyCostLimit:  1,
+	}
+
+	diffs, err := DiffAPIKeyConfigs(a, b)
+	if err != nil {
+		t.Fatalf("DiffAPIKeyConfigs() error = %v", err)
+	}
+
+	want := []struct{ field, category string }{
+		{"permissions", APIKeyDiffPermissions},
+		{"allowedClients", APIKeyDiffClients},
+		{"modelBlacklist", APIKeyDiffModels},
+		{"dailyCostLimit", APIKeyDiffLimits},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("diffs = %+v, want %d entries", diffs, len(want))
+	}
+	for i, w := range want {
+		if diffs[i].Field != w.field || diffs[i].Category != w.category {
+			t.Errorf("diffs[%d] = %s/%s, want %s/%s", i, diffs[i].Field, diffs[i].Category, w.field, w.category)
+		}
+	}
+	if diffs[3].A != float64(10) || diffs[3].B != float64(1) {
+		t.Errorf("dailyCostLimit diff = %v -> %v, want 10 -> 1", diffs[3].A, diffs[3].B)
+	}
+	if diffs[2].A != nil {
+		t.Errorf("modelBlacklist A = %v, want nil", diffs[2].A)
+	}
+}
+
+func TestDiffAPIKeyConfigs_IdenticalConfigs(t *testing.T) {
+	a := &APIKey{ID: "key-a", Name: "first", HashedKey: "hash-a", IsActive: true, Permissions: []string{"all"}, RateLimitPerMin: 60}
+	b := &APIKey{ID: "key-b", Name: "second", HashedKey: "hash-b", IsActive: true, Permissions: []string{"all"}, RateLimitPerMin: 60, UsedToday: 42}
+
+	diffs, err := DiffAPIKeyConfigs(a, b)
+	if err != nil {
+		t.Fatalf("DiffAPIKeyConfigs() error = %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("diffs = %+v, want none (ids, names, hashes and usage are ignored)", diffs)
+	}
+}
@@ -0,0 +1,217 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// apiKeyCursorScanCount 游标分页每次 SCAN 的数量（单次请求内存占用上限约为该值）
+const apiKeyCursorScanCount = 200
+
+// ErrInvalidAPIKeyCursor 游标格式错误或与当前过滤条件不匹配
+var ErrInvalidAPIKeyCursor = errors.New("invalid API key cursor")
+
+// APIKeyCursorPage 游标分页结果
+type APIKeyCursorPage struct {
+	Keys       []APIKey `json:"keys"`
+	PageSize   int      `json:"pageSize"`
+	NextCursor string   `json:"nextCursor"` // 为空表示遍历结束
+	Done       bool     `json:"done"`
+}
+
+// apiKeyCursor 游标状态：先遍历新前缀再遍历旧前缀
+// Scan 为当前 SCAN 批次的起始游标，Skip 为该批次中已处理的键数
+type apiKeyCursor struct {
+	Legacy bool
+	Scan   uint64
+	Skip   int
+	Filter string
+}
+
+// encode 编码为不透明游标
+func (cur apiKeyCursor) encode() string {
+	phase := "0"
+	if cur.Legacy {
+		phase = "1"
+	}
+	raw := fmt.Sprintf("%s:%d:%d:%s", phase, cur.Scan, cur.Skip, cur.Filter)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeAPIKeyCursor 解析游标，并校验其过滤条件哈希与本次查询一致
+func decodeAPIKeyCursor(cursor, filter string) (apiKeyCursor, error) {
+	if cursor == "" {
+		return apiKeyCursor{Filter: filter}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return apiKeyCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidAPIKeyCursor)
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || (parts[0] != "0" && parts[0] != "1") {
+		return apiKeyCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidAPIKeyCursor)
+	}
+	scan, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return apiKeyCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidAPIKeyCursor)
+	}
+	skip, err := strconv.Atoi(parts[2])
+	if err != nil || skip < 0 {
+		return apiKeyCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidAPIKeyCursor)
+	}
+	if parts[3] != filter {
+		return apiKeyCursor{}, fmt.Errorf("%w: filters changed since the cursor was issued", ErrInvalidAPIKeyCursor)
+	}
+	return apiKeyCursor{Legacy: parts[0] == "1", Scan: scan, Skip: skip, Filter: filter}, nil
+}
+
+// apiKeyFilterHash 过滤条件的哈希（不含分页与排序字段）
+func apiKeyFilterHash(opts APIKeyQueryOptions) string {
+	tags := append([]string(nil), opts.Tags...)
+	sort.Strings(tags)
+	isActive := ""
+	if opts.IsActive != nil {
+		isActive = strconv.FormatBool(*opts.IsActive)
+	}
+	raw := strings.Join([]string{
+		strconv.FormatBool(opts.IncludeDeleted),
+		opts.UserID,
+		strings.Join(tags, ","),
+		isActive,
+		strings.ToLower(opts.Search),
+		strconv.FormatBool(opts.ExcludeTest),
+		opts.Permission,
+		opts.AllowedClient,
+	}, "\x00")
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetAPIKeysByCursor 基于 Redis SCAN 游标分页获取 API Key
+// 与 GetAPIKeysPaginated 不同，不加载全部 Key：每次请求只读取若干 SCAN 批次，结果按 SCAN 顺序返回（不支持排序）。
+// 游标包含 SCAN 位置与过滤条件哈希，过滤条件变化后旧游标会被拒绝；SCAN 语义下遍历期间变化的 Key 可能重复或遗漏
+func (c *Client) GetAPIKeysByCursor(ctx context.Context, opts APIKeyQueryOptions, cursor string) (*APIKeyCursorPage, error) {
+	if opts.PageSize < 1 {
+		opts.PageSize = APIKeyDefaultPageSize
+	}
+	if opts.PageSize > APIKeyMaxPageSize {
+		opts.PageSize = APIKeyMaxPageSize
+	}
+
+	state, err := decodeAPIKeyCursor(cursor, apiKeyFilterHash(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &APIKeyCursorPage{Keys: []APIKey{}, PageSize: opts.PageSize}
+	for {
+		prefix := PrefixAPIKey
+		if state.Legacy {
+			prefix = PrefixAPIKeyLegacy
+		}
+		keys, next, err := client.Scan(ctx, state.Scan, prefix+"*", apiKeyCursorScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API keys: %w", err)
+		}
+		if state.Skip > len(keys) {
+			state.Skip = len(keys)
+		}
+
+		matched, err := c.loadCursorBatch(ctx, client, keys[state.Skip:], state.Legacy, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i, key := range matched {
+			if key == nil {
+				continue
+			}
+			page.Keys = append(page.Keys, *key)
+			if len(page.Keys) == opts.PageSize {
+				// 本批次未处理完时从批次内的下一个键继续
+				if consumed := state.Skip + i + 1; consumed < len(keys) {
+					page.NextCursor = apiKeyCursor{Legacy: state.Legacy, Scan: state.Scan, Skip: consumed, Filter: state.Filter}.encode()
+					return page, nil
+				}
+				break
+			}
+		}
+
+		// 进入下一批次或下一阶段
+		state.Scan, state.Skip = next, 0
+		if next == 0 {
+			if state.Legacy {
+				page.Done = true
+				return page, nil
+			}
+			state.Legacy = true
+		}
+		if len(page.Keys) == opts.PageSize {
+			page.NextCursor = state.encode()
+			return page, nil
+		}
+	}
+}
+
+// loadCursorBatch 读取一批 Redis key 并按过滤条件筛选（与 keys 一一对应，不匹配的为 nil）
+// 旧前缀下的 Key 若新前缀已存在则跳过，避免重复
+func (c *Client) loadCursorBatch(ctx context.Context, client *goredis.Client, keys []string, legacy bool, opts APIKeyQueryOptions) ([]*APIKey, error) {
+	result := make([]*APIKey, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	prefix := PrefixAPIKey
+	if legacy {
+		prefix = PrefixAPIKeyLegacy
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(keys))
+	existsCmds := make([]*goredis.IntCmd, len(keys))
+	for i, key := range keys {
+		if !legacy && isAPIKeyAuxiliaryKey(key) {
+			continue
+		}
+		cmds[i] = pipe.HGetAll(ctx, key)
+		if legacy {
+			existsCmds[i] = pipe.Exists(ctx, PrefixAPIKey+strings.TrimPrefix(key, prefix))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil && !isRedisServerError(err) {
+		return nil, fmt.Errorf("failed to batch get API keys: %w", err)
+	}
+
+	for i, key := range keys {
+		if cmds[i] == nil || (existsCmds[i] != nil && existsCmds[i].Val() > 0) {
+			continue
+		}
+		data, err := cmds[i].Result()
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		apiKey := mapToAPIKey(data)
+		apiKey.ID = strings.TrimPrefix(key, prefix)
+		if !opts.IncludeDeleted && apiKey.IsDeleted {
+			continue
+		}
+		if len(c.filterAPIKeys([]APIKey{*apiKey}, opts)) == 0 {
+			continue
+		}
+		result[i] = apiKey
+	}
+	return result, nil
+}
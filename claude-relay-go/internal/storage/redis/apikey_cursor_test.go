@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetAPIKeysByCursor_PagesThroughFilteredKeys(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	want := make(map[string]bool)
+	for i := 0; i < 25; i++ {
+		id := fmt.Sprintf("key-%02d", i)
+		active := i%3 != 0
+		hook.hashes[PrefixAPIKey+id] = map[string]string{"id": id, "name": id, "isActive": fmt.Sprint(active)}
+		if active {
+			want[id] = true
+		}
+	}
+	// 旧前缀：仅存在于旧前缀的 Key 需返回，新前缀已存在的不重复返回
+	hook.hashes[PrefixAPIKeyLegacy+"legacy-only"] = map[string]string{"id": "legacy-only", "name": "legacy", "isActive": "true"}
+	hook.hashes[PrefixAPIKeyLegacy+"key-01"] = map[string]string{"id": "key-01", "name": "stale", "isActive": "true"}
+	want["legacy-only"] = true
+	hook.hashes[PrefixAPIKey+"deleted"] = map[string]string{"id": "deleted", "isActive": "true", "isDeleted": "true"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash": "key-01"}
+
+	active := true
+	opts := APIKeyQueryOptions{PageSize: 4, IsActive: &active}
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := c.GetAPIKeysByCursor(ctx, opts, cursor)
+		if err != nil {
+			t.Fatalf("GetAPIKeysByCursor() error = %v", err)
+		}
+		if len(page.Keys) > opts.PageSize {
+			t.Fatalf("page has %d keys, want at most %d", len(page.Keys), opts.PageSize)
+		}
+		for _, key := range page.Keys {
+			if seen[key.ID] {
+				t.Errorf("key %s returned twice", key.ID)
+			}
+			seen[key.ID] = true
+			if key.ID == "key-01" && key.Name != "key-01" {
+				t.Errorf("key-01 should come from the new prefix, got name %q", key.Name)
+			}
+		}
+		if page.Done != (page.NextCursor == "") {
+			t.Fatalf("page = %+v, Done must match an empty NextCursor", page)
+		}
+		if page.Done {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != len(want) {
+		t.Errorf("collected %d keys, want %d", len(seen), len(want))
+	}
+	for id := range want {
+		if !seen[id] {
+			t.Errorf("key %s missing from cursor pages", id)
+		}
+	}
+}
+
+func TestGetAPIKeysByCursor_RejectsChangedFilters(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("key-%d", i)
+		hook.hashes[PrefixAPIKey+id] = map[string]string{"id": id, "name": id, "isActive": "true"}
+	}
+
+	page, err := c.GetAPIKeysByCursor(ctx, APIKeyQueryOptions{PageSize: 2, Search: "key"}, "")
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("first page = %+v, %v; want a next cursor", page, err)
+	}
+
+	if _, err := c.GetAPIKeysByCursor(ctx, APIKeyQueryOptions{PageSize: 2, Search: "other"}, page.NextCursor); !errors.Is(err, ErrInvalidAPIKeyCursor) {
+		t.Errorf("changed filters error = %v, want ErrInvalidAPIKeyCursor", err)
+	}
+	if _, err := c.GetAPIKeysByCursor(ctx, APIKeyQueryOptions{PageSize: 2}, "not-a-cursor!"); !errors.Is(err, ErrInvalidAPIKeyCursor) {
+		t.Errorf("malformed cursor error = %v, want ErrInvalidAPIKeyCursor", err)
+	}
+	// 仅分页大小变化时游标仍可继续使用
+	if _, err := c.GetAPIKeysByCursor(ctx, APIKeyQueryOptions{PageSize: 3, Search: "key"}, page.NextCursor); err != nil {
+		t.Errorf("page size change error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidFeatureFlag 功能开关名称不合法
+var ErrInvalidFeatureFlag = errors.New("invalid feature flag")
+
+// featureFlagNamePattern 功能开关名称（字母、数字、下划线、点、冒号、连字符，最长 64 个字符）
+var featureFlagNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// HasFeature 检查 Key 是否开启了指定功能开关
+func (k *APIKey) HasFeature(name string) bool {
+	return k != nil && k.FeatureFlags[name]
+}
+
+// SetAPIKeyFeatureFlags 更新 API Key 的功能开关（与现有开关合并，返回更新后的全部开关）
+// 读取与写入通过 WATCH 保护，期间 Key 被并发修改时返回 ErrAPIKeyConfigConflict；Key 不存在时返回 ErrAPIKeyNotFound。
+func (c *Client) SetAPIKeyFeatureFlags(ctx context.Context, keyID string, flags map[string]bool) (map[string]bool, error) {
+	for name := range flags {
+		if !featureFlagNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFeatureFlag, name)
+		}
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var current map[string]bool
+	err = client.Watch(ctx, func(tx *goredis.Tx) error {
+		exists, err := tx.Exists(ctx, redisKey).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+		}
+
+		current = make(map[string]bool)
+		data, err := tx.HGet(ctx, redisKey, "featureFlags").Result()
+		if err != nil && err != goredis.Nil {
+			return fmt.Errorf("failed to get feature flags: %w", err)
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &current); err != nil {
+				return fmt.Errorf("failed to parse feature flags: %w", err)
+			}
+		}
+		for name, enabled := range flags {
+			current[name] = enabled
+		}
+
+		encoded, err := json.Marshal(current)
+		if err != nil {
+			return fmt.Errorf("failed to marshal feature flags: %w", err)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, redisKey, "featureFlags", string(encoded))
+			return nil
+		})
+		return err
+	}, redisKey)
+	if err != nil {
+		if errors.Is(err, goredis.TxFailedErr) {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyConfigConflict, keyID)
+		}
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to set feature flags: %w", err)
+	}
+	return current, nil
+}
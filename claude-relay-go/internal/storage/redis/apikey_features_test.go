@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAPIKeyFeatureFlags_RoundTrip(t *testing.T) {
+	original := &APIKey{
+		ID:           "key-1",
+		Name:         "flags",
+		FeatureFlags: map[string]bool{"streaming-v2": true, "legacy-retry": false},
+	}
+
+	stringMap := make(map[string]string)
+	for k, v := range apiKeyToMap(original) {
+		if s, ok := v.(string); ok {
+			stringMap[k] = s
+		}
+	}
+	result := mapToAPIKey(stringMap)
+
+	if len(result.FeatureFlags) != 2 || !result.FeatureFlags["streaming-v2"] || result.FeatureFlags["legacy-retry"] {
+		t.Errorf("FeatureFlags = %v, want %v", result.FeatureFlags, original.FeatureFlags)
+	}
+	if _, ok := apiKeyToMap(&APIKey{ID: "key-2"})["featureFlags"]; ok {
+		t.Error("empty FeatureFlags should not be written")
+	}
+}
+
+func TestAPIKey_HasFeature(t *testing.T) {
+	key := &APIKey{FeatureFlags: map[string]bool{"on": true, "off": false}}
+
+	tests := []struct {
+		key  *APIKey
+		name string
+		want bool
+	}{
+		{key, "on", true},
+		{key, "off", false},
+		{key, "unset", false},
+		{&APIKey{}, "on", false},
+		{nil, "on", false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.HasFeature(tt.name); got != tt.want {
+			t.Errorf("HasFeature(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetAPIKeyFeatureFlags_MergesWithExisting(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "k", "featureFlags": `{"a":true,"b":true}`}
+
+	flags, err := c.SetAPIKeyFeatureFlags(ctx, "key-1", map[string]bool{"b": false, "c": true})
+	if err != nil {
+		t.Fatalf("SetAPIKeyFeatureFlags() error = %v", err)
+	}
+	if len(flags) != 3 || !flags["a"] || flags["b"] || !flags["c"] {
+		t.Errorf("flags = %v, want a:true b:false c:true", flags)
+	}
+
+	key, err := c.GetAPIKey(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetAPIKey() error = %v", err)
+	}
+	if !key.HasFeature("a") || key.HasFeature("b") || !key.HasFeature("c") {
+		t.Errorf("stored flags = %v", key.FeatureFlags)
+	}
+
+	if _, err := c.SetAPIKeyFeatureFlags(ctx, "key-1", map[string]bool{"bad name": true}); !errors.Is(err, ErrInvalidFeatureFlag) {
+		t.Errorf("invalid name error = %v, want ErrInvalidFeatureFlag", err)
+	}
+}
+
+func TestSetAPIKeyFeatureFlags_MissingKey(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	if _, err := c.SetAPIKeyFeatureFlags(context.Background(), "missing", map[string]bool{"a": true}); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("error = %v, want ErrAPIKeyNotFound", err)
+	}
+	if _, ok := hook.hashes[PrefixAPIKey+"missing"]; ok {
+		t.Error("missing key should not be created")
+	}
+}
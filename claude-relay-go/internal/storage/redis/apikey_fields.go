@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAPIKeyFields 字段更新校验失败（字段名未知或取值类型不符）
+var ErrInvalidAPIKeyFields = errors.New("invalid API key fields")
+
+// apiKeyRuntimeFields 可通过字段更新修改、但不属于配置替换范围的字段
+// 包括运行时字段，以及 Node updateApiKey（allowedUpdates）、软删除与恢复写入的字段
+var apiKeyRuntimeFields = map[string]apiKeyConfigFieldKind{
+	"hashedKey":           configFieldString,
+	"apiKey":              configFieldString,
+	"usedToday":           configFieldNumber,
+	"createdAt":           configFieldTime,
+	"lastUsedAt":          configFieldTime,
+	"isDeleted":           configFieldBool,
+	"isActivated":         configFieldBool,
+	"activatedAt":         configFieldTime,
+	"fuelBalance":         configFieldNumber,
+	"fuelEntries":         configFieldNumber,
+	"fuelNextExpiresAtMs": configFieldNumber,
+	"debugCaptureCount":   configFieldNumber,
+
+	// Node allowedUpdates
+	"tokenLimit":              configFieldNumber,
+	"concurrencyLimit":        configFieldNumber,
+	"rateLimitRequests":       configFieldNumber,
+	"claudeAccountId":         configFieldString,
+	"claudeConsoleAccountId":  configFieldString,
+	"geminiAccountId":         configFieldString,
+	"openaiAccountId":         configFieldString,
+	"azureOpenaiAccountId":    configFieldString,
+	"bedrockAccountId":        configFieldString,
+	"droidAccountId":          configFieldString,
+	"enableModelRestriction":  configFieldBool,
+	"restrictedModels":        configFieldStringArray,
+	"enableClientRestriction": configFieldBool,
+	"enableModelPassthrough":  configFieldBool,
+	"userUsername":            configFieldString,
+	"createdBy":               configFieldString,
+
+	// 软删除与恢复
+	"deletedAt":      configFieldTime,
+	"deletedBy":      configFieldString,
+	"deletedByType":  configFieldString,
+	"restoredAt":     configFieldTime,
+	"restoredBy":     configFieldString,
+	"restoredByType": configFieldString,
+}
+
+// APIKeyFieldsError 字段更新校验错误，列出所有不合法的字段
+type APIKeyFieldsError struct {
+	Unknown []string          `json:"unknownFields,omitempty"` // 不在 APIKey 字段中的字段名
+	Invalid map[string]string `json:"invalidFields,omitempty"` // 字段名 -> 取值错误原因
+}
+
+func (e *APIKeyFieldsError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown fields: "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		fields := make([]string, 0, len(e.Invalid))
+		for field := range e.Invalid {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for i, field := range fields {
+			fields[i] = fmt.Sprintf("%s (%s)", field, e.Invalid[field])
+		}
+		parts = append(parts, "invalid fields: "+strings.Join(fields, ", "))
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidAPIKeyFields, strings.Join(parts, "; "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrInvalidAPIKeyFields)
+func (e *APIKeyFieldsError) Unwrap() error {
+	return ErrInvalidAPIKeyFields
+}
+
+// apiKeyUpdateFieldKind 字段更新允许的字段及其类型（配置字段 + 运行时字段）
+func apiKeyUpdateFieldKind(field string) (apiKeyConfigFieldKind, bool) {
+	if kind, ok := apiKeyConfigFields[field]; ok {
+		return kind, true
+	}
+	kind, ok := apiKeyRuntimeFields[field]
+	return kind, ok
+}
+
+// validateAPIKeyFieldUpdates 校验字段更新的字段名与取值（按写入 Hash 的字符串形式校验，空字符串表示清空）
+func validateAPIKeyFieldUpdates(updates map[string]interface{}) error {
+	fieldsErr := &APIKeyFieldsError{}
+	invalid := func(field, reason string) {
+		if fieldsErr.Invalid == nil {
+			fieldsErr.Invalid = make(map[string]string)
+		}
+		fieldsErr.Invalid[field] = reason
+	}
+
+	for field, value := range updates {
+		kind, ok := apiKeyUpdateFieldKind(field)
+		if !ok {
+			fieldsErr.Unknown = append(fieldsErr.Unknown, field)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		str := interfaceToString(value)
+		if str == "" {
+			continue
+		}
+		switch kind {
+		case configFieldNumber:
+			if _, err := strconv.ParseFloat(str, 64); err != nil {
+				invalid(field, "must be a number")
+			}
+		case configFieldBool:
+			if str != "true" && str != "false" && str != "1" && str != "0" {
+				invalid(field, "must be a boolean")
+			}
+		case configFieldTime:
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				invalid(field, "must be an RFC3339 time")
+			}
+		case configFieldStringArray:
+			var items []string
+			if err := json.Unmarshal([]byte(str), &items); err != nil {
+				invalid(field, "must be an array of strings")
+			}
+		case configFieldNumberArray:
+			var items []float64
+			if err := json.Unmarshal([]byte(str), &items); err != nil {
+				invalid(field, "must be an array of numbers")
+			}
+		case configFieldBoolMap:
+			var items map[string]bool
+			if err := json.Unmarshal([]byte(str), &items); err != nil {
+				invalid(field, "must be an object of booleans")
+			}
+		}
+	}
+
+	if mode, ok := updates["expirationMode"].(string); ok && mode != "" && mode != "fixed" && mode != "activation" {
+		invalid("expirationMode", "must be fixed or activation")
+	}
+	if unit, ok := updates["activationUnit"].(string); ok && unit != "" && unit != "days" && unit != "hours" {
+		invalid("activationUnit", "must be days or hours")
+	}
+
+	if len(fieldsErr.Unknown) == 0 && len(fieldsErr.Invalid) == 0 {
+		return nil
+	}
+	sort.Strings(fieldsErr.Unknown)
+	return fieldsErr
+}
@@ -0,0 +1,172 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// prefixAPIKeyHashMapRebuild 重建哈希映射时使用的临时 key（不在 apikey:* 扫描范围内）
+const prefixAPIKeyHashMapRebuild = "apikey_hash_map_rebuild:"
+
+// HashMapRebuildResult 哈希映射重建结果
+type HashMapRebuildResult struct {
+	Rebuilt     int      `json:"rebuilt"`     // 写入的映射条目数
+	ScannedKeys int      `json:"scannedKeys"` // 扫描到的 API Key 数
+	MissingHash []string `json:"missingHash"` // 没有 hashedKey/apiKey 字段的 Key ID
+	Conflicts   []string `json:"conflicts"`   // 与其他 Key 哈希重复的 Key ID（保留先扫描到的 Key）
+	Deleted     int      `json:"deleted"`     // 跳过的已软删除 Key 数（不写入映射）
+	Corrected   int      `json:"corrected"`   // 旧映射中缺失、多余或指向错误 Key 的条目数
+}
+
+// RebuildAPIKeyHashMap 扫描所有 API Key（新旧前缀）并重建哈希映射（跳过 isDeleted=true 的 Key）
+// 新映射先写入临时 key，再通过 RENAME 原子替换，重建过程中查找不会看到空映射
+// 重建期间新创建的 Key 可能未被扫描到，需在写入流量较低时执行
+func (c *Client) RebuildAPIKeyHashMap(ctx context.Context) (*HashMapRebuildResult, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	redisKeys, err := c.scanAPIKeyRedisKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取每个 Key 的哈希值
+	keyIDs := make([]string, 0, len(redisKeys))
+	for keyID := range redisKeys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	cmds := make([]*goredis.SliceCmd, len(keyIDs))
+	pipe := client.Pipeline()
+	for i, keyID := range keyIDs {
+		cmds[i] = pipe.HMGet(ctx, redisKeys[keyID], "hashedKey", "apiKey", "isDeleted")
+	}
+	if len(keyIDs) > 0 {
+		_, _ = pipe.Exec(ctx)
+	}
+
+	result := &HashMapRebuildResult{
+		ScannedKeys: len(keyIDs),
+		MissingHash: []string{},
+		Conflicts:   []string{},
+	}
+	entries := make(map[string]string, len(keyIDs))
+	for i, keyID := range keyIDs {
+		values, err := cmds[i].Result()
+		if err != nil {
+			logger.Warn("Failed to read API key hash during rebuild", zap.String("keyId", keyID), zap.Error(err))
+			result.MissingHash = append(result.MissingHash, keyID)
+			continue
+		}
+		if redisValueToString(values[2]) == "true" {
+			// 软删除的 Key 不应再能通过哈希查找到
+			result.Deleted++
+			continue
+		}
+		hashValue := redisValueToString(values[0])
+		if hashValue == "" {
+			hashValue = redisValueToString(values[1])
+		}
+		if hashValue == "" {
+			result.MissingHash = append(result.MissingHash, keyID)
+			continue
+		}
+		if _, exists := entries[hashValue]; exists {
+			result.Conflicts = append(result.Conflicts, keyID)
+			continue
+		}
+		entries[hashValue] = keyID
+	}
+	result.Rebuilt = len(entries)
+
+	// 统计旧映射中缺失、多余或指向错误 Key 的条目
+	oldEntries, _ := client.HGetAll(ctx, PrefixAPIKeyHashMap).Result()
+	for hashValue, keyID := range oldEntries {
+		if entries[hashValue] != keyID {
+			result.Corrected++
+		}
+	}
+	for hashValue := range entries {
+		if _, ok := oldEntries[hashValue]; !ok {
+			result.Corrected++
+		}
+	}
+
+	if len(entries) == 0 {
+		if err := client.Del(ctx, PrefixAPIKeyHashMap).Err(); err != nil {
+			return nil, fmt.Errorf("failed to clear hash map: %w", err)
+		}
+		return result, nil
+	}
+
+	// 写入临时映射后原子替换
+	tmpKey := prefixAPIKeyHashMapRebuild + uuid.New().String()
+	pipe = client.Pipeline()
+	batch := make(map[string]interface{}, APIKeyBatchSize)
+	for hashValue, keyID := range entries {
+		batch[hashValue] = keyID
+		if len(batch) == APIKeyBatchSize {
+			pipe.HSet(ctx, tmpKey, batch)
+			batch = make(map[string]interface{}, APIKeyBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		pipe.HSet(ctx, tmpKey, batch)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		client.Del(ctx, tmpKey)
+		return nil, fmt.Errorf("failed to write rebuilt hash map: %w", err)
+	}
+	if err := client.Rename(ctx, tmpKey, PrefixAPIKeyHashMap).Err(); err != nil {
+		client.Del(ctx, tmpKey)
+		return nil, fmt.Errorf("failed to swap rebuilt hash map: %w", err)
+	}
+
+	logger.Info("API key hash map rebuilt",
+		zap.Int("rebuilt", result.Rebuilt),
+		zap.Int("missingHash", len(result.MissingHash)),
+		zap.Int("conflicts", len(result.Conflicts)),
+		zap.Int("deleted", result.Deleted),
+		zap.Int("corrected", result.Corrected))
+	return result, nil
+}
+
+// scanAPIKeyRedisKeys 扫描所有 API Key 数据的 Redis key（Key ID -> Redis key，新前缀优先于旧前缀）
+// 与 scanAPIKeyIDs 不同，不读取哈希映射，避免损坏的映射影响扫描结果
+func (c *Client) scanAPIKeyRedisKeys(ctx context.Context) (map[string]string, error) {
+	redisKeys := make(map[string]string)
+
+	legacyKeys, err := c.ScanKeys(ctx, PrefixAPIKeyLegacy+"*", APIKeyScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan legacy API keys: %w", err)
+	}
+	for _, key := range legacyKeys {
+		if keyID := strings.TrimPrefix(key, PrefixAPIKeyLegacy); keyID != "" {
+			redisKeys[keyID] = key
+		}
+	}
+
+	keys, err := c.ScanKeys(ctx, PrefixAPIKey+"*", APIKeyScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan API keys: %w", err)
+	}
+	for _, key := range keys {
+		if isAPIKeyAuxiliaryKey(key) {
+			continue
+		}
+		if keyID := strings.TrimPrefix(key, PrefixAPIKey); keyID != "" {
+			redisKeys[keyID] = key
+		}
+	}
+	return redisKeys, nil
+}
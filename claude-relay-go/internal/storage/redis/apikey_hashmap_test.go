@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRebuildAPIKeyHashMap_RestoresCorruptedMap(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a", "hashedKey": "hash-a", "isActive": "true"}
+	hook.hashes[PrefixAPIKey+"key-b"] = map[string]string{"id": "key-b", "name": "b", "apiKey": "hash-b", "isActive": "true"} // 仅 Node.js 兼容字段
+	hook.hashes[PrefixAPIKeyLegacy+"key-legacy"] = map[string]string{"id": "key-legacy", "name": "legacy", "hashedKey": "hash-legacy", "isActive": "true"}
+	hook.hashes[PrefixAPIKey+"key-nohash"] = map[string]string{"id": "key-nohash", "name": "nohash"}
+	hook.hashes[PrefixAPIKey+"key-dup"] = map[string]string{"id": "key-dup", "name": "dup", "hashedKey": "hash-a"}
+	hook.hashes[PrefixAPIKey+"key-deleted"] = map[string]string{"id": "key-deleted", "name": "deleted", "hashedKey": "hash-deleted", "isDeleted": "true"}
+
+	// 损坏的映射：指向错误的 Key、已不存在的 Key，且缺少部分条目
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{
+		"hash-a":       "key-b",
+		"hash-stale":   "key-gone",
+		"hash-deleted": "key-deleted",
+	}
+
+	result, err := c.RebuildAPIKeyHashMap(ctx)
+	if err != nil {
+		t.Fatalf("RebuildAPIKeyHashMap() error = %v", err)
+	}
+
+	if result.ScannedKeys != 6 || result.Rebuilt != 3 || result.Deleted != 1 {
+		t.Errorf("result = %+v, want 6 scanned, 3 rebuilt and 1 deleted", result)
+	}
+	// hash-a 指向错误、hash-stale 与 hash-deleted 多余、hash-b 与 hash-legacy 缺失
+	if result.Corrected != 5 {
+		t.Errorf("Corrected = %d, want 5", result.Corrected)
+	}
+	if len(result.MissingHash) != 1 || result.MissingHash[0] != "key-nohash" {
+		t.Errorf("MissingHash = %v, want [key-nohash]", result.MissingHash)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "key-dup" {
+		t.Errorf("Conflicts = %v, want [key-dup]", result.Conflicts)
+	}
+
+	want := map[string]string{"hash-a": "key-a", "hash-b": "key-b", "hash-legacy": "key-legacy"}
+	got := hook.hashes[PrefixAPIKeyHashMap]
+	if len(got) != len(want) {
+		t.Fatalf("hash map = %v, want %v", got, want)
+	}
+	for hash, id := range want {
+		if got[hash] != id {
+			t.Errorf("hash map[%s] = %q, want %q", hash, got[hash], id)
+		}
+	}
+
+	// 临时映射已被替换，不残留
+	var leftovers []string
+	for key := range hook.hashes {
+		if strings.HasPrefix(key, prefixAPIKeyHashMapRebuild) {
+			leftovers = append(leftovers, key)
+		}
+	}
+	sort.Strings(leftovers)
+	if len(leftovers) > 0 {
+		t.Errorf("temporary maps left behind: %v", leftovers)
+	}
+
+	// 重建后通过哈希查找可以解析到正确的 Key
+	for hash, id := range want {
+		key, err := c.GetAPIKeyByHash(ctx, hash)
+		if err != nil || key == nil || key.ID != id {
+			t.Errorf("GetAPIKeyByHash(%s) = %+v, %v; want %s", hash, key, err, id)
+		}
+	}
+	if key, _ := c.GetAPIKeyByHash(ctx, "hash-stale"); key != nil {
+		t.Errorf("stale hash should no longer resolve, got %+v", key)
+	}
+	if _, ok := got["hash-deleted"]; ok {
+		t.Error("soft-deleted key should not be mapped")
+	}
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MaxTagUsageDays 标签用量汇总的最大天数（受每日统计保留时间限制）
+const MaxTagUsageDays = 31
+
+// TagUsageTotals 用量与成本合计
+type TagUsageTotals struct {
+	Requests          int64   `json:"requests"`
+	InputTokens       int64   `json:"inputTokens"`
+	OutputTokens      int64   `json:"outputTokens"`
+	CacheCreateTokens int64   `json:"cacheCreateTokens"`
+	CacheReadTokens   int64   `json:"cacheReadTokens"`
+	AllTokens         int64   `json:"allTokens"`
+	Cost              float64 `json:"cost"`
+
+	costMicros int64
+}
+
+// add 累加单日用量与成本
+func (t *TagUsageTotals) add(usage map[string]string, costMicros int64) {
+	t.Requests += parseInt64(usage["requests"])
+	t.InputTokens += parseInt64(usage["inputTokens"])
+	t.OutputTokens += parseInt64(usage["outputTokens"])
+	t.CacheCreateTokens += parseInt64(usage["cacheCreateTokens"])
+	t.CacheReadTokens += parseInt64(usage["cacheReadTokens"])
+	t.AllTokens += parseInt64(usage["allTokens"])
+	t.costMicros += costMicros
+	t.Cost = RoundCostForStorage(MicrosToCost(t.costMicros))
+}
+
+// TagKeyUsage 标签下单个 Key 的用量
+type TagKeyUsage struct {
+	KeyID string `json:"keyId"`
+	Name  string `json:"name"`
+	TagUsageTotals
+}
+
+// TagUsageRollup 标签下所有 Key 的用量汇总
+type TagUsageRollup struct {
+	Tag   string         `json:"tag"`
+	Days  int            `json:"days"`
+	Total TagUsageTotals `json:"total"`
+	Keys  []TagKeyUsage  `json:"keys"` // 按成本降序
+}
+
+// GetTagUsageRollup 汇总带有指定标签的所有 Key 最近 days 天（含今天）的用量与成本
+func (c *Client) GetTagUsageRollup(ctx context.Context, tag string, days int) (*TagUsageRollup, error) {
+	return c.getTagUsageRollupAt(ctx, tag, days, time.Now())
+}
+
+func (c *Client) getTagUsageRollupAt(ctx context.Context, tag string, days int, now time.Time) (*TagUsageRollup, error) {
+	if days <= 0 || days > MaxTagUsageDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxTagUsageDays)
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := c.GetAllAPIKeys(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	tagged := c.filterAPIKeys(keys, APIKeyQueryOptions{Tags: []string{tag}})
+
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = getDateStringInTimezone(now.AddDate(0, 0, -i))
+	}
+
+	// 每个 Key 每天读取用量 Hash，成本按 Key 单独汇总
+	usageCmds := make([][]*goredis.MapStringStringCmd, len(tagged))
+	pipe := client.Pipeline()
+	for i, key := range tagged {
+		usageCmds[i] = make([]*goredis.MapStringStringCmd, len(dates))
+		for j, dateStr := range dates {
+			usageCmds[i][j] = pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, key.ID, dateStr))
+		}
+	}
+	if len(tagged) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+			return nil, fmt.Errorf("failed to get daily usage: %w", err)
+		}
+	}
+
+	rollup := &TagUsageRollup{Tag: tag, Days: days, Keys: make([]TagKeyUsage, 0, len(tagged))}
+	for i, key := range tagged {
+		keyUsage := TagKeyUsage{KeyID: key.ID, Name: key.Name}
+		for _, cmd := range usageCmds[i] {
+			usage, err := cmd.Result()
+			if err != nil && err != goredis.Nil {
+				return nil, fmt.Errorf("failed to get daily usage for key %s: %w", key.ID, err)
+			}
+			keyUsage.add(usage, 0)
+			rollup.Total.add(usage, 0)
+		}
+
+		costMicros, err := sumDailyCostMicros(ctx, client, key.ID, dates)
+		if err != nil {
+			return nil, err
+		}
+		keyUsage.add(nil, costMicros)
+		rollup.Total.add(nil, costMicros)
+		rollup.Keys = append(rollup.Keys, keyUsage)
+	}
+
+	sort.SliceStable(rollup.Keys, func(a, b int) bool {
+		if rollup.Keys[a].costMicros != rollup.Keys[b].costMicros {
+			return rollup.Keys[a].costMicros > rollup.Keys[b].costMicros
+		}
+		return rollup.Keys[a].KeyID < rollup.Keys[b].KeyID
+	})
+
+	return rollup, nil
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// seedTaggedKey 写入带标签的 API Key 及其哈希映射
+func seedTaggedKey(hook *memoryRedisHook, id, name, tags string) {
+	hook.hashes[PrefixAPIKey+id] = map[string]string{
+		"id":       id,
+		"name":     name,
+		"isActive": "true",
+		"tags":     tags,
+	}
+	if hook.hashes[PrefixAPIKeyHashMap] == nil {
+		hook.hashes[PrefixAPIKeyHashMap] = make(map[string]string)
+	}
+	hook.hashes[PrefixAPIKeyHashMap]["hash-"+id] = id
+}
+
+// seedDailyUsage 写入某天的用量与成本
+func seedDailyUsage(hook *memoryRedisHook, keyID, dateStr string, requests, inputTokens int64, cost string) {
+	hook.hashes[fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, dateStr)] = map[string]string{
+		"requests":    fmt.Sprint(requests),
+		"inputTokens": fmt.Sprint(inputTokens),
+		"allTokens":   fmt.Sprint(inputTokens),
+	}
+	hook.hashes[fmt.Sprintf("usage:cost:daily:%s:%s", keyID, dateStr)] = map[string]string{"totalCost": cost}
+}
+
+func TestGetTagUsageRollup_AggregatesTaggedKeys(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+	today := getDateStringInTimezone(now)
+	yesterday := getDateStringInTimezone(now.AddDate(0, 0, -1))
+	old := getDateStringInTimezone(now.AddDate(0, 0, -10))
+
+	seedTaggedKey(hook, "ios", "iOS app", `["mobile","prod"]`)
+	seedTaggedKey(hook, "android", "Android app", `["mobile"]`)
+	seedTaggedKey(hook, "web", "Web app", `["web"]`)
+
+	seedDailyUsage(hook, "ios", today, 3, 300, "0.3")
+	seedDailyUsage(hook, "ios", yesterday, 2, 200, "0.2")
+	seedDailyUsage(hook, "ios", old, 100, 10000, "10") // 超出统计范围
+	seedDailyUsage(hook, "android", today, 1, 100, "0.7")
+	seedDailyUsage(hook, "web", today, 50, 5000, "5") // 不带标签
+
+	rollup, err := c.getTagUsageRollupAt(ctx, "mobile", 7, now)
+	if err != nil {
+		t.Fatalf("getTagUsageRollupAt() error = %v", err)
+	}
+
+	if rollup.Total.Requests != 6 || rollup.Total.InputTokens != 600 || rollup.Total.AllTokens != 600 {
+		t.Errorf("total usage = %+v, want 6 requests / 600 tokens", rollup.Total)
+	}
+	if rollup.Total.Cost != 1.2 {
+		t.Errorf("total cost = %v, want 1.2", rollup.Total.Cost)
+	}
+	if len(rollup.Keys) != 2 {
+		t.Fatalf("keys = %+v, want ios and android", rollup.Keys)
+	}
+	// 按成本降序
+	if rollup.Keys[0].KeyID != "android" || rollup.Keys[0].Cost != 0.7 || rollup.Keys[0].Name != "Android app" {
+		t.Errorf("keys[0] = %+v, want android with cost 0.7", rollup.Keys[0])
+	}
+	if rollup.Keys[1].KeyID != "ios" || rollup.Keys[1].Requests != 5 || rollup.Keys[1].Cost != 0.5 {
+		t.Errorf("keys[1] = %+v, want ios with 5 requests and cost 0.5", rollup.Keys[1])
+	}
+}
+
+func TestGetTagUsageRollup_UnknownTagAndInvalidDays(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	seedTaggedKey(hook, "ios", "iOS app", `["mobile"]`)
+
+	rollup, err := c.GetTagUsageRollup(ctx, "desktop", 7)
+	if err != nil {
+		t.Fatalf("GetTagUsageRollup() error = %v", err)
+	}
+	if len(rollup.Keys) != 0 || rollup.Total.Requests != 0 {
+		t.Errorf("rollup = %+v, want empty", rollup)
+	}
+
+	if _, err := c.GetTagUsageRollup(ctx, "mobile", MaxTagUsageDays+1); err == nil {
+		t.Error("expected error for days beyond retention")
+	}
+}
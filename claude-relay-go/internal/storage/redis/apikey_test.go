@@ -1,6 +1,10 @@
 package redis
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -166,6 +170,69 @@ func TestMapToAPIKeyDefaults(t *testing.T) {
 	}
 }
 
+func TestMapToAPIKey_ConcurrentRequestQueueMode(t *testing.T) {
+	tests := []struct {
+		data map[string]string
+		want string
+	}{
+		// Node 创建 Key 时默认写入 false，不视为关闭
+		{map[string]string{"concurrentRequestQueueEnabled": "false"}, ConcurrentRequestQueueModeDefault},
+		{map[string]string{"concurrentRequestQueueEnabled": "true"}, ConcurrentRequestQueueModeEnabled},
+		{map[string]string{"concurrentRequestQueueMode": "disabled"}, ConcurrentRequestQueueModeDisabled},
+		// 显式模式优先于 Node 端字段
+		{map[string]string{"concurrentRequestQueueEnabled": "true", "concurrentRequestQueueMode": "disabled"}, ConcurrentRequestQueueModeDisabled},
+		{map[string]string{"concurrentRequestQueueMode": "bogus"}, ConcurrentRequestQueueModeDefault},
+	}
+	for _, tt := range tests {
+		if got := mapToAPIKey(tt.data).ConcurrentRequestQueueMode; got != tt.want {
+			t.Errorf("mapToAPIKey(%v) mode = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+
+	m := apiKeyToMap(&APIKey{ID: "k", ConcurrentRequestQueueMode: ConcurrentRequestQueueModeDisabled})
+	if m["concurrentRequestQueueMode"] != "disabled" || m["concurrentRequestQueueEnabled"] != "false" {
+		t.Errorf("disabled key serialized as %v", m)
+	}
+}
+
+func TestSetAPIKey_ConcurrentRequestQueueModeOptOut(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	// 曾经开启过排队的 Key
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "hashedKey": "hash-a", "concurrentRequestQueueEnabled": "true"}
+
+	if err := c.SetAPIKey(ctx, &APIKey{ID: "key-a", HashedKey: "hash-a", ConcurrentRequestQueueMode: ConcurrentRequestQueueModeDisabled}); err != nil {
+		t.Fatalf("SetAPIKey() error = %v", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["concurrentRequestQueueEnabled"]; got != "false" {
+		t.Errorf("concurrentRequestQueueEnabled = %q, want false", got)
+	}
+	key, err := c.GetAPIKey(ctx, "key-a")
+	if err != nil {
+		t.Fatalf("GetAPIKey() error = %v", err)
+	}
+	if key.ConcurrentRequestQueueMode != ConcurrentRequestQueueModeDisabled || key.ConcurrentRequestQueueEnabled {
+		t.Errorf("mode = %q enabled = %v, want disabled", key.ConcurrentRequestQueueMode, key.ConcurrentRequestQueueEnabled)
+	}
+
+	// 恢复默认后不再残留 disabled 模式
+	if err := c.SetAPIKey(ctx, &APIKey{ID: "key-a", HashedKey: "hash-a"}); err != nil {
+		t.Fatalf("SetAPIKey() error = %v", err)
+	}
+	if key, _ = c.GetAPIKey(ctx, "key-a"); key.ConcurrentRequestQueueMode != ConcurrentRequestQueueModeDefault {
+		t.Errorf("mode = %q, want default", key.ConcurrentRequestQueueMode)
+	}
+
+	// 只更新 Node 端字段时同步模式
+	if err := c.UpdateAPIKeyFields(ctx, "key-a", map[string]interface{}{"concurrentRequestQueueEnabled": true}); err != nil {
+		t.Fatalf("UpdateAPIKeyFields() error = %v", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["concurrentRequestQueueMode"]; got != ConcurrentRequestQueueModeEnabled {
+		t.Errorf("concurrentRequestQueueMode = %q, want enabled", got)
+	}
+}
+
 func TestAPIKeyRoundTrip(t *testing.T) {
 	// Test that converting to map and back preserves data
 	now := time.Now().Truncate(time.Second)
@@ -300,6 +367,95 @@ func TestAPIKeyStatsStruct(t *testing.T) {
 	}
 }
 
+func TestComputeAPIKeyStats_TestKeys(t *testing.T) {
+	keys := []APIKey{
+		{ID: "prod-1", IsActive: true, UserID: "user-1"},
+		{ID: "prod-2", IsActive: true},
+		{ID: "load-1", IsActive: true, IsTest: true, UserID: "user-2"},
+		{ID: "load-2", IsDeleted: true, IsTest: true},
+	}
+	now := time.Now()
+
+	included := computeAPIKeyStats(keys, false, now)
+	if included.TotalKeys != 4 || included.ActiveKeys != 3 || included.DeletedKeys != 1 || included.KeysWithUsers != 2 {
+		t.Errorf("included stats = %+v, want total 4, active 3, deleted 1, withUsers 2", included)
+	}
+	if included.TestKeys != 2 {
+		t.Errorf("included TestKeys = %d, want 2", included.TestKeys)
+	}
+
+	excluded := computeAPIKeyStats(keys, true, now)
+	if excluded.TotalKeys != 2 || excluded.ActiveKeys != 2 || excluded.DeletedKeys != 0 || excluded.KeysWithUsers != 1 {
+		t.Errorf("excluded stats = %+v, want total 2, active 2, deleted 0, withUsers 1", excluded)
+	}
+	if excluded.TestKeys != 0 {
+		t.Errorf("excluded TestKeys = %d, want 0", excluded.TestKeys)
+	}
+}
+
+func TestFilterAPIKeys_ExcludeTest(t *testing.T) {
+	c := &Client{}
+	keys := []APIKey{{ID: "prod-1"}, {ID: "load-1", IsTest: true}}
+
+	if got := c.filterAPIKeys(keys, APIKeyQueryOptions{}); len(got) != 2 {
+		t.Errorf("without ExcludeTest got %d keys, want 2", len(got))
+	}
+
+	got := c.filterAPIKeys(keys, APIKeyQueryOptions{ExcludeTest: true})
+	if len(got) != 1 || got[0].ID != "prod-1" {
+		t.Errorf("with ExcludeTest got %+v, want only prod-1", got)
+	}
+}
+
+func filteredIDs(keys []APIKey) []string {
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
+
+func TestFilterAPIKeys_Permission(t *testing.T) {
+	c := &Client{}
+	keys := []APIKey{
+		{ID: "claude-only", Permissions: []string{"claude"}},
+		{ID: "gemini-only", Permissions: []string{"Gemini"}},
+		{ID: "all", Permissions: []string{"all"}},
+		{ID: "unset"},
+		{ID: "multi", Permissions: []string{"claude", "openai"}},
+	}
+
+	got := filteredIDs(c.filterAPIKeys(keys, APIKeyQueryOptions{Permission: "gemini"}))
+	if want := []string{"gemini-only", "all", "unset"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("permission=gemini got %v, want %v", got, want)
+	}
+
+	got = filteredIDs(c.filterAPIKeys(keys, APIKeyQueryOptions{Permission: "claude"}))
+	if want := []string{"claude-only", "all", "unset", "multi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("permission=claude got %v, want %v", got, want)
+	}
+}
+
+func TestFilterAPIKeys_AllowedClient(t *testing.T) {
+	c := &Client{}
+	keys := []APIKey{
+		{ID: "cc", Permissions: []string{"claude"}, AllowedClients: []string{"claude_code"}},
+		{ID: "gemini-cli", AllowedClients: []string{"gemini_cli"}},
+		{ID: "wildcard", Permissions: []string{"claude"}, AllowedClients: []string{"claude_*"}},
+		{ID: "unrestricted"},
+	}
+
+	got := filteredIDs(c.filterAPIKeys(keys, APIKeyQueryOptions{AllowedClient: "Claude_Code"}))
+	if want := []string{"cc", "wildcard"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowedClient=claude_code got %v, want %v", got, want)
+	}
+
+	got = filteredIDs(c.filterAPIKeys(keys, APIKeyQueryOptions{AllowedClient: "claude_code", Permission: "gemini"}))
+	if len(got) != 0 {
+		t.Errorf("combined filters got %v, want none", got)
+	}
+}
+
 func TestAPIKeyQueryOptions(t *testing.T) {
 	isActive := true
 	opts := APIKeyQueryOptions{
@@ -431,7 +587,10 @@ func TestNormalizeAPIKeyFieldUpdates_NoHashFields(t *testing.T) {
 		"isActive": true,
 	}
 
-	stringUpdates, newHashValue, hashValueUpdated := normalizeAPIKeyFieldUpdates(updates)
+	stringUpdates, newHashValue, hashValueUpdated, err := normalizeAPIKeyFieldUpdates(updates)
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyFieldUpdates() error = %v", err)
+	}
 
 	if hashValueUpdated {
 		t.Errorf("expected hashValueUpdated false, got true")
@@ -458,7 +617,10 @@ func TestNormalizeAPIKeyFieldUpdates_APIKeySync(t *testing.T) {
 		"apiKey": "newhash",
 	}
 
-	stringUpdates, newHashValue, hashValueUpdated := normalizeAPIKeyFieldUpdates(updates)
+	stringUpdates, newHashValue, hashValueUpdated, err := normalizeAPIKeyFieldUpdates(updates)
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyFieldUpdates() error = %v", err)
+	}
 
 	if !hashValueUpdated {
 		t.Errorf("expected hashValueUpdated true, got false")
@@ -480,7 +642,10 @@ func TestNormalizeAPIKeyFieldUpdates_HashedKeyPreferred(t *testing.T) {
 		"apiKey":    "hashB",
 	}
 
-	stringUpdates, newHashValue, hashValueUpdated := normalizeAPIKeyFieldUpdates(updates)
+	stringUpdates, newHashValue, hashValueUpdated, err := normalizeAPIKeyFieldUpdates(updates)
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyFieldUpdates() error = %v", err)
+	}
 
 	if !hashValueUpdated {
 		t.Errorf("expected hashValueUpdated true, got false")
@@ -501,7 +666,10 @@ func TestNormalizeAPIKeyFieldUpdates_NilHashValue(t *testing.T) {
 		"hashedKey": nil,
 	}
 
-	stringUpdates, newHashValue, hashValueUpdated := normalizeAPIKeyFieldUpdates(updates)
+	stringUpdates, newHashValue, hashValueUpdated, err := normalizeAPIKeyFieldUpdates(updates)
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyFieldUpdates() error = %v", err)
+	}
 
 	if !hashValueUpdated {
 		t.Errorf("expected hashValueUpdated true, got false")
@@ -516,3 +684,150 @@ func TestNormalizeAPIKeyFieldUpdates_NilHashValue(t *testing.T) {
 		t.Errorf("expected apiKey empty string, got %v", stringUpdates["apiKey"])
 	}
 }
+
+func TestRestoreAPIKey(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	// Node deleteApiKey 写入的软删除形态：禁用并记录删除信息
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{
+		"id": "key-a", "name": "a", "hashedKey": "hash-a", "isActive": "false", "isDeleted": "true",
+		"deletedAt": "2025-01-01T00:00:00.000Z", "deletedBy": "admin", "deletedByType": "admin",
+	}
+	hook.hashes[PrefixAPIKey+"key-live"] = map[string]string{"id": "key-live", "name": "live", "hashedKey": "hash-live", "isDeleted": "false"}
+	hook.hashes[PrefixAPIKey+"key-taken"] = map[string]string{"id": "key-taken", "hashedKey": "hash-live", "isDeleted": "true"}
+	hook.hashes[PrefixAPIKeyHashMap] = map[string]string{"hash-live": "key-live"} // key-a 的映射已被清理
+
+	if err := c.RestoreAPIKey(ctx, "key-a"); err != nil {
+		t.Fatalf("RestoreAPIKey() error = %v", err)
+	}
+	key, err := c.GetAPIKeyByHash(ctx, "hash-a")
+	if err != nil || key == nil || key.ID != "key-a" || key.IsDeleted {
+		t.Errorf("GetAPIKeyByHash(hash-a) = %+v, %v; want restored key-a", key, err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]["name"]; got != "a" {
+		t.Errorf("name = %q, other fields must be preserved", got)
+	}
+	if !key.IsActive {
+		t.Error("restored key should be active")
+	}
+	restored := hook.hashes[PrefixAPIKey+"key-a"]
+	for _, field := range []string{"deletedAt", "deletedBy", "deletedByType"} {
+		if _, ok := restored[field]; ok {
+			t.Errorf("%s should be removed on restore", field)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, restored["restoredAt"]); err != nil {
+		t.Errorf("restoredAt = %q, want an RFC3339 timestamp", restored["restoredAt"])
+	}
+
+	if err := c.RestoreAPIKey(ctx, "key-a"); !errors.Is(err, ErrAPIKeyNotDeleted) {
+		t.Errorf("restore live key error = %v, want ErrAPIKeyNotDeleted", err)
+	}
+	if err := c.RestoreAPIKey(ctx, "key-missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("restore missing key error = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := c.RestoreAPIKey(ctx, "key-taken"); !errors.Is(err, ErrAPIKeyHashConflict) {
+		t.Errorf("restore key with reused hash error = %v, want ErrAPIKeyHashConflict", err)
+	}
+	if got := hook.hashes[PrefixAPIKeyHashMap]["hash-live"]; got != "key-live" {
+		t.Errorf("hash-live mapped to %q, must stay on key-live", got)
+	}
+}
+
+func TestNormalizeAPIKeyFieldUpdates_RejectsUnknownAndInvalidFields(t *testing.T) {
+	_, _, _, err := normalizeAPIKeyFieldUpdates(map[string]interface{}{
+		"dailycostlimit":      10.0,
+		"foo":                 "bar",
+		"dailyCostLimit":      "ten",
+		"isActive":            "yes",
+		"expiresAt":           "tomorrow",
+		"tags":                "not-json",
+		"expirationMode":      "forever",
+		"concurrentLimit":     float64(5),
+		"lastUsedAt":          time.Now(),
+		"costAlertThresholds": []interface{}{0.5, 0.8},
+	})
+
+	var fieldsErr *APIKeyFieldsError
+	if !errors.As(err, &fieldsErr) || !errors.Is(err, ErrInvalidAPIKeyFields) {
+		t.Fatalf("error = %v, want *APIKeyFieldsError", err)
+	}
+	if !reflect.DeepEqual(fieldsErr.Unknown, []string{"dailycostlimit", "foo"}) {
+		t.Errorf("Unknown = %v, want [dailycostlimit foo]", fieldsErr.Unknown)
+	}
+	wantInvalid := []string{"dailyCostLimit", "expirationMode", "expiresAt", "isActive", "tags"}
+	if len(fieldsErr.Invalid) != len(wantInvalid) {
+		t.Errorf("Invalid = %v, want %v", fieldsErr.Invalid, wantInvalid)
+	}
+	for _, field := range wantInvalid {
+		if _, ok := fieldsErr.Invalid[field]; !ok {
+			t.Errorf("Invalid missing %s: %v", field, fieldsErr.Invalid)
+		}
+	}
+	for _, field := range []string{"dailycostlimit", "dailyCostLimit", "expirationMode"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error %q should name field %s", err.Error(), field)
+		}
+	}
+}
+
+func TestUpdateAPIKeyFields_ValidatesBeforeWriting(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	hook.hashes[PrefixAPIKey+"key-a"] = map[string]string{"id": "key-a", "name": "a"}
+
+	err := c.UpdateAPIKeyFields(ctx, "key-a", map[string]interface{}{"name": "renamed", "dailycostlimit": 10.0})
+	if !errors.Is(err, ErrInvalidAPIKeyFields) {
+		t.Fatalf("UpdateAPIKeyFields() error = %v, want ErrInvalidAPIKeyFields", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]; got["name"] != "a" || got["dailycostlimit"] != "" {
+		t.Errorf("key = %v, rejected updates must not be written", got)
+	}
+
+	if err := c.UpdateAPIKeyFields(ctx, "key-a", map[string]interface{}{
+		"name": "renamed", "dailyCostLimit": "12.5", "expirationMode": "activation", "isActive": true,
+	}); err != nil {
+		t.Fatalf("UpdateAPIKeyFields() error = %v", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]; got["name"] != "renamed" || got["dailyCostLimit"] != "12.5" {
+		t.Errorf("key = %v, want valid updates written", got)
+	}
+	// Node updateApiKey 允许的字段与软删除字段同样可更新
+	if err := c.UpdateAPIKeyFields(ctx, "key-a", map[string]interface{}{
+		"tokenLimit": 1000, "concurrencyLimit": 2, "claudeAccountId": "acct-1", "bedrockAccountId": "",
+		"enableModelRestriction": true, "restrictedModels": []string{"claude-3-opus"}, "enableClientRestriction": false,
+		"enableModelPassthrough": true, "userUsername": "alice", "deletedAt": "2025-01-01T00:00:00.000Z",
+		"deletedBy": "admin", "deletedByType": "admin", "restoredAt": "2025-01-02T00:00:00.000Z",
+	}); err != nil {
+		t.Fatalf("UpdateAPIKeyFields() with Node fields error = %v", err)
+	}
+	if got := hook.hashes[PrefixAPIKey+"key-a"]; got["claudeAccountId"] != "acct-1" || got["userUsername"] != "alice" {
+		t.Errorf("key = %v, want Node fields written", got)
+	}
+}
+
+func TestMapToAPIKey_NodeActivationShape(t *testing.T) {
+	// Node.js 创建的激活模式 Key：未激活时 expiresAt 为空字符串
+	pending := mapToAPIKey(map[string]string{
+		"id": "key-1", "isActive": "true", "expirationMode": "activation",
+		"activationDays": "30", "activationUnit": "days", "isActivated": "false", "expiresAt": "",
+	})
+	if pending.IsActivated || pending.ExpiresAt != nil || pending.ActivatedAt != nil {
+		t.Errorf("pending key = activated %v expiresAt %v activatedAt %v, want unactivated without dates",
+			pending.IsActivated, pending.ExpiresAt, pending.ActivatedAt)
+	}
+
+	// Node.js 激活后写入 toISOString() 格式的 activatedAt / expiresAt
+	activated := mapToAPIKey(map[string]string{
+		"id": "key-1", "isActive": "true", "expirationMode": "activation",
+		"activationDays": "30", "activationUnit": "days", "isActivated": "true",
+		"activatedAt": "2024-05-01T08:00:00.000Z", "expiresAt": "2024-05-31T08:00:00.000Z",
+	})
+	wantExpiry := time.Date(2024, 5, 31, 8, 0, 0, 0, time.UTC)
+	if !activated.IsActivated || activated.ExpiresAt == nil || !activated.ExpiresAt.Equal(wantExpiry) {
+		t.Errorf("activated key expiresAt = %v, want %v", activated.ExpiresAt, wantExpiry)
+	}
+}
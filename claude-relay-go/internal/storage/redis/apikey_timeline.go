@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// TimelineUsageDays 时间线中包含的每日使用汇总天数
+const TimelineUsageDays = 7
+
+// 时间线事件类型
+const (
+	TimelineEventCreated        = "created"
+	TimelineEventActivated      = "activated"
+	TimelineEventConfigReplaced = "config_replaced"
+	TimelineEventUsage          = "usage"
+	TimelineEventError          = "error"
+	TimelineEventLastUsed       = "last_used"
+	TimelineEventExpired        = "expired"
+)
+
+// TimelineEvent API Key 生命周期中的单个事件
+type TimelineEvent struct {
+	At      time.Time              `json:"at"`
+	Type    string                 `json:"type"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// APIKeyTimeline API Key 生命周期时间线（按时间正序）
+type APIKeyTimeline struct {
+	KeyID       string          `json:"keyId"`
+	Events      []TimelineEvent `json:"events"`
+	Unavailable []string        `json:"unavailable,omitempty"` // 读取失败而被跳过的数据源
+}
+
+// timelineDailyUsage 单日使用汇总
+type timelineDailyUsage struct {
+	day   time.Time // 当日零点（配置时区）
+	date  string
+	stats *UsageStats
+}
+
+// timelineSources 组装时间线所需的数据，缺失的部分为空
+type timelineSources struct {
+	snapshot   *APIKeyConfigSnapshot
+	captures   []DebugCapture
+	dailyUsage []timelineDailyUsage
+}
+
+// GetAPIKeyTimeline 汇总 API Key 的生命周期时间戳、配置替换、近期错误与每日使用
+// API Key 不存在时返回 nil；单个数据源读取失败时跳过并记录在 Unavailable 中
+func (c *Client) GetAPIKeyTimeline(ctx context.Context, keyID string) (*APIKeyTimeline, error) {
+	return c.getAPIKeyTimelineAt(ctx, keyID, time.Now())
+}
+
+// getAPIKeyTimelineAt 按指定时间组装时间线
+func (c *Client) getAPIKeyTimelineAt(ctx context.Context, keyID string, now time.Time) (*APIKeyTimeline, error) {
+	key, err := c.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	var sources timelineSources
+	var unavailable []string
+	skip := func(source string, err error) {
+		unavailable = append(unavailable, source)
+		logger.Warn("Skipping timeline source",
+			zap.String("keyId", keyID),
+			zap.String("source", source),
+			zap.Error(err))
+	}
+
+	if sources.snapshot, err = c.GetAPIKeyConfigSnapshot(ctx, keyID); err != nil {
+		skip("configSnapshot", err)
+	}
+	if sources.captures, err = c.GetDebugCaptures(ctx, keyID); err != nil {
+		skip("debugCaptures", err)
+	}
+	if sources.dailyUsage, err = c.getTimelineDailyUsage(ctx, keyID, now); err != nil {
+		skip("dailyUsage", err)
+	}
+
+	timeline := buildAPIKeyTimeline(key, sources, now)
+	timeline.Unavailable = unavailable
+	return timeline, nil
+}
+
+// getTimelineDailyUsage 获取最近 TimelineUsageDays 天的每日使用统计
+func (c *Client) getTimelineDailyUsage(ctx context.Context, keyID string, now time.Time) ([]timelineDailyUsage, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := getTimezoneOffset()
+	days := make([]timelineDailyUsage, 0, TimelineUsageDays)
+	for i := 0; i < TimelineUsageDays; i++ {
+		dateStr := getDateStringInTimezone(now.AddDate(0, 0, -i))
+		day, err := parseDateString(dateStr)
+		if err != nil {
+			continue
+		}
+		days = append(days, timelineDailyUsage{day: day.Add(-offset), date: dateStr})
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(days))
+	for i, d := range days {
+		cmds[i] = pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, d.date))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+
+	for i := range days {
+		data, _ := cmds[i].Result()
+		days[i].stats = parseUsageData(data)
+	}
+	return days, nil
+}
+
+// buildAPIKeyTimeline 将各数据源转换为事件并按时间排序（同一时间保持生成顺序）
+func buildAPIKeyTimeline(key *APIKey, sources timelineSources, now time.Time) *APIKeyTimeline {
+	events := make([]TimelineEvent, 0, 8+len(sources.captures)+len(sources.dailyUsage))
+	add := func(at time.Time, eventType string, details map[string]interface{}) {
+		events = append(events, TimelineEvent{At: at, Type: eventType, Details: details})
+	}
+
+	if !key.CreatedAt.IsZero() {
+		add(key.CreatedAt, TimelineEventCreated, map[string]interface{}{"name": key.Name})
+	}
+	if key.ActivatedAt != nil {
+		add(*key.ActivatedAt, TimelineEventActivated, nil)
+	}
+
+	if snapshot := sources.snapshot; snapshot != nil && !snapshot.CreatedAt.IsZero() {
+		fields := make([]string, 0, len(snapshot.Fields)+len(snapshot.Missing))
+		for field := range snapshot.Fields {
+			fields = append(fields, field)
+		}
+		fields = append(fields, snapshot.Missing...)
+		sort.Strings(fields)
+		add(snapshot.CreatedAt, TimelineEventConfigReplaced, map[string]interface{}{"fields": fields})
+	}
+
+	for _, d := range sources.dailyUsage {
+		if d.stats == nil || d.stats.RequestCount == 0 {
+			continue
+		}
+		add(d.day, TimelineEventUsage, map[string]interface{}{
+			"date":     d.date,
+			"requests": d.stats.RequestCount,
+			"tokens":   d.stats.TotalTokens,
+		})
+	}
+
+	for _, capture := range sources.captures {
+		if capture.StatusCode < 400 {
+			continue
+		}
+		details := map[string]interface{}{
+			"statusCode": capture.StatusCode,
+			"method":     capture.Method,
+			"path":       capture.Path,
+		}
+		if capture.RequestID != "" {
+			details["requestId"] = capture.RequestID
+		}
+		if capture.Model != "" {
+			details["model"] = capture.Model
+		}
+		add(capture.CapturedAt, TimelineEventError, details)
+	}
+
+	if key.LastUsedAt != nil {
+		add(*key.LastUsedAt, TimelineEventLastUsed, nil)
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		add(*key.ExpiresAt, TimelineEventExpired, nil)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+
+	return &APIKeyTimeline{KeyID: key.ID, Events: events}
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildAPIKeyTimeline_OrdersSeededEventsChronologically(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	activated := at(2)
+	lastUsed := at(100)
+	expires := at(120)
+	now := at(150)
+
+	key := &APIKey{
+		ID:          "key-1",
+		Name:        "timeline",
+		CreatedAt:   at(0),
+		ActivatedAt: &activated,
+		LastUsedAt:  &lastUsed,
+		ExpiresAt:   &expires,
+	}
+	sources := timelineSources{
+		snapshot: &APIKeyConfigSnapshot{
+			Fields:    map[string]string{"tokenLimit": "100"},
+			Missing:   []string{"dailyCostLimit"},
+			CreatedAt: at(50),
+		},
+		// 采样列表最新的在前
+		captures: []DebugCapture{
+			{CapturedAt: at(90), StatusCode: 500, Method: "POST", Path: "/v1/messages"},
+			{CapturedAt: at(80), StatusCode: 200, Method: "POST", Path: "/v1/messages"},
+			{CapturedAt: at(30), StatusCode: 429, Method: "POST", Path: "/v1/messages"},
+		},
+		dailyUsage: []timelineDailyUsage{
+			{day: at(96), date: "2024-06-05", stats: &UsageStats{RequestCount: 3, TotalTokens: 300}},
+			{day: at(72), date: "2024-06-04"},
+			{day: at(24), date: "2024-06-02", stats: &UsageStats{RequestCount: 1, TotalTokens: 10}},
+		},
+	}
+
+	timeline := buildAPIKeyTimeline(key, sources, now)
+
+	want := []struct {
+		at        time.Time
+		eventType string
+	}{
+		{at(0), TimelineEventCreated},
+		{at(2), TimelineEventActivated},
+		{at(24), TimelineEventUsage},
+		{at(30), TimelineEventError},
+		{at(50), TimelineEventConfigReplaced},
+		{at(90), TimelineEventError},
+		{at(96), TimelineEventUsage},
+		{at(100), TimelineEventLastUsed},
+		{at(120), TimelineEventExpired},
+	}
+	if len(timeline.Events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(timeline.Events), len(want), timeline.Events)
+	}
+	for i, w := range want {
+		got := timeline.Events[i]
+		if !got.At.Equal(w.at) || got.Type != w.eventType {
+			t.Errorf("event %d = %s at %v, want %s at %v", i, got.Type, got.At, w.eventType, w.at)
+		}
+	}
+
+	fields := timeline.Events[4].Details["fields"].([]string)
+	if len(fields) != 2 || fields[0] != "dailyCostLimit" || fields[1] != "tokenLimit" {
+		t.Errorf("config_replaced fields = %v", fields)
+	}
+}
+
+func TestBuildAPIKeyTimeline_DegradesWithoutOptionalData(t *testing.T) {
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	future := created.Add(48 * time.Hour)
+	key := &APIKey{ID: "key-1", CreatedAt: created, ExpiresAt: &future}
+
+	timeline := buildAPIKeyTimeline(key, timelineSources{}, created.Add(time.Hour))
+
+	if len(timeline.Events) != 1 || timeline.Events[0].Type != TimelineEventCreated {
+		t.Fatalf("events = %+v, want only created (future expiry is not an event)", timeline.Events)
+	}
+}
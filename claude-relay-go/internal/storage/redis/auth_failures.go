@@ -0,0 +1,141 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxAuthFailureWindow 认证失败统计最大查询窗口（受分钟桶 TTL 限制）
+const MaxAuthFailureWindow = 24 * time.Hour
+
+// AuthFailureStats 认证失败统计（按原因汇总）
+type AuthFailureStats struct {
+	Window string           `json:"window"`
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Total  int64            `json:"total"`
+	ByCode map[string]int64 `json:"byCode"`
+}
+
+// authFailureKey 认证失败分钟桶键
+func authFailureKey(code string, minute int64) string {
+	return fmt.Sprintf("%s%s:%d", PrefixAuthFailures, code, minute)
+}
+
+// GetAuthFailureSamplePercent 获取认证失败采样比例（1-100）
+func GetAuthFailureSamplePercent() int {
+	if config.Cfg != nil && config.Cfg.Security.AuthFailureSamplePercent > 0 {
+		if config.Cfg.Security.AuthFailureSamplePercent > 100 {
+			return 100
+		}
+		return config.Cfg.Security.AuthFailureSamplePercent
+	}
+	return 100
+}
+
+// RecordAuthFailure 按采样比例记录一次认证失败
+// 采样命中时按 100/percent 的期望权重累加（非整数部分随机进位），保证汇总值的期望等于真实次数
+func (c *Client) RecordAuthFailure(ctx context.Context, code string) error {
+	percent := GetAuthFailureSamplePercent()
+	if percent < 100 && rand.Float64()*100 >= float64(percent) {
+		return nil
+	}
+	return c.IncrAuthFailure(ctx, code, authFailureWeight(percent, rand.Float64()), time.Now())
+}
+
+// authFailureWeight 采样权重：100/percent 向下取整，小数部分以 roll（[0,1) 随机数）概率进位
+func authFailureWeight(percent int, roll float64) int64 {
+	exact := 100 / float64(percent)
+	weight := int64(exact)
+	if roll < exact-float64(weight) {
+		weight++
+	}
+	return weight
+}
+
+// IncrAuthFailure 累加指定原因在某分钟桶的失败次数
+func (c *Client) IncrAuthFailure(ctx context.Context, code string, weight int64, at time.Time) error {
+	if code == "" {
+		code = "unknown"
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key := authFailureKey(code, getMinuteTimestamp(at))
+	pipe := client.Pipeline()
+	pipe.IncrBy(ctx, key, weight)
+	pipe.Expire(ctx, key, TTLAuthFailures)
+	pipe.SAdd(ctx, KeyAuthFailureCodes, code)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record auth failure: %w", err)
+	}
+	return nil
+}
+
+// GetAuthFailureStats 汇总最近 window 时间内各原因的认证失败次数
+func (c *Client) GetAuthFailureStats(ctx context.Context, window time.Duration) (*AuthFailureStats, error) {
+	return c.getAuthFailureStatsAt(ctx, window, time.Now())
+}
+
+func (c *Client) getAuthFailureStatsAt(ctx context.Context, window time.Duration, now time.Time) (*AuthFailureStats, error) {
+	if window <= 0 || window > MaxAuthFailureWindow {
+		return nil, fmt.Errorf("window must be between 1m and %s", MaxAuthFailureWindow)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &AuthFailureStats{
+		Window: window.String(),
+		From:   now.Add(-window),
+		To:     now,
+		ByCode: make(map[string]int64),
+	}
+
+	codes, err := client.SMembers(ctx, KeyAuthFailureCodes).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth failure codes: %w", err)
+	}
+	if len(codes) == 0 {
+		return stats, nil
+	}
+
+	startMinute := getMinuteTimestamp(stats.From)
+	endMinute := getMinuteTimestamp(now)
+
+	pipe := client.Pipeline()
+	cmds := make(map[string][]*redis.StringCmd, len(codes))
+	for _, code := range codes {
+		for minute := startMinute; minute <= endMinute; minute += 60 {
+			cmds[code] = append(cmds[code], pipe.Get(ctx, authFailureKey(code, minute)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get auth failure buckets: %w", err)
+	}
+
+	for code, codeCmds := range cmds {
+		var sum int64
+		for _, cmd := range codeCmds {
+			if val, err := cmd.Result(); err == nil {
+				sum += parseInt64(val)
+			}
+		}
+		if sum > 0 {
+			stats.ByCode[code] = sum
+			stats.Total += sum
+		}
+	}
+
+	return stats, nil
+}
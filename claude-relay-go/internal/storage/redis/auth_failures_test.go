@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIncrAuthFailure_BumpsPerCodeCounters(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 30, 15, 0, time.UTC)
+	minute := getMinuteTimestamp(now)
+
+	for _, code := range []string{"invalid_api_key", "invalid_api_key", "client_not_allowed"} {
+		if err := c.IncrAuthFailure(ctx, code, 1, now); err != nil {
+			t.Fatalf("IncrAuthFailure(%s) error = %v", code, err)
+		}
+	}
+
+	if got := hook.strings[authFailureKey("invalid_api_key", minute)]; got != "2" {
+		t.Errorf("invalid_api_key counter = %q, want 2", got)
+	}
+	if got := hook.strings[authFailureKey("client_not_allowed", minute)]; got != "1" {
+		t.Errorf("client_not_allowed counter = %q, want 1", got)
+	}
+	if !hook.sets[KeyAuthFailureCodes]["invalid_api_key"] || !hook.sets[KeyAuthFailureCodes]["client_not_allowed"] {
+		t.Errorf("codes set = %v", hook.sets[KeyAuthFailureCodes])
+	}
+}
+
+func TestGetAuthFailureStats_AggregatesWindow(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 30, 0, 0, time.UTC)
+
+	record := func(code string, ago time.Duration, weight int64) {
+		if err := c.IncrAuthFailure(ctx, code, weight, now.Add(-ago)); err != nil {
+			t.Fatalf("IncrAuthFailure() error = %v", err)
+		}
+	}
+	record("invalid_api_key", 0, 1)
+	record("invalid_api_key", 5*time.Minute, 2)
+	record("key_expired", 10*time.Minute, 1)
+	// 窗口外的数据不计入
+	record("invalid_api_key", 2*time.Hour, 100)
+
+	stats, err := c.getAuthFailureStatsAt(ctx, 15*time.Minute, now)
+	if err != nil {
+		t.Fatalf("getAuthFailureStatsAt() error = %v", err)
+	}
+	if stats.ByCode["invalid_api_key"] != 3 {
+		t.Errorf("invalid_api_key = %d, want 3", stats.ByCode["invalid_api_key"])
+	}
+	if stats.ByCode["key_expired"] != 1 {
+		t.Errorf("key_expired = %d, want 1", stats.ByCode["key_expired"])
+	}
+	if stats.Total != 4 {
+		t.Errorf("Total = %d, want 4", stats.Total)
+	}
+
+	if _, err := c.getAuthFailureStatsAt(ctx, 48*time.Hour, now); err == nil {
+		t.Error("expected error for window beyond bucket retention")
+	}
+}
+
+func TestAuthFailureWeight_UnbiasedForUnevenPercent(t *testing.T) {
+	// 30% 采样：权重 3 或 4，期望为 100/30
+	const rolls = 1000
+	var sum int64
+	for i := 0; i < rolls; i++ {
+		sum += authFailureWeight(30, float64(i)/rolls)
+	}
+	if got, want := float64(sum)/rolls, 100.0/30; got < want-0.01 || got > want+0.01 {
+		t.Errorf("mean weight = %.3f, want %.3f", got, want)
+	}
+	if w := authFailureWeight(25, 0.99); w != 4 {
+		t.Errorf("weight for 25%% = %d, want 4", w)
+	}
+	if w := authFailureWeight(100, 0.5); w != 1 {
+		t.Errorf("weight for 100%% = %d, want 1", w)
+	}
+}
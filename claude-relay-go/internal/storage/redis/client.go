@@ -30,9 +30,13 @@ var (
 // Client Redis 客户端封装
 type Client struct {
 	client      *redis.Client
+	replica     *redis.Client // 只读副本（未配置或连接失败时为 nil）
 	isConnected bool
 	mu          sync.RWMutex
 	cfg         *config.RedisConfig
+	held        heldConcurrency // 本实例持有的并发租约与排队计数
+
+	accountListeners accountListeners // 账户变更监听器
 }
 
 var (
@@ -72,6 +76,12 @@ func (c *Client) Connect(cfg *config.RedisConfig) error {
 	}
 
 	c.client = redis.NewClient(opts)
+	if cfg.KeyPrefix != "" {
+		// Node.js 不识别命名空间，启用后 Node.js 读写的仍是未加前缀的 key
+		logger.Warn("⚠️ Redis key namespace enabled; data is not shared with the Node.js service",
+			zap.String("keyPrefix", cfg.KeyPrefix))
+		c.client.AddHook(newNamespaceHook(cfg.KeyPrefix))
+	}
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
@@ -86,16 +96,53 @@ func (c *Client) Connect(cfg *config.RedisConfig) error {
 	logger.Info("🔗 Redis connected successfully",
 		zap.String("host", cfg.Host),
 		zap.Int("port", cfg.Port),
-		zap.Int("db", cfg.DB))
+		zap.Int("db", cfg.DB),
+		zap.String("keyPrefix", cfg.KeyPrefix))
+
+	if cfg.ReplicaHost != "" {
+		c.connectReplica(cfg, *opts)
+	}
 
 	return nil
 }
 
+// connectReplica 连接只读副本（失败时仅告警，读取回退到主库）
+func (c *Client) connectReplica(cfg *config.RedisConfig, opts redis.Options) {
+	opts.Addr = fmt.Sprintf("%s:%d", cfg.ReplicaHost, cfg.ReplicaPort)
+	replica := redis.NewClient(&opts)
+	if cfg.KeyPrefix != "" {
+		replica.AddHook(newNamespaceHook(cfg.KeyPrefix))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	if _, err := replica.Ping(ctx).Result(); err != nil {
+		logger.Warn("Failed to connect to Redis replica, reads will use primary",
+			zap.String("host", cfg.ReplicaHost),
+			zap.Int("port", cfg.ReplicaPort),
+			zap.Error(err))
+		_ = replica.Close()
+		return
+	}
+
+	c.replica = replica
+	logger.Info("🔗 Redis replica connected",
+		zap.String("host", cfg.ReplicaHost),
+		zap.Int("port", cfg.ReplicaPort))
+}
+
 // Disconnect 断开连接
 func (c *Client) Disconnect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.replica != nil {
+		if err := c.replica.Close(); err != nil {
+			logger.Warn("Failed to close Redis replica", zap.Error(err))
+		}
+		c.replica = nil
+	}
 	if c.client != nil {
 		if err := c.client.Close(); err != nil {
 			return err
@@ -117,6 +164,21 @@ func (c *Client) GetClientSafe() (*redis.Client, error) {
 	return c.client, nil
 }
 
+// GetReadClientSafe 获取只读客户端（配置了只读副本时返回副本，否则返回主库）
+// 仅用于可容忍复制延迟的扫描与统计；认证、限额检查等强一致读取及所有写入必须使用 GetClientSafe
+func (c *Client) GetReadClientSafe() (*redis.Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.isConnected || c.client == nil {
+		return nil, ErrNotConnected
+	}
+	if c.replica != nil {
+		return c.replica, nil
+	}
+	return c.client, nil
+}
+
 // IsConnected 检查连接状态
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
@@ -162,6 +224,27 @@ func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, er
 	return client.HGetAll(ctx, key).Result()
 }
 
+// HScanPage 使用 HSCAN 分页读取 Hash 字段（避免大 Hash 的 HGETALL 阻塞 Redis）
+// 返回本页字段及下一页游标，游标为 0 表示遍历结束
+func (c *Client) HScanPage(ctx context.Context, key string, cursor uint64, match string, count int64) (map[string]string, uint64, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, next, err := client.HScan(ctx, key, cursor, match, count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// HSCAN 返回 field1, value1, field2, value2...
+	fields := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		fields[items[i]] = items[i+1]
+	}
+	return fields, next, nil
+}
+
 // HSet 设置 Hash 字段
 func (c *Client) HSet(ctx context.Context, key string, values ...interface{}) error {
 	client, err := c.GetClientSafe()
@@ -186,7 +269,20 @@ func (c *Client) ScanKeys(ctx context.Context, pattern string, count int64) ([]s
 	if err != nil {
 		return nil, err
 	}
+	return scanKeys(ctx, client, pattern, count)
+}
+
+// ScanReadKeys 在只读客户端上扫描匹配的键（见 GetReadClientSafe）
+func (c *Client) ScanReadKeys(ctx context.Context, pattern string, count int64) ([]string, error) {
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+	return scanKeys(ctx, client, pattern, count)
+}
 
+// scanKeys 使用 SCAN 遍历匹配的键
+func scanKeys(ctx context.Context, client *redis.Client, pattern string, count int64) ([]string, error) {
 	var keys []string
 	var cursor uint64
 
@@ -291,3 +387,21 @@ func (c *Client) IncrWithExpiry(ctx context.Context, key string, expiry time.Dur
 
 	return result.(int64), nil
 }
+
+// GetCounter 只读获取计数器当前值（不存在返回 0）
+func (c *Client) GetCounter(ctx context.Context, key string) (int64, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return parseInt64(result), nil
+}
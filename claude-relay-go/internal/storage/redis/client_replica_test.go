@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// newReplicaClientForTest 创建主库与只读副本分别由两个 hook 拦截的客户端
+func newReplicaClientForTest(t *testing.T, primary, replica goredis.Hook) *Client {
+	t.Helper()
+
+	c := newConnectedClientForTest(t, primary)
+	replicaClient := goredis.NewClient(&goredis.Options{
+		Addr:             "127.0.0.1:6380",
+		DisableIndentity: true,
+	})
+	replicaClient.AddHook(replica)
+	c.replica = replicaClient
+	return c
+}
+
+func TestReadReplica_ScanHeavyReadsUseReplica(t *testing.T) {
+	primary, replica := newMemoryRedisHook(), newMemoryRedisHook()
+	c := newReplicaClientForTest(t, primary, replica)
+	ctx := context.Background()
+
+	replica.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "replica-only", "isActive": "true"}
+	seedStickySession(t, replica, "session-1", "acc-1", time.Now().Add(time.Hour))
+
+	keys, err := c.GetAllAPIKeys(ctx, false)
+	if err != nil {
+		t.Fatalf("GetAllAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].Name != "replica-only" {
+		t.Errorf("GetAllAPIKeys() = %+v, want key read from replica", keys)
+	}
+
+	counts, err := c.GetStickySessionCountsByAccount(ctx)
+	if err != nil {
+		t.Fatalf("GetStickySessionCountsByAccount() error = %v", err)
+	}
+	if counts["acc-1"] != 1 {
+		t.Errorf("GetStickySessionCountsByAccount() = %v, want session read from replica", counts)
+	}
+
+	// 强一致读取（认证查询）仍走主库
+	key, err := c.GetAPIKey(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetAPIKey() error = %v", err)
+	}
+	if key != nil {
+		t.Errorf("GetAPIKey() = %+v, want nil because the primary has no such key", key)
+	}
+}
+
+func TestReadReplica_WritesUsePrimary(t *testing.T) {
+	primary, replica := newMemoryRedisHook(), newMemoryRedisHook()
+	c := newReplicaClientForTest(t, primary, replica)
+	ctx := context.Background()
+
+	if err := c.SetStickySession(ctx, "session-1", "acc-1", "claude", time.Hour); err != nil {
+		t.Fatalf("SetStickySession() error = %v", err)
+	}
+	if err := c.HSet(ctx, PrefixAPIKey+"key-2", "name", "written"); err != nil {
+		t.Fatalf("HSet() error = %v", err)
+	}
+
+	if _, ok := primary.strings[PrefixStickySession+"session-1"]; !ok {
+		t.Error("sticky session should be written to the primary")
+	}
+	if primary.hashes[PrefixAPIKey+"key-2"]["name"] != "written" {
+		t.Error("hash field should be written to the primary")
+	}
+	if len(replica.strings) != 0 || len(replica.hashes) != 0 {
+		t.Errorf("replica received writes: strings=%v hashes=%v", replica.strings, replica.hashes)
+	}
+}
+
+func TestGetReadClientSafe_FallsBackToPrimary(t *testing.T) {
+	primary := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, primary)
+
+	readClient, err := c.GetReadClientSafe()
+	if err != nil {
+		t.Fatalf("GetReadClientSafe() error = %v", err)
+	}
+	if readClient != c.client {
+		t.Error("read client should be the primary when no replica is configured")
+	}
+
+	if _, err := (&Client{}).GetReadClientSafe(); err != ErrNotConnected {
+		t.Errorf("GetReadClientSafe() on disconnected client error = %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestHScanPage_IteratesLargeHash(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	const total = 250
+	hash := make(map[string]string, total)
+	for i := 0; i < total; i++ {
+		hash[fmt.Sprintf("hash-%03d", i)] = fmt.Sprintf("key-%03d", i)
+	}
+	hook.hashes[PrefixAPIKeyHashMap] = hash
+
+	seen := make(map[string]string)
+	var cursor uint64
+	for pages := 0; ; pages++ {
+		if pages > total {
+			t.Fatal("HSCAN did not terminate")
+		}
+		fields, next, err := c.HScanPage(ctx, PrefixAPIKeyHashMap, cursor, "", 40)
+		if err != nil {
+			t.Fatalf("HScanPage() error = %v", err)
+		}
+		for field, value := range fields {
+			if _, dup := seen[field]; dup {
+				t.Errorf("field %s returned twice", field)
+			}
+			seen[field] = value
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	if len(seen) != total {
+		t.Fatalf("read %d fields, want %d", len(seen), total)
+	}
+	for field, value := range hash {
+		if seen[field] != value {
+			t.Errorf("field %s = %q, want %q", field, seen[field], value)
+		}
+	}
+}
+
+func TestHScanPage_Match(t *testing.T) {
+	hook := newMemoryRedisHook()
+	hook.hashes["h"] = map[string]string{"a:1": "1", "a:2": "2", "b:1": "3"}
+	c := newConnectedClientForTest(t, hook)
+
+	fields, next, err := c.HScanPage(context.Background(), "h", 0, "a:*", 100)
+	if err != nil {
+		t.Fatalf("HScanPage() error = %v", err)
+	}
+	if next != 0 || len(fields) != 2 || fields["a:1"] != "1" || fields["a:2"] != "2" {
+		t.Errorf("HScanPage() = %v, %d", fields, next)
+	}
+}
@@ -18,6 +18,8 @@ const (
 	DefaultConcurrencyCleanupGraceSeconds = 60 // 1分钟
 	// MinConcurrencyLeaseSeconds 最小租约时间（秒）
 	MinConcurrencyLeaseSeconds = 30
+	// minConcurrencyKeyTTLMillis 并发租约键的最小 TTL（毫秒）
+	minConcurrencyKeyTTLMillis = 60000
 )
 
 // ConcurrencyConfig 并发控制配置
@@ -26,6 +28,11 @@ type ConcurrencyConfig struct {
 	CleanupGraceSeconds int // 清理宽限期（秒）
 }
 
+// keyTTLMillis 并发租约相关键的 TTL（租约时间 + 清理宽限期，最小 60 秒）
+func (cfg ConcurrencyConfig) keyTTLMillis(leaseSeconds int) int64 {
+	return max(int64((leaseSeconds+cfg.CleanupGraceSeconds)*1000), minConcurrencyKeyTTLMillis)
+}
+
 // ConcurrencyStatus 并发状态
 type ConcurrencyStatus struct {
 	APIKeyID       string            `json:"apiKeyId"`
@@ -42,57 +49,99 @@ type ActiveRequest struct {
 	RequestID        string `json:"requestId"`
 	ExpireAt         string `json:"expireAt"`
 	RemainingSeconds int64  `json:"remainingSeconds"`
+
+	// 旁路哈希 concurrency_meta:{keyId} 中记录了元数据时返回
+	Model      string `json:"model,omitempty"`
+	ClientType string `json:"clientType,omitempty"`
+	StartedAt  string `json:"startedAt,omitempty"`
 }
 
 // Lua 脚本（嵌入式）
 const (
+	// 同步并发数计数器（与有序集合同 TTL，并发为 0 时删除），供租约脚本拼接使用
+	luaSyncConcurrencyCount = `
+local function syncConcurrencyCount(key, countKey)
+    local count = redis.call('ZCARD', key)
+    if count <= 0 then
+        redis.call('DEL', countKey)
+        return 0
+    end
+    local pttl = redis.call('PTTL', key)
+    if pttl > 0 then
+        redis.call('SET', countKey, count, 'PX', pttl)
+    else
+        redis.call('SET', countKey, count)
+    end
+    return count
+end
+`
+
 	// 并发控制脚本
-	luaConcurrencyIncr = `
+	luaConcurrencyIncr = luaSyncConcurrencyCount + `
 local key = KEYS[1]
+local countKey = KEYS[2]
+local metaKey = KEYS[3]
 local member = ARGV[1]
 local expireAt = tonumber(ARGV[2])
 local now = tonumber(ARGV[3])
 local ttl = tonumber(ARGV[4])
+local meta = ARGV[5]
 
 redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
 redis.call('ZADD', key, expireAt, member)
+if meta and meta ~= '' then
+    redis.call('HSET', metaKey, member, meta)
+end
 
 if ttl > 0 then
     redis.call('PEXPIRE', key, ttl)
+    if meta and meta ~= '' then
+        redis.call('PEXPIRE', metaKey, ttl)
+    end
 end
 
-local count = redis.call('ZCARD', key)
-return count
+return syncConcurrencyCount(key, countKey)
 `
 
-	// 释放并发租约脚本
-	luaConcurrencyDecr = `
+	// 释放并发租约脚本（同时释放全局租约）
+	// 返回 {剩余并发数, 租约是否存在并被移除}
+	luaConcurrencyDecr = luaSyncConcurrencyCount + `
 local key = KEYS[1]
+local globalKey = KEYS[2]
+local countKey = KEYS[3]
+local metaKey = KEYS[4]
 local member = ARGV[1]
 local now = tonumber(ARGV[2])
+local globalMember = ARGV[3]
 
+local removed = 0
 if member and member ~= '' then
-    redis.call('ZREM', key, member)
+    removed = redis.call('ZREM', key, member)
+    redis.call('ZREM', globalKey, globalMember)
+    redis.call('HDEL', metaKey, member)
 end
 
 redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
 
-local count = redis.call('ZCARD', key)
+local count = syncConcurrencyCount(key, countKey)
 if count <= 0 then
-    redis.call('DEL', key)
-    return 0
+    redis.call('DEL', key, metaKey)
+    return {0, removed}
 end
 
-return count
+return {count, removed}
 `
 
-	// 刷新并发租约脚本
+	// 刷新并发租约脚本（全局租约存在时一并续约）
 	luaConcurrencyRefresh = `
 local key = KEYS[1]
+local globalKey = KEYS[2]
+local metaKey = KEYS[3]
 local member = ARGV[1]
 local expireAt = tonumber(ARGV[2])
 local now = tonumber(ARGV[3])
 local ttl = tonumber(ARGV[4])
+local globalMember = ARGV[5]
 
 redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
 
@@ -102,14 +151,92 @@ if exists then
     redis.call('ZADD', key, expireAt, member)
     if ttl > 0 then
         redis.call('PEXPIRE', key, ttl)
+        if redis.call('HEXISTS', metaKey, member) == 1 then
+            redis.call('PEXPIRE', metaKey, ttl)
+        end
+    end
+    if redis.call('ZSCORE', globalKey, globalMember) then
+        redis.call('ZADD', globalKey, expireAt, globalMember)
+        if ttl > 0 then
+            redis.call('PEXPIRE', globalKey, ttl)
+        end
     end
     return 1
 end
 
 return 0
+`
+
+	// 并发控制脚本（含全局上限）：全局已满时不写入任何租约，否则同时写入 API Key 与全局租约
+	// 返回 {API Key 并发数, 全局并发数}，全局已满时 API Key 并发数为 -1
+	luaConcurrencyIncrGlobal = luaSyncConcurrencyCount + `
+local key = KEYS[1]
+local globalKey = KEYS[2]
+local countKey = KEYS[3]
+local metaKey = KEYS[4]
+local member = ARGV[1]
+local expireAt = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local globalMember = ARGV[5]
+local globalLimit = tonumber(ARGV[6])
+local meta = ARGV[7]
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+redis.call('ZREMRANGEBYSCORE', globalKey, '-inf', now)
+
+local globalCount = redis.call('ZCARD', globalKey)
+if globalLimit > 0 and not redis.call('ZSCORE', globalKey, globalMember) and globalCount >= globalLimit then
+    return {-1, globalCount}
+end
+
+redis.call('ZADD', key, expireAt, member)
+redis.call('ZADD', globalKey, expireAt, globalMember)
+if meta and meta ~= '' then
+    redis.call('HSET', metaKey, member, meta)
+end
+
+if ttl > 0 then
+    redis.call('PEXPIRE', key, ttl)
+    redis.call('PEXPIRE', globalKey, ttl)
+    if meta and meta ~= '' then
+        redis.call('PEXPIRE', metaKey, ttl)
+    end
+end
+
+return {syncConcurrencyCount(key, countKey), redis.call('ZCARD', globalKey)}
 `
 )
 
+// globalConcurrencyMember 全局并发租约成员（同一 requestID 可能出现在不同 API Key 下）
+func globalConcurrencyMember(apiKeyID, requestID string) string {
+	return apiKeyID + ":" + requestID
+}
+
+// concurrencyMetas 批量读取并发成员的旁路元数据（元数据仅用于展示，读取失败时忽略）
+func concurrencyMetas(ctx context.Context, client goredis.Cmdable, apiKeyID string, members []goredis.Z) map[string]string {
+	metas := make(map[string]string)
+	if len(members) == 0 {
+		return metas
+	}
+
+	fields := make([]string, len(members))
+	for i, member := range members {
+		fields[i] = member.Member.(string)
+	}
+	values, err := client.HMGet(ctx, PrefixConcurrencyMeta+apiKeyID, fields...).Result()
+	if err != nil {
+		logger.Debug("Failed to read concurrency metadata", zap.String("apiKeyId", apiKeyID), zap.Error(err))
+		return metas
+	}
+	for i, value := range values {
+		if meta, ok := value.(string); ok {
+			metas[fields[i]] = meta
+		}
+	}
+	return metas
+}
+
 // getConcurrencyConfig 获取并发控制配置
 func (c *Client) getConcurrencyConfig() ConcurrencyConfig {
 	return ConcurrencyConfig{
@@ -140,13 +267,10 @@ func (c *Client) IncrConcurrency(ctx context.Context, apiKeyID, requestID string
 	key := PrefixConcurrency + apiKeyID
 	now := time.Now().UnixMilli()
 	expireAt := now + int64(leaseSeconds)*1000
-	ttl := int64((leaseSeconds + config.CleanupGraceSeconds) * 1000)
-	if ttl < 60000 {
-		ttl = 60000 // 最小 60 秒
-	}
+	ttl := config.keyTTLMillis(leaseSeconds)
 
-	result, err := client.Eval(ctx, luaConcurrencyIncr, []string{key},
-		requestID, expireAt, now, ttl).Result()
+	result, err := client.Eval(ctx, luaConcurrencyIncr, []string{key, PrefixConcurrencyCount + apiKeyID, PrefixConcurrencyMeta + apiKeyID},
+		requestID, expireAt, now, ttl, concurrencyMetaFromContext(ctx)).Result()
 	if err != nil {
 		logger.Error("Failed to increment concurrency", zap.Error(err))
 		return 0, err
@@ -156,6 +280,7 @@ func (c *Client) IncrConcurrency(ctx context.Context, apiKeyID, requestID string
 	if !ok {
 		return 0, fmt.Errorf("unexpected result type from concurrency incr: %T", result)
 	}
+	c.held.trackSlot(ctx, apiKeyID, requestID)
 	logger.Debug("Incremented concurrency",
 		zap.String("apiKeyId", apiKeyID),
 		zap.String("requestId", requestID),
@@ -164,33 +289,117 @@ func (c *Client) IncrConcurrency(ctx context.Context, apiKeyID, requestID string
 	return count, nil
 }
 
+// IncrConcurrencyWithGlobal 增加并发计数并同步占用全局并发租约
+// globalLimit > 0 且全局并发已满时不占用任何租约，返回 acquired=false
+func (c *Client) IncrConcurrencyWithGlobal(ctx context.Context, apiKeyID, requestID string, leaseSeconds int, globalLimit int64) (int64, int64, bool, error) {
+	if requestID == "" {
+		return 0, 0, false, fmt.Errorf("request ID is required for concurrency tracking")
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	config := c.getConcurrencyConfig()
+	if leaseSeconds <= 0 {
+		leaseSeconds = config.LeaseSeconds
+	}
+	if leaseSeconds < MinConcurrencyLeaseSeconds {
+		leaseSeconds = MinConcurrencyLeaseSeconds
+	}
+
+	key := PrefixConcurrency + apiKeyID
+	now := time.Now().UnixMilli()
+	expireAt := now + int64(leaseSeconds)*1000
+	ttl := config.keyTTLMillis(leaseSeconds)
+
+	result, err := client.Eval(ctx, luaConcurrencyIncrGlobal, []string{key, KeyGlobalConcurrency, PrefixConcurrencyCount + apiKeyID, PrefixConcurrencyMeta + apiKeyID},
+		requestID, expireAt, now, ttl, globalConcurrencyMember(apiKeyID, requestID), globalLimit, concurrencyMetaFromContext(ctx)).Result()
+	if err != nil {
+		logger.Error("Failed to increment concurrency with global limit", zap.Error(err))
+		return 0, 0, false, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, false, fmt.Errorf("unexpected result from global concurrency incr: %v", result)
+	}
+	count, ok1 := values[0].(int64)
+	globalCount, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, false, fmt.Errorf("unexpected result from global concurrency incr: %v", result)
+	}
+
+	if count < 0 {
+		logger.Debug("Global concurrency limit reached",
+			zap.String("apiKeyId", apiKeyID),
+			zap.String("requestId", requestID),
+			zap.Int64("globalCount", globalCount),
+			zap.Int64("globalLimit", globalLimit))
+		return 0, globalCount, false, nil
+	}
+
+	c.held.trackSlot(ctx, apiKeyID, requestID)
+	return count, globalCount, true, nil
+}
+
+// GetGlobalConcurrency 获取全局并发数（仅统计未过期租约）
+func (c *Client) GetGlobalConcurrency(ctx context.Context) (int64, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixMilli()
+
+	// 先清理过期
+	client.ZRemRangeByScore(ctx, KeyGlobalConcurrency, "-inf", fmt.Sprintf("%d", now))
+
+	return client.ZCard(ctx, KeyGlobalConcurrency).Result()
+}
+
 // DecrConcurrency 减少并发计数
 func (c *Client) DecrConcurrency(ctx context.Context, apiKeyID, requestID string) (int64, error) {
+	count, _, err := c.ReleaseConcurrency(ctx, apiKeyID, requestID)
+	return count, err
+}
+
+// ReleaseConcurrency 释放并发租约，返回剩余并发数与租约是否确实存在
+// 重复释放（如 defer 与断连处理各释放一次）或租约已过期时 released 为 false
+func (c *Client) ReleaseConcurrency(ctx context.Context, apiKeyID, requestID string) (int64, bool, error) {
 	client, err := c.GetClientSafe()
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 
 	key := PrefixConcurrency + apiKeyID
 	now := time.Now().UnixMilli()
 
-	result, err := client.Eval(ctx, luaConcurrencyDecr, []string{key},
-		requestID, now).Result()
+	result, err := client.Eval(ctx, luaConcurrencyDecr, []string{key, KeyGlobalConcurrency, PrefixConcurrencyCount + apiKeyID, PrefixConcurrencyMeta + apiKeyID},
+		requestID, now, globalConcurrencyMember(apiKeyID, requestID)).Result()
 	if err != nil {
 		logger.Error("Failed to decrement concurrency", zap.Error(err))
-		return 0, err
+		return 0, false, err
 	}
 
-	count, ok := result.(int64)
-	if !ok {
-		return 0, fmt.Errorf("unexpected result type from concurrency decr: %T", result)
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, false, fmt.Errorf("unexpected result from concurrency decr: %v", result)
 	}
+	count, ok1 := values[0].(int64)
+	removed, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, false, fmt.Errorf("unexpected result from concurrency decr: %v", result)
+	}
+	c.held.untrackSlot(apiKeyID, requestID)
 	logger.Debug("Decremented concurrency",
 		zap.String("apiKeyId", apiKeyID),
 		zap.String("requestId", requestID),
-		zap.Int64("count", count))
+		zap.Int64("count", count),
+		zap.Bool("released", removed > 0))
 
-	return count, nil
+	return count, removed > 0, nil
 }
 
 // RefreshConcurrencyLease 刷新并发租约，防止长连接提前过期
@@ -212,13 +421,10 @@ func (c *Client) RefreshConcurrencyLease(ctx context.Context, apiKeyID, requestI
 	key := PrefixConcurrency + apiKeyID
 	now := time.Now().UnixMilli()
 	expireAt := now + int64(leaseSeconds)*1000
-	ttl := int64((leaseSeconds + config.CleanupGraceSeconds) * 1000)
-	if ttl < 60000 {
-		ttl = 60000
-	}
+	ttl := config.keyTTLMillis(leaseSeconds)
 
-	result, err := client.Eval(ctx, luaConcurrencyRefresh, []string{key},
-		requestID, expireAt, now, ttl).Result()
+	result, err := client.Eval(ctx, luaConcurrencyRefresh, []string{key, KeyGlobalConcurrency, PrefixConcurrencyMeta + apiKeyID},
+		requestID, expireAt, now, ttl, globalConcurrencyMember(apiKeyID, requestID)).Result()
 	if err != nil {
 		logger.Error("Failed to refresh concurrency lease", zap.Error(err))
 		return false, err
@@ -238,13 +444,42 @@ func (c *Client) RefreshConcurrencyLease(ctx context.Context, apiKeyID, requestI
 	return refreshed, nil
 }
 
+// GetConcurrencyLeaseExpiry 获取并发租约的到期时间（ZSCORE），租约不存在时返回 false
+func (c *Client) GetConcurrencyLeaseExpiry(ctx context.Context, apiKeyID, requestID string) (time.Time, bool, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	score, err := client.ZScore(ctx, PrefixConcurrency+apiKeyID, requestID).Result()
+	if err != nil {
+		if err == goredis.Nil {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("failed to get concurrency lease: %w", err)
+	}
+	return time.UnixMilli(int64(score)), true, nil
+}
+
 // GetConcurrency 获取当前并发数
+// 启用计数器校准时读取 O(1) 计数器（偏差由定时校准限定），计数器不存在时回退到实时统计
 func (c *Client) GetConcurrency(ctx context.Context, apiKeyID string) (int64, error) {
 	client, err := c.GetClientSafe()
 	if err != nil {
 		return 0, err
 	}
 
+	if concurrencyCounterEnabled() {
+		value, err := client.Get(ctx, PrefixConcurrencyCount+apiKeyID).Result()
+		if err == nil {
+			return parseInt64(value), nil
+		}
+		if err != goredis.Nil {
+			logger.Debug("Failed to read concurrency counter, falling back to ZCARD",
+				zap.String("apiKeyId", apiKeyID), zap.Error(err))
+		}
+	}
+
 	key := PrefixConcurrency + apiKeyID
 	now := time.Now().UnixMilli()
 
@@ -296,16 +531,11 @@ func (c *Client) GetConcurrencyStatus(ctx context.Context, apiKeyID string) (*Co
 	var activeRequests []ActiveRequest
 	var expiredRequests []ActiveRequest
 
+	metas := concurrencyMetas(ctx, client, apiKeyID, members)
 	for _, member := range members {
-		requestID := member.Member.(string)
 		expireAt := int64(member.Score)
-		remainingSeconds := (expireAt - now) / 1000
-
-		request := ActiveRequest{
-			RequestID:        requestID,
-			ExpireAt:         time.UnixMilli(expireAt).Format(time.RFC3339),
-			RemainingSeconds: remainingSeconds,
-		}
+		requestID := member.Member.(string)
+		request := newActiveRequest(requestID, metas[requestID], expireAt, now)
 
 		if expireAt > now {
 			activeRequests = append(activeRequests, request)
@@ -325,15 +555,15 @@ func (c *Client) GetConcurrencyStatus(ctx context.Context, apiKeyID string) (*Co
 	}, nil
 }
 
-// GetAllConcurrencyStatus 获取所有并发状态
+// GetAllConcurrencyStatus 获取所有并发状态（从只读副本读取）
 func (c *Client) GetAllConcurrencyStatus(ctx context.Context) ([]ConcurrencyStatus, error) {
-	keys, err := c.ScanKeys(ctx, PrefixConcurrency+"*", 1000)
+	keys, err := c.ScanReadKeys(ctx, PrefixConcurrency+"*", 1000)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now().UnixMilli()
-	client, err := c.GetClientSafe()
+	client, err := c.GetReadClientSafe()
 	if err != nil {
 		return nil, err
 	}
@@ -353,16 +583,10 @@ func (c *Client) GetAllConcurrencyStatus(ctx context.Context) ([]ConcurrencyStat
 		}
 
 		var activeRequests []ActiveRequest
+		metas := concurrencyMetas(ctx, client, apiKeyID, members)
 		for _, member := range members {
 			requestID := member.Member.(string)
-			expireAt := int64(member.Score)
-			remainingSeconds := (expireAt - now) / 1000
-
-			activeRequests = append(activeRequests, ActiveRequest{
-				RequestID:        requestID,
-				ExpireAt:         time.UnixMilli(expireAt).Format(time.RFC3339),
-				RemainingSeconds: remainingSeconds,
-			})
+			activeRequests = append(activeRequests, newActiveRequest(requestID, metas[requestID], int64(member.Score), now))
 		}
 
 		// 获取过期成员数量
@@ -393,7 +617,8 @@ func (c *Client) ForceClearConcurrency(ctx context.Context, apiKeyID string) (in
 	beforeCount, _ := client.ZCard(ctx, key).Result()
 
 	// 删除整个 key
-	client.Del(ctx, key)
+	client.Del(ctx, key, PrefixConcurrencyCount+apiKeyID, PrefixConcurrencyMeta+apiKeyID)
+	c.held.untrackKey(apiKeyID)
 
 	logger.Warn("Force cleared concurrency",
 		zap.String("apiKeyId", apiKeyID),
@@ -417,9 +642,12 @@ func (c *Client) ForceClearAllConcurrency(ctx context.Context) (int, int64, erro
 	var totalCleared int64
 	for _, key := range keys {
 		count, _ := client.ZCard(ctx, key).Result()
-		client.Del(ctx, key)
+		apiKeyID := key[len(PrefixConcurrency):]
+		client.Del(ctx, key, PrefixConcurrencyCount+apiKeyID, PrefixConcurrencyMeta+apiKeyID)
 		totalCleared += count
 	}
+	client.Del(ctx, KeyGlobalConcurrency)
+	c.held.untrackAllSlots()
 
 	logger.Warn("Force cleared all concurrency",
 		zap.Int("keysCleared", len(keys)),
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MaxConcurrencyBoostTTL 临时并发上限的最长有效期
+const MaxConcurrencyBoostTTL = 24 * time.Hour
+
+// ConcurrencyBoost API Key 临时并发上限
+type ConcurrencyBoost struct {
+	Limit     int       `json:"limit"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SetConcurrencyBoost 设置 API Key 的临时并发上限，ttl 到期后自动恢复静态上限（覆盖已有的临时上限）
+func (c *Client) SetConcurrencyBoost(ctx context.Context, apiKeyID string, limit int, ttl time.Duration) (*ConcurrencyBoost, error) {
+	return c.setConcurrencyBoostAt(ctx, apiKeyID, limit, ttl, time.Now())
+}
+
+func (c *Client) setConcurrencyBoostAt(ctx context.Context, apiKeyID string, limit int, ttl time.Duration, now time.Time) (*ConcurrencyBoost, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	boost := &ConcurrencyBoost{Limit: limit, ExpiresAt: now.Add(ttl)}
+	data, err := json.Marshal(boost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal concurrency boost: %w", err)
+	}
+	if err := client.Set(ctx, PrefixConcurrencyBoost+apiKeyID, data, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to set concurrency boost: %w", err)
+	}
+	return boost, nil
+}
+
+// GetConcurrencyBoost 获取 API Key 当前生效的临时并发上限（未设置或已过期时返回 nil）
+func (c *Client) GetConcurrencyBoost(ctx context.Context, apiKeyID string) (*ConcurrencyBoost, error) {
+	return c.getConcurrencyBoostAt(ctx, apiKeyID, time.Now())
+}
+
+func (c *Client) getConcurrencyBoostAt(ctx context.Context, apiKeyID string, now time.Time) (*ConcurrencyBoost, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.Get(ctx, PrefixConcurrencyBoost+apiKeyID).Result()
+	if err != nil {
+		if err == goredis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get concurrency boost: %w", err)
+	}
+
+	var boost ConcurrencyBoost
+	if err := json.Unmarshal([]byte(data), &boost); err != nil {
+		return nil, fmt.Errorf("failed to parse concurrency boost: %w", err)
+	}
+	// 以记录的过期时间为准，避免 key 过期删除前的短暂延迟
+	if !now.Before(boost.ExpiresAt) {
+		return nil, nil
+	}
+	return &boost, nil
+}
@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyBoost_ActiveUntilTTL(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if boost, err := c.getConcurrencyBoostAt(ctx, "key-1", now); err != nil || boost != nil {
+		t.Fatalf("boost before set = %+v, %v; want nil", boost, err)
+	}
+
+	if _, err := c.setConcurrencyBoostAt(ctx, "key-1", 20, 10*time.Minute, now); err != nil {
+		t.Fatalf("setConcurrencyBoostAt() error = %v", err)
+	}
+	boost, err := c.getConcurrencyBoostAt(ctx, "key-1", now.Add(9*time.Minute))
+	if err != nil || boost == nil || boost.Limit != 20 {
+		t.Fatalf("boost within TTL = %+v, %v; want limit 20", boost, err)
+	}
+
+	if boost, err := c.getConcurrencyBoostAt(ctx, "key-1", now.Add(10*time.Minute)); err != nil || boost != nil {
+		t.Errorf("boost after TTL = %+v, %v; want nil", boost, err)
+	}
+	if boost, _ := c.getConcurrencyBoostAt(ctx, "key-2", now); boost != nil {
+		t.Errorf("boost leaked to another key: %+v", boost)
+	}
+}
@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// concurrencyReconcileTimeout 单次校准超时
+const concurrencyReconcileTimeout = 30 * time.Second
+
+// luaConcurrencyReconcile 清理过期租约后按有序集合重写计数器
+// 返回 {校准前计数器值（不存在为 0）, 实际并发数}
+const luaConcurrencyReconcile = luaSyncConcurrencyCount + `
+local key = KEYS[1]
+local countKey = KEYS[2]
+local now = tonumber(ARGV[1])
+
+local before = tonumber(redis.call('GET', countKey) or '0') or 0
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+
+local count = syncConcurrencyCount(key, countKey)
+if count <= 0 then
+    redis.call('DEL', key)
+end
+return {before, count}
+`
+
+// ConcurrencyCounterCorrection 计数器与有序集合不一致的一次修正
+type ConcurrencyCounterCorrection struct {
+	APIKeyID string `json:"apiKeyId"`
+	Counter  int64  `json:"counter"` // 校准前计数器值
+	Actual   int64  `json:"actual"`  // 清理过期租约后的实际并发数
+}
+
+// ConcurrencyReconcileResult 一次计数器校准的结果
+type ConcurrencyReconcileResult struct {
+	KeysChecked int                            `json:"keysChecked"`
+	Failed      int                            `json:"failed"` // 校准出错而跳过的 Key 数
+	Corrections []ConcurrencyCounterCorrection `json:"corrections"`
+}
+
+// concurrencyCounterEnabled 是否启用并发数计数器读取（需同时启用定时校准以限定偏差）
+func concurrencyCounterEnabled() bool {
+	return config.Cfg != nil && config.Cfg.System.ConcurrencyCounterReconcileInterval > 0
+}
+
+// ReconcileConcurrencyCounters 清理过期租约并按有序集合校准全部并发数计数器
+// 计数器仅由 Go 侧租约脚本更新，租约过期、强制清理等情况下会偏离实际值；
+// Node 的回退路径（Go 服务不可用时 incrConcurrency 直接执行的 ZADD、getAllConcurrencyStatus 等的过期清理）
+// 只操作 concurrency:* 有序集合而不更新 concurrency_count:*，这部分偏差同样只能由本次校准修正
+// 单个 Key 校准失败时记录日志并继续校准其余 Key
+func (c *Client) ReconcileConcurrencyCounters(ctx context.Context) (*ConcurrencyReconcileResult, error) {
+	return c.reconcileConcurrencyCountersAt(ctx, time.Now())
+}
+
+func (c *Client) reconcileConcurrencyCountersAt(ctx context.Context, now time.Time) (*ConcurrencyReconcileResult, error) {
+	leaseKeys, err := c.ScanKeys(ctx, PrefixConcurrency+"*", 1000)
+	if err != nil {
+		return nil, err
+	}
+	counterKeys, err := c.ScanKeys(ctx, PrefixConcurrencyCount+"*", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	// 有序集合与计数器任一存在即需校准（孤立计数器会被删除）
+	ids := make(map[string]struct{}, len(leaseKeys))
+	for _, key := range leaseKeys {
+		if strings.HasPrefix(key, PrefixConcurrencyQueue) {
+			continue
+		}
+		ids[key[len(PrefixConcurrency):]] = struct{}{}
+	}
+	for _, key := range counterKeys {
+		ids[key[len(PrefixConcurrencyCount):]] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	result := &ConcurrencyReconcileResult{Corrections: []ConcurrencyCounterCorrection{}}
+	for _, id := range sorted {
+		values, err := client.Eval(ctx, luaConcurrencyReconcile,
+			[]string{PrefixConcurrency + id, PrefixConcurrencyCount + id}, now.UnixMilli()).Int64Slice()
+		if err == nil && len(values) != 2 {
+			err = fmt.Errorf("unexpected result from concurrency reconcile: %v", values)
+		}
+		if err != nil {
+			logger.Warn("Failed to reconcile concurrency counter", zap.String("apiKeyId", id), zap.Error(err))
+			result.Failed++
+			continue
+		}
+		result.KeysChecked++
+		if values[0] != values[1] {
+			result.Corrections = append(result.Corrections, ConcurrencyCounterCorrection{
+				APIKeyID: id,
+				Counter:  values[0],
+				Actual:   values[1],
+			})
+		}
+	}
+
+	if len(result.Corrections) > 0 || result.Failed > 0 {
+		logger.Info("Reconciled concurrency counters",
+			zap.Int("keysChecked", result.KeysChecked),
+			zap.Int("failed", result.Failed),
+			zap.Int("corrected", len(result.Corrections)))
+	}
+	return result, nil
+}
+
+// ConcurrencyReconciler 定时校准并发数计数器
+type ConcurrencyReconciler struct {
+	client   *Client
+	interval time.Duration
+
+	stopChan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewConcurrencyReconciler 创建并发数计数器校准器
+func NewConcurrencyReconciler(client *Client, interval time.Duration) *ConcurrencyReconciler {
+	return &ConcurrencyReconciler{
+		client:   client,
+		interval: interval,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// NewConcurrencyReconcilerFromConfig 根据全局配置创建校准器（未启用时返回 nil）
+func NewConcurrencyReconcilerFromConfig(client *Client) *ConcurrencyReconciler {
+	if !concurrencyCounterEnabled() {
+		return nil
+	}
+	return NewConcurrencyReconciler(client, config.Cfg.System.ConcurrencyCounterReconcileInterval)
+}
+
+// Start 启动定时校准（立即执行一次）
+func (r *ConcurrencyReconciler) Start() {
+	go r.run()
+	logger.Info("Concurrency counter reconciler started", zap.Duration("interval", r.interval))
+}
+
+// Stop 停止校准并等待进行中的校准结束
+func (r *ConcurrencyReconciler) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		<-r.done
+	})
+}
+
+// run 校准循环
+func (r *ConcurrencyReconciler) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.reconcile()
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			r.reconcile()
+		}
+	}
+}
+
+// reconcile 执行一次校准
+func (r *ConcurrencyReconciler) reconcile() {
+	ctx, cancel := context.WithTimeout(context.Background(), concurrencyReconcileTimeout)
+	defer cancel()
+
+	if _, err := r.client.ReconcileConcurrencyCounters(ctx); err != nil {
+		logger.Warn("Failed to reconcile concurrency counters", zap.Error(err))
+	}
+}
@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestConcurrencyCounter_TracksIncrAndDecr(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{ConcurrencyCounterReconcileInterval: time.Minute}}
+
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	countKey := PrefixConcurrencyCount + "key-a"
+
+	for _, reqID := range []string{"req-1", "req-2"} {
+		if _, err := c.IncrConcurrency(ctx, "key-a", reqID, 60); err != nil {
+			t.Fatalf("IncrConcurrency(%s) error = %v", reqID, err)
+		}
+	}
+	if _, _, _, err := c.IncrConcurrencyWithGlobal(ctx, "key-a", "req-3", 60, 0); err != nil {
+		t.Fatalf("IncrConcurrencyWithGlobal() error = %v", err)
+	}
+	if got := hook.counts[countKey]; got != 3 {
+		t.Fatalf("counter after acquire = %d, want 3", got)
+	}
+
+	if _, err := c.DecrConcurrency(ctx, "key-a", "req-1"); err != nil {
+		t.Fatalf("DecrConcurrency() error = %v", err)
+	}
+	if got, err := c.GetConcurrency(ctx, "key-a"); err != nil || got != 2 {
+		t.Fatalf("GetConcurrency() = %d, %v; want 2 from counter", got, err)
+	}
+
+	for _, reqID := range []string{"req-2", "req-3"} {
+		if _, err := c.DecrConcurrency(ctx, "key-a", reqID); err != nil {
+			t.Fatalf("DecrConcurrency(%s) error = %v", reqID, err)
+		}
+	}
+	if _, ok := hook.counts[countKey]; ok {
+		t.Error("counter should be deleted when concurrency drops to 0")
+	}
+}
+
+func TestReconcileConcurrencyCounters_FixesDrift(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{ConcurrencyCounterReconcileInterval: time.Minute}}
+
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+	live := now.Add(time.Minute).UnixMilli()
+	expired := now.Add(-time.Minute).UnixMilli()
+
+	// key-a：一个租约已过期但计数器仍为 2；key-b：计数器缺失；key-c：孤立计数器
+	hook.zsets[PrefixConcurrency+"key-a"] = map[string]int64{"req-1": live, "req-old": expired}
+	hook.counts[PrefixConcurrencyCount+"key-a"] = 2
+	hook.zsets[PrefixConcurrency+"key-b"] = map[string]int64{"req-2": live}
+	hook.counts[PrefixConcurrencyCount+"key-c"] = 4
+
+	if got, _ := c.GetConcurrency(ctx, "key-a"); got != 2 {
+		t.Fatalf("GetConcurrency(key-a) before reconcile = %d, want drifted 2", got)
+	}
+	// 计数器缺失时回退到实时统计
+	if got, _ := c.GetConcurrency(ctx, "key-b"); got != 1 {
+		t.Fatalf("GetConcurrency(key-b) = %d, want 1 from ZCARD fallback", got)
+	}
+
+	result, err := c.reconcileConcurrencyCountersAt(ctx, now)
+	if err != nil {
+		t.Fatalf("ReconcileConcurrencyCounters() error = %v", err)
+	}
+	if result.KeysChecked != 3 || len(result.Corrections) != 3 {
+		t.Fatalf("result = %+v, want 3 keys checked and 3 corrections", result)
+	}
+	want := map[string][2]int64{"key-a": {2, 1}, "key-b": {0, 1}, "key-c": {4, 0}}
+	for _, fix := range result.Corrections {
+		if w := want[fix.APIKeyID]; fix.Counter != w[0] || fix.Actual != w[1] {
+			t.Errorf("correction %s = %d -> %d, want %d -> %d", fix.APIKeyID, fix.Counter, fix.Actual, w[0], w[1])
+		}
+	}
+
+	if got, _ := c.GetConcurrency(ctx, "key-a"); got != 1 {
+		t.Errorf("GetConcurrency(key-a) after reconcile = %d, want 1", got)
+	}
+	if _, ok := hook.counts[PrefixConcurrencyCount+"key-c"]; ok {
+		t.Error("orphan counter should be removed")
+	}
+
+	again, err := c.reconcileConcurrencyCountersAt(ctx, now)
+	if err != nil || len(again.Corrections) != 0 {
+		t.Errorf("second reconcile = %+v, %v; want no corrections", again, err)
+	}
+}
+
+func TestReconcileConcurrencyCounters_ContinuesAfterKeyError(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{ConcurrencyCounterReconcileInterval: time.Minute}}
+
+	hook := newConcurrencyRedisHook()
+	hook.failReconcile = map[string]bool{PrefixConcurrency + "key-a": true}
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+
+	hook.zsets[PrefixConcurrency+"key-a"] = map[string]int64{"req-1": now.Add(time.Minute).UnixMilli()}
+	hook.counts[PrefixConcurrencyCount+"key-a"] = 3
+	hook.zsets[PrefixConcurrency+"key-b"] = map[string]int64{"req-2": now.Add(time.Minute).UnixMilli()}
+	hook.counts[PrefixConcurrencyCount+"key-b"] = 5
+
+	result, err := c.reconcileConcurrencyCountersAt(ctx, now)
+	if err != nil {
+		t.Fatalf("reconcileConcurrencyCountersAt() error = %v", err)
+	}
+	if result.Failed != 1 || result.KeysChecked != 1 || len(result.Corrections) != 1 || result.Corrections[0].APIKeyID != "key-b" {
+		t.Errorf("result = %+v, want key-a failed and key-b corrected", result)
+	}
+	if got := hook.counts[PrefixConcurrencyCount+"key-b"]; got != 1 {
+		t.Errorf("key-b counter = %d, want 1", got)
+	}
+}
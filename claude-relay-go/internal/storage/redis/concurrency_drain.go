@@ -0,0 +1,210 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// MaxHeldConcurrency 本实例最多跟踪的并发租约数与排队 Key 数（超出后不再跟踪，依赖租约过期回收）
+const MaxHeldConcurrency = 10000
+
+// heldConcurrencyKey 标记上下文中的占用属于本实例自身处理的请求
+type heldConcurrencyKey struct{}
+
+// WithHeldConcurrency 标记本次占用属于本实例自身处理的请求，关闭时由 DrainConcurrency 释放
+// 代理 Node 的占用（IncrConcurrency、IncrConcurrencyQueue、AcquireWithIntent 等接口）不标记，由 Node 自行释放
+func WithHeldConcurrency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, heldConcurrencyKey{}, true)
+}
+
+// isHeldConcurrency 检查上下文是否标记为本实例自身的请求
+func isHeldConcurrency(ctx context.Context) bool {
+	held, _ := ctx.Value(heldConcurrencyKey{}).(bool)
+	return held
+}
+
+// heldConcurrency 本实例持有的并发租约与排队计数（关闭时释放，避免遗留孤儿条目）
+type heldConcurrency struct {
+	mu        sync.Mutex
+	slots     map[string]map[string]struct{} // apiKeyID -> requestID
+	slotCount int
+	queues    map[string]int64 // apiKeyID -> 未释放的排队计数
+}
+
+// ConcurrencyDrainResult 关闭时释放并发占用的结果
+type ConcurrencyDrainResult struct {
+	ReleasedSlots  int   `json:"releasedSlots"`
+	ReleasedQueue  int64 `json:"releasedQueue"`
+	RemainingSlots int   `json:"remainingSlots"`
+	RemainingQueue int64 `json:"remainingQueue"`
+	ExpiredCleaned int64 `json:"expiredCleaned"`
+}
+
+// trackSlot 记录本实例自身请求占用的并发租约（未标记的上下文或超出上限时忽略）
+func (h *heldConcurrency) trackSlot(ctx context.Context, apiKeyID, requestID string) {
+	if !isHeldConcurrency(ctx) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.slots[apiKeyID][requestID]; ok {
+		return
+	}
+	if h.slotCount >= MaxHeldConcurrency {
+		logger.Debug("Held concurrency tracking is full, skipping slot",
+			zap.String("apiKeyId", apiKeyID),
+			zap.String("requestId", requestID))
+		return
+	}
+	if h.slots == nil {
+		h.slots = make(map[string]map[string]struct{})
+	}
+	if h.slots[apiKeyID] == nil {
+		h.slots[apiKeyID] = make(map[string]struct{})
+	}
+	h.slots[apiKeyID][requestID] = struct{}{}
+	h.slotCount++
+}
+
+// untrackSlot 移除已释放的并发租约
+func (h *heldConcurrency) untrackSlot(apiKeyID, requestID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.slots[apiKeyID][requestID]; !ok {
+		return
+	}
+	delete(h.slots[apiKeyID], requestID)
+	h.slotCount--
+	if len(h.slots[apiKeyID]) == 0 {
+		delete(h.slots, apiKeyID)
+	}
+}
+
+// untrackKey 移除 API Key 下的全部并发租约（强制清理后调用）
+func (h *heldConcurrency) untrackKey(apiKeyID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.slotCount -= len(h.slots[apiKeyID])
+	delete(h.slots, apiKeyID)
+}
+
+// untrackAllSlots 移除全部并发租约
+func (h *heldConcurrency) untrackAllSlots() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.slots = nil
+	h.slotCount = 0
+}
+
+// trackQueue 调整本实例自身请求的排队计数（delta 为 0 以下时清零不再跟踪，未标记的上下文忽略）
+func (h *heldConcurrency) trackQueue(ctx context.Context, apiKeyID string, delta int64) {
+	if !isHeldConcurrency(ctx) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.queues == nil {
+		h.queues = make(map[string]int64)
+	}
+	if _, ok := h.queues[apiKeyID]; !ok && (delta <= 0 || len(h.queues) >= MaxHeldConcurrency) {
+		return
+	}
+	h.queues[apiKeyID] += delta
+	if h.queues[apiKeyID] <= 0 {
+		delete(h.queues, apiKeyID)
+	}
+}
+
+// untrackQueue 移除 API Key 的排队计数（清空队列后调用）
+func (h *heldConcurrency) untrackQueue(apiKeyID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.queues, apiKeyID)
+}
+
+// snapshot 复制当前持有的租约与排队计数
+func (h *heldConcurrency) snapshot() (map[string][]string, map[string]int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	slots := make(map[string][]string, len(h.slots))
+	for apiKeyID, requests := range h.slots {
+		for requestID := range requests {
+			slots[apiKeyID] = append(slots[apiKeyID], requestID)
+		}
+	}
+	queues := make(map[string]int64, len(h.queues))
+	for apiKeyID, count := range h.queues {
+		queues[apiKeyID] = count
+	}
+	return slots, queues
+}
+
+// HeldConcurrency 获取本实例当前持有的并发租约数与排队计数
+func (c *Client) HeldConcurrency() (int, int64) {
+	slots, queues := c.held.snapshot()
+	var slotCount int
+	for _, requests := range slots {
+		slotCount += len(requests)
+	}
+	var queueCount int64
+	for _, count := range queues {
+		queueCount += count
+	}
+	return slotCount, queueCount
+}
+
+// DrainConcurrency 释放本实例自身请求仍持有的并发租约与排队计数，并清理过期租约
+// 用于服务关闭时，防止处理器被强制终止后遗留占用；代理 Node 的占用不在此释放
+func (c *Client) DrainConcurrency(ctx context.Context) (*ConcurrencyDrainResult, error) {
+	result := &ConcurrencyDrainResult{}
+	slots, queues := c.held.snapshot()
+
+	for apiKeyID, requests := range slots {
+		for _, requestID := range requests {
+			if _, err := c.DecrConcurrency(ctx, apiKeyID, requestID); err != nil {
+				logger.Warn("Failed to release concurrency slot on shutdown",
+					zap.String("apiKeyId", apiKeyID),
+					zap.String("requestId", requestID),
+					zap.Error(err))
+				continue
+			}
+			result.ReleasedSlots++
+		}
+	}
+
+	for apiKeyID, count := range queues {
+		for i := int64(0); i < count; i++ {
+			if _, err := c.DecrConcurrencyQueue(ctx, apiKeyID); err != nil {
+				logger.Warn("Failed to release queue count on shutdown",
+					zap.String("apiKeyId", apiKeyID),
+					zap.Error(err))
+				break
+			}
+			result.ReleasedQueue++
+		}
+	}
+
+	_, cleaned, err := c.CleanupExpiredConcurrency(ctx)
+	result.ExpiredCleaned = cleaned
+	result.RemainingSlots, result.RemainingQueue = c.HeldConcurrency()
+
+	if result.RemainingSlots > 0 || result.RemainingQueue > 0 {
+		logger.Warn("Concurrency still held after shutdown drain",
+			zap.Int("remainingSlots", result.RemainingSlots),
+			zap.Int64("remainingQueue", result.RemainingQueue))
+	}
+	logger.Info("Drained concurrency on shutdown",
+		zap.Int("releasedSlots", result.ReleasedSlots),
+		zap.Int64("releasedQueue", result.ReleasedQueue),
+		zap.Int64("expiredCleaned", result.ExpiredCleaned))
+
+	if err != nil {
+		return result, fmt.Errorf("failed to cleanup expired concurrency: %w", err)
+	}
+	return result, nil
+}
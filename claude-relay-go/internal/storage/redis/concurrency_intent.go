@@ -0,0 +1,335 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ConcurrencyIntent 在途请求的预估成本标记
+type ConcurrencyIntent struct {
+	RequestID     string  `json:"requestId"`
+	EstimatedCost float64 `json:"estimatedCost"`
+	Model         string  `json:"model,omitempty"`
+	CreatedAt     int64   `json:"createdAt"` // 毫秒时间戳
+	ExpireAt      int64   `json:"expireAt"`  // 租约过期时间（毫秒），过期视为已放弃
+}
+
+// IntentAcquireResult 占用并发槽位并标记意图的结果
+type IntentAcquireResult struct {
+	Acquired           bool  `json:"acquired"`
+	Count              int64 `json:"count"`                        // 当前并发数（未占用时为已满的并发数）
+	GlobalLimitReached bool  `json:"globalLimitReached,omitempty"` // 因全局并发上限未占用（此时 Count 为全局并发数）
+}
+
+// IntentSettlement 意图结算结果
+type IntentSettlement struct {
+	Found         bool    `json:"found"` // 意图是否仍存在（已过期或重复结算时为 false，不记录成本）
+	EstimatedCost float64 `json:"estimatedCost"`
+	ActualCost    float64 `json:"actualCost"`
+	Delta         float64 `json:"delta"` // 实际成本 - 预估成本
+	Count         int64   `json:"count"` // 释放后的并发数
+}
+
+// PendingIntents API Key 的在途请求成本
+type PendingIntents struct {
+	APIKeyID    string              `json:"apiKeyId"`
+	Count       int                 `json:"count"`
+	PendingCost float64             `json:"pendingCost"`
+	Intents     []ConcurrencyIntent `json:"intents"`
+}
+
+const (
+	// 占用并发租约并写入成本意图（limit > 0 且已满、或 globalLimit > 0 且全局已满时不写入任何数据）
+	// 返回 {是否占用, 并发数}，全局已满时为 {-1, 全局并发数}
+	luaConcurrencyIncrIntent = luaSyncConcurrencyCount + `
+local key = KEYS[1]
+local intentKey = KEYS[2]
+local indexKey = KEYS[3]
+local countKey = KEYS[4]
+local globalKey = KEYS[5]
+local member = ARGV[1]
+local expireAt = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local intentTTL = tonumber(ARGV[5])
+local limit = tonumber(ARGV[6])
+local globalMember = ARGV[9]
+local globalLimit = tonumber(ARGV[10])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+redis.call('ZREMRANGEBYSCORE', indexKey, '-inf', now)
+
+local count = redis.call('ZCARD', key)
+if limit > 0 and not redis.call('ZSCORE', key, member) and count >= limit then
+    return {0, count}
+end
+
+if globalLimit > 0 then
+    redis.call('ZREMRANGEBYSCORE', globalKey, '-inf', now)
+    local globalCount = redis.call('ZCARD', globalKey)
+    if not redis.call('ZSCORE', globalKey, globalMember) and globalCount >= globalLimit then
+        return {-1, globalCount}
+    end
+    redis.call('ZADD', globalKey, expireAt, globalMember)
+    if ttl > 0 then
+        redis.call('PEXPIRE', globalKey, ttl)
+    end
+end
+
+redis.call('ZADD', key, expireAt, member)
+redis.call('ZADD', indexKey, expireAt, member)
+redis.call('HSET', intentKey, 'estimatedCost', ARGV[7], 'model', ARGV[8], 'createdAt', now, 'expireAt', expireAt)
+
+if ttl > 0 then
+    redis.call('PEXPIRE', key, ttl)
+    redis.call('PEXPIRE', indexKey, ttl)
+end
+redis.call('PEXPIRE', intentKey, intentTTL)
+
+return {1, syncConcurrencyCount(key, countKey)}
+`
+
+	// 结算意图：释放并发租约（含全局租约）并删除意图；意图存在时在同一脚本内记录实际成本
+	// 意图已过期或重复结算时不记录成本，保证同一 requestId 只计费一次
+	// 成本 key 与 IncrementDailyCost 一致；微美元模式下同时累加伴随计数（不存在时先以浮点值初始化）
+	// 返回 {意图是否存在, 释放后的并发数, 预估成本}
+	luaConcurrencySettleIntent = luaSyncConcurrencyCount + `
+local key = KEYS[1]
+local globalKey = KEYS[2]
+local intentKey = KEYS[3]
+local indexKey = KEYS[4]
+local countKey = KEYS[5]
+local member = ARGV[1]
+local now = tonumber(ARGV[2])
+local globalMember = ARGV[3]
+local amount = tonumber(ARGV[4])
+local micros = ARGV[5]
+local microsMode = ARGV[6] == '1'
+
+local function incrCost(costKey, microsKey, ttl)
+    if microsMode then
+        if redis.call('EXISTS', microsKey) == 0 then
+            local legacy = tonumber(redis.call('GET', costKey) or '0') or 0
+            redis.call('SET', microsKey, math.floor(legacy * 1000000 + 0.5))
+        end
+        redis.call('INCRBY', microsKey, micros)
+        if ttl > 0 then
+            redis.call('PEXPIRE', microsKey, ttl)
+        end
+    end
+    redis.call('INCRBYFLOAT', costKey, ARGV[4])
+    if ttl > 0 then
+        redis.call('PEXPIRE', costKey, ttl)
+    end
+end
+
+local estimated = redis.call('HGET', intentKey, 'estimatedCost')
+redis.call('DEL', intentKey)
+redis.call('ZREM', indexKey, member)
+redis.call('ZREM', key, member)
+redis.call('ZREM', globalKey, globalMember)
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+
+local count = syncConcurrencyCount(key, countKey)
+if count <= 0 then
+    redis.call('DEL', key)
+end
+
+if not estimated then
+    return {0, count, '0'}
+end
+
+if amount > 0 then
+    incrCost(KEYS[6], KEYS[7], tonumber(ARGV[7]))
+    incrCost(KEYS[8], KEYS[9], tonumber(ARGV[8]))
+    incrCost(KEYS[10], KEYS[11], 0)
+    if microsMode then
+        if redis.call('HEXISTS', KEYS[12], 'totalCostMicros') == 0 then
+            local legacy = tonumber(redis.call('HGET', KEYS[12], 'totalCost') or '0') or 0
+            redis.call('HSET', KEYS[12], 'totalCostMicros', math.floor(legacy * 1000000 + 0.5))
+        end
+        redis.call('HINCRBY', KEYS[12], 'totalCostMicros', micros)
+    end
+    redis.call('HINCRBYFLOAT', KEYS[12], 'totalCost', ARGV[4])
+    redis.call('PEXPIRE', KEYS[12], ARGV[9])
+end
+return {1, count, estimated}
+`
+)
+
+func concurrencyIntentKey(apiKeyID, requestID string) string {
+	return PrefixConcurrencyIntent + apiKeyID + ":" + requestID
+}
+
+// AcquireWithIntent 原子地占用并发槽位并记录在途请求的预估成本
+// limit > 0 时并发已满不占用槽位也不写入意图；globalLimit > 0 时同时占用全局并发租约，全局已满同样不写入
+// 意图随租约过期（请求被放弃）自动清理
+func (c *Client) AcquireWithIntent(ctx context.Context, apiKeyID, requestID string, leaseSeconds, limit int, globalLimit int64, estimatedCost float64, model string) (*IntentAcquireResult, error) {
+	if requestID == "" {
+		return nil, fmt.Errorf("request ID is required for concurrency tracking")
+	}
+	return c.acquireWithIntentAt(ctx, apiKeyID, requestID, leaseSeconds, limit, globalLimit, estimatedCost, model, time.Now())
+}
+
+func (c *Client) acquireWithIntentAt(ctx context.Context, apiKeyID, requestID string, leaseSeconds, limit int, globalLimit int64, estimatedCost float64, model string, now time.Time) (*IntentAcquireResult, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	config := c.getConcurrencyConfig()
+	if leaseSeconds <= 0 {
+		leaseSeconds = config.LeaseSeconds
+	}
+	if leaseSeconds < MinConcurrencyLeaseSeconds {
+		leaseSeconds = MinConcurrencyLeaseSeconds
+	}
+
+	nowMs := now.UnixMilli()
+	expireAt := nowMs + int64(leaseSeconds)*1000
+	ttl := config.keyTTLMillis(leaseSeconds)
+
+	keys := []string{
+		PrefixConcurrency + apiKeyID,
+		concurrencyIntentKey(apiKeyID, requestID),
+		PrefixConcurrencyIntentIndex + apiKeyID,
+		PrefixConcurrencyCount + apiKeyID,
+		KeyGlobalConcurrency,
+	}
+	result, err := client.Eval(ctx, luaConcurrencyIncrIntent, keys,
+		requestID, expireAt, nowMs, ttl, int64(leaseSeconds)*1000, limit,
+		strconv.FormatFloat(estimatedCost, 'f', -1, 64), model,
+		globalConcurrencyMember(apiKeyID, requestID), globalLimit).Result()
+	if err != nil {
+		logger.Error("Failed to acquire concurrency with intent", zap.Error(err))
+		return nil, fmt.Errorf("failed to acquire concurrency with intent: %w", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, fmt.Errorf("unexpected result from concurrency intent acquire: %v", result)
+	}
+	acquired, ok1 := values[0].(int64)
+	count, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("unexpected result from concurrency intent acquire: %v", result)
+	}
+
+	if acquired < 0 {
+		logger.Debug("Global concurrency limit reached",
+			zap.String("apiKeyId", apiKeyID),
+			zap.String("requestId", requestID),
+			zap.Int64("globalCount", count),
+			zap.Int64("globalLimit", globalLimit))
+		return &IntentAcquireResult{Count: count, GlobalLimitReached: true}, nil
+	}
+	if acquired == 1 {
+		c.held.trackSlot(ctx, apiKeyID, requestID)
+	}
+	return &IntentAcquireResult{Acquired: acquired == 1, Count: count}, nil
+}
+
+// CompleteIntent 请求完成时结算意图：释放并发槽位、删除意图并记录实际成本（同一 Lua 脚本内原子完成）
+// 意图已过期（被放弃后请求仍完成）或重复结算时 Found 为 false，不记录成本
+func (c *Client) CompleteIntent(ctx context.Context, apiKeyID, requestID string, actualCost float64) (*IntentSettlement, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	costKeys := newCostCounterKeys(apiKeyID, now)
+	microsMode := "0"
+	if GetCostStorageMode() == CostStorageMicros {
+		microsMode = "1"
+	}
+	keys := []string{
+		PrefixConcurrency + apiKeyID,
+		KeyGlobalConcurrency,
+		concurrencyIntentKey(apiKeyID, requestID),
+		PrefixConcurrencyIntentIndex + apiKeyID,
+		PrefixConcurrencyCount + apiKeyID,
+		costKeys.daily, costMicrosKey(costKeys.daily),
+		costKeys.monthly, costMicrosKey(costKeys.monthly),
+		costKeys.total, costMicrosKey(costKeys.total),
+		costKeys.systemMinute,
+	}
+	result, err := client.Eval(ctx, luaConcurrencySettleIntent, keys,
+		requestID, now.UnixMilli(), globalConcurrencyMember(apiKeyID, requestID),
+		actualCost, CostToMicros(actualCost), microsMode,
+		TTLUsageDaily.Milliseconds(), TTLUsageMonthly.Milliseconds(), systemMetricsTTL().Milliseconds()).Result()
+	if err != nil {
+		logger.Error("Failed to settle concurrency intent", zap.Error(err))
+		return nil, fmt.Errorf("failed to settle concurrency intent: %w", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 3 {
+		return nil, fmt.Errorf("unexpected result from concurrency intent settle: %v", result)
+	}
+	found, ok1 := values[0].(int64)
+	count, ok2 := values[1].(int64)
+	estimated, ok3 := values[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("unexpected result from concurrency intent settle: %v", result)
+	}
+	c.held.untrackSlot(apiKeyID, requestID)
+
+	settlement := &IntentSettlement{
+		Found:      found == 1,
+		ActualCost: actualCost,
+		Count:      count,
+	}
+	settlement.EstimatedCost, _ = strconv.ParseFloat(estimated, 64)
+	settlement.Delta = actualCost - settlement.EstimatedCost
+	return settlement, nil
+}
+
+// GetPendingIntents 获取 API Key 的在途请求及预估成本合计（不含已过期的意图）
+func (c *Client) GetPendingIntents(ctx context.Context, apiKeyID string) (*PendingIntents, error) {
+	return c.getPendingIntentsAt(ctx, apiKeyID, time.Now())
+}
+
+func (c *Client) getPendingIntentsAt(ctx context.Context, apiKeyID string, now time.Time) (*PendingIntents, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	indexKey := PrefixConcurrencyIntentIndex + apiKeyID
+	requestIDs, err := client.ZRangeByScore(ctx, indexKey, &goredis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", now.UnixMilli()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending intents: %w", err)
+	}
+
+	pending := &PendingIntents{APIKeyID: apiKeyID, Intents: make([]ConcurrencyIntent, 0, len(requestIDs))}
+	for _, requestID := range requestIDs {
+		data, err := client.HGetAll(ctx, concurrencyIntentKey(apiKeyID, requestID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get intent %s: %w", requestID, err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		intent := ConcurrencyIntent{
+			RequestID: requestID,
+			Model:     data["model"],
+			CreatedAt: parseInt64(data["createdAt"]),
+			ExpireAt:  parseInt64(data["expireAt"]),
+		}
+		intent.EstimatedCost, _ = strconv.ParseFloat(data["estimatedCost"], 64)
+		pending.Intents = append(pending.Intents, intent)
+		pending.PendingCost += intent.EstimatedCost
+	}
+	pending.Count = len(pending.Intents)
+	return pending, nil
+}
@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAcquireWithIntent_RecordsPendingCost(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for i, reqID := range []string{"req-1", "req-2"} {
+		result, err := c.AcquireWithIntent(ctx, "key-1", reqID, 60, 2, 0, 0.25, "claude-sonnet-4")
+		if err != nil {
+			t.Fatalf("AcquireWithIntent(%s) error = %v", reqID, err)
+		}
+		if !result.Acquired || result.Count != int64(i+1) {
+			t.Fatalf("AcquireWithIntent(%s) = %+v, want acquired with count %d", reqID, result, i+1)
+		}
+	}
+
+	// 并发已满时既不占用槽位也不写入意图
+	result, err := c.AcquireWithIntent(ctx, "key-1", "req-3", 60, 2, 0, 1, "")
+	if err != nil {
+		t.Fatalf("AcquireWithIntent(req-3) error = %v", err)
+	}
+	if result.Acquired || result.Count != 2 {
+		t.Fatalf("AcquireWithIntent(req-3) = %+v, want rejected with count 2", result)
+	}
+	if _, ok := hook.hashes[concurrencyIntentKey("key-1", "req-3")]; ok {
+		t.Error("rejected request should not record an intent")
+	}
+
+	pending, err := c.GetPendingIntents(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetPendingIntents() error = %v", err)
+	}
+	if pending.Count != 2 || math.Abs(pending.PendingCost-0.5) > 1e-9 {
+		t.Errorf("pending = count %d cost %v, want 2 and 0.5", pending.Count, pending.PendingCost)
+	}
+	if pending.Intents[0].Model != "claude-sonnet-4" {
+		t.Errorf("intent model = %q, want claude-sonnet-4", pending.Intents[0].Model)
+	}
+	if ttl := hook.ttls[concurrencyIntentKey("key-1", "req-1")]; ttl != 60*time.Second {
+		t.Errorf("intent TTL = %v, want lease duration 60s", ttl)
+	}
+}
+
+func TestCompleteIntent_ReconcilesActualCost(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if _, err := c.AcquireWithIntent(ctx, "key-1", "req-1", 60, 0, 0, 0.3, ""); err != nil {
+		t.Fatalf("AcquireWithIntent() error = %v", err)
+	}
+
+	settlement, err := c.CompleteIntent(ctx, "key-1", "req-1", 0.42)
+	if err != nil {
+		t.Fatalf("CompleteIntent() error = %v", err)
+	}
+	if !settlement.Found || settlement.Count != 0 {
+		t.Fatalf("settlement = %+v, want found with count 0", settlement)
+	}
+	if settlement.EstimatedCost != 0.3 || math.Abs(settlement.Delta-0.12) > 1e-9 {
+		t.Errorf("settlement estimated %v delta %v, want 0.3 and 0.12", settlement.EstimatedCost, settlement.Delta)
+	}
+
+	pending, err := c.GetPendingIntents(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetPendingIntents() error = %v", err)
+	}
+	if pending.Count != 0 || pending.PendingCost != 0 {
+		t.Errorf("pending after completion = %+v, want empty", pending)
+	}
+
+	cost, err := c.GetDailyCost(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetDailyCost() error = %v", err)
+	}
+	if math.Abs(cost-0.42) > 1e-9 {
+		t.Errorf("daily cost = %v, want actual cost 0.42", cost)
+	}
+
+	// 重复结算不再找到意图，也不重复记录成本
+	again, err := c.CompleteIntent(ctx, "key-1", "req-1", 0.42)
+	if err != nil {
+		t.Fatalf("CompleteIntent() again error = %v", err)
+	}
+	if again.Found {
+		t.Error("second completion should not find the intent")
+	}
+	if cost, _ := c.GetDailyCost(ctx, "key-1"); math.Abs(cost-0.42) > 1e-9 {
+		t.Errorf("daily cost after repeated completion = %v, want 0.42", cost)
+	}
+
+	// 从未登记（或已过期）的意图不记录成本
+	if _, err := c.CompleteIntent(ctx, "key-1", "unknown", 1); err != nil {
+		t.Fatalf("CompleteIntent(unknown) error = %v", err)
+	}
+	if cost, _ := c.GetDailyCost(ctx, "key-1"); math.Abs(cost-0.42) > 1e-9 {
+		t.Errorf("daily cost after unknown completion = %v, want 0.42", cost)
+	}
+}
+
+func TestAcquireWithIntent_RespectsGlobalLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if result, err := c.AcquireWithIntent(ctx, "key-1", "req-1", 60, 0, 1, 0.1, ""); err != nil || !result.Acquired {
+		t.Fatalf("AcquireWithIntent(key-1) = %+v, %v, want acquired", result, err)
+	}
+
+	result, err := c.AcquireWithIntent(ctx, "key-2", "req-2", 60, 0, 1, 0.1, "")
+	if err != nil {
+		t.Fatalf("AcquireWithIntent(key-2) error = %v", err)
+	}
+	if result.Acquired || !result.GlobalLimitReached || result.Count != 1 {
+		t.Fatalf("AcquireWithIntent(key-2) = %+v, want rejected by global limit", result)
+	}
+	if _, ok := hook.hashes[concurrencyIntentKey("key-2", "req-2")]; ok {
+		t.Error("globally rejected request should not record an intent")
+	}
+
+	// 结算后释放全局租约
+	if _, err := c.CompleteIntent(ctx, "key-1", "req-1", 0); err != nil {
+		t.Fatalf("CompleteIntent() error = %v", err)
+	}
+	if result, err := c.AcquireWithIntent(ctx, "key-2", "req-2", 60, 0, 1, 0.1, ""); err != nil || !result.Acquired {
+		t.Fatalf("AcquireWithIntent(key-2) after release = %+v, %v, want acquired", result, err)
+	}
+}
+
+func TestPendingIntents_AbandonedIntentExpiresWithLease(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+
+	// 租约已过期的请求（被放弃，未结算）
+	if _, err := c.acquireWithIntentAt(ctx, "key-1", "stale", 60, 1, 0, 5, "", now.Add(-2*time.Minute)); err != nil {
+		t.Fatalf("acquireWithIntentAt(stale) error = %v", err)
+	}
+
+	pending, err := c.getPendingIntentsAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getPendingIntentsAt() error = %v", err)
+	}
+	if pending.Count != 0 || pending.PendingCost != 0 {
+		t.Errorf("abandoned intent should not count as pending, got %+v", pending)
+	}
+
+	// 过期租约不再占用槽位，新请求可以占用
+	result, err := c.acquireWithIntentAt(ctx, "key-1", "fresh", 60, 1, 0, 1, "", now)
+	if err != nil {
+		t.Fatalf("acquireWithIntentAt(fresh) error = %v", err)
+	}
+	if !result.Acquired || result.Count != 1 {
+		t.Errorf("fresh acquire = %+v, want acquired with count 1", result)
+	}
+	if _, ok := hook.zsets[PrefixConcurrencyIntentIndex+"key-1"]["stale"]; ok {
+		t.Error("expired intent should be pruned from the index")
+	}
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// concurrencyMetaSeparator 并发元数据字段分隔符（model|clientType|startMs）
+const concurrencyMetaSeparator = "|"
+
+// ConcurrencyMemberMeta 并发租约附带的请求元数据
+// 有序集合成员始终为原始请求 ID（与 Node 端一致），元数据写入旁路哈希 concurrency_meta:{keyId}
+type ConcurrencyMemberMeta struct {
+	Model      string
+	ClientType string
+	StartedAt  time.Time
+}
+
+type concurrencyMetaContextKey struct{}
+
+// ConcurrencyMemberMetadataEnabled 是否为并发租约记录请求元数据
+func ConcurrencyMemberMetadataEnabled() bool {
+	return config.Cfg != nil && config.Cfg.System.ConcurrencyMemberMetadata
+}
+
+// WithConcurrencyMeta 在 context 中附带请求元数据，领取并发租约时一并写入旁路哈希
+func WithConcurrencyMeta(ctx context.Context, meta ConcurrencyMemberMeta) context.Context {
+	return context.WithValue(ctx, concurrencyMetaContextKey{}, meta)
+}
+
+// concurrencyMetaFromContext 读取 context 中的请求元数据并编码（未启用或未设置时返回空字符串）
+func concurrencyMetaFromContext(ctx context.Context) string {
+	if !ConcurrencyMemberMetadataEnabled() {
+		return ""
+	}
+	meta, ok := ctx.Value(concurrencyMetaContextKey{}).(ConcurrencyMemberMeta)
+	if !ok {
+		return ""
+	}
+	return encodeConcurrencyMeta(meta)
+}
+
+// encodeConcurrencyMeta 编码请求元数据（元数据为空时返回空字符串）
+func encodeConcurrencyMeta(meta ConcurrencyMemberMeta) string {
+	if meta.Model == "" && meta.ClientType == "" && meta.StartedAt.IsZero() {
+		return ""
+	}
+
+	var startMs string
+	if !meta.StartedAt.IsZero() {
+		startMs = strconv.FormatInt(meta.StartedAt.UnixMilli(), 10)
+	}
+	clean := func(s string) string {
+		return strings.ReplaceAll(s, concurrencyMetaSeparator, "")
+	}
+	return strings.Join([]string{clean(meta.Model), clean(meta.ClientType), startMs}, concurrencyMetaSeparator)
+}
+
+// newActiveRequest 根据并发成员与旁路元数据构造活跃请求（元数据缺失时仅包含请求 ID）
+func newActiveRequest(member, meta string, expireAt, now int64) ActiveRequest {
+	request := ActiveRequest{
+		RequestID:        member,
+		ExpireAt:         time.UnixMilli(expireAt).Format(time.RFC3339),
+		RemainingSeconds: (expireAt - now) / 1000,
+	}
+
+	parts := strings.Split(meta, concurrencyMetaSeparator)
+	if len(parts) != 3 {
+		return request
+	}
+	request.Model = parts[0]
+	request.ClientType = parts[1]
+	if startMs, err := strconv.ParseInt(parts[2], 10, 64); err == nil && startMs > 0 {
+		request.StartedAt = time.UnixMilli(startMs).Format(time.RFC3339)
+	}
+	return request
+}
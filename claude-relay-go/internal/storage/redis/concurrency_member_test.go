@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEncodeConcurrencyMeta(t *testing.T) {
+	startedAt := time.UnixMilli(1735689600000)
+
+	if got := encodeConcurrencyMeta(ConcurrencyMemberMeta{}); got != "" {
+		t.Errorf("empty metadata encoded as %q, want empty", got)
+	}
+
+	got := encodeConcurrencyMeta(ConcurrencyMemberMeta{Model: "claude|opus", ClientType: "claude_code", StartedAt: startedAt})
+	if want := "claudeopus|claude_code|1735689600000"; got != want {
+		t.Errorf("encodeConcurrencyMeta() = %q, want %q", got, want)
+	}
+}
+
+func TestGetConcurrencyStatus_ReadsMetadataFromSideHash(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	expireAt := float64(time.Now().Add(5 * time.Minute).UnixMilli())
+	startedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	hook.zsets[PrefixConcurrency+"key-1"] = map[string]float64{
+		"req-plain": expireAt,
+		"req-meta":  expireAt + 1,
+	}
+	hook.hashes[PrefixConcurrencyMeta+"key-1"] = map[string]string{
+		"req-meta": encodeConcurrencyMeta(ConcurrencyMemberMeta{Model: "claude-sonnet-4", ClientType: "claude_code", StartedAt: startedAt}),
+	}
+
+	status, err := c.GetConcurrencyStatus(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetConcurrencyStatus() error = %v", err)
+	}
+	if status.ActiveCount != 2 || len(status.ActiveRequests) != 2 {
+		t.Fatalf("status = %+v, want 2 active requests", status)
+	}
+
+	plain, meta := status.ActiveRequests[0], status.ActiveRequests[1]
+	if plain.RequestID != "req-plain" || plain.Model != "" || plain.StartedAt != "" {
+		t.Errorf("plain member parsed as %+v", plain)
+	}
+	if meta.RequestID != "req-meta" {
+		t.Errorf("metadata member id = %q, want bare request id", meta.RequestID)
+	}
+	if meta.Model != "claude-sonnet-4" || meta.ClientType != "claude_code" {
+		t.Errorf("metadata member model = %q, clientType = %q", meta.Model, meta.ClientType)
+	}
+	if meta.StartedAt != startedAt.Format(time.RFC3339) {
+		t.Errorf("StartedAt = %q, want %q", meta.StartedAt, startedAt.Format(time.RFC3339))
+	}
+	if meta.RemainingSeconds <= 0 {
+		t.Errorf("RemainingSeconds = %d, want positive", meta.RemainingSeconds)
+	}
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// MaxLeaseRefreshBatch 单次批量续约的最大条目数
+const MaxLeaseRefreshBatch = 1000
+
+// LeaseRefreshItem 批量续约的租约条目（LeaseSeconds 为 0 时使用默认租约时长）
+type LeaseRefreshItem struct {
+	APIKeyID     string `json:"apiKeyId"`
+	RequestID    string `json:"requestId"`
+	LeaseSeconds int    `json:"leaseSeconds,omitempty"`
+}
+
+// LeaseRefreshResult 单个租约的续约结果，Refreshed 为 false 表示租约已不存在
+type LeaseRefreshResult struct {
+	APIKeyID  string `json:"apiKeyId"`
+	RequestID string `json:"requestId"`
+	Refreshed bool   `json:"refreshed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RefreshLeasesBatch 在一个管道中批量续约并发租约（Redis 恢复后重新声明本实例持有的租约）
+// 单条失败记录在结果中；全部失败时返回错误
+func (c *Client) RefreshLeasesBatch(ctx context.Context, items []LeaseRefreshItem) ([]LeaseRefreshResult, error) {
+	if len(items) == 0 {
+		return []LeaseRefreshResult{}, nil
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	config := c.getConcurrencyConfig()
+	now := time.Now().UnixMilli()
+
+	results := make([]LeaseRefreshResult, len(items))
+	cmds := make([]*goredis.Cmd, len(items))
+	pipe := client.Pipeline()
+	for i, item := range items {
+		results[i] = LeaseRefreshResult{APIKeyID: item.APIKeyID, RequestID: item.RequestID}
+		if item.APIKeyID == "" || item.RequestID == "" {
+			continue
+		}
+
+		leaseSeconds := item.LeaseSeconds
+		if leaseSeconds <= 0 {
+			leaseSeconds = config.LeaseSeconds
+		}
+		expireAt := now + int64(leaseSeconds)*1000
+		ttl := config.keyTTLMillis(leaseSeconds)
+
+		cmds[i] = pipe.Eval(ctx, luaConcurrencyRefresh,
+			[]string{PrefixConcurrency + item.APIKeyID, KeyGlobalConcurrency, PrefixConcurrencyMeta + item.APIKeyID},
+			item.RequestID, expireAt, now, ttl, globalConcurrencyMember(item.APIKeyID, item.RequestID))
+	}
+
+	queued := pipe.Len()
+	if queued == 0 {
+		return results, nil
+	}
+	_, execErr := pipe.Exec(ctx)
+
+	var refreshed, failed int
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		val, err := cmd.Result()
+		if err != nil {
+			results[i].Error = err.Error()
+			failed++
+			continue
+		}
+		resultInt, ok := val.(int64)
+		if !ok {
+			results[i].Error = fmt.Sprintf("unexpected result type from concurrency refresh: %T", val)
+			failed++
+			continue
+		}
+		results[i].Refreshed = resultInt == 1
+		if results[i].Refreshed {
+			refreshed++
+		}
+	}
+
+	if failed == queued && execErr != nil {
+		logger.Error("Failed to refresh concurrency leases in batch", zap.Error(execErr))
+		return results, fmt.Errorf("failed to refresh concurrency leases: %w", execErr)
+	}
+
+	logger.Info("Refreshed concurrency leases in batch",
+		zap.Int("requested", len(items)),
+		zap.Int("refreshed", refreshed),
+		zap.Int("failed", failed))
+	return results, nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshLeasesBatch_ReportsPresentAndAbsentLeases(t *testing.T) {
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if _, _, acquired, err := c.IncrConcurrencyWithGlobal(ctx, "key-a", "req-1", 60, 0); err != nil || !acquired {
+		t.Fatalf("acquire req-1 = %v, %v; want acquired", acquired, err)
+	}
+	if _, err := c.IncrConcurrency(ctx, "key-b", "req-2", 60); err != nil {
+		t.Fatalf("IncrConcurrency(req-2) error = %v", err)
+	}
+
+	results, err := c.RefreshLeasesBatch(ctx, []LeaseRefreshItem{
+		{APIKeyID: "key-a", RequestID: "req-1", LeaseSeconds: 600},
+		{APIKeyID: "key-b", RequestID: "req-2", LeaseSeconds: 600},
+		{APIKeyID: "key-a", RequestID: "req-gone", LeaseSeconds: 600},
+		{APIKeyID: "key-c", RequestID: ""},
+	})
+	if err != nil {
+		t.Fatalf("RefreshLeasesBatch() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("len(results) = %d, want 4", len(results))
+	}
+
+	want := []bool{true, true, false, false}
+	for i, result := range results {
+		if result.Refreshed != want[i] {
+			t.Errorf("results[%d] (%s/%s) refreshed = %v, want %v",
+				i, result.APIKeyID, result.RequestID, result.Refreshed, want[i])
+		}
+		if result.Error != "" {
+			t.Errorf("results[%d] error = %q, want none", i, result.Error)
+		}
+	}
+
+	// 续约后到期时间应延长到新的租约时长
+	minExpiry := time.Now().Add(500 * time.Second).UnixMilli()
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if exp := hook.zsets[PrefixConcurrency+"key-a"]["req-1"]; exp < minExpiry {
+		t.Errorf("key-a lease expiry = %d, want >= %d", exp, minExpiry)
+	}
+	if exp := hook.zsets[KeyGlobalConcurrency][globalConcurrencyMember("key-a", "req-1")]; exp < minExpiry {
+		t.Errorf("global lease expiry = %d, want >= %d", exp, minExpiry)
+	}
+	if _, ok := hook.zsets[PrefixConcurrency+"key-a"]["req-gone"]; ok {
+		t.Error("absent lease should not be recreated by refresh")
+	}
+}
+
+func TestRefreshLeasesBatch_EmptyInput(t *testing.T) {
+	c := newConnectedClientForTest(t, newConcurrencyRedisHook())
+
+	results, err := c.RefreshLeasesBatch(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RefreshLeasesBatch(nil) error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("len(results) = %d, want 0", len(results))
+	}
+}
@@ -0,0 +1,399 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// concurrencyRedisHook 模拟并发租约脚本（有序集合，成员分数为过期时间）
+type concurrencyRedisHook struct {
+	mu       sync.Mutex
+	zsets    map[string]map[string]int64
+	counters map[string]int64 // 排队计数器
+	counts   map[string]int64 // 并发数计数器
+
+	failReconcile map[string]bool // 校准脚本对这些有序集合返回错误
+}
+
+func newConcurrencyRedisHook() *concurrencyRedisHook {
+	return &concurrencyRedisHook{
+		zsets:    make(map[string]map[string]int64),
+		counters: make(map[string]int64),
+		counts:   make(map[string]int64),
+	}
+}
+
+func (h *concurrencyRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessPipelineHook 逐条执行管道命令（复用单命令模拟）
+func (h *concurrencyRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		process := h.ProcessHook(nil)
+		var firstErr error
+		for _, cmd := range cmds {
+			if err := process(ctx, cmd); err != nil {
+				cmd.SetErr(err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		return firstErr
+	}
+}
+
+// prune 清理过期成员，返回清理数量（调用方需持有锁）
+func (h *concurrencyRedisHook) prune(key string, now int64) int64 {
+	var removed int64
+	for member, expireAt := range h.zsets[key] {
+		if expireAt <= now {
+			delete(h.zsets[key], member)
+			removed++
+		}
+	}
+	return removed
+}
+
+// add 写入成员（调用方需持有锁）
+func (h *concurrencyRedisHook) add(key, member string, expireAt int64) {
+	if h.zsets[key] == nil {
+		h.zsets[key] = make(map[string]int64)
+	}
+	h.zsets[key][member] = expireAt
+}
+
+// syncCount 按有序集合重写并发数计数器，并发为 0 时删除（调用方需持有锁）
+func (h *concurrencyRedisHook) syncCount(key, countKey string) int64 {
+	count := int64(len(h.zsets[key]))
+	if count == 0 {
+		delete(h.counts, countKey)
+	} else if countKey != "" {
+		h.counts[countKey] = count
+	}
+	return count
+}
+
+func (h *concurrencyRedisHook) count(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.zsets[key])
+}
+
+func (h *concurrencyRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		args := cmd.Args()
+		argString := func(i int) string { return fmt.Sprint(args[i]) }
+		argInt := func(i int) int64 {
+			v, _ := strconv.ParseInt(argString(i), 10, 64)
+			return v
+		}
+
+		switch strings.ToLower(cmd.Name()) {
+		case "eval":
+			// KEYS / ARGV 按下标取值（下标从 1 开始，与 Lua 一致）
+			numKeys := int(argInt(2))
+			keyAt := func(i int) string {
+				if i > numKeys {
+					return ""
+				}
+				return argString(2 + i)
+			}
+			argvString := func(i int) string { return argString(2 + numKeys + i) }
+			argvInt := func(i int) int64 { return argInt(2 + numKeys + i) }
+			key := keyAt(1)
+			switch argString(1) {
+			case luaConcurrencyIncr:
+				member, expireAt, now := argvString(1), argvInt(2), argvInt(3)
+				h.prune(key, now)
+				h.add(key, member, expireAt)
+				cmd.(*redis.Cmd).SetVal(h.syncCount(key, keyAt(2)))
+			case luaQueueIncr:
+				h.counters[key]++
+				cmd.(*redis.Cmd).SetVal(h.counters[key])
+			case luaQueueDecr:
+				h.counters[key]--
+				if h.counters[key] <= 0 {
+					delete(h.counters, key)
+				}
+				cmd.(*redis.Cmd).SetVal(h.counters[key])
+			case luaConcurrencyIncrGlobal:
+				globalKey := keyAt(2)
+				member, expireAt, now := argvString(1), argvInt(2), argvInt(3)
+				globalMember, globalLimit := argvString(5), argvInt(6)
+				h.prune(key, now)
+				h.prune(globalKey, now)
+				_, held := h.zsets[globalKey][globalMember]
+				globalCount := int64(len(h.zsets[globalKey]))
+				if globalLimit > 0 && !held && globalCount >= globalLimit {
+					cmd.(*redis.Cmd).SetVal([]interface{}{int64(-1), globalCount})
+					return nil
+				}
+				h.add(key, member, expireAt)
+				h.add(globalKey, globalMember, expireAt)
+				cmd.(*redis.Cmd).SetVal([]interface{}{h.syncCount(key, keyAt(3)), int64(len(h.zsets[globalKey]))})
+			case luaConcurrencyDecr:
+				globalKey := keyAt(2)
+				member, now, globalMember := argvString(1), argvInt(2), argvString(3)
+				var removed int64
+				if _, ok := h.zsets[key][member]; ok {
+					removed = 1
+				}
+				delete(h.zsets[key], member)
+				delete(h.zsets[globalKey], globalMember)
+				h.prune(key, now)
+				cmd.(*redis.Cmd).SetVal([]interface{}{h.syncCount(key, keyAt(3)), removed})
+			case luaConcurrencyRefresh:
+				globalKey := keyAt(2)
+				member, expireAt, now, globalMember := argvString(1), argvInt(2), argvInt(3), argvString(5)
+				h.prune(key, now)
+				if _, ok := h.zsets[key][member]; !ok {
+					cmd.(*redis.Cmd).SetVal(int64(0))
+					return nil
+				}
+				h.add(key, member, expireAt)
+				if _, ok := h.zsets[globalKey][globalMember]; ok {
+					h.add(globalKey, globalMember, expireAt)
+				}
+				cmd.(*redis.Cmd).SetVal(int64(1))
+			case luaConcurrencyReconcile:
+				if h.failReconcile[key] {
+					return errors.New("reconcile failed")
+				}
+				countKey := keyAt(2)
+				before := h.counts[countKey]
+				h.prune(key, argvInt(1))
+				cmd.(*redis.Cmd).SetVal([]interface{}{before, h.syncCount(key, countKey)})
+			default:
+				return errors.New("unexpected script")
+			}
+		case "zremrangebyscore":
+			cmd.(*redis.IntCmd).SetVal(h.prune(argString(1), argInt(3)))
+		case "zcard":
+			cmd.(*redis.IntCmd).SetVal(int64(len(h.zsets[argString(1)])))
+		case "get":
+			count, ok := h.counts[argString(1)]
+			if !ok {
+				return redis.Nil
+			}
+			cmd.(*redis.StringCmd).SetVal(strconv.FormatInt(count, 10))
+		case "del":
+			var deleted int64
+			for i := 1; i < len(args); i++ {
+				if _, ok := h.zsets[argString(i)]; ok {
+					delete(h.zsets, argString(i))
+					deleted++
+				}
+				if _, ok := h.counts[argString(i)]; ok {
+					delete(h.counts, argString(i))
+					deleted++
+				}
+			}
+			cmd.(*redis.IntCmd).SetVal(deleted)
+		case "scan":
+			// 仅支持前缀匹配，单批返回
+			prefix := strings.TrimSuffix(argString(3), "*")
+			var keys []string
+			for key := range h.zsets {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			for key := range h.counts {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			cmd.(*redis.ScanCmd).SetVal(keys, 0)
+		default:
+			return errors.New("unexpected command: " + cmd.Name())
+		}
+		return nil
+	}
+}
+
+func TestIncrConcurrencyWithGlobal_RejectsBeyondCeiling(t *testing.T) {
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	// 每个 Key 只有 1 个并发，远低于单 Key 上限，但全局上限为 2
+	for _, keyID := range []string{"key-a", "key-b"} {
+		count, _, acquired, err := c.IncrConcurrencyWithGlobal(ctx, keyID, "req-"+keyID, 60, 2)
+		if err != nil {
+			t.Fatalf("IncrConcurrencyWithGlobal(%s) error = %v", keyID, err)
+		}
+		if !acquired || count != 1 {
+			t.Fatalf("IncrConcurrencyWithGlobal(%s) = count %d, acquired %v; want 1, true", keyID, count, acquired)
+		}
+	}
+
+	_, globalCount, acquired, err := c.IncrConcurrencyWithGlobal(ctx, "key-c", "req-key-c", 60, 2)
+	if err != nil {
+		t.Fatalf("IncrConcurrencyWithGlobal(key-c) error = %v", err)
+	}
+	if acquired {
+		t.Fatal("expected key-c to be rejected by the global ceiling")
+	}
+	if globalCount != 2 {
+		t.Errorf("globalCount = %d, want 2", globalCount)
+	}
+	if n := hook.count(PrefixConcurrency + "key-c"); n != 0 {
+		t.Errorf("rejected request should not hold a per-key slot, got %d", n)
+	}
+
+	total, err := c.GetGlobalConcurrency(ctx)
+	if err != nil {
+		t.Fatalf("GetGlobalConcurrency() error = %v", err)
+	}
+	if total != 2 {
+		t.Errorf("GetGlobalConcurrency() = %d, want 2", total)
+	}
+}
+
+func TestDecrConcurrency_ReleasesGlobalSlot(t *testing.T) {
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if _, _, acquired, err := c.IncrConcurrencyWithGlobal(ctx, "key-a", "req-1", 60, 1); err != nil || !acquired {
+		t.Fatalf("first acquire = %v, %v; want acquired", acquired, err)
+	}
+	if _, _, acquired, _ := c.IncrConcurrencyWithGlobal(ctx, "key-b", "req-2", 60, 1); acquired {
+		t.Fatal("expected second key to be rejected while the global slot is held")
+	}
+
+	if _, err := c.DecrConcurrency(ctx, "key-a", "req-1"); err != nil {
+		t.Fatalf("DecrConcurrency() error = %v", err)
+	}
+	if n := hook.count(KeyGlobalConcurrency); n != 0 {
+		t.Fatalf("global slots after release = %d, want 0", n)
+	}
+
+	if _, _, acquired, err := c.IncrConcurrencyWithGlobal(ctx, "key-b", "req-2", 60, 1); err != nil || !acquired {
+		t.Fatalf("acquire after release = %v, %v; want acquired", acquired, err)
+	}
+}
+
+func TestReleaseConcurrency_SecondReleaseIsNoOp(t *testing.T) {
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for _, reqID := range []string{"req-1", "req-2"} {
+		if _, err := c.IncrConcurrency(ctx, "key-a", reqID, 60); err != nil {
+			t.Fatalf("IncrConcurrency(%s) error = %v", reqID, err)
+		}
+	}
+
+	count, released, err := c.ReleaseConcurrency(ctx, "key-a", "req-1")
+	if err != nil {
+		t.Fatalf("first ReleaseConcurrency() error = %v", err)
+	}
+	if !released || count != 1 {
+		t.Fatalf("first release = count %d, released %v; want 1, true", count, released)
+	}
+
+	// 重复释放（如 defer 与断连处理各释放一次）不影响其他请求的租约
+	count, released, err = c.ReleaseConcurrency(ctx, "key-a", "req-1")
+	if err != nil {
+		t.Fatalf("second ReleaseConcurrency() error = %v", err)
+	}
+	if released || count != 1 {
+		t.Fatalf("second release = count %d, released %v; want 1, false", count, released)
+	}
+	if n := hook.count(PrefixConcurrency + "key-a"); n != 1 {
+		t.Errorf("remaining slots = %d, want 1", n)
+	}
+}
+
+func TestDrainConcurrency_ReleasesOnlyThisInstanceSlots(t *testing.T) {
+	hook := newConcurrencyRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := WithHeldConcurrency(context.Background())
+
+	if _, err := c.IncrConcurrency(ctx, "key-a", "req-1", 60); err != nil {
+		t.Fatalf("IncrConcurrency() error = %v", err)
+	}
+	// 代理 Node 的占用（未标记上下文）不跟踪，关闭时不释放
+	if _, err := c.IncrConcurrency(context.Background(), "key-a", "proxied", 60); err != nil {
+		t.Fatalf("IncrConcurrency() error = %v", err)
+	}
+	if _, err := c.IncrConcurrencyQueue(context.Background(), "key-a", 1000); err != nil {
+		t.Fatalf("IncrConcurrencyQueue() error = %v", err)
+	}
+	if _, err := c.IncrConcurrency(ctx, "key-a", "req-2", 60); err != nil {
+		t.Fatalf("IncrConcurrency() error = %v", err)
+	}
+	if _, _, acquired, err := c.IncrConcurrencyWithGlobal(ctx, "key-b", "req-3", 60, 10); err != nil || !acquired {
+		t.Fatalf("IncrConcurrencyWithGlobal() = %v, %v; want acquired", acquired, err)
+	}
+	if _, err := c.IncrConcurrencyQueue(ctx, "key-a", 1000); err != nil {
+		t.Fatalf("IncrConcurrencyQueue() error = %v", err)
+	}
+
+	// 正常释放的租约不再计入
+	if _, err := c.DecrConcurrency(ctx, "key-a", "req-2"); err != nil {
+		t.Fatalf("DecrConcurrency() error = %v", err)
+	}
+	if slots, queued := c.HeldConcurrency(); slots != 2 || queued != 1 {
+		t.Fatalf("HeldConcurrency() = %d, %d; want 2, 1", slots, queued)
+	}
+
+	// 其他实例持有的租约与已过期的孤儿租约
+	hook.mu.Lock()
+	hook.add(PrefixConcurrency+"key-a", "other-instance", time.Now().Add(time.Minute).UnixMilli())
+	hook.add(PrefixConcurrency+"key-c", "expired", time.Now().Add(-time.Minute).UnixMilli())
+	hook.mu.Unlock()
+
+	result, err := c.DrainConcurrency(ctx)
+	if err != nil {
+		t.Fatalf("DrainConcurrency() error = %v", err)
+	}
+	if result.ReleasedSlots != 2 || result.ReleasedQueue != 1 || result.RemainingSlots != 0 || result.RemainingQueue != 0 {
+		t.Errorf("DrainConcurrency() = %+v, want 2 slots and 1 queue released, none remaining", result)
+	}
+	if result.ExpiredCleaned != 1 {
+		t.Errorf("ExpiredCleaned = %d, want 1", result.ExpiredCleaned)
+	}
+
+	if n := hook.count(PrefixConcurrency + "key-a"); n != 2 {
+		t.Errorf("key-a slots after drain = %d, want 2 (other instance and proxied)", n)
+	}
+	if n := hook.count(PrefixConcurrency + "key-b"); n != 0 {
+		t.Errorf("key-b slots after drain = %d, want 0", n)
+	}
+	if n := hook.count(KeyGlobalConcurrency); n != 0 {
+		t.Errorf("global slots after drain = %d, want 0", n)
+	}
+	hook.mu.Lock()
+	queued := hook.counters[PrefixConcurrencyQueue+"key-a"]
+	hook.mu.Unlock()
+	if queued != 1 {
+		t.Errorf("queue count after drain = %d, want 1 (proxied)", queued)
+	}
+}
+
+func TestConcurrencyConfig_KeyTTLMillis(t *testing.T) {
+	cfg := ConcurrencyConfig{LeaseSeconds: DefaultConcurrencyLeaseSeconds, CleanupGraceSeconds: 10}
+
+	if got := cfg.keyTTLMillis(300); got != 310000 {
+		t.Errorf("keyTTLMillis(300) = %d, want 310000", got)
+	}
+	// 不足 60 秒时取最小值
+	if got := cfg.keyTTLMillis(30); got != minConcurrencyKeyTTLMillis {
+		t.Errorf("keyTTLMillis(30) = %d, want %d", got, minConcurrencyKeyTTLMillis)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -17,8 +16,19 @@ type CostStats struct {
 	OutputCost   float64 `json:"outputCost"`
 	CacheCost    float64 `json:"cacheCost"`
 	RequestCount int64   `json:"requestCount"`
+	Currency     string  `json:"currency,omitempty"`
+	// Formatted 按请求 locale 格式化的展示值（数值字段仍为准）
+	Formatted *FormattedCostStats `json:"formatted,omitempty"`
 }
 
+// 成本历史查询天数
+const (
+	// DefaultCostHistoryDays 默认查询天数
+	DefaultCostHistoryDays = 30
+	// MaxCostHistoryDays 最大查询天数
+	MaxCostHistoryDays = 365
+)
+
 // DailyCostRecord 每日成本记录
 type DailyCostRecord struct {
 	Date         string  `json:"date"`
@@ -29,6 +39,24 @@ type DailyCostRecord struct {
 	RequestCount int64   `json:"requestCount"`
 }
 
+// costCounterKeys 一次成本累加涉及的计数 key
+type costCounterKeys struct {
+	daily        string
+	monthly      string
+	total        string
+	systemMinute string
+}
+
+// newCostCounterKeys 生成 API Key 在指定时间的成本计数 key
+func newCostCounterKeys(keyID string, now time.Time) costCounterKeys {
+	return costCounterKeys{
+		daily:        fmt.Sprintf("usage:cost:daily:%s:%s", keyID, getDateStringInTimezone(now)),
+		monthly:      fmt.Sprintf("usage:cost:monthly:%s:%s", keyID, getMonthStringInTimezone(now)),
+		total:        fmt.Sprintf("usage:cost:total:%s", keyID),
+		systemMinute: fmt.Sprintf("%s%d", PrefixSystemMetrics, getMinuteTimestamp(now)),
+	}
+}
+
 // IncrementDailyCost 增加每日成本
 func (c *Client) IncrementDailyCost(ctx context.Context, keyID string, amount float64) error {
 	client, err := c.GetClientSafe()
@@ -36,25 +64,22 @@ func (c *Client) IncrementDailyCost(ctx context.Context, keyID string, amount fl
 		return err
 	}
 
-	now := time.Now()
-	dateStr := getDateStringInTimezone(now)
-	monthStr := getMonthStringInTimezone(now)
+	keys := newCostCounterKeys(keyID, time.Now())
 
 	pipe := client.Pipeline()
 
 	// 每日成本
-	dailyCostKey := fmt.Sprintf("usage:cost:daily:%s:%s", keyID, dateStr)
-	pipe.IncrByFloat(ctx, dailyCostKey, amount)
-	pipe.Expire(ctx, dailyCostKey, TTLUsageDaily)
+	incrCost(ctx, pipe, keys.daily, amount, TTLUsageDaily)
 
 	// 每月成本
-	monthlyCostKey := fmt.Sprintf("usage:cost:monthly:%s:%s", keyID, monthStr)
-	pipe.IncrByFloat(ctx, monthlyCostKey, amount)
-	pipe.Expire(ctx, monthlyCostKey, TTLUsageMonthly)
+	incrCost(ctx, pipe, keys.monthly, amount, TTLUsageMonthly)
 
 	// 总成本
-	totalCostKey := fmt.Sprintf("usage:cost:total:%s", keyID)
-	pipe.IncrByFloat(ctx, totalCostKey, amount)
+	incrCost(ctx, pipe, keys.total, amount, 0)
+
+	// 系统级分钟成本（用于成本速率指标）
+	hincrCost(ctx, pipe, keys.systemMinute, "totalCost", amount)
+	pipe.Expire(ctx, keys.systemMinute, systemMetricsTTL())
 
 	_, err = pipe.Exec(ctx)
 	if err != nil {
@@ -82,60 +107,50 @@ func (c *Client) IncrementDetailedCost(ctx context.Context, keyID string, inputC
 
 	// 每日详细成本
 	dailyCostKey := fmt.Sprintf("usage:cost:daily:%s:%s", keyID, dateStr)
-	pipe.HIncrByFloat(ctx, dailyCostKey, "totalCost", totalCost)
-	pipe.HIncrByFloat(ctx, dailyCostKey, "inputCost", inputCost)
-	pipe.HIncrByFloat(ctx, dailyCostKey, "outputCost", outputCost)
-	pipe.HIncrByFloat(ctx, dailyCostKey, "cacheCost", cacheCost)
+	hincrCost(ctx, pipe, dailyCostKey, "totalCost", totalCost)
+	hincrCost(ctx, pipe, dailyCostKey, "inputCost", inputCost)
+	hincrCost(ctx, pipe, dailyCostKey, "outputCost", outputCost)
+	hincrCost(ctx, pipe, dailyCostKey, "cacheCost", cacheCost)
 	pipe.HIncrBy(ctx, dailyCostKey, "requestCount", 1)
 	pipe.Expire(ctx, dailyCostKey, TTLUsageDaily)
 
 	// 每月详细成本
 	monthlyCostKey := fmt.Sprintf("usage:cost:monthly:%s:%s", keyID, monthStr)
-	pipe.HIncrByFloat(ctx, monthlyCostKey, "totalCost", totalCost)
-	pipe.HIncrByFloat(ctx, monthlyCostKey, "inputCost", inputCost)
-	pipe.HIncrByFloat(ctx, monthlyCostKey, "outputCost", outputCost)
-	pipe.HIncrByFloat(ctx, monthlyCostKey, "cacheCost", cacheCost)
+	hincrCost(ctx, pipe, monthlyCostKey, "totalCost", totalCost)
+	hincrCost(ctx, pipe, monthlyCostKey, "inputCost", inputCost)
+	hincrCost(ctx, pipe, monthlyCostKey, "outputCost", outputCost)
+	hincrCost(ctx, pipe, monthlyCostKey, "cacheCost", cacheCost)
 	pipe.HIncrBy(ctx, monthlyCostKey, "requestCount", 1)
 	pipe.Expire(ctx, monthlyCostKey, TTLUsageMonthly)
 
 	// 总成本
 	totalCostKey := fmt.Sprintf("usage:cost:total:%s", keyID)
-	pipe.HIncrByFloat(ctx, totalCostKey, "totalCost", totalCost)
-	pipe.HIncrByFloat(ctx, totalCostKey, "inputCost", inputCost)
-	pipe.HIncrByFloat(ctx, totalCostKey, "outputCost", outputCost)
-	pipe.HIncrByFloat(ctx, totalCostKey, "cacheCost", cacheCost)
+	hincrCost(ctx, pipe, totalCostKey, "totalCost", totalCost)
+	hincrCost(ctx, pipe, totalCostKey, "inputCost", inputCost)
+	hincrCost(ctx, pipe, totalCostKey, "outputCost", outputCost)
+	hincrCost(ctx, pipe, totalCostKey, "cacheCost", cacheCost)
 	pipe.HIncrBy(ctx, totalCostKey, "requestCount", 1)
 
-	_, err = pipe.Exec(ctx)
-	return err
+	return execIncrPipeline(ctx, client, pipe)
 }
 
 // GetDailyCost 获取每日成本
 func (c *Client) GetDailyCost(ctx context.Context, keyID string) (float64, error) {
+	return c.getDailyCostAt(ctx, keyID, time.Now())
+}
+
+// getDailyCostAt 获取指定时间所在日期的成本
+func (c *Client) getDailyCostAt(ctx context.Context, keyID string, now time.Time) (float64, error) {
 	client, err := c.GetClientSafe()
 	if err != nil {
 		return 0, err
 	}
 
-	dateStr := getDateStringInTimezone(time.Now())
+	dateStr := getDateStringInTimezone(now)
 	costKey := fmt.Sprintf("usage:cost:daily:%s:%s", keyID, dateStr)
 
-	// 尝试从 Hash 获取
-	result, err := client.HGet(ctx, costKey, "totalCost").Result()
-	if err == nil {
-		return parseFloat64(result), nil
-	}
-
-	// 兼容旧格式（直接存储）
-	result, err = client.Get(ctx, costKey).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return 0, nil // 未找到返回 0
-		}
-		return 0, err
-	}
-
-	return parseFloat64(result), nil
+	// 优先 Hash 字段，兼容旧格式（直接存储）
+	return readCost(ctx, client, costKey, "totalCost")
 }
 
 // GetDailyCostDetailed 获取每日详细成本
@@ -154,10 +169,10 @@ func (c *Client) GetDailyCostDetailed(ctx context.Context, keyID string, date ti
 	}
 
 	return &CostStats{
-		TotalCost:    parseFloat64(data["totalCost"]),
-		InputCost:    parseFloat64(data["inputCost"]),
-		OutputCost:   parseFloat64(data["outputCost"]),
-		CacheCost:    parseFloat64(data["cacheCost"]),
+		TotalCost:    hashCost(data, "totalCost"),
+		InputCost:    hashCost(data, "inputCost"),
+		OutputCost:   hashCost(data, "outputCost"),
+		CacheCost:    hashCost(data, "cacheCost"),
 		RequestCount: parseInt64(data["requestCount"]),
 	}, nil
 }
@@ -172,19 +187,12 @@ func (c *Client) GetMonthlyCost(ctx context.Context, keyID string) (float64, err
 	monthStr := getMonthStringInTimezone(time.Now())
 	costKey := fmt.Sprintf("usage:cost:monthly:%s:%s", keyID, monthStr)
 
-	// 尝试从 Hash 获取
-	result, err := client.HGet(ctx, costKey, "totalCost").Result()
-	if err == nil {
-		return parseFloat64(result), nil
-	}
-
-	// 兼容旧格式
-	result, err = client.Get(ctx, costKey).Result()
+	// 优先 Hash 字段，兼容旧格式
+	cost, err := readCost(ctx, client, costKey, "totalCost")
 	if err != nil {
 		return 0, nil
 	}
-
-	return parseFloat64(result), nil
+	return cost, nil
 }
 
 // GetMonthlyCostDetailed 获取每月详细成本
@@ -203,10 +211,10 @@ func (c *Client) GetMonthlyCostDetailed(ctx context.Context, keyID string, date
 	}
 
 	return &CostStats{
-		TotalCost:    parseFloat64(data["totalCost"]),
-		InputCost:    parseFloat64(data["inputCost"]),
-		OutputCost:   parseFloat64(data["outputCost"]),
-		CacheCost:    parseFloat64(data["cacheCost"]),
+		TotalCost:    hashCost(data, "totalCost"),
+		InputCost:    hashCost(data, "inputCost"),
+		OutputCost:   hashCost(data, "outputCost"),
+		CacheCost:    hashCost(data, "cacheCost"),
 		RequestCount: parseInt64(data["requestCount"]),
 	}, nil
 }
@@ -226,10 +234,10 @@ func (c *Client) GetTotalCost(ctx context.Context, keyID string) (*CostStats, er
 	}
 
 	return &CostStats{
-		TotalCost:    parseFloat64(data["totalCost"]),
-		InputCost:    parseFloat64(data["inputCost"]),
-		OutputCost:   parseFloat64(data["outputCost"]),
-		CacheCost:    parseFloat64(data["cacheCost"]),
+		TotalCost:    hashCost(data, "totalCost"),
+		InputCost:    hashCost(data, "inputCost"),
+		OutputCost:   hashCost(data, "outputCost"),
+		CacheCost:    hashCost(data, "cacheCost"),
 		RequestCount: parseInt64(data["requestCount"]),
 	}, nil
 }
@@ -252,23 +260,23 @@ func (c *Client) GetCostHistory(ctx context.Context, keyID string, days int) ([]
 		data, err := client.HGetAll(ctx, costKey).Result()
 		if err != nil || len(data) == 0 {
 			// 尝试旧格式
-			result, err := client.Get(ctx, costKey).Result()
-			if err != nil {
+			cost, exists, err := readStringCost(ctx, client, costKey)
+			if err != nil || !exists {
 				continue
 			}
 			records = append(records, DailyCostRecord{
 				Date:      dateStr,
-				TotalCost: parseFloat64(result),
+				TotalCost: cost,
 			})
 			continue
 		}
 
 		records = append(records, DailyCostRecord{
 			Date:         dateStr,
-			TotalCost:    parseFloat64(data["totalCost"]),
-			InputCost:    parseFloat64(data["inputCost"]),
-			OutputCost:   parseFloat64(data["outputCost"]),
-			CacheCost:    parseFloat64(data["cacheCost"]),
+			TotalCost:    hashCost(data, "totalCost"),
+			InputCost:    hashCost(data, "inputCost"),
+			OutputCost:   hashCost(data, "outputCost"),
+			CacheCost:    hashCost(data, "cacheCost"),
 			RequestCount: parseInt64(data["requestCount"]),
 		})
 	}
@@ -283,15 +291,22 @@ func (c *Client) GetCostStats(ctx context.Context, keyID string, days int) (*Cos
 		return nil, err
 	}
 
+	// 以微美元整数累加，避免多日求和的浮点漂移
+	var totalMicros, inputMicros, outputMicros, cacheMicros int64
 	stats := &CostStats{}
 	for _, record := range records {
-		stats.TotalCost += record.TotalCost
-		stats.InputCost += record.InputCost
-		stats.OutputCost += record.OutputCost
-		stats.CacheCost += record.CacheCost
+		totalMicros += CostToMicros(record.TotalCost)
+		inputMicros += CostToMicros(record.InputCost)
+		outputMicros += CostToMicros(record.OutputCost)
+		cacheMicros += CostToMicros(record.CacheCost)
 		stats.RequestCount += record.RequestCount
 	}
 
+	stats.TotalCost = RoundCostForStorage(MicrosToCost(totalMicros))
+	stats.InputCost = RoundCostForStorage(MicrosToCost(inputMicros))
+	stats.OutputCost = RoundCostForStorage(MicrosToCost(outputMicros))
+	stats.CacheCost = RoundCostForStorage(MicrosToCost(cacheMicros))
+
 	return stats, nil
 }
 
@@ -314,20 +329,19 @@ func (c *Client) IncrementAccountCost(ctx context.Context, accountID string, amo
 
 	// 账户总成本
 	accountCostKey := fmt.Sprintf("account_usage:%s", accountID)
-	pipe.HIncrByFloat(ctx, accountCostKey, "totalCost", amount)
+	hincrCost(ctx, pipe, accountCostKey, "totalCost", amount)
 
 	// 账户每日成本
 	accountDailyCostKey := fmt.Sprintf("account_usage:daily:%s:%s", accountID, dateStr)
-	pipe.HIncrByFloat(ctx, accountDailyCostKey, "cost", amount)
+	hincrCost(ctx, pipe, accountDailyCostKey, "cost", amount)
 	pipe.Expire(ctx, accountDailyCostKey, TTLUsageDaily)
 
 	// 账户每月成本
 	accountMonthlyCostKey := fmt.Sprintf("account_usage:monthly:%s:%s", accountID, monthStr)
-	pipe.HIncrByFloat(ctx, accountMonthlyCostKey, "cost", amount)
+	hincrCost(ctx, pipe, accountMonthlyCostKey, "cost", amount)
 	pipe.Expire(ctx, accountMonthlyCostKey, TTLUsageMonthly)
 
-	_, err = pipe.Exec(ctx)
-	return err
+	return execIncrPipeline(ctx, client, pipe)
 }
 
 // GetAccountCost 获取账户总成本
@@ -338,12 +352,12 @@ func (c *Client) GetAccountCost(ctx context.Context, accountID string) (float64,
 	}
 
 	accountCostKey := fmt.Sprintf("account_usage:%s", accountID)
-	result, err := client.HGet(ctx, accountCostKey, "totalCost").Result()
+	cost, err := readHashCost(ctx, client, accountCostKey, "totalCost")
 	if err != nil {
 		return 0, nil
 	}
 
-	return parseFloat64(result), nil
+	return cost, nil
 }
 
 // GetAccountDailyCost 获取账户每日成本
@@ -356,23 +370,29 @@ func (c *Client) GetAccountDailyCost(ctx context.Context, accountID string, date
 	dateStr := getDateStringInTimezone(date)
 	accountDailyCostKey := fmt.Sprintf("account_usage:daily:%s:%s", accountID, dateStr)
 
-	result, err := client.HGet(ctx, accountDailyCostKey, "cost").Result()
+	cost, err := readHashCost(ctx, client, accountDailyCostKey, "cost")
 	if err != nil {
 		return 0, nil
 	}
 
-	return parseFloat64(result), nil
+	return cost, nil
 }
 
-// getWeekStartDate 获取本周一的日期字符串
+// getWeekStartDate 获取本周一的日期字符串（统计时区，与每日统计桶的日期一致）
 func getWeekStartDate(t time.Time) string {
-	// 获取本周一
-	weekday := int(t.Weekday())
-	if weekday == 0 {
-		weekday = 7 // 周日
-	}
-	monday := t.AddDate(0, 0, -(weekday - 1))
-	return getDateStringInTimezone(monday)
+	start, _, _ := periodBounds(CostPeriodWeekly, t)
+	return getDateStringInTimezone(start)
+}
+
+// WeekStartDate 获取本周一的日期字符串（统计时区）
+func WeekStartDate(t time.Time) string {
+	return getWeekStartDate(t)
+}
+
+// NextWeeklyReset 获取 Opus 周成本的下一次重置时间（统计时区的下周一零点）
+func NextWeeklyReset(now time.Time) time.Time {
+	_, end, _ := periodBounds(CostPeriodWeekly, now)
+	return end
 }
 
 // IncrementWeeklyOpusCost 增加 Opus 周成本
@@ -387,9 +407,8 @@ func (c *Client) IncrementWeeklyOpusCost(ctx context.Context, keyID string, amou
 	weeklyOpusCostKey := fmt.Sprintf("usage:cost:weekly_opus:%s:%s", keyID, weekStartDate)
 
 	pipe := client.Pipeline()
-	pipe.IncrByFloat(ctx, weeklyOpusCostKey, amount)
 	// 设置 8 天过期，确保跨周时仍可读取
-	pipe.Expire(ctx, weeklyOpusCostKey, 8*24*time.Hour)
+	incrCost(ctx, pipe, weeklyOpusCostKey, amount, 8*24*time.Hour)
 
 	_, err = pipe.Exec(ctx)
 	if err != nil {
@@ -411,15 +430,8 @@ func (c *Client) GetWeeklyOpusCost(ctx context.Context, keyID string) (float64,
 	weekStartDate := getWeekStartDate(now)
 	weeklyOpusCostKey := fmt.Sprintf("usage:cost:weekly_opus:%s:%s", keyID, weekStartDate)
 
-	result, err := client.Get(ctx, weeklyOpusCostKey).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return 0, nil
-		}
-		return 0, err
-	}
-
-	return parseFloat64(result), nil
+	cost, _, err := readStringCost(ctx, client, weeklyOpusCostKey)
+	return cost, err
 }
 
 // GetRateLimitWindowCost 获取速率限制窗口内的费用
@@ -430,15 +442,8 @@ func (c *Client) GetRateLimitWindowCost(ctx context.Context, keyID string) (floa
 	}
 
 	costCountKey := fmt.Sprintf("rate_limit:cost:%s", keyID)
-	result, err := client.Get(ctx, costCountKey).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return 0, nil
-		}
-		return 0, err
-	}
-
-	return parseFloat64(result), nil
+	cost, _, err := readStringCost(ctx, client, costCountKey)
+	return cost, err
 }
 
 // IncrementRateLimitWindowCost 增加速率限制窗口内的费用
@@ -451,8 +456,7 @@ func (c *Client) IncrementRateLimitWindowCost(ctx context.Context, keyID string,
 	costCountKey := fmt.Sprintf("rate_limit:cost:%s", keyID)
 
 	pipe := client.Pipeline()
-	pipe.IncrByFloat(ctx, costCountKey, amount)
-	pipe.Expire(ctx, costCountKey, time.Duration(windowMinutes)*time.Minute)
+	incrCost(ctx, pipe, costCountKey, amount, time.Duration(windowMinutes)*time.Minute)
 
 	_, err = pipe.Exec(ctx)
 	return err
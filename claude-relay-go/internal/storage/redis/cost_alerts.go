@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CostAlert 一次新跨越的成本预警阈值
+type CostAlert struct {
+	KeyID      string  `json:"keyId"`
+	KeyName    string  `json:"keyName,omitempty"`
+	Threshold  float64 `json:"threshold"` // 阈值（占每日成本限制的百分比）
+	DailyCost  float64 `json:"dailyCost"`
+	DailyLimit float64 `json:"dailyLimit"`
+	Date       string  `json:"date"`
+}
+
+// costAlertsKey 当天已通知阈值集合的 key
+func costAlertsKey(keyID, dateStr string) string {
+	return PrefixCostAlerts + keyID + ":" + dateStr
+}
+
+// CheckCostAlerts 检查当天成本新跨越的预警阈值（已通知过的阈值当天不再返回）
+func (c *Client) CheckCostAlerts(ctx context.Context, key *APIKey) ([]CostAlert, error) {
+	return c.checkCostAlertsAt(ctx, key, time.Now())
+}
+
+// checkCostAlertsAt 按指定时间检查成本预警阈值
+func (c *Client) checkCostAlertsAt(ctx context.Context, key *APIKey, now time.Time) ([]CostAlert, error) {
+	if key == nil || key.DailyCostLimit <= 0 || len(key.CostAlertThresholds) == 0 {
+		return nil, nil
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	dailyCost, err := c.getDailyCostAt(ctx, key.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily cost: %w", err)
+	}
+
+	percent := dailyCost / key.DailyCostLimit * 100
+	thresholds := append([]float64(nil), key.CostAlertThresholds...)
+	sort.Float64s(thresholds)
+
+	dateStr := getDateStringInTimezone(now)
+	alertsKey := costAlertsKey(key.ID, dateStr)
+
+	var alerts []CostAlert
+	for _, threshold := range thresholds {
+		if threshold <= 0 || percent < threshold {
+			continue
+		}
+		// SADD 返回 1 表示当天首次跨越该阈值
+		added, err := client.SAdd(ctx, alertsKey, strconv.FormatFloat(threshold, 'f', -1, 64)).Result()
+		if err != nil {
+			return alerts, fmt.Errorf("failed to record cost alert: %w", err)
+		}
+		if added == 0 {
+			continue
+		}
+		alerts = append(alerts, CostAlert{
+			KeyID:      key.ID,
+			KeyName:    key.Name,
+			Threshold:  threshold,
+			DailyCost:  RoundCostForStorage(dailyCost),
+			DailyLimit: key.DailyCostLimit,
+			Date:       dateStr,
+		})
+	}
+	if len(alerts) > 0 {
+		client.Expire(ctx, alertsKey, TTLCostAlerts)
+	}
+
+	return alerts, nil
+}
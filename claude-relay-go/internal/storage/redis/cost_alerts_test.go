@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckCostAlerts_FiresOncePerThresholdPerDay(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	key := &APIKey{ID: "key-1", DailyCostLimit: 10, CostAlertThresholds: []float64{80, 50, 100}}
+
+	day1 := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+	setCost := func(at time.Time, cost float64) {
+		hook.strings["usage:cost:daily:key-1:"+getDateStringInTimezone(at)] = strconv.FormatFloat(cost, 'f', -1, 64)
+	}
+	check := func(at time.Time) []CostAlert {
+		t.Helper()
+		alerts, err := c.checkCostAlertsAt(ctx, key, at)
+		if err != nil {
+			t.Fatalf("checkCostAlertsAt() error = %v", err)
+		}
+		return alerts
+	}
+
+	setCost(day1, 4)
+	if alerts := check(day1); len(alerts) != 0 {
+		t.Fatalf("below 50%%: got %+v", alerts)
+	}
+
+	// 首次跨越 50%
+	setCost(day1, 5.5)
+	alerts := check(day1)
+	if len(alerts) != 1 || alerts[0].Threshold != 50 || alerts[0].DailyCost != 5.5 || alerts[0].DailyLimit != 10 {
+		t.Fatalf("crossing 50%%: got %+v", alerts)
+	}
+
+	// 同一天再次超过 50% 不再通知
+	setCost(day1, 6)
+	if alerts := check(day1); len(alerts) != 0 {
+		t.Fatalf("re-crossing 50%%: got %+v", alerts)
+	}
+
+	// 一次跨越多个阈值时按阈值升序返回
+	setCost(day1, 12)
+	alerts = check(day1)
+	if len(alerts) != 2 || alerts[0].Threshold != 80 || alerts[1].Threshold != 100 {
+		t.Fatalf("crossing 80%% and 100%%: got %+v", alerts)
+	}
+
+	// 次日重新计数
+	day2 := day1.AddDate(0, 0, 1)
+	setCost(day2, 5)
+	alerts = check(day2)
+	if len(alerts) != 1 || alerts[0].Threshold != 50 || alerts[0].Date != getDateStringInTimezone(day2) {
+		t.Fatalf("next day: got %+v", alerts)
+	}
+}
+
+func TestCheckCostAlerts_RequiresDailyLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	now := time.Now()
+	hook.strings["usage:cost:daily:key-1:"+getDateStringInTimezone(now)] = "100"
+
+	alerts, err := c.checkCostAlertsAt(context.Background(), &APIKey{ID: "key-1", CostAlertThresholds: []float64{50}}, now)
+	if err != nil || len(alerts) != 0 {
+		t.Fatalf("no daily limit: alerts = %+v, err = %v", alerts, err)
+	}
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ModelCost 单个模型在统计窗口内的累计成本
+type ModelCost struct {
+	Model    string  `json:"model"`
+	Cost     float64 `json:"cost"`
+	Requests int64   `json:"requests"`
+
+	costMicros int64
+}
+
+// CostByModel 按模型汇总的成本
+type CostByModel struct {
+	Days      int          `json:"days"`
+	TotalCost float64      `json:"totalCost"`
+	Requests  int64        `json:"requests"`
+	Models    []*ModelCost `json:"models"`
+	Currency  string       `json:"currency,omitempty"`
+}
+
+// ModelCostFunc 按模型价格计算一段用量的成本（由调用方注入定价服务，避免存储层依赖定价）
+type ModelCostFunc func(model string, usage *UsageStats) float64
+
+// GetCostByModel 获取最近 days 天按模型汇总的成本（按成本降序）
+// 成本由每日模型用量 usage:{keyId}:model:daily:{model}:{date} 按当前价格计算
+func (c *Client) GetCostByModel(ctx context.Context, keyID string, days int, costFn ModelCostFunc) (*CostByModel, error) {
+	return c.getCostByModelAt(ctx, keyID, days, time.Now(), costFn)
+}
+
+// getCostByModelAt 按指定时间汇总每日模型用量并计算成本
+func (c *Client) getCostByModelAt(ctx context.Context, keyID string, days int, now time.Time, costFn ModelCostFunc) (*CostByModel, error) {
+	if days <= 0 {
+		days = 30
+	}
+	if days > MaxCostReconciliationDays {
+		days = MaxCostReconciliationDays
+	}
+
+	dates := make(map[string]bool, days)
+	for i := 0; i < days; i++ {
+		dates[getDateStringInTimezone(now.AddDate(0, 0, -i))] = true
+	}
+
+	// 一次扫描该 Key 的全部每日模型用量，按日期后缀过滤窗口内的键
+	prefix := fmt.Sprintf("usage:%s:model:daily:", keyID)
+	keys, err := c.ScanReadKeys(ctx, prefix+"*", 1000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan model usage: %w", err)
+	}
+
+	var models []string
+	var usageKeys []string
+	for _, key := range keys {
+		// 格式: usage:{keyId}:model:daily:{model}:{date}
+		rest := strings.TrimPrefix(key, prefix)
+		sep := strings.LastIndex(rest, ":")
+		if sep <= 0 || !dates[rest[sep+1:]] {
+			continue
+		}
+		models = append(models, rest[:sep])
+		usageKeys = append(usageKeys, key)
+	}
+
+	result := &CostByModel{Days: days, Models: []*ModelCost{}}
+	if len(usageKeys) == 0 {
+		return result, nil
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(usageKeys))
+	for i, key := range usageKeys {
+		cmds[i] = pipe.HGetAll(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get model usage: %w", err)
+	}
+
+	// 按天计算后以微美元整数累加，避免多日求和的浮点漂移
+	byModel := make(map[string]*ModelCost)
+	var totalMicros int64
+	for i, cmd := range cmds {
+		data := cmd.Val()
+		if len(data) == 0 {
+			continue
+		}
+		usage := parseUsageData(data)
+		mc := byModel[models[i]]
+		if mc == nil {
+			mc = &ModelCost{Model: models[i]}
+			byModel[models[i]] = mc
+		}
+		var micros int64
+		if costFn != nil {
+			micros = CostToMicros(costFn(models[i], usage))
+		}
+		mc.costMicros += micros
+		mc.Requests += usage.RequestCount
+		totalMicros += micros
+		result.Requests += usage.RequestCount
+	}
+
+	for _, mc := range byModel {
+		mc.Cost = MicrosToCost(mc.costMicros)
+		result.Models = append(result.Models, mc)
+	}
+	result.TotalCost = MicrosToCost(totalMicros)
+
+	sort.Slice(result.Models, func(i, j int) bool {
+		if result.Models[i].costMicros != result.Models[j].costMicros {
+			return result.Models[i].costMicros > result.Models[j].costMicros
+		}
+		return result.Models[i].Model < result.Models[j].Model
+	})
+
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// testModelCost 测试用定价：每个输入 Token 按模型单价计费
+func testModelCost(model string, usage *UsageStats) float64 {
+	prices := map[string]float64{"claude-haiku": 0.01, "claude-opus-4": 0.10, "claude-sonnet-4": 0.05}
+	return float64(usage.InputTokens) * prices[model]
+}
+
+func TestCostByModel_AggregatesModelUsageAcrossDays(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	seed := []struct {
+		model    string
+		input    int64
+		requests int64
+		at       time.Time
+	}{
+		{"claude-haiku", 40, 1, now},
+		{"claude-haiku", 30, 1, now.AddDate(0, 0, -2)},
+		{"claude-opus-4", 12, 1, now.AddDate(0, 0, -1)},
+		{"claude-opus-4", 8, 1, now.AddDate(0, 0, -3)},
+		{"claude-sonnet-4", 14, 1, now.AddDate(0, 0, -4)},
+		// 窗口外
+		{"claude-haiku", 900, 3, now.AddDate(0, 0, -5)},
+	}
+	for _, s := range seed {
+		key := fmt.Sprintf("usage:key-1:model:daily:%s:%s", s.model, getDateStringInTimezone(s.at))
+		hook.hashes[key] = map[string]string{
+			"inputTokens": fmt.Sprint(s.input),
+			"requests":    fmt.Sprint(s.requests),
+		}
+	}
+	// 其他 Key 的用量不计入
+	hook.hashes["usage:key-2:model:daily:claude-opus-4:"+getDateStringInTimezone(now)] = map[string]string{"inputTokens": "100", "requests": "1"}
+
+	result, err := c.getCostByModelAt(ctx, "key-1", 5, now, testModelCost)
+	if err != nil {
+		t.Fatalf("getCostByModelAt() error = %v", err)
+	}
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+
+	want := []struct {
+		model    string
+		cost     float64
+		requests int64
+	}{
+		{"claude-opus-4", 2.00, 2},
+		// 成本相同时按模型名排序
+		{"claude-haiku", 0.70, 2},
+		{"claude-sonnet-4", 0.70, 1},
+	}
+	if len(result.Models) != len(want) {
+		t.Fatalf("models = %+v, want %d", result.Models, len(want))
+	}
+	for i, w := range want {
+		got := result.Models[i]
+		if got.Model != w.model || !near(got.Cost, w.cost) || got.Requests != w.requests {
+			t.Errorf("models[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	if !near(result.TotalCost, 3.40) || result.Requests != 5 || result.Days != 5 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestCostByModel_EmptyHistory(t *testing.T) {
+	c := newConnectedClientForTest(t, newMemoryRedisHook())
+
+	result, err := c.getCostByModelAt(context.Background(), "key-1", 0, time.Now(), testModelCost)
+	if err != nil {
+		t.Fatalf("getCostByModelAt() error = %v", err)
+	}
+	if result.Days != 30 || len(result.Models) != 0 || result.TotalCost != 0 {
+		t.Errorf("result = %+v", result)
+	}
+}
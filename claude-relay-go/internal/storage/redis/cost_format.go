@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"fmt"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// CostFormatter 按 locale 格式化成本金额（千分位、小数点与货币符号），仅用于展示
+type CostFormatter struct {
+	printer  *message.Printer
+	symbol   string
+	decimals int
+}
+
+// FormattedCostStats 成本统计的本地化展示值
+type FormattedCostStats struct {
+	TotalCost  string `json:"totalCost"`
+	InputCost  string `json:"inputCost"`
+	OutputCost string `json:"outputCost"`
+	CacheCost  string `json:"cacheCost"`
+}
+
+// NewCostFormatter 根据 BCP 47 locale（如 en-US、de-DE）创建格式化器
+// 货币取 COST_CURRENCY 配置，非 ISO 4217 代码时直接使用代码作为符号
+func NewCostFormatter(locale string) (*CostFormatter, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+
+	printer := message.NewPrinter(tag)
+	code := GetCostCurrency()
+	symbol := code
+	if unit, err := currency.ParseISO(code); err == nil {
+		symbol = printer.Sprint(currency.Symbol(unit))
+	}
+
+	return &CostFormatter{
+		printer:  printer,
+		symbol:   symbol,
+		decimals: GetCostDisplayDecimals(),
+	}, nil
+}
+
+// Format 按展示精度格式化金额，如 en-US 下 "$ 1,234.57"、de-DE 下 "$ 1.234,57"
+func (f *CostFormatter) Format(v float64) string {
+	return f.symbol + " " + f.printer.Sprint(number.Decimal(RoundCost(v, f.decimals), number.Scale(f.decimals)))
+}
+
+// FormatStats 格式化成本统计中的各项金额
+func (f *CostFormatter) FormatStats(stats *CostStats) *FormattedCostStats {
+	return &FormattedCostStats{
+		TotalCost:  f.Format(stats.TotalCost),
+		InputCost:  f.Format(stats.InputCost),
+		OutputCost: f.Format(stats.OutputCost),
+		CacheCost:  f.Format(stats.CacheCost),
+	}
+}
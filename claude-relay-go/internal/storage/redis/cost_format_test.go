@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestCostFormatter_Locales(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Cost: config.CostConfig{Currency: "USD", DisplayDecimals: 2}}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "en-US", want: "$ 1,234.57"},
+		{locale: "de-DE", want: "$ 1.234,57"},
+		{locale: "fr-FR", want: "$US 1\u00a0234,57"},
+	}
+	for _, tt := range tests {
+		f, err := NewCostFormatter(tt.locale)
+		if err != nil {
+			t.Fatalf("NewCostFormatter(%q) error = %v", tt.locale, err)
+		}
+		if got := f.Format(1234.5678); got != tt.want {
+			t.Errorf("Format(1234.5678) with %s = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestCostFormatter_CurrencyAndStats(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Cost: config.CostConfig{Currency: "EUR", DisplayDecimals: 4}}
+
+	f, err := NewCostFormatter("de-DE")
+	if err != nil {
+		t.Fatalf("NewCostFormatter() error = %v", err)
+	}
+	got := f.FormatStats(&CostStats{TotalCost: 1500.25, InputCost: 0.00125})
+	if got.TotalCost != "€ 1.500,2500" {
+		t.Errorf("TotalCost = %q, want %q", got.TotalCost, "€ 1.500,2500")
+	}
+	if got.InputCost != "€ 0,0013" {
+		t.Errorf("InputCost = %q, want %q", got.InputCost, "€ 0,0013")
+	}
+	if got.CacheCost != "€ 0,0000" {
+		t.Errorf("CacheCost = %q, want %q", got.CacheCost, "€ 0,0000")
+	}
+}
+
+func TestNewCostFormatter_InvalidLocale(t *testing.T) {
+	if _, err := NewCostFormatter("not a locale"); err == nil {
+		t.Fatal("NewCostFormatter() expected error for invalid locale")
+	}
+}
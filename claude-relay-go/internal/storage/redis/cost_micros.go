@@ -0,0 +1,284 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 成本累加方式
+const (
+	// CostStorageFloat 使用 INCRBYFLOAT/HINCRBYFLOAT 累加美元（默认）
+	CostStorageFloat = "float"
+	// CostStorageMicros 额外使用 INCRBY/HINCRBY 累加整数微美元（伴随计数），Go 读取伴随计数
+	// 浮点值照常累加且从不删除，Node 仍按浮点值读取
+	CostStorageMicros = "micros"
+)
+
+// GetCostStorageMode 获取成本累加方式
+func GetCostStorageMode() string {
+	if config.Cfg != nil && config.Cfg.Cost.StorageMode == CostStorageMicros {
+		return CostStorageMicros
+	}
+	return CostStorageFloat
+}
+
+// costMicrosKey 字符串成本 key 对应的微美元计数 key
+func costMicrosKey(key string) string {
+	return key + ":micros"
+}
+
+// costMicrosField Hash 成本字段对应的微美元计数字段
+func costMicrosField(field string) string {
+	return field + "Micros"
+}
+
+// luaIncrCostMicros 同时累加字符串成本的浮点值与微美元伴随计数
+// 伴随计数不存在时先以当前浮点值初始化，之后两者同步累加（浮点值保留给 Node）
+// KEYS[1]=成本 key, KEYS[2]=微美元计数 key; ARGV[1]=金额, ARGV[2]=微美元, ARGV[3]=TTL 毫秒（0 表示不过期）
+const luaIncrCostMicros = `
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	local legacy = tonumber(redis.call('GET', KEYS[1]) or '0') or 0
+	redis.call('SET', KEYS[2], math.floor(legacy * 1000000 + 0.5))
+end
+redis.call('INCRBY', KEYS[2], ARGV[2])
+redis.call('INCRBYFLOAT', KEYS[1], ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	redis.call('PEXPIRE', KEYS[2], ARGV[3])
+end
+return 1
+`
+
+// luaHIncrCostMicros 同时累加 Hash 成本字段的浮点值与微美元伴随字段（伴随字段不存在时先以浮点值初始化）
+// KEYS[1]=Hash key; ARGV[1]=成本字段, ARGV[2]=微美元字段, ARGV[3]=金额, ARGV[4]=微美元
+const luaHIncrCostMicros = `
+if redis.call('HEXISTS', KEYS[1], ARGV[2]) == 0 then
+	local legacy = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0') or 0
+	redis.call('HSET', KEYS[1], ARGV[2], math.floor(legacy * 1000000 + 0.5))
+end
+redis.call('HINCRBY', KEYS[1], ARGV[2], ARGV[4])
+redis.call('HINCRBYFLOAT', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`
+
+// incrCost 在管道中增加字符串成本（ttl 为 0 表示不过期）
+func incrCost(ctx context.Context, pipe goredis.Pipeliner, key string, amount float64, ttl time.Duration) {
+	if GetCostStorageMode() == CostStorageMicros {
+		pipe.Eval(ctx, luaIncrCostMicros, []string{key, costMicrosKey(key)}, amount, CostToMicros(amount), ttl.Milliseconds())
+		return
+	}
+	pipe.IncrByFloat(ctx, key, amount)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+}
+
+// hincrCost 在管道中增加 Hash 成本字段
+func hincrCost(ctx context.Context, pipe goredis.Pipeliner, key, field string, amount float64) {
+	if GetCostStorageMode() == CostStorageMicros {
+		pipe.Eval(ctx, luaHIncrCostMicros, []string{key}, field, costMicrosField(field), amount, CostToMicros(amount))
+		return
+	}
+	pipe.HIncrByFloat(ctx, key, field, amount)
+}
+
+// hashCost 读取 Hash 中的成本字段（存在微美元伴随字段时以其为准）
+func hashCost(data map[string]string, field string) float64 {
+	return combineCost(data[field], data[costMicrosField(field)])
+}
+
+// combineCost 选取成本值：存在微美元伴随计数时以其为准（已包含初始化时的浮点值），否则使用浮点值
+func combineCost(legacy, micros string) float64 {
+	if micros == "" {
+		return parseFloat64(legacy)
+	}
+	return MicrosToCost(parseInt64(micros))
+}
+
+// cmdString 将 MGET/HMGET 的返回值转换为字符串（nil 为空字符串）
+func cmdString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// readStringCost 读取字符串格式的成本，返回是否存在
+// micros 方式下优先读取微美元伴随计数
+func readStringCost(ctx context.Context, client *goredis.Client, key string) (float64, bool, error) {
+	if GetCostStorageMode() != CostStorageMicros {
+		result, err := client.Get(ctx, key).Result()
+		if err != nil {
+			if err == goredis.Nil {
+				return 0, false, nil
+			}
+			return 0, false, err
+		}
+		return parseFloat64(result), true, nil
+	}
+
+	values, err := client.MGet(ctx, key, costMicrosKey(key)).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	legacy, micros := cmdString(values[0]), cmdString(values[1])
+	return combineCost(legacy, micros), legacy != "" || micros != "", nil
+}
+
+// sumDailyCostMicros 汇总 API Key 在指定日期（配置时区日期字符串）的每日成本（微美元）
+// 一次管道读取所有日期的成本 Hash，不存在或非 Hash 的日期回退读取旧格式的字符串成本
+func sumDailyCostMicros(ctx context.Context, client *goredis.Client, keyID string, dates []string) (int64, error) {
+	costKeys := make([]string, len(dates))
+	cmds := make([]*goredis.MapStringStringCmd, len(dates))
+	pipe := client.Pipeline()
+	for i, dateStr := range dates {
+		costKeys[i] = fmt.Sprintf("usage:cost:daily:%s:%s", keyID, dateStr)
+		cmds[i] = pipe.HGetAll(ctx, costKeys[i])
+	}
+	// 单个命令失败（如旧格式的字符串成本）在下面逐个处理
+	_, _ = pipe.Exec(ctx)
+
+	var costMicros int64
+	for i, cmd := range cmds {
+		if costData, err := cmd.Result(); err == nil && len(costData) > 0 {
+			costMicros += CostToMicros(hashCost(costData, "totalCost"))
+			continue
+		}
+		// 兼容旧格式（直接存储）
+		cost, exists, err := readStringCost(ctx, client, costKeys[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to get daily cost for key %s: %w", keyID, err)
+		}
+		if exists {
+			costMicros += CostToMicros(cost)
+		}
+	}
+	return costMicros, nil
+}
+
+// readHashCost 读取 Hash 成本字段，字段不存在时返回 goredis.Nil
+// micros 方式下优先读取微美元伴随字段
+func readHashCost(ctx context.Context, client *goredis.Client, key, field string) (float64, error) {
+	if GetCostStorageMode() != CostStorageMicros {
+		result, err := client.HGet(ctx, key, field).Result()
+		if err != nil {
+			return 0, err
+		}
+		return parseFloat64(result), nil
+	}
+
+	values, err := client.HMGet(ctx, key, field, costMicrosField(field)).Result()
+	if err != nil {
+		return 0, err
+	}
+	legacy, micros := cmdString(values[0]), cmdString(values[1])
+	if legacy == "" && micros == "" {
+		return 0, goredis.Nil
+	}
+	return combineCost(legacy, micros), nil
+}
+
+// readCost 读取成本：优先 Hash 字段，Key 不是 Hash 或字段不存在时按字符串格式读取
+func readCost(ctx context.Context, client *goredis.Client, key, field string) (float64, error) {
+	if cost, err := readHashCost(ctx, client, key, field); err == nil {
+		return cost, nil
+	}
+
+	// 兼容旧格式（直接存储）
+	cost, _, err := readStringCost(ctx, client, key)
+	return cost, err
+}
+
+// luaMigrateCostToMicros 以浮点成本初始化尚不存在的微美元伴随计数（浮点值保留给 Node，不删除）
+// KEYS[1]=成本 key, KEYS[2]=微美元计数 key（字符串格式）
+// ARGV[1]=Hash 字段（为空表示字符串格式）, ARGV[2]=微美元字段
+const luaMigrateCostToMicros = `
+local value
+if ARGV[1] == '' then
+	if redis.call('TYPE', KEYS[1]).ok ~= 'string' or redis.call('EXISTS', KEYS[2]) == 1 then
+		return 0
+	end
+	value = redis.call('GET', KEYS[1])
+else
+	if redis.call('TYPE', KEYS[1]).ok ~= 'hash' or redis.call('HEXISTS', KEYS[1], ARGV[2]) == 1 then
+		return 0
+	end
+	value = redis.call('HGET', KEYS[1], ARGV[1])
+end
+if not value then
+	return 0
+end
+
+local micros = math.floor(tonumber(value) * 1000000 + 0.5)
+if ARGV[1] == '' then
+	local ttl = redis.call('PTTL', KEYS[1])
+	redis.call('SET', KEYS[2], micros)
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+else
+	redis.call('HSET', KEYS[1], ARGV[2], micros)
+end
+return 1
+`
+
+// detailedCostFields 详细成本 Hash 中的成本字段
+var detailedCostFields = []string{"totalCost", "inputCost", "outputCost", "cacheCost"}
+
+// CostMigrationResult 成本迁移结果
+type CostMigrationResult struct {
+	KeyID    string `json:"keyId"`
+	Migrated int    `json:"migrated"` // 新初始化的微美元伴随计数数量
+}
+
+// MigrateCostToMicros 以 API Key 最近 days 天的日成本、本月成本、总成本与本周 Opus 成本的浮点值初始化微美元伴随计数
+// 浮点值保持不变（Node 继续读取）；micros 方式下首次写入也会自动初始化，此处用于提前完成初始化
+func (c *Client) MigrateCostToMicros(ctx context.Context, keyID string, days int) (*CostMigrationResult, error) {
+	if days <= 0 {
+		days = 1
+	}
+	if days > MaxCostReconciliationDays {
+		days = MaxCostReconciliationDays
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	keys := []string{
+		fmt.Sprintf("usage:cost:monthly:%s:%s", keyID, getMonthStringInTimezone(now)),
+		fmt.Sprintf("usage:cost:total:%s", keyID),
+		fmt.Sprintf("usage:cost:weekly_opus:%s:%s", keyID, getWeekStartDate(now)),
+	}
+	for i := 0; i < days; i++ {
+		keys = append(keys, fmt.Sprintf("usage:cost:daily:%s:%s", keyID, getDateStringInTimezone(now.AddDate(0, 0, -i))))
+	}
+
+	result := &CostMigrationResult{KeyID: keyID}
+	migrate := func(key, field, microsField string) error {
+		migrated, err := client.Eval(ctx, luaMigrateCostToMicros, []string{key, costMicrosKey(key)}, field, microsField).Int()
+		if err != nil {
+			return fmt.Errorf("failed to migrate cost %s: %w", key, err)
+		}
+		result.Migrated += migrated
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := migrate(key, "", ""); err != nil {
+			return result, err
+		}
+		for _, field := range detailedCostFields {
+			if err := migrate(key, field, costMicrosField(field)); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func useCostStorageMode(t *testing.T, mode string) {
+	t.Helper()
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{Cost: config.CostConfig{StorageMode: mode}}
+}
+
+func TestIncrCost_MicrosSumMillionIncrementsExactly(t *testing.T) {
+	useCostStorageMode(t, CostStorageMicros)
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	client, err := c.GetClientSafe()
+	if err != nil {
+		t.Fatalf("GetClientSafe() error = %v", err)
+	}
+
+	const (
+		increments = 1000000
+		chunk      = 10000
+	)
+	key := "usage:cost:total:key-1"
+	for done := 0; done < increments; done += chunk {
+		pipe := client.Pipeline()
+		for i := 0; i < chunk; i++ {
+			incrCost(ctx, pipe, key, 0.000001, 0)
+			hincrCost(ctx, pipe, key+":hash", "totalCost", 0.000001)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			t.Fatalf("pipeline error = %v", err)
+		}
+	}
+
+	// 浮点值照常累加，供 Node 读取
+	if _, ok := hook.strings[key]; !ok {
+		t.Error("micros mode should keep writing the float key")
+	}
+	cost, exists, err := readStringCost(ctx, client, key)
+	if err != nil || !exists {
+		t.Fatalf("readStringCost() = %v, %v, %v", cost, exists, err)
+	}
+	if cost != 1 {
+		t.Errorf("string cost = %v, want exactly 1", cost)
+	}
+	hashed, err := readHashCost(ctx, client, key+":hash", "totalCost")
+	if err != nil {
+		t.Fatalf("readHashCost() error = %v", err)
+	}
+	if hashed != 1 {
+		t.Errorf("hash cost = %v, want exactly 1", hashed)
+	}
+}
+
+func TestCostMicros_ReadsLegacyFloatValues(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+	dailyKey := "usage:cost:daily:key-1:" + getDateStringInTimezone(now)
+	monthlyKey := "usage:cost:monthly:key-1:" + getMonthStringInTimezone(now)
+	hook.strings[dailyKey] = "12.5"
+	hook.hashes[monthlyKey] = map[string]string{"totalCost": "3.25", "inputCost": "1.25"}
+
+	// 默认 float 方式读取旧值
+	if cost, err := c.GetDailyCost(ctx, "key-1"); err != nil || cost != 12.5 {
+		t.Fatalf("float mode GetDailyCost() = %v, %v, want 12.5", cost, err)
+	}
+
+	// 切换到 micros 方式后，伴随计数以旧值初始化，浮点值继续同步累加
+	useCostStorageMode(t, CostStorageMicros)
+	if cost, err := c.GetDailyCost(ctx, "key-1"); err != nil || cost != 12.5 {
+		t.Fatalf("micros mode legacy GetDailyCost() = %v, %v, want 12.5", cost, err)
+	}
+	if err := c.IncrementDailyCost(ctx, "key-1", 0.1); err != nil {
+		t.Fatalf("IncrementDailyCost() error = %v", err)
+	}
+	if got := parseFloat64(hook.strings[dailyKey]); got < 12.599 || got > 12.601 {
+		t.Errorf("legacy float value = %v, want dual-written 12.6", got)
+	}
+	if cost, err := c.GetDailyCost(ctx, "key-1"); err != nil || cost != 12.6 {
+		t.Errorf("GetDailyCost() = %v, %v, want 12.6", cost, err)
+	}
+
+	if err := c.IncrementDetailedCost(ctx, "key-1", 0.5, 0.25, 0); err != nil {
+		t.Fatalf("IncrementDetailedCost() error = %v", err)
+	}
+	stats, err := c.GetMonthlyCostDetailed(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("GetMonthlyCostDetailed() error = %v", err)
+	}
+	if stats.TotalCost != 4 || stats.InputCost != 1.75 || stats.OutputCost != 0.25 {
+		t.Errorf("monthly stats = %+v, want total 4 input 1.75 output 0.25", stats)
+	}
+}
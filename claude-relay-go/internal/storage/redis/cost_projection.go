@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 成本预测周期
+const (
+	CostPeriodDaily   = "daily"
+	CostPeriodMonthly = "monthly"
+	// CostPeriodWeekly 周一零点起的自然周（仅用于周期边界，如 Opus 周成本重置；不支持成本预测）
+	CostPeriodWeekly = "weekly"
+)
+
+// MinProjectionElapsedFraction 开始预测所需的最小周期进度（过早线性外推噪声太大）
+const MinProjectionElapsedFraction = 0.1
+
+// CostProjection 周期末成本预测
+type CostProjection struct {
+	Period           string  `json:"period"`
+	ElapsedCost      float64 `json:"elapsedCost"`      // 本周期已产生成本
+	ElapsedFraction  float64 `json:"elapsedFraction"`  // 本周期已过去的比例 (0-1)
+	Projected        bool    `json:"projected"`        // 是否已达到最小进度并完成外推
+	ProjectedCost    float64 `json:"projectedCost"`    // 周期末预测成本（未外推时等于已产生成本）
+	Limit            float64 `json:"limit"`            // 对应的成本限制（0 表示不限制）
+	ProjectedOverage float64 `json:"projectedOverage"` // 预测超出限制的金额
+	WillExceed       bool    `json:"willExceed"`
+	Currency         string  `json:"currency"`
+}
+
+// periodBounds 获取时间所在周期的起止时间（按配置时区划分）
+func periodBounds(period string, now time.Time) (time.Time, time.Time, error) {
+	offset := getTimezoneOffset()
+	tz := getDateInTimezone(now)
+
+	var start, end time.Time
+	switch period {
+	case CostPeriodDaily:
+		start = time.Date(tz.Year(), tz.Month(), tz.Day(), 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 0, 1)
+	case CostPeriodWeekly:
+		weekday := int(tz.Weekday())
+		if weekday == 0 {
+			weekday = 7 // 周日
+		}
+		start = time.Date(tz.Year(), tz.Month(), tz.Day()-(weekday-1), 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 0, 7)
+	case CostPeriodMonthly:
+		start = time.Date(tz.Year(), tz.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, 0)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unsupported cost period: %s", period)
+	}
+
+	// 转回真实 UTC 时间
+	return start.Add(-offset), end.Add(-offset), nil
+}
+
+// periodElapsedFraction 计算周期已过去的比例
+func periodElapsedFraction(period string, now time.Time) (float64, error) {
+	start, end, err := periodBounds(period, now)
+	if err != nil {
+		return 0, err
+	}
+	fraction := float64(now.Sub(start)) / float64(end.Sub(start))
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return fraction, nil
+}
+
+// projectCost 线性外推周期末成本
+// base 为周期外已计入限制的成本（月度预测按总成本限制比较时使用）
+func projectCost(period string, elapsedCost, fraction, base, limit float64) *CostProjection {
+	projection := &CostProjection{
+		Period:          period,
+		ElapsedCost:     RoundCostForStorage(elapsedCost),
+		ElapsedFraction: fraction,
+		ProjectedCost:   RoundCostForStorage(elapsedCost),
+		Limit:           limit,
+		Currency:        GetCostCurrency(),
+	}
+
+	if fraction >= MinProjectionElapsedFraction {
+		projection.Projected = true
+		projection.ProjectedCost = RoundCostForStorage(elapsedCost / fraction)
+	}
+
+	if limit > 0 {
+		if overage := base + projection.ProjectedCost - limit; overage > 0 {
+			projection.ProjectedOverage = RoundCostForStorage(overage)
+			projection.WillExceed = true
+		}
+	}
+
+	return projection
+}
+
+// ProjectPeriodCost 按当前消耗速度预测周期末成本
+// daily 与每日成本限制比较；monthly 以月初前的累计成本加本月预测值与总成本限制比较。
+// API Key 不存在时返回 nil, nil
+func (c *Client) ProjectPeriodCost(ctx context.Context, keyID, period string) (*CostProjection, error) {
+	return c.projectPeriodCostAt(ctx, keyID, period, time.Now())
+}
+
+func (c *Client) projectPeriodCostAt(ctx context.Context, keyID, period string, now time.Time) (*CostProjection, error) {
+	if period != CostPeriodDaily && period != CostPeriodMonthly {
+		return nil, fmt.Errorf("unsupported cost projection period: %s", period)
+	}
+	fraction, err := periodElapsedFraction(period, now)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := c.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, nil
+	}
+
+	switch period {
+	case CostPeriodDaily:
+		daily, err := c.GetDailyCostDetailed(ctx, keyID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily cost: %w", err)
+		}
+		return projectCost(period, daily.TotalCost, fraction, 0, apiKey.DailyCostLimit), nil
+	default:
+		monthly, err := c.GetMonthlyCostDetailed(ctx, keyID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get monthly cost: %w", err)
+		}
+		total, err := c.GetTotalCost(ctx, keyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get total cost: %w", err)
+		}
+		base := total.TotalCost - monthly.TotalCost
+		if base < 0 {
+			base = 0
+		}
+		return projectCost(period, monthly.TotalCost, fraction, base, apiKey.TotalCostLimit), nil
+	}
+}
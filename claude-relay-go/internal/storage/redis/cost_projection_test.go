@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func seedProjectionKey(hook *memoryRedisHook) {
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{
+		"id":             "key-1",
+		"name":           "projection",
+		"isActive":       "true",
+		"dailyCostLimit": "5",
+		"totalCostLimit": "30",
+	}
+}
+
+func TestProjectPeriodCost_Daily(t *testing.T) {
+	hook := newMemoryRedisHook()
+	seedProjectionKey(hook)
+	// UTC+8 下 2024-06-10 12:00，当日已过去一半
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+	hook.hashes["usage:cost:daily:key-1:2024-06-10"] = map[string]string{"totalCost": "3"}
+	c := newConnectedClientForTest(t, hook)
+
+	projection, err := c.projectPeriodCostAt(context.Background(), "key-1", CostPeriodDaily, now)
+	if err != nil {
+		t.Fatalf("projectPeriodCostAt() error = %v", err)
+	}
+	if math.Abs(projection.ElapsedFraction-0.5) > 1e-9 {
+		t.Errorf("ElapsedFraction = %v, want 0.5", projection.ElapsedFraction)
+	}
+	if !projection.Projected || projection.ProjectedCost != 6 {
+		t.Errorf("ProjectedCost = %v (projected=%v), want 6", projection.ProjectedCost, projection.Projected)
+	}
+	if !projection.WillExceed || projection.ProjectedOverage != 1 {
+		t.Errorf("ProjectedOverage = %v, want 1", projection.ProjectedOverage)
+	}
+}
+
+func TestProjectPeriodCost_MonthlyAgainstTotalLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	seedProjectionKey(hook)
+	// UTC+8 下 2024-06-16 00:00，6 月（30 天）已过去一半
+	now := time.Date(2024, 6, 15, 16, 0, 0, 0, time.UTC)
+	hook.hashes["usage:cost:monthly:key-1:2024-06"] = map[string]string{"totalCost": "10"}
+	hook.hashes["usage:cost:total:key-1"] = map[string]string{"totalCost": "25"}
+	c := newConnectedClientForTest(t, hook)
+
+	projection, err := c.projectPeriodCostAt(context.Background(), "key-1", CostPeriodMonthly, now)
+	if err != nil {
+		t.Fatalf("projectPeriodCostAt() error = %v", err)
+	}
+	if projection.ProjectedCost != 20 {
+		t.Errorf("ProjectedCost = %v, want 20", projection.ProjectedCost)
+	}
+	// 月初前 15 + 本月预测 20 - 限制 30
+	if projection.ProjectedOverage != 5 {
+		t.Errorf("ProjectedOverage = %v, want 5", projection.ProjectedOverage)
+	}
+}
+
+func TestProjectPeriodCost_TooEarlyDoesNotExtrapolate(t *testing.T) {
+	hook := newMemoryRedisHook()
+	seedProjectionKey(hook)
+	// UTC+8 下 2024-06-10 01:12，仅过去 5%
+	now := time.Date(2024, 6, 9, 17, 12, 0, 0, time.UTC)
+	hook.hashes["usage:cost:daily:key-1:2024-06-10"] = map[string]string{"totalCost": "1"}
+	c := newConnectedClientForTest(t, hook)
+
+	projection, err := c.projectPeriodCostAt(context.Background(), "key-1", CostPeriodDaily, now)
+	if err != nil {
+		t.Fatalf("projectPeriodCostAt() error = %v", err)
+	}
+	if projection.Projected {
+		t.Error("projection should not extrapolate below the minimum elapsed fraction")
+	}
+	if projection.ProjectedCost != 1 || projection.WillExceed {
+		t.Errorf("projection = %+v, want elapsed cost without overage", projection)
+	}
+}
+
+func TestProjectPeriodCost_UnknownKeyAndPeriod(t *testing.T) {
+	c := newConnectedClientForTest(t, newMemoryRedisHook())
+	ctx := context.Background()
+
+	projection, err := c.ProjectPeriodCost(ctx, "missing", CostPeriodDaily)
+	if err != nil || projection != nil {
+		t.Errorf("ProjectPeriodCost(missing) = %+v, %v; want nil, nil", projection, err)
+	}
+	if _, err := c.ProjectPeriodCost(ctx, "missing", "yearly"); err == nil {
+		t.Error("expected error for unsupported period")
+	}
+}
@@ -0,0 +1,208 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MaxCostReconciliationDays 对账窗口最大天数（受每日统计保留时间限制）
+const MaxCostReconciliationDays = 31
+
+// costReconcileKey 模型对账 Hash 的 key（计算成本与上游报告成本并行记录）
+func costReconcileKey(keyID, model, dateStr string) string {
+	return fmt.Sprintf("usage:cost:reconcile:%s:%s:%s", keyID, model, dateStr)
+}
+
+// costReconcileIndexKey 当天有对账记录的模型集合的 key
+func costReconcileIndexKey(keyID, dateStr string) string {
+	return fmt.Sprintf("usage:cost:reconcile_models:%s:%s", keyID, dateStr)
+}
+
+// ModelCostVariance 单个模型的成本对账结果
+// Difference 与 VariancePercent 只比较同时带有上游报告成本的请求
+type ModelCostVariance struct {
+	Model               string  `json:"model,omitempty"`
+	ComputedCost        float64 `json:"computedCost"`        // 全部请求的计算成本
+	ReportedCost        float64 `json:"reportedCost"`        // 上游报告的成本
+	MatchedComputedCost float64 `json:"matchedComputedCost"` // 有报告成本的请求对应的计算成本
+	Difference          float64 `json:"difference"`          // 报告成本 - 对应的计算成本
+	VariancePercent     float64 `json:"variancePercent"`     // 差异占对应计算成本的百分比
+	Requests            int64   `json:"requests"`
+	ReportedRequests    int64   `json:"reportedRequests"`
+
+	computedMicros, reportedMicros, matchedMicros int64
+}
+
+// CostReconciliation 成本对账汇总
+type CostReconciliation struct {
+	Days     int                  `json:"days"`
+	Total    ModelCostVariance    `json:"total"`
+	Models   []*ModelCostVariance `json:"models"`
+	Currency string               `json:"currency,omitempty"`
+}
+
+// RecordCostReconciliation 记录一次请求的计算成本与上游报告成本（reported 为 nil 表示上游未报告）
+func (c *Client) RecordCostReconciliation(ctx context.Context, keyID, model string, computed float64, reported *float64) error {
+	return c.recordCostReconciliationAt(ctx, keyID, model, computed, reported, time.Now())
+}
+
+// recordCostReconciliationAt 在指定时间记录对账成本
+func (c *Client) recordCostReconciliationAt(ctx context.Context, keyID, model string, computed float64, reported *float64, now time.Time) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	model = normalizeModelName(model)
+	dateStr := getDateStringInTimezone(now)
+	indexKey := costReconcileIndexKey(keyID, dateStr)
+	key := costReconcileKey(keyID, model, dateStr)
+
+	pipe := client.Pipeline()
+	pipe.SAdd(ctx, indexKey, model)
+	pipe.Expire(ctx, indexKey, TTLUsageDaily)
+	pipe.HIncrByFloat(ctx, key, "computedCost", computed)
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	if reported != nil {
+		pipe.HIncrByFloat(ctx, key, "reportedCost", *reported)
+		pipe.HIncrByFloat(ctx, key, "matchedComputedCost", computed)
+		pipe.HIncrBy(ctx, key, "reportedRequests", 1)
+	}
+	pipe.Expire(ctx, key, TTLUsageDaily)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record cost reconciliation: %w", err)
+	}
+	return nil
+}
+
+// GetCostReconciliation 获取最近 days 天计算成本与上游报告成本的差异（按模型）
+func (c *Client) GetCostReconciliation(ctx context.Context, keyID string, days int) (*CostReconciliation, error) {
+	return c.getCostReconciliationAt(ctx, keyID, days, time.Now())
+}
+
+// getCostReconciliationAt 按指定时间汇总对账结果
+func (c *Client) getCostReconciliationAt(ctx context.Context, keyID string, days int, now time.Time) (*CostReconciliation, error) {
+	if days <= 0 {
+		days = 7
+	}
+	if days > MaxCostReconciliationDays {
+		days = MaxCostReconciliationDays
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := readModelDailyCosts(ctx, client, keyID, days, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以微美元整数累加，避免多日求和的浮点漂移
+	byModel := make(map[string]*ModelCostVariance)
+	for _, entry := range entries {
+		data := entry.data
+		variance := byModel[entry.model]
+		if variance == nil {
+			variance = &ModelCostVariance{Model: entry.model}
+			byModel[entry.model] = variance
+		}
+		variance.computedMicros += CostToMicros(parseFloat64(data["computedCost"]))
+		variance.reportedMicros += CostToMicros(parseFloat64(data["reportedCost"]))
+		variance.matchedMicros += CostToMicros(parseFloat64(data["matchedComputedCost"]))
+		variance.Requests += parseInt64(data["requests"])
+		variance.ReportedRequests += parseInt64(data["reportedRequests"])
+	}
+
+	result := &CostReconciliation{Days: days, Models: make([]*ModelCostVariance, 0, len(byModel))}
+	for _, variance := range byModel {
+		variance.finalize()
+		result.Models = append(result.Models, variance)
+
+		result.Total.computedMicros += variance.computedMicros
+		result.Total.reportedMicros += variance.reportedMicros
+		result.Total.matchedMicros += variance.matchedMicros
+		result.Total.Requests += variance.Requests
+		result.Total.ReportedRequests += variance.ReportedRequests
+	}
+	result.Total.finalize()
+
+	// 差异绝对值大的模型在前
+	sort.Slice(result.Models, func(i, j int) bool {
+		di, dj := absMicros(result.Models[i].reportedMicros-result.Models[i].matchedMicros), absMicros(result.Models[j].reportedMicros-result.Models[j].matchedMicros)
+		if di != dj {
+			return di > dj
+		}
+		return result.Models[i].Model < result.Models[j].Model
+	})
+
+	return result, nil
+}
+
+// modelDailyCost 单个模型一天的对账成本数据
+type modelDailyCost struct {
+	model string
+	data  map[string]string
+}
+
+// readModelDailyCosts 分两次管道读取最近 days 天有记录的模型及其每日对账 Hash（跳过空数据）
+func readModelDailyCosts(ctx context.Context, client *goredis.Client, keyID string, days int, now time.Time) ([]modelDailyCost, error) {
+	dateStrs := make([]string, days)
+	pipe := client.Pipeline()
+	indexCmds := make([]*goredis.StringSliceCmd, days)
+	for i := 0; i < days; i++ {
+		dateStrs[i] = getDateStringInTimezone(now.AddDate(0, 0, -i))
+		indexCmds[i] = pipe.SMembers(ctx, costReconcileIndexKey(keyID, dateStrs[i]))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation models: %w", err)
+	}
+
+	pipe = client.Pipeline()
+	var cmds []*goredis.MapStringStringCmd
+	var cmdModels []string
+	for i, cmd := range indexCmds {
+		for _, model := range cmd.Val() {
+			cmds = append(cmds, pipe.HGetAll(ctx, costReconcileKey(keyID, model, dateStrs[i])))
+			cmdModels = append(cmdModels, model)
+		}
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation costs: %w", err)
+	}
+
+	entries := make([]modelDailyCost, 0, len(cmds))
+	for i, cmd := range cmds {
+		if data := cmd.Val(); len(data) > 0 {
+			entries = append(entries, modelDailyCost{model: cmdModels[i], data: data})
+		}
+	}
+	return entries, nil
+}
+
+// finalize 根据累加的微美元计算对外字段
+func (v *ModelCostVariance) finalize() {
+	v.ComputedCost = RoundCostForStorage(MicrosToCost(v.computedMicros))
+	v.ReportedCost = RoundCostForStorage(MicrosToCost(v.reportedMicros))
+	v.MatchedComputedCost = RoundCostForStorage(MicrosToCost(v.matchedMicros))
+	v.Difference = RoundCostForStorage(MicrosToCost(v.reportedMicros - v.matchedMicros))
+	if v.matchedMicros != 0 {
+		v.VariancePercent = float64(v.reportedMicros-v.matchedMicros) / float64(v.matchedMicros) * 100
+	}
+}
+
+// absMicros 微美元绝对值
+func absMicros(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCostReconciliation_ReportsVarianceByModel(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	reported := func(v float64) *float64 { return &v }
+
+	seed := []struct {
+		model    string
+		computed float64
+		reported *float64
+		at       time.Time
+	}{
+		// 上游报告比计算高 10%
+		{"claude-sonnet-4", 1.00, reported(1.10), now},
+		{"claude-sonnet-4", 0.50, reported(0.55), now.AddDate(0, 0, -1)},
+		// 未报告成本的请求只计入计算成本
+		{"claude-sonnet-4", 0.25, nil, now},
+		// 上游报告比计算低 20%
+		{"claude-haiku", 0.10, reported(0.08), now},
+		// 窗口外
+		{"claude-haiku", 5.00, reported(1.00), now.AddDate(0, 0, -5)},
+	}
+	for _, s := range seed {
+		if err := c.recordCostReconciliationAt(ctx, "key-1", s.model, s.computed, s.reported, s.at); err != nil {
+			t.Fatalf("recordCostReconciliationAt() error = %v", err)
+		}
+	}
+
+	result, err := c.getCostReconciliationAt(ctx, "key-1", 3, now)
+	if err != nil {
+		t.Fatalf("getCostReconciliationAt() error = %v", err)
+	}
+	if len(result.Models) != 2 {
+		t.Fatalf("models = %+v, want 2", result.Models)
+	}
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+
+	sonnet := result.Models[0]
+	if sonnet.Model != "claude-sonnet-4" || !near(sonnet.ComputedCost, 1.75) || !near(sonnet.ReportedCost, 1.65) ||
+		!near(sonnet.MatchedComputedCost, 1.50) || !near(sonnet.Difference, 0.15) || !near(sonnet.VariancePercent, 10) ||
+		sonnet.Requests != 3 || sonnet.ReportedRequests != 2 {
+		t.Errorf("sonnet = %+v", sonnet)
+	}
+
+	haiku := result.Models[1]
+	if haiku.Model != "claude-haiku" || !near(haiku.Difference, -0.02) || !near(haiku.VariancePercent, -20) {
+		t.Errorf("haiku = %+v", haiku)
+	}
+
+	total := result.Total
+	if !near(total.ComputedCost, 1.85) || !near(total.Difference, 0.13) || !near(total.VariancePercent, 0.13/1.60*100) {
+		t.Errorf("total = %+v", total)
+	}
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"math"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// 成本精度默认值
+const (
+	// MicrosPerDollar 1 美元对应的微美元数
+	MicrosPerDollar = 1_000_000
+	// DefaultCostStorageDecimals 默认存储精度（微美元）
+	DefaultCostStorageDecimals = 6
+	// DefaultCostDisplayDecimals 默认展示精度
+	DefaultCostDisplayDecimals = 2
+	// DefaultCostCurrency 默认货币代码
+	DefaultCostCurrency = "USD"
+)
+
+// GetCostStorageDecimals 获取存储精度（限制在 0-6 位，超出微美元精度无意义）
+func GetCostStorageDecimals() int {
+	decimals := DefaultCostStorageDecimals
+	if config.Cfg != nil && config.Cfg.Cost.StorageDecimals > 0 {
+		decimals = config.Cfg.Cost.StorageDecimals
+	}
+	if decimals > DefaultCostStorageDecimals {
+		decimals = DefaultCostStorageDecimals
+	}
+	return decimals
+}
+
+// GetCostDisplayDecimals 获取展示精度
+func GetCostDisplayDecimals() int {
+	decimals := DefaultCostDisplayDecimals
+	if config.Cfg != nil && config.Cfg.Cost.DisplayDecimals > 0 {
+		decimals = config.Cfg.Cost.DisplayDecimals
+	}
+	if decimals > DefaultCostStorageDecimals {
+		decimals = DefaultCostStorageDecimals
+	}
+	return decimals
+}
+
+// GetCostCurrency 获取货币代码
+func GetCostCurrency() string {
+	if config.Cfg != nil && config.Cfg.Cost.Currency != "" {
+		return config.Cfg.Cost.Currency
+	}
+	return DefaultCostCurrency
+}
+
+// RoundCost 按指定小数位四舍五入
+func RoundCost(v float64, decimals int) float64 {
+	if decimals < 0 {
+		decimals = 0
+	}
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(v*factor) / factor
+}
+
+// RoundCostForStorage 按存储精度四舍五入
+func RoundCostForStorage(v float64) float64 {
+	return RoundCost(v, GetCostStorageDecimals())
+}
+
+// RoundCostForDisplay 按展示精度四舍五入
+func RoundCostForDisplay(v float64) float64 {
+	return RoundCost(v, GetCostDisplayDecimals())
+}
+
+// CostToMicros 美元转换为整数微美元
+func CostToMicros(v float64) int64 {
+	return int64(math.Round(v * MicrosPerDollar))
+}
+
+// MicrosToCost 整数微美元转换为美元
+func MicrosToCost(micros int64) float64 {
+	return float64(micros) / MicrosPerDollar
+}
+
+// SumCosts 以微美元整数累加，避免浮点漂移
+func SumCosts(values ...float64) float64 {
+	var total int64
+	for _, v := range values {
+		total += CostToMicros(v)
+	}
+	return RoundCostForStorage(MicrosToCost(total))
+}
@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 成本归因标签限制
+const (
+	// MaxCostTagLength 单个标签最大长度
+	MaxCostTagLength = 64
+	// MaxCostTagsPerDay 每个 API Key 每天最多的不同标签数，超出部分归入 CostTagOverflow
+	MaxCostTagsPerDay = 50
+	// CostTagOverflow 超出标签数上限时使用的汇总标签
+	CostTagOverflow = "_other"
+)
+
+// ErrInvalidCostTag 标签格式不合法
+var ErrInvalidCostTag = errors.New("invalid cost tag")
+
+var costTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]*$`)
+
+// CostTagUsage 单次请求的标签成本与 Token 用量
+type CostTagUsage struct {
+	Cost              float64 `json:"cost"`
+	InputTokens       int64   `json:"inputTokens"`
+	OutputTokens      int64   `json:"outputTokens"`
+	CacheCreateTokens int64   `json:"cacheCreateTokens"`
+	CacheReadTokens   int64   `json:"cacheReadTokens"`
+}
+
+// TagCostStats 标签成本统计
+type TagCostStats struct {
+	TotalCost         float64 `json:"totalCost"`
+	InputTokens       int64   `json:"inputTokens"`
+	OutputTokens      int64   `json:"outputTokens"`
+	CacheCreateTokens int64   `json:"cacheCreateTokens"`
+	CacheReadTokens   int64   `json:"cacheReadTokens"`
+	RequestCount      int64   `json:"requestCount"`
+}
+
+// NormalizeCostTag 规范化并校验标签（去除首尾空白、转小写）
+func NormalizeCostTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > MaxCostTagLength || !costTagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: must be 1-%d characters of a-z, 0-9, '.', '_', '/', '-'", ErrInvalidCostTag, MaxCostTagLength)
+	}
+	return tag, nil
+}
+
+// costTagKey 标签成本 Hash 的 key
+func costTagKey(keyID, tag, dateStr string) string {
+	return fmt.Sprintf("usage:cost:tag:%s:%s:%s", keyID, tag, dateStr)
+}
+
+// costTagIndexKey 当天已使用标签集合的 key
+func costTagIndexKey(keyID, dateStr string) string {
+	return fmt.Sprintf("usage:cost:tags:%s:%s", keyID, dateStr)
+}
+
+// IncrementTagCost 将一次请求的成本与 Token 记入标签桶，返回实际记入的标签
+// 当天不同标签数超过 MaxCostTagsPerDay 时新标签归入 CostTagOverflow
+func (c *Client) IncrementTagCost(ctx context.Context, keyID, tag string, usage CostTagUsage) (string, error) {
+	return c.incrementTagCostAt(ctx, keyID, tag, usage, time.Now())
+}
+
+// incrementTagCostAt 在指定时间记录标签成本
+func (c *Client) incrementTagCostAt(ctx context.Context, keyID, tag string, usage CostTagUsage, now time.Time) (string, error) {
+	tag, err := NormalizeCostTag(tag)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return "", err
+	}
+
+	dateStr := getDateStringInTimezone(now)
+	indexKey := costTagIndexKey(keyID, dateStr)
+
+	// 先登记标签，超出上限时撤销并改用汇总标签
+	added, err := client.SAdd(ctx, indexKey, tag).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to register cost tag: %w", err)
+	}
+	if added > 0 {
+		count, err := client.SCard(ctx, indexKey).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to count cost tags: %w", err)
+		}
+		if count > MaxCostTagsPerDay && tag != CostTagOverflow {
+			client.SRem(ctx, indexKey, tag)
+			tag = CostTagOverflow
+		}
+	}
+
+	tagKey := costTagKey(keyID, tag, dateStr)
+
+	pipe := client.Pipeline()
+	pipe.SAdd(ctx, indexKey, tag)
+	pipe.Expire(ctx, indexKey, TTLUsageDaily)
+	pipe.HIncrByFloat(ctx, tagKey, "totalCost", usage.Cost)
+	pipe.HIncrBy(ctx, tagKey, "inputTokens", usage.InputTokens)
+	pipe.HIncrBy(ctx, tagKey, "outputTokens", usage.OutputTokens)
+	pipe.HIncrBy(ctx, tagKey, "cacheCreateTokens", usage.CacheCreateTokens)
+	pipe.HIncrBy(ctx, tagKey, "cacheReadTokens", usage.CacheReadTokens)
+	pipe.HIncrBy(ctx, tagKey, "requests", 1)
+	pipe.Expire(ctx, tagKey, TTLUsageDaily)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to increment tag cost: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetCostByTag 获取指定日期各标签的成本统计
+func (c *Client) GetCostByTag(ctx context.Context, keyID string, date time.Time) (map[string]*TagCostStats, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	dateStr := getDateStringInTimezone(date)
+	tags, err := client.SMembers(ctx, costTagIndexKey(keyID, dateStr)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost tags: %w", err)
+	}
+
+	result := make(map[string]*TagCostStats, len(tags))
+	if len(tags) == 0 {
+		return result, nil
+	}
+
+	pipe := client.Pipeline()
+	cmds := make(map[string]*goredis.MapStringStringCmd, len(tags))
+	for _, tag := range tags {
+		cmds[tag] = pipe.HGetAll(ctx, costTagKey(keyID, tag, dateStr))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get tag costs: %w", err)
+	}
+
+	for tag, cmd := range cmds {
+		data := cmd.Val()
+		if len(data) == 0 {
+			continue
+		}
+		result[tag] = &TagCostStats{
+			TotalCost:         parseFloat64(data["totalCost"]),
+			InputTokens:       parseInt64(data["inputTokens"]),
+			OutputTokens:      parseInt64(data["outputTokens"]),
+			CacheCreateTokens: parseInt64(data["cacheCreateTokens"]),
+			CacheReadTokens:   parseInt64(data["cacheReadTokens"]),
+			RequestCount:      parseInt64(data["requests"]),
+		}
+	}
+
+	return result, nil
+}
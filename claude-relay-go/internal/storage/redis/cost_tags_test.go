@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNormalizeCostTag(t *testing.T) {
+	valid := map[string]string{
+		"  Checkout ":      "checkout",
+		"team-a/feature.x": "team-a/feature.x",
+		"v2_search":        "v2_search",
+	}
+	for input, want := range valid {
+		got, err := NormalizeCostTag(input)
+		if err != nil || got != want {
+			t.Errorf("NormalizeCostTag(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "   ", "has space", "a:b", "-leading", string(make([]byte, MaxCostTagLength+1))} {
+		if _, err := NormalizeCostTag(input); !errors.Is(err, ErrInvalidCostTag) {
+			t.Errorf("NormalizeCostTag(%q) error = %v, want ErrInvalidCostTag", input, err)
+		}
+	}
+}
+
+func TestIncrementTagCost_AccumulatesAndMatchesTotal(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	requests := []struct {
+		tag  string
+		cost float64
+	}{
+		{"checkout", 0.25},
+		{"search", 0.10},
+		{"Checkout", 0.05},
+	}
+	for _, r := range requests {
+		if err := c.IncrementDailyCost(ctx, "key-1", r.cost); err != nil {
+			t.Fatalf("IncrementDailyCost() error = %v", err)
+		}
+		if _, err := c.IncrementTagCost(ctx, "key-1", r.tag, CostTagUsage{Cost: r.cost, InputTokens: 100, OutputTokens: 10}); err != nil {
+			t.Fatalf("IncrementTagCost(%s) error = %v", r.tag, err)
+		}
+	}
+
+	tags, err := c.GetCostByTag(ctx, "key-1", time.Now())
+	if err != nil {
+		t.Fatalf("GetCostByTag() error = %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("len(tags) = %d, want 2: %+v", len(tags), tags)
+	}
+	checkout := tags["checkout"]
+	if checkout == nil || checkout.RequestCount != 2 || checkout.InputTokens != 200 || math.Abs(checkout.TotalCost-0.30) > 1e-9 {
+		t.Errorf("checkout = %+v, want 2 requests, 200 input tokens, cost 0.30", checkout)
+	}
+
+	var tagged float64
+	for _, stats := range tags {
+		tagged += stats.TotalCost
+	}
+	daily, err := c.GetDailyCost(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetDailyCost() error = %v", err)
+	}
+	if math.Abs(tagged-daily) > 1e-9 {
+		t.Errorf("sum of tag costs = %v, daily total = %v; want equal", tagged, daily)
+	}
+}
+
+func TestIncrementTagCost_OverflowBeyondCardinalityLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < MaxCostTagsPerDay+5; i++ {
+		tag, err := c.incrementTagCostAt(ctx, "key-1", fmt.Sprintf("tag-%d", i), CostTagUsage{Cost: 0.01}, now)
+		if err != nil {
+			t.Fatalf("incrementTagCostAt() error = %v", err)
+		}
+		if i >= MaxCostTagsPerDay && tag != CostTagOverflow {
+			t.Fatalf("tag #%d recorded as %q, want %q", i, tag, CostTagOverflow)
+		}
+	}
+
+	tags, err := c.GetCostByTag(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("GetCostByTag() error = %v", err)
+	}
+	// 上限内的标签 + 汇总标签
+	if len(tags) != MaxCostTagsPerDay+1 {
+		t.Errorf("len(tags) = %d, want %d", len(tags), MaxCostTagsPerDay+1)
+	}
+	if overflow := tags[CostTagOverflow]; overflow == nil || overflow.RequestCount != 5 {
+		t.Errorf("overflow = %+v, want 5 requests", overflow)
+	}
+}
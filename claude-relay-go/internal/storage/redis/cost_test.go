@@ -2,9 +2,11 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -125,3 +127,110 @@ func TestGetDailyCost_FallbackToLegacyOnHGetError(t *testing.T) {
 		t.Fatalf("expected cost 12.34, got %v", cost)
 	}
 }
+
+func TestRoundCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		decimals int
+		want     float64
+	}{
+		{"round to 2", 1.23456, 2, 1.23},
+		{"round half up", 0.125, 2, 0.13},
+		{"round to 6", 0.0000014, 6, 0.000001},
+		{"negative decimals", 1.6, -1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RoundCost(tt.value, tt.decimals); got != tt.want {
+				t.Errorf("RoundCost(%v, %d) = %v, want %v", tt.value, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostMicrosRoundTrip(t *testing.T) {
+	if got := CostToMicros(1.234567); got != 1234567 {
+		t.Errorf("CostToMicros() = %d, want 1234567", got)
+	}
+	if got := MicrosToCost(1234567); got != 1.234567 {
+		t.Errorf("MicrosToCost() = %v, want 1.234567", got)
+	}
+}
+
+func TestSumCosts_RepeatedSmallIncrements(t *testing.T) {
+	// 浮点直接累加 0.1 十次会得到 0.9999999999999999
+	values := make([]float64, 10)
+	for i := range values {
+		values[i] = 0.1
+	}
+	if got := SumCosts(values...); got != 1.0 {
+		t.Errorf("SumCosts(0.1 x10) = %v, want 1", got)
+	}
+
+	// 一百万次 1 微美元累加
+	micro := make([]float64, 1_000_000)
+	for i := range micro {
+		micro[i] = 0.000001
+	}
+	if got := SumCosts(micro...); got != 1.0 {
+		t.Errorf("SumCosts(0.000001 x1e6) = %v, want 1", got)
+	}
+
+	// 展示精度
+	if got := RoundCostForDisplay(SumCosts(0.333333, 0.333333, 0.333333)); got != 1.0 {
+		t.Errorf("RoundCostForDisplay() = %v, want 1", got)
+	}
+}
+
+func TestCostPrecisionDefaults(t *testing.T) {
+	if GetCostStorageDecimals() != DefaultCostStorageDecimals {
+		t.Errorf("GetCostStorageDecimals() = %d, want %d", GetCostStorageDecimals(), DefaultCostStorageDecimals)
+	}
+	if GetCostDisplayDecimals() != DefaultCostDisplayDecimals {
+		t.Errorf("GetCostDisplayDecimals() = %d, want %d", GetCostDisplayDecimals(), DefaultCostDisplayDecimals)
+	}
+	if GetCostCurrency() != DefaultCostCurrency {
+		t.Errorf("GetCostCurrency() = %s, want %s", GetCostCurrency(), DefaultCostCurrency)
+	}
+}
+
+func TestGetCostHistory_DetailedFieldsAndEmptyHistory(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	now := time.Now()
+	today := getDateStringInTimezone(now)
+	older := getDateStringInTimezone(now.AddDate(0, 0, -2))
+	hook.hashes["usage:cost:daily:key-a:"+today] = map[string]string{
+		"totalCost": "1.5", "inputCost": "0.5", "outputCost": "0.75", "cacheCost": "0.25", "requestCount": "3",
+	}
+	hook.strings["usage:cost:daily:key-a:"+older] = "2" // 旧格式
+
+	records, err := c.GetCostHistory(ctx, "key-a", 3)
+	if err != nil {
+		t.Fatalf("GetCostHistory() error = %v", err)
+	}
+	want := []DailyCostRecord{
+		{Date: today, TotalCost: 1.5, InputCost: 0.5, OutputCost: 0.75, CacheCost: 0.25, RequestCount: 3},
+		{Date: older, TotalCost: 2},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %+v, want %+v", records, want)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("records[%d] = %+v, want %+v", i, records[i], want[i])
+		}
+	}
+
+	empty, err := c.GetCostHistory(ctx, "key-none", 30)
+	if err != nil {
+		t.Fatalf("GetCostHistory(empty) error = %v", err)
+	}
+	if data, _ := json.Marshal(empty); string(data) != "[]" {
+		t.Errorf("empty history JSON = %s, want []", data)
+	}
+}
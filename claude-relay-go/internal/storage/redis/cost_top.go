@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxTopCostDays 成本排行的最大天数（受每日成本保留时间限制）
+	MaxTopCostDays = 31
+	// DefaultTopCostLimit 成本排行默认返回的 Key 数
+	DefaultTopCostLimit = 20
+	// MaxTopCostLimit 成本排行最多返回的 Key 数
+	MaxTopCostLimit = 100
+	// MaxTopCostKeysScanned 成本排行最多统计的 Key 数（按 ID 排序后截断）
+	MaxTopCostKeysScanned = 10000
+)
+
+// TopCostKey 成本排行中的单个 Key
+type TopCostKey struct {
+	KeyID     string  `json:"keyId"`
+	Name      string  `json:"name"`
+	UserID    string  `json:"userId,omitempty"`
+	TotalCost float64 `json:"totalCost"`
+
+	costMicros int64
+}
+
+// TopCostKeys 最近 days 天成本最高的 Key
+type TopCostKeys struct {
+	Days        int          `json:"days"`
+	Limit       int          `json:"limit"`
+	KeysScanned int          `json:"keysScanned"`
+	Truncated   bool         `json:"truncated"` // Key 总数超过 MaxTopCostKeysScanned，未全部统计
+	Keys        []TopCostKey `json:"keys"`      // 按成本降序，不含零成本 Key
+}
+
+// GetTopCostKeys 获取最近 days 天（含今天）成本最高的 limit 个 Key
+func (c *Client) GetTopCostKeys(ctx context.Context, days, limit int) (*TopCostKeys, error) {
+	return c.getTopCostKeysAt(ctx, days, limit, time.Now())
+}
+
+func (c *Client) getTopCostKeysAt(ctx context.Context, days, limit int, now time.Time) (*TopCostKeys, error) {
+	if days <= 0 || days > MaxTopCostDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxTopCostDays)
+	}
+	if limit <= 0 || limit > MaxTopCostLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxTopCostLimit)
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := c.GetAllAPIKeys(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	result := &TopCostKeys{Days: days, Limit: limit, Keys: []TopCostKey{}}
+	if len(keys) > MaxTopCostKeysScanned {
+		keys = keys[:MaxTopCostKeysScanned]
+		result.Truncated = true
+	}
+	result.KeysScanned = len(keys)
+
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = getDateStringInTimezone(now.AddDate(0, 0, -i))
+	}
+
+	var ranked []TopCostKey
+	for _, key := range keys {
+		costMicros, err := sumDailyCostMicros(ctx, client, key.ID, dates)
+		if err != nil {
+			return nil, err
+		}
+		if costMicros <= 0 {
+			continue
+		}
+		ranked = append(ranked, TopCostKey{
+			KeyID:      key.ID,
+			Name:       key.Name,
+			UserID:     key.UserID,
+			TotalCost:  RoundCostForStorage(MicrosToCost(costMicros)),
+			costMicros: costMicros,
+		})
+	}
+
+	sort.SliceStable(ranked, func(a, b int) bool {
+		if ranked[a].costMicros != ranked[b].costMicros {
+			return ranked[a].costMicros > ranked[b].costMicros
+		}
+		return ranked[a].KeyID < ranked[b].KeyID
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	result.Keys = append(result.Keys, ranked...)
+
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetTopCostKeys_OrdersAndLimits(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+	today := getDateStringInTimezone(now)
+	yesterday := getDateStringInTimezone(now.AddDate(0, 0, -1))
+	old := getDateStringInTimezone(now.AddDate(0, 0, -10))
+
+	for _, id := range []string{"alpha", "beta", "gamma", "delta", "idle"} {
+		seedTaggedKey(hook, id, "Key "+id, `[]`)
+		hook.hashes[PrefixAPIKey+id]["userId"] = "user-" + id
+	}
+	costs := map[string][]string{
+		"alpha": {today, "1.5", yesterday, "0.5"}, // 2.0
+		"beta":  {today, "3"},                     // 3.0
+		"gamma": {yesterday, "0.25", old, "99"},   // 窗口外的成本不计入
+		"delta": {today, "2"},                     // 与 alpha 相同，按 ID 排序
+	}
+	for id, entries := range costs {
+		for i := 0; i < len(entries); i += 2 {
+			hook.hashes[fmt.Sprintf("usage:cost:daily:%s:%s", id, entries[i])] = map[string]string{"totalCost": entries[i+1]}
+		}
+	}
+
+	top, err := c.getTopCostKeysAt(ctx, 7, 3, now)
+	if err != nil {
+		t.Fatalf("getTopCostKeysAt() error = %v", err)
+	}
+	if top.KeysScanned != 5 || top.Truncated {
+		t.Errorf("scanned = %d (truncated %v), want 5", top.KeysScanned, top.Truncated)
+	}
+
+	want := []struct {
+		id   string
+		cost float64
+	}{{"beta", 3}, {"alpha", 2}, {"delta", 2}}
+	if len(top.Keys) != len(want) {
+		t.Fatalf("keys = %+v, want %d entries", top.Keys, len(want))
+	}
+	for i, w := range want {
+		got := top.Keys[i]
+		if got.KeyID != w.id || got.TotalCost != w.cost {
+			t.Errorf("keys[%d] = %s %v, want %s %v", i, got.KeyID, got.TotalCost, w.id, w.cost)
+		}
+	}
+	if top.Keys[0].Name != "Key beta" || top.Keys[0].UserID != "user-beta" {
+		t.Errorf("keys[0] = %+v, want name and userId of beta", top.Keys[0])
+	}
+
+	// 不限制数量时只返回有成本的 Key
+	all, err := c.getTopCostKeysAt(ctx, 7, MaxTopCostLimit, now)
+	if err != nil {
+		t.Fatalf("getTopCostKeysAt() error = %v", err)
+	}
+	if len(all.Keys) != 4 || all.Keys[3].KeyID != "gamma" || all.Keys[3].TotalCost != 0.25 {
+		t.Errorf("all keys = %+v, want 4 keys ending with gamma at 0.25", all.Keys)
+	}
+
+	if _, err := c.GetTopCostKeys(ctx, 0, 10); err == nil {
+		t.Error("expected error for days = 0")
+	}
+	if _, err := c.GetTopCostKeys(ctx, 7, MaxTopCostLimit+1); err == nil {
+		t.Error("expected error for limit above maximum")
+	}
+}
@@ -0,0 +1,195 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 每日 Token 限额的计数权重项
+const (
+	DailyTokenWeightInput       = "input"
+	DailyTokenWeightOutput      = "output"
+	DailyTokenWeightCacheCreate = "cacheCreate"
+	DailyTokenWeightCacheRead   = "cacheRead"
+)
+
+// dailyTokenWeightFields 权重项对应的每日使用统计字段
+var dailyTokenWeightFields = map[string]string{
+	DailyTokenWeightInput:       "inputTokens",
+	DailyTokenWeightOutput:      "outputTokens",
+	DailyTokenWeightCacheCreate: "cacheCreateTokens",
+	DailyTokenWeightCacheRead:   "cacheReadTokens",
+}
+
+// DailyTokenStatus API Key 每日 Token 计数与限额（重置基线只影响此计数与限额判断，不影响其他计数）
+type DailyTokenStatus struct {
+	KeyID     string    `json:"keyId"`
+	Used      int64     `json:"used"`      // 今日已用 Token（按权重计数，扣除重置基线；与限额比较）
+	RawUsed   int64     `json:"rawUsed"`   // 今日已用 Token（allTokens 原始值，扣除重置基线）
+	Weighted  bool      `json:"weighted"`  // 是否配置了非默认权重
+	Limit     int64     `json:"limit"`     // 每日限额（0 表示不限制）
+	Remaining int64     `json:"remaining"` // 剩余额度（不限制时为 -1）
+	Exceeded  bool      `json:"exceeded"`  // 是否已达到限额
+	ResetAt   time.Time `json:"resetAt"`   // 下一次自动重置时间（配置时区的次日零点）
+}
+
+// DailyTokenWeights 获取每日 Token 限额的计数权重（未配置的项为 1.0，负数视为 0）
+func DailyTokenWeights() map[string]float64 {
+	weights := make(map[string]float64, len(dailyTokenWeightFields))
+	for name := range dailyTokenWeightFields {
+		weights[name] = 1
+	}
+	if config.Cfg != nil {
+		for name, weight := range config.Cfg.System.DailyTokenWeights {
+			if _, ok := weights[name]; !ok {
+				continue
+			}
+			weights[name] = math.Max(weight, 0)
+		}
+	}
+	return weights
+}
+
+// isDefaultDailyTokenWeights 是否全部为默认权重（此时直接使用 allTokens，与未加权行为一致）
+func isDefaultDailyTokenWeights(weights map[string]float64) bool {
+	for _, weight := range weights {
+		if weight != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// weightedDailyTokens 按权重累加各类 Token
+func weightedDailyTokens(data map[string]string, weights map[string]float64) float64 {
+	var total float64
+	for name, field := range dailyTokenWeightFields {
+		total += float64(parseInt64(data[field])) * weights[name]
+	}
+	return total
+}
+
+// dailyTokensBaselineKey 每日 Token 计数重置基线 key
+func dailyTokensBaselineKey(keyID, dateStr string) string {
+	return fmt.Sprintf("%s%s:%s", PrefixDailyTokensBaseline, keyID, dateStr)
+}
+
+// dailyTokensBaselinePartsKey 重置时各类 Token 的基线（哈希，用于加权计数）
+func dailyTokensBaselinePartsKey(keyID, dateStr string) string {
+	return dailyTokensBaselineKey(keyID, dateStr) + ":parts"
+}
+
+// GetDailyTokenStatus 获取 API Key 今日 Token 计数，key 不存在时返回 nil
+func (c *Client) GetDailyTokenStatus(ctx context.Context, keyID string) (*DailyTokenStatus, error) {
+	return c.getDailyTokenStatusAt(ctx, keyID, time.Now())
+}
+
+// getDailyTokenStatusAt 获取指定时间所在日期的 Token 计数
+func (c *Client) getDailyTokenStatusAt(ctx context.Context, keyID string, now time.Time) (*DailyTokenStatus, error) {
+	key, err := c.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+	return c.dailyTokenStatusForKeyAt(ctx, key, now)
+}
+
+// GetDailyTokenStatusForKey 使用已加载的 API Key 获取今日 Token 计数（认证中间件的限额检查，避免重复读取 Key）
+func (c *Client) GetDailyTokenStatusForKey(ctx context.Context, key *APIKey) (*DailyTokenStatus, error) {
+	return c.dailyTokenStatusForKeyAt(ctx, key, time.Now())
+}
+
+// dailyTokenStatusForKeyAt 按 Key 的每日限额计算指定时间所在日期的 Token 计数
+func (c *Client) dailyTokenStatusForKeyAt(ctx context.Context, key *APIKey, now time.Time) (*DailyTokenStatus, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := key.ID
+	dateStr := getDateStringInTimezone(now)
+	pipe := client.Pipeline()
+	daily := pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, dateStr))
+	baseline := pipe.Get(ctx, dailyTokensBaselineKey(keyID, dateStr))
+	baselineParts := pipe.HGetAll(ctx, dailyTokensBaselinePartsKey(keyID, dateStr))
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get daily tokens: %w", err)
+	}
+
+	rawUsed := parseInt64(daily.Val()["allTokens"]) - parseInt64(baseline.Val())
+	if rawUsed < 0 {
+		rawUsed = 0
+	}
+
+	status := &DailyTokenStatus{
+		KeyID:     keyID,
+		Used:      rawUsed,
+		RawUsed:   rawUsed,
+		Limit:     key.Limit,
+		Remaining: -1,
+		ResetAt:   NextDailyReset(now),
+	}
+
+	if weights := DailyTokenWeights(); !isDefaultDailyTokenWeights(weights) {
+		status.Weighted = true
+		weighted := weightedDailyTokens(daily.Val(), weights)
+		if parts := baselineParts.Val(); len(parts) > 0 {
+			weighted -= weightedDailyTokens(parts, weights)
+		} else {
+			// 升级前的重置只记录了 allTokens，按未加权基线扣除
+			weighted -= float64(parseInt64(baseline.Val()))
+		}
+		status.Used = int64(math.Round(math.Max(weighted, 0)))
+	}
+
+	if key.Limit > 0 {
+		status.Remaining = key.Limit - status.Used
+		if status.Remaining < 0 {
+			status.Remaining = 0
+		}
+		status.Exceeded = status.Used >= key.Limit
+	}
+	return status, nil
+}
+
+// ResetDailyTokens 将 API Key 今日 Token 计数清零（记录当前用量为基线）
+// 基线只被每日 Token 计数扣除（状态接口与认证中间件的限额检查）：usedToday 字段与 usage:daily 计数保持不变
+func (c *Client) ResetDailyTokens(ctx context.Context, keyID string) error {
+	return c.resetDailyTokensAt(ctx, keyID, time.Now())
+}
+
+// resetDailyTokensAt 将指定时间所在日期的 Token 计数清零
+func (c *Client) resetDailyTokensAt(ctx context.Context, keyID string, now time.Time) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	dateStr := getDateStringInTimezone(now)
+	daily, err := client.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, dateStr)).Result()
+	if err != nil && err != goredis.Nil {
+		return fmt.Errorf("failed to get daily tokens: %w", err)
+	}
+
+	parts := make(map[string]interface{}, len(dailyTokenWeightFields))
+	for _, field := range dailyTokenWeightFields {
+		parts[field] = parseInt64(daily[field])
+	}
+
+	partsKey := dailyTokensBaselinePartsKey(keyID, dateStr)
+	pipe := client.Pipeline()
+	pipe.Set(ctx, dailyTokensBaselineKey(keyID, dateStr), parseInt64(daily["allTokens"]), TTLDailyTokensBaseline)
+	pipe.HSet(ctx, partsKey, parts)
+	pipe.Expire(ctx, partsKey, TTLDailyTokensBaseline)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to reset daily tokens: %w", err)
+	}
+	return nil
+}
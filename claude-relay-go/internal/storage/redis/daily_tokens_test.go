@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestDailyTokenStatus_ReadAndReset(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+	dailyKey := PrefixUsageDaily + "key-1:" + getDateStringInTimezone(now)
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "k", "limit": "1000"}
+	hook.hashes[dailyKey] = map[string]string{"allTokens": "300", "requests": "4"}
+
+	status, err := c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getDailyTokenStatusAt() error = %v", err)
+	}
+	if status.Used != 300 || status.Limit != 1000 || status.Remaining != 700 {
+		t.Errorf("status = %+v, want used 300 limit 1000 remaining 700", status)
+	}
+	if !status.ResetAt.Equal(NextDailyReset(now)) || !status.ResetAt.After(now) {
+		t.Errorf("resetAt = %v, want next local midnight after %v", status.ResetAt, now)
+	}
+
+	// 重置后计数清零，使用统计保持不变
+	if err := c.resetDailyTokensAt(ctx, "key-1", now); err != nil {
+		t.Fatalf("resetDailyTokensAt() error = %v", err)
+	}
+	if got := hook.hashes[dailyKey]["allTokens"]; got != "300" {
+		t.Errorf("daily usage allTokens = %q, want untouched", got)
+	}
+	status, err = c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getDailyTokenStatusAt() after reset error = %v", err)
+	}
+	if status.Used != 0 || status.Remaining != 1000 {
+		t.Errorf("status after reset = %+v, want used 0 remaining 1000", status)
+	}
+
+	// 重置后的新用量继续计入
+	hook.hashes[dailyKey]["allTokens"] = "1250"
+	status, _ = c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if status.Used != 950 || status.Remaining != 50 {
+		t.Errorf("status after new usage = %+v, want used 950 remaining 50", status)
+	}
+
+	// 次日不受前一天重置基线影响
+	hook.hashes[PrefixUsageDaily+"key-1:"+getDateStringInTimezone(now.Add(24*time.Hour))] = map[string]string{"allTokens": "10"}
+	status, _ = c.getDailyTokenStatusAt(ctx, "key-1", now.Add(24*time.Hour))
+	if status.Used != 10 {
+		t.Errorf("next day used = %d, want 10", status.Used)
+	}
+}
+
+func TestDailyTokenStatus_UnlimitedAndMissingKey(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "k"}
+	status, err := c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getDailyTokenStatusAt() error = %v", err)
+	}
+	if status.Used != 0 || status.Limit != 0 || status.Remaining != -1 {
+		t.Errorf("unlimited status = %+v, want remaining -1", status)
+	}
+
+	if status, err := c.getDailyTokenStatusAt(ctx, "missing", now); err != nil || status != nil {
+		t.Errorf("missing key = %+v, %v, want nil", status, err)
+	}
+}
+
+func TestDailyTokenStatus_WeightedCacheHeavyKey(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = nil
+
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+	dailyKey := PrefixUsageDaily + "key-1:" + getDateStringInTimezone(now)
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "k", "limit": "1000"}
+	hook.hashes[dailyKey] = map[string]string{
+		"inputTokens": "100", "outputTokens": "100", "cacheCreateTokens": "0", "cacheReadTokens": "2000", "allTokens": "2200",
+	}
+
+	// 默认权重：按 allTokens 计数，缓存读取占满限额
+	status, err := c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getDailyTokenStatusAt() error = %v", err)
+	}
+	if status.Weighted || status.Used != 2200 || status.RawUsed != 2200 || !status.Exceeded || status.Remaining != 0 {
+		t.Errorf("unweighted status = %+v, want used 2200 exceeded", status)
+	}
+
+	// 缓存读取按 0.1 计数后未超限，原始用量不变
+	config.Cfg = &config.Config{System: config.SystemConfig{DailyTokenWeights: map[string]float64{"cacheRead": 0.1}}}
+	status, err = c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if err != nil {
+		t.Fatalf("getDailyTokenStatusAt() weighted error = %v", err)
+	}
+	if !status.Weighted || status.Used != 400 || status.RawUsed != 2200 || status.Exceeded || status.Remaining != 600 {
+		t.Errorf("weighted status = %+v, want used 400 raw 2200 remaining 600", status)
+	}
+	if got := hook.hashes[dailyKey]["allTokens"]; got != "2200" {
+		t.Errorf("daily usage allTokens = %q, want untouched", got)
+	}
+
+	// 重置后按各类 Token 的基线扣除
+	if err := c.resetDailyTokensAt(ctx, "key-1", now); err != nil {
+		t.Fatalf("resetDailyTokensAt() error = %v", err)
+	}
+	hook.hashes[dailyKey]["outputTokens"] = "150"
+	hook.hashes[dailyKey]["cacheReadTokens"] = "3000"
+	hook.hashes[dailyKey]["allTokens"] = "3250"
+	status, _ = c.getDailyTokenStatusAt(ctx, "key-1", now)
+	if status.Used != 150 || status.RawUsed != 1050 {
+		t.Errorf("weighted status after reset = %+v, want used 150 raw 1050", status)
+	}
+}
@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 调试采样限制
+const (
+	// MaxDebugCaptures 每个 API Key 保留的最大采样条数
+	MaxDebugCaptures = 20
+	// MaxDebugCaptureCount 单次允许设置的最大采样次数
+	MaxDebugCaptureCount = 100
+	// MaxDebugCaptureBodyBytes 请求体最大保留字节数，超出时只记录大小
+	MaxDebugCaptureBodyBytes = 64 * 1024
+	// debugRedacted 脱敏占位符
+	debugRedacted = "[REDACTED]"
+)
+
+// debugSecretHeaders 需要脱敏的请求/响应头（小写）
+var debugSecretHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"x-goog-api-key":      true,
+	"api-key":             true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-crs-debug-token":   true,
+}
+
+// debugSecretFields 需要脱敏的请求体字段（小写并去除 _ 与 -）
+var debugSecretFields = map[string]bool{
+	"apikey":        true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"clientsecret":  true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"idtoken":       true,
+	"sessionkey":    true,
+	"privatekey":    true,
+}
+
+// DebugCapture 单次请求/响应采样
+type DebugCapture struct {
+	CapturedAt      time.Time         `json:"capturedAt"`
+	RequestID       string            `json:"requestId,omitempty"`
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Model           string            `json:"model,omitempty"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody     json.RawMessage   `json:"requestBody,omitempty"`
+	RequestBodySize int               `json:"requestBodySize"`
+	BodyOmitted     string            `json:"bodyOmitted,omitempty"` // 请求体未保留的原因
+	StatusCode      int               `json:"statusCode"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseSize    int               `json:"responseSize"`
+	DurationMs      int64             `json:"durationMs"`
+}
+
+// DebugCaptureEnabled 是否启用调试采样（DEBUG_CAPTURE_ENABLED，默认关闭）
+func DebugCaptureEnabled() bool {
+	return config.Cfg != nil && config.Cfg.Security.DebugCaptureEnabled
+}
+
+// debugCaptureKey 调试采样列表的 key
+func debugCaptureKey(keyID string) string {
+	return PrefixAPIKeyDebug + keyID
+}
+
+// RedactDebugHeaders 脱敏请求/响应头
+func RedactDebugHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if debugSecretHeaders[strings.ToLower(name)] {
+			value = debugRedacted
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// RedactDebugBody 脱敏 JSON 请求体中的敏感字段（非 JSON 返回错误）
+func RedactDebugBody(body []byte) (json.RawMessage, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactDebugValue(data))
+}
+
+// redactDebugValue 递归脱敏
+func redactDebugValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range val {
+			normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(field))
+			if debugSecretFields[normalized] {
+				val[field] = debugRedacted
+				continue
+			}
+			val[field] = redactDebugValue(fieldValue)
+		}
+		return val
+	case []interface{}:
+		for i := range val {
+			val[i] = redactDebugValue(val[i])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// NewDebugCapture 创建脱敏后的采样记录
+func NewDebugCapture(method, path string, requestHeaders map[string]string, body []byte) *DebugCapture {
+	capture := &DebugCapture{
+		CapturedAt:      time.Now(),
+		Method:          method,
+		Path:            path,
+		RequestHeaders:  RedactDebugHeaders(requestHeaders),
+		RequestBodySize: len(body),
+	}
+
+	switch {
+	case len(body) == 0:
+	case len(body) > MaxDebugCaptureBodyBytes:
+		capture.BodyOmitted = "too_large"
+	default:
+		redacted, err := RedactDebugBody(body)
+		if err != nil {
+			capture.BodyOmitted = "not_json"
+		} else {
+			capture.RequestBody = redacted
+		}
+	}
+
+	return capture
+}
+
+// SetDebugCaptureCount 设置 API Key 剩余的采样次数（0 表示关闭）
+func (c *Client) SetDebugCaptureCount(ctx context.Context, keyID string, count int) error {
+	if count < 0 || count > MaxDebugCaptureCount {
+		return fmt.Errorf("debug capture count must be between 0 and %d", MaxDebugCaptureCount)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		err = client.HDel(ctx, redisKey, "debugCaptureCount").Err()
+	} else {
+		err = client.HSet(ctx, redisKey, "debugCaptureCount", count).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set debug capture count: %w", err)
+	}
+	return nil
+}
+
+// RecordDebugCapture 消耗一次采样次数并记录采样，次数已用完时返回 false
+// 调用方应仅在 APIKey.DebugCaptureCount > 0 时调用，采样需事先脱敏（见 NewDebugCapture）
+func (c *Client) RecordDebugCapture(ctx context.Context, keyID string, capture *DebugCapture) (bool, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return false, err
+	}
+
+	redisKey, err := resolveAPIKeyRedisKey(ctx, client, keyID)
+	if err != nil {
+		return false, err
+	}
+
+	// 先扣减次数，并发请求超额扣减时回退，保证不超过设定次数
+	remaining, err := client.HIncrBy(ctx, redisKey, "debugCaptureCount", -1).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to decrement debug capture count: %w", err)
+	}
+	if remaining <= 0 {
+		client.HDel(ctx, redisKey, "debugCaptureCount")
+	}
+	if remaining < 0 {
+		return false, nil
+	}
+
+	data, err := json.Marshal(capture)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal debug capture: %w", err)
+	}
+
+	listKey := debugCaptureKey(keyID)
+	pipe := client.Pipeline()
+	pipe.LPush(ctx, listKey, string(data))
+	pipe.LTrim(ctx, listKey, 0, MaxDebugCaptures-1)
+	pipe.Expire(ctx, listKey, TTLAPIKeyDebug)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to record debug capture: %w", err)
+	}
+
+	logger.Debug("Recorded debug capture",
+		zap.String("apiKeyId", keyID),
+		zap.Int64("remaining", remaining))
+	return true, nil
+}
+
+// GetDebugCaptures 获取 API Key 的调试采样（最新的在前）
+func (c *Client) GetDebugCaptures(ctx context.Context, keyID string) ([]DebugCapture, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := client.LRange(ctx, debugCaptureKey(keyID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debug captures: %w", err)
+	}
+
+	captures := make([]DebugCapture, 0, len(values))
+	for _, value := range values {
+		var capture DebugCapture
+		if err := json.Unmarshal([]byte(value), &capture); err != nil {
+			continue
+		}
+		captures = append(captures, capture)
+	}
+	return captures, nil
+}
+
+// ClearDebugCaptures 清空 API Key 的调试采样
+func (c *Client) ClearDebugCaptures(ctx context.Context, keyID string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Del(ctx, debugCaptureKey(keyID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear debug captures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRecordDebugCapture_DecrementsAndStopsAtZero(t *testing.T) {
+	hook := newMemoryRedisHook()
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "customer"}
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if err := c.SetDebugCaptureCount(ctx, "key-1", 2); err != nil {
+		t.Fatalf("SetDebugCaptureCount() error = %v", err)
+	}
+
+	for i, want := range []bool{true, true, false} {
+		captured, err := c.RecordDebugCapture(ctx, "key-1", NewDebugCapture("POST", "/v1/messages", nil, []byte(`{"model":"claude"}`)))
+		if err != nil {
+			t.Fatalf("RecordDebugCapture() #%d error = %v", i+1, err)
+		}
+		if captured != want {
+			t.Errorf("RecordDebugCapture() #%d = %v, want %v", i+1, captured, want)
+		}
+	}
+
+	if _, ok := hook.hashes[PrefixAPIKey+"key-1"]["debugCaptureCount"]; ok {
+		t.Errorf("debugCaptureCount should be removed once exhausted, got %q", hook.hashes[PrefixAPIKey+"key-1"]["debugCaptureCount"])
+	}
+
+	captures, err := c.GetDebugCaptures(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetDebugCaptures() error = %v", err)
+	}
+	if len(captures) != 2 {
+		t.Fatalf("len(captures) = %d, want 2", len(captures))
+	}
+
+	if err := c.ClearDebugCaptures(ctx, "key-1"); err != nil {
+		t.Fatalf("ClearDebugCaptures() error = %v", err)
+	}
+	if captures, _ := c.GetDebugCaptures(ctx, "key-1"); len(captures) != 0 {
+		t.Errorf("captures after clear = %d, want 0", len(captures))
+	}
+}
+
+func TestNewDebugCapture_RedactsSecrets(t *testing.T) {
+	headers := map[string]string{
+		"Authorization": "Bearer cr_secret-key",
+		"X-Api-Key":     "cr_secret-key",
+		"Content-Type":  "application/json",
+	}
+	body := []byte(`{
+		"model": "claude-sonnet-4",
+		"metadata": {"api_key": "sk-nested-secret", "user_id": "u-1"},
+		"messages": [{"role": "user", "content": "hello", "accessToken": "tok-secret"}]
+	}`)
+
+	capture := NewDebugCapture("POST", "/v1/messages", headers, body)
+
+	if capture.RequestHeaders["Authorization"] != debugRedacted || capture.RequestHeaders["X-Api-Key"] != debugRedacted {
+		t.Errorf("secret headers not redacted: %v", capture.RequestHeaders)
+	}
+	if capture.RequestHeaders["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q, want preserved", capture.RequestHeaders["Content-Type"])
+	}
+
+	redacted := string(capture.RequestBody)
+	for _, secret := range []string{"sk-nested-secret", "tok-secret"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("request body still contains %q: %s", secret, redacted)
+		}
+	}
+	for _, kept := range []string{"claude-sonnet-4", "hello", "u-1"} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("request body lost %q: %s", kept, redacted)
+		}
+	}
+
+	if got := NewDebugCapture("POST", "/v1/messages", nil, []byte("not json")); got.RequestBody != nil || got.BodyOmitted != "not_json" {
+		t.Errorf("non-JSON body = %s (omitted %q), want omitted", got.RequestBody, got.BodyOmitted)
+	}
+}
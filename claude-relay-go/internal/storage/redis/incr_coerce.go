@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 哈希字段自增：字段现值无法自增（非数值或溢出）时先置 0 再自增
+// 返回 {自增后的值, 是否置 0}
+const luaHashIncrCoerce = `
+local key = KEYS[1]
+local field = ARGV[1]
+local incr = ARGV[2]
+local op = ARGV[3]
+
+local ok, res = pcall(redis.call, op, key, field, incr)
+if ok then
+    return {tostring(res), 0}
+end
+
+redis.call('HSET', key, field, 0)
+return {tostring(redis.call(op, key, field, incr)), 1}
+`
+
+// isCoercibleIncrError 自增失败是否由字段现值损坏导致（如 Node.js 写入了非数值）
+func isCoercibleIncrError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "not an integer") ||
+		strings.Contains(msg, "not a float") ||
+		strings.Contains(msg, "not a valid float") ||
+		strings.Contains(msg, "would overflow")
+}
+
+// execIncrPipeline 执行统计自增管道
+// 管道中各命令独立执行，单个 HINCRBY/HINCRBYFLOAT 因字段现值损坏失败时将该字段置 0 后重新自增并记录警告，
+// 避免一个损坏字段使整批计数被当作失败（调用方重试会导致其余字段重复计数）；
+// 其余因 Redis 命令错误失败的命令单独重试一次，仍失败时返回 *PartialPipelineError
+func execIncrPipeline(ctx context.Context, client *goredis.Client, pipe goredis.Pipeliner) error {
+	cmds, err := pipe.Exec(ctx)
+	if err == nil {
+		return nil
+	}
+
+	var failed []goredis.Cmder
+	for _, cmd := range cmds {
+		cmdErr := cmd.Err()
+		if cmdErr == nil || cmdErr == goredis.Nil {
+			continue
+		}
+		if !isCoercibleIncrError(cmdErr) || !coerceHashIncr(ctx, client, cmd) {
+			failed = append(failed, cmd)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if failed = retryFailedCommands(ctx, client, failed); len(failed) == 0 {
+		return nil
+	}
+	return newPartialPipelineError(len(cmds), failed)
+}
+
+// coerceHashIncr 重新执行失败的哈希字段自增，成功时更新命令结果
+func coerceHashIncr(ctx context.Context, client *goredis.Client, cmd goredis.Cmder) bool {
+	op := strings.ToUpper(cmd.Name())
+	args := cmd.Args()
+	if (op != "HINCRBY" && op != "HINCRBYFLOAT") || len(args) != 4 {
+		return false
+	}
+	key, field, incr := fmt.Sprint(args[1]), fmt.Sprint(args[2]), fmt.Sprint(args[3])
+
+	result, err := client.Eval(ctx, luaHashIncrCoerce, []string{key}, field, incr, op).Slice()
+	if err != nil || len(result) != 2 {
+		logger.Error("Failed to coerce corrupted usage field",
+			zap.String("key", key),
+			zap.String("field", field),
+			zap.Error(err))
+		return false
+	}
+
+	value, _ := result[0].(string)
+	if coerced, _ := result[1].(int64); coerced == 1 {
+		logger.Warn("Coerced corrupted usage field to 0 before increment",
+			zap.String("key", key),
+			zap.String("field", field),
+			zap.String("increment", incr),
+			zap.NamedError("originalError", cmd.Err()))
+	}
+
+	switch c := cmd.(type) {
+	case *goredis.IntCmd:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		c.SetVal(n)
+		c.SetErr(nil)
+	case *goredis.FloatCmd:
+		f, _ := strconv.ParseFloat(value, 64)
+		c.SetVal(f)
+		c.SetErr(nil)
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIncrementTokenUsage_CoercesCorruptedField(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	usageKey := PrefixUsage + "key-1"
+	hook.hashes[usageKey] = map[string]string{
+		"totalInputTokens": "not-a-number", // 被其他写入方损坏的字段
+		"totalRequests":    "5",
+	}
+
+	params := TokenUsageParams{KeyID: "key-1", Model: "claude-sonnet-4", InputTokens: 100, OutputTokens: 20}
+	if err := c.IncrementTokenUsage(ctx, params); err != nil {
+		t.Fatalf("IncrementTokenUsage() error = %v", err)
+	}
+
+	if got := hook.hashes[usageKey]["totalInputTokens"]; got != "100" {
+		t.Errorf("totalInputTokens = %q, want corrupted value coerced to 0 then incremented to 100", got)
+	}
+	if got := hook.hashes[usageKey]["totalRequests"]; got != "6" {
+		t.Errorf("totalRequests = %q, want 6", got)
+	}
+	if got := hook.hashes[usageKey]["totalOutputTokens"]; got != "20" {
+		t.Errorf("totalOutputTokens = %q, want 20", got)
+	}
+}
+
+func TestIncrementAccountCost_CoercesCorruptedFloatField(t *testing.T) {
+	useCostStorageMode(t, CostStorageFloat)
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes["account_usage:acct-1"] = map[string]string{"totalCost": "1.2.3"}
+
+	if err := c.IncrementAccountCost(ctx, "acct-1", 0.5); err != nil {
+		t.Fatalf("IncrementAccountCost() error = %v", err)
+	}
+	if got := hook.hashes["account_usage:acct-1"]["totalCost"]; got != "0.5" {
+		t.Errorf("totalCost = %q, want 0.5", got)
+	}
+}
+
+func TestIncrementTokenUsageBatch_CorruptedFieldDoesNotFailEntries(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	hook.hashes[PrefixUsage+"key-1"] = map[string]string{"totalRequests": "{bad}"}
+
+	results, err := c.IncrementTokenUsageBatch(ctx, []UsageBatchEntry{
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-1", Model: "m", InputTokens: 1}, IdempotencyKey: "idem-1"},
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-2", Model: "m", InputTokens: 1}},
+	})
+	if err != nil {
+		t.Fatalf("IncrementTokenUsageBatch() error = %v", err)
+	}
+	for _, r := range results {
+		if !r.Success || r.Error != "" {
+			t.Errorf("result %d = %+v, want success", r.Index, r)
+		}
+	}
+	if got := hook.hashes[PrefixUsage+"key-1"]["totalRequests"]; got != "1" {
+		t.Errorf("totalRequests = %q, want 1", got)
+	}
+	// 幂等键保留，重试不会重复计数
+	if _, ok := hook.strings[PrefixUsageIdempotency+"idem-1"]; !ok {
+		t.Error("idempotency key should be kept after coercion")
+	}
+}
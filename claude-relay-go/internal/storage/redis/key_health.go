@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// API Key 健康分组成项
+const (
+	KeyHealthCost        = "cost"        // 今日成本 / 每日成本限制
+	KeyHealthRateLimit   = "rateLimit"   // 速率限制窗口费用 / 窗口费用限制
+	KeyHealthConcurrency = "concurrency" // 当前并发 / 并发限制
+	KeyHealthExpiry      = "expiry"      // 距过期时间 / KeyHealthExpiryHorizon
+)
+
+// KeyHealthExpiryHorizon 距过期超过该时长时过期项为满分
+const KeyHealthExpiryHorizon = 7 * 24 * time.Hour
+
+// defaultKeyHealthWeights 默认权重：成本 40、速率限制 25、并发 20、过期 15
+var defaultKeyHealthWeights = map[string]float64{
+	KeyHealthCost:        40,
+	KeyHealthRateLimit:   25,
+	KeyHealthConcurrency: 20,
+	KeyHealthExpiry:      15,
+}
+
+// keyHealthComponentOrder 组成项输出顺序
+var keyHealthComponentOrder = []string{KeyHealthCost, KeyHealthRateLimit, KeyHealthConcurrency, KeyHealthExpiry}
+
+// KeyHealthComponent 健康分组成项
+type KeyHealthComponent struct {
+	Name    string  `json:"name"`
+	Weight  float64 `json:"weight"`
+	Usage   float64 `json:"usage"`   // 已用比例（0-1，未设置限制时为 0）
+	Limited bool    `json:"limited"` // 是否设置了对应限制
+	Score   float64 `json:"score"`   // 余量比例（0-1）
+}
+
+// KeyHealth API Key 健康分（0-100，越高越健康）
+// 各组成项的余量比例按权重加权平均后乘以 100；未设置限制的项视为满分
+type KeyHealth struct {
+	KeyID      string               `json:"keyId"`
+	Score      int                  `json:"score"`
+	Components []KeyHealthComponent `json:"components"`
+
+	// 激活模式 Key 激活后的过期时间与剩余秒数（已过期为 0；非激活模式或尚未激活时为 null）
+	ActivationExpiresAt *time.Time `json:"activationExpiresAt"`
+	ActivationRemaining *int64     `json:"activationRemaining"`
+}
+
+// keyHealthInputs 计算健康分所需的用量
+type keyHealthInputs struct {
+	dailyCost      float64
+	windowCost     float64
+	concurrency    int64
+	untilExpiry    time.Duration
+	hasExpiry      bool
+	dailyCostLimit float64
+	windowLimit    float64
+	concurrencyMax int
+}
+
+// KeyHealthWeights 获取健康分各项权重（配置覆盖默认值，负数视为 0）
+func KeyHealthWeights() map[string]float64 {
+	weights := make(map[string]float64, len(defaultKeyHealthWeights))
+	for name, weight := range defaultKeyHealthWeights {
+		weights[name] = weight
+	}
+	if config.Cfg != nil {
+		for name, weight := range config.Cfg.System.KeyHealthWeights {
+			if _, ok := weights[name]; !ok {
+				continue
+			}
+			weights[name] = math.Max(weight, 0)
+		}
+	}
+	return weights
+}
+
+// GetKeyHealthScore 计算 API Key 健康分，key 不存在时返回 nil
+func (c *Client) GetKeyHealthScore(ctx context.Context, keyID string) (*KeyHealth, error) {
+	return c.getKeyHealthScoreAt(ctx, keyID, time.Now())
+}
+
+// getKeyHealthScoreAt 按指定时间计算健康分
+func (c *Client) getKeyHealthScoreAt(ctx context.Context, keyID string, now time.Time) (*KeyHealth, error) {
+	key, err := c.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	in := keyHealthInputs{
+		dailyCostLimit: key.DailyCostLimit,
+		windowLimit:    key.RateLimitCost,
+		concurrencyMax: key.ConcurrentLimit,
+	}
+	if key.DailyCostLimit > 0 {
+		if in.dailyCost, err = c.getDailyCostAt(ctx, keyID, now); err != nil {
+			return nil, err
+		}
+	}
+	if key.RateLimitCost > 0 {
+		if in.windowCost, err = c.GetRateLimitWindowCost(ctx, keyID); err != nil {
+			return nil, err
+		}
+	}
+	if key.ConcurrentLimit > 0 {
+		if in.concurrency, err = c.GetConcurrency(ctx, keyID); err != nil {
+			return nil, err
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.IsZero() {
+		in.hasExpiry = true
+		in.untilExpiry = key.ExpiresAt.Sub(now)
+	}
+
+	health := computeKeyHealth(in, KeyHealthWeights())
+	health.KeyID = keyID
+	health.ActivationExpiresAt, health.ActivationRemaining = activationRemaining(key, now)
+	return health, nil
+}
+
+// computeKeyHealth 按权重合成健康分
+func computeKeyHealth(in keyHealthInputs, weights map[string]float64) *KeyHealth {
+	usageRatio := func(used, limit float64) (float64, bool) {
+		if limit <= 0 {
+			return 0, false
+		}
+		return math.Min(math.Max(used/limit, 0), 1), true
+	}
+
+	usage := make(map[string]float64, len(keyHealthComponentOrder))
+	limited := make(map[string]bool, len(keyHealthComponentOrder))
+	usage[KeyHealthCost], limited[KeyHealthCost] = usageRatio(in.dailyCost, in.dailyCostLimit)
+	usage[KeyHealthRateLimit], limited[KeyHealthRateLimit] = usageRatio(in.windowCost, in.windowLimit)
+	usage[KeyHealthConcurrency], limited[KeyHealthConcurrency] = usageRatio(float64(in.concurrency), float64(in.concurrencyMax))
+	if in.hasExpiry {
+		// 距过期越近已用比例越高，超过 KeyHealthExpiryHorizon 为 0
+		usage[KeyHealthExpiry], limited[KeyHealthExpiry] = 1-math.Min(math.Max(float64(in.untilExpiry)/float64(KeyHealthExpiryHorizon), 0), 1), true
+	}
+
+	health := &KeyHealth{Components: make([]KeyHealthComponent, 0, len(keyHealthComponentOrder))}
+	var weighted, total float64
+	for _, name := range keyHealthComponentOrder {
+		component := KeyHealthComponent{
+			Name:    name,
+			Weight:  weights[name],
+			Usage:   usage[name],
+			Limited: limited[name],
+			Score:   1 - usage[name],
+		}
+		health.Components = append(health.Components, component)
+		weighted += component.Weight * component.Score
+		total += component.Weight
+	}
+
+	health.Score = 100
+	if total > 0 {
+		health.Score = int(math.Round(weighted / total * 100))
+	}
+	return health
+}
+
+// activationRemaining 计算激活模式 Key 的激活过期时间与剩余秒数（不适用时均为 nil）
+func activationRemaining(key *APIKey, now time.Time) (*time.Time, *int64) {
+	expiresAt := key.ActivationExpiresAt()
+	if expiresAt == nil {
+		return nil, nil
+	}
+	remaining := int64(math.Max(expiresAt.Sub(now).Seconds(), 0))
+	return expiresAt, &remaining
+}
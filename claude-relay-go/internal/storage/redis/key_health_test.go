@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+// seedKeyHealth 写入带各项限制的 API Key 及其用量
+func seedKeyHealth(hook *memoryRedisHook, keyID string, now time.Time, dailyCost, windowCost string, concurrency int, expiresAt time.Time) {
+	hook.hashes[PrefixAPIKey+keyID] = map[string]string{
+		"id":              keyID,
+		"name":            keyID,
+		"dailyCostLimit":  "100",
+		"rateLimitWindow": "60",
+		"rateLimitCost":   "10",
+		"concurrentLimit": "10",
+		"expiresAt":       expiresAt.Format(time.RFC3339),
+	}
+	hook.hashes[fmt.Sprintf("usage:cost:daily:%s:%s", keyID, getDateStringInTimezone(now))] = map[string]string{"totalCost": dailyCost}
+	hook.strings["rate_limit:cost:"+keyID] = windowCost
+
+	slots := make(map[string]float64, concurrency)
+	leaseUntil := float64(time.Now().Add(time.Hour).UnixMilli())
+	for i := 0; i < concurrency; i++ {
+		slots[fmt.Sprintf("req-%d", i)] = leaseUntil
+	}
+	hook.zsets[PrefixConcurrency+keyID] = slots
+}
+
+func TestKeyHealthScore_HealthyAndNearLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+
+	// 余量各 90%，30 天后过期：(40*0.9 + 25*0.9 + 20*0.9 + 15*1) = 91.5
+	seedKeyHealth(hook, "healthy", now, "10", "1", 1, now.Add(30*24*time.Hour))
+	health, err := c.getKeyHealthScoreAt(ctx, "healthy", now)
+	if err != nil {
+		t.Fatalf("getKeyHealthScoreAt() error = %v", err)
+	}
+	if health.Score != 92 {
+		t.Errorf("healthy score = %d, want 92 (components %+v)", health.Score, health.Components)
+	}
+
+	// 成本与窗口费用余量 5%，并发余量 10%，12 小时后过期：2 + 1.25 + 2 + 15*(0.5/7) ≈ 6.32
+	seedKeyHealth(hook, "near-limit", now, "95", "9.5", 9, now.Add(12*time.Hour))
+	health, err = c.getKeyHealthScoreAt(ctx, "near-limit", now)
+	if err != nil {
+		t.Fatalf("getKeyHealthScoreAt() error = %v", err)
+	}
+	if health.Score != 6 {
+		t.Errorf("near-limit score = %d, want 6 (components %+v)", health.Score, health.Components)
+	}
+
+	if health, err := c.getKeyHealthScoreAt(ctx, "missing", now); err != nil || health != nil {
+		t.Errorf("missing key = %+v, %v, want nil", health, err)
+	}
+}
+
+func TestComputeKeyHealth_UnlimitedAndWeights(t *testing.T) {
+	// 未设置任何限制的 key 为满分
+	if health := computeKeyHealth(keyHealthInputs{}, KeyHealthWeights()); health.Score != 100 {
+		t.Errorf("unlimited score = %d, want 100", health.Score)
+	}
+
+	// 已过期的 key 过期项为 0，且配置的权重覆盖默认值
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{}
+	config.Cfg.System.KeyHealthWeights = map[string]float64{KeyHealthExpiry: 100, KeyHealthCost: 0, KeyHealthRateLimit: 0, KeyHealthConcurrency: 0, "unknown": 50}
+
+	health := computeKeyHealth(keyHealthInputs{hasExpiry: true, untilExpiry: -time.Hour}, KeyHealthWeights())
+	if health.Score != 0 {
+		t.Errorf("expired score = %d, want 0 (components %+v)", health.Score, health.Components)
+	}
+}
+
+func TestKeyHealthScore_ActivationRemaining(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	seed := func(keyID string, fields map[string]string) {
+		data := map[string]string{"id": keyID, "name": keyID}
+		for k, v := range fields {
+			data[k] = v
+		}
+		hook.hashes[PrefixAPIKey+keyID] = data
+	}
+	seed("days", map[string]string{
+		"expirationMode": "activation",
+		"isActivated":    "true",
+		"activatedAt":    now.Add(-36 * time.Hour).Format(time.RFC3339),
+		"activationDays": "7",
+		"activationUnit": "days",
+	})
+	seed("hours", map[string]string{
+		"expirationMode": "activation",
+		"isActivated":    "true",
+		"activatedAt":    now.Add(-90 * time.Minute).Format(time.RFC3339),
+		"activationDays": "12",
+		"activationUnit": "hours",
+	})
+	// 管理员修改过激活后写入的 expiresAt
+	seed("edited", map[string]string{
+		"expirationMode": "activation",
+		"isActivated":    "true",
+		"activatedAt":    now.Add(-36 * time.Hour).Format(time.RFC3339),
+		"activationDays": "7",
+		"activationUnit": "days",
+		"expiresAt":      now.Add(2 * time.Hour).Format(time.RFC3339),
+	})
+	seed("pending", map[string]string{"expirationMode": "activation", "activationDays": "7"})
+	seed("fixed", map[string]string{"expiresAt": now.Add(time.Hour).Format(time.RFC3339)})
+
+	tests := []struct {
+		keyID     string
+		expiresAt time.Time
+		remaining time.Duration
+	}{
+		{"days", now.Add(-36*time.Hour).AddDate(0, 0, 7), 5*24*time.Hour + 12*time.Hour},
+		{"hours", now.Add(-90 * time.Minute).Add(12 * time.Hour), 10*time.Hour + 30*time.Minute},
+		{"edited", now.Add(2 * time.Hour), 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		health, err := c.getKeyHealthScoreAt(ctx, tt.keyID, now)
+		if err != nil {
+			t.Fatalf("%s: getKeyHealthScoreAt() error = %v", tt.keyID, err)
+		}
+		if health.ActivationExpiresAt == nil || !health.ActivationExpiresAt.Equal(tt.expiresAt) {
+			t.Errorf("%s: activationExpiresAt = %v, want %v", tt.keyID, health.ActivationExpiresAt, tt.expiresAt)
+		}
+		if health.ActivationRemaining == nil || *health.ActivationRemaining != int64(tt.remaining.Seconds()) {
+			t.Errorf("%s: activationRemaining = %v, want %d", tt.keyID, health.ActivationRemaining, int64(tt.remaining.Seconds()))
+		}
+	}
+
+	// 未激活与固定过期的 Key 返回 null
+	for _, keyID := range []string{"pending", "fixed"} {
+		health, err := c.getKeyHealthScoreAt(ctx, keyID, now)
+		if err != nil {
+			t.Fatalf("%s: getKeyHealthScoreAt() error = %v", keyID, err)
+		}
+		if health.ActivationExpiresAt != nil || health.ActivationRemaining != nil {
+			t.Errorf("%s: activation = %v/%v, want nil", keyID, health.ActivationExpiresAt, health.ActivationRemaining)
+		}
+	}
+}
@@ -8,6 +8,12 @@ const (
 	PrefixAPIKey        = "apikey:"
 	PrefixAPIKeyHashMap = "apikey:hash_map"
 	PrefixAPIKeyLegacy  = "api_key:" // 历史兼容
+	// API Key 配置快照（用于原子替换后的回滚）
+	PrefixAPIKeyConfigSnapshot = "apikey_config_snapshot:"
+	// API Key 调试采样（请求/响应记录列表，不使用 apikey: 前缀，避免被 Node 的 apikey:* 扫描当作 API Key）
+	PrefixAPIKeyDebug = "apikey_debug:"
+	// API Key 账户亲和（值为上次选中的账户 ID，不使用 apikey: 前缀，避免被 Node 的 apikey:* 扫描当作 API Key）
+	PrefixAPIKeyAffinity = "apikey_affinity:"
 
 	// 使用统计
 	PrefixUsage        = "usage:"
@@ -19,6 +25,10 @@ const (
 	// 账户使用统计
 	PrefixAccountUsage = "account_usage:"
 
+	// 账户错误统计（窗口内错误为有序集合，累计错误数为 Hash，字段为 type:id）
+	PrefixAccountErrors   = "account_errors:"
+	KeyAccountErrorTotals = "account_errors:totals"
+
 	// 账户数据
 	PrefixClaudeAccount          = "claude:account:"
 	PrefixClaudeConsoleAccount   = "claude_console:account:"
@@ -33,23 +43,64 @@ const (
 
 	// 并发控制
 	PrefixConcurrency = "concurrency:"
+	// 并发数计数器（由 Go 租约脚本维护，Node 回退路径不更新，定时按有序集合校准；不在 concurrency:* 扫描范围内）
+	PrefixConcurrencyCount = "concurrency_count:"
+	// 全局并发租约（成员为 apiKeyID:requestID，不在 concurrency:* 扫描范围内）
+	KeyGlobalConcurrency = "global_concurrency"
+	// 并发请求元数据（哈希 concurrency_meta:{keyId}，字段为 requestId，值为 model|clientType|startMs，与租约同 TTL）
+	PrefixConcurrencyMeta = "concurrency_meta:"
+	// 在途请求成本意图（哈希 concurrency_intent:{keyId}:{requestId}，索引为有序集合，分数为租约过期时间）
+	PrefixConcurrencyIntent      = "concurrency_intent:"
+	PrefixConcurrencyIntentIndex = "concurrency_intents:"
+	// API Key 临时并发上限（JSON，带 TTL，过期后恢复静态上限）
+	PrefixConcurrencyBoost = "concurrency_boost:"
+	// 用户级默认限制（哈希 user_limit_defaults:{userId}，Key 未设置的限制继承该默认值）
+	PrefixUserLimitDefaults = "user_limit_defaults:"
 
 	// 并发请求排队
 	PrefixConcurrencyQueue      = "concurrency:queue:"
 	PrefixConcurrencyQueueStats = "concurrency:queue:stats:"
 	PrefixConcurrencyQueueWait  = "concurrency:queue:wait_times:"
+	// 按小时分桶的排队统计（不在 concurrency:queue:stats:* 扫描范围内，避免被当作 Key 统计重复累加）
+	PrefixConcurrencyQueueStatsHourly = "queue_stats_hourly:"
+
+	// 优先级排队（有序集合，不在 concurrency:* 扫描范围内）
+	PrefixPriorityQueue    = "priority_queue:"
+	KeyGlobalPriorityQueue = "priority_queue:global"
 
 	// 用户消息队列锁
 	PrefixUserMsgLock = "user_msg_queue_lock:"
 	PrefixUserMsgLast = "user_msg_queue_last:"
+	// 锁获取时间（毫秒时间戳）与等待者（有序集合，分数为最近一次尝试获取的时间）
+	PrefixUserMsgLockAt  = "user_msg_queue_lock_at:"
+	PrefixUserMsgWaiters = "user_msg_queue_waiters:"
 
 	// 会话
 	PrefixSession       = "session:"
 	PrefixStickySession = "sticky_session:"
 	PrefixOAuthSession  = "oauth_session:"
+	// 活跃粘性会话索引（有序集合，分数为会话过期时间，不在 sticky_session:* 扫描范围内）
+	KeyStickySessionIndex = "sticky_session_index"
 
 	// 系统
 	PrefixSystemMetrics = "system:metrics:minute:"
+	PrefixSelfTest      = "system:selftest:"
+
+	// 认证失败统计（按原因、分钟分桶）
+	PrefixAuthFailures  = "auth:failures:"
+	KeyAuthFailureCodes = "auth:failures:codes"
+
+	// 软限制预警统计（按天，字段为 keyID:limitType）
+	PrefixLimitWarnings = "limit_warnings:"
+
+	// 成本预警当天已通知的阈值（集合，按天）
+	PrefixCostAlerts = "usage:cost:alerts:"
+
+	// 批量使用量上报的幂等键（已处理的条目）
+	PrefixUsageIdempotency = "usage:idempotency:"
+
+	// 每日 Token 计数重置基线（重置时当天已用的 allTokens，按天）
+	PrefixDailyTokensBaseline = "usage:daily_tokens:baseline:"
 )
 
 // TTL 常量
@@ -61,6 +112,16 @@ const (
 	TTLQueueStats      = 7 * 24 * time.Hour   // 7天
 	TTLWaitTimeSamples = 24 * time.Hour       // 1天
 	TTLQueueBuffer     = 30 * time.Second     // 排队缓冲
+	TTLAuthFailures    = 25 * time.Hour       // 认证失败分钟桶
+	TTLLimitWarnings   = 7 * 24 * time.Hour   // 软限制预警统计
+	TTLCostAlerts      = 48 * time.Hour       // 成本预警已通知阈值（按天）
+
+	TTLUsageIdempotency    = 24 * time.Hour // 使用量上报幂等键
+	TTLDailyTokensBaseline = 48 * time.Hour // 每日 Token 计数重置基线
+
+	TTLAPIKeyConfigSnapshot = 24 * time.Hour     // 配置快照保留时间
+	TTLAPIKeyDebug          = 7 * 24 * time.Hour // 调试采样保留时间
+	TTLAPIKeyAffinity       = 24 * time.Hour     // 账户亲和（每次选中时续期）
 
 	TTLSessionDefault = 24 * time.Hour   // 默认会话 TTL
 	TTLOAuthSession   = 10 * time.Minute // OAuth 会话
@@ -70,4 +131,6 @@ const (
 const (
 	WaitTimeSamplesPerKey = 500  // 每 API Key 等待时间样本数
 	WaitTimeSamplesGlobal = 2000 // 全局等待时间样本数
+
+	DefaultWaitTimeSamplesLimit = 100 // 样本查询默认返回数
 )
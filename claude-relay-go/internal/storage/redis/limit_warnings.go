@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IncrLimitWarning 记录一次软限制预警（按天汇总）
+func (c *Client) IncrLimitWarning(ctx context.Context, keyID, limitType string) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key := PrefixLimitWarnings + getDateStringInTimezone(time.Now())
+	pipe := client.Pipeline()
+	pipe.HIncrBy(ctx, key, keyID+":"+limitType, 1)
+	pipe.Expire(ctx, key, TTLLimitWarnings)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record limit warning: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/catstream/claude-relay-go/internal/pkg/logger"
@@ -18,6 +19,8 @@ const (
 	DefaultLockRetryDelay = 100 * time.Millisecond
 	// DefaultLockMaxRetries 默认最大重试次数
 	DefaultLockMaxRetries = 50
+	// lockReleaseTimeout 释放锁的超时时间（独立于业务 ctx，确保 ctx 取消后仍能释放）
+	lockReleaseTimeout = 5 * time.Second
 )
 
 // Lua 脚本常量
@@ -51,6 +54,8 @@ end
 	luaUserMessageLockAcquire = `
 local lockKey = KEYS[1]
 local lastTimeKey = KEYS[2]
+local lockAtKey = KEYS[3]
+local waitersKey = KEYS[4]
 local requestId = ARGV[1]
 local lockTtl = tonumber(ARGV[2])
 local delayMs = tonumber(ARGV[3])
@@ -65,17 +70,23 @@ if currentLock == false then
     if lastTime then
         local elapsed = nowMs - tonumber(lastTime)
         if elapsed < delayMs then
-            -- 需要等待的毫秒数
+            -- 记录等待者，需要等待的毫秒数
+            redis.call('ZADD', waitersKey, nowMs, requestId)
+            redis.call('PEXPIRE', waitersKey, lockTtl)
             return {0, delayMs - elapsed}
         end
     end
 
-    -- 获取锁
+    -- 获取锁（同时记录获取时间，用于发现长时间未释放的锁）
     redis.call('SET', lockKey, requestId, 'PX', lockTtl)
+    redis.call('SET', lockAtKey, nowMs, 'PX', lockTtl)
+    redis.call('ZREM', waitersKey, requestId)
     return {1, 0}
 end
 
--- 锁被占用，返回等待
+-- 锁被占用，记录等待者并返回等待
+redis.call('ZADD', waitersKey, nowMs, requestId)
+redis.call('PEXPIRE', waitersKey, lockTtl)
 return {0, -1}
 `
 
@@ -83,6 +94,7 @@ return {0, -1}
 	luaUserMessageLockRelease = `
 local lockKey = KEYS[1]
 local lastTimeKey = KEYS[2]
+local lockAtKey = KEYS[3]
 local requestId = ARGV[1]
 local nowMs = ARGV[2]
 
@@ -93,7 +105,7 @@ if currentLock == requestId then
     redis.call('SET', lastTimeKey, nowMs, 'EX', 60)  -- 60秒后过期
 
     -- 删除锁
-    redis.call('DEL', lockKey)
+    redis.call('DEL', lockKey, lockAtKey)
     return 1
 end
 return 0
@@ -212,7 +224,12 @@ func (c *Client) ExtendLock(ctx context.Context, lockKey, token string, ttl time
 }
 
 // WithLock 在持有锁的情况下执行函数
+// 执行期间每 ttl/3 自动续期，结束后按令牌校验释放（不会误删他人持有的锁）
 func (c *Client) WithLock(ctx context.Context, lockKey string, ttl time.Duration, fn func() error) error {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
 	result, err := c.AcquireLock(ctx, lockKey, ttl)
 	if err != nil {
 		return err
@@ -222,27 +239,78 @@ func (c *Client) WithLock(ctx context.Context, lockKey string, ttl time.Duration
 		return fmt.Errorf("failed to acquire lock: %s", lockKey)
 	}
 
-	defer func() {
-		c.ReleaseLock(ctx, lockKey, result.Token)
-	}()
-
-	return fn()
+	return c.runWithLockRenewal(ctx, lockKey, result.Token, ttl, fn)
 }
 
-// WithLockRetry 在持有锁的情况下执行函数（带重试）
+// WithLockRetry 在持有锁的情况下执行函数（带重试，同样自动续期）
 func (c *Client) WithLockRetry(ctx context.Context, lockKey string, ttl time.Duration, maxRetries int, fn func() error) error {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
 	token, err := c.TryLockWithRetry(ctx, lockKey, ttl, maxRetries, DefaultLockRetryDelay)
 	if err != nil {
 		return err
 	}
 
+	return c.runWithLockRenewal(ctx, lockKey, token, ttl, fn)
+}
+
+// runWithLockRenewal 执行函数并在后台续期锁，结束后释放
+func (c *Client) runWithLockRenewal(ctx context.Context, lockKey, token string, ttl time.Duration, fn func() error) error {
+	renewCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.autoRenewLock(renewCtx, lockKey, token, ttl)
+	}()
+
 	defer func() {
-		c.ReleaseLock(ctx, lockKey, token)
+		// 先停止续期，再释放锁
+		cancel()
+		<-done
+
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), lockReleaseTimeout)
+		defer releaseCancel()
+		if _, err := c.ReleaseLock(releaseCtx, lockKey, token); err != nil {
+			logger.Warn("Failed to release lock", zap.String("key", lockKey), zap.Error(err))
+		}
 	}()
 
 	return fn()
 }
 
+// autoRenewLock 每 ttl/3 续期一次，直到 ctx 取消或锁已不再由当前令牌持有
+func (c *Client) autoRenewLock(ctx context.Context, lockKey, token string, ttl time.Duration) {
+	interval := ttl / 3
+	if interval <= 0 {
+		interval = ttl
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			extended, err := c.ExtendLock(ctx, lockKey, token, ttl)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Warn("Failed to renew lock", zap.String("key", lockKey), zap.Error(err))
+				continue
+			}
+			if !extended {
+				logger.Warn("Lock lost before renewal", zap.String("key", lockKey))
+				return
+			}
+		}
+	}
+}
+
 // ========== 账户锁定（与 Node.js 兼容）==========
 
 // SetAccountLock 设置账户锁（用于 Token 刷新等场景）
@@ -291,7 +359,8 @@ func (c *Client) AcquireUserMessageLock(ctx context.Context, accountID, requestI
 	lastTimeKey := PrefixUserMsgLast + accountID
 	nowMs := time.Now().UnixMilli() // 从 Go 传入时间，避免 Lua 使用 TIME 命令（Redis Cluster 兼容性）
 
-	result, err := client.Eval(ctx, luaUserMessageLockAcquire, []string{lockKey, lastTimeKey},
+	keys := []string{lockKey, lastTimeKey, PrefixUserMsgLockAt + accountID, PrefixUserMsgWaiters + accountID}
+	result, err := client.Eval(ctx, luaUserMessageLockAcquire, keys,
 		requestID, lockTTLMs, delayMs, nowMs).Result()
 	if err != nil {
 		return &UserMessageLockResult{
@@ -345,7 +414,8 @@ func (c *Client) ReleaseUserMessageLock(ctx context.Context, accountID, requestI
 	lastTimeKey := PrefixUserMsgLast + accountID
 	nowMs := time.Now().UnixMilli() // 从 Go 传入时间，避免 Lua 使用 TIME 命令
 
-	result, err := client.Eval(ctx, luaUserMessageLockRelease, []string{lockKey, lastTimeKey}, requestID, nowMs).Result()
+	keys := []string{lockKey, lastTimeKey, PrefixUserMsgLockAt + accountID}
+	result, err := client.Eval(ctx, luaUserMessageLockRelease, keys, requestID, nowMs).Result()
 	if err != nil {
 		return false, err
 	}
@@ -365,7 +435,7 @@ func (c *Client) ForceReleaseUserMessageLock(ctx context.Context, accountID stri
 	}
 
 	lockKey := PrefixUserMsgLock + accountID
-	_, err = client.Del(ctx, lockKey).Result()
+	_, err = client.Del(ctx, lockKey, PrefixUserMsgLockAt+accountID).Result()
 	return err == nil, err
 }
 
@@ -436,3 +506,91 @@ func (c *Client) ScanUserMessageQueueLocks(ctx context.Context) ([]string, error
 
 	return accountIDs, nil
 }
+
+// userMsgWaiterWindow 等待者最近一次尝试在此时间内才计入排队深度（轮询会刷新时间）
+const userMsgWaiterWindow = 30 * time.Second
+
+// UserMessageLockInfo 用户消息锁详情
+type UserMessageLockInfo struct {
+	AccountID  string     `json:"accountId"`
+	Holder     string     `json:"holder"`
+	TTLMs      int64      `json:"ttlMs"` // -1 表示未设置过期时间
+	AcquiredAt *time.Time `json:"acquiredAt,omitempty"`
+	HeldMs     int64      `json:"heldMs,omitempty"`
+	QueueDepth int64      `json:"queueDepth"`
+}
+
+// ListUserMessageLocks 列出当前持有的用户消息锁（持有者、剩余 TTL、持有时长与排队深度）
+func (c *Client) ListUserMessageLocks(ctx context.Context) ([]UserMessageLockInfo, error) {
+	return c.listUserMessageLocksAt(ctx, time.Now())
+}
+
+// listUserMessageLocksAt 按指定时间列出用户消息锁
+func (c *Client) listUserMessageLocksAt(ctx context.Context, now time.Time) ([]UserMessageLockInfo, error) {
+	accountIDs, err := c.ScanUserMessageQueueLocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user message locks: %w", err)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	minWaiterScore := fmt.Sprintf("%d", now.Add(-userMsgWaiterWindow).UnixMilli())
+	locks := make([]UserMessageLockInfo, 0, len(accountIDs))
+	for _, accountID := range accountIDs {
+		pipe := client.Pipeline()
+		holderCmd := pipe.Get(ctx, PrefixUserMsgLock+accountID)
+		ttlCmd := pipe.PTTL(ctx, PrefixUserMsgLock+accountID)
+		acquiredCmd := pipe.Get(ctx, PrefixUserMsgLockAt+accountID)
+		depthCmd := pipe.ZCount(ctx, PrefixUserMsgWaiters+accountID, minWaiterScore, "+inf")
+		pipe.Exec(ctx)
+
+		holder, err := holderCmd.Result()
+		if err != nil || holder == "" {
+			continue // 扫描后已释放
+		}
+
+		info := UserMessageLockInfo{
+			AccountID:  accountID,
+			Holder:     holder,
+			TTLMs:      -1,
+			QueueDepth: depthCmd.Val(),
+		}
+		if ttl, err := ttlCmd.Result(); err == nil && ttl > 0 {
+			info.TTLMs = ttl.Milliseconds()
+		}
+		if acquiredMs := parseInt64(acquiredCmd.Val()); acquiredMs > 0 {
+			acquiredAt := time.UnixMilli(acquiredMs)
+			info.AcquiredAt = &acquiredAt
+			info.HeldMs = now.Sub(acquiredAt).Milliseconds()
+		}
+		locks = append(locks, info)
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].AccountID < locks[j].AccountID })
+	return locks, nil
+}
+
+// GetStuckUserMessageLocks 获取可能已成为孤儿的用户消息锁
+func (c *Client) GetStuckUserMessageLocks(ctx context.Context, olderThan time.Duration) ([]UserMessageLockInfo, error) {
+	locks, err := c.ListUserMessageLocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterStuckUserMessageLocks(locks, olderThan), nil
+}
+
+// filterStuckUserMessageLocks 筛选持有超过 olderThan 或永不过期的锁（按持有时长降序）
+// 未记录获取时间的锁（如由其他实现获取）只在未设置过期时间时视为卡住
+func filterStuckUserMessageLocks(locks []UserMessageLockInfo, olderThan time.Duration) []UserMessageLockInfo {
+	stuck := make([]UserMessageLockInfo, 0)
+	for _, lock := range locks {
+		if lock.TTLMs < 0 || (lock.AcquiredAt != nil && lock.HeldMs >= olderThan.Milliseconds()) {
+			stuck = append(stuck, lock)
+		}
+	}
+	sort.SliceStable(stuck, func(i, j int) bool { return stuck[i].HeldMs > stuck[j].HeldMs })
+	return stuck
+}
@@ -0,0 +1,258 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockRedisHook 模拟带过期时间的 SET NX / GET / 锁脚本
+type lockRedisHook struct {
+	mu       sync.Mutex
+	values   map[string]string
+	expiries map[string]time.Time
+}
+
+func newLockRedisHook() *lockRedisHook {
+	return &lockRedisHook{
+		values:   make(map[string]string),
+		expiries: make(map[string]time.Time),
+	}
+}
+
+func (h *lockRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *lockRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// get 读取未过期的值（调用方需持有锁）
+func (h *lockRedisHook) get(key string) (string, bool) {
+	if exp, ok := h.expiries[key]; ok && time.Now().After(exp) {
+		delete(h.values, key)
+		delete(h.expiries, key)
+	}
+	val, ok := h.values[key]
+	return val, ok
+}
+
+// expire 手动让键立即过期
+func (h *lockRedisHook) expire(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.values, key)
+	delete(h.expiries, key)
+}
+
+// holder 返回当前持有者令牌
+func (h *lockRedisHook) holder(key string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	val, _ := h.get(key)
+	return val
+}
+
+func (h *lockRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		args := cmd.Args()
+		argString := func(i int) string { return fmt.Sprint(args[i]) }
+
+		switch strings.ToLower(cmd.Name()) {
+		case "set":
+			key, val := argString(1), argString(2)
+			var ttl time.Duration
+			nx := false
+			for i := 3; i < len(args); i++ {
+				switch strings.ToLower(argString(i)) {
+				case "nx":
+					nx = true
+				case "px":
+					ms, _ := strconv.ParseInt(argString(i+1), 10, 64)
+					ttl = time.Duration(ms) * time.Millisecond
+					i++
+				case "ex":
+					sec, _ := strconv.ParseInt(argString(i+1), 10, 64)
+					ttl = time.Duration(sec) * time.Second
+					i++
+				}
+			}
+			if _, exists := h.get(key); nx && exists {
+				cmd.(*redis.BoolCmd).SetVal(false)
+				return nil
+			}
+			h.values[key] = val
+			if ttl > 0 {
+				h.expiries[key] = time.Now().Add(ttl)
+			}
+			if boolCmd, ok := cmd.(*redis.BoolCmd); ok {
+				boolCmd.SetVal(true)
+			}
+		case "eval":
+			script, key, token := argString(1), argString(3), argString(4)
+			current, ok := h.get(key)
+			if !ok || current != token {
+				cmd.(*redis.Cmd).SetVal(int64(0))
+				return nil
+			}
+			switch script {
+			case luaLockRelease:
+				delete(h.values, key)
+				delete(h.expiries, key)
+			case luaLockExtend:
+				ms, _ := strconv.ParseInt(argString(5), 10, 64)
+				h.expiries[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			default:
+				return errors.New("unexpected script")
+			}
+			cmd.(*redis.Cmd).SetVal(int64(1))
+		default:
+			return errors.New("unexpected command: " + cmd.Name())
+		}
+		return nil
+	}
+}
+
+func TestWithLock_MutualExclusion(t *testing.T) {
+	hook := newLockRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	err := c.WithLock(ctx, "lock:test", time.Second, func() error {
+		// 持有锁期间，其他调用者无法获取同一把锁
+		innerErr := c.WithLock(ctx, "lock:test", time.Second, func() error {
+			t.Error("inner critical section should not run")
+			return nil
+		})
+		if innerErr == nil {
+			t.Error("expected second WithLock to fail while lock is held")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithLock() error = %v", err)
+	}
+
+	if holder := hook.holder("lock:test"); holder != "" {
+		t.Errorf("lock should be released after WithLock, holder = %q", holder)
+	}
+}
+
+func TestWithLock_AutoRenewalExtendsPastTTL(t *testing.T) {
+	hook := newLockRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	ttl := 150 * time.Millisecond
+	err := c.WithLock(ctx, "lock:renew", ttl, func() error {
+		// 运行时间远超原始 TTL
+		time.Sleep(3 * ttl)
+		if holder := hook.holder("lock:renew"); holder == "" {
+			t.Error("lock expired during critical section despite auto-renewal")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithLock() error = %v", err)
+	}
+}
+
+func TestReleaseLock_SafeAfterExpiry(t *testing.T) {
+	hook := newLockRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	first, err := c.AcquireLock(ctx, "lock:expiry", time.Second)
+	if err != nil || !first.Success {
+		t.Fatalf("AcquireLock() = %+v, %v", first, err)
+	}
+
+	// 锁过期后被其他调用者获取
+	hook.expire("lock:expiry")
+	second, err := c.AcquireLock(ctx, "lock:expiry", time.Second)
+	if err != nil || !second.Success {
+		t.Fatalf("second AcquireLock() = %+v, %v", second, err)
+	}
+
+	released, err := c.ReleaseLock(ctx, "lock:expiry", first.Token)
+	if err != nil {
+		t.Fatalf("ReleaseLock() error = %v", err)
+	}
+	if released {
+		t.Error("stale token must not release another holder's lock")
+	}
+	if holder := hook.holder("lock:expiry"); holder != second.Token {
+		t.Errorf("lock holder = %q, want %q", holder, second.Token)
+	}
+}
+
+func TestWithLock_ReturnsFnError(t *testing.T) {
+	c := newConnectedClientForTest(t, newLockRedisHook())
+	wantErr := errors.New("boom")
+
+	err := c.WithLock(context.Background(), "lock:err", time.Second, func() error {
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("WithLock() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestListUserMessageLocks_AndStuckFilter(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+
+	// acct-fresh：刚获取，有两个活跃等待者和一个早已放弃的等待者
+	hook.strings[PrefixUserMsgLock+"acct-fresh"] = "req-1"
+	hook.strings[PrefixUserMsgLockAt+"acct-fresh"] = strconv.FormatInt(now.Add(-10*time.Second).UnixMilli(), 10)
+	hook.ttls[PrefixUserMsgLock+"acct-fresh"] = 50 * time.Second
+	hook.zsets[PrefixUserMsgWaiters+"acct-fresh"] = map[string]float64{
+		"req-2": float64(now.Add(-time.Second).UnixMilli()),
+		"req-3": float64(now.UnixMilli()),
+		"req-4": float64(now.Add(-time.Hour).UnixMilli()),
+	}
+	// acct-old：持有 20 分钟
+	hook.strings[PrefixUserMsgLock+"acct-old"] = "req-9"
+	hook.strings[PrefixUserMsgLockAt+"acct-old"] = strconv.FormatInt(now.Add(-20*time.Minute).UnixMilli(), 10)
+	hook.ttls[PrefixUserMsgLock+"acct-old"] = time.Minute
+	// acct-orphan：未记录获取时间且永不过期
+	hook.strings[PrefixUserMsgLock+"acct-orphan"] = "req-x"
+	// 非锁键
+	hook.strings[PrefixUserMsgLast+"acct-fresh"] = "123"
+
+	locks, err := c.listUserMessageLocksAt(ctx, now)
+	if err != nil {
+		t.Fatalf("listUserMessageLocksAt() error = %v", err)
+	}
+	if len(locks) != 3 {
+		t.Fatalf("locks = %+v, want 3", locks)
+	}
+	fresh := locks[0]
+	if fresh.AccountID != "acct-fresh" || fresh.Holder != "req-1" || fresh.TTLMs != 50000 || fresh.QueueDepth != 2 || fresh.HeldMs != 10000 {
+		t.Errorf("fresh lock = %+v", fresh)
+	}
+	if orphan := locks[2]; orphan.AccountID != "acct-orphan" || orphan.TTLMs != -1 || orphan.AcquiredAt != nil {
+		t.Errorf("orphan lock = %+v", orphan)
+	}
+
+	stuck := filterStuckUserMessageLocks(locks, 5*time.Minute)
+	if len(stuck) != 2 || stuck[0].AccountID != "acct-old" || stuck[1].AccountID != "acct-orphan" {
+		t.Errorf("stuck = %+v, want acct-old then acct-orphan", stuck)
+	}
+	if stuck := filterStuckUserMessageLocks(locks, time.Hour); len(stuck) != 1 || stuck[0].AccountID != "acct-orphan" {
+		t.Errorf("stuck with 1h threshold = %+v, want only acct-orphan", stuck)
+	}
+}
@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNamespaceUnsupportedCommand 启用命名空间时执行了未登记 key 位置的命令（拒绝发送，避免写到命名空间之外）
+var ErrNamespaceUnsupportedCommand = errors.New("command not supported with key namespace")
+
+// 命令中 key 参数的位置（args[0] 为命令名）
+const (
+	keyPosNone  = iota // 不含 key
+	keyPosFirst        // 仅 args[1]（KEYS 命令为 key 模式）
+	keyPosAll          // args[1:] 全部为 key
+	keyPosPairs        // args[1:] 为 key/value 交替
+	keyPosEval         // args[2] 为 key 数量，随后为 key
+	keyPosScan         // MATCH 参数为 key 模式
+)
+
+// namespacedCommands 已登记 key 位置的命令；未列出的命令一律拒绝（fail closed）
+var namespacedCommands = func() map[string]int {
+	positions := map[int][]string{
+		keyPosNone: {
+			"ping", "echo", "info", "dbsize", "time", "hello", "auth", "select", "client", "quit",
+			"multi", "exec", "discard", "unwatch", "readonly", "readwrite", "command", "script",
+		},
+		keyPosFirst: {
+			"get", "set", "setnx", "setex", "getset", "getdel", "incr", "incrby", "incrbyfloat", "decr", "decrby",
+			"expire", "pexpire", "expireat", "pexpireat", "ttl", "pttl", "persist", "type", "keys",
+			"hget", "hset", "hsetnx", "hmset", "hmget", "hgetall", "hdel", "hexists", "hincrby", "hincrbyfloat",
+			"hkeys", "hvals", "hlen", "hscan",
+			"lpush", "rpush", "lpop", "rpop", "lrange", "ltrim", "llen", "lrem", "lindex",
+			"sadd", "srem", "smembers", "scard", "sismember", "sscan",
+			"zadd", "zrem", "zcard", "zcount", "zscore", "zincrby", "zrank", "zscan",
+			"zrange", "zrevrange", "zrangebyscore", "zrevrangebyscore", "zremrangebyscore", "zremrangebyrank",
+		},
+		keyPosAll:   {"del", "unlink", "exists", "mget", "touch", "rename", "watch"},
+		keyPosPairs: {"mset"},
+		keyPosEval:  {"eval", "evalsha", "eval_ro", "evalsha_ro"},
+		keyPosScan:  {"scan"},
+	}
+	commands := make(map[string]int)
+	for pos, names := range positions {
+		for _, name := range names {
+			commands[name] = pos
+		}
+	}
+	return commands
+}()
+
+// KeyNamespace 获取全局 key 命名空间前缀（默认为空，与未配置时的 key 完全一致）
+// Node.js 服务不识别命名空间，启用后 Go 与 Node.js 无法共享同一份数据，仅适用于纯 Go 部署
+func KeyNamespace() string {
+	if config.Cfg != nil {
+		return config.Cfg.Redis.KeyPrefix
+	}
+	return ""
+}
+
+// NamespacedKey 返回 key 在 Redis 中实际存储的名称
+// 通过本包客户端执行的命令会自动加命名空间，仅在绕过客户端（如运维脚本、日志）时需要使用
+func NamespacedKey(key string) string {
+	return KeyNamespace() + key
+}
+
+// namespaceHook 为所有命令中的 key 加上命名空间前缀，并从 SCAN/KEYS 结果中去掉前缀
+// 使多个部署可以安全共享同一个 Redis DB，上层代码中的 Prefix* 常量与 key 拼接逻辑无需感知命名空间
+// 未登记 key 位置的命令直接返回 ErrNamespaceUnsupportedCommand，不会发送到 Redis
+type namespaceHook struct {
+	prefix string
+}
+
+func newNamespaceHook(prefix string) *namespaceHook {
+	return &namespaceHook{prefix: prefix}
+}
+
+func (h *namespaceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *namespaceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.rewrite(cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		err := next(ctx, cmd)
+		h.strip(cmd)
+		return err
+	}
+}
+
+func (h *namespaceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		// 任一命令不受支持时整个管道都不发送
+		for _, cmd := range cmds {
+			if err := h.checkSupported(cmd); err != nil {
+				for _, c := range cmds {
+					c.SetErr(err)
+				}
+				return err
+			}
+		}
+		for _, cmd := range cmds {
+			h.rewrite(cmd)
+		}
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.strip(cmd)
+		}
+		return err
+	}
+}
+
+// checkSupported 检查命令是否已登记 key 位置
+func (h *namespaceHook) checkSupported(cmd redis.Cmder) error {
+	if _, ok := namespacedCommands[strings.ToLower(cmd.Name())]; !ok {
+		return fmt.Errorf("%w: %s", ErrNamespaceUnsupportedCommand, cmd.Name())
+	}
+	return nil
+}
+
+// rewrite 原地改写命令参数中的 key（未登记的命令返回 ErrNamespaceUnsupportedCommand）
+func (h *namespaceHook) rewrite(cmd redis.Cmder) error {
+	if err := h.checkSupported(cmd); err != nil {
+		return err
+	}
+	args := cmd.Args()
+
+	switch namespacedCommands[strings.ToLower(cmd.Name())] {
+	case keyPosFirst:
+		h.prefixArg(args, 1)
+	case keyPosAll:
+		for i := 1; i < len(args); i++ {
+			h.prefixArg(args, i)
+		}
+	case keyPosPairs:
+		for i := 1; i < len(args); i += 2 {
+			h.prefixArg(args, i)
+		}
+	case keyPosEval:
+		if len(args) < 3 {
+			return nil
+		}
+		numKeys, err := strconv.Atoi(argToString(args[2]))
+		if err != nil {
+			return nil
+		}
+		for i := 3; i < 3+numKeys && i < len(args); i++ {
+			h.prefixArg(args, i)
+		}
+	case keyPosScan:
+		for i := 2; i+1 < len(args); i++ {
+			if strings.EqualFold(argToString(args[i]), "match") {
+				h.prefixArg(args, i+1)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (h *namespaceHook) prefixArg(args []interface{}, i int) {
+	if i < len(args) {
+		args[i] = h.prefix + argToString(args[i])
+	}
+}
+
+// strip 从 SCAN/KEYS 返回的 key 中去掉命名空间，并过滤掉其他命名空间的 key（未指定 MATCH 时）
+func (h *namespaceHook) strip(cmd redis.Cmder) {
+	if cmd.Err() != nil {
+		return
+	}
+	switch c := cmd.(type) {
+	case *redis.ScanCmd:
+		if strings.ToLower(cmd.Name()) != "scan" {
+			return
+		}
+		keys, cursor := c.Val()
+		c.SetVal(h.stripKeys(keys), cursor)
+	case *redis.StringSliceCmd:
+		if strings.ToLower(cmd.Name()) != "keys" {
+			return
+		}
+		c.SetVal(h.stripKeys(c.Val()))
+	}
+}
+
+func (h *namespaceHook) stripKeys(keys []string) []string {
+	stripped := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, h.prefix) {
+			stripped = append(stripped, strings.TrimPrefix(key, h.prefix))
+		}
+	}
+	return stripped
+}
+
+func argToString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newNamespacedClientForTest 创建带 key 命名空间的客户端（命名空间 hook 在存储 hook 之前执行）
+func newNamespacedClientForTest(t *testing.T, prefix string, hook redis.Hook) *Client {
+	t.Helper()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:             "127.0.0.1:6379",
+		DisableIndentity: true,
+	})
+	redisClient.AddHook(newNamespaceHook(prefix))
+	redisClient.AddHook(hook)
+
+	return &Client{
+		client:      redisClient,
+		isConnected: true,
+	}
+}
+
+func TestNamespace_ReadsAndWritesUseNamespacedKeys(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newNamespacedClientForTest(t, "tenant-a:", hook)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, PrefixAPIKeyHashMap, "v", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, ok := hook.strings["tenant-a:"+PrefixAPIKeyHashMap]; !ok {
+		t.Fatalf("expected namespaced key to be written, got %v", hook.strings)
+	}
+	if got, err := c.Get(ctx, PrefixAPIKeyHashMap); err != nil || got != "v" {
+		t.Errorf("Get() = %q, %v; want v", got, err)
+	}
+
+	// Pipeline 写入与 Lua 脚本的 KEYS 同样加命名空间
+	if err := c.IncrementDailyCost(ctx, "key-1", 1.5); err != nil {
+		t.Fatalf("IncrementDailyCost() error = %v", err)
+	}
+	cost, err := c.GetDailyCost(ctx, "key-1")
+	if err != nil || math.Abs(cost-1.5) > 1e-9 {
+		t.Errorf("GetDailyCost() = %v, %v; want 1.5", cost, err)
+	}
+	if _, err := c.AcquireWithIntent(ctx, "key-1", "req-1", 60, 0, 0, 0.1, ""); err != nil {
+		t.Fatalf("AcquireWithIntent() error = %v", err)
+	}
+	if _, ok := hook.zsets["tenant-a:"+PrefixConcurrency+"key-1"]; !ok {
+		t.Error("expected script KEYS to be namespaced")
+	}
+	for key := range hook.strings {
+		if !strings.HasPrefix(key, "tenant-a:") {
+			t.Errorf("found key outside namespace: %s", key)
+		}
+	}
+
+	// SCAN 返回的 key 不含命名空间
+	keys, err := c.ScanKeys(ctx, PrefixAPIKey+"*", 100)
+	if err != nil {
+		t.Fatalf("ScanKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0] != PrefixAPIKeyHashMap {
+		t.Errorf("ScanKeys() = %v, want [%s]", keys, PrefixAPIKeyHashMap)
+	}
+}
+
+func TestNamespace_IsolatesDeployments(t *testing.T) {
+	hook := newMemoryRedisHook()
+	a := newNamespacedClientForTest(t, "a:", hook)
+	b := newNamespacedClientForTest(t, "b:", hook)
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "shared:key", "from-a", 0); err != nil {
+		t.Fatalf("a.Set() error = %v", err)
+	}
+	if err := b.Set(ctx, "shared:key", "from-b", 0); err != nil {
+		t.Fatalf("b.Set() error = %v", err)
+	}
+	if got, _ := a.Get(ctx, "shared:key"); got != "from-a" {
+		t.Errorf("a.Get() = %q, want from-a", got)
+	}
+	if got, _ := b.Get(ctx, "shared:key"); got != "from-b" {
+		t.Errorf("b.Get() = %q, want from-b", got)
+	}
+
+	if err := a.Set(ctx, "only:a", "1", 0); err != nil {
+		t.Fatalf("a.Set() error = %v", err)
+	}
+	if _, err := b.Get(ctx, "only:a"); err != redis.Nil {
+		t.Errorf("b.Get(only:a) error = %v, want redis.Nil", err)
+	}
+
+	// 未指定 MATCH 的 SCAN 也不会返回其他命名空间的 key
+	keys, err := b.ScanKeys(ctx, "", 100)
+	if err != nil {
+		t.Fatalf("ScanKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "shared:key" {
+		t.Errorf("b.ScanKeys() = %v, want [shared:key]", keys)
+	}
+
+	if deleted, err := b.Del(ctx, "shared:key"); err != nil || deleted != 1 {
+		t.Fatalf("b.Del() = %d, %v; want 1", deleted, err)
+	}
+	if got, _ := a.Get(ctx, "shared:key"); got != "from-a" {
+		t.Errorf("a.Get() after b.Del() = %q, want from-a", got)
+	}
+}
+
+func TestNamespace_RejectsUnknownCommands(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newNamespacedClientForTest(t, "a:", hook)
+	ctx := context.Background()
+
+	// 未登记 key 位置的命令不发送（否则会写到命名空间之外）
+	err := c.client.Do(ctx, "setrange", "shared:key", 0, "x").Err()
+	if !errors.Is(err, ErrNamespaceUnsupportedCommand) {
+		t.Fatalf("Do(setrange) error = %v, want ErrNamespaceUnsupportedCommand", err)
+	}
+	if _, ok := hook.strings["shared:key"]; ok {
+		t.Error("unsupported command must not reach Redis")
+	}
+
+	// 管道中任一命令不受支持时整个管道都不发送
+	pipe := c.client.Pipeline()
+	pipe.Set(ctx, "k", "v", 0)
+	pipe.Do(ctx, "setrange", "k", 0, "x")
+	if _, err := pipe.Exec(ctx); !errors.Is(err, ErrNamespaceUnsupportedCommand) {
+		t.Fatalf("pipeline error = %v, want ErrNamespaceUnsupportedCommand", err)
+	}
+	if _, ok := hook.strings["a:k"]; ok {
+		t.Error("pipeline with an unsupported command must not be sent")
+	}
+}
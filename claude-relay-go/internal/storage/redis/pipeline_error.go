@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// PipelineCommandFailure 管道中执行失败的单条命令
+type PipelineCommandFailure struct {
+	Command string `json:"command"`
+	Key     string `json:"key,omitempty"`
+	Err     error  `json:"-"`
+}
+
+// PartialPipelineError 管道执行后仍有命令失败（其余命令已生效）
+// 调用方可通过 errors.As 判断是否部分成功：部分成功时整体重试会重复计数，应依赖幂等键或只处理失败的命令
+type PartialPipelineError struct {
+	Total    int
+	Failures []PipelineCommandFailure
+}
+
+func (e *PartialPipelineError) Error() string {
+	first := e.Failures[0]
+	return fmt.Sprintf("pipeline: %d of %d commands failed (first: %s %s: %v)",
+		len(e.Failures), e.Total, first.Command, first.Key, first.Err)
+}
+
+// Unwrap 返回第一条失败命令的错误
+func (e *PartialPipelineError) Unwrap() error {
+	return e.Failures[0].Err
+}
+
+// Succeeded 已生效的命令数
+func (e *PartialPipelineError) Succeeded() int {
+	return e.Total - len(e.Failures)
+}
+
+// Partial 是否有命令已生效
+func (e *PartialPipelineError) Partial() bool {
+	return e.Succeeded() > 0
+}
+
+// isRedisServerError 是否为 Redis 返回的命令错误（命令确定未执行，可安全重试；网络错误时命令可能已生效）
+func isRedisServerError(err error) bool {
+	var redisErr goredis.Error
+	return errors.As(err, &redisErr)
+}
+
+// retryFailedCommands 重新执行因 Redis 命令错误失败的命令（仅一次），返回仍失败的命令
+func retryFailedCommands(ctx context.Context, client *goredis.Client, failed []goredis.Cmder) []goredis.Cmder {
+	var retry, remaining []goredis.Cmder
+	for _, cmd := range failed {
+		if isRedisServerError(cmd.Err()) {
+			retry = append(retry, cmd)
+		} else {
+			remaining = append(remaining, cmd)
+		}
+	}
+	if len(retry) == 0 {
+		return remaining
+	}
+
+	pipe := client.Pipeline()
+	for _, cmd := range retry {
+		cmd.SetErr(nil)
+		_ = pipe.Process(ctx, cmd)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	for _, cmd := range retry {
+		if cmd.Err() != nil && cmd.Err() != goredis.Nil {
+			remaining = append(remaining, cmd)
+		}
+	}
+	return remaining
+}
+
+// newPartialPipelineError 记录失败命令并生成错误
+func newPartialPipelineError(total int, failed []goredis.Cmder) *PartialPipelineError {
+	pipeErr := &PartialPipelineError{Total: total}
+	for _, cmd := range failed {
+		failure := PipelineCommandFailure{
+			Command: strings.ToUpper(cmd.Name()),
+			Err:     cmd.Err(),
+		}
+		if args := cmd.Args(); len(args) > 1 {
+			failure.Key = argToString(args[1])
+		}
+		pipeErr.Failures = append(pipeErr.Failures, failure)
+
+		logger.Error("Pipeline command failed",
+			zap.String("command", failure.Command),
+			zap.String("key", failure.Key),
+			zap.Error(failure.Err))
+	}
+	return pipeErr
+}
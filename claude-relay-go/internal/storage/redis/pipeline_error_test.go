@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testServerError 模拟 Redis 返回的命令错误
+type testServerError string
+
+func (e testServerError) Error() string { return string(e) }
+func (e testServerError) RedisError()   {}
+
+// failingPipelineHook 让管道中写入指定前缀 key 的命令失败，其余命令交给内存实现
+type failingPipelineHook struct {
+	*memoryRedisHook
+	keyPrefix string
+	err       error
+	failures  int // 剩余失败次数（<0 表示一直失败）
+}
+
+func (h *failingPipelineHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		var firstErr error
+		failing := h.failures != 0
+		for _, cmd := range cmds {
+			var err error
+			if args := cmd.Args(); failing && len(args) > 1 && strings.HasPrefix(argToString(args[1]), h.keyPrefix) {
+				err = h.err
+				cmd.SetErr(err)
+			} else {
+				err = h.process(cmd)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if failing && h.failures > 0 {
+			h.failures--
+		}
+		return firstErr
+	}
+}
+
+func TestIncrementTokenUsage_PartialFailureReturnsRicherError(t *testing.T) {
+	hook := &failingPipelineHook{
+		memoryRedisHook: newMemoryRedisHook(),
+		keyPrefix:       PrefixUsageMonthly,
+		err:             testServerError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+		failures:        -1,
+	}
+	c := newConnectedClientForTest(t, hook)
+
+	err := c.IncrementTokenUsage(context.Background(), TokenUsageParams{KeyID: "key-1", Model: "m", InputTokens: 10})
+	var pipeErr *PartialPipelineError
+	if !errors.As(err, &pipeErr) {
+		t.Fatalf("error = %v, want *PartialPipelineError", err)
+	}
+	if !pipeErr.Partial() || pipeErr.Succeeded() == 0 {
+		t.Errorf("Succeeded() = %d, want partial success", pipeErr.Succeeded())
+	}
+	if len(pipeErr.Failures) == 0 {
+		t.Fatal("expected failed commands to be reported")
+	}
+	for _, f := range pipeErr.Failures {
+		if !strings.HasPrefix(f.Key, PrefixUsageMonthly) {
+			t.Errorf("failure on unexpected key %s (%s)", f.Key, f.Command)
+		}
+	}
+	if !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("error = %q, want first failure cause", err.Error())
+	}
+
+	// 其余统计已生效
+	if got := hook.hashes[PrefixUsage+"key-1"]["totalInputTokens"]; got != "10" {
+		t.Errorf("totalInputTokens = %q, want 10", got)
+	}
+}
+
+func TestIncrementTokenUsage_RetriesTransientCommandErrors(t *testing.T) {
+	hook := &failingPipelineHook{
+		memoryRedisHook: newMemoryRedisHook(),
+		keyPrefix:       PrefixUsageMonthly,
+		err:             testServerError("LOADING Redis is loading the dataset in memory"),
+		failures:        1,
+	}
+	c := newConnectedClientForTest(t, hook)
+
+	if err := c.IncrementTokenUsage(context.Background(), TokenUsageParams{KeyID: "key-1", Model: "m", InputTokens: 10}); err != nil {
+		t.Fatalf("IncrementTokenUsage() error = %v, want retried subset to succeed", err)
+	}
+
+	// 只重试失败的命令，已生效的统计不会重复计数
+	if got := hook.hashes[PrefixUsage+"key-1"]["totalInputTokens"]; got != "10" {
+		t.Errorf("totalInputTokens = %q, want 10", got)
+	}
+	retried := false
+	for key, fields := range hook.hashes {
+		if !strings.HasPrefix(key, PrefixUsageMonthly) {
+			continue
+		}
+		retried = true
+		if fields["inputTokens"] != "10" {
+			t.Errorf("%s inputTokens = %q, want 10 after retry", key, fields["inputTokens"])
+		}
+	}
+	if !retried {
+		t.Error("monthly usage should be written by the retry")
+	}
+}
+
+func TestIncrementTokenUsage_NetworkErrorsAreNotRetried(t *testing.T) {
+	hook := &failingPipelineHook{
+		memoryRedisHook: newMemoryRedisHook(),
+		keyPrefix:       PrefixUsageMonthly,
+		err:             errors.New("i/o timeout"),
+		failures:        1,
+	}
+	c := newConnectedClientForTest(t, hook)
+
+	err := c.IncrementTokenUsage(context.Background(), TokenUsageParams{KeyID: "key-1", Model: "m", InputTokens: 10})
+	var pipeErr *PartialPipelineError
+	if !errors.As(err, &pipeErr) {
+		t.Fatalf("error = %v, want *PartialPipelineError (ambiguous errors must not be retried)", err)
+	}
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 优先级排队
+const (
+	// MaxQueuePriority 排队优先级上限（超出按上限计算）
+	MaxQueuePriority = 100
+	// priorityScoreStep 每级优先级对应的分数跨度（大于任意毫秒时间戳，保证优先级先于入队时间比较）
+	priorityScoreStep = 1e13
+	// maxStaleWaiterPurge 单次检查最多清理的过期等待者数量
+	maxStaleWaiterPurge = 10
+)
+
+// PriorityWaiter 优先级队列中的等待者
+// 成员格式为 deadlineMs:requestID，等待者异常退出时可按截止时间清理
+type PriorityWaiter struct {
+	Key    string
+	Member string
+	Score  float64
+}
+
+// PriorityQueueKey 获取优先级队列的 key（apiKeyID 为空时为全局队列）
+func PriorityQueueKey(apiKeyID string) string {
+	if apiKeyID == "" {
+		return KeyGlobalPriorityQueue
+	}
+	return PrefixPriorityQueue + apiKeyID
+}
+
+// NewPriorityWaiter 创建等待者，分数越小越先放行（优先级高者在前，同级按入队时间）
+func NewPriorityWaiter(queueKey, requestID string, priority int, enqueuedAt, deadline time.Time) *PriorityWaiter {
+	if priority < 0 {
+		priority = 0
+	}
+	if priority > MaxQueuePriority {
+		priority = MaxQueuePriority
+	}
+	return &PriorityWaiter{
+		Key:    queueKey,
+		Member: fmt.Sprintf("%d:%s", deadline.UnixMilli(), requestID),
+		Score:  float64(-priority)*priorityScoreStep + float64(enqueuedAt.UnixMilli()),
+	}
+}
+
+// waiterExpired 检查成员的截止时间是否已过
+func waiterExpired(member string, now time.Time) bool {
+	deadline, _, ok := strings.Cut(member, ":")
+	if !ok {
+		return true
+	}
+	ms, err := strconv.ParseInt(deadline, 10, 64)
+	return err != nil || ms < now.UnixMilli()
+}
+
+// JoinPriorityQueue 加入优先级队列（已在队列中时保持原分数）
+func (c *Client) JoinPriorityQueue(ctx context.Context, waiter *PriorityWaiter, ttl time.Duration) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	pipe := client.Pipeline()
+	pipe.ZAddNX(ctx, waiter.Key, goredis.Z{Score: waiter.Score, Member: waiter.Member})
+	pipe.Expire(ctx, waiter.Key, ttl+TTLQueueBuffer)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to join priority queue: %w", err)
+	}
+	return nil
+}
+
+// IsPriorityQueueHead 检查等待者是否位于队首（顺带清理队首已过期的等待者）
+func (c *Client) IsPriorityQueueHead(ctx context.Context, waiter *PriorityWaiter) (bool, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	for i := 0; i <= maxStaleWaiterPurge; i++ {
+		head, err := client.ZRange(ctx, waiter.Key, 0, 0).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to get priority queue head: %w", err)
+		}
+		if len(head) == 0 {
+			return false, nil
+		}
+		if head[0] == waiter.Member {
+			return true, nil
+		}
+		if !waiterExpired(head[0], now) {
+			return false, nil
+		}
+		client.ZRem(ctx, waiter.Key, head[0])
+	}
+	return false, nil
+}
+
+// LeavePriorityQueue 离开优先级队列
+func (c *Client) LeavePriorityQueue(ctx context.Context, waiter *PriorityWaiter) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	if err := client.ZRem(ctx, waiter.Key, waiter.Member).Err(); err != nil {
+		return fmt.Errorf("failed to leave priority queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriorityQueue_HigherPriorityAdmittedBeforeEarlierLowerPriority(t *testing.T) {
+	client := newConnectedClientForTest(t, newMemoryRedisHook())
+	ctx := context.Background()
+	now := time.Now()
+	deadline := now.Add(time.Minute)
+
+	low := NewPriorityWaiter(KeyGlobalPriorityQueue, "req-low", 0, now, deadline)
+	high := NewPriorityWaiter(KeyGlobalPriorityQueue, "req-high", 10, now.Add(time.Second), deadline)
+
+	for _, waiter := range []*PriorityWaiter{low, high} {
+		if err := client.JoinPriorityQueue(ctx, waiter, time.Minute); err != nil {
+			t.Fatalf("JoinPriorityQueue() error = %v", err)
+		}
+	}
+
+	if isHead, _ := client.IsPriorityQueueHead(ctx, low); isHead {
+		t.Error("low-priority waiter enqueued earlier should not be admitted first")
+	}
+	if isHead, _ := client.IsPriorityQueueHead(ctx, high); !isHead {
+		t.Error("high-priority waiter should be admitted first")
+	}
+
+	if err := client.LeavePriorityQueue(ctx, high); err != nil {
+		t.Fatalf("LeavePriorityQueue() error = %v", err)
+	}
+	if isHead, _ := client.IsPriorityQueueHead(ctx, low); !isHead {
+		t.Error("low-priority waiter should be admitted after the high-priority one leaves")
+	}
+}
+
+func TestPriorityQueue_SamePriorityIsFIFOAndStaleHeadIsPurged(t *testing.T) {
+	client := newConnectedClientForTest(t, newMemoryRedisHook())
+	ctx := context.Background()
+	now := time.Now()
+
+	// 已过截止时间的等待者（异常退出未离开队列）
+	stale := NewPriorityWaiter(PriorityQueueKey("key-1"), "req-stale", 0, now.Add(-time.Minute), now.Add(-time.Second))
+	first := NewPriorityWaiter(PriorityQueueKey("key-1"), "req-1", 0, now, now.Add(time.Minute))
+	second := NewPriorityWaiter(PriorityQueueKey("key-1"), "req-2", 0, now.Add(time.Millisecond), now.Add(time.Minute))
+
+	for _, waiter := range []*PriorityWaiter{second, stale, first} {
+		if err := client.JoinPriorityQueue(ctx, waiter, time.Minute); err != nil {
+			t.Fatalf("JoinPriorityQueue() error = %v", err)
+		}
+	}
+
+	if isHead, _ := client.IsPriorityQueueHead(ctx, second); isHead {
+		t.Error("later waiter should not be head")
+	}
+	if isHead, _ := client.IsPriorityQueueHead(ctx, first); !isHead {
+		t.Error("earliest live waiter should be head once the stale one is purged")
+	}
+}
@@ -101,6 +101,7 @@ func (c *Client) IncrConcurrencyQueue(ctx context.Context, apiKeyID string, time
 	if !ok {
 		return 0, fmt.Errorf("unexpected result type from queue incr: %T", result)
 	}
+	c.held.trackQueue(ctx, apiKeyID, 1)
 	logger.Debug("Incremented queue count",
 		zap.String("apiKeyId", apiKeyID),
 		zap.Int64("count", count),
@@ -128,6 +129,7 @@ func (c *Client) DecrConcurrencyQueue(ctx context.Context, apiKeyID string) (int
 	if !ok {
 		return 0, fmt.Errorf("unexpected result type from queue decr: %T", result)
 	}
+	c.held.trackQueue(ctx, apiKeyID, -1)
 	if count == 0 {
 		logger.Debug("Queue count is 0, removed key", zap.String("apiKeyId", apiKeyID))
 	} else {
@@ -175,6 +177,7 @@ func (c *Client) ClearConcurrencyQueue(ctx context.Context, apiKeyID string) err
 		return err
 	}
 
+	c.held.untrackQueue(apiKeyID)
 	logger.Debug("Cleared queue count", zap.String("apiKeyId", apiKeyID))
 	return nil
 }
@@ -187,15 +190,102 @@ func (c *Client) IncrQueueStats(ctx context.Context, apiKeyID, field string, del
 	}
 
 	key := PrefixConcurrencyQueueStats + apiKeyID
+	bucketKey := queueStatsHourlyKey(apiKeyID, getHourStringInTimezone(time.Now()))
 
 	pipe := client.Pipeline()
+	// 累计计数
 	pipe.HIncrBy(ctx, key, field, delta)
 	pipe.Expire(ctx, key, TTLQueueStats)
+	// 按小时分桶计数（用于时间窗口查询）
+	pipe.HIncrBy(ctx, bucketKey, field, delta)
+	pipe.Expire(ctx, bucketKey, TTLQueueStats)
 
 	_, err = pipe.Exec(ctx)
 	return err
 }
 
+// queueStatsHourlyKey 按小时分桶的排队统计键
+func queueStatsHourlyKey(apiKeyID, hourStr string) string {
+	return PrefixConcurrencyQueueStatsHourly + apiKeyID + ":" + hourStr
+}
+
+// getHoursInRange 获取时间范围内的所有小时字符串（包含首尾所在小时）
+func getHoursInRange(from, to time.Time) []string {
+	var hours []string
+	current := from.Truncate(time.Hour)
+	for !current.After(to) {
+		hours = append(hours, getHourStringInTimezone(current))
+		current = current.Add(time.Hour)
+	}
+	return hours
+}
+
+// QueueStatsWindow 时间窗口内的排队统计
+type QueueStatsWindow struct {
+	APIKeyID         string    `json:"apiKeyId"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	Buckets          int       `json:"buckets"`
+	Entered          int64     `json:"entered"`
+	Success          int64     `json:"success"`
+	Timeout          int64     `json:"timeout"`
+	Cancelled        int64     `json:"cancelled"`
+	SocketChanged    int64     `json:"socketChanged"`
+	RejectedOverload int64     `json:"rejectedOverload"`
+}
+
+// MaxQueueStatsWindow 时间窗口最大跨度（与分桶 TTL 一致）
+const MaxQueueStatsWindow = TTLQueueStats
+
+// GetQueueStatsWindow 获取时间窗口内的排队统计（按小时分桶求和）
+func (c *Client) GetQueueStatsWindow(ctx context.Context, apiKeyID string, from, to time.Time) (*QueueStatsWindow, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("invalid window: to is before from")
+	}
+	if to.Sub(from) > MaxQueueStatsWindow {
+		from = to.Add(-MaxQueueStatsWindow)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	hours := getHoursInRange(from, to)
+
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(hours))
+	for i, hour := range hours {
+		cmds[i] = pipe.HGetAll(ctx, queueStatsHourlyKey(apiKeyID, hour))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get queue stats window: %w", err)
+	}
+
+	window := &QueueStatsWindow{
+		APIKeyID: apiKeyID,
+		From:     from,
+		To:       to,
+		Buckets:  len(hours),
+	}
+
+	for _, cmd := range cmds {
+		data, err := cmd.Result()
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		window.Entered += parseInt64(data["entered"])
+		window.Success += parseInt64(data["success"])
+		window.Timeout += parseInt64(data["timeout"])
+		window.Cancelled += parseInt64(data["cancelled"])
+		window.SocketChanged += parseInt64(data["socket_changed"])
+		window.RejectedOverload += parseInt64(data["rejected_overload"])
+	}
+
+	return window, nil
+}
+
 // RecordWaitTime 记录等待时间
 func (c *Client) RecordWaitTime(ctx context.Context, apiKeyID string, waitMs int64) error {
 	client, err := c.GetClientSafe()
@@ -223,6 +313,36 @@ func (c *Client) RecordWaitTime(ctx context.Context, apiKeyID string, waitMs int
 	return err
 }
 
+// GetWaitTimeSamples 获取 API Key 最近的排队等待时间样本（毫秒，最新在前）
+// limit 超出 [1, WaitTimeSamplesPerKey] 时按边界处理，<= 0 使用默认值
+func (c *Client) GetWaitTimeSamples(ctx context.Context, apiKeyID string, limit int) ([]int64, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = DefaultWaitTimeSamplesLimit
+	}
+	if limit > WaitTimeSamplesPerKey {
+		limit = WaitTimeSamplesPerKey
+	}
+
+	waitKey := PrefixConcurrencyQueueWait + apiKeyID
+	values, err := client.LRange(ctx, waitKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wait time samples: %w", err)
+	}
+
+	samples := make([]int64, 0, len(values))
+	for _, v := range values {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			samples = append(samples, ms)
+		}
+	}
+	return samples, nil
+}
+
 // GetQueueStats 获取排队统计
 func (c *Client) GetQueueStats(ctx context.Context, apiKeyID string) (*QueueStats, error) {
 	client, err := c.GetClientSafe()
@@ -280,9 +400,9 @@ func (c *Client) GetQueueStats(ctx context.Context, apiKeyID string) (*QueueStat
 	return stats, nil
 }
 
-// GetGlobalQueueStats 获取全局排队统计
+// GetGlobalQueueStats 获取全局排队统计（汇总部分从只读副本读取）
 func (c *Client) GetGlobalQueueStats(ctx context.Context, includePerKey bool) (*GlobalQueueStats, error) {
-	client, err := c.GetClientSafe()
+	client, err := c.GetReadClientSafe()
 	if err != nil {
 		return nil, err
 	}
@@ -290,7 +410,7 @@ func (c *Client) GetGlobalQueueStats(ctx context.Context, includePerKey bool) (*
 	globalStats := &GlobalQueueStats{}
 
 	// 扫描所有排队计数器（单次扫描，同时收集 keyIDs）
-	queueKeys, _ := c.ScanKeys(ctx, PrefixConcurrencyQueue+"*", 1000)
+	queueKeys, _ := c.ScanReadKeys(ctx, PrefixConcurrencyQueue+"*", 1000)
 	keyIDs := make([]string, 0, len(queueKeys))
 
 	for _, key := range queueKeys {
@@ -309,7 +429,7 @@ func (c *Client) GetGlobalQueueStats(ctx context.Context, includePerKey bool) (*
 	}
 
 	// 扫描所有统计
-	statsKeys, _ := c.ScanKeys(ctx, PrefixConcurrencyQueueStats+"*", 1000)
+	statsKeys, _ := c.ScanReadKeys(ctx, PrefixConcurrencyQueueStats+"*", 1000)
 	for _, key := range statsKeys {
 		data, _ := client.HGetAll(ctx, key).Result()
 		globalStats.TotalEntered += parseInt64(data["entered"])
@@ -385,6 +505,7 @@ func (c *Client) ClearAllConcurrencyQueues(ctx context.Context) (int, error) {
 	for _, keyID := range keyIDs {
 		key := PrefixConcurrencyQueue + keyID
 		client.Del(ctx, key)
+		c.held.untrackQueue(keyID)
 	}
 
 	logger.Info("Cleared all concurrency queues", zap.Int("count", len(keyIDs)))
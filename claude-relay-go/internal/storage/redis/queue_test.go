@@ -1,8 +1,18 @@
 package redis
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func TestQueueStatsStruct(t *testing.T) {
@@ -214,3 +224,901 @@ func TestLuaScripts(t *testing.T) {
 		t.Error("luaQueueDecr should contain DEL command")
 	}
 }
+
+// memoryRedisHook 基于内存的 Redis hook，支持常用的 Hash/String 命令（含 Pipeline）
+type memoryRedisHook struct {
+	mu      sync.Mutex
+	hashes  map[string]map[string]string
+	strings map[string]string
+	sets    map[string]map[string]bool
+	lists   map[string][]string
+	zsets   map[string]map[string]float64
+	ttls    map[string]time.Duration // PTTL 返回的剩余时间（未设置时视为永不过期）
+
+	// beforeEval 在执行脚本前调用（持有锁），用于模拟并发写入
+	beforeEval func(h *memoryRedisHook)
+}
+
+func newMemoryRedisHook() *memoryRedisHook {
+	return &memoryRedisHook{
+		hashes:  make(map[string]map[string]string),
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]bool),
+		lists:   make(map[string][]string),
+		zsets:   make(map[string]map[string]float64),
+		ttls:    make(map[string]time.Duration),
+	}
+}
+
+func (h *memoryRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *memoryRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.process(cmd)
+	}
+}
+
+func (h *memoryRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		var firstErr error
+		for _, cmd := range cmds {
+			if err := h.process(cmd); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+// scoreInRange 判断分数是否在 ZRANGEBYSCORE 风格的区间内（支持 -inf/+inf 与 "(" 开区间）
+func scoreInRange(score float64, min, max string) bool {
+	bound := func(s string) (float64, bool) {
+		exclusive := strings.HasPrefix(s, "(")
+		v, _ := strconv.ParseFloat(strings.TrimPrefix(s, "("), 64)
+		return v, exclusive
+	}
+	lo, loExclusive := bound(min)
+	hi, hiExclusive := bound(max)
+	if score < lo || (loExclusive && score == lo) {
+		return false
+	}
+	return score < hi || (!hiExclusive && score == hi)
+}
+
+func (h *memoryRedisHook) process(cmd redis.Cmder) error {
+	args := cmd.Args()
+	argString := func(i int) string {
+		if b, ok := args[i].([]byte); ok {
+			return string(b)
+		}
+		return fmt.Sprint(args[i])
+	}
+	argFloat := func(i int) float64 {
+		v, _ := strconv.ParseFloat(argString(i), 64)
+		return v
+	}
+
+	switch strings.ToLower(cmd.Name()) {
+	case "hgetall":
+		data := make(map[string]string)
+		for k, v := range h.hashes[argString(1)] {
+			data[k] = v
+		}
+		cmd.(*redis.MapStringStringCmd).SetVal(data)
+	case "hvals":
+		vals := make([]string, 0, len(h.hashes[argString(1)]))
+		for _, v := range h.hashes[argString(1)] {
+			vals = append(vals, v)
+		}
+		sort.Strings(vals)
+		cmd.(*redis.StringSliceCmd).SetVal(vals)
+	case "hget":
+		val, ok := h.hashes[argString(1)][argString(2)]
+		if !ok {
+			cmd.SetErr(redis.Nil)
+			return redis.Nil
+		}
+		cmd.(*redis.StringCmd).SetVal(val)
+	case "hmget":
+		fields := h.hashes[argString(1)]
+		vals := make([]interface{}, 0, len(args)-2)
+		for i := 2; i < len(args); i++ {
+			if val, ok := fields[argString(i)]; ok {
+				vals = append(vals, val)
+			} else {
+				vals = append(vals, nil)
+			}
+		}
+		cmd.(*redis.SliceCmd).SetVal(vals)
+	case "mget":
+		vals := make([]interface{}, 0, len(args)-1)
+		for i := 1; i < len(args); i++ {
+			if val, ok := h.strings[argString(i)]; ok {
+				vals = append(vals, val)
+			} else {
+				vals = append(vals, nil)
+			}
+		}
+		cmd.(*redis.SliceCmd).SetVal(vals)
+	case "hset":
+		key := argString(1)
+		if h.hashes[key] == nil {
+			h.hashes[key] = make(map[string]string)
+		}
+		var added int64
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := h.hashes[key][argString(i)]; !ok {
+				added++
+			}
+			h.hashes[key][argString(i)] = argString(i + 1)
+		}
+		cmd.(*redis.IntCmd).SetVal(added)
+	case "zadd":
+		key := argString(1)
+		if h.zsets[key] == nil {
+			h.zsets[key] = make(map[string]float64)
+		}
+		i, nx, xx := 2, false, false
+		if strings.EqualFold(argString(i), "nx") {
+			i, nx = i+1, true
+		} else if strings.EqualFold(argString(i), "xx") {
+			i, xx = i+1, true
+		}
+		var added int64
+		for ; i+1 < len(args); i += 2 {
+			member := argString(i + 1)
+			if _, ok := h.zsets[key][member]; ok && nx || !ok && xx {
+				continue
+			} else if !ok {
+				added++
+			}
+			score, _ := strconv.ParseFloat(argString(i), 64)
+			h.zsets[key][member] = score
+		}
+		cmd.(*redis.IntCmd).SetVal(added)
+	case "zremrangebyscore":
+		key := argString(1)
+		var removed int64
+		for member, score := range h.zsets[key] {
+			if scoreInRange(score, argString(2), argString(3)) {
+				delete(h.zsets[key], member)
+				removed++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(removed)
+	case "zcard":
+		cmd.(*redis.IntCmd).SetVal(int64(len(h.zsets[argString(1)])))
+	case "zrange":
+		zset := h.zsets[argString(1)]
+		members := make([]string, 0, len(zset))
+		for member := range zset {
+			members = append(members, member)
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if zset[members[i]] != zset[members[j]] {
+				return zset[members[i]] < zset[members[j]]
+			}
+			return members[i] < members[j]
+		})
+		start, _ := strconv.Atoi(argString(2))
+		stop, _ := strconv.Atoi(argString(3))
+		if stop < 0 || stop >= len(members) {
+			stop = len(members) - 1
+		}
+		if start > stop {
+			members = nil
+		} else {
+			members = members[start : stop+1]
+		}
+		if zcmd, ok := cmd.(*redis.ZSliceCmd); ok {
+			zcmd.SetVal(zsliceWithScores(zset, members))
+			break
+		}
+		if members == nil {
+			members = []string{}
+		}
+		cmd.(*redis.StringSliceCmd).SetVal(members)
+	case "zrangebyscore":
+		zset := h.zsets[argString(1)]
+		members := make([]string, 0, len(zset))
+		for member, score := range zset {
+			if scoreInRange(score, argString(2), argString(3)) {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if zset[members[i]] != zset[members[j]] {
+				return zset[members[i]] < zset[members[j]]
+			}
+			return members[i] < members[j]
+		})
+		if zcmd, ok := cmd.(*redis.ZSliceCmd); ok {
+			zcmd.SetVal(zsliceWithScores(zset, members))
+			break
+		}
+		cmd.(*redis.StringSliceCmd).SetVal(members)
+	case "zrem":
+		var removed int64
+		for i := 2; i < len(args); i++ {
+			if _, ok := h.zsets[argString(1)][argString(i)]; ok {
+				delete(h.zsets[argString(1)], argString(i))
+				removed++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(removed)
+	case "zcount":
+		var count int64
+		for _, score := range h.zsets[argString(1)] {
+			if scoreInRange(score, argString(2), argString(3)) {
+				count++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(count)
+	case "hincrbyfloat":
+		val, err := h.hashIncr(argString(1), argString(2), argString(3), true)
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		current, _ := strconv.ParseFloat(val, 64)
+		cmd.(*redis.FloatCmd).SetVal(current)
+	case "incrbyfloat":
+		key := argString(1)
+		delta, _ := strconv.ParseFloat(argString(2), 64)
+		current, _ := strconv.ParseFloat(h.strings[key], 64)
+		current += delta
+		h.strings[key] = strconv.FormatFloat(current, 'f', -1, 64)
+		cmd.(*redis.FloatCmd).SetVal(current)
+	case "hincrby":
+		val, err := h.hashIncr(argString(1), argString(2), argString(3), false)
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		current, _ := strconv.ParseInt(val, 10, 64)
+		cmd.(*redis.IntCmd).SetVal(current)
+	case "eval":
+		switch argString(1) {
+		case luaAccountCompareAndSet:
+			if h.beforeEval != nil {
+				h.beforeEval(h)
+			}
+			key := argString(3)
+			if current, ok := h.strings[key]; !ok || current != argString(4) {
+				cmd.(*redis.Cmd).SetVal(int64(0))
+				return nil
+			}
+			h.strings[key] = argString(5)
+			cmd.(*redis.Cmd).SetVal(int64(1))
+		case luaSelfTest:
+			key := argString(3)
+			if h.strings[key] != argString(4) {
+				cmd.(*redis.Cmd).SetVal(int64(0))
+				return nil
+			}
+			ttl, ok := h.ttls[key]
+			if !ok {
+				cmd.(*redis.Cmd).SetVal(int64(-1))
+				return nil
+			}
+			cmd.(*redis.Cmd).SetVal(ttl.Milliseconds())
+		case luaIncrCostMicros:
+			key, microsKey := argString(3), argString(4)
+			if _, ok := h.strings[microsKey]; !ok {
+				legacy, _ := strconv.ParseFloat(h.strings[key], 64)
+				h.strings[microsKey] = strconv.FormatInt(CostToMicros(legacy), 10)
+			}
+			micros, _ := strconv.ParseInt(h.strings[microsKey], 10, 64)
+			delta, _ := strconv.ParseInt(argString(6), 10, 64)
+			h.strings[microsKey] = strconv.FormatInt(micros+delta, 10)
+			legacy, _ := strconv.ParseFloat(h.strings[key], 64)
+			amount, _ := strconv.ParseFloat(argString(5), 64)
+			h.strings[key] = strconv.FormatFloat(legacy+amount, 'f', -1, 64)
+			cmd.(*redis.Cmd).SetVal(int64(1))
+		case luaHIncrCostMicros:
+			key, field, microsField := argString(3), argString(4), argString(5)
+			if _, ok := h.hashes[key][microsField]; !ok {
+				legacy, _ := strconv.ParseFloat(h.hashes[key][field], 64)
+				if h.hashes[key] == nil {
+					h.hashes[key] = make(map[string]string)
+				}
+				h.hashes[key][microsField] = strconv.FormatInt(CostToMicros(legacy), 10)
+			}
+			h.hashIncr(key, microsField, argString(7), false)
+			h.hashIncr(key, field, argString(6), true)
+			cmd.(*redis.Cmd).SetVal(int64(1))
+		case luaHashIncrCoerce:
+			key, field, incr := argString(3), argString(4), argString(5)
+			isFloat := argString(6) == "HINCRBYFLOAT"
+			coerced := int64(0)
+			val, err := h.hashIncr(key, field, incr, isFloat)
+			if err != nil {
+				h.hashes[key][field] = "0"
+				coerced = 1
+				val, _ = h.hashIncr(key, field, incr, isFloat)
+			}
+			cmd.(*redis.Cmd).SetVal([]interface{}{val, coerced})
+		case luaConcurrencyIncrIntent:
+			key, intentKey, indexKey, countKey, globalKey := argString(3), argString(4), argString(5), argString(6), argString(7)
+			member, expireAt, now := argString(8), argFloat(9), argFloat(10)
+			for _, k := range []string{key, indexKey, globalKey} {
+				for m, score := range h.zsets[k] {
+					if score <= now {
+						delete(h.zsets[k], m)
+					}
+				}
+			}
+			count := int64(len(h.zsets[key]))
+			limit, _ := strconv.ParseInt(argString(13), 10, 64)
+			if _, held := h.zsets[key][member]; limit > 0 && !held && count >= limit {
+				cmd.(*redis.Cmd).SetVal([]interface{}{int64(0), count})
+				return nil
+			}
+			globalMember := argString(16)
+			globalLimit, _ := strconv.ParseInt(argString(17), 10, 64)
+			if globalLimit > 0 {
+				globalCount := int64(len(h.zsets[globalKey]))
+				if _, held := h.zsets[globalKey][globalMember]; !held && globalCount >= globalLimit {
+					cmd.(*redis.Cmd).SetVal([]interface{}{int64(-1), globalCount})
+					return nil
+				}
+				if h.zsets[globalKey] == nil {
+					h.zsets[globalKey] = make(map[string]float64)
+				}
+				h.zsets[globalKey][globalMember] = expireAt
+			}
+			for _, k := range []string{key, indexKey} {
+				if h.zsets[k] == nil {
+					h.zsets[k] = make(map[string]float64)
+				}
+				h.zsets[k][member] = expireAt
+			}
+			h.hashes[intentKey] = map[string]string{
+				"estimatedCost": argString(14),
+				"model":         argString(15),
+				"createdAt":     argString(10),
+				"expireAt":      argString(9),
+			}
+			intentTTL, _ := strconv.ParseInt(argString(12), 10, 64)
+			h.ttls[intentKey] = time.Duration(intentTTL) * time.Millisecond
+			cmd.(*redis.Cmd).SetVal([]interface{}{int64(1), h.syncConcurrencyCount(key, countKey)})
+		case luaConcurrencySettleIntent:
+			key, globalKey, intentKey, indexKey, countKey := argString(3), argString(4), argString(5), argString(6), argString(7)
+			member, now, globalMember := argString(15), argFloat(16), argString(17)
+			estimated, found := h.hashes[intentKey]["estimatedCost"]
+			delete(h.hashes, intentKey)
+			delete(h.zsets[indexKey], member)
+			delete(h.zsets[key], member)
+			delete(h.zsets[globalKey], globalMember)
+			for m, score := range h.zsets[key] {
+				if score <= now {
+					delete(h.zsets[key], m)
+				}
+			}
+			count := h.syncConcurrencyCount(key, countKey)
+			if count == 0 {
+				delete(h.zsets, key)
+			}
+			if !found {
+				cmd.(*redis.Cmd).SetVal([]interface{}{int64(0), count, "0"})
+				return nil
+			}
+			if amount := argFloat(18); amount > 0 {
+				microsMode := argString(20) == "1"
+				for _, i := range []int{8, 10, 12} {
+					costKey, microsKey := argString(i), argString(i+1)
+					if microsMode {
+						if _, ok := h.strings[microsKey]; !ok {
+							legacy, _ := strconv.ParseFloat(h.strings[costKey], 64)
+							h.strings[microsKey] = strconv.FormatInt(CostToMicros(legacy), 10)
+						}
+						micros, _ := strconv.ParseInt(h.strings[microsKey], 10, 64)
+						delta, _ := strconv.ParseInt(argString(19), 10, 64)
+						h.strings[microsKey] = strconv.FormatInt(micros+delta, 10)
+					}
+					legacy, _ := strconv.ParseFloat(h.strings[costKey], 64)
+					h.strings[costKey] = strconv.FormatFloat(legacy+amount, 'f', -1, 64)
+				}
+				if microsMode {
+					if _, ok := h.hashes[argString(14)]["totalCostMicros"]; !ok {
+						legacy, _ := strconv.ParseFloat(h.hashes[argString(14)]["totalCost"], 64)
+						if h.hashes[argString(14)] == nil {
+							h.hashes[argString(14)] = make(map[string]string)
+						}
+						h.hashes[argString(14)]["totalCostMicros"] = strconv.FormatInt(CostToMicros(legacy), 10)
+					}
+					h.hashIncr(argString(14), "totalCostMicros", argString(19), false)
+				}
+				h.hashIncr(argString(14), "totalCost", argString(18), true)
+			}
+			cmd.(*redis.Cmd).SetVal([]interface{}{int64(1), count, estimated})
+		case luaStickySessionReserve:
+			index, member := argString(3), argString(4)
+			expireAt, now := argFloat(5), argFloat(6)
+			maxCount, _ := strconv.Atoi(argString(7))
+			if h.zsets[index] == nil {
+				h.zsets[index] = make(map[string]float64)
+			}
+			zset := h.zsets[index]
+			for m, score := range zset {
+				if score <= now {
+					delete(zset, m)
+				}
+			}
+			if _, ok := zset[member]; ok {
+				zset[member] = expireAt
+				cmd.(*redis.Cmd).SetVal([]interface{}{int64(1)})
+				return nil
+			}
+			result := []interface{}{int64(1)}
+			if maxCount > 0 && len(zset) >= maxCount {
+				if argString(8) != "1" {
+					cmd.(*redis.Cmd).SetVal([]interface{}{int64(0)})
+					return nil
+				}
+				members := make([]string, 0, len(zset))
+				for m := range zset {
+					members = append(members, m)
+				}
+				sort.Slice(members, func(i, j int) bool {
+					if zset[members[i]] != zset[members[j]] {
+						return zset[members[i]] < zset[members[j]]
+					}
+					return members[i] < members[j]
+				})
+				for _, m := range members[:len(zset)-maxCount+1] {
+					delete(zset, m)
+					result = append(result, m)
+				}
+			}
+			zset[member] = expireAt
+			cmd.(*redis.Cmd).SetVal(result)
+		default:
+			return errors.New("unexpected script")
+		}
+	case "get":
+		val, ok := h.strings[argString(1)]
+		if !ok {
+			cmd.SetErr(redis.Nil)
+			return redis.Nil
+		}
+		cmd.(*redis.StringCmd).SetVal(val)
+	case "set":
+		if boolCmd, ok := cmd.(*redis.BoolCmd); ok {
+			// SET NX
+			if _, exists := h.strings[argString(1)]; exists {
+				boolCmd.SetVal(false)
+				return nil
+			}
+			h.strings[argString(1)] = argString(2)
+			boolCmd.SetVal(true)
+			return nil
+		}
+		h.strings[argString(1)] = argString(2)
+		if len(args) > 4 {
+			switch strings.ToLower(argString(3)) {
+			case "ex":
+				h.ttls[argString(1)] = time.Duration(argFloat(4)) * time.Second
+			case "px":
+				h.ttls[argString(1)] = time.Duration(argFloat(4)) * time.Millisecond
+			}
+		}
+		cmd.(*redis.StatusCmd).SetVal("OK")
+	case "del":
+		var deleted int64
+		for i := 1; i < len(args); i++ {
+			key := argString(i)
+			if _, ok := h.hashes[key]; ok {
+				delete(h.hashes, key)
+				deleted++
+			}
+			if _, ok := h.strings[key]; ok {
+				delete(h.strings, key)
+				deleted++
+			}
+			if _, ok := h.zsets[key]; ok {
+				delete(h.zsets, key)
+				deleted++
+			}
+			if _, ok := h.lists[key]; ok {
+				delete(h.lists, key)
+				deleted++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(deleted)
+	case "rename":
+		src, dst := argString(1), argString(2)
+		switch {
+		case h.hashes[src] != nil:
+			h.hashes[dst] = h.hashes[src]
+			delete(h.hashes, src)
+		default:
+			val, ok := h.strings[src]
+			if !ok {
+				return errors.New("ERR no such key")
+			}
+			h.strings[dst] = val
+			delete(h.strings, src)
+		}
+		cmd.(*redis.StatusCmd).SetVal("OK")
+	case "hdel":
+		key := argString(1)
+		var deleted int64
+		for i := 2; i < len(args); i++ {
+			if _, ok := h.hashes[key][argString(i)]; ok {
+				delete(h.hashes[key], argString(i))
+				deleted++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(deleted)
+	case "exists":
+		var count int64
+		for i := 1; i < len(args); i++ {
+			key := argString(i)
+			if len(h.hashes[key]) > 0 || len(h.zsets[key]) > 0 {
+				count++
+			} else if _, ok := h.strings[key]; ok {
+				count++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(count)
+	case "multi", "watch", "unwatch":
+		cmd.(*redis.StatusCmd).SetVal("OK")
+	case "exec":
+		cmd.(*redis.SliceCmd).SetVal([]interface{}{})
+	case "expire":
+		cmd.(*redis.BoolCmd).SetVal(true)
+	case "pttl":
+		ttl, ok := h.ttls[argString(1)]
+		if !ok {
+			ttl = -1
+		}
+		cmd.(*redis.DurationCmd).SetVal(ttl)
+	case "lrange":
+		list := h.lists[argString(1)]
+		start, _ := strconv.Atoi(argString(2))
+		stop, _ := strconv.Atoi(argString(3))
+		if stop < 0 || stop >= len(list) {
+			stop = len(list) - 1
+		}
+		if start > stop {
+			cmd.(*redis.StringSliceCmd).SetVal([]string{})
+			break
+		}
+		cmd.(*redis.StringSliceCmd).SetVal(append([]string{}, list[start:stop+1]...))
+	case "lpush":
+		key := argString(1)
+		for i := 2; i < len(args); i++ {
+			h.lists[key] = append([]string{argString(i)}, h.lists[key]...)
+		}
+		cmd.(*redis.IntCmd).SetVal(int64(len(h.lists[key])))
+	case "ltrim":
+		key := argString(1)
+		stop, _ := strconv.Atoi(argString(3))
+		if stop >= 0 && stop+1 < len(h.lists[key]) {
+			h.lists[key] = h.lists[key][:stop+1]
+		}
+		cmd.(*redis.StatusCmd).SetVal("OK")
+	case "incrby":
+		key := argString(1)
+		delta, _ := strconv.ParseInt(argString(2), 10, 64)
+		current, _ := strconv.ParseInt(h.strings[key], 10, 64)
+		current += delta
+		h.strings[key] = strconv.FormatInt(current, 10)
+		cmd.(*redis.IntCmd).SetVal(current)
+	case "sadd":
+		key := argString(1)
+		if h.sets[key] == nil {
+			h.sets[key] = make(map[string]bool)
+		}
+		var added int64
+		for i := 2; i < len(args); i++ {
+			if !h.sets[key][argString(i)] {
+				h.sets[key][argString(i)] = true
+				added++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(added)
+	case "scard":
+		cmd.(*redis.IntCmd).SetVal(int64(len(h.sets[argString(1)])))
+	case "srem":
+		var removed int64
+		for i := 2; i < len(args); i++ {
+			if h.sets[argString(1)][argString(i)] {
+				delete(h.sets[argString(1)], argString(i))
+				removed++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(removed)
+	case "smembers":
+		members := make([]string, 0, len(h.sets[argString(1)]))
+		for member := range h.sets[argString(1)] {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+		cmd.(*redis.StringSliceCmd).SetVal(members)
+	case "scan":
+		h.scan(cmd.(*redis.ScanCmd), args)
+	case "hscan":
+		h.hscan(cmd.(*redis.ScanCmd), args)
+	default:
+		return errors.New("unexpected command: " + cmd.Name())
+	}
+	return nil
+}
+
+// hashIncr 模拟 HINCRBY/HINCRBYFLOAT（字段现值非数值时返回与 Redis 相同的错误）
+// syncConcurrencyCount 按有序集合重写并发数计数器，并发为 0 时删除
+func (h *memoryRedisHook) syncConcurrencyCount(key, countKey string) int64 {
+	count := int64(len(h.zsets[key]))
+	if count == 0 {
+		delete(h.strings, countKey)
+	} else {
+		h.strings[countKey] = strconv.FormatInt(count, 10)
+	}
+	return count
+}
+
+func (h *memoryRedisHook) hashIncr(key, field, delta string, isFloat bool) (string, error) {
+	if h.hashes[key] == nil {
+		h.hashes[key] = make(map[string]string)
+	}
+	existing, ok := h.hashes[key][field]
+	if isFloat {
+		current, err := strconv.ParseFloat(existing, 64)
+		if ok && err != nil {
+			return "", errors.New("ERR hash value is not a float")
+		}
+		d, _ := strconv.ParseFloat(delta, 64)
+		h.hashes[key][field] = strconv.FormatFloat(current+d, 'f', -1, 64)
+	} else {
+		current, err := strconv.ParseInt(existing, 10, 64)
+		if ok && err != nil {
+			return "", errors.New("ERR hash value is not an integer")
+		}
+		d, _ := strconv.ParseInt(delta, 10, 64)
+		h.hashes[key][field] = strconv.FormatInt(current+d, 10)
+	}
+	return h.hashes[key][field], nil
+}
+
+// zsliceWithScores 按成员顺序附带分数（WITHSCORES）
+func zsliceWithScores(zset map[string]float64, members []string) []redis.Z {
+	result := make([]redis.Z, 0, len(members))
+	for _, member := range members {
+		result = append(result, redis.Z{Member: member, Score: zset[member]})
+	}
+	return result
+}
+
+// scan 按键名排序模拟 SCAN 游标（游标为下一页起始下标）
+func (h *memoryRedisHook) scan(cmd *redis.ScanCmd, args []interface{}) {
+	cursor, _ := strconv.ParseUint(fmt.Sprint(args[1]), 10, 64)
+	pattern, count := "*", 10
+	for i := 2; i+1 < len(args); i += 2 {
+		switch strings.ToLower(fmt.Sprint(args[i])) {
+		case "match":
+			pattern = fmt.Sprint(args[i+1])
+		case "count":
+			count, _ = strconv.Atoi(fmt.Sprint(args[i+1]))
+		}
+	}
+
+	all := make([]string, 0, len(h.strings)+len(h.hashes))
+	for key := range h.strings {
+		all = append(all, key)
+	}
+	for key := range h.hashes {
+		all = append(all, key)
+	}
+	sort.Strings(all)
+
+	var keys []string
+	next := int(cursor)
+	for ; next < len(all) && next < int(cursor)+count; next++ {
+		if ok, _ := path.Match(pattern, all[next]); ok {
+			keys = append(keys, all[next])
+		}
+	}
+	if next >= len(all) {
+		next = 0
+	}
+	cmd.SetVal(keys, uint64(next))
+}
+
+// hscan 按字段名排序模拟 HSCAN 游标（返回 field/value 交替列表）
+func (h *memoryRedisHook) hscan(cmd *redis.ScanCmd, args []interface{}) {
+	hash := h.hashes[fmt.Sprint(args[1])]
+	cursor, _ := strconv.ParseUint(fmt.Sprint(args[2]), 10, 64)
+	pattern, count := "*", 10
+	for i := 3; i+1 < len(args); i += 2 {
+		switch strings.ToLower(fmt.Sprint(args[i])) {
+		case "match":
+			pattern = fmt.Sprint(args[i+1])
+		case "count":
+			count, _ = strconv.Atoi(fmt.Sprint(args[i+1]))
+		}
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var items []string
+	next := int(cursor)
+	for ; next < len(fields) && next < int(cursor)+count; next++ {
+		if ok, _ := path.Match(pattern, fields[next]); ok {
+			items = append(items, fields[next], hash[fields[next]])
+		}
+	}
+	if next >= len(fields) {
+		next = 0
+	}
+	cmd.SetVal(items, uint64(next))
+}
+
+func TestGetHoursInRange(t *testing.T) {
+	to := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	from := to.Add(-2 * time.Hour)
+
+	hours := getHoursInRange(from, to)
+	if len(hours) != 3 {
+		t.Fatalf("Expected 3 hourly buckets, got %d: %v", len(hours), hours)
+	}
+	// 默认 UTC+8
+	if hours[0] != "2024-01-15:16" || hours[2] != "2024-01-15:18" {
+		t.Errorf("Unexpected buckets: %v", hours)
+	}
+}
+
+func TestGetQueueStatsWindow(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	now := time.Now()
+	// 累计计数（包含窗口外的历史数据）
+	hook.hashes[PrefixConcurrencyQueueStats+"key-1"] = map[string]string{
+		"entered": "100",
+		"timeout": "40",
+		"success": "60",
+	}
+	// 当前小时桶
+	hook.hashes[queueStatsHourlyKey("key-1", getHourStringInTimezone(now))] = map[string]string{
+		"entered": "5",
+		"timeout": "2",
+	}
+	// 一小时前的桶
+	hook.hashes[queueStatsHourlyKey("key-1", getHourStringInTimezone(now.Add(-time.Hour)))] = map[string]string{
+		"entered": "3",
+		"timeout": "1",
+		"success": "2",
+	}
+	// 窗口外的桶
+	hook.hashes[queueStatsHourlyKey("key-1", getHourStringInTimezone(now.Add(-5*time.Hour)))] = map[string]string{
+		"entered": "50",
+		"timeout": "30",
+	}
+
+	window, err := c.GetQueueStatsWindow(ctx, "key-1", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetQueueStatsWindow() error = %v", err)
+	}
+	if window.Entered != 8 || window.Timeout != 3 || window.Success != 2 {
+		t.Errorf("Unexpected window sums: %+v", window)
+	}
+
+	lifetime, err := c.GetQueueStats(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetQueueStats() error = %v", err)
+	}
+	if lifetime.Timeout == window.Timeout || lifetime.Entered == window.Entered {
+		t.Errorf("Windowed stats should differ from lifetime totals: lifetime=%+v window=%+v", lifetime, window)
+	}
+}
+
+func TestIncrQueueStatsWritesBucket(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if err := c.IncrQueueStats(ctx, "key-1", "timeout", 2); err != nil {
+		t.Fatalf("IncrQueueStats() error = %v", err)
+	}
+
+	if got := hook.hashes[PrefixConcurrencyQueueStats+"key-1"]["timeout"]; got != "2" {
+		t.Errorf("Lifetime timeout = %s, want 2", got)
+	}
+	bucketKey := queueStatsHourlyKey("key-1", getHourStringInTimezone(time.Now()))
+	if got := hook.hashes[bucketKey]["timeout"]; got != "2" {
+		t.Errorf("Bucket timeout = %s, want 2", got)
+	}
+}
+
+func TestGetGlobalQueueStatsIgnoresHourlyBuckets(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	if err := c.IncrQueueStats(ctx, "key-1", "entered", 3); err != nil {
+		t.Fatalf("IncrQueueStats() error = %v", err)
+	}
+
+	stats, err := c.GetGlobalQueueStats(ctx, false)
+	if err != nil {
+		t.Fatalf("GetGlobalQueueStats() error = %v", err)
+	}
+	if stats.TotalEntered != 3 {
+		t.Errorf("TotalEntered = %d, want 3 (hourly buckets must not be counted)", stats.TotalEntered)
+	}
+}
+
+func TestGetQueueStatsWindowInvalidRange(t *testing.T) {
+	c := newConnectedClientForTest(t, newMemoryRedisHook())
+	now := time.Now()
+	if _, err := c.GetQueueStatsWindow(context.Background(), "key-1", now, now.Add(-time.Hour)); err == nil {
+		t.Error("Expected error when to is before from")
+	}
+}
+
+func TestGetWaitTimeSamples(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for _, ms := range []int64{100, 200, 300} {
+		if err := c.RecordWaitTime(ctx, "key-1", ms); err != nil {
+			t.Fatalf("RecordWaitTime() error = %v", err)
+		}
+	}
+
+	samples, err := c.GetWaitTimeSamples(ctx, "key-1", 0)
+	if err != nil {
+		t.Fatalf("GetWaitTimeSamples() error = %v", err)
+	}
+	want := []int64{300, 200, 100}
+	if len(samples) != len(want) {
+		t.Fatalf("samples = %v, want %v", samples, want)
+	}
+	for i := range want {
+		if samples[i] != want[i] {
+			t.Fatalf("samples = %v, want newest first %v", samples, want)
+		}
+	}
+
+	limited, err := c.GetWaitTimeSamples(ctx, "key-1", 2)
+	if err != nil {
+		t.Fatalf("GetWaitTimeSamples() error = %v", err)
+	}
+	if len(limited) != 2 || limited[0] != 300 || limited[1] != 200 {
+		t.Errorf("limited samples = %v, want [300 200]", limited)
+	}
+}
+
+func TestGetWaitTimeSamplesCapsLimit(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	list := make([]string, WaitTimeSamplesPerKey+50)
+	for i := range list {
+		list[i] = strconv.Itoa(i)
+	}
+	hook.lists[PrefixConcurrencyQueueWait+"key-1"] = list
+
+	samples, err := c.GetWaitTimeSamples(ctx, "key-1", WaitTimeSamplesPerKey*10)
+	if err != nil {
+		t.Fatalf("GetWaitTimeSamples() error = %v", err)
+	}
+	if len(samples) != WaitTimeSamplesPerKey {
+		t.Errorf("len(samples) = %d, want %d", len(samples), WaitTimeSamplesPerKey)
+	}
+}
@@ -0,0 +1,189 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// selfTestKeyTTL 自检探针键 TTL（清理失败时自动过期）
+	selfTestKeyTTL = time.Minute
+	// selfTestTimeout 单次自检超时
+	selfTestTimeout = 5 * time.Second
+	// DefaultSelfTestFailureThreshold 判定不健康所需的连续失败次数（避免单次抖动导致健康检查翻转）
+	DefaultSelfTestFailureThreshold = 3
+)
+
+// luaSelfTest 读回探针键并返回剩余 TTL（值不一致时返回 0）
+const luaSelfTest = `
+local value = redis.call('GET', KEYS[1])
+if value ~= ARGV[1] then
+    return 0
+end
+return redis.call('PTTL', KEYS[1])
+`
+
+// SelfTestStatus 最近一次自检结果
+// Error 记录最近一次失败原因，连续失败次数达到阈值后 Healthy 才变为 false
+type SelfTestStatus struct {
+	Healthy             bool      `json:"healthy"`
+	Error               string    `json:"error,omitempty"`
+	CheckedAt           time.Time `json:"checkedAt"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+}
+
+// RunSelfTest 执行一次 Redis 自检：写入带 TTL 的探针键、读回校验、执行 Lua 脚本校验
+// 用于提前发现淘汰策略误删数据、连接到只读副本等问题
+func (c *Client) RunSelfTest(ctx context.Context) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key := PrefixSelfTest + uuid.New().String()
+	value := uuid.New().String()
+	defer func() {
+		if err := client.Del(context.Background(), key).Err(); err != nil {
+			logger.Debug("Failed to delete self-test key", zap.Error(err))
+		}
+	}()
+
+	if err := client.Set(ctx, key, value, selfTestKeyTTL).Err(); err != nil {
+		return fmt.Errorf("write canary: %w", err)
+	}
+
+	got, err := client.Get(ctx, key).Result()
+	if errors.Is(err, goredis.Nil) {
+		return fmt.Errorf("read canary: key missing after write")
+	}
+	if err != nil {
+		return fmt.Errorf("read canary: %w", err)
+	}
+	if got != value {
+		return fmt.Errorf("read canary: value mismatch")
+	}
+
+	ttl, err := client.Eval(ctx, luaSelfTest, []string{key}, value).Int64()
+	if err != nil {
+		return fmt.Errorf("run script: %w", err)
+	}
+	switch {
+	case ttl == 0:
+		return fmt.Errorf("run script: value mismatch")
+	case ttl < 0:
+		return fmt.Errorf("run script: canary key has no TTL")
+	}
+
+	return nil
+}
+
+// SelfTester 定时执行 Redis 自检并记录结果（供健康检查使用）
+type SelfTester struct {
+	client           *Client
+	interval         time.Duration
+	failureThreshold int
+
+	mu       sync.RWMutex
+	status   SelfTestStatus
+	stopChan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSelfTester 创建 Redis 自检器（failureThreshold <= 0 时使用默认阈值）
+func NewSelfTester(client *Client, interval time.Duration, failureThreshold int) *SelfTester {
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultSelfTestFailureThreshold
+	}
+	return &SelfTester{
+		client:           client,
+		interval:         interval,
+		failureThreshold: failureThreshold,
+		status:           SelfTestStatus{Healthy: true}, // 首次自检完成前视为正常
+		stopChan:         make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// NewSelfTesterFromConfig 根据全局配置创建自检器（未启用时返回 nil）
+func NewSelfTesterFromConfig(client *Client) *SelfTester {
+	if config.Cfg == nil || config.Cfg.System.RedisSelfTestInterval <= 0 {
+		return nil
+	}
+	return NewSelfTester(client, config.Cfg.System.RedisSelfTestInterval, config.Cfg.System.RedisSelfTestFailureThreshold)
+}
+
+// Start 启动定时自检（立即执行一次）
+func (t *SelfTester) Start() {
+	go t.run()
+	logger.Info("Redis self-test started", zap.Duration("interval", t.interval))
+}
+
+// Stop 停止自检并等待进行中的自检结束
+func (t *SelfTester) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stopChan)
+		<-t.done
+	})
+}
+
+// run 自检循环
+func (t *SelfTester) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	t.Check(context.Background())
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+			t.Check(context.Background())
+		}
+	}
+}
+
+// Check 执行一次自检并记录结果
+func (t *SelfTester) Check(ctx context.Context) SelfTestStatus {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	err := t.client.RunSelfTest(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.CheckedAt = time.Now()
+	if err != nil {
+		t.status.Error = err.Error()
+		t.status.ConsecutiveFailures++
+		t.status.Healthy = t.status.ConsecutiveFailures < t.failureThreshold
+		logger.Warn("Redis self-test failed",
+			zap.Int("consecutiveFailures", t.status.ConsecutiveFailures),
+			zap.Int("failureThreshold", t.failureThreshold),
+			zap.Error(err))
+	} else {
+		t.status.Healthy = true
+		t.status.Error = ""
+		t.status.ConsecutiveFailures = 0
+	}
+	return t.status
+}
+
+// Status 获取最近一次自检结果
+func (t *SelfTester) Status() SelfTestStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
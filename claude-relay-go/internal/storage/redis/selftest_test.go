@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// canaryFaultHook 模拟自检探针键的读写异常，其余命令交给内存实现
+type canaryFaultHook struct {
+	*memoryRedisHook
+	corruptGet bool  // GET 返回与写入不一致的值
+	writeErr   error // SET 返回的错误（如只读副本）
+}
+
+func (h *canaryFaultHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if args := cmd.Args(); len(args) > 1 && strings.HasPrefix(argToString(args[1]), PrefixSelfTest) {
+			switch cmd.Name() {
+			case "set":
+				if h.writeErr != nil {
+					cmd.SetErr(h.writeErr)
+					return h.writeErr
+				}
+			case "get":
+				if h.corruptGet {
+					cmd.(*redis.StringCmd).SetVal("evicted-and-replaced")
+					return nil
+				}
+			}
+		}
+		return h.process(cmd)
+	}
+}
+
+func TestRunSelfTest_Passes(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	if err := c.RunSelfTest(context.Background()); err != nil {
+		t.Fatalf("RunSelfTest() error = %v", err)
+	}
+	for key := range hook.strings {
+		if strings.HasPrefix(key, PrefixSelfTest) {
+			t.Errorf("self-test key %s was not cleaned up", key)
+		}
+	}
+}
+
+func TestSelfTester_WriteReadMismatchMarksFailed(t *testing.T) {
+	hook := &canaryFaultHook{memoryRedisHook: newMemoryRedisHook(), corruptGet: true}
+	tester := NewSelfTester(newConnectedClientForTest(t, hook), 0, 2)
+
+	if !tester.Status().Healthy {
+		t.Fatal("self-test should be healthy before the first check")
+	}
+
+	// 未达到连续失败阈值时仍视为健康，但记录失败原因
+	status := tester.Check(context.Background())
+	if !status.Healthy || !strings.Contains(status.Error, "value mismatch") || status.ConsecutiveFailures != 1 {
+		t.Fatalf("status after first failure = %+v, want healthy with value mismatch recorded", status)
+	}
+	status = tester.Check(context.Background())
+	if status.Healthy || status.ConsecutiveFailures != 2 {
+		t.Errorf("status after second failure = %+v, want unhealthy", status)
+	}
+
+	// 恢复后重置失败计数
+	hook.corruptGet = false
+	if status := tester.Check(context.Background()); !status.Healthy || status.ConsecutiveFailures != 0 || status.Error != "" {
+		t.Errorf("status after recovery = %+v, want healthy", status)
+	}
+}
+
+func TestSelfTester_ReadOnlyWriteMarksFailed(t *testing.T) {
+	hook := &canaryFaultHook{
+		memoryRedisHook: newMemoryRedisHook(),
+		writeErr:        testServerError("READONLY You can't write against a read only replica."),
+	}
+	tester := NewSelfTester(newConnectedClientForTest(t, hook), 0, 1)
+
+	status := tester.Check(context.Background())
+	if status.Healthy || !strings.Contains(status.Error, "write canary") {
+		t.Errorf("status = %+v, want failed at write", status)
+	}
+}
@@ -133,12 +133,13 @@ func (c *Client) SetStickySession(ctx context.Context, sessionHash, accountID, a
 		ttl = DefaultStickySessionTTL
 	}
 
+	now := time.Now()
 	session := &StickySession{
 		SessionHash: sessionHash,
 		AccountID:   accountID,
 		AccountType: accountType,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(ttl),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
 	}
 
 	data, err := json.Marshal(session)
@@ -146,6 +147,11 @@ func (c *Client) SetStickySession(ctx context.Context, sessionHash, accountID, a
 		return fmt.Errorf("failed to marshal sticky session: %w", err)
 	}
 
+	// 登记到会话索引（超出全局上限时淘汰或跳过）
+	if err := c.reserveStickySession(ctx, client, sessionHash, session.ExpiresAt, now); err != nil {
+		return err
+	}
+
 	key := PrefixStickySession + sessionHash
 	if err := client.Set(ctx, key, data, ttl).Err(); err != nil {
 		return err
@@ -190,8 +196,11 @@ func (c *Client) DeleteStickySession(ctx context.Context, sessionHash string) er
 		return err
 	}
 
-	key := PrefixStickySession + sessionHash
-	return client.Del(ctx, key).Err()
+	pipe := client.Pipeline()
+	pipe.Del(ctx, PrefixStickySession+sessionHash)
+	pipe.ZRem(ctx, KeyStickySessionIndex, sessionHash)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 // RenewStickySession 续期粘性会话
@@ -222,10 +231,18 @@ func (c *Client) RenewStickySession(ctx context.Context, sessionHash string, ttl
 		return err
 	}
 
-	return client.Set(ctx, key, data, ttl).Err()
+	if err := client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return err
+	}
+	// 同步会话索引中的到期时间（仅更新已登记的会话）
+	return client.ZAddXX(ctx, KeyStickySessionIndex, goredis.Z{
+		Score:  float64(session.ExpiresAt.UnixMilli()),
+		Member: sessionHash,
+	}).Err()
 }
 
 // GetOrCreateStickySession 获取或创建粘性会话
+// 粘性会话数达到上限且处理方式为 skip 时返回 ErrStickySessionCapReached，调用方应按无状态方式路由
 func (c *Client) GetOrCreateStickySession(ctx context.Context, sessionHash, accountID, accountType string, ttl time.Duration) (*StickySession, bool, error) {
 	// 先尝试获取
 	session, err := c.GetStickySession(ctx, sessionHash)
@@ -254,14 +271,14 @@ func (c *Client) GetOrCreateStickySession(ctx context.Context, sessionHash, acco
 	return session, true, nil
 }
 
-// GetAllStickySessions 获取所有粘性会话
+// GetAllStickySessions 获取所有粘性会话（从只读副本读取）
 func (c *Client) GetAllStickySessions(ctx context.Context) ([]*StickySession, error) {
-	client, err := c.GetClientSafe()
+	client, err := c.GetReadClientSafe()
 	if err != nil {
 		return nil, err
 	}
 
-	keys, err := c.ScanKeys(ctx, PrefixStickySession+"*", 1000)
+	keys, err := c.ScanReadKeys(ctx, PrefixStickySession+"*", 1000)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 达到粘性会话上限时的处理方式
+const (
+	// StickySessionCapEvict 淘汰最早到期的会话，为新会话腾出位置
+	StickySessionCapEvict = "evict"
+	// StickySessionCapSkip 不绑定新会话，本次请求按无状态方式路由
+	StickySessionCapSkip = "skip"
+)
+
+// ErrStickySessionCapReached 粘性会话数已达上限且处理方式为 skip
+var ErrStickySessionCapReached = errors.New("sticky session cap reached")
+
+// 登记粘性会话脚本：清理已过期条目，已登记的会话仅刷新到期时间；
+// 新会话超出上限时按方式淘汰最早到期的会话或拒绝登记。返回 {是否登记, 被淘汰的会话...}
+const luaStickySessionReserve = `
+local index = KEYS[1]
+local member = ARGV[1]
+local expireAt = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local maxCount = tonumber(ARGV[4])
+local evict = ARGV[5] == '1'
+
+redis.call('ZREMRANGEBYSCORE', index, '-inf', now)
+
+if redis.call('ZSCORE', index, member) then
+    redis.call('ZADD', index, expireAt, member)
+    return {1}
+end
+
+local result = {1}
+local count = redis.call('ZCARD', index)
+if maxCount > 0 and count >= maxCount then
+    if not evict then
+        return {0}
+    end
+    local oldest = redis.call('ZRANGE', index, 0, count - maxCount)
+    for _, m in ipairs(oldest) do
+        redis.call('ZREM', index, m)
+        table.insert(result, m)
+    end
+end
+
+redis.call('ZADD', index, expireAt, member)
+return result
+`
+
+// stickySessionMaxCount 全局活跃粘性会话上限（0 表示不限制）
+func stickySessionMaxCount() int {
+	if config.Cfg != nil && config.Cfg.System.StickySessionMaxCount > 0 {
+		return config.Cfg.System.StickySessionMaxCount
+	}
+	return 0
+}
+
+// stickySessionCapEvicts 达到上限时是否淘汰最早到期的会话（默认淘汰）
+func stickySessionCapEvicts() bool {
+	return config.Cfg == nil || !strings.EqualFold(config.Cfg.System.StickySessionCapMode, StickySessionCapSkip)
+}
+
+// reserveStickySession 在会话索引中登记会话，超出上限时淘汰最早到期的会话（删除其绑定）或返回 ErrStickySessionCapReached
+func (c *Client) reserveStickySession(ctx context.Context, client *goredis.Client, sessionHash string, expireAt, now time.Time) error {
+	maxCount := stickySessionMaxCount()
+	if maxCount == 0 {
+		// 不限制时无需脚本，仅维护索引供计数使用
+		_, err := client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, KeyStickySessionIndex, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+			pipe.ZAdd(ctx, KeyStickySessionIndex, goredis.Z{Score: float64(expireAt.UnixMilli()), Member: sessionHash})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index sticky session: %w", err)
+		}
+		return nil
+	}
+
+	evict := "0"
+	if stickySessionCapEvicts() {
+		evict = "1"
+	}
+
+	result, err := client.Eval(ctx, luaStickySessionReserve, []string{KeyStickySessionIndex},
+		sessionHash, expireAt.UnixMilli(), now.UnixMilli(), maxCount, evict).Result()
+	if err != nil {
+		return fmt.Errorf("failed to reserve sticky session: %w", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) == 0 {
+		return fmt.Errorf("unexpected result type from sticky session reserve: %T", result)
+	}
+	if reserved, _ := values[0].(int64); reserved != 1 {
+		return ErrStickySessionCapReached
+	}
+	if len(values) == 1 {
+		return nil
+	}
+
+	evicted := make([]string, 0, len(values)-1)
+	for _, v := range values[1:] {
+		if member, ok := v.(string); ok {
+			evicted = append(evicted, PrefixStickySession+member)
+		}
+	}
+	if err := client.Del(ctx, evicted...).Err(); err != nil {
+		logger.Warn("Failed to delete evicted sticky sessions", zap.Int("count", len(evicted)), zap.Error(err))
+	}
+	logger.Info("Evicted sticky sessions over cap",
+		zap.Int("evicted", len(evicted)),
+		zap.Int("maxCount", maxCount))
+	return nil
+}
+
+// CountStickySessions 获取会话索引中未过期的粘性会话数
+func (c *Client) CountStickySessions(ctx context.Context) (int64, error) {
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return 0, err
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return client.ZCount(ctx, KeyStickySessionIndex, "("+now, "+inf").Result()
+}
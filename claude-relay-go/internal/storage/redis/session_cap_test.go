@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func setStickySessionCap(t *testing.T, maxCount int, mode string) {
+	t.Helper()
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{StickySessionMaxCount: maxCount, StickySessionCapMode: mode}}
+}
+
+func TestGetOrCreateStickySession_EvictsOldestOverCap(t *testing.T) {
+	setStickySessionCap(t, 2, StickySessionCapEvict)
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for i, hash := range []string{"s1", "s2", "s3"} {
+		ttl := time.Duration(i+1) * time.Hour
+		if _, created, err := c.GetOrCreateStickySession(ctx, hash, "acc-"+hash, "claude", ttl); err != nil || !created {
+			t.Fatalf("GetOrCreateStickySession(%s) = created %v, err %v; want created", hash, created, err)
+		}
+	}
+
+	if _, ok := hook.strings[PrefixStickySession+"s1"]; ok {
+		t.Error("oldest session s1 should be evicted")
+	}
+	for _, hash := range []string{"s2", "s3"} {
+		if _, ok := hook.strings[PrefixStickySession+hash]; !ok {
+			t.Errorf("session %s should still be bound", hash)
+		}
+	}
+
+	count, err := c.CountStickySessions(ctx)
+	if err != nil {
+		t.Fatalf("CountStickySessions() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountStickySessions() = %d, want 2", count)
+	}
+
+	// 已存在的会话不受上限影响
+	session, created, err := c.GetOrCreateStickySession(ctx, "s2", "acc-other", "claude", time.Hour)
+	if err != nil || created || session == nil || session.AccountID != "acc-s2" {
+		t.Errorf("existing session = %+v, created %v, err %v; want acc-s2 unchanged", session, created, err)
+	}
+}
+
+func TestGetOrCreateStickySession_SkipsBindingOverCap(t *testing.T) {
+	setStickySessionCap(t, 2, StickySessionCapSkip)
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for _, hash := range []string{"s1", "s2"} {
+		if _, _, err := c.GetOrCreateStickySession(ctx, hash, "acc-"+hash, "claude", time.Hour); err != nil {
+			t.Fatalf("GetOrCreateStickySession(%s) error = %v", hash, err)
+		}
+	}
+
+	session, created, err := c.GetOrCreateStickySession(ctx, "s3", "acc-s3", "claude", time.Hour)
+	if !errors.Is(err, ErrStickySessionCapReached) {
+		t.Fatalf("GetOrCreateStickySession(s3) error = %v, want ErrStickySessionCapReached", err)
+	}
+	if session != nil || created {
+		t.Errorf("skipped session = %+v, created %v; want nil, false", session, created)
+	}
+	if _, ok := hook.strings[PrefixStickySession+"s3"]; ok {
+		t.Error("skipped session should not be written")
+	}
+	for _, hash := range []string{"s1", "s2"} {
+		if _, ok := hook.strings[PrefixStickySession+hash]; !ok {
+			t.Errorf("session %s should not be evicted in skip mode", hash)
+		}
+	}
+
+	// 删除会话后释放名额
+	if err := c.DeleteStickySession(ctx, "s1"); err != nil {
+		t.Fatalf("DeleteStickySession() error = %v", err)
+	}
+	if _, created, err := c.GetOrCreateStickySession(ctx, "s3", "acc-s3", "claude", time.Hour); err != nil || !created {
+		t.Errorf("GetOrCreateStickySession(s3) after delete = created %v, err %v; want created", created, err)
+	}
+}
+
+func TestGetOrCreateStickySession_UncappedSkipsReserveScript(t *testing.T) {
+	setStickySessionCap(t, 0, StickySessionCapSkip)
+	hook := newMemoryRedisHook()
+	hook.beforeEval = func(*memoryRedisHook) { t.Error("reserve script should not run when uncapped") }
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for _, hash := range []string{"s1", "s2", "s3"} {
+		if _, created, err := c.GetOrCreateStickySession(ctx, hash, "acc-"+hash, "claude", time.Hour); err != nil || !created {
+			t.Fatalf("GetOrCreateStickySession(%s) = created %v, err %v; want created", hash, created, err)
+		}
+	}
+
+	count, err := c.CountStickySessions(ctx)
+	if err != nil {
+		t.Fatalf("CountStickySessions() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("CountStickySessions() = %d, want 3", count)
+	}
+}
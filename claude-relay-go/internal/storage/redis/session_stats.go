@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxStickySessionScanKeys 统计粘性会话时最多扫描的 Key 数量
+	MaxStickySessionScanKeys = 10000
+	// stickySessionBatchSize 粘性会话批量读取大小
+	stickySessionBatchSize = 500
+)
+
+// GetStickySessionCountsByAccount 按账户统计当前绑定的粘性会话数（用于发现热点账户）
+// 最多扫描 MaxStickySessionScanKeys 个会话，超出部分不计入
+func (c *Client) GetStickySessionCountsByAccount(ctx context.Context) (map[string]int, error) {
+	return c.getStickySessionCountsByAccountAt(ctx, time.Now())
+}
+
+func (c *Client) getStickySessionCountsByAccountAt(ctx context.Context, now time.Time) (map[string]int, error) {
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := client.Scan(ctx, cursor, PrefixStickySession+"*", 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		if len(keys) >= MaxStickySessionScanKeys {
+			keys = keys[:MaxStickySessionScanKeys]
+			logger.Warn("Sticky session scan limit reached, counts are partial",
+				zap.Int("limit", MaxStickySessionScanKeys))
+			break
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	counts := make(map[string]int)
+	for offset := 0; offset < len(keys); offset += stickySessionBatchSize {
+		end := offset + stickySessionBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		pipe := client.Pipeline()
+		cmds := make([]*goredis.StringCmd, 0, end-offset)
+		for _, key := range keys[offset:end] {
+			cmds = append(cmds, pipe.Get(ctx, key))
+		}
+		// 会话可能在扫描后过期，单个命令失败时跳过
+		_, _ = pipe.Exec(ctx)
+
+		for _, cmd := range cmds {
+			data, err := cmd.Bytes()
+			if err != nil {
+				continue
+			}
+
+			var session StickySession
+			if err := json.Unmarshal(data, &session); err != nil || session.AccountID == "" {
+				continue
+			}
+			if !session.ExpiresAt.IsZero() && session.ExpiresAt.Before(now) {
+				continue
+			}
+			counts[session.AccountID]++
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func seedStickySession(t *testing.T, hook *memoryRedisHook, sessionHash, accountID string, expiresAt time.Time) {
+	t.Helper()
+	data, err := json.Marshal(&StickySession{SessionHash: sessionHash, AccountID: accountID, AccountType: "claude", ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("marshal sticky session: %v", err)
+	}
+	hook.strings[PrefixStickySession+sessionHash] = string(data)
+}
+
+func TestGetStickySessionCountsByAccount(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		seedStickySession(t, hook, fmt.Sprintf("hot-%d", i), "acc-hot", now.Add(time.Hour))
+	}
+	for i := 0; i < 2; i++ {
+		seedStickySession(t, hook, fmt.Sprintf("warm-%d", i), "acc-warm", now.Add(time.Hour))
+	}
+	seedStickySession(t, hook, "expired", "acc-warm", now.Add(-time.Minute))
+	hook.strings[PrefixStickySession+"corrupt"] = "not-json"
+	hook.strings[PrefixSession+"other"] = `{"accountId":"acc-hot"}` // 非粘性会话不计入
+
+	counts, err := c.getStickySessionCountsByAccountAt(ctx, now)
+	if err != nil {
+		t.Fatalf("getStickySessionCountsByAccountAt() error = %v", err)
+	}
+
+	want := map[string]int{"acc-hot": 5, "acc-warm": 2}
+	if len(counts) != len(want) {
+		t.Fatalf("counts = %v, want %v", counts, want)
+	}
+	for accountID, n := range want {
+		if counts[accountID] != n {
+			t.Errorf("counts[%s] = %d, want %d", accountID, counts[accountID], n)
+		}
+	}
+}
+
+func TestGetStickySessionCountsByAccount_Empty(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	counts, err := c.GetStickySessionCountsByAccount(context.Background())
+	if err != nil {
+		t.Fatalf("GetStickySessionCountsByAccount() error = %v", err)
+	}
+	if counts == nil || len(counts) != 0 {
+		t.Errorf("counts = %v, want empty map", counts)
+	}
+}
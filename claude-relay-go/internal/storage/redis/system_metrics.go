@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// SystemMetricsSnapshot 系统关键指标快照（分钟类指标取上一个完整分钟）
+type SystemMetricsSnapshot struct {
+	Minute        int64   `json:"minute"`        // 统计的分钟时间戳
+	Requests      int64   `json:"requests"`      // 每分钟请求数
+	AuthFailures  int64   `json:"authFailures"`  // 每分钟认证失败数
+	Concurrency   int64   `json:"concurrency"`   // 当前全局并发数
+	QueueDepth    int64   `json:"queueDepth"`    // 当前排队总数
+	CostPerMinute float64 `json:"costPerMinute"` // 每分钟成本（美元）
+}
+
+// GetSystemMetricsSnapshot 获取系统关键指标快照
+func (c *Client) GetSystemMetricsSnapshot(ctx context.Context) (*SystemMetricsSnapshot, error) {
+	return c.getSystemMetricsSnapshotAt(ctx, time.Now())
+}
+
+// getSystemMetricsSnapshotAt 按指定时间获取系统关键指标快照
+func (c *Client) getSystemMetricsSnapshotAt(ctx context.Context, now time.Time) (*SystemMetricsSnapshot, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &SystemMetricsSnapshot{Minute: getMinuteTimestamp(now) - 60}
+
+	minuteKey := fmt.Sprintf("%s%d", PrefixSystemMetrics, snapshot.Minute)
+	data, err := client.HGetAll(ctx, minuteKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system metrics: %w", err)
+	}
+	snapshot.Requests = parseInt64(data["requests"])
+	snapshot.CostPerMinute = hashCost(data, "totalCost")
+
+	if snapshot.AuthFailures, err = authFailuresInMinute(ctx, client, snapshot.Minute); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Concurrency, err = c.GetGlobalConcurrency(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get global concurrency: %w", err)
+	}
+
+	queueStats, err := c.GetGlobalQueueStats(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	snapshot.QueueDepth = queueStats.TotalQueueCount
+
+	return snapshot, nil
+}
+
+// authFailuresInMinute 汇总指定分钟桶内所有原因的认证失败次数
+func authFailuresInMinute(ctx context.Context, client *goredis.Client, minute int64) (int64, error) {
+	codes, err := client.SMembers(ctx, KeyAuthFailureCodes).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auth failure codes: %w", err)
+	}
+	if len(codes) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = authFailureKey(code, minute)
+	}
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auth failure buckets: %w", err)
+	}
+
+	var total int64
+	for _, v := range values {
+		total += parseInt64(cmdString(v))
+	}
+	return total, nil
+}
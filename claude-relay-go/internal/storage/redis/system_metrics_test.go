@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSystemMetricsSnapshot_PreviousMinute(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+	prevMinute := getMinuteTimestamp(now) - 60
+
+	hook.hashes[fmt.Sprintf("%s%d", PrefixSystemMetrics, prevMinute)] = map[string]string{"requests": "42", "totalCost": "0.75"}
+	hook.hashes[fmt.Sprintf("%s%d", PrefixSystemMetrics, getMinuteTimestamp(now))] = map[string]string{"requests": "5"}
+	hook.sets[KeyAuthFailureCodes] = map[string]bool{"invalid_api_key": true, "rate_limit_exceeded": true}
+	hook.strings[authFailureKey("invalid_api_key", prevMinute)] = "2"
+	hook.strings[authFailureKey("rate_limit_exceeded", prevMinute)] = "1"
+	hook.zsets[KeyGlobalConcurrency] = map[string]float64{
+		"key-1:req-1": float64(now.Add(time.Hour).UnixMilli()),
+		"key-1:req-2": float64(now.Add(-time.Hour).UnixMilli()), // 已过期
+	}
+	hook.strings[PrefixConcurrencyQueue+"key-1"] = "3"
+
+	snapshot, err := c.getSystemMetricsSnapshotAt(ctx, now)
+	if err != nil {
+		t.Fatalf("getSystemMetricsSnapshotAt() error = %v", err)
+	}
+	if snapshot.Minute != prevMinute || snapshot.Requests != 42 || snapshot.CostPerMinute != 0.75 {
+		t.Errorf("snapshot = %+v, want previous minute requests 42 cost 0.75", snapshot)
+	}
+	if snapshot.AuthFailures != 3 || snapshot.Concurrency != 1 || snapshot.QueueDepth != 3 {
+		t.Errorf("snapshot = %+v, want authFailures 3 concurrency 1 queueDepth 3", snapshot)
+	}
+}
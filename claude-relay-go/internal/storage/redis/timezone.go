@@ -18,6 +18,12 @@ func getTimezoneOffset() time.Duration {
 	return time.Duration(DefaultTimezoneOffset) * time.Hour
 }
 
+// UsageLocation 获取统计使用的时区（按配置的偏移量，与每日/每月统计桶一致，不受服务器本地时区影响）
+func UsageLocation() *time.Location {
+	offset := getTimezoneOffset()
+	return time.FixedZone(fmt.Sprintf("UTC%+d", int(offset.Hours())), int(offset.Seconds()))
+}
+
 // getDateInTimezone 获取指定时区的日期
 func getDateInTimezone(t time.Time) time.Time {
 	return t.UTC().Add(getTimezoneOffset())
@@ -3,6 +3,8 @@ package redis
 import (
 	"testing"
 	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
 )
 
 func TestGetDateStringInTimezone(t *testing.T) {
@@ -169,3 +171,36 @@ func TestGetDateInTimezone(t *testing.T) {
 		t.Errorf("getDateInTimezone() = %v, want %v", result, expected)
 	}
 }
+
+func TestWeeklyResetAlignsWithDailyBuckets(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{TimezoneOffset: -5}}
+
+	// UTC 周一 03:00 在 UTC-5 仍是周日 22:00，属于上一周
+	sundayLate := time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)
+	if got := getDateStringInTimezone(sundayLate); got != "2025-03-09" {
+		t.Fatalf("daily bucket = %s, want 2025-03-09", got)
+	}
+	if got := getWeekStartDate(sundayLate); got != "2025-03-03" {
+		t.Errorf("week start = %s, want 2025-03-03", got)
+	}
+
+	reset := NextWeeklyReset(sundayLate)
+	if want := time.Date(2025, 3, 10, 5, 0, 0, 0, time.UTC); !reset.Equal(want) {
+		t.Errorf("reset = %v, want %v (Monday 00:00 UTC-5)", reset, want)
+	}
+	// 重置时刻即每日统计桶的边界
+	if !reset.Equal(NextDailyReset(sundayLate)) {
+		t.Errorf("reset %v should equal the next daily reset %v", reset, NextDailyReset(sundayLate))
+	}
+	if got := getDateStringInTimezone(reset); got != "2025-03-10" {
+		t.Errorf("daily bucket at reset = %s, want 2025-03-10", got)
+	}
+	if got := getWeekStartDate(reset); got != "2025-03-10" {
+		t.Errorf("week start at reset = %s, want 2025-03-10", got)
+	}
+	if got := getWeekStartDate(reset.Add(-time.Nanosecond)); got != "2025-03-03" {
+		t.Errorf("week start just before reset = %s, want 2025-03-03", got)
+	}
+}
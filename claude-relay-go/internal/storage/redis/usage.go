@@ -195,11 +195,25 @@ func (uc *usageContext) incrSystemMetrics(ctx context.Context, pipe goredis.Pipe
 	pipe.HIncrBy(ctx, systemMinuteKey, "cacheCreateTokens", uc.params.CacheCreateTokens)
 	pipe.HIncrBy(ctx, systemMinuteKey, "cacheReadTokens", uc.params.CacheReadTokens)
 
+	pipe.Expire(ctx, systemMinuteKey, systemMetricsTTL())
+}
+
+// systemMetricsTTL 系统分钟统计的过期时间（指标窗口的两倍）
+func systemMetricsTTL() time.Duration {
 	metricsWindow := 5
 	if config.Cfg != nil {
 		metricsWindow = config.Cfg.System.MetricsWindow
 	}
-	pipe.Expire(ctx, systemMinuteKey, time.Duration(metricsWindow*60*2)*time.Second)
+	return time.Duration(metricsWindow*60*2) * time.Second
+}
+
+// incrAll 分模块增加全部统计
+func (uc *usageContext) incrAll(ctx context.Context, pipe goredis.Pipeliner, now time.Time) {
+	uc.incrAPIKeyTotalUsage(ctx, pipe)
+	uc.incrTimeBasedUsage(ctx, pipe)
+	uc.incrModelUsage(ctx, pipe)
+	uc.incrKeyModelUsage(ctx, pipe)
+	uc.incrSystemMetrics(ctx, pipe, now)
 }
 
 // IncrementTokenUsage 增加 Token 使用量（与 Node.js 完全兼容）
@@ -210,18 +224,11 @@ func (c *Client) IncrementTokenUsage(ctx context.Context, params TokenUsageParam
 	}
 
 	now := time.Now()
-	uc := newUsageContext(params, now)
 	pipe := client.Pipeline()
-
-	// 分模块增加统计
-	uc.incrAPIKeyTotalUsage(ctx, pipe)
-	uc.incrTimeBasedUsage(ctx, pipe)
-	uc.incrModelUsage(ctx, pipe)
-	uc.incrKeyModelUsage(ctx, pipe)
-	uc.incrSystemMetrics(ctx, pipe, now)
+	newUsageContext(params, now).incrAll(ctx, pipe, now)
 
 	// 执行管道
-	_, err = pipe.Exec(ctx)
+	err = execIncrPipeline(ctx, client, pipe)
 	if err != nil {
 		logger.Error("Failed to increment token usage", zap.Error(err))
 		return err
@@ -350,8 +357,31 @@ func (c *Client) IncrementAccountUsage(ctx context.Context, params TokenUsagePar
 	pipe.HIncrBy(ctx, accountModelHourlyKey, "requests", 1)
 	pipe.Expire(ctx, accountModelHourlyKey, TTLUsageHourly)
 
-	_, err = pipe.Exec(ctx)
-	return err
+	return execIncrPipeline(ctx, client, pipe)
+}
+
+// GetAccountDailyTokens 获取账户指定日期的总 Token 数（allTokens）
+func (c *Client) GetAccountDailyTokens(ctx context.Context, accountID string, date time.Time) (int64, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return 0, err
+	}
+
+	key := fmt.Sprintf("account_usage:daily:%s:%s", accountID, getDateStringInTimezone(date))
+	val, err := client.HGet(ctx, key, "allTokens").Result()
+	if err != nil {
+		if err == goredis.Nil {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get account daily tokens: %w", err)
+	}
+	return parseInt64(val), nil
+}
+
+// NextDailyReset 获取下一次每日统计重置时间（配置时区的次日零点）
+func NextDailyReset(now time.Time) time.Time {
+	_, end, _ := periodBounds(CostPeriodDaily, now)
+	return end
 }
 
 // GetUsageStats 获取使用统计
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 批量使用量上报
+const (
+	// MaxUsageBatchSize 单次批量上报的最大条目数
+	MaxUsageBatchSize = 1000
+	// usageBatchChunkSize 每个管道处理的条目数
+	usageBatchChunkSize = 100
+)
+
+// UsageBatchEntry 批量上报中的单条使用量
+// IdempotencyKey 非空时同一 key 在 TTLUsageIdempotency 内只计入一次
+type UsageBatchEntry struct {
+	TokenUsageParams
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// UsageBatchResult 单条使用量的处理结果
+type UsageBatchResult struct {
+	Index     int    `json:"index"`
+	Success   bool   `json:"success"`
+	Duplicate bool   `json:"duplicate,omitempty"` // 幂等键已处理过，本次未重复计入
+	Error     string `json:"error,omitempty"`
+}
+
+// IncrementTokenUsageBatch 批量增加 Token 使用量（按块使用管道，返回每条的处理结果）
+func (c *Client) IncrementTokenUsageBatch(ctx context.Context, entries []UsageBatchEntry) ([]UsageBatchResult, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]UsageBatchResult, len(entries))
+	for i := range entries {
+		results[i].Index = i
+	}
+
+	now := time.Now()
+	for start := 0; start < len(entries); start += usageBatchChunkSize {
+		end := start + usageBatchChunkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		c.incrementUsageChunk(ctx, client, entries, results, start, end, now)
+	}
+	return results, nil
+}
+
+// incrementUsageChunk 处理 [start, end) 范围内的条目
+func (c *Client) incrementUsageChunk(ctx context.Context, client *goredis.Client, entries []UsageBatchEntry, results []UsageBatchResult, start, end int, now time.Time) {
+	// 1. 占用幂等键（同一批次内重复的 key 也只有第一条生效）
+	claims := make(map[int]*goredis.BoolCmd)
+	pipe := client.Pipeline()
+	for i := start; i < end; i++ {
+		if entries[i].KeyID == "" {
+			results[i].Error = "keyId is required"
+			continue
+		}
+		if entries[i].IdempotencyKey != "" {
+			claims[i] = pipe.SetNX(ctx, PrefixUsageIdempotency+entries[i].IdempotencyKey, now.UnixMilli(), TTLUsageIdempotency)
+		}
+	}
+	if len(claims) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			for i := range claims {
+				results[i].Error = err.Error()
+			}
+			logger.Error("Failed to claim usage idempotency keys", zap.Error(err))
+		}
+	}
+
+	// 2. 计入未处理过的条目
+	var pending []int
+	var claimed []string
+	pipe = client.Pipeline()
+	for i := start; i < end; i++ {
+		if results[i].Error != "" {
+			continue
+		}
+		if claim, ok := claims[i]; ok {
+			if !claim.Val() {
+				results[i].Success = true
+				results[i].Duplicate = true
+				continue
+			}
+			claimed = append(claimed, PrefixUsageIdempotency+entries[i].IdempotencyKey)
+		}
+		newUsageContext(entries[i].TokenUsageParams, now).incrAll(ctx, pipe, now)
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := execIncrPipeline(ctx, client, pipe); err != nil {
+		logger.Error("Failed to increment batched token usage", zap.Int("entries", len(pending)), zap.Error(err))
+		for _, i := range pending {
+			results[i].Error = err.Error()
+		}
+		// 没有任何计数生效时释放幂等键，允许调用方重试失败的条目（部分生效时保留，避免重试重复计数）
+		var pipeErr *PartialPipelineError
+		if len(claimed) > 0 && !(errors.As(err, &pipeErr) && pipeErr.Partial()) {
+			client.Del(ctx, claimed...)
+		}
+		return
+	}
+	for _, i := range pending {
+		results[i].Success = true
+	}
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIncrementTokenUsageBatch_UpdatesBucketsAcrossKeys(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	entries := []UsageBatchEntry{
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-a", Model: "claude-sonnet-4", InputTokens: 100, OutputTokens: 10}, IdempotencyKey: "req-1"},
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-a", Model: "claude-haiku", InputTokens: 50, OutputTokens: 5, CacheReadTokens: 20}},
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-b", Model: "claude-sonnet-4", InputTokens: 7, OutputTokens: 3}, IdempotencyKey: "req-2"},
+		// 同一批次内重复的幂等键只计入一次
+		{TokenUsageParams: TokenUsageParams{KeyID: "key-b", Model: "claude-sonnet-4", InputTokens: 7, OutputTokens: 3}, IdempotencyKey: "req-2"},
+		{TokenUsageParams: TokenUsageParams{Model: "claude-sonnet-4", InputTokens: 1}},
+	}
+
+	results, err := c.IncrementTokenUsageBatch(ctx, entries)
+	if err != nil {
+		t.Fatalf("IncrementTokenUsageBatch() error = %v", err)
+	}
+	wantSuccess := []bool{true, true, true, true, false}
+	wantDuplicate := []bool{false, false, false, true, false}
+	for i, r := range results {
+		if r.Index != i || r.Success != wantSuccess[i] || r.Duplicate != wantDuplicate[i] {
+			t.Errorf("result %d = %+v, want success=%v duplicate=%v", i, r, wantSuccess[i], wantDuplicate[i])
+		}
+	}
+	if results[4].Error == "" {
+		t.Error("entry without keyId should report an error")
+	}
+
+	now := time.Now()
+	date := getDateStringInTimezone(now)
+	month := getMonthStringInTimezone(now)
+	hour := getHourStringInTimezone(now)
+	checks := []struct {
+		key, field, want string
+	}{
+		{"usage:key-a", "totalRequests", "2"},
+		{"usage:key-a", "totalTokens", "165"},
+		{"usage:key-a", "totalAllTokens", "185"},
+		{"usage:key-b", "totalRequests", "1"},
+		{"usage:key-b", "totalTokens", "10"},
+		{"usage:daily:key-a:" + date, "requests", "2"},
+		{"usage:monthly:key-b:" + month, "allTokens", "10"},
+		{"usage:hourly:key-a:" + hour, "inputTokens", "150"},
+		{"usage:model:daily:claude-sonnet-4:" + date, "requests", "2"},
+		{"usage:key-a:model:daily:claude-haiku:" + date, "cacheReadTokens", "20"},
+	}
+	for _, check := range checks {
+		if got := hook.hashes[check.key][check.field]; got != check.want {
+			t.Errorf("%s %s = %q, want %q", check.key, check.field, got, check.want)
+		}
+	}
+
+	// 重放整个批次：带幂等键的条目不再计入
+	results, err = c.IncrementTokenUsageBatch(ctx, entries[:3])
+	if err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	if !results[0].Duplicate || results[1].Duplicate || !results[2].Duplicate {
+		t.Errorf("replay results = %+v", results)
+	}
+	if got := hook.hashes["usage:key-a"]["totalRequests"]; got != "3" {
+		t.Errorf("key-a totalRequests after replay = %q, want 3", got)
+	}
+	if got := hook.hashes["usage:key-b"]["totalRequests"]; got != "1" {
+		t.Errorf("key-b totalRequests after replay = %q, want 1", got)
+	}
+}
+
+func TestIncrementTokenUsageBatch_SpansMultipleChunks(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	entries := make([]UsageBatchEntry, usageBatchChunkSize*2+5)
+	for i := range entries {
+		entries[i].TokenUsageParams = TokenUsageParams{KeyID: "key-a", Model: "claude-sonnet-4", InputTokens: 1}
+	}
+
+	results, err := c.IncrementTokenUsageBatch(context.Background(), entries)
+	if err != nil {
+		t.Fatalf("IncrementTokenUsageBatch() error = %v", err)
+	}
+	for _, r := range results {
+		if !r.Success {
+			t.Fatalf("result %+v failed", r)
+		}
+	}
+	if got := hook.hashes["usage:key-a"]["totalRequests"]; got != "205" {
+		t.Errorf("totalRequests = %q, want 205", got)
+	}
+}
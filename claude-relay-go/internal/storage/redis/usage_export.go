@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// usageExportChunkDays 导出时每个管道读取的天数
+const usageExportChunkDays = 31
+
+// UsageExportRow 用量导出中的单日数据
+type UsageExportRow struct {
+	Date              string  `json:"date"`
+	InputTokens       int64   `json:"inputTokens"`
+	OutputTokens      int64   `json:"outputTokens"`
+	CacheCreateTokens int64   `json:"cacheCreateTokens"`
+	CacheReadTokens   int64   `json:"cacheReadTokens"`
+	Requests          int64   `json:"requests"`
+	TotalCost         float64 `json:"totalCost"`
+}
+
+// UsageExportDates 最近 days 天（含今天，配置时区）的起止日期
+func UsageExportDates(now time.Time, days int) (from, to string) {
+	return getDateStringInTimezone(now.AddDate(0, 0, -(days - 1))), getDateStringInTimezone(now)
+}
+
+// ExportDailyUsage 按日期升序逐日导出 API Key 最近 days 天（含今天）的用量与成本，无数据的日期为 0
+// 成本取自 GetCostHistory；Token 与请求数读取每日统计（与按模型统计之和一致，避免逐日 SCAN 模型键）
+func (c *Client) ExportDailyUsage(ctx context.Context, keyID string, days int, emit func(*UsageExportRow) error) error {
+	if days <= 0 || days > MaxCostHistoryDays {
+		return fmt.Errorf("days must be between 1 and %d", MaxCostHistoryDays)
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return err
+	}
+
+	history, err := c.GetCostHistory(ctx, keyID, days)
+	if err != nil {
+		return err
+	}
+	costs := make(map[string]float64, len(history))
+	for _, record := range history {
+		costs[record.Date] = record.TotalCost
+	}
+
+	now := time.Now()
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = getDateStringInTimezone(now.AddDate(0, 0, i-(days-1)))
+	}
+
+	for start := 0; start < len(dates); start += usageExportChunkDays {
+		chunk := dates[start:min(start+usageExportChunkDays, len(dates))]
+
+		pipe := client.Pipeline()
+		cmds := make([]*goredis.MapStringStringCmd, len(chunk))
+		for i, dateStr := range chunk {
+			cmds[i] = pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, dateStr))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+			return fmt.Errorf("failed to get daily usage: %w", err)
+		}
+
+		for i, dateStr := range chunk {
+			usage := parseUsageData(cmds[i].Val())
+			if err := emit(&UsageExportRow{
+				Date:              dateStr,
+				InputTokens:       usage.InputTokens,
+				OutputTokens:      usage.OutputTokens,
+				CacheCreateTokens: usage.CacheCreateTokens,
+				CacheReadTokens:   usage.CacheReadTokens,
+				Requests:          usage.RequestCount,
+				TotalCost:         costs[dateStr],
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExportDailyUsage_RowsInDateOrder(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	now := time.Now()
+	today := getDateStringInTimezone(now)
+	twoDaysAgo := getDateStringInTimezone(now.AddDate(0, 0, -2))
+	hook.hashes[PrefixUsageDaily+"key-a:"+today] = map[string]string{
+		"inputTokens": "100", "outputTokens": "50", "cacheCreateTokens": "10", "cacheReadTokens": "5", "requests": "3",
+	}
+	hook.hashes["usage:cost:daily:key-a:"+today] = map[string]string{"totalCost": "0.42"}
+	hook.strings["usage:cost:daily:key-a:"+twoDaysAgo] = "1.5" // 旧格式成本，无用量记录
+
+	var rows []*UsageExportRow
+	if err := c.ExportDailyUsage(ctx, "key-a", 3, func(row *UsageExportRow) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("ExportDailyUsage() error = %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("rows = %d, want 3", len(rows))
+	}
+	if rows[0].Date != twoDaysAgo || rows[0].TotalCost != 1.5 || rows[0].Requests != 0 {
+		t.Errorf("rows[0] = %+v, want %s with legacy cost only", rows[0], twoDaysAgo)
+	}
+	if rows[1].Requests != 0 || rows[1].TotalCost != 0 {
+		t.Errorf("rows[1] = %+v, want an empty day", rows[1])
+	}
+	want := UsageExportRow{Date: today, InputTokens: 100, OutputTokens: 50, CacheCreateTokens: 10, CacheReadTokens: 5, Requests: 3, TotalCost: 0.42}
+	if *rows[2] != want {
+		t.Errorf("rows[2] = %+v, want %+v", *rows[2], want)
+	}
+
+	from, to := UsageExportDates(now, 3)
+	if from != twoDaysAgo || to != today {
+		t.Errorf("UsageExportDates() = %s, %s; want %s, %s", from, to, twoDaysAgo, today)
+	}
+	if err := c.ExportDailyUsage(ctx, "key-a", MaxCostHistoryDays+1, func(*UsageExportRow) error { return nil }); err == nil {
+		t.Error("ExportDailyUsage() should reject days above the limit")
+	}
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// HourlyUsage 某一小时的使用统计（配置时区）
+type HourlyUsage struct {
+	Hour int `json:"hour"`
+	UsageStats
+}
+
+// GetHourlyUsage 获取 API Key 指定日期 24 个小时的使用统计（无数据的小时为 0）
+// 每小时统计保留 7 天，更早的日期全部为 0
+func (c *Client) GetHourlyUsage(ctx context.Context, keyID string, date time.Time) ([]HourlyUsage, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	dateStr := getDateStringInTimezone(date)
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, 24)
+	for hour := range cmds {
+		cmds[hour] = pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s:%02d", PrefixUsageHourly, keyID, dateStr, hour))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get hourly usage: %w", err)
+	}
+
+	result := make([]HourlyUsage, len(cmds))
+	for hour, cmd := range cmds {
+		result[hour] = HourlyUsage{Hour: hour, UsageStats: *parseUsageData(cmd.Val())}
+	}
+	return result, nil
+}
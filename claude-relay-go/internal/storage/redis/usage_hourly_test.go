@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetHourlyUsage_FillsEmptyHours(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	hourly := func(dateStr string, hour, requests, tokens int) {
+		hook.hashes[fmt.Sprintf("%s%s:%s:%02d", PrefixUsageHourly, "key-1", dateStr, hour)] = map[string]string{
+			"requests":  fmt.Sprint(requests),
+			"tokens":    fmt.Sprint(tokens),
+			"allTokens": fmt.Sprint(tokens + 10),
+		}
+	}
+	hourly("2025-03-01", 0, 2, 200)
+	hourly("2025-03-01", 9, 5, 500)
+	hourly("2025-03-01", 23, 1, 100)
+	hourly("2025-03-02", 0, 99, 9900) // 其他日期不计入
+
+	usage, err := c.GetHourlyUsage(context.Background(), "key-1", date)
+	if err != nil {
+		t.Fatalf("GetHourlyUsage() error = %v", err)
+	}
+	if len(usage) != 24 {
+		t.Fatalf("got %d hours, want 24", len(usage))
+	}
+
+	want := map[int][2]int64{0: {2, 200}, 9: {5, 500}, 23: {1, 100}}
+	for i, h := range usage {
+		if h.Hour != i {
+			t.Errorf("usage[%d].Hour = %d", i, h.Hour)
+		}
+		w := want[i]
+		if h.RequestCount != w[0] || h.TotalTokens != w[1] {
+			t.Errorf("hour %d = %d requests, %d tokens; want %d, %d", i, h.RequestCount, h.TotalTokens, w[0], w[1])
+		}
+	}
+	if usage[9].AllTokens != 510 {
+		t.Errorf("hour 9 allTokens = %d, want 510", usage[9].AllTokens)
+	}
+}
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/catstream/claude-relay-go/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// modelUsagePurgeBatchSize 单次 DEL 的最大键数
+const modelUsagePurgeBatchSize = 500
+
+// modelUsagePeriods 模型使用统计的时间粒度
+var modelUsagePeriods = map[string]bool{"daily": true, "monthly": true, "hourly": true}
+
+// modelUsageDateSuffix 模型使用统计键末尾的日期（日、月或小时）
+var modelUsageDateSuffix = regexp.MustCompile(`:\d{4}-\d{2}(-\d{2}(:\d{2})?)?$`)
+
+// escapeScanPattern 转义 SCAN MATCH 中的通配字符
+func escapeScanPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// modelUsageScanPatterns 指定模型的使用统计键扫描模式（全局、按 Key、按账户）
+func modelUsageScanPatterns(model string) []string {
+	m := escapeScanPattern(model)
+	return []string{
+		fmt.Sprintf("usage:model:*:%s:*", m),
+		fmt.Sprintf("usage:*:model:*:%s:*", m),
+		fmt.Sprintf("account_usage:model:*:%s:*", m),
+	}
+}
+
+// isModelUsageKey 检查键是否为指定模型的使用统计键（模型须为完整的一段，避免误匹配名称相近的模型）
+// 支持的格式：
+//   - usage:model:<period>:<model>:<date>
+//   - usage:<keyId>:model:<period>:<model>:<date>
+//   - account_usage:model:<period>:<accountId>:<model>:<date>
+func isModelUsageKey(key, model string) bool {
+	loc := modelUsageDateSuffix.FindStringIndex(key)
+	if loc == nil {
+		return false
+	}
+	head, ok := strings.CutSuffix(key[:loc[0]], ":"+model)
+	if !ok {
+		return false
+	}
+
+	parts := strings.Split(head, ":")
+	switch {
+	case len(parts) == 3 && parts[0] == "usage" && parts[1] == "model":
+		return modelUsagePeriods[parts[2]]
+	case len(parts) == 4 && parts[0] == "usage" && parts[1] != "" && parts[2] == "model":
+		return modelUsagePeriods[parts[3]]
+	case len(parts) == 4 && parts[0] == "account_usage" && parts[1] == "model" && parts[3] != "":
+		return modelUsagePeriods[parts[2]]
+	}
+	return false
+}
+
+// FindModelUsageKeys 查找指定模型（按归一化名称）在所有 Key 与账户下的使用统计键
+func (c *Client) FindModelUsageKeys(ctx context.Context, model string) ([]string, error) {
+	normalized := normalizeModelName(model)
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, pattern := range modelUsageScanPatterns(normalized) {
+		matched, err := c.ScanKeys(ctx, pattern, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model usage keys: %w", err)
+		}
+		for _, key := range matched {
+			if !seen[key] && isModelUsageKey(key, normalized) {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// PurgeModelUsage 删除指定模型（按归一化名称）在所有 Key 与账户下的使用统计（用于清理已下线的模型）
+func (c *Client) PurgeModelUsage(ctx context.Context, model string) (int, error) {
+	keys, err := c.FindModelUsageKeys(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for start := 0; start < len(keys); start += modelUsagePurgeBatchSize {
+		end := min(start+modelUsagePurgeBatchSize, len(keys))
+		n, err := client.Del(ctx, keys[start:end]...).Result()
+		deleted += int(n)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete model usage keys: %w", err)
+		}
+	}
+
+	logger.Info("Model usage purged",
+		zap.String("model", normalizeModelName(model)),
+		zap.Int("deleted", deleted))
+
+	return deleted, nil
+}
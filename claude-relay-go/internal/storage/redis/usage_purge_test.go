@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func TestPurgeModelUsage_OnlyTargetModel(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	target := []string{
+		"usage:model:daily:claude-3-opus:2024-06-10",
+		"usage:model:monthly:claude-3-opus:2024-06",
+		"usage:model:hourly:claude-3-opus:2024-06-10:09",
+		"usage:key-1:model:daily:claude-3-opus:2024-06-10",
+		"usage:key-2:model:hourly:claude-3-opus:2024-06-10:23",
+		"account_usage:model:daily:acct-1:claude-3-opus:2024-06-10",
+	}
+	spared := []string{
+		// 名称相近的模型
+		"usage:model:daily:claude-3-opus-plus:2024-06-10",
+		"usage:key-1:model:daily:claude-3-opus-plus:2024-06-10",
+		"account_usage:model:daily:acct-1:xclaude-3-opus:2024-06-10",
+		// 其他统计
+		"usage:key-1",
+		"usage:daily:key-1:2024-06-10",
+	}
+	for _, key := range append(append([]string{}, target...), spared...) {
+		hook.hashes[key] = map[string]string{"requests": "1"}
+	}
+
+	// 带日期后缀的模型名按归一化名称匹配
+	keys, err := c.FindModelUsageKeys(ctx, "claude-3-opus-20240229")
+	if err != nil {
+		t.Fatalf("FindModelUsageKeys() error = %v", err)
+	}
+	sort.Strings(keys)
+	want := append([]string{}, target...)
+	sort.Strings(want)
+	if len(keys) != len(want) {
+		t.Fatalf("dry run keys = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("dry run keys = %v, want %v", keys, want)
+		}
+	}
+	if len(hook.hashes) != len(target)+len(spared) {
+		t.Fatal("dry run must not delete keys")
+	}
+
+	deleted, err := c.PurgeModelUsage(ctx, "claude-3-opus")
+	if err != nil {
+		t.Fatalf("PurgeModelUsage() error = %v", err)
+	}
+	if deleted != len(target) {
+		t.Errorf("deleted = %d, want %d", deleted, len(target))
+	}
+	for _, key := range target {
+		if _, ok := hook.hashes[key]; ok {
+			t.Errorf("%s should be purged", key)
+		}
+	}
+	for _, key := range spared {
+		if _, ok := hook.hashes[key]; !ok {
+			t.Errorf("%s should be spared", key)
+		}
+	}
+}
+
+func TestIsModelUsageKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"usage:model:daily:gpt-4o:2024-06-10", true},
+		{"usage:model:weekly:gpt-4o:2024-06-10", false},
+		{"usage:key-1:model:monthly:gpt-4o:2024-06", true},
+		{"account_usage:model:hourly:acct-1:gpt-4o:2024-06-10:05", true},
+		{"usage:model:daily:gpt-4o-mini:2024-06-10", false},
+		{"usage:model:daily:gpt-4o", false},
+		{"usage:cost:reconcile:key-1:gpt-4o:2024-06-10", false},
+	}
+	for _, tt := range tests {
+		if got := isModelUsageKey(tt.key, "gpt-4o"); got != tt.want {
+			t.Errorf("isModelUsageKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
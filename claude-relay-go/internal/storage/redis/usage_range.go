@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MaxUsageRangeDays 区间用量汇总的最大天数（受每日统计保留时间限制）
+const MaxUsageRangeDays = 31
+
+// ErrInvalidUsageRange 用量汇总区间无效
+var ErrInvalidUsageRange = errors.New("invalid usage range")
+
+// add 累加另一份用量统计
+func (s *UsageStats) add(o *UsageStats) {
+	s.TotalTokens += o.TotalTokens
+	s.InputTokens += o.InputTokens
+	s.OutputTokens += o.OutputTokens
+	s.CacheCreateTokens += o.CacheCreateTokens
+	s.CacheReadTokens += o.CacheReadTokens
+	s.AllTokens += o.AllTokens
+	s.RequestCount += o.RequestCount
+	s.Ephemeral5mTokens += o.Ephemeral5mTokens
+	s.Ephemeral1hTokens += o.Ephemeral1hTokens
+	s.LongContextRequests += o.LongContextRequests
+}
+
+// usageRangeDates 返回 [from, to] 覆盖的配置时区日期（含两端）
+func usageRangeDates(from, to time.Time) ([]string, error) {
+	loc := UsageLocation()
+	start := from.In(loc)
+	end := to.In(loc)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+
+	if start.After(end) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidUsageRange)
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	if days > MaxUsageRangeDays {
+		return nil, fmt.Errorf("%w: range spans %d days, max %d", ErrInvalidUsageRange, days, MaxUsageRangeDays)
+	}
+
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = getDateStringInTimezone(start.AddDate(0, 0, i))
+	}
+	return dates, nil
+}
+
+// GetUsageStatsRange 汇总 API Key 在 [from, to]（按配置时区日期，含两端）内的每日用量与成本
+func (c *Client) GetUsageStatsRange(ctx context.Context, keyID string, from, to time.Time) (*UsageStats, error) {
+	dates, err := usageRangeDates(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.GetReadClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	usageCmds := make([]*goredis.MapStringStringCmd, len(dates))
+	pipe := client.Pipeline()
+	for i, dateStr := range dates {
+		usageCmds[i] = pipe.HGetAll(ctx, fmt.Sprintf("%s%s:%s", PrefixUsageDaily, keyID, dateStr))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+
+	stats := &UsageStats{}
+	for _, cmd := range usageCmds {
+		usage, err := cmd.Result()
+		if err != nil && err != goredis.Nil {
+			return nil, fmt.Errorf("failed to get daily usage: %w", err)
+		}
+		stats.add(parseUsageData(usage))
+	}
+
+	costMicros, err := sumDailyCostMicros(ctx, client, keyID, dates)
+	if err != nil {
+		return nil, err
+	}
+	stats.TotalCost = RoundCostForStorage(MicrosToCost(costMicros))
+	return stats, nil
+}
+
+// GetWeeklyUsage 汇总 API Key 本周（配置时区周一零点至 now）的用量与成本
+func (c *Client) GetWeeklyUsage(ctx context.Context, keyID string, now time.Time) (*UsageStats, error) {
+	start, _, _ := periodBounds(CostPeriodWeekly, now)
+	return c.GetUsageStatsRange(ctx, keyID, start, now)
+}
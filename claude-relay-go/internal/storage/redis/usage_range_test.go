@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/catstream/claude-relay-go/internal/config"
+)
+
+func TestGetUsageStatsRange_SumsDailyBuckets(t *testing.T) {
+	oldCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = oldCfg })
+	config.Cfg = &config.Config{System: config.SystemConfig{TimezoneOffset: 8}}
+
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	for date, requests := range map[string]string{"2025-03-09": "1", "2025-03-10": "2", "2025-03-12": "4", "2025-03-13": "8"} {
+		hook.hashes[PrefixUsageDaily+"key-a:"+date] = map[string]string{
+			"requests": requests, "inputTokens": "10", "outputTokens": "5", "allTokens": "15",
+		}
+	}
+	hook.hashes["usage:cost:daily:key-a:2025-03-10"] = map[string]string{"totalCost": "0.1"}
+	hook.strings["usage:cost:daily:key-a:2025-03-12"] = "0.2" // 旧格式
+	hook.hashes["usage:cost:daily:key-a:2025-03-13"] = map[string]string{"totalCost": "5"}
+
+	loc := UsageLocation()
+	from := time.Date(2025, 3, 10, 0, 0, 0, 0, loc)
+	to := time.Date(2025, 3, 12, 0, 0, 0, 0, loc)
+	stats, err := c.GetUsageStatsRange(ctx, "key-a", from, to)
+	if err != nil {
+		t.Fatalf("GetUsageStatsRange() error = %v", err)
+	}
+	if stats.RequestCount != 6 || stats.InputTokens != 20 || stats.AllTokens != 30 {
+		t.Errorf("stats = %+v, want 6 requests, 20 input and 30 total tokens over 2 days", stats)
+	}
+	if stats.TotalCost != 0.3 {
+		t.Errorf("TotalCost = %v, want 0.3", stats.TotalCost)
+	}
+
+	// 周四 UTC 02:00 = UTC+8 周四 10:00，本周为 03-10 至 03-13
+	weekly, err := c.GetWeeklyUsage(ctx, "key-a", time.Date(2025, 3, 13, 2, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetWeeklyUsage() error = %v", err)
+	}
+	if weekly.RequestCount != 14 || weekly.TotalCost != 5.3 {
+		t.Errorf("weekly = %+v, want 14 requests and cost 5.3 from Monday on", weekly)
+	}
+}
+
+func TestGetUsageStatsRange_ValidatesRange(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, UsageLocation())
+
+	if _, err := c.GetUsageStatsRange(ctx, "key-a", day, day.AddDate(0, 0, -1)); !errors.Is(err, ErrInvalidUsageRange) {
+		t.Errorf("from after to error = %v, want ErrInvalidUsageRange", err)
+	}
+	if _, err := c.GetUsageStatsRange(ctx, "key-a", day, day.AddDate(0, 0, MaxUsageRangeDays)); !errors.Is(err, ErrInvalidUsageRange) {
+		t.Errorf("oversized range error = %v, want ErrInvalidUsageRange", err)
+	}
+	if _, err := c.GetUsageStatsRange(ctx, "key-a", day, day.AddDate(0, 0, MaxUsageRangeDays-1)); err != nil {
+		t.Errorf("max range error = %v, want nil", err)
+	}
+}
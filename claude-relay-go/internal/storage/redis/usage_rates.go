@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultRecentRateWindowMinutes 近期速率默认统计窗口（分钟）
+	DefaultRecentRateWindowMinutes = 60
+	// MaxRecentRateWindowMinutes 近期速率最大统计窗口（分钟）
+	MaxRecentRateWindowMinutes = 24 * 60
+)
+
+// RecentRates 近期窗口内的请求与 Token 速率
+type RecentRates struct {
+	KeyID         string  `json:"keyId"`
+	WindowMinutes int     `json:"windowMinutes"`
+	Requests      float64 `json:"requests"` // 窗口内请求数（部分重叠的小时按比例折算）
+	Tokens        float64 `json:"tokens"`
+	RPM           float64 `json:"rpm"`
+	TPM           float64 `json:"tpm"`
+}
+
+// GetRecentRates 计算 API Key 最近 windowMinutes 分钟的 RPM/TPM（区别于 UsageAverages 的创建以来平均值）
+// 基于每小时统计：每个小时桶按与窗口重叠的比例折算（当前小时的跨度为已过去的部分）
+func (c *Client) GetRecentRates(ctx context.Context, keyID string, windowMinutes int) (*RecentRates, error) {
+	return c.getRecentRatesAt(ctx, keyID, windowMinutes, time.Now())
+}
+
+func (c *Client) getRecentRatesAt(ctx context.Context, keyID string, windowMinutes int, now time.Time) (*RecentRates, error) {
+	if windowMinutes <= 0 || windowMinutes > MaxRecentRateWindowMinutes {
+		return nil, fmt.Errorf("window must be between 1 and %d minutes", MaxRecentRateWindowMinutes)
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	windowStart := now.Add(-time.Duration(windowMinutes) * time.Minute)
+
+	type hourBucket struct {
+		cmd    *goredis.MapStringStringCmd
+		weight float64
+	}
+	var buckets []hourBucket
+	pipe := client.Pipeline()
+	for hourStart := now.Truncate(time.Hour); hourStart.After(windowStart.Add(-time.Hour)); hourStart = hourStart.Add(-time.Hour) {
+		weight := hourBucketWeight(hourStart, windowStart, now)
+		key := fmt.Sprintf("%s%s:%s", PrefixUsageHourly, keyID, getHourStringInTimezone(hourStart))
+		buckets = append(buckets, hourBucket{cmd: pipe.HGetAll(ctx, key), weight: weight})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get hourly usage: %w", err)
+	}
+
+	rates := &RecentRates{KeyID: keyID, WindowMinutes: windowMinutes}
+	for _, bucket := range buckets {
+		data := bucket.cmd.Val()
+		tokens := parseInt64(data["tokens"])
+		if tokens == 0 {
+			tokens = parseInt64(data["allTokens"])
+		}
+		rates.Requests += float64(parseInt64(data["requests"])) * bucket.weight
+		rates.Tokens += float64(tokens) * bucket.weight
+	}
+	rates.RPM = rates.Requests / float64(windowMinutes)
+	rates.TPM = rates.Tokens / float64(windowMinutes)
+
+	return rates, nil
+}
+
+// hourBucketWeight 小时桶计入窗口 [windowStart, now] 的比例
+// 桶内请求视为均匀分布在其跨度内：已结束的小时为整小时，当前小时为 [hourStart, now]
+// 例如 12:40 统计 5 分钟窗口时，当前小时桶只计入 5/40
+func hourBucketWeight(hourStart, windowStart, now time.Time) float64 {
+	spanEnd := hourStart.Add(time.Hour)
+	if now.Before(spanEnd) {
+		spanEnd = now
+	}
+	span := spanEnd.Sub(hourStart)
+	if span <= 0 {
+		return 1
+	}
+	overlapStart := hourStart
+	if windowStart.After(overlapStart) {
+		overlapStart = windowStart
+	}
+	overlap := spanEnd.Sub(overlapStart)
+	if overlap <= 0 {
+		return 0
+	}
+	return float64(overlap) / float64(span)
+}
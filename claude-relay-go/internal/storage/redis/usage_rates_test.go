@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetRecentRates_WindowedVersusLifetime(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	hourly := func(hour time.Time, requests, tokens int) {
+		hook.hashes[fmt.Sprintf("%s%s:%s", PrefixUsageHourly, "key-1", getHourStringInTimezone(hour))] = map[string]string{
+			"requests": fmt.Sprint(requests),
+			"tokens":   fmt.Sprint(tokens),
+		}
+	}
+	hourly(now.Truncate(time.Hour), 30, 3000)                   // 12:00，30 分钟以上的窗口整桶计入
+	hourly(now.Truncate(time.Hour).Add(-time.Hour), 60, 6000)   // 11:00，60 分钟窗口只计入一半
+	hourly(now.Truncate(time.Hour).Add(-2*time.Hour), 1000, 50) // 10:00，仅 120 分钟窗口计入
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "createdAt": time.Now().AddDate(0, 0, -30).Format(time.RFC3339)}
+	hook.hashes[PrefixUsage+"key-1"] = map[string]string{"totalRequests": "1090", "totalTokens": "9050"}
+
+	rates, err := c.getRecentRatesAt(ctx, "key-1", 60, now)
+	if err != nil {
+		t.Fatalf("getRecentRatesAt() error = %v", err)
+	}
+	if math.Abs(rates.RPM-1.0) > 1e-9 || math.Abs(rates.TPM-100.0) > 1e-9 {
+		t.Errorf("60m rates = %+v, want rpm 1 and tpm 100", rates)
+	}
+
+	rates, err = c.getRecentRatesAt(ctx, "key-1", 120, now)
+	if err != nil {
+		t.Fatalf("getRecentRatesAt() error = %v", err)
+	}
+	if want := 590.0 / 120; math.Abs(rates.RPM-want) > 1e-9 {
+		t.Errorf("120m rpm = %v, want %v", rates.RPM, want)
+	}
+
+	stats, err := c.GetUsageStats(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetUsageStats() error = %v", err)
+	}
+	if stats.Averages.RPM >= rates.RPM/10 {
+		t.Errorf("lifetime rpm = %v should be far below recent rpm %v", stats.Averages.RPM, rates.RPM)
+	}
+}
+
+func TestGetRecentRates_ProratesCurrentHour(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	now := time.Date(2025, 3, 1, 12, 40, 0, 0, time.UTC)
+
+	// 当前小时已过去 40 分钟，共 400 个请求
+	hook.hashes[fmt.Sprintf("%s%s:%s", PrefixUsageHourly, "key-1", getHourStringInTimezone(now.Truncate(time.Hour)))] = map[string]string{
+		"requests": "400",
+		"tokens":   "40000",
+	}
+
+	rates, err := c.getRecentRatesAt(context.Background(), "key-1", 5, now)
+	if err != nil {
+		t.Fatalf("getRecentRatesAt() error = %v", err)
+	}
+	// 5 分钟窗口只计入 5/40 的当前小时桶：50 个请求，RPM 10
+	if math.Abs(rates.Requests-50) > 1e-9 || math.Abs(rates.RPM-10) > 1e-9 || math.Abs(rates.TPM-1000) > 1e-9 {
+		t.Errorf("5m rates = %+v, want 50 requests, rpm 10, tpm 1000", rates)
+	}
+}
+
+func TestGetRecentRates_InvalidWindow(t *testing.T) {
+	c := newConnectedClientForTest(t, newMemoryRedisHook())
+
+	for _, window := range []int{0, -5, MaxRecentRateWindowMinutes + 1} {
+		if _, err := c.GetRecentRates(context.Background(), "key-1", window); err == nil {
+			t.Errorf("GetRecentRates(window=%d) expected error", window)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// UserKeyConcurrency 用户单个 API Key 的并发与排队情况
+type UserKeyConcurrency struct {
+	KeyID           string `json:"keyId"`
+	Name            string `json:"name"`
+	Concurrency     int64  `json:"concurrency"`
+	ConcurrentLimit int    `json:"concurrentLimit"`
+	Queued          int64  `json:"queued"`
+}
+
+// UserConcurrency 用户全部 API Key 的并发与排队汇总
+type UserConcurrency struct {
+	UserID           string               `json:"userId"`
+	TotalConcurrency int64                `json:"totalConcurrency"`
+	TotalQueued      int64                `json:"totalQueued"`
+	Keys             []UserKeyConcurrency `json:"keys"`
+}
+
+// GetUserConcurrency 汇总用户全部 API Key 的当前并发与排队数（只读，不清理过期租约）
+func (c *Client) GetUserConcurrency(ctx context.Context, userID string) (*UserConcurrency, error) {
+	keys, err := c.GetAllAPIKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []APIKey
+	for _, key := range keys {
+		if key.UserID == userID {
+			owned = append(owned, key)
+		}
+	}
+	return c.aggregateUserConcurrencyAt(ctx, userID, owned, time.Now())
+}
+
+// aggregateUserConcurrencyAt 在一个管道中读取各 Key 的未过期并发租约数与排队计数
+func (c *Client) aggregateUserConcurrencyAt(ctx context.Context, userID string, keys []APIKey, now time.Time) (*UserConcurrency, error) {
+	result := &UserConcurrency{UserID: userID, Keys: []UserKeyConcurrency{}}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	minScore := "(" + strconv.FormatInt(now.UnixMilli(), 10)
+	pipe := client.Pipeline()
+	slotCmds := make([]*goredis.IntCmd, len(keys))
+	queueCmds := make([]*goredis.StringCmd, len(keys))
+	for i, key := range keys {
+		slotCmds[i] = pipe.ZCount(ctx, PrefixConcurrency+key.ID, minScore, "+inf")
+		queueCmds[i] = pipe.Get(ctx, PrefixConcurrencyQueue+key.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, fmt.Errorf("failed to get user concurrency: %w", err)
+	}
+
+	for i, key := range keys {
+		entry := UserKeyConcurrency{
+			KeyID:           key.ID,
+			Name:            key.Name,
+			Concurrency:     slotCmds[i].Val(),
+			ConcurrentLimit: key.ConcurrentLimit,
+			Queued:          parseInt64(queueCmds[i].Val()),
+		}
+		if entry.Queued < 0 {
+			entry.Queued = 0
+		}
+		result.TotalConcurrency += entry.Concurrency
+		result.TotalQueued += entry.Queued
+		result.Keys = append(result.Keys, entry)
+	}
+
+	sort.Slice(result.Keys, func(i, j int) bool {
+		a, b := result.Keys[i], result.Keys[j]
+		if a.Concurrency+a.Queued != b.Concurrency+b.Queued {
+			return a.Concurrency+a.Queued > b.Concurrency+b.Queued
+		}
+		return a.KeyID < b.KeyID
+	})
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetUserConcurrency_AggregatesUserKeys(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+	now := time.Now()
+	live := float64(now.Add(time.Minute).UnixMilli())
+	expired := float64(now.Add(-time.Minute).UnixMilli())
+
+	hook.hashes[PrefixAPIKey+"key-1"] = map[string]string{"id": "key-1", "name": "one", "userId": "user-1", "concurrentLimit": "5"}
+	hook.hashes[PrefixAPIKey+"key-2"] = map[string]string{"id": "key-2", "name": "two", "userId": "user-1"}
+	hook.hashes[PrefixAPIKey+"key-3"] = map[string]string{"id": "key-3", "name": "three", "userId": "user-2"}
+
+	hook.zsets[PrefixConcurrency+"key-1"] = map[string]float64{"req-1": live, "req-2": live, "req-old": expired}
+	hook.zsets[PrefixConcurrency+"key-2"] = map[string]float64{"req-3": live}
+	hook.zsets[PrefixConcurrency+"key-3"] = map[string]float64{"req-4": live, "req-5": live}
+	hook.strings[PrefixConcurrencyQueue+"key-2"] = strconv.Itoa(3)
+	hook.strings[PrefixConcurrencyQueue+"key-3"] = strconv.Itoa(7)
+
+	result, err := c.GetUserConcurrency(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetUserConcurrency() error = %v", err)
+	}
+	if result.TotalConcurrency != 3 || result.TotalQueued != 3 {
+		t.Errorf("totals = concurrency %d queued %d, want 3 and 3", result.TotalConcurrency, result.TotalQueued)
+	}
+	if len(result.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2: %+v", len(result.Keys), result.Keys)
+	}
+
+	// 按占用（并发 + 排队）降序
+	if got := result.Keys[0]; got.KeyID != "key-2" || got.Concurrency != 1 || got.Queued != 3 {
+		t.Errorf("keys[0] = %+v, want key-2 with 1 active and 3 queued", got)
+	}
+	if got := result.Keys[1]; got.KeyID != "key-1" || got.Concurrency != 2 || got.Queued != 0 || got.ConcurrentLimit != 5 {
+		t.Errorf("keys[1] = %+v, want key-1 with 2 active of 5", got)
+	}
+
+	// 只读：过期租约不被清理
+	if _, ok := hook.zsets[PrefixConcurrency+"key-1"]["req-old"]; !ok {
+		t.Error("expired lease should not be removed by a read-only query")
+	}
+
+	empty, err := c.GetUserConcurrency(ctx, "user-none")
+	if err != nil || empty.TotalConcurrency != 0 || len(empty.Keys) != 0 {
+		t.Errorf("user without keys = %+v, %v; want empty result", empty, err)
+	}
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// userDefaultsKey 用户级默认限制 Hash 的 key
+func userDefaultsKey(userID string) string {
+	return PrefixUserLimitDefaults + userID
+}
+
+// UserLimitDefaults 用户级默认限制，用户名下的 API Key 未设置对应字段时继承（0 表示未设置）
+type UserLimitDefaults struct {
+	ConcurrentLimit     int     `json:"concurrentLimit,omitempty"`
+	RateLimitPerMin     int     `json:"rateLimitPerMin,omitempty"`
+	RateLimitPerHour    int     `json:"rateLimitPerHour,omitempty"`
+	RateLimitWindow     int     `json:"rateLimitWindow,omitempty"`
+	RateLimitCost       float64 `json:"rateLimitCost,omitempty"`
+	DailyCostLimit      float64 `json:"dailyCostLimit,omitempty"`
+	TotalCostLimit      float64 `json:"totalCostLimit,omitempty"`
+	WeeklyOpusCostLimit float64 `json:"weeklyOpusCostLimit,omitempty"`
+}
+
+// IsEmpty 是否未设置任何默认限制
+func (d *UserLimitDefaults) IsEmpty() bool {
+	return d == nil || *d == UserLimitDefaults{}
+}
+
+// ApplyTo 将默认限制填入 API Key 未设置的字段（Key 自身的设置优先）
+// Key 在 UnlimitedLimits 中列出的字段显式保持不限制（0），不继承用户默认值
+func (d *UserLimitDefaults) ApplyTo(key *APIKey) {
+	if d == nil || key == nil {
+		return
+	}
+	inherit := func(field string, unset bool) bool {
+		return unset && !slices.Contains(key.UnlimitedLimits, field)
+	}
+	if inherit("concurrentLimit", key.ConcurrentLimit == 0) {
+		key.ConcurrentLimit = d.ConcurrentLimit
+	}
+	if inherit("rateLimitPerMin", key.RateLimitPerMin == 0) {
+		key.RateLimitPerMin = d.RateLimitPerMin
+	}
+	if inherit("rateLimitPerHour", key.RateLimitPerHour == 0) {
+		key.RateLimitPerHour = d.RateLimitPerHour
+	}
+	// 窗口费用限制按窗口与费用成对继承，避免 Key 的窗口与用户的费用混用（rateLimitCost 同时覆盖窗口）
+	if inherit("rateLimitCost", key.RateLimitWindow == 0 && key.RateLimitCost == 0) {
+		key.RateLimitWindow = d.RateLimitWindow
+		key.RateLimitCost = d.RateLimitCost
+	}
+	if inherit("dailyCostLimit", key.DailyCostLimit == 0) {
+		key.DailyCostLimit = d.DailyCostLimit
+	}
+	if inherit("totalCostLimit", key.TotalCostLimit == 0) {
+		key.TotalCostLimit = d.TotalCostLimit
+	}
+	if inherit("weeklyOpusCostLimit", key.WeeklyOpusCostLimit == 0) {
+		key.WeeklyOpusCostLimit = d.WeeklyOpusCostLimit
+	}
+}
+
+// toMap 序列化为 Hash 字段（只写入已设置的字段）
+func (d *UserLimitDefaults) toMap() map[string]string {
+	m := make(map[string]string)
+	setInt := func(field string, v int) {
+		if v != 0 {
+			m[field] = strconv.Itoa(v)
+		}
+	}
+	setFloat := func(field string, v float64) {
+		if v != 0 {
+			m[field] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	setInt("concurrentLimit", d.ConcurrentLimit)
+	setInt("rateLimitPerMin", d.RateLimitPerMin)
+	setInt("rateLimitPerHour", d.RateLimitPerHour)
+	setInt("rateLimitWindow", d.RateLimitWindow)
+	setFloat("rateLimitCost", d.RateLimitCost)
+	setFloat("dailyCostLimit", d.DailyCostLimit)
+	setFloat("totalCostLimit", d.TotalCostLimit)
+	setFloat("weeklyOpusCostLimit", d.WeeklyOpusCostLimit)
+	return m
+}
+
+// GetUserLimitDefaults 获取用户级默认限制（未设置时返回 nil）
+func (c *Client) GetUserLimitDefaults(ctx context.Context, userID string) (*UserLimitDefaults, error) {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.HGetAll(ctx, userDefaultsKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user defaults: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return &UserLimitDefaults{
+		ConcurrentLimit:     int(parseInt64(data["concurrentLimit"])),
+		RateLimitPerMin:     int(parseInt64(data["rateLimitPerMin"])),
+		RateLimitPerHour:    int(parseInt64(data["rateLimitPerHour"])),
+		RateLimitWindow:     int(parseInt64(data["rateLimitWindow"])),
+		RateLimitCost:       parseFloat64(data["rateLimitCost"]),
+		DailyCostLimit:      parseFloat64(data["dailyCostLimit"]),
+		TotalCostLimit:      parseFloat64(data["totalCostLimit"]),
+		WeeklyOpusCostLimit: parseFloat64(data["weeklyOpusCostLimit"]),
+	}, nil
+}
+
+// SetUserLimitDefaults 覆盖用户级默认限制（全部为 0 时删除）
+func (c *Client) SetUserLimitDefaults(ctx context.Context, userID string, defaults *UserLimitDefaults) error {
+	client, err := c.GetClientSafe()
+	if err != nil {
+		return err
+	}
+
+	key := userDefaultsKey(userID)
+	_, err = client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if !defaults.IsEmpty() {
+			pipe.HSet(ctx, key, defaults.toMap())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set user defaults: %w", err)
+	}
+	return nil
+}
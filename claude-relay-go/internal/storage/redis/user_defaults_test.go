@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserLimitDefaults_RoundTripAndClear(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+	ctx := context.Background()
+
+	want := &UserLimitDefaults{ConcurrentLimit: 4, RateLimitPerMin: 60, DailyCostLimit: 12.5}
+	if err := c.SetUserLimitDefaults(ctx, "user-1", want); err != nil {
+		t.Fatalf("SetUserLimitDefaults() error = %v", err)
+	}
+	got, err := c.GetUserLimitDefaults(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetUserLimitDefaults() error = %v", err)
+	}
+	if got == nil || *got != *want {
+		t.Fatalf("defaults = %+v, want %+v", got, want)
+	}
+
+	// 覆盖写入不保留旧字段
+	if err := c.SetUserLimitDefaults(ctx, "user-1", &UserLimitDefaults{TotalCostLimit: 100}); err != nil {
+		t.Fatalf("SetUserLimitDefaults() error = %v", err)
+	}
+	got, _ = c.GetUserLimitDefaults(ctx, "user-1")
+	if got == nil || got.ConcurrentLimit != 0 || got.TotalCostLimit != 100 {
+		t.Errorf("defaults after overwrite = %+v", got)
+	}
+
+	if err := c.SetUserLimitDefaults(ctx, "user-1", &UserLimitDefaults{}); err != nil {
+		t.Fatalf("SetUserLimitDefaults() error = %v", err)
+	}
+	if got, _ := c.GetUserLimitDefaults(ctx, "user-1"); got != nil {
+		t.Errorf("defaults after clear = %+v, want nil", got)
+	}
+}
+
+func TestUserLimitDefaults_ApplyToKeepsKeyOverrides(t *testing.T) {
+	defaults := &UserLimitDefaults{
+		ConcurrentLimit: 5,
+		DailyCostLimit:  10,
+		RateLimitWindow: 60,
+		RateLimitCost:   2,
+	}
+
+	inherited := &APIKey{ID: "k1"}
+	defaults.ApplyTo(inherited)
+	if inherited.ConcurrentLimit != 5 || inherited.DailyCostLimit != 10 || inherited.RateLimitWindow != 60 || inherited.RateLimitCost != 2 {
+		t.Errorf("inherited = %+v", inherited)
+	}
+
+	overridden := &APIKey{ID: "k2", ConcurrentLimit: 2, RateLimitWindow: 5}
+	defaults.ApplyTo(overridden)
+	if overridden.ConcurrentLimit != 2 || overridden.DailyCostLimit != 10 {
+		t.Errorf("overridden = %+v", overridden)
+	}
+	// 窗口费用限制成对继承
+	if overridden.RateLimitWindow != 5 || overridden.RateLimitCost != 0 {
+		t.Errorf("window limit = %d/%v, want 5/0", overridden.RateLimitWindow, overridden.RateLimitCost)
+	}
+
+	// 显式不限制的字段保持 0，其余字段照常继承
+	unlimited := &APIKey{ID: "k3", UnlimitedLimits: []string{"dailyCostLimit", "rateLimitCost"}}
+	defaults.ApplyTo(unlimited)
+	if unlimited.DailyCostLimit != 0 || unlimited.RateLimitWindow != 0 || unlimited.RateLimitCost != 0 {
+		t.Errorf("unlimited overrides were replaced by defaults: %+v", unlimited)
+	}
+	if unlimited.ConcurrentLimit != 5 {
+		t.Errorf("ConcurrentLimit = %d, want inherited 5", unlimited.ConcurrentLimit)
+	}
+}
+
+func TestUserLimitDefaults_StoredUnderDedicatedPrefix(t *testing.T) {
+	hook := newMemoryRedisHook()
+	c := newConnectedClientForTest(t, hook)
+
+	if err := c.SetUserLimitDefaults(context.Background(), "user-1", &UserLimitDefaults{ConcurrentLimit: 3}); err != nil {
+		t.Fatalf("SetUserLimitDefaults() error = %v", err)
+	}
+	if hook.hashes["user_limit_defaults:user-1"]["concurrentLimit"] != "3" {
+		t.Errorf("hashes = %v, want user_limit_defaults:user-1", hook.hashes)
+	}
+}